@@ -4,9 +4,12 @@
 package main
 
 import (
+	"context"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/cppforlife/cobrautil"
 	uierrs "github.com/cppforlife/go-cli-ui/errors"
@@ -32,12 +35,21 @@ func main() {
 	}
 	// End
 
-	err := command.Execute()
+	// The first interrupt cancels the requests to the registries, so that the command stops promptly and removes its
+	// temporary files, a second one terminates the process right away
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+		confUI.ErrorLinef("Interrupted, stopping the requests to the registries (interrupt again to exit right away)")
+	}()
+
+	executedCmd, err := command.ExecuteContextC(ctx)
 	if err != nil {
 		confUI.ErrorLinef("imgpkg: Error: %v", uierrs.NewMultiLineError(err))
 		os.Exit(1)
 	}
-	if !cobrautil.IsCobraManagedCommand(os.Args) {
+	if !cmd.IsMachineReadableOutput(executedCmd) && !cobrautil.IsCobraManagedCommand(os.Args) {
 		confUI.PrintLinef("Succeeded")
 	}
 }
@@ -5,6 +5,8 @@ package bundle
 
 import (
 	"fmt"
+	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -17,12 +19,29 @@ import (
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
 	plainimg "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/plainimage"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/workspace"
+	"sigs.k8s.io/yaml"
 )
 
 const (
 	BundleConfigLabel = "dev.carvel.imgpkg.bundle"
+	// BundlePlatformsLabel Label that holds the comma separated list of platforms the bundle content is intended for
+	BundlePlatformsLabel = "dev.carvel.imgpkg.bundle.platforms"
+	// BundleConvertedLabel Label of the bundles created by imgpkg convert, that keep the layers of the plain image and
+	// have the ImagesLock in an additional last layer
+	BundleConvertedLabel = "dev.carvel.imgpkg.bundle.converted"
+	// BundleArtifactType artifactType of the manifest of bundles pushed as OCI artifacts
+	BundleArtifactType = "application/vnd.imgpkg.bundle"
+	// ImageMetadataFile File, written when pulling only the configuration of a bundle, with the labels and annotations of the bundle image
+	ImageMetadataFile = "image-metadata.yml"
 )
 
+// ImageMetadata Labels and annotations of a bundle image
+type ImageMetadata struct {
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
 // Logger Interface used for logging
 type Logger interface {
 	Errorf(msg string, args ...interface{})
@@ -66,6 +85,29 @@ type Bundle struct {
 	// discovered as part of reading the bundle.
 	// Includes refs only directly referenced by the bundle.
 	cachedImageRefs *imageRefCache
+
+	// layerCache when present is used to extract the bundle contents on pull
+	layerCache *ctlimg.LayerCache
+	// warnOnContentsMismatch when true the files that do not match the contents manifest are reported
+	// as warnings instead of failing the pull
+	warnOnContentsMismatch bool
+	// locationsRepo when present is the repository where the locations images of this bundle,
+	// and its nested bundles, are looked up instead of the repository of each bundle
+	locationsRepo *regname.Repository
+	// workspace when present holds the temporary files created while pulling or copying the bundle
+	workspace *workspace.Workspace
+	// maxDepth when greater than zero is the number of levels of bundles, starting with this one, whose images
+	// are retrieved. Bundles nested deeper are recorded as bundles but their images are not retrieved
+	maxDepth int
+	// excludedImages images of the ImagesLock, of this bundle or its nested bundles, that were not copied
+	excludedImages map[string]struct{}
+	// nestedImagesLock how the ImagesLock of the nested bundles is written on a recursive pull, defaults to
+	// NestedImagesLockCollocated
+	nestedImagesLock NestedImagesLockMode
+	// nestedImagesLockMapping new locations of the images, by their original location, used by NestedImagesLockMapping
+	nestedImagesLockMapping map[string]string
+	// httpClient when present downloads the external artifacts of this bundle and its nested bundles
+	httpClient *http.Client
 }
 
 // NewBundleFromPlainImage Creates a new Bundle with a PlainImage and uses Registry Fetcher
@@ -88,6 +130,58 @@ func NewBundleFromRef(ref string, imagesMetadata ImagesMetadata, imagesLockReade
 	return NewBundle(plainimg.NewPlainImage(ref, imagesMetadata), imagesMetadata, imagesLockReader, bundleFetcher)
 }
 
+// WithLayerCache uses the cache to extract the contents of this bundle, and its nested bundles, on pull
+func (o *Bundle) WithLayerCache(cache *ctlimg.LayerCache) *Bundle {
+	o.layerCache = cache
+	return o
+}
+
+// WithContentsMismatchWarnings reports the files, of this bundle and its nested bundles, that do not match
+// the contents manifest as warnings instead of failing the pull
+func (o *Bundle) WithContentsMismatchWarnings() *Bundle {
+	o.warnOnContentsMismatch = true
+	return o
+}
+
+// WithLocationsRepo looks up the locations images of this bundle, and its nested bundles, in the provided repository.
+// Useful when the repository of the bundle reference is not where the bundle was copied to, e.g. when using a
+// registry proxy that adds a prefix to the repository path
+func (o *Bundle) WithLocationsRepo(repo regname.Repository) *Bundle {
+	o.locationsRepo = &repo
+	return o
+}
+
+// WithWorkspace creates the temporary files used while pulling or copying this bundle, and its nested bundles,
+// in the workspace so that they are removed when the workspace is cleaned up
+func (o *Bundle) WithWorkspace(ws *workspace.Workspace) *Bundle {
+	o.workspace = ws
+	return o
+}
+
+// WithHTTPClient downloads the external artifacts of this bundle, and its nested bundles, with the client.
+// Use registry.NewHTTPClient so that the downloads trust the same certificates as the registries
+func (o *Bundle) WithHTTPClient(client *http.Client) *Bundle {
+	o.httpClient = client
+	return o
+}
+
+// WithMaxDepth retrieves only the images of the first depth levels of bundles, starting with this one, when
+// collecting the images of the bundle. A depth of zero retrieves every level
+func (o *Bundle) WithMaxDepth(depth int) *Bundle {
+	o.maxDepth = depth
+	return o
+}
+
+// WithExcludedImages records the provided images, as they are in the ImagesLock, as excluded from the copy when
+// noting the copy of the bundle, instead of expecting them to be copied
+func (o *Bundle) WithExcludedImages(images []string) *Bundle {
+	o.excludedImages = map[string]struct{}{}
+	for _, image := range images {
+		o.excludedImages[image] = struct{}{}
+	}
+	return o
+}
+
 // DigestRef Bundle full location including registry, repository and digest
 func (o *Bundle) DigestRef() string { return o.plainImg.DigestRef() }
 
@@ -127,13 +221,23 @@ func (o *Bundle) NoteCopy(processedImages *imageset.ProcessedImages, reg ImagesM
 		Kind:       ImageLocationsKind,
 	}
 	var bundleProcessedImage imageset.ProcessedImage
+	notedImages := map[string]struct{}{}
 	for _, image := range processedImages.All() {
 		ref, found := o.findCachedImageRef(image.UnprocessedImageRef.DigestRef)
 		if found {
-			locationsCfg.Images = append(locationsCfg.Images, ImageLocation{
+			location := ImageLocation{
 				Image:    ref.Image,
 				IsBundle: *ref.IsBundle,
-			})
+			}
+			copiedDigest, err := regname.NewDigest(image.DigestRef)
+			if err != nil {
+				panic(fmt.Sprintf("Internal inconsistency: Image '%s' is not a valid Digest Reference", image.DigestRef))
+			}
+			if copiedDigest.DigestStr() != ref.Digest() {
+				location.Digest = copiedDigest.DigestStr()
+			}
+			locationsCfg.Images = append(locationsCfg.Images, location)
+			notedImages[ref.Image] = struct{}{}
 		}
 		imgDigest, err := regname.NewDigest(image.UnprocessedImageRef.DigestRef)
 		if err != nil {
@@ -145,6 +249,15 @@ func (o *Bundle) NoteCopy(processedImages *imageset.ProcessedImages, reg ImagesM
 		}
 	}
 
+	for _, ref := range o.cachedImageRefs.All() {
+		if _, noted := notedImages[ref.Image]; noted {
+			continue
+		}
+		if _, excluded := o.excludedImages[ref.Image]; excluded {
+			locationsCfg.Images = append(locationsCfg.Images, ImageLocation{Image: ref.Image, IsBundle: *ref.IsBundle, Excluded: true})
+		}
+	}
+
 	if len(locationsCfg.Images) != o.cachedImageRefs.Size() {
 		panic(fmt.Sprintf("Expected: on bundle %s %d images to be written to Location OCI. Actual: %d were written", o.DigestRef(), o.cachedImageRefs.Size(), len(locationsCfg.Images)))
 	}
@@ -157,12 +270,22 @@ func (o *Bundle) NoteCopy(processedImages *imageset.ProcessedImages, reg ImagesM
 	ui.Debugf("creating Locations OCI Image\n")
 
 	// Using NewNoopLevelLogger because we do not want to have output from this push
-	return NewLocations(ui).Save(reg, destinationRef, locationsCfg, util.NewNoopLevelLogger())
+	return NewLocations(ui).WithWorkspace(o.workspace).Save(reg, destinationRef, locationsCfg, util.NewNoopLevelLogger())
 }
 
 // Pull Downloads bundle image to disk and checks if it can update the ImagesLock file
 func (o *Bundle) Pull(outputPath string, logger Logger, pullNestedBundles bool) (bool, error) {
-	isRootBundleRelocated, err := o.pull(outputPath, logger, pullNestedBundles, "", map[string]bool{}, 0)
+	return o.pullWithOpts(outputPath, logger, pullNestedBundles, false)
+}
+
+// PullConfig Downloads only the .imgpkg directory of the bundle image, together with a file containing
+// the labels and annotations of the image, and checks if it can update the ImagesLock file
+func (o *Bundle) PullConfig(outputPath string, logger Logger, pullNestedBundles bool) (bool, error) {
+	return o.pullWithOpts(outputPath, logger, pullNestedBundles, true)
+}
+
+func (o *Bundle) pullWithOpts(outputPath string, logger Logger, pullNestedBundles bool, configOnly bool) (bool, error) {
+	isRootBundleRelocated, err := o.pull(outputPath, logger, pullNestedBundles, configOnly, "", map[string]bool{}, 0)
 	if err != nil {
 		return false, err
 	}
@@ -176,7 +299,7 @@ func (o *Bundle) Pull(outputPath string, logger Logger, pullNestedBundles bool)
 	return isRootBundleRelocated, nil
 }
 
-func (o *Bundle) pull(baseOutputPath string, logger Logger, pullNestedBundles bool, bundlePath string, imagesProcessed map[string]bool, numSubBundles int) (bool, error) {
+func (o *Bundle) pull(baseOutputPath string, logger Logger, pullNestedBundles bool, configOnly bool, bundlePath string, imagesProcessed map[string]bool, numSubBundles int) (bool, error) {
 	img, err := o.checkedImage()
 	if err != nil {
 		return false, err
@@ -193,11 +316,35 @@ func (o *Bundle) pull(baseOutputPath string, logger Logger, pullNestedBundles bo
 		return false, err
 	}
 
-	err = ctlimg.NewDirImage(filepath.Join(baseOutputPath, bundlePath), img, util.NewIndentedLevelLogger(logger)).AsDirectory()
+	dirImage := ctlimg.NewDirImage(filepath.Join(baseOutputPath, bundlePath), img, util.NewIndentedLevelLogger(logger))
+	if configOnly {
+		dirImage.OnlyPaths(ImgpkgDir)
+	}
+	if o.layerCache != nil {
+		dirImage.WithLayerCache(o.layerCache)
+	}
+	err = dirImage.AsDirectory()
 	if err != nil {
 		return false, fmt.Errorf("Extracting bundle into directory: %s", err)
 	}
 
+	err = o.verifyContents(img, filepath.Join(baseOutputPath, bundlePath), configOnly, util.NewIndentedLevelLogger(logger))
+	if err != nil {
+		return false, err
+	}
+
+	if configOnly {
+		err = o.writeImageMetadata(img, filepath.Join(baseOutputPath, bundlePath, ImageMetadataFile))
+		if err != nil {
+			return false, err
+		}
+	} else {
+		err = o.fetchExternalArtifacts(img, filepath.Join(baseOutputPath, bundlePath), util.NewIndentedLevelLogger(logger))
+		if err != nil {
+			return false, err
+		}
+	}
+
 	imagesLock, err := lockconfig.NewImagesLockFromPath(filepath.Join(baseOutputPath, bundlePath, ImgpkgDir, ImagesLockFile))
 	if err != nil {
 		return false, err
@@ -207,6 +354,7 @@ func (o *Bundle) pull(baseOutputPath string, logger Logger, pullNestedBundles bo
 		logger:          logger,
 		imgRetriever:    o.imgRetriever,
 		bundleDigestRef: bundleDigestRef,
+		locationsRepo:   o.locationsRepo,
 	})
 	if err != nil {
 		return false, err
@@ -228,6 +376,13 @@ func (o *Bundle) pull(baseOutputPath string, logger Logger, pullNestedBundles bo
 			}
 
 			subBundle := NewBundleFromRef(bundleImgRef.PrimaryLocation(), o.imgRetriever, o.imagesLockReader, o.bundleFetcher)
+			subBundle.layerCache = o.layerCache
+			subBundle.warnOnContentsMismatch = o.warnOnContentsMismatch
+			subBundle.locationsRepo = o.locationsRepo
+			subBundle.workspace = o.workspace
+			subBundle.nestedImagesLock = o.nestedImagesLock
+			subBundle.nestedImagesLockMapping = o.nestedImagesLockMapping
+			subBundle.httpClient = o.httpClient
 
 			var isBundle bool
 			if bundleImgRef.IsBundle != nil {
@@ -254,7 +409,7 @@ func (o *Bundle) pull(baseOutputPath string, logger Logger, pullNestedBundles bo
 			if err != nil {
 				return false, err
 			}
-			_, err = subBundle.pull(baseOutputPath, util.NewIndentedLevelLogger(logger), pullNestedBundles, o.subBundlePath(bundleDigest), imagesProcessed, numSubBundles)
+			_, err = subBundle.pull(baseOutputPath, util.NewIndentedLevelLogger(logger), pullNestedBundles, configOnly, o.subBundlePath(bundleDigest), imagesProcessed, numSubBundles)
 			if err != nil {
 				return false, err
 			}
@@ -267,8 +422,18 @@ func (o *Bundle) pull(baseOutputPath string, logger Logger, pullNestedBundles bo
 		}
 	}
 
-	if isRelocatedToBundle {
-		err := bundleImageRefs.ImagesLock().WriteToPath(filepath.Join(baseOutputPath, bundlePath, ImgpkgDir, ImagesLockFile))
+	updatedImagesLock, rewriteImagesLock, err := o.imagesLockToWrite(bundlePath, imagesLock, bundleImageRefs, isRelocatedToBundle)
+	if err != nil {
+		return false, err
+	}
+	if rewriteImagesLock {
+		imagesLockPath := filepath.Join(baseOutputPath, bundlePath, ImgpkgDir, ImagesLockFile)
+		// The file might be hard linked to the layer cache, replace it instead of changing its content
+		err := os.Remove(imagesLockPath)
+		if err != nil {
+			return false, fmt.Errorf("Rewriting image lock file: %s", err)
+		}
+		err = updatedImagesLock.WriteToPath(imagesLockPath)
 		if err != nil {
 			return false, fmt.Errorf("Rewriting image lock file: %s", err)
 		}
@@ -277,6 +442,67 @@ func (o *Bundle) pull(baseOutputPath string, logger Logger, pullNestedBundles bo
 	return isRelocatedToBundle, nil
 }
 
+// verifyContents checks that the extracted files match the contents manifest of the bundle, when it has one
+func (o *Bundle) verifyContents(img regv1.Image, outputPath string, configOnly bool, logger Logger) error {
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return fmt.Errorf("Reading bundle image configuration: %s", err)
+	}
+	manifest, err := contentsManifestFromLabels(cfg.Config.Labels)
+	if err != nil || manifest == nil {
+		return err
+	}
+
+	onlyPath := ""
+	if configOnly {
+		onlyPath = ImgpkgDir
+	}
+	mismatches, err := manifest.Verify(outputPath, onlyPath)
+	if err != nil {
+		return fmt.Errorf("Verifying contents of bundle '%s': %s", o.DigestRef(), err)
+	}
+	if len(mismatches) == 0 {
+		return nil
+	}
+
+	if o.warnOnContentsMismatch {
+		for _, mismatch := range mismatches {
+			logger.Warnf("File '%s' does not match the contents manifest of bundle '%s': %s\n", mismatch.Path, o.DigestRef(), mismatch.Reason)
+		}
+		return nil
+	}
+
+	var details []string
+	for _, mismatch := range mismatches {
+		details = append(details, fmt.Sprintf("  %s: %s", mismatch.Path, mismatch.Reason))
+	}
+	return fmt.Errorf("Verifying contents of bundle '%s': %d file(s) do not match the contents manifest\n%s",
+		o.DigestRef(), len(mismatches), strings.Join(details, "\n"))
+}
+
+// writeImageMetadata stores the labels and annotations of the bundle image in the provided path
+func (*Bundle) writeImageMetadata(img regv1.Image, path string) error {
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return fmt.Errorf("Reading bundle image configuration: %s", err)
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return fmt.Errorf("Reading bundle image manifest: %s", err)
+	}
+
+	bs, err := yaml.Marshal(ImageMetadata{Labels: cfg.Config.Labels, Annotations: manifest.Annotations})
+	if err != nil {
+		return fmt.Errorf("Marshaling bundle image metadata: %s", err)
+	}
+
+	err = os.WriteFile(path, bs, 0600)
+	if err != nil {
+		return fmt.Errorf("Writing bundle image metadata: %s", err)
+	}
+	return nil
+}
+
 func (*Bundle) subBundlePath(bundleDigest regname.Digest) string {
 	return filepath.Join(ImgpkgDir, BundlesDir, strings.ReplaceAll(bundleDigest.DigestStr(), "sha256:", "sha256-"))
 }
@@ -289,6 +515,25 @@ func (o *Bundle) rootBundle(bundlePath string) bool {
 	return bundlePath == ""
 }
 
+// Platforms returns the platforms the content of the bundle is intended for, as recorded when the bundle was pushed
+func (o *Bundle) Platforms() ([]string, error) {
+	img, err := o.checkedImage()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	platforms, found := cfg.Config.Labels[BundlePlatformsLabel]
+	if !found || platforms == "" {
+		return nil, nil
+	}
+	return strings.Split(platforms, ","), nil
+}
+
 func (o *Bundle) checkedImage() (regv1.Image, error) {
 	isBundle, err := o.IsBundle()
 	if err != nil {
@@ -9,6 +9,7 @@ import (
 	"io"
 	"io/ioutil"
 	"path/filepath"
+	"sort"
 	"sync"
 
 	regname "github.com/google/go-containerregistry/pkg/name"
@@ -28,11 +29,55 @@ func (o *Bundle) ImagesRefsWithErrors() []ImageRef {
 func (o *Bundle) AllImagesLockRefs(concurrency int, logger util.LoggerWithLevels) ([]*Bundle, ImageRefs, error) {
 	throttleReq := util.NewThrottle(concurrency)
 
-	return o.buildAllImagesLock(&throttleReq, logger)
+	return o.buildAllImagesLock(&throttleReq, logger, 1, o.maxDepth, o.locationsRepo)
 }
 
-// buildAllImagesLock recursive function that will iterate over the Bundle graph and collect all the bundles and images
-func (o *Bundle) buildAllImagesLock(throttleReq *util.Throttle, logger util.LoggerWithLevels) ([]*Bundle, ImageRefs, error) {
+// ImageRefsByNestingLevel Groups the images and bundles referenced by this bundle, directly or through its nested
+// bundles, by the level they are nested at. The first level holds the ones referenced directly by this bundle, images
+// referenced from more than one level are only present in the shallowest one.
+// bundles are the bundles returned by AllImagesLockRefs
+func (o *Bundle) ImageRefsByNestingLevel(bundles []*Bundle) [][]ImageRef {
+	bundlesByRef := map[string]*Bundle{}
+	for _, b := range bundles {
+		bundlesByRef[b.DigestRef()] = b
+	}
+
+	var levels [][]ImageRef
+	seen := map[string]struct{}{o.Digest(): {}}
+	currentBundles := []*Bundle{o}
+	for len(currentBundles) > 0 {
+		var level []ImageRef
+		var nextBundles []*Bundle
+		for _, b := range currentBundles {
+			for _, ref := range b.ImagesRefsWithErrors() {
+				if ref.Error != "" || (ref.ImageType != ContentImage && ref.ImageType != BundleImage) {
+					continue
+				}
+				if _, found := seen[ref.Digest()]; found {
+					continue
+				}
+				seen[ref.Digest()] = struct{}{}
+				level = append(level, ref)
+
+				if nestedBundle, found := bundlesByRef[ref.PrimaryLocation()]; found && ref.ImageType == BundleImage {
+					nextBundles = append(nextBundles, nestedBundle)
+				}
+			}
+		}
+		if len(level) == 0 {
+			break
+		}
+		sort.Slice(level, func(i, j int) bool { return level[i].PrimaryLocation() < level[j].PrimaryLocation() })
+		levels = append(levels, level)
+		currentBundles = nextBundles
+	}
+	return levels
+}
+
+// buildAllImagesLock recursive function that will iterate over the Bundle graph and collect all the bundles and images.
+// depth is the level of this bundle in the graph, the nested bundles past maxDepth are not iterated over.
+// locationsRepo is the repository of the locations images of every bundle in the graph, nil when they are next to each bundle
+func (o *Bundle) buildAllImagesLock(throttleReq *util.Throttle, logger util.LoggerWithLevels, depth int, maxDepth int, locationsRepo *regname.Repository) ([]*Bundle, ImageRefs, error) {
 	img, err := o.checkedImage()
 	if err != nil {
 		return nil, ImageRefs{}, err
@@ -47,6 +92,7 @@ func (o *Bundle) buildAllImagesLock(throttleReq *util.Throttle, logger util.Logg
 		logger:          logger,
 		imgRetriever:    o.imgRetriever,
 		bundleDigestRef: bundleDigestRef,
+		locationsRepo:   locationsRepo,
 	}
 	imageRefsToProcess, err := o.fetchImagesRef(img, &locationsConfig)
 	if err != nil {
@@ -73,7 +119,7 @@ func (o *Bundle) buildAllImagesLock(throttleReq *util.Throttle, logger util.Logg
 
 		image := image.DeepCopy()
 		go func() {
-			nestedBundles, nestedBundlesProcessedImageRefs, imgRef, err := o.imagesLockIfIsBundle(throttleReq, image, logger)
+			isBundle, nestedBundles, nestedBundlesProcessedImageRefs, imgRef, err := o.imagesLockIfIsBundle(throttleReq, image, logger, depth, maxDepth, locationsRepo)
 			if err != nil {
 				errChan <- err
 				return
@@ -84,7 +130,6 @@ func (o *Bundle) buildAllImagesLock(throttleReq *util.Throttle, logger util.Logg
 			bundles = append(bundles, nestedBundles...)
 
 			// Adds Image to the resulting ImagesLock
-			isBundle := len(nestedBundles) > 0 // nestedBundles have Bundles when the image is a bundle
 			var typedImgRef ImageRef
 			if isBundle {
 				typedImgRef = NewBundleImageRef(imgRef)
@@ -128,22 +173,23 @@ func (o *Bundle) fetchImagesRef(img regv1.Image, locationsConfig ImageRefLocatio
 	return refs, nil
 }
 
-// imagesLockIfIsBundle retrieve all the images associated with Bundle imgRef. if it is not a bundle will return no new images
-func (o *Bundle) imagesLockIfIsBundle(throttleReq *util.Throttle, imgRef ImageRef, logger util.LoggerWithLevels) ([]*Bundle, ImageRefs, lockconfig.ImageRef, error) {
+// imagesLockIfIsBundle retrieve all the images associated with Bundle imgRef. if it is not a bundle, or it is nested
+// deeper than maxDepth, will return no new images
+func (o *Bundle) imagesLockIfIsBundle(throttleReq *util.Throttle, imgRef ImageRef, logger util.LoggerWithLevels, depth int, maxDepth int, locationsRepo *regname.Repository) (bool, []*Bundle, ImageRefs, lockconfig.ImageRef, error) {
 	newImgRef, bundle, err := o.bundleFetcher.Bundle(throttleReq, imgRef)
 	if err != nil {
-		return nil, ImageRefs{}, lockconfig.ImageRef{}, err
+		return false, nil, ImageRefs{}, lockconfig.ImageRef{}, err
 	}
 
 	var processedImageRefs ImageRefs
 	var nestedBundles []*Bundle
-	if bundle != nil {
-		nestedBundles, processedImageRefs, err = bundle.buildAllImagesLock(throttleReq, logger)
+	if bundle != nil && (maxDepth <= 0 || depth < maxDepth) {
+		nestedBundles, processedImageRefs, err = bundle.buildAllImagesLock(throttleReq, logger, depth+1, maxDepth, locationsRepo)
 		if err != nil {
-			return nil, ImageRefs{}, lockconfig.ImageRef{}, fmt.Errorf("Retrieving images for bundle '%s': %s", imgRef.Image, err)
+			return false, nil, ImageRefs{}, lockconfig.ImageRef{}, fmt.Errorf("Retrieving images for bundle '%s': %s", imgRef.Image, err)
 		}
 	}
-	return nestedBundles, processedImageRefs, newImgRef, nil
+	return bundle != nil, nestedBundles, processedImageRefs, newImgRef, nil
 }
 
 // NewImagesLockReader Creates a SingleLayerReader
@@ -160,7 +206,8 @@ type SingleLayerReader struct {
 	imagesLockMutex *sync.Mutex
 }
 
-// Read the ImagesLock from the provided img
+// Read the ImagesLock from the single layer of the provided img, or from the last layer when it was converted from a
+// plain image
 func (o *SingleLayerReader) Read(img regv1.Image) (lockconfig.ImagesLock, error) {
 	imagesLock, found := o.cachedImagesLock(img)
 	if found {
@@ -173,11 +220,19 @@ func (o *SingleLayerReader) Read(img regv1.Image) (lockconfig.ImagesLock, error)
 		return conf, err
 	}
 
-	if len(layers) != 1 {
-		return conf, fmt.Errorf("Expected bundle to only have a single layer, got %d", len(layers))
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return conf, err
 	}
 
-	layer := layers[0]
+	// Bundles converted from plain images keep the layers of the image and have the ImagesLock in the last layer
+	if _, converted := cfg.Config.Labels[BundleConvertedLabel]; !converted && len(layers) != 1 {
+		return conf, fmt.Errorf("Expected bundle to only have a single layer, got %d", len(layers))
+	}
+	if len(layers) == 0 {
+		return conf, fmt.Errorf("Expected bundle to have at least one layer")
+	}
+	layer := layers[len(layers)-1]
 
 	mediaType, err := layer.MediaType()
 	if err != nil {
@@ -259,10 +314,11 @@ type LocationsConfig struct {
 	logger          util.LoggerWithLevels
 	imgRetriever    ImagesMetadata
 	bundleDigestRef regname.Digest
+	locationsRepo   *regname.Repository
 }
 
 func (l LocationsConfig) Config() (ImageLocationsConfig, error) {
-	return NewLocations(l.logger).Fetch(l.imgRetriever, l.bundleDigestRef)
+	return NewLocations(l.logger).WithRepo(l.locationsRepo).Fetch(l.imgRetriever, l.bundleDigestRef)
 }
 
 // NotFoundLocationsConfig Noop Locations Configuration retrieval
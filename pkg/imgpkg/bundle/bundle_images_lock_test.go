@@ -4,15 +4,24 @@
 package bundle_test
 
 import (
+	"archive/tar"
+	"bytes"
 	"fmt"
 	"os"
 	"testing"
 
 	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/stretchr/testify/require"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/plainimage"
 	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
 )
@@ -1293,3 +1302,57 @@ func handleSetup(t *testing.T, setup imageOrBundleDef, logger *helpers.Logger, t
 	fmt.Printf("top bundle digest: %s\n", tree.TopRef()[0])
 	return imagesLockReader, registryBuilder, tree.TopRef()[0], tree
 }
+
+func TestSingleLayerReader(t *testing.T) {
+	imagesLockLayer := func(t *testing.T) regv1.Layer {
+		imagesLock := lockconfig.NewEmptyImagesLock()
+		imagesLock.AddImageRef(lockconfig.ImageRef{Image: "registry.io/app/img@sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"})
+		bs, err := imagesLock.AsBytes()
+		require.NoError(t, err)
+
+		buf := &bytes.Buffer{}
+		tarWriter := tar.NewWriter(buf)
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: ".imgpkg/images.yml", Mode: 0600, Size: int64(len(bs))}))
+		_, err = tarWriter.Write(bs)
+		require.NoError(t, err)
+		require.NoError(t, tarWriter.Close())
+
+		layer, err := tarball.LayerFromReader(buf)
+		require.NoError(t, err)
+		return layer
+	}
+	multiLayerBundle := func(t *testing.T, labels map[string]string) regv1.Image {
+		contentLayer, err := random.Layer(100, types.DockerLayer)
+		require.NoError(t, err)
+		img, err := mutate.AppendLayers(empty.Image, contentLayer, imagesLockLayer(t))
+		require.NoError(t, err)
+		img, err = mutate.Config(img, regv1.Config{Labels: labels})
+		require.NoError(t, err)
+		return img
+	}
+
+	t.Run("reads the ImagesLock of a bundle with a single layer", func(t *testing.T) {
+		img, err := mutate.AppendLayers(empty.Image, imagesLockLayer(t))
+		require.NoError(t, err)
+
+		imagesLock, err := bundle.NewImagesLockReader().Read(img)
+		require.NoError(t, err)
+		require.Len(t, imagesLock.Images, 1)
+	})
+
+	t.Run("fails when a bundle that was not converted from a plain image has more than one layer", func(t *testing.T) {
+		img := multiLayerBundle(t, map[string]string{bundle.BundleConfigLabel: "true"})
+
+		_, err := bundle.NewImagesLockReader().Read(img)
+		require.Error(t, err)
+		require.Equal(t, "Expected bundle to only have a single layer, got 2", err.Error())
+	})
+
+	t.Run("reads the ImagesLock from the last layer of a bundle converted from a plain image", func(t *testing.T) {
+		img := multiLayerBundle(t, map[string]string{bundle.BundleConfigLabel: "true", bundle.BundleConvertedLabel: "true"})
+
+		imagesLock, err := bundle.NewImagesLockReader().Read(img)
+		require.NoError(t, err)
+		require.Len(t, imagesLock.Images, 1)
+	})
+}
@@ -4,15 +4,20 @@
 package bundle
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	regname "github.com/google/go-containerregistry/pkg/name"
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
 	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	ctlimg "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/image"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/plainimage"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
 )
@@ -27,6 +32,17 @@ type Contents struct {
 	paths               []string
 	excludedPaths       []string
 	preservePermissions bool
+	platforms           []string
+	formatVersion       int
+	externalArtifacts   []ExternalArtifactSource
+	// contentsManifestAlgorithm when provided the hash of each file is recorded in the bundle
+	contentsManifestAlgorithm string
+	// sbomFormat when provided an SBOM of the bundle is attached to it as an OCI artifact
+	sbomFormat string
+	// compression used for the layer of the bundle image, gzip when not provided
+	compression ctlimg.LayerCompression
+	// pushAsArtifact when true the manifest of the bundle image records BundleArtifactType
+	pushAsArtifact bool
 }
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . ImagesMetadataWriter
@@ -42,15 +58,178 @@ func NewContents(paths []string, excludedPaths []string, preservePermissions boo
 	return Contents{paths: paths, excludedPaths: excludedPaths, preservePermissions: preservePermissions}
 }
 
+// WithPlatforms records the platforms the content of the bundle is intended for, when the bundle is pushed
+func (b Contents) WithPlatforms(platforms []string) Contents {
+	b.platforms = platforms
+	return b
+}
+
+// WithFormatVersion sets the format version recorded in the bundle, by default the oldest format version
+// that supports the contents of the bundle is used
+func (b Contents) WithFormatVersion(version int) Contents {
+	b.formatVersion = version
+	return b
+}
+
+// WithExternalArtifacts keeps the files out of the bundle image, the bundle only references them by URL and digest
+// and they are downloaded from the URL when the bundle is pulled
+func (b Contents) WithExternalArtifacts(artifacts []ExternalArtifactSource) Contents {
+	b.externalArtifacts = artifacts
+	return b
+}
+
+// WithContentsManifest records in the bundle the hash of each of its files, calculated with the algorithm,
+// so that pull can verify the extracted files
+func (b Contents) WithContentsManifest(algorithm string) Contents {
+	b.contentsManifestAlgorithm = algorithm
+	return b
+}
+
+// WithSBOM generates an SBOM, in the format, that describes the files of the bundle and the images in its ImagesLock,
+// and attaches it to the bundle image as an OCI artifact that refers to the bundle
+func (b Contents) WithSBOM(format string) Contents {
+	b.sbomFormat = format
+	return b
+}
+
+// WithCompression Compresses the layer of the bundle image with the provided compression instead of gzip
+func (b Contents) WithCompression(compression ctlimg.LayerCompression) Contents {
+	b.compression = compression
+	return b
+}
+
+// WithArtifactManifest Pushes the bundle image with an OCI image manifest whose artifactType is BundleArtifactType,
+// so that registries and scanners can identify bundles without retrieving their config
+func (b Contents) WithArtifactManifest() Contents {
+	b.pushAsArtifact = true
+	return b
+}
+
 // Push the contents of the bundle to the registry as an OCI Image
 func (b Contents) Push(uploadRef regname.Tag, registry ImagesMetadataWriter, logger Logger) (string, error) {
 	err := b.validate()
 	if err != nil {
 		return "", err
 	}
+	if b.sbomFormat != "" {
+		err = ValidateSBOMFormat(b.sbomFormat)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	requiredFormatVersion := FormatVersion1
+	if len(b.externalArtifacts) > 0 {
+		requiredFormatVersion = FormatVersion2
+	}
+	formatVersion := b.formatVersion
+	if formatVersion == 0 {
+		formatVersion = requiredFormatVersion
+	}
+	err = ValidateFormatVersion(formatVersion)
+	if err != nil {
+		return "", err
+	}
+	if formatVersion < requiredFormatVersion {
+		return "", fmt.Errorf("External artifacts require bundle format version %d or newer, but format version %d was requested", requiredFormatVersion, formatVersion)
+	}
+
+	labels := map[string]string{BundleConfigLabel: "true", BundleFormatVersionLabel: strconv.Itoa(formatVersion)}
+	if len(b.platforms) > 0 {
+		labels[BundlePlatformsLabel] = strings.Join(b.platforms, ",")
+	}
+
+	excludedPaths := b.excludedPaths
+	if len(b.externalArtifacts) > 0 {
+		artifacts, err := externalArtifacts(b.paths, b.externalArtifacts)
+		if err != nil {
+			return "", err
+		}
+		artifactsJSON, err := json.Marshal(artifacts)
+		if err != nil {
+			return "", err
+		}
+		labels[BundleExternalArtifactsLabel] = string(artifactsJSON)
+
+		excludedPaths = append([]string{}, b.excludedPaths...)
+		for _, artifact := range artifacts {
+			excludedPaths = append(excludedPaths, filepath.FromSlash(artifact.Path))
+		}
+	}
+	if b.contentsManifestAlgorithm != "" {
+		manifest, err := newContentsManifest(b.paths, excludedPaths, b.contentsManifestAlgorithm)
+		if err != nil {
+			return "", err
+		}
+		manifestJSON, err := json.Marshal(manifest)
+		if err != nil {
+			return "", err
+		}
+		labels[BundleContentsManifestLabel] = string(manifestJSON)
+	}
+
+	imageContents := plainimage.NewContents(b.paths, excludedPaths, b.preservePermissions).WithCompression(b.compression)
+	if b.pushAsArtifact {
+		imageContents = imageContents.WithArtifactType(BundleArtifactType)
+	}
+	imageURL, err := imageContents.Push(uploadRef, labels, registry, logger)
+	if err != nil {
+		return "", err
+	}
+
+	if b.sbomFormat != "" {
+		err = b.attachSBOM(imageURL, excludedPaths, registry, logger)
+		if err != nil {
+			return "", fmt.Errorf("Attaching SBOM to bundle '%s': %s", imageURL, err)
+		}
+	}
+
+	return imageURL, nil
+}
+
+// attachSBOM pushes the SBOM of the bundle as an OCI artifact whose subject is the bundle image
+func (b Contents) attachSBOM(imageURL string, excludedPaths []string, registry ImagesMetadataWriter, logger Logger) error {
+	bundleRef, err := regname.NewDigest(imageURL)
+	if err != nil {
+		return err
+	}
+
+	files, err := newContentsManifest(b.paths, excludedPaths, DefaultContentsManifestAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	imgpkgDirs, err := b.findImgpkgDirs()
+	if err != nil {
+		return err
+	}
+	imagesLock, err := lockconfig.NewImagesLockFromPath(filepath.Join(imgpkgDirs[0], ImagesLockFile))
+	if err != nil {
+		return err
+	}
+
+	contents, err := newSBOMContents(bundleRef, files, imagesLock, time.Now())
+	if err != nil {
+		return err
+	}
+
+	bundleDesc, err := registry.Get(bundleRef)
+	if err != nil {
+		return err
+	}
+
+	artifact, err := newSBOMArtifact(b.sbomFormat, contents, bundleDesc.Descriptor)
+	if err != nil {
+		return err
+	}
+	artifactDigest, err := artifact.Digest()
+	if err != nil {
+		return err
+	}
 
-	labels := map[string]string{BundleConfigLabel: "true"}
-	return plainimage.NewContents(b.paths, b.excludedPaths, b.preservePermissions).Push(uploadRef, labels, registry, logger)
+	artifactRef := bundleRef.Context().Digest(artifactDigest.String())
+	logger.Logf("Attaching %s SBOM '%s'\n", b.sbomFormat, artifactRef.Name())
+	return registry.WriteImage(artifactRef, artifact, nil)
 }
 
 // PresentsAsBundle checks if the provided folders have the needed structure to be a bundle
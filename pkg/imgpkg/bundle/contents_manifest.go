@@ -0,0 +1,207 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// BundleContentsManifestLabel Label that holds the hash of each file of the bundle, used to verify the files when pulling
+	BundleContentsManifestLabel = "dev.carvel.imgpkg.bundle.contents-manifest"
+	// DefaultContentsManifestAlgorithm Hash algorithm used by the contents manifest when none is provided
+	DefaultContentsManifestAlgorithm = "sha256"
+)
+
+// contentsHashers Hash algorithms that can be used in the contents manifest
+var contentsHashers = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// ContentsManifestAlgorithms returns the hash algorithms that can be used in the contents manifest
+func ContentsManifestAlgorithms() []string {
+	var algorithms []string
+	for algorithm := range contentsHashers {
+		algorithms = append(algorithms, algorithm)
+	}
+	sort.Strings(algorithms)
+	return algorithms
+}
+
+// ContentsManifest Hash of each file of the bundle, keyed by the path of the file relative to the root of the bundle
+type ContentsManifest struct {
+	Algorithm string            `json:"algorithm"`
+	Files     map[string]string `json:"files"`
+}
+
+// ContentsMismatch File that does not match the contents manifest
+type ContentsMismatch struct {
+	Path   string
+	Reason string
+}
+
+// newContentsManifest hashes the files that are added to the bundle image when the paths are pushed
+func newContentsManifest(paths []string, excludedPaths []string, algorithm string) (ContentsManifest, error) {
+	if _, found := contentsHashers[algorithm]; !found {
+		return ContentsManifest{}, fmt.Errorf("Unsupported contents manifest algorithm '%s' (supported algorithms: %s)", algorithm, strings.Join(ContentsManifestAlgorithms(), ", "))
+	}
+
+	isExcluded := func(relPath string) bool {
+		for _, excludedPath := range excludedPaths {
+			if excludedPath == relPath {
+				return true
+			}
+		}
+		return false
+	}
+
+	manifest := ContentsManifest{Algorithm: algorithm, Files: map[string]string{}}
+	addFile := func(filePath, relPath string) error {
+		if isExcluded(relPath) {
+			return nil
+		}
+		fileHash, err := hashFile(algorithm, filePath)
+		if err != nil {
+			return fmt.Errorf("Hashing file '%s': %s", filePath, err)
+		}
+		manifest.Files[filepath.ToSlash(relPath)] = fileHash
+		return nil
+	}
+
+	// Follows the same rules as image.TarImage to decide the files that are part of the image
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return ContentsManifest{}, err
+		}
+
+		if !info.IsDir() {
+			err = addFile(path, filepath.Base(path))
+			if err != nil {
+				return ContentsManifest{}, err
+			}
+			continue
+		}
+
+		err = filepath.Walk(path, func(walkedPath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			relPath, err := filepath.Rel(path, walkedPath)
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if isExcluded(relPath) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			return addFile(walkedPath, relPath)
+		})
+		if err != nil {
+			return ContentsManifest{}, err
+		}
+	}
+	return manifest, nil
+}
+
+// Verify compares the files in rootPath with the manifest, only the files in onlyPath are verified when provided
+func (m ContentsManifest) Verify(rootPath string, onlyPath string) ([]ContentsMismatch, error) {
+	if _, found := contentsHashers[m.Algorithm]; !found {
+		return nil, fmt.Errorf("Unsupported contents manifest algorithm '%s' (hint: upgrade imgpkg)", m.Algorithm)
+	}
+
+	inScope := func(relPath string) bool {
+		return onlyPath == "" || relPath == onlyPath || strings.HasPrefix(relPath, onlyPath+"/")
+	}
+
+	var mismatches []ContentsMismatch
+	var paths []string
+	for path := range m.Files {
+		if inScope(path) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		fileHash, err := hashFile(m.Algorithm, filepath.Join(rootPath, filepath.FromSlash(path)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				mismatches = append(mismatches, ContentsMismatch{Path: path, Reason: "file is missing"})
+				continue
+			}
+			return nil, fmt.Errorf("Hashing file '%s': %s", path, err)
+		}
+		if fileHash != m.Files[path] {
+			mismatches = append(mismatches, ContentsMismatch{Path: path, Reason: fmt.Sprintf("expected %s %s but got %s", m.Algorithm, m.Files[path], fileHash)})
+		}
+	}
+
+	err := filepath.Walk(rootPath, func(walkedPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(rootPath, walkedPath)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if _, found := m.Files[relPath]; !found && inScope(relPath) {
+			mismatches = append(mismatches, ContentsMismatch{Path: relPath, Reason: "file is not in the contents manifest"})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mismatches, nil
+}
+
+func hashFile(algorithm string, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := contentsHashers[algorithm]()
+	_, err = io.Copy(hasher, file)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func contentsManifestFromLabels(labels map[string]string) (*ContentsManifest, error) {
+	value, found := labels[BundleContentsManifestLabel]
+	if !found || value == "" {
+		return nil, nil
+	}
+
+	var manifest ContentsManifest
+	err := json.Unmarshal([]byte(value), &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("Reading contents manifest from label %s: %s", BundleContentsManifestLabel, err)
+	}
+	return &manifest, nil
+}
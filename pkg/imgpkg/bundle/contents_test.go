@@ -4,11 +4,20 @@
 package bundle_test
 
 import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/fake"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle/bundlefakes"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
@@ -84,3 +93,262 @@ images:
 		}
 	})
 }
+
+func TestNewContentsBundleWithPlatforms(t *testing.T) {
+	fakeRegistry := &bundlefakes.FakeImagesMetadataWriter{}
+	assets := &helpers.Assets{T: t}
+	defer assets.CleanCreatedFolders()
+	bundleBuilder := helpers.NewBundleDir(t, assets)
+	bundleDir := bundleBuilder.CreateBundleDir(helpers.BundleYAML, helpers.ImagesYAML)
+
+	t.Run("push records the platforms in the bundle config", func(t *testing.T) {
+		subject := bundle.NewContents([]string{bundleDir}, nil, false).WithPlatforms([]string{"linux/amd64", "linux/arm64"})
+		imgTag, err := name.NewTag("my.registry.io/new-bundle:tag")
+		if err != nil {
+			t.Fatalf("failed to read tag: %s", err)
+		}
+
+		_, err = subject.Push(imgTag, fakeRegistry, util.NewNoopLevelLogger())
+		if err != nil {
+			t.Fatalf("not expecting push to fail: %s", err)
+		}
+
+		if fakeRegistry.WriteImageCallCount() != 1 {
+			t.Fatalf("expected one image to be written, got %d", fakeRegistry.WriteImageCallCount())
+		}
+		_, img, _ := fakeRegistry.WriteImageArgsForCall(0)
+		cfg, err := img.ConfigFile()
+		if err != nil {
+			t.Fatalf("failed to read config: %s", err)
+		}
+		if got := cfg.Config.Labels[bundle.BundlePlatformsLabel]; got != "linux/amd64,linux/arm64" {
+			t.Fatalf("expected platforms label to be 'linux/amd64,linux/arm64', got '%s'", got)
+		}
+	})
+}
+
+func TestNewContentsBundleWithFormatVersion(t *testing.T) {
+	assets := &helpers.Assets{T: t}
+	defer assets.CleanCreatedFolders()
+	bundleBuilder := helpers.NewBundleDir(t, assets)
+	bundleDir := bundleBuilder.CreateBundleDir(helpers.BundleYAML, helpers.ImagesYAML)
+	imgTag, err := name.NewTag("my.registry.io/new-bundle:tag")
+	if err != nil {
+		t.Fatalf("failed to read tag: %s", err)
+	}
+
+	t.Run("push records format version 1 when the bundle does not need a newer format", func(t *testing.T) {
+		fakeRegistry := &bundlefakes.FakeImagesMetadataWriter{}
+		subject := bundle.NewContents([]string{bundleDir}, nil, false)
+
+		_, err = subject.Push(imgTag, fakeRegistry, util.NewNoopLevelLogger())
+		if err != nil {
+			t.Fatalf("not expecting push to fail: %s", err)
+		}
+
+		_, img, _ := fakeRegistry.WriteImageArgsForCall(0)
+		cfg, err := img.ConfigFile()
+		if err != nil {
+			t.Fatalf("failed to read config: %s", err)
+		}
+		if got := cfg.Config.Labels[bundle.BundleFormatVersionLabel]; got != "1" {
+			t.Fatalf("expected format version label to be '1', got '%s'", got)
+		}
+	})
+
+	t.Run("push fails when the format version is not supported", func(t *testing.T) {
+		fakeRegistry := &bundlefakes.FakeImagesMetadataWriter{}
+		subject := bundle.NewContents([]string{bundleDir}, nil, false).WithFormatVersion(bundle.LatestFormatVersion + 1)
+
+		_, err = subject.Push(imgTag, fakeRegistry, util.NewNoopLevelLogger())
+		if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("Unsupported bundle format version %d", bundle.LatestFormatVersion+1)) {
+			t.Fatalf("expected push to fail with unsupported format version, got: %v", err)
+		}
+		if fakeRegistry.WriteImageCallCount() != 0 {
+			t.Fatalf("expected no image to be written, got %d", fakeRegistry.WriteImageCallCount())
+		}
+	})
+}
+
+func TestNewContentsBundleWithExternalArtifacts(t *testing.T) {
+	assets := &helpers.Assets{T: t}
+	defer assets.CleanCreatedFolders()
+	bundleBuilder := helpers.NewBundleDir(t, assets)
+	bundleDir := bundleBuilder.CreateBundleDir(helpers.BundleYAML, helpers.ImagesYAML)
+	err := os.MkdirAll(filepath.Join(bundleDir, "data"), 0700)
+	if err != nil {
+		t.Fatalf("failed to create data folder: %s", err)
+	}
+	err = os.WriteFile(filepath.Join(bundleDir, "data", "dataset.bin"), []byte("dataset"), 0600)
+	if err != nil {
+		t.Fatalf("failed to create dataset: %s", err)
+	}
+	imgTag, err := name.NewTag("my.registry.io/new-bundle:tag")
+	if err != nil {
+		t.Fatalf("failed to read tag: %s", err)
+	}
+	artifacts := []bundle.ExternalArtifactSource{{Path: "data/dataset.bin", URL: "https://some.storage/dataset.bin"}}
+
+	t.Run("push references the file in the bundle config instead of storing it", func(t *testing.T) {
+		fakeRegistry := &bundlefakes.FakeImagesMetadataWriter{}
+		var layerFiles []string
+		// The layer is only available while the image is being written
+		fakeRegistry.WriteImageStub = func(_ name.Reference, img v1.Image, _ chan v1.Update) error {
+			layers, err := img.Layers()
+			if err != nil {
+				return err
+			}
+			reader, err := layers[0].Uncompressed()
+			if err != nil {
+				return err
+			}
+			defer reader.Close()
+			tarReader := tar.NewReader(reader)
+			for {
+				header, err := tarReader.Next()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				layerFiles = append(layerFiles, header.Name)
+			}
+		}
+		subject := bundle.NewContents([]string{bundleDir}, nil, false).WithExternalArtifacts(artifacts)
+
+		_, err = subject.Push(imgTag, fakeRegistry, util.NewNoopLevelLogger())
+		if err != nil {
+			t.Fatalf("not expecting push to fail: %s", err)
+		}
+
+		_, img, _ := fakeRegistry.WriteImageArgsForCall(0)
+		cfg, err := img.ConfigFile()
+		if err != nil {
+			t.Fatalf("failed to read config: %s", err)
+		}
+		if got := cfg.Config.Labels[bundle.BundleFormatVersionLabel]; got != "2" {
+			t.Fatalf("expected format version label to be '2', got '%s'", got)
+		}
+		var recorded []bundle.ExternalArtifact
+		err = json.Unmarshal([]byte(cfg.Config.Labels[bundle.BundleExternalArtifactsLabel]), &recorded)
+		if err != nil {
+			t.Fatalf("failed to read external artifacts label: %s", err)
+		}
+		expected := []bundle.ExternalArtifact{{
+			Path:   "data/dataset.bin",
+			URL:    "https://some.storage/dataset.bin",
+			Digest: "sha256:b277fd623676a525c29b9eb155afc8c9010681814ceafb2d7627f47b9a232576",
+			Size:   7,
+		}}
+		if !reflect.DeepEqual(recorded, expected) {
+			t.Fatalf("expected external artifacts %v, got %v", expected, recorded)
+		}
+
+		for _, file := range layerFiles {
+			if file == "data/dataset.bin" {
+				t.Fatalf("expected external artifact to not be stored in the bundle, got files: %v", layerFiles)
+			}
+		}
+		if len(layerFiles) == 0 {
+			t.Fatalf("expected the other files to be stored in the bundle")
+		}
+	})
+
+	t.Run("push fails when the requested format version does not support external artifacts", func(t *testing.T) {
+		fakeRegistry := &bundlefakes.FakeImagesMetadataWriter{}
+		subject := bundle.NewContents([]string{bundleDir}, nil, false).WithExternalArtifacts(artifacts).WithFormatVersion(bundle.FormatVersion1)
+
+		_, err = subject.Push(imgTag, fakeRegistry, util.NewNoopLevelLogger())
+		if err == nil || !strings.Contains(err.Error(), "External artifacts require bundle format version 2 or newer") {
+			t.Fatalf("expected push to fail because of the format version, got: %v", err)
+		}
+	})
+
+	t.Run("push fails when the file is not in the bundle", func(t *testing.T) {
+		fakeRegistry := &bundlefakes.FakeImagesMetadataWriter{}
+		subject := bundle.NewContents([]string{bundleDir}, nil, false).
+			WithExternalArtifacts([]bundle.ExternalArtifactSource{{Path: "data/missing.bin", URL: "https://some.storage/missing.bin"}})
+
+		_, err = subject.Push(imgTag, fakeRegistry, util.NewNoopLevelLogger())
+		if err == nil || !strings.Contains(err.Error(), "Expected external artifact 'data/missing.bin' to be a file in the bundle") {
+			t.Fatalf("expected push to fail because the file is missing, got: %v", err)
+		}
+	})
+}
+
+func TestNewContentsBundleWithArtifactManifest(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	reg := fakeRegistry.Build()
+
+	assets := &helpers.Assets{T: t}
+	defer assets.CleanCreatedFolders()
+	bundleBuilder := helpers.NewBundleDir(t, assets)
+	bundleDir := bundleBuilder.CreateBundleDir(helpers.BundleYAML, `---
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images: []
+`)
+
+	imgTag, err := name.NewTag(fakeRegistry.ReferenceOnTestServer("artifact/bundle:v1"))
+	if err != nil {
+		t.Fatalf("failed to read tag: %s", err)
+	}
+	bundleRef, err := bundle.NewContents([]string{bundleDir}, nil, false).WithArtifactManifest().Push(imgTag, reg, util.NewNoopLevelLogger())
+	if err != nil {
+		t.Fatalf("not expecting push to fail: %s", err)
+	}
+
+	t.Run("push records the artifactType in an OCI image manifest", func(t *testing.T) {
+		digestRef, err := name.NewDigest(bundleRef)
+		if err != nil {
+			t.Fatalf("failed to parse digest: %s", err)
+		}
+		desc, err := reg.Get(digestRef)
+		if err != nil {
+			t.Fatalf("failed to get bundle: %s", err)
+		}
+
+		var manifest struct {
+			MediaType    string          `json:"mediaType"`
+			ArtifactType string          `json:"artifactType"`
+			Config       v1.Descriptor   `json:"config"`
+			Layers       []v1.Descriptor `json:"layers"`
+		}
+		if err := json.Unmarshal(desc.Manifest, &manifest); err != nil {
+			t.Fatalf("failed to parse manifest: %s", err)
+		}
+		if manifest.ArtifactType != bundle.BundleArtifactType {
+			t.Fatalf("expected artifactType to be '%s', got '%s'", bundle.BundleArtifactType, manifest.ArtifactType)
+		}
+		if manifest.MediaType != string(types.OCIManifestSchema1) || desc.MediaType != types.OCIManifestSchema1 {
+			t.Fatalf("expected an OCI image manifest, got '%s'", manifest.MediaType)
+		}
+		if manifest.Config.MediaType != types.OCIConfigJSON {
+			t.Fatalf("expected an OCI config, got '%s'", manifest.Config.MediaType)
+		}
+		for _, layer := range manifest.Layers {
+			if layer.MediaType != types.OCILayer {
+				t.Fatalf("expected an OCI layer, got '%s'", layer.MediaType)
+			}
+		}
+	})
+
+	t.Run("pull extracts the bundle", func(t *testing.T) {
+		imagesLockReader := bundle.NewImagesLockReader()
+		subject := bundle.NewBundleFromRef(imgTag.Name(), reg, imagesLockReader, bundle.NewRegistryFetcher(reg, imagesLockReader))
+		isBundle, err := subject.IsBundle()
+		if err != nil || !isBundle {
+			t.Fatalf("expected the artifact to be a bundle: %v, %s", isBundle, err)
+		}
+
+		outputPath := t.TempDir()
+		if _, err := subject.Pull(outputPath, util.NewNoopLevelLogger(), false); err != nil {
+			t.Fatalf("not expecting pull to fail: %s", err)
+		}
+		if _, err := os.Stat(filepath.Join(outputPath, ".imgpkg", "bundle.yml")); err != nil {
+			t.Fatalf("expected the bundle files to be extracted: %s", err)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	ctlimg "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/image"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
+	plainimg "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/plainimage"
+)
+
+// ConvertImage Creates a Bundle, pushed to uploadRef, from the plain image imageRef and the provided ImagesLock.
+// The layers of the image are reused and a new layer with the ImagesLock is added on top of them
+func ConvertImage(imageRef string, imagesLock lockconfig.ImagesLock, uploadRef regname.Tag, reg ImagesMetadataWriter, logger Logger) (string, error) {
+	plainImg := plainimg.NewPlainImage(imageRef, reg)
+	img, err := plainImg.Fetch()
+	if err != nil {
+		if plainimg.IsNotAnImageError(err) {
+			return "", fmt.Errorf("Expected '%s' to be an image, but found an image index", imageRef)
+		}
+		return "", err
+	}
+
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return "", fmt.Errorf("Reading image configuration: %s", err)
+	}
+	if _, found := cfg.Config.Labels[BundleConfigLabel]; found {
+		return "", fmt.Errorf("Image '%s' is already a bundle", imageRef)
+	}
+
+	imagesLockImg, err := imagesLockFileImage(imagesLock, logger)
+	if err != nil {
+		return "", err
+	}
+	defer imagesLockImg.Remove()
+
+	imagesLockLayers, err := imagesLockImg.Layers()
+	if err != nil {
+		return "", err
+	}
+
+	bundleImg, err := mutate.Append(img, mutate.Addendum{
+		Layer: imagesLockLayers[0],
+		History: regv1.History{
+			Author:    "imgpkg",
+			CreatedBy: "imgpkg convert",
+			Created:   regv1.Time{}, // static
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	bundleCfg, err := bundleImg.ConfigFile()
+	if err != nil {
+		return "", fmt.Errorf("Reading image configuration: %s", err)
+	}
+	bundleCfg = bundleCfg.DeepCopy()
+	if bundleCfg.Config.Labels == nil {
+		bundleCfg.Config.Labels = map[string]string{}
+	}
+	bundleCfg.Config.Labels[BundleConfigLabel] = "true"
+	bundleCfg.Config.Labels[BundleFormatVersionLabel] = strconv.Itoa(FormatVersion1)
+	bundleCfg.Config.Labels[BundleConvertedLabel] = "true"
+
+	bundleImg, err = mutate.ConfigFile(bundleImg, bundleCfg)
+	if err != nil {
+		return "", err
+	}
+
+	err = reg.WriteImage(uploadRef, bundleImg, nil)
+	if err != nil {
+		return "", fmt.Errorf("Writing '%s': %s", uploadRef.Name(), err)
+	}
+
+	digest, err := bundleImg.Digest()
+	if err != nil {
+		return "", err
+	}
+
+	uploadTagRef, err := util.BuildDefaultUploadTagRef(bundleImg, uploadRef.Repository)
+	if err != nil {
+		return "", fmt.Errorf("Building default upload tag image ref: %s", err)
+	}
+
+	err = reg.WriteTag(uploadTagRef, bundleImg)
+	if err != nil {
+		return "", fmt.Errorf("Writing Tag '%s': %s", uploadRef.Name(), err)
+	}
+
+	return fmt.Sprintf("%s@%s", uploadRef.Context(), digest), nil
+}
+
+// imagesLockFileImage Creates an image with a single layer containing only .imgpkg/images.yml
+func imagesLockFileImage(imagesLock lockconfig.ImagesLock, logger Logger) (*ctlimg.FileImage, error) {
+	tmpDir, err := os.MkdirTemp("", "imgpkg-convert")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = os.Mkdir(filepath.Join(tmpDir, ImgpkgDir), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	err = imagesLock.WriteToPath(filepath.Join(tmpDir, ImgpkgDir, ImagesLockFile))
+	if err != nil {
+		return nil, err
+	}
+
+	return ctlimg.NewTarImage([]string{tmpDir}, nil, logger, false).AsFileImage(nil)
+}
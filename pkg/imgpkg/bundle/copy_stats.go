@@ -0,0 +1,112 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+const (
+	copyStatsTagFmt string = "%s-%s.copy-stats.imgpkg"
+
+	copyStatsCopiedAtAnnotationKey      string = "dev.carvel.imgpkg.copy.stats.copied-at"
+	copyStatsDurationAnnotationKey      string = "dev.carvel.imgpkg.copy.stats.duration"
+	copyStatsSourceAnnotationKey        string = "dev.carvel.imgpkg.copy.stats.source"
+	copyStatsImgpkgVersionAnnotationKey string = "dev.carvel.imgpkg.copy.stats.imgpkg-version"
+	copyStatsImagesAnnotationKey        string = "dev.carvel.imgpkg.copy.stats.images"
+	copyStatsBytesAnnotationKey         string = "dev.carvel.imgpkg.copy.stats.bytes"
+)
+
+// CopyStats Information about the copy that placed a bundle in its current location
+type CopyStats struct {
+	CopiedAt      time.Time `json:"copiedAt"`
+	Duration      string    `json:"duration"`
+	Source        string    `json:"source"`
+	ImgpkgVersion string    `json:"imgpkgVersion"`
+	// Images Number of images copied, including the bundles
+	Images int `json:"images"`
+	// Bytes Size of the copied images, blobs shared between images are counted once
+	Bytes int64 `json:"bytes"`
+}
+
+// WriteCopyStats tags, next to the bundle, an image annotated with the statistics of the copy.
+// The bundle itself is not changed, which keeps its digest
+func WriteCopyStats(reg ImagesMetadataWriter, bundleRef regname.Digest, stats CopyStats) error {
+	statsImg := mutate.Annotations(empty.Image, map[string]string{
+		copyStatsCopiedAtAnnotationKey:      stats.CopiedAt.UTC().Format(time.RFC3339),
+		copyStatsDurationAnnotationKey:      stats.Duration,
+		copyStatsSourceAnnotationKey:        stats.Source,
+		copyStatsImgpkgVersionAnnotationKey: stats.ImgpkgVersion,
+		copyStatsImagesAnnotationKey:        strconv.Itoa(stats.Images),
+		copyStatsBytesAnnotationKey:         strconv.FormatInt(stats.Bytes, 10),
+	}).(regv1.Image)
+
+	statsRef, err := copyStatsRef(bundleRef)
+	if err != nil {
+		return err
+	}
+	err = reg.WriteImage(statsRef, statsImg, nil)
+	if err != nil {
+		return fmt.Errorf("Writing copy statistics to %s: %s", statsRef.Name(), err)
+	}
+	return nil
+}
+
+// FetchCopyStats returns the statistics of the copy of the bundle, or nil when they were not recorded
+func FetchCopyStats(reg ImagesMetadata, bundleRef regname.Digest) (*CopyStats, error) {
+	statsRef, err := copyStatsRef(bundleRef)
+	if err != nil {
+		return nil, err
+	}
+
+	statsImg, err := reg.Image(statsRef)
+	if err != nil {
+		if terr, ok := err.(*transport.Error); ok {
+			if _, ok := imageNotFoundStatusCode[terr.StatusCode]; ok {
+				return nil, nil
+			}
+		}
+		return nil, fmt.Errorf("Fetching copy statistics from %s: %s", statsRef.Name(), err)
+	}
+	manifest, err := statsImg.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("Reading copy statistics from %s: %s", statsRef.Name(), err)
+	}
+
+	annotations := manifest.Annotations
+	stats := &CopyStats{
+		Duration:      annotations[copyStatsDurationAnnotationKey],
+		Source:        annotations[copyStatsSourceAnnotationKey],
+		ImgpkgVersion: annotations[copyStatsImgpkgVersionAnnotationKey],
+	}
+	stats.CopiedAt, err = time.Parse(time.RFC3339, annotations[copyStatsCopiedAtAnnotationKey])
+	if err != nil {
+		return nil, fmt.Errorf("Reading copy statistics from %s: invalid annotation %s: %s", statsRef.Name(), copyStatsCopiedAtAnnotationKey, err)
+	}
+	stats.Images, err = strconv.Atoi(annotations[copyStatsImagesAnnotationKey])
+	if err != nil {
+		return nil, fmt.Errorf("Reading copy statistics from %s: invalid annotation %s: %s", statsRef.Name(), copyStatsImagesAnnotationKey, err)
+	}
+	stats.Bytes, err = strconv.ParseInt(annotations[copyStatsBytesAnnotationKey], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Reading copy statistics from %s: invalid annotation %s: %s", statsRef.Name(), copyStatsBytesAnnotationKey, err)
+	}
+	return stats, nil
+}
+
+func copyStatsRef(bundleRef regname.Digest) (regname.Tag, error) {
+	hash, err := regv1.NewHash(bundleRef.DigestStr())
+	if err != nil {
+		return regname.Tag{}, fmt.Errorf("Calculating copy statistics image tag: %s", err)
+	}
+	return bundleRef.Context().Tag(fmt.Sprintf(copyStatsTagFmt, hash.Algorithm, hash.Hex)), nil
+}
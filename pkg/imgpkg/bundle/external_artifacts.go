@@ -0,0 +1,271 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/workspace"
+)
+
+// BundleExternalArtifactsLabel Label that holds the files of the bundle that are not stored in the registry
+const BundleExternalArtifactsLabel = "dev.carvel.imgpkg.bundle.external-artifacts"
+
+// externalArtifactStallTimeout time without receiving any data after which the download of an external artifact fails
+var externalArtifactStallTimeout = time.Minute
+
+// ExternalArtifactSource File of the bundle that is uploaded to an external store instead of the registry
+type ExternalArtifactSource struct {
+	// Path of the file relative to the root of the bundle
+	Path string
+	// URL where the file is downloaded from when pulling the bundle
+	URL string
+}
+
+// ExternalArtifact File of the bundle that is referenced by the bundle instead of being stored in it
+type ExternalArtifact struct {
+	Path   string `json:"path"`
+	URL    string `json:"url"`
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+// NewExternalArtifactSource parses an external artifact in the format path=url
+func NewExternalArtifactSource(value string) (ExternalArtifactSource, error) {
+	pieces := strings.SplitN(value, "=", 2)
+	if len(pieces) != 2 || pieces[0] == "" || pieces[1] == "" {
+		return ExternalArtifactSource{}, fmt.Errorf("Invalid external artifact '%s' (expected format: path=url)", value)
+	}
+
+	source := ExternalArtifactSource{Path: filepath.ToSlash(filepath.Clean(pieces[0])), URL: pieces[1]}
+	if filepath.IsAbs(pieces[0]) || source.Path == ".." || strings.HasPrefix(source.Path, "../") {
+		return ExternalArtifactSource{}, fmt.Errorf("Invalid external artifact '%s' (expected path to be relative to the root of the bundle)", value)
+	}
+	if source.Path == ImgpkgDir || strings.HasPrefix(source.Path, ImgpkgDir+"/") {
+		return ExternalArtifactSource{}, fmt.Errorf("Invalid external artifact '%s' (files in the %s directory are always stored in the bundle)", value, ImgpkgDir)
+	}
+	parsedURL, err := url.Parse(source.URL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return ExternalArtifactSource{}, fmt.Errorf("Invalid external artifact '%s' (expected an http or https url)", value)
+	}
+	return source, nil
+}
+
+// externalArtifacts Locates the files of the external artifacts in the paths pushed as the bundle and calculates their digests
+func externalArtifacts(paths []string, sources []ExternalArtifactSource) ([]ExternalArtifact, error) {
+	var artifacts []ExternalArtifact
+	seen := map[string]struct{}{}
+	for _, source := range sources {
+		if _, found := seen[source.Path]; found {
+			return nil, fmt.Errorf("Expected external artifact '%s' to be provided only once", source.Path)
+		}
+		seen[source.Path] = struct{}{}
+
+		filePath, err := findBundleFile(paths, source.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		digest, size, err := fileDigest(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("Calculating digest of external artifact '%s': %s", source.Path, err)
+		}
+		artifacts = append(artifacts, ExternalArtifact{Path: source.Path, URL: source.URL, Digest: digest.String(), Size: size})
+	}
+	return artifacts, nil
+}
+
+// findBundleFile returns the location on disk of the file that is in relPath when the paths are pushed
+func findBundleFile(paths []string, relPath string) (string, error) {
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+
+		candidate := path
+		if info.IsDir() {
+			candidate = filepath.Join(path, filepath.FromSlash(relPath))
+		} else if filepath.Base(path) != relPath {
+			continue
+		}
+
+		candidateInfo, err := os.Stat(candidate)
+		if err == nil && candidateInfo.Mode().IsRegular() {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("Expected external artifact '%s' to be a file in the bundle", relPath)
+}
+
+func fileDigest(path string) (regv1.Hash, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return regv1.Hash{}, 0, err
+	}
+	defer file.Close()
+
+	return regv1.SHA256(file)
+}
+
+// ExternalArtifacts returns the files of the bundle that are not stored in the registry
+func (o *Bundle) ExternalArtifacts() ([]ExternalArtifact, error) {
+	img, err := o.checkedImage()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	return externalArtifactsFromLabels(cfg.Config.Labels)
+}
+
+func externalArtifactsFromLabels(labels map[string]string) ([]ExternalArtifact, error) {
+	value, found := labels[BundleExternalArtifactsLabel]
+	if !found || value == "" {
+		return nil, nil
+	}
+
+	var artifacts []ExternalArtifact
+	err := json.Unmarshal([]byte(value), &artifacts)
+	if err != nil {
+		return nil, fmt.Errorf("Reading external artifacts from label %s: %s", BundleExternalArtifactsLabel, err)
+	}
+	return artifacts, nil
+}
+
+// fetchExternalArtifacts Downloads the external artifacts of the bundle into outputPath, checking their digests
+func (o *Bundle) fetchExternalArtifacts(img regv1.Image, outputPath string, logger Logger) error {
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return err
+	}
+	artifacts, err := externalArtifactsFromLabels(cfg.Config.Labels)
+	if err != nil {
+		return err
+	}
+
+	for _, artifact := range artifacts {
+		logger.Logf("Fetching external artifact '%s' from '%s'\n", artifact.Path, artifact.URL)
+		err := fetchExternalArtifact(artifact, outputPath, o.workspace, o.externalArtifactsClient())
+		if err != nil {
+			return fmt.Errorf("Fetching external artifact '%s' from '%s': %s", artifact.Path, artifact.URL, err)
+		}
+	}
+	return nil
+}
+
+// externalArtifactsClient Returns the client the external artifacts are downloaded with
+func (o *Bundle) externalArtifactsClient() *http.Client {
+	if o.httpClient != nil {
+		return o.httpClient
+	}
+	return http.DefaultClient
+}
+
+func fetchExternalArtifact(artifact ExternalArtifact, outputPath string, ws *workspace.Workspace, client *http.Client) error {
+	destination := filepath.Join(outputPath, filepath.FromSlash(artifact.Path))
+	relPath, err := filepath.Rel(outputPath, destination)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("Expected path to be inside the bundle")
+	}
+
+	expectedDigest, err := regv1.NewHash(artifact.Digest)
+	if err != nil {
+		return fmt.Errorf("Parsing digest '%s': %s", artifact.Digest, err)
+	}
+
+	// Cancel the download when the server stops sending data, the client has no timeout for the whole download
+	// because artifacts can be large
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	stallErr := fmt.Errorf("No data received for %s", externalArtifactStallTimeout)
+	stallTimer := time.AfterFunc(externalArtifactStallTimeout, func() { cancel(stallErr) })
+	defer stallTimer.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, artifact.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if context.Cause(ctx) == stallErr {
+			return stallErr
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected status code %d", resp.StatusCode)
+	}
+
+	err = os.MkdirAll(filepath.Dir(destination), 0777)
+	if err != nil {
+		return err
+	}
+	// Download next to the destination so that an incomplete or tampered file never ends up in the bundle
+	tmpFile, err := os.CreateTemp(filepath.Dir(destination), ".imgpkg-external-artifact")
+	if err != nil {
+		return err
+	}
+	// The file is outside of the workspace, track it so that it is removed if the pull is interrupted
+	ws.Track(tmpFile.Name())
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+		ws.Untrack(tmpFile.Name())
+	}()
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmpFile, hasher), &stallDetectingReader{Reader: resp.Body, timer: stallTimer})
+	closeErr := tmpFile.Close()
+	if err != nil {
+		if context.Cause(ctx) == stallErr {
+			return stallErr
+		}
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	digest := "sha256:" + hex.EncodeToString(hasher.Sum(nil))
+	if digest != expectedDigest.String() || size != artifact.Size {
+		return fmt.Errorf("Expected digest %s and size %d but got digest %s and size %d", expectedDigest, artifact.Size, digest, size)
+	}
+
+	err = os.Chmod(tmpFile.Name(), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), destination)
+}
+
+// stallDetectingReader Postpones the timer every time data is read
+type stallDetectingReader struct {
+	io.Reader
+	timer *time.Timer
+}
+
+// Read Reads from the reader, postponing the timer when data is received
+func (s *stallDetectingReader) Read(p []byte) (int, error) {
+	n, err := s.Reader.Read(p)
+	if n > 0 {
+		s.timer.Reset(externalArtifactStallTimeout)
+	}
+	return n, err
+}
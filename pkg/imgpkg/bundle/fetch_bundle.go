@@ -65,7 +65,8 @@ func (t *FetcherFromProcessedImages) Bundle(_ *util.Throttle, imgRef ImageRef) (
 		}
 	}
 	if img.DigestRef == "" {
-		panic(fmt.Sprintf("Internal inconsistency: was not able to find '%s' in the list of procced images", imgRef.Image))
+		// Images excluded from the copy, e.g. with --exclude-image, are not processed. Bundles are never excluded
+		return imgRef.ImageRef, nil, nil
 	}
 	if img.ImageIndex != nil {
 		return imgRef.ImageRef, nil, nil
@@ -54,7 +54,7 @@ func (o *Bundle) FetchAllImagesRefs(concurrency int, ui Logger, sigFetcher Signa
 			panic(fmt.Sprintf("Internal inconsistency: '%s' have to be a digest", bundle.plainImg.DigestRef()))
 		}
 
-		locationsImageRef, err := NewLocations(ui).LocationsImageDigest(o.imgRetriever, bundleRef)
+		locationsImageRef, err := NewLocations(ui).WithRepo(o.locationsRepo).LocationsImageDigest(o.imgRetriever, bundleRef)
 		if err != nil {
 			if _, ok := err.(*LocationsNotFound); ok {
 				continue
@@ -0,0 +1,59 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// BundleFormatVersionLabel Label that holds the version of the layout of the bundle
+	BundleFormatVersionLabel = "dev.carvel.imgpkg.bundle.format-version"
+
+	// FormatVersion1 Bundle configuration in the .imgpkg directory with the images it references in images.yml.
+	// Bundles without BundleFormatVersionLabel use this format
+	FormatVersion1 = 1
+	// FormatVersion2 FormatVersion1 where some files are stored outside of the registry and only referenced by the bundle,
+	// see BundleExternalArtifactsLabel
+	FormatVersion2 = 2
+	// LatestFormatVersion Newest format version this version of imgpkg can read and write
+	LatestFormatVersion = FormatVersion2
+)
+
+// UnsupportedFormatVersionError Error returned when a bundle uses a format version newer than LatestFormatVersion
+type UnsupportedFormatVersionError struct {
+	Bundle  string
+	Version string
+}
+
+func (e UnsupportedFormatVersionError) Error() string {
+	return fmt.Sprintf("Bundle '%s' uses format version %s but this version of imgpkg only supports up to format version %d (hint: upgrade imgpkg)",
+		e.Bundle, e.Version, LatestFormatVersion)
+}
+
+// ValidateFormatVersion checks that bundles with the format version can be written by this version of imgpkg
+func ValidateFormatVersion(version int) error {
+	if version < FormatVersion1 || version > LatestFormatVersion {
+		return fmt.Errorf("Unsupported bundle format version %d (supported versions: %d to %d)", version, FormatVersion1, LatestFormatVersion)
+	}
+	return nil
+}
+
+// formatVersion returns the format version recorded in the labels of the bundle
+func formatVersion(bundleRef string, labels map[string]string) (int, error) {
+	value, found := labels[BundleFormatVersionLabel]
+	if !found {
+		return FormatVersion1, nil
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil || version < FormatVersion1 {
+		return 0, fmt.Errorf("Bundle '%s' has an invalid format version '%s' in label %s", bundleRef, value, BundleFormatVersionLabel)
+	}
+	if version > LatestFormatVersion {
+		return 0, UnsupportedFormatVersionError{Bundle: bundleRef, Version: value}
+	}
+	return version, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle_test
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/plainimage"
+)
+
+func TestBundleIsBundleFormatVersion(t *testing.T) {
+	bundleWithLabels := func(t *testing.T, labels map[string]string) *bundle.Bundle {
+		img, err := random.Image(100, 1)
+		require.NoError(t, err)
+		cfg, err := img.ConfigFile()
+		require.NoError(t, err)
+		cfg = cfg.DeepCopy()
+		cfg.Config.Labels = labels
+		img, err = mutate.ConfigFile(img, cfg)
+		require.NoError(t, err)
+		digest, err := img.Digest()
+		require.NoError(t, err)
+
+		plainImg := plainimage.NewFetchedPlainImageWithTag("my.registry.io/bundle@"+digest.String(), "", img)
+		return bundle.NewBundleFromPlainImage(plainImg, nil)
+	}
+
+	t.Run("when the bundle has no format version, it is a bundle", func(t *testing.T) {
+		isBundle, err := bundleWithLabels(t, map[string]string{bundle.BundleConfigLabel: "true"}).IsBundle()
+		require.NoError(t, err)
+		assert.True(t, isBundle)
+	})
+
+	t.Run("when the bundle has a supported format version, it is a bundle", func(t *testing.T) {
+		isBundle, err := bundleWithLabels(t, map[string]string{bundle.BundleConfigLabel: "true", bundle.BundleFormatVersionLabel: "1"}).IsBundle()
+		require.NoError(t, err)
+		assert.True(t, isBundle)
+	})
+
+	t.Run("when the bundle has a newer format version, it fails asking to upgrade imgpkg", func(t *testing.T) {
+		newerVersion := strconv.Itoa(bundle.LatestFormatVersion + 1)
+		_, err := bundleWithLabels(t, map[string]string{bundle.BundleConfigLabel: "true", bundle.BundleFormatVersionLabel: newerVersion}).IsBundle()
+		require.Error(t, err)
+		assert.True(t, errors.As(err, &bundle.UnsupportedFormatVersionError{}))
+		assert.Contains(t, err.Error(), fmt.Sprintf("uses format version %s but this version of imgpkg only supports up to format version %d (hint: upgrade imgpkg)", newerVersion, bundle.LatestFormatVersion))
+	})
+
+	t.Run("when the bundle has an invalid format version, it fails", func(t *testing.T) {
+		_, err := bundleWithLabels(t, map[string]string{bundle.BundleConfigLabel: "true", bundle.BundleFormatVersionLabel: "latest"}).IsBundle()
+		require.ErrorContains(t, err, "has an invalid format version 'latest'")
+	})
+
+	t.Run("when the image is not a bundle, the format version is ignored", func(t *testing.T) {
+		isBundle, err := bundleWithLabels(t, map[string]string{bundle.BundleFormatVersionLabel: "99"}).IsBundle()
+		require.NoError(t, err)
+		assert.False(t, isBundle)
+	})
+}
@@ -5,9 +5,9 @@ package bundle
 
 import (
 	"fmt"
-	"io/ioutil"
 	"sort"
 
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/filelock"
 	"sigs.k8s.io/yaml"
 )
 
@@ -26,10 +26,16 @@ type ImageLocationsConfig struct {
 type ImageLocation struct {
 	Image    string `json:"image"`    // This generated yaml, but due to lib we need to use `json`
 	IsBundle bool   `json:"isBundle"` // This generated yaml, but due to lib we need to use `json`
+	// Digest of the image in the repository when it is different from the one in Image,
+	// e.g. an index that only kept the images for some platforms when copied with --platform
+	Digest string `json:"digest,omitempty"`
+	// Excluded when true the image was not copied, e.g. filtered out with --exclude-image, and is only
+	// available in its original location
+	Excluded bool `json:"excluded,omitempty"`
 }
 
 func NewLocationConfigFromPath(path string) (ImageLocationsConfig, error) {
-	bs, err := ioutil.ReadFile(path)
+	bs, err := filelock.ReadFile(path)
 	if err != nil {
 		return ImageLocationsConfig{}, fmt.Errorf("Reading path %s: %s", path, err)
 	}
@@ -79,7 +85,7 @@ func (c ImageLocationsConfig) WriteToPath(path string) error {
 	if err != nil {
 		return err
 	}
-	err = ioutil.WriteFile(path, bs, 0600)
+	err = filelock.WriteFile(path, bs, 0600)
 	if err != nil {
 		return fmt.Errorf("Writing image locations config: %s", err)
 	}
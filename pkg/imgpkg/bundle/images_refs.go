@@ -128,10 +128,30 @@ func (i *ImageRefs) LocalizeToRepo(relativeToRepo string) {
 	defer i.refsLock.Unlock()
 
 	for j, imgRef := range i.refs {
-		i.refs[j].AddLocation(replaceImageRepo(imgRef.Image, relativeToRepo))
+		i.refs[j].AddLocation(i.relocatedImage(imgRef.Image, relativeToRepo))
 	}
 }
 
+// relocatedImage returns the location of the image in the repository, using the digest recorded in the
+// ImageLocationsConfig when the copied image has a different digest. Images excluded from the copy keep
+// their original location
+func (i *ImageRefs) relocatedImage(image string, relativeToRepo string) string {
+	if i.imageLocationsConfig != nil {
+		for _, imgLoc := range i.imageLocationsConfig.Images {
+			if imgLoc.Image != image {
+				continue
+			}
+			if imgLoc.Excluded {
+				return image
+			}
+			if imgLoc.Digest != "" {
+				return relativeToRepo + "@" + imgLoc.Digest
+			}
+		}
+	}
+	return replaceImageRepo(image, relativeToRepo)
+}
+
 func (i *ImageRefs) UpdateRelativeToRepo(imgRetriever ImagesMetadata, relativeToRepo string) (bool, error) {
 	if i.imageLocationsConfig != nil {
 		i.LocalizeToRepo(relativeToRepo)
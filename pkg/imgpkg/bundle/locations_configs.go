@@ -19,6 +19,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/plainimage"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/workspace"
 )
 
 const (
@@ -42,6 +43,11 @@ func (n LocationsNotFound) Error() string {
 type LocationsConfigs struct {
 	reader LocationImageReader
 	ui     util.LoggerWithLevels
+	// repo when present is the repository where the locations image is looked up
+	// instead of the repository of the bundle
+	repo *name.Repository
+	// workspace when present holds the temporary files created to save the locations image
+	workspace *workspace.Workspace
 }
 
 type LocationImageReader interface {
@@ -58,6 +64,18 @@ func NewLocationsWithReader(reader LocationImageReader, ui util.LoggerWithLevels
 	return &LocationsConfigs{reader: reader, ui: ui}
 }
 
+// WithRepo looks up the locations image in the provided repository instead of deriving it from the bundle reference
+func (r *LocationsConfigs) WithRepo(repo *name.Repository) *LocationsConfigs {
+	r.repo = repo
+	return r
+}
+
+// WithWorkspace Creates the temporary files used to save the locations image in the workspace
+func (r *LocationsConfigs) WithWorkspace(ws *workspace.Workspace) *LocationsConfigs {
+	r.workspace = ws
+	return r
+}
+
 // Fetch Retrieve the ImageLocationsConfig for a particular Bundle
 func (r *LocationsConfigs) Fetch(registry ImagesMetadata, bundleRef name.Digest) (ImageLocationsConfig, error) {
 	r.ui.Tracef("Fetching Locations OCI Images for bundle: %s\n", bundleRef)
@@ -120,7 +138,7 @@ func (r LocationsConfigs) Save(reg ImagesMetadataWriter, bundleRef name.Digest,
 		return fmt.Errorf("Calculating locations image tag: %s", err)
 	}
 
-	tmpDir, err := os.MkdirTemp("", "imgpkg-bundle-locations")
+	tmpDir, err := r.workspace.MkdirTemp("imgpkg-bundle-locations")
 	if err != nil {
 		return err
 	}
@@ -133,7 +151,7 @@ func (r LocationsConfigs) Save(reg ImagesMetadataWriter, bundleRef name.Digest,
 
 	r.ui.Tracef("Pushing image\n")
 
-	_, err = plainimage.NewContents([]string{tmpDir}, nil, false).Push(locRef, nil, reg.CloneWithLogger(util.NewNoopProgressBar()), logger)
+	_, err = plainimage.NewContents([]string{tmpDir}, nil, false).WithWorkspace(r.workspace).Push(locRef, nil, reg.CloneWithLogger(util.NewNoopProgressBar()), logger)
 	if err != nil {
 		// Immutable tag errors within registries are not standardized.
 		// Assume word "immutable" would be present in most cases.
@@ -166,12 +184,14 @@ func (r LocationsConfigs) locationsRefFromBundleRef(bundleRef name.Digest) (name
 		return name.Tag{}, err
 	}
 
-	tag, err := name.NewTag(bundleRef.Context().Name())
-	if err != nil {
-		return name.Tag{}, err
+	// Keep the parsed repository, instead of parsing its name again, so that nested repository paths
+	// and the options the reference was parsed with (e.g. insecure registries) are preserved
+	repo := bundleRef.Context()
+	if r.repo != nil {
+		repo = *r.repo
 	}
 
-	return tag.Tag(fmt.Sprintf(locationsTagFmt, hash.Algorithm, hash.Hex)), nil
+	return repo.Tag(fmt.Sprintf(locationsTagFmt, hash.Algorithm, hash.Hex)), nil
 }
 
 type locationsSingleLayerReader struct{}
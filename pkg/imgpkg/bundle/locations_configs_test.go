@@ -69,4 +69,62 @@ func TestLocations(t *testing.T) {
 		require.Error(t, err)
 		require.IsType(t, &bundle.LocationsNotFound{}, err)
 	})
+
+	t.Run("when the bundle repository has nested paths it saves and fetches the configuration next to the bundle", func(t *testing.T) {
+		fakeRegistryBuilder := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		fakeRegistry := fakeRegistryBuilder.Build()
+
+		logger := util.NewNoopLevelLogger()
+
+		subject := bundle.NewLocations(logger)
+
+		bundleRef := fakeRegistryBuilder.ReferenceOnTestServer("team/project/sub/path/testing@sha256:cf31af331f38d1d7158470e095b132acd126a7180a54f263d386da88eb681d93")
+		bundleDigestRef, err := regname.NewDigest(bundleRef)
+		require.NoError(t, err)
+
+		expectedConfig := bundle.ImageLocationsConfig{
+			APIVersion: "imgpkg.carvel.dev/v1alpha1",
+			Kind:       "ImageLocations",
+			Images:     []bundle.ImageLocation{{Image: "gcr.io/img1@sha256:acf7795dc91df17e10effee064bd229580a9c34213b4dba578d64768af5d8c51"}},
+		}
+		err = subject.Save(fakeRegistry, bundleDigestRef, expectedConfig, logger)
+		require.NoError(t, err)
+
+		locationsDigestRef, err := subject.LocationsImageDigest(fakeRegistry, bundleDigestRef)
+		require.NoError(t, err)
+		require.Equal(t, bundleDigestRef.Context().Name(), locationsDigestRef.Context().Name())
+
+		cfg, err := subject.Fetch(fakeRegistry, bundleDigestRef)
+		require.NoError(t, err)
+		require.Equal(t, expectedConfig, cfg)
+	})
+
+	t.Run("when a locations repository is provided it fetches the configuration from that repository", func(t *testing.T) {
+		fakeRegistryBuilder := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		fakeRegistry := fakeRegistryBuilder.Build()
+
+		logger := util.NewNoopLevelLogger()
+
+		copiedBundleRef, err := regname.NewDigest(fakeRegistryBuilder.ReferenceOnTestServer("copied/bundle@sha256:cf31af331f38d1d7158470e095b132acd126a7180a54f263d386da88eb681d93"))
+		require.NoError(t, err)
+		// Same bundle as seen through a registry proxy that prefixes the repository path
+		proxiedBundleRef, err := regname.NewDigest(fakeRegistryBuilder.ReferenceOnTestServer("proxy/prefix/copied/bundle@sha256:cf31af331f38d1d7158470e095b132acd126a7180a54f263d386da88eb681d93"))
+		require.NoError(t, err)
+
+		expectedConfig := bundle.ImageLocationsConfig{
+			APIVersion: "imgpkg.carvel.dev/v1alpha1",
+			Kind:       "ImageLocations",
+			Images:     []bundle.ImageLocation{{Image: "gcr.io/img1@sha256:acf7795dc91df17e10effee064bd229580a9c34213b4dba578d64768af5d8c51"}},
+		}
+		err = bundle.NewLocations(logger).Save(fakeRegistry, copiedBundleRef, expectedConfig, logger)
+		require.NoError(t, err)
+
+		_, err = bundle.NewLocations(logger).Fetch(fakeRegistry, proxiedBundleRef)
+		require.IsType(t, &bundle.LocationsNotFound{}, err)
+
+		locationsRepo := copiedBundleRef.Context()
+		cfg, err := bundle.NewLocations(logger).WithRepo(&locationsRepo).Fetch(fakeRegistry, proxiedBundleRef)
+		require.NoError(t, err)
+		require.Equal(t, expectedConfig, cfg)
+	})
 }
@@ -0,0 +1,81 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"fmt"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
+)
+
+// NestedImagesLockMode How the ImagesLock of the nested bundles is written on a recursive pull
+type NestedImagesLockMode string
+
+const (
+	// NestedImagesLockCollocated The images are rewritten to the repository of the bundle when they were copied with it
+	NestedImagesLockCollocated NestedImagesLockMode = "collocated"
+	// NestedImagesLockOrigin The ImagesLock is left as it was pushed, referencing the images where they originated
+	NestedImagesLockOrigin NestedImagesLockMode = "origin"
+	// NestedImagesLockMapping The images are rewritten to the locations provided in a mapping of their original locations
+	NestedImagesLockMapping NestedImagesLockMode = "mapping"
+)
+
+// NestedImagesLockModes All the supported ways of writing the ImagesLock of the nested bundles
+var NestedImagesLockModes = []NestedImagesLockMode{NestedImagesLockCollocated, NestedImagesLockOrigin, NestedImagesLockMapping}
+
+// WithNestedImagesLock writes the ImagesLock of the nested bundles, on a recursive pull, as defined by mode. The
+// mapping, from the original location of each image to its new location, is only used by NestedImagesLockMapping
+func (o *Bundle) WithNestedImagesLock(mode NestedImagesLockMode, mapping map[string]string) *Bundle {
+	o.nestedImagesLock = mode
+	o.nestedImagesLockMapping = map[string]string{}
+	for src, dst := range mapping {
+		o.nestedImagesLockMapping[normalizedImageRef(src)] = dst
+	}
+	return o
+}
+
+// imagesLockToWrite Returns the ImagesLock written to the bundle in bundlePath and false when the pulled ImagesLock is
+// kept. The root bundle is always rewritten to its repository when it was relocated
+func (o *Bundle) imagesLockToWrite(bundlePath string, pulled lockconfig.ImagesLock, imageRefs ImageRefs, isRelocatedToBundle bool) (lockconfig.ImagesLock, bool, error) {
+	mode := o.nestedImagesLock
+	if o.rootBundle(bundlePath) || mode == "" {
+		mode = NestedImagesLockCollocated
+	}
+
+	switch mode {
+	case NestedImagesLockCollocated:
+		if !isRelocatedToBundle {
+			return lockconfig.ImagesLock{}, false, nil
+		}
+		return imageRefs.ImagesLock(), true, nil
+
+	case NestedImagesLockOrigin:
+		return lockconfig.ImagesLock{}, false, nil
+
+	case NestedImagesLockMapping:
+		imagesLock := lockconfig.NewEmptyImagesLock()
+		for _, img := range pulled.Images {
+			dst, found := o.nestedImagesLockMapping[normalizedImageRef(img.Image)]
+			if !found {
+				return lockconfig.ImagesLock{}, false, fmt.Errorf("Expected image '%s' of nested bundle '%s' to be in the mapping of the images", img.Image, o.DigestRef())
+			}
+			imagesLock.Images = append(imagesLock.Images, lockconfig.ImageRef{Image: dst, Annotations: img.Annotations})
+		}
+		return imagesLock, true, nil
+
+	default:
+		return lockconfig.ImagesLock{}, false, fmt.Errorf("Unknown way of writing the ImagesLock of nested bundles '%s'", mode)
+	}
+}
+
+// normalizedImageRef Returns the fully qualified reference of the image so that different spellings of the same
+// reference, e.g. with or without the default registry, match
+func normalizedImageRef(ref string) string {
+	digest, err := regname.NewDigest(ref)
+	if err != nil {
+		return ref
+	}
+	return digest.Name()
+}
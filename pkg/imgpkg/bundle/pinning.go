@@ -0,0 +1,167 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"gopkg.in/yaml.v3"
+)
+
+// UnpinnedImageRef Image referenced by tag, instead of digest, in a file of the bundle
+type UnpinnedImageRef struct {
+	File  string `json:"file"`
+	Line  int    `json:"line"`
+	Image string `json:"image"`
+}
+
+// PinningReport Result of checking that the images referenced in the files of a bundle are pinned to a digest
+type PinningReport struct {
+	UnpinnedImageRefs []UnpinnedImageRef `json:"unpinnedImageRefs"`
+	// SkippedFiles YAML files that could not be parsed, e.g. templates, so their images were not checked
+	SkippedFiles []string `json:"skippedFiles"`
+}
+
+// CheckPinning Finds the images referenced by tag in the ImagesLock and in the YAML files of the bundle.
+// Every value of an "image" key that is an image reference is checked, which covers the ImagesLock as well as
+// Kubernetes resources
+func (b Contents) CheckPinning() (PinningReport, error) {
+	report := PinningReport{UnpinnedImageRefs: []UnpinnedImageRef{}, SkippedFiles: []string{}}
+
+	isExcluded := func(relPath string) bool {
+		for _, excludedPath := range b.excludedPaths {
+			if excludedPath == relPath {
+				return true
+			}
+		}
+		return false
+	}
+
+	checkFile := func(filePath string) error {
+		ext := strings.ToLower(filepath.Ext(filePath))
+		if ext != ".yml" && ext != ".yaml" {
+			return nil
+		}
+		refs, err := findUnpinnedImageRefs(filePath)
+		if err != nil {
+			var parseErr yamlParseError
+			if errors.As(err, &parseErr) {
+				report.SkippedFiles = append(report.SkippedFiles, filePath)
+				return nil
+			}
+			return err
+		}
+		report.UnpinnedImageRefs = append(report.UnpinnedImageRefs, refs...)
+		return nil
+	}
+
+	for _, path := range b.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return PinningReport{}, err
+		}
+		if !info.IsDir() {
+			if err := checkFile(path); err != nil {
+				return PinningReport{}, err
+			}
+			continue
+		}
+
+		err = filepath.Walk(path, func(walkedPath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			relPath, err := filepath.Rel(path, walkedPath)
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if isExcluded(relPath) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.Mode().IsRegular() || isExcluded(relPath) {
+				return nil
+			}
+			return checkFile(walkedPath)
+		})
+		if err != nil {
+			return PinningReport{}, err
+		}
+	}
+
+	sort.SliceStable(report.UnpinnedImageRefs, func(i, j int) bool {
+		if report.UnpinnedImageRefs[i].File != report.UnpinnedImageRefs[j].File {
+			return report.UnpinnedImageRefs[i].File < report.UnpinnedImageRefs[j].File
+		}
+		return report.UnpinnedImageRefs[i].Line < report.UnpinnedImageRefs[j].Line
+	})
+	return report, nil
+}
+
+type yamlParseError struct {
+	err error
+}
+
+func (y yamlParseError) Error() string { return y.err.Error() }
+
+// findUnpinnedImageRefs returns the values of the "image" keys, in every document of the file, that reference a tag
+func findUnpinnedImageRefs(filePath string) ([]UnpinnedImageRef, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var refs []UnpinnedImageRef
+	var visit func(node *yaml.Node)
+	visit = func(node *yaml.Node) {
+		if node.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				if key.Value == "image" && value.Kind == yaml.ScalarNode && isUnpinnedImageRef(value.Value) {
+					refs = append(refs, UnpinnedImageRef{File: filePath, Line: value.Line, Image: value.Value})
+				}
+			}
+		}
+		for _, child := range node.Content {
+			visit(child)
+		}
+	}
+
+	decoder := yaml.NewDecoder(file)
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, yamlParseError{fmt.Errorf("Parsing '%s': %s", filePath, err)}
+		}
+		visit(&doc)
+	}
+	return refs, nil
+}
+
+// isUnpinnedImageRef returns true when the value is an image reference that does not include a digest.
+// Values that are not image references, e.g. templated values, are ignored
+func isUnpinnedImageRef(value string) bool {
+	if value == "" || strings.ContainsAny(value, " {}$()") {
+		return false
+	}
+	if _, err := regname.NewDigest(value, regname.WeakValidation); err == nil {
+		return false
+	}
+	_, err := regname.NewTag(value, regname.WeakValidation)
+	return err == nil
+}
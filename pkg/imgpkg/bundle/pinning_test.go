@@ -0,0 +1,64 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+)
+
+func TestContentsCheckPinning(t *testing.T) {
+	bundleDir := t.TempDir()
+	writeFile := func(relPath, content string) {
+		path := filepath.Join(bundleDir, relPath)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+
+	writeFile(".imgpkg/images.yml", `---
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images:
+- image: index.docker.io/library/nginx@sha256:4c0fdaa8b6341bfdeca5f18f7837462c80cff90527ee35ef185571e1c327beac
+- image: index.docker.io/library/redis:7
+`)
+	writeFile("config/deployment.yml", `---
+apiVersion: v1
+kind: ConfigMap
+data:
+  image: not an image reference
+---
+apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: registry.io/app:1.0
+      - name: sidecar
+        image: registry.io/sidecar:1.0@sha256:4c0fdaa8b6341bfdeca5f18f7837462c80cff90527ee35ef185571e1c327beac
+      - name: templated
+        image: #@ data.values.image
+`)
+	writeFile("config/chart/deployment.yaml", `image: {{ .Values.image }}
+  broken: [
+`)
+	writeFile("excluded/deployment.yml", "image: registry.io/excluded:1.0\n")
+	writeFile("README.md", "image: registry.io/readme:1.0\n")
+
+	report, err := bundle.NewContents([]string{bundleDir}, []string{"excluded"}, false).CheckPinning()
+	require.NoError(t, err)
+
+	assert.Equal(t, []bundle.UnpinnedImageRef{
+		{File: filepath.Join(bundleDir, ".imgpkg/images.yml"), Line: 6, Image: "index.docker.io/library/redis:7"},
+		{File: filepath.Join(bundleDir, "config/deployment.yml"), Line: 14, Image: "registry.io/app:1.0"},
+	}, report.UnpinnedImageRefs)
+	assert.Equal(t, []string{filepath.Join(bundleDir, "config/chart/deployment.yaml")}, report.SkippedFiles)
+}
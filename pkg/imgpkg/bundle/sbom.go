@@ -0,0 +1,326 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
+)
+
+const (
+	// SBOMFormatSPDX SBOM in the SPDX 2.3 JSON format
+	SBOMFormatSPDX = "spdx"
+	// SBOMFormatCycloneDX SBOM in the CycloneDX 1.4 JSON format
+	SBOMFormatCycloneDX = "cyclonedx"
+
+	// SBOMMediaTypeSPDX Media type, and artifact type, of the SPDX SBOM attached to a bundle
+	SBOMMediaTypeSPDX types.MediaType = "application/spdx+json"
+	// SBOMMediaTypeCycloneDX Media type, and artifact type, of the CycloneDX SBOM attached to a bundle
+	SBOMMediaTypeCycloneDX types.MediaType = "application/vnd.cyclonedx+json"
+)
+
+var sbomMediaTypes = map[string]types.MediaType{
+	SBOMFormatSPDX:      SBOMMediaTypeSPDX,
+	SBOMFormatCycloneDX: SBOMMediaTypeCycloneDX,
+}
+
+// SBOMFormats returns the formats of the SBOM that can be generated for a bundle
+func SBOMFormats() []string {
+	var formats []string
+	for format := range sbomMediaTypes {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// ValidateSBOMFormat returns an error when the SBOM format is not supported
+func ValidateSBOMFormat(format string) error {
+	if _, found := sbomMediaTypes[format]; !found {
+		return fmt.Errorf("Unsupported SBOM format '%s' (supported formats: %s)", format, strings.Join(SBOMFormats(), ", "))
+	}
+	return nil
+}
+
+// sbomContents Information about the bundle that is recorded in the SBOM
+type sbomContents struct {
+	bundleRef regname.Digest
+	files     ContentsManifest
+	images    []sbomImage
+	created   time.Time
+}
+
+// sbomImage Image of the ImagesLock of the bundle
+type sbomImage struct {
+	repo   regname.Repository
+	digest string
+}
+
+func newSBOMContents(bundleRef regname.Digest, files ContentsManifest, imagesLock lockconfig.ImagesLock, created time.Time) (sbomContents, error) {
+	contents := sbomContents{bundleRef: bundleRef, files: files, created: created}
+	for _, img := range imagesLock.Images {
+		digestRef, err := regname.NewDigest(img.Image)
+		if err != nil {
+			return sbomContents{}, fmt.Errorf("Parsing image '%s': %s", img.Image, err)
+		}
+		contents.images = append(contents.images, sbomImage{repo: digestRef.Context(), digest: digestRef.DigestStr()})
+	}
+	return contents, nil
+}
+
+// newSBOMArtifact Creates an OCI artifact, with the SBOM as its only layer, that refers to the bundle as its subject
+func newSBOMArtifact(format string, contents sbomContents, bundleDesc regv1.Descriptor) (regv1.Image, error) {
+	var doc interface{}
+	switch format {
+	case SBOMFormatSPDX:
+		doc = newSPDXDocument(contents)
+	case SBOMFormatCycloneDX:
+		doc = newCycloneDXDocument(contents)
+	default:
+		return nil, ValidateSBOMFormat(format)
+	}
+
+	docJSON, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType := sbomMediaTypes[format]
+	artifact, err := mutate.Append(empty.Image, mutate.Addendum{Layer: static.NewLayer(docJSON, mediaType)})
+	if err != nil {
+		return nil, err
+	}
+	artifact = mutate.MediaType(artifact, types.OCIManifestSchema1)
+	artifact = mutate.ConfigMediaType(artifact, mediaType)
+
+	return mutate.Subject(artifact, bundleDesc).(regv1.Image), nil
+}
+
+func (c sbomContents) filePaths() []string {
+	var paths []string
+	for filePath := range c.files.Files {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ociPackageURL Package URL of an image, as described in https://github.com/package-url/purl-spec
+func ociPackageURL(repo regname.Repository, digest string) string {
+	return fmt.Sprintf("pkg:oci/%s@%s?repository_url=%s", repositoryShortName(repo), url.QueryEscape(digest), url.QueryEscape(repo.Name()))
+}
+
+func repositoryShortName(repo regname.Repository) string {
+	return strings.ToLower(path.Base(repo.RepositoryStr()))
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Files             []spdxFile         `json:"files,omitempty"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	Checksums        []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxFile struct {
+	FileName  string         `json:"fileName"`
+	SPDXID    string         `json:"SPDXID"`
+	Checksums []spdxChecksum `json:"checksums"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+func newSPDXDocument(contents sbomContents) spdxDocument {
+	const bundleID = "SPDXRef-Bundle"
+
+	bundleRepo := contents.bundleRef.Context()
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              contents.bundleRef.Name(),
+		DocumentNamespace: fmt.Sprintf("https://carvel.dev/imgpkg/spdx/%s/%s", bundleRepo.Name(), contents.bundleRef.DigestStr()),
+		CreationInfo: spdxCreationInfo{
+			Created:  contents.created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: imgpkg"},
+		},
+		Packages: []spdxPackage{spdxImagePackage(bundleID, bundleRepo, contents.bundleRef.DigestStr())},
+		Relationships: []spdxRelationship{
+			{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: bundleID},
+		},
+	}
+
+	for i, filePath := range contents.filePaths() {
+		fileID := fmt.Sprintf("SPDXRef-File-%d", i+1)
+		doc.Files = append(doc.Files, spdxFile{
+			FileName: "./" + filePath,
+			SPDXID:   fileID,
+			Checksums: []spdxChecksum{{
+				Algorithm:     strings.ToUpper(contents.files.Algorithm),
+				ChecksumValue: contents.files.Files[filePath],
+			}},
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{SPDXElementID: bundleID, RelationshipType: "CONTAINS", RelatedSPDXElement: fileID})
+	}
+
+	for i, img := range contents.images {
+		imageID := fmt.Sprintf("SPDXRef-Image-%d", i+1)
+		doc.Packages = append(doc.Packages, spdxImagePackage(imageID, img.repo, img.digest))
+		doc.Relationships = append(doc.Relationships, spdxRelationship{SPDXElementID: bundleID, RelationshipType: "DEPENDS_ON", RelatedSPDXElement: imageID})
+	}
+
+	return doc
+}
+
+func spdxImagePackage(id string, repo regname.Repository, digest string) spdxPackage {
+	return spdxPackage{
+		Name:             repo.Name(),
+		SPDXID:           id,
+		VersionInfo:      digest,
+		DownloadLocation: "NOASSERTION",
+		FilesAnalyzed:    false,
+		Checksums: []spdxChecksum{{
+			Algorithm:     "SHA256",
+			ChecksumValue: strings.TrimPrefix(digest, "sha256:"),
+		}},
+		ExternalRefs: []spdxExternalRef{{
+			ReferenceCategory: "PACKAGE-MANAGER",
+			ReferenceType:     "purl",
+			ReferenceLocator:  ociPackageURL(repo, digest),
+		}},
+	}
+}
+
+type cycloneDXDocument struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components,omitempty"`
+	Dependencies []cycloneDXDependency `json:"dependencies,omitempty"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     []cycloneDXTool    `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXTool struct {
+	Name string `json:"name"`
+}
+
+type cycloneDXComponent struct {
+	BOMRef  string          `json:"bom-ref"`
+	Type    string          `json:"type"`
+	Name    string          `json:"name"`
+	Version string          `json:"version,omitempty"`
+	PURL    string          `json:"purl,omitempty"`
+	Hashes  []cycloneDXHash `json:"hashes,omitempty"`
+}
+
+type cycloneDXHash struct {
+	Algorithm string `json:"alg"`
+	Content   string `json:"content"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+func newCycloneDXDocument(contents sbomContents) cycloneDXDocument {
+	bundleRepo := contents.bundleRef.Context()
+	bundleComponent := cycloneDXImageComponent(bundleRepo, contents.bundleRef.DigestStr())
+
+	doc := cycloneDXDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.4",
+		Version:     1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: contents.created.UTC().Format(time.RFC3339),
+			Tools:     []cycloneDXTool{{Name: "imgpkg"}},
+			Component: bundleComponent,
+		},
+	}
+
+	hashAlgorithm := "SHA-" + strings.TrimPrefix(contents.files.Algorithm, "sha")
+	for _, filePath := range contents.filePaths() {
+		doc.Components = append(doc.Components, cycloneDXComponent{
+			BOMRef: "file:" + filePath,
+			Type:   "file",
+			Name:   filePath,
+			Hashes: []cycloneDXHash{{Algorithm: hashAlgorithm, Content: contents.files.Files[filePath]}},
+		})
+	}
+
+	bundleDependency := cycloneDXDependency{Ref: bundleComponent.BOMRef}
+	for _, img := range contents.images {
+		component := cycloneDXImageComponent(img.repo, img.digest)
+		doc.Components = append(doc.Components, component)
+		bundleDependency.DependsOn = append(bundleDependency.DependsOn, component.BOMRef)
+	}
+	doc.Dependencies = []cycloneDXDependency{bundleDependency}
+
+	return doc
+}
+
+func cycloneDXImageComponent(repo regname.Repository, digest string) cycloneDXComponent {
+	purl := ociPackageURL(repo, digest)
+	return cycloneDXComponent{
+		BOMRef:  purl,
+		Type:    "container",
+		Name:    repo.Name(),
+		Version: digest,
+		PURL:    purl,
+		Hashes:  []cycloneDXHash{{Algorithm: "SHA-256", Content: strings.TrimPrefix(digest, "sha256:")}},
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle_test
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestNewContentsBundleWithSBOM(t *testing.T) {
+	const imageRef = "my.registry.io/some/image@sha256:cf31af331f38d1d7158470e095b132acd126a7180a54f263d386da88eb681d93"
+	imagesYAML := helpers.ImagesYAML + "images:\n- image: " + imageRef + "\n"
+
+	assets := &helpers.Assets{T: t}
+	defer assets.CleanCreatedFolders()
+	bundleBuilder := helpers.NewBundleDir(t, assets)
+	bundleDir := bundleBuilder.CreateBundleDir(helpers.BundleYAML, imagesYAML)
+
+	pushWithSBOM := func(t *testing.T, format string) (regname.Digest, map[string]interface{}) {
+		fakeRegistryBuilder := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistryBuilder.CleanUp()
+		fakeRegistry := fakeRegistryBuilder.Build()
+
+		uploadRef, err := regname.NewTag(fakeRegistryBuilder.ReferenceOnTestServer("some/bundle:tag"))
+		require.NoError(t, err)
+
+		imageURL, err := bundle.NewContents([]string{bundleDir}, nil, false).WithSBOM(format).Push(uploadRef, fakeRegistry, util.NewNoopLevelLogger())
+		require.NoError(t, err)
+		bundleRef, err := regname.NewDigest(imageURL)
+		require.NoError(t, err)
+
+		referrers, err := regremote.Referrers(bundleRef)
+		require.NoError(t, err)
+		require.Len(t, referrers.Manifests, 1)
+
+		sbomRef := bundleRef.Context().Digest(referrers.Manifests[0].Digest.String())
+		sbomImg, err := regremote.Image(sbomRef)
+		require.NoError(t, err)
+		manifest, err := sbomImg.Manifest()
+		require.NoError(t, err)
+		require.NotNil(t, manifest.Subject)
+		assert.Equal(t, bundleRef.DigestStr(), manifest.Subject.Digest.String())
+		assert.Equal(t, referrers.Manifests[0].ArtifactType, string(manifest.Config.MediaType))
+
+		layers, err := sbomImg.Layers()
+		require.NoError(t, err)
+		require.Len(t, layers, 1)
+		mediaType, err := layers[0].MediaType()
+		require.NoError(t, err)
+		assert.Equal(t, manifest.Config.MediaType, mediaType)
+
+		contents, err := layers[0].Uncompressed()
+		require.NoError(t, err)
+		defer contents.Close()
+		docJSON, err := io.ReadAll(contents)
+		require.NoError(t, err)
+
+		doc := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(docJSON, &doc))
+		return bundleRef, doc
+	}
+
+	t.Run("attaches an SPDX SBOM with the files and images of the bundle", func(t *testing.T) {
+		bundleRef, doc := pushWithSBOM(t, bundle.SBOMFormatSPDX)
+
+		assert.Equal(t, "SPDX-2.3", doc["spdxVersion"])
+		assert.Equal(t, bundleRef.Name(), doc["name"])
+
+		var packageNames []string
+		for _, pkg := range doc["packages"].([]interface{}) {
+			packageNames = append(packageNames, pkg.(map[string]interface{})["name"].(string))
+		}
+		assert.Equal(t, []string{bundleRef.Context().Name(), "my.registry.io/some/image"}, packageNames)
+
+		var fileNames []string
+		for _, file := range doc["files"].([]interface{}) {
+			fileNames = append(fileNames, file.(map[string]interface{})["fileName"].(string))
+		}
+		assert.Contains(t, fileNames, "./.imgpkg/images.yml")
+		assert.Contains(t, fileNames, "./.imgpkg/bundle.yml")
+	})
+
+	t.Run("attaches a CycloneDX SBOM with the files and images of the bundle", func(t *testing.T) {
+		bundleRef, doc := pushWithSBOM(t, bundle.SBOMFormatCycloneDX)
+
+		assert.Equal(t, "CycloneDX", doc["bomFormat"])
+		component := doc["metadata"].(map[string]interface{})["component"].(map[string]interface{})
+		assert.Equal(t, bundleRef.Context().Name(), component["name"])
+		assert.Equal(t, bundleRef.DigestStr(), component["version"])
+
+		var containers, files []string
+		for _, c := range doc["components"].([]interface{}) {
+			c := c.(map[string]interface{})
+			switch c["type"] {
+			case "container":
+				containers = append(containers, c["purl"].(string))
+			case "file":
+				files = append(files, c["name"].(string))
+			}
+		}
+		assert.Equal(t, []string{"pkg:oci/image@sha256%3Acf31af331f38d1d7158470e095b132acd126a7180a54f263d386da88eb681d93?repository_url=my.registry.io%2Fsome%2Fimage"}, containers)
+		assert.Contains(t, files, ".imgpkg/images.yml")
+	})
+
+	t.Run("fails before pushing when the format is not supported", func(t *testing.T) {
+		uploadRef, err := regname.NewTag("my.registry.io/some/bundle:tag")
+		require.NoError(t, err)
+
+		_, err = bundle.NewContents([]string{bundleDir}, nil, false).WithSBOM("swid").Push(uploadRef, nil, util.NewNoopLevelLogger())
+		require.ErrorContains(t, err, "Unsupported SBOM format 'swid' (supported formats: cyclonedx, spdx)")
+	})
+}
@@ -40,5 +40,13 @@ func (o *Bundle) IsBundle() (bool, error) {
 		return false, err
 	}
 	_, present := cfg.Config.Labels[BundleConfigLabel]
-	return present, nil
+	if !present {
+		return false, nil
+	}
+
+	_, err = formatVersion(o.plainImg.DigestRef(), cfg.Config.Labels)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
 }
@@ -0,0 +1,43 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"fmt"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/signature"
+)
+
+// SignaturesVerifier Verifies the signatures of the images of a bundle grouped by nesting level
+type SignaturesVerifier interface {
+	VerifyLevels(levels [][]regname.Digest) ([]signature.LevelResult, error)
+}
+
+// VerifyNestedSignatures Verifies the signatures of the images and bundles referenced by this bundle, directly or through
+// its nested bundles, and logs how many passed and failed at each nesting level.
+// bundles are the bundles returned by AllImagesLockRefs
+func (o *Bundle) VerifyNestedSignatures(bundles []*Bundle, verifier SignaturesVerifier, logger Logger) error {
+	var levels [][]regname.Digest
+	for _, levelRefs := range o.ImageRefsByNestingLevel(bundles) {
+		var level []regname.Digest
+		for _, ref := range levelRefs {
+			digestRef, err := regname.NewDigest(ref.PrimaryLocation())
+			if err != nil {
+				return fmt.Errorf("Parsing '%s': %s", ref.PrimaryLocation(), err)
+			}
+			level = append(level, digestRef)
+		}
+		levels = append(levels, level)
+	}
+
+	results, err := verifier.VerifyLevels(levels)
+	if len(results) > 0 {
+		logger.Logf("Signature verification of the images of bundle '%s':\n", o.DigestRef())
+		for _, result := range results {
+			logger.Logf("  level %d: %d verified, %d failed, %d not verified\n", result.Level, result.Verified, result.Failed, result.Unverified)
+		}
+	}
+	return err
+}
@@ -0,0 +1,17 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewAuthCmd Creates the auth command that groups the commands to troubleshoot registry credentials
+func NewAuthCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Registry authentication",
+	}
+	return cmd
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+// AuthCheckOptions Command Line options that can be provided to the auth check command
+type AuthCheckOptions struct {
+	ui ui.UI
+
+	RegistryFlags RegistryFlags
+
+	Registry string
+	Repo     string
+}
+
+// NewAuthCheckOptions constructor for building an AuthCheckOptions, holding values derived via flags
+func NewAuthCheckOptions(ui ui.UI) *AuthCheckOptions {
+	return &AuthCheckOptions{ui: ui}
+}
+
+// NewAuthCheckCmd Creates the auth check command
+func NewAuthCheckCmd(o *AuthCheckOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check REGISTRY",
+		Short: "Show which credentials are used for a registry and what they allow",
+		Long: `Show which credentials are used for a registry and what they allow.
+Asks each keychain for credentials in the same order as the other commands, printing the answer of each one,
+and uses the selected credentials to access the registry. When --repo is provided, also checks if the credentials
+allow pulling from and pushing to the repository. Nothing is written to the registry.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			o.Registry = args[0]
+			return o.Run()
+		},
+		Example: `
+    # Check the credentials used for Docker Hub
+    imgpkg auth check index.docker.io
+
+    # Check if the credentials in the environment allow pushing to a repository
+    imgpkg auth check my.registry.io --repo my.registry.io/team/app`,
+	}
+
+	o.RegistryFlags.Set(cmd)
+	cmd.Flags().StringVar(&o.Repo, "repo", "", "Repository to check pull and push access to")
+	return cmd
+}
+
+// Run Executes the auth check command
+func (a *AuthCheckOptions) Run() error {
+	opts := a.RegistryFlags.AsRegistryOpts()
+	// Always talk to the registry instead of relying on cached responses
+	opts.CacheDir = ""
+
+	result, err := registry.CheckAuth(opts, a.Registry, a.Repo)
+	if err != nil {
+		return err
+	}
+
+	lines := []string{fmt.Sprintf("Registry: %s", result.Registry), "", "Credentials resolution (in order):"}
+	selected := false
+	for i, resolution := range result.Resolutions {
+		lines = append(lines, fmt.Sprintf("  %d. %s: %s", i+1, resolution.Keychain, describeResolution(resolution)))
+		selected = selected || resolution.Selected
+	}
+	if !selected {
+		lines = append(lines, "  No credentials found, using anonymous access")
+	}
+
+	lines = append(lines, "", "Access:")
+	for _, access := range result.Access {
+		if access.Allowed {
+			lines = append(lines, fmt.Sprintf("  %s: allowed", access.Scope))
+		} else {
+			lines = append(lines, fmt.Sprintf("  %s: denied (%s)", access.Scope, access.Error))
+		}
+	}
+	writeResult(a.ui, strings.Join(lines, "\n"))
+
+	if !result.Allowed() {
+		return fmt.Errorf("Credentials for registry '%s' do not allow all the checked access", result.Registry)
+	}
+	return nil
+}
+
+func describeResolution(resolution registry.AuthResolution) string {
+	switch {
+	case !resolution.Tried:
+		return "not tried"
+	case resolution.Error != nil:
+		return fmt.Sprintf("error, stops the resolution (%s)", resolution.Error)
+	case resolution.Selected:
+		return fmt.Sprintf("%s <- selected", resolution.Identity)
+	default:
+		return "no credentials"
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// BandwidthFlags command line flags to limit the bandwidth used to transfer data from and to registries
+type BandwidthFlags struct {
+	MaxBandwidth string
+}
+
+// Set Registers the flags available to the provided command
+func (b *BandwidthFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&b.MaxBandwidth, "max-bandwidth", "",
+		"Maximum bytes per second transferred from and to all registries, shared by all the concurrent transfers (e.g. 50MB, 10MiB)")
+}
+
+// BytesPerSecond returns the maximum bandwidth, 0 when there is no limit
+func (b BandwidthFlags) BytesPerSecond() (int64, error) {
+	if b.MaxBandwidth == "" {
+		return 0, nil
+	}
+	bytesPerSecond, err := parseByteSize(b.MaxBandwidth)
+	if err != nil {
+		return 0, fmt.Errorf("Parsing --max-bandwidth: %s", err)
+	}
+	return bytesPerSecond, nil
+}
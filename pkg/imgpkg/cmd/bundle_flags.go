@@ -13,8 +13,10 @@ type BundleFlags struct {
 
 func (b *BundleFlags) Set(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&b.Bundle, "bundle", "b", "", "Set bundle (example: docker.io/dkalinin/test-content)")
+	_ = cmd.RegisterFlagCompletionFunc("bundle", completeRegistries)
 }
 
 func (b *BundleFlags) SetCopy(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&b.Bundle, "bundle", "b", "", "Bundle reference for copying (happens thickly, i.e. bundle image + all referenced images)")
+	_ = cmd.RegisterFlagCompletionFunc("bundle", completeRegistries)
 }
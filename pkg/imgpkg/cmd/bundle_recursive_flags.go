@@ -4,17 +4,78 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
 )
 
 type BundleRecursiveFlags struct {
 	Recursive bool
+
+	NestedImagesLock        string
+	NestedImagesLockMapping string
 }
 
 func (b *BundleRecursiveFlags) Set(cmd *cobra.Command) {
-	cmd.Flags().BoolVarP(&b.Recursive, "recursive", "r", false, "Recursively iterate and fetch content of every bundle")
+	cmd.Flags().BoolVarP(&b.Recursive, "recursive", "r", false,
+		"Recursively iterate and fetch content of every bundle, nested bundles are extracted into .imgpkg/bundles/sha256-<digest> of the output directory")
+	cmd.Flags().StringVar(&b.NestedImagesLock, "nested-images-lock", string(bundle.NestedImagesLockCollocated),
+		"How the ImagesLock of nested bundles is written with --recursive (collocated: rewritten to the repository of the bundle when copied with it, "+
+			"origin: left as pushed, mapping: rewritten to the destinations in --nested-images-lock-mapping)")
+	cmd.Flags().StringVar(&b.NestedImagesLockMapping, "nested-images-lock-mapping", "",
+		"Result of copy --output-type yaml, the images of the ImagesLock of nested bundles are rewritten to their destination in it (requires --nested-images-lock mapping)")
 }
 
 func (b *BundleRecursiveFlags) SetCopy(cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(&b.Recursive, "recursive", "r", false, "Recursively iterate and fetch content of every bundle")
 }
+
+// Validate Checks that the flags controlling the ImagesLock of nested bundles are only used with --recursive
+func (b *BundleRecursiveFlags) Validate() error {
+	mode := bundle.NestedImagesLockMode(b.NestedImagesLock)
+	if !b.isValidNestedImagesLock() {
+		return fmt.Errorf("Expected --nested-images-lock to be one of %v, got '%s'", bundle.NestedImagesLockModes, b.NestedImagesLock)
+	}
+	if mode != "" && mode != bundle.NestedImagesLockCollocated && !b.Recursive {
+		return fmt.Errorf("Expected --recursive (-r) when using --nested-images-lock, only the ImagesLock of nested bundles is affected")
+	}
+	if mode == bundle.NestedImagesLockMapping && b.NestedImagesLockMapping == "" {
+		return fmt.Errorf("Expected --nested-images-lock-mapping when using --nested-images-lock mapping")
+	}
+	if mode != bundle.NestedImagesLockMapping && b.NestedImagesLockMapping != "" {
+		return fmt.Errorf("Expected --nested-images-lock mapping when using --nested-images-lock-mapping")
+	}
+	return nil
+}
+
+// NestedImagesLockOpts Returns how the ImagesLock of nested bundles is written and, with a mapping, the destination
+// of each image by its source
+func (b *BundleRecursiveFlags) NestedImagesLockOpts() (bundle.NestedImagesLockMode, map[string]string, error) {
+	mode := bundle.NestedImagesLockMode(b.NestedImagesLock)
+	if mode != bundle.NestedImagesLockMapping {
+		return mode, nil, nil
+	}
+
+	images, err := readCopyMapping(b.NestedImagesLockMapping)
+	if err != nil {
+		return "", nil, err
+	}
+	mapping := map[string]string{}
+	for _, image := range images {
+		mapping[image.Source] = image.Destination
+	}
+	return mode, mapping, nil
+}
+
+func (b *BundleRecursiveFlags) isValidNestedImagesLock() bool {
+	if b.NestedImagesLock == "" {
+		return true
+	}
+	for _, mode := range bundle.NestedImagesLockModes {
+		if b.NestedImagesLock == string(mode) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+)
+
+// CheckPinningOptions Command Line options that can be provided to the check-pinning command
+type CheckPinningOptions struct {
+	ui ui.UI
+
+	OutputTypeFlags OutputTypeFlags
+
+	Files             []string
+	ExcludedFilePaths []string
+}
+
+// NewCheckPinningOptions constructor for building a CheckPinningOptions, holding values derived via flags
+func NewCheckPinningOptions(ui ui.UI) *CheckPinningOptions {
+	return &CheckPinningOptions{ui: ui}
+}
+
+// NewCheckPinningCmd Creates the check-pinning command
+func NewCheckPinningCmd(o *CheckPinningOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check-pinning",
+		Short: "Check that the images referenced in a bundle directory are pinned to a digest",
+		Long: `Check that the images referenced in a bundle directory are pinned to a digest.
+The .imgpkg/images.yml and every YAML file of the directory are scanned, and the values of the "image" keys that
+reference an image by tag instead of digest are reported with their file and line. Fails when any is found, so it
+can be used in pre-commit hooks and CI jobs to enforce pinning policies.`,
+		RunE: func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+    # Check the images referenced in the bundle directory ./app1-bundle
+    imgpkg check-pinning -f ./app1-bundle`,
+	}
+	o.OutputTypeFlags.Set(cmd)
+	cmd.Flags().StringSliceVarP(&o.Files, "file", "f", nil, "Set file or directory to check (format: /tmp/foo) (can be specified multiple times)")
+	cmd.Flags().StringSliceVar(&o.ExcludedFilePaths, "file-exclusion", []string{".git"}, "Exclude file whose path, relative to the directory, matches (format: bar.yaml, nested-dir/baz.txt) (can be specified multiple times)")
+	return cmd
+}
+
+// Run Scans the files and reports the images referenced by tag
+func (c *CheckPinningOptions) Run() error {
+	if len(c.Files) == 0 {
+		return fmt.Errorf("Expected at least one file or directory to check, provided with --file (-f)")
+	}
+	if err := c.OutputTypeFlags.Validate(); err != nil {
+		return err
+	}
+
+	report, err := bundle.NewContents(c.Files, c.ExcludedFilePaths, false).CheckPinning()
+	if err != nil {
+		return err
+	}
+
+	if c.OutputTypeFlags.IsJSON() {
+		err = c.OutputTypeFlags.WriteJSON(c.ui, report)
+		if err != nil {
+			return err
+		}
+	} else {
+		c.printText(report)
+	}
+
+	if len(report.UnpinnedImageRefs) > 0 {
+		return fmt.Errorf("Found %d image references that are not pinned to a digest", len(report.UnpinnedImageRefs))
+	}
+	return nil
+}
+
+func (c *CheckPinningOptions) printText(report bundle.PinningReport) {
+	for _, skipped := range report.SkippedFiles {
+		c.ui.BeginLinef("Warning: Skipped '%s', it is not valid YAML\n", skipped)
+	}
+
+	if len(report.UnpinnedImageRefs) == 0 {
+		c.ui.BeginLinef("Every image reference is pinned to a digest\n")
+		return
+	}
+
+	table := uitable.Table{
+		Title:   "Images not pinned to a digest",
+		Content: "image references",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("File"),
+			uitable.NewHeader("Line"),
+			uitable.NewHeader("Image"),
+		},
+	}
+	for _, ref := range report.UnpinnedImageRefs {
+		table.Rows = append(table.Rows, []uitable.Value{
+			uitable.NewValueString(ref.File),
+			uitable.NewValueInt(ref.Line),
+			uitable.NewValueString(ref.Image),
+		})
+	}
+	c.ui.PrintTable(table)
+}
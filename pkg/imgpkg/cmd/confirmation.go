@@ -0,0 +1,47 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	goui "github.com/cppforlife/go-cli-ui/ui"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+)
+
+// confirmation Asks the user to confirm operations that delete or overwrite content, so that all the commands
+// behave the same way:
+//   - with --yes (-y) the operation proceeds without asking
+//   - when the input is a terminal the user is prompted
+//   - otherwise the operation is refused, so that scripts never wait for an answer that will not come
+type confirmation struct {
+	ui         goui.UI
+	isTerminal func() bool
+}
+
+func newConfirmation(ui goui.UI) confirmation {
+	return confirmation{ui: ui, isTerminal: func() bool { return util.IsTerminal(os.Stdin) }}
+}
+
+// Confirm describes the operation to the user, e.g. "delete 3 tags from 'repo/app'", and returns an error
+// when the user does not confirm it
+func (c confirmation) Confirm(operation string) error {
+	// --yes configures the ui to be non interactive
+	if !c.ui.IsInteractive() {
+		return nil
+	}
+
+	if !c.isTerminal() {
+		return fmt.Errorf("Refusing to %s without confirmation (hint: use --yes (-y) to confirm when not running in a terminal)", operation)
+	}
+
+	// Written to stderr, together with the prompt, to keep the output of the command free of it
+	c.ui.ErrorLinef("About to %s", operation)
+	err := c.ui.AskForConfirmation()
+	if err != nil {
+		return fmt.Errorf("Not confirmed to %s: %s", operation, err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	goui "github.com/cppforlife/go-cli-ui/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type confirmationUI struct {
+	goui.UI
+	interactive bool
+	answer      error
+	asked       bool
+	messages    []string
+}
+
+func (c *confirmationUI) IsInteractive() bool { return c.interactive }
+
+func (c *confirmationUI) ErrorLinef(pattern string, args ...interface{}) {
+	c.messages = append(c.messages, pattern)
+}
+
+func (c *confirmationUI) AskForConfirmation() error {
+	c.asked = true
+	return c.answer
+}
+
+func TestConfirmation(t *testing.T) {
+	t.Run("when --yes is provided, it does not ask", func(t *testing.T) {
+		confUI := &confirmationUI{UI: goui.NewNoopUI()}
+		subject := newConfirmation(confUI)
+		subject.isTerminal = func() bool { return false }
+
+		require.NoError(t, subject.Confirm("delete tag 'repo:1.0'"))
+		assert.False(t, confUI.asked)
+	})
+
+	t.Run("when the input is a terminal, it asks the user", func(t *testing.T) {
+		confUI := &confirmationUI{UI: goui.NewNoopUI(), interactive: true}
+		subject := newConfirmation(confUI)
+		subject.isTerminal = func() bool { return true }
+
+		require.NoError(t, subject.Confirm("delete tag 'repo:1.0'"))
+		assert.True(t, confUI.asked)
+		assert.Len(t, confUI.messages, 1)
+	})
+
+	t.Run("when the user does not confirm, it fails", func(t *testing.T) {
+		confUI := &confirmationUI{UI: goui.NewNoopUI(), interactive: true, answer: errors.New("Stopped")}
+		subject := newConfirmation(confUI)
+		subject.isTerminal = func() bool { return true }
+
+		require.EqualError(t, subject.Confirm("delete tag 'repo:1.0'"), "Not confirmed to delete tag 'repo:1.0': Stopped")
+	})
+
+	t.Run("when the input is not a terminal and --yes is not provided, it fails without asking", func(t *testing.T) {
+		confUI := &confirmationUI{UI: goui.NewNoopUI(), interactive: true}
+		subject := newConfirmation(confUI)
+		subject.isTerminal = func() bool { return false }
+
+		err := subject.Confirm("delete tag 'repo:1.0'")
+		require.ErrorContains(t, err, "Refusing to delete tag 'repo:1.0' without confirmation (hint: use --yes (-y)")
+		assert.False(t, confUI.asked)
+	})
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+// ConvertOptions Command Line options that can be provided to the convert command
+type ConvertOptions struct {
+	ui ui.UI
+
+	ImageFlags          ImageFlags
+	BundleFlags         BundleFlags
+	RegistryFlags       RegistryFlags
+	RequireDigestsFlags RequireDigestsFlags
+
+	ImagesLockFile string
+}
+
+// NewConvertOptions constructor for building a ConvertOptions, holding values derived via flags
+func NewConvertOptions(ui ui.UI) *ConvertOptions {
+	return &ConvertOptions{ui: ui}
+}
+
+// NewConvertCmd Creates the convert command
+func NewConvertCmd(o *ConvertOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "convert",
+		Short: "Convert a plain image into a bundle",
+		Long: `Convert a plain image into a bundle.
+The layers of the image are reused and a new layer containing the provided ImagesLock, as .imgpkg/images.yml, is added on top of them.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			o.RequireDigestsFlags.SetFromCmd(cmd)
+			return o.Run()
+		},
+		Example: `
+    # Convert image repo/app1-config, with the images resolved by kbld in images.yml, into bundle repo/app1-bundle
+    imgpkg convert -i repo/app1-config -b repo/app1-bundle --images-file images.yml`,
+	}
+	o.ImageFlags.Set(cmd)
+	o.BundleFlags.Set(cmd)
+	o.RegistryFlags.Set(cmd)
+	cmd.Flags().StringVar(&o.ImagesLockFile, "images-file", "", "ImagesLock file with the images referenced by the image being converted")
+	return cmd
+}
+
+// Run Executes the convert command
+func (c *ConvertOptions) Run() error {
+	if c.ImageFlags.Image == "" {
+		return fmt.Errorf("Expected --image (-i) with the image to convert")
+	}
+	if c.BundleFlags.Bundle == "" {
+		return fmt.Errorf("Expected --bundle (-b) with the bundle to create")
+	}
+	if c.ImagesLockFile == "" {
+		return fmt.Errorf("Expected --images-file with the ImagesLock of the bundle")
+	}
+	if err := c.RequireDigestsFlags.ValidateRef("--image (-i)", c.ImageFlags.Image); err != nil {
+		return err
+	}
+
+	imagesLock, err := lockconfig.NewImagesLockFromPath(c.ImagesLockFile)
+	if err != nil {
+		return err
+	}
+
+	uploadRef, err := regname.NewTag(c.BundleFlags.Bundle, regname.WeakValidation)
+	if err != nil {
+		return fmt.Errorf("Parsing '%s': %s", c.BundleFlags.Bundle, err)
+	}
+
+	registryOpts, err := c.RegistryFlags.AsRegistryOptsWritingTo(uploadRef.Name())
+	if err != nil {
+		return err
+	}
+
+	reg, err := registry.NewSimpleRegistry(registryOpts)
+	if err != nil {
+		return err
+	}
+
+	logger := util.NewUILevelLogger(util.LogWarn, util.NewLogger(c.ui))
+	bundleURL, err := bundle.ConvertImage(c.ImageFlags.Image, imagesLock, uploadRef, reg, logger)
+	if err != nil {
+		return err
+	}
+
+	printResult(c.ui, bundleURL, "Converted '%s' into '%s'", c.ImageFlags.Image, bundleURL)
+
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestConvert(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	configImage := fakeRegistry.WithImageFromPath("app/config", "test_assets/image_with_config", nil)
+	appImage := fakeRegistry.WithRandomImage("app/image")
+	existingBundle := fakeRegistry.WithRandomBundle("app/existing-bundle")
+	reg := fakeRegistry.Build()
+
+	imagesLock := lockconfig.NewEmptyImagesLock()
+	imagesLock.AddImageRef(lockconfig.ImageRef{Image: appImage.RefDigest})
+	imagesLockPath := filepath.Join(t.TempDir(), "images.yml")
+	require.NoError(t, imagesLock.WriteToPath(imagesLockPath))
+
+	confUI := ui.NewConfUI(ui.NewNoopLogger())
+	defer confUI.Flush()
+
+	t.Run("creates a bundle with the layers of the image and the ImagesLock", func(t *testing.T) {
+		bundleRef := fakeRegistry.ReferenceOnTestServer("app/bundle")
+		convert := ConvertOptions{
+			ui:             confUI,
+			ImageFlags:     ImageFlags{Image: configImage.RefDigest},
+			BundleFlags:    BundleFlags{Bundle: bundleRef},
+			ImagesLockFile: imagesLockPath,
+		}
+		require.NoError(t, convert.Run())
+
+		lockReader := bundle.NewImagesLockReader()
+		convertedBundle := bundle.NewBundleFromRef(bundleRef, reg, lockReader, bundle.NewRegistryFetcher(reg, lockReader))
+		isBundle, err := convertedBundle.IsBundle()
+		require.NoError(t, err)
+		require.True(t, isBundle)
+
+		_, imageRefs, err := convertedBundle.AllImagesLockRefs(1, util.NewNoopLevelLogger())
+		require.NoError(t, err)
+		require.Len(t, imageRefs.ImageRefs(), 1)
+		require.Equal(t, appImage.RefDigest, imageRefs.ImageRefs()[0].Image)
+
+		outputPath := t.TempDir()
+		pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, OutputPath: outputPath, ImageIsBundleCheck: true}
+		require.NoError(t, pull.Run())
+		require.FileExists(t, filepath.Join(outputPath, "config.yml"))
+
+		pulledImagesLock, err := lockconfig.NewImagesLockFromPath(filepath.Join(outputPath, bundle.ImgpkgDir, bundle.ImagesLockFile))
+		require.NoError(t, err)
+		require.Equal(t, imagesLock.Images, pulledImagesLock.Images)
+
+		configLayers, err := configImage.Image.Layers()
+		require.NoError(t, err)
+		configLayerDigest, err := configLayers[0].Digest()
+		require.NoError(t, err)
+		parsedBundleRef, err := regname.ParseReference(bundleRef)
+		require.NoError(t, err)
+		bundleImg, err := reg.Image(parsedBundleRef)
+		require.NoError(t, err)
+		bundleLayers, err := bundleImg.Layers()
+		require.NoError(t, err)
+		require.Len(t, bundleLayers, 2)
+		bundleLayerDigest, err := bundleLayers[0].Digest()
+		require.NoError(t, err)
+		require.Equal(t, configLayerDigest, bundleLayerDigest)
+	})
+
+	t.Run("fails when the image is already a bundle", func(t *testing.T) {
+		convert := ConvertOptions{
+			ui:             confUI,
+			ImageFlags:     ImageFlags{Image: existingBundle.RefDigest},
+			BundleFlags:    BundleFlags{Bundle: fakeRegistry.ReferenceOnTestServer("app/other-bundle")},
+			ImagesLockFile: imagesLockPath,
+		}
+		require.ErrorContains(t, convert.Run(), "is already a bundle")
+	})
+
+	t.Run("fails when the images file is not an ImagesLock", func(t *testing.T) {
+		notImagesLockPath := filepath.Join(t.TempDir(), "images.yml")
+		require.NoError(t, os.WriteFile(notImagesLockPath, []byte("kind: Something"), 0600))
+
+		convert := ConvertOptions{
+			ui:             confUI,
+			ImageFlags:     ImageFlags{Image: configImage.RefDigest},
+			BundleFlags:    BundleFlags{Bundle: fakeRegistry.ReferenceOnTestServer("app/other-bundle")},
+			ImagesLockFile: notImagesLockPath,
+		}
+		require.Error(t, convert.Run())
+	})
+
+	t.Run("fails when the images file is not provided", func(t *testing.T) {
+		convert := ConvertOptions{
+			ui:          confUI,
+			ImageFlags:  ImageFlags{Image: configImage.RefDigest},
+			BundleFlags: BundleFlags{Bundle: fakeRegistry.ReferenceOnTestServer("app/other-bundle")},
+		}
+		require.ErrorContains(t, convert.Run(), "Expected --images-file")
+	})
+}
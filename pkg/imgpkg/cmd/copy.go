@@ -4,49 +4,91 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/spf13/cobra"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
 	ctlimgset "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagetar"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/plainimage"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/signature"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/workspace"
 )
 
-const rootBundleLabelKey string = "dev.carvel.imgpkg.copy.root-bundle"
+const rootBundleLabelKey = ctlimgset.RootBundleLabelKey
 
 type CopyOptions struct {
-	ui ui.UI
-
-	ImageFlags      ImageFlags
-	BundleFlags     BundleFlags
-	LockInputFlags  LockInputFlags
-	LockOutputFlags LockOutputFlags
-	TarFlags        TarFlags
-	RegistryFlags   RegistryFlags
-	SignatureFlags  SignatureFlags
+	ui    ui.UI
+	stdin io.Reader
+	// result of the copy to a repository, sent in the notification
+	result *copyResult
+
+	ImageFlags          ImageFlags
+	BundleFlags         BundleFlags
+	LockInputFlags      LockInputFlags
+	LockOutputFlags     LockOutputFlags
+	ImagesFileFlags     ImagesFileFlags
+	RepoTagsFlags       RepoTagsFlags
+	TarFlags            TarFlags
+	OCIFlags            OCIFlags
+	RegistryFlags       RegistryFlags
+	BandwidthFlags      BandwidthFlags
+	SignatureFlags      SignatureFlags
+	TrustFlags          TrustFlags
+	OutputTypeFlags     OutputTypeFlags
+	ConditionFlags      CopyConditionFlags
+	RequireDigestsFlags RequireDigestsFlags
+	NotifyFlags         NotifyFlags
+	ImageFilterFlags    ImageFilterFlags
+	ProgressFlags       ProgressFlags
+	MirrorsConfigFlags  MirrorsConfigFlags
 
 	RepoDst string
+	// ArtifactoryImportDst folder where the images are written following the layout of an Artifactory Docker repository
+	ArtifactoryImportDst string
 
 	Concurrency             int
+	LayerConcurrency        int
 	IncludeNonDistributable bool
 	UseRepoBasedTags        bool
+	StampStats              bool
+	LocationsRepo           string
+	PauseBetweenImages      time.Duration
+	TransferWindow          string
+	DstAnnotations          map[string]string
+	Force                   bool
+	RegistryMirrors         []string
+	Platforms               []string
+
+	// EventListener when provided receives the progress of the copy of each image
+	EventListener ctlimgset.EventListener
 }
 
 // NewCopyOptions constructor for building a CopyOptions, holding values derived via flags
-func NewCopyOptions(ui *ui.ConfUI) *CopyOptions {
-	return &CopyOptions{ui: ui}
+func NewCopyOptions(ui ui.UI) *CopyOptions {
+	return &CopyOptions{ui: ui, stdin: os.Stdin}
 }
 
 func NewCopyCmd(o *CopyOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "copy",
 		Short: "Copy a bundle from one location to another",
-		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			o.RequireDigestsFlags.SetFromCmd(cmd)
+			return o.RunWithContext(cmd.Context())
+		},
 		Example: `
     # Copy bundle dkalinin/app1-bundle to local tarball at /Volumes/app1-bundle.tar
     imgpkg copy -b dkalinin/app1-bundle --to-tar /Volumes/app1-bundle.tar
@@ -54,6 +96,63 @@ func NewCopyCmd(o *CopyOptions) *cobra.Command {
     # Copy bundle dkalinin/app1-bundle to another registry (or repository)
     imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle
 
+    # Copy bundle dkalinin/app1-bundle, tagging it as v1.0.0, and write the BundleLock of the copied bundle to bundle.lock.yml
+    imgpkg copy -b dkalinin/app1-bundle@sha256:... --to-repo internal-registry/app1-bundle --to-tag v1.0.0 --lock-output bundle.lock.yml
+
+    # Copy bundle from local tarball at /Volumes/app1-bundle.tar to a registry, tagging it as build-1234
+    imgpkg copy --tar /Volumes/app1-bundle.tar --to-repo internal-registry/app1-bundle --to-tag build-1234
+
+    # Copy bundle dkalinin/app1-bundle to parts of at most 2GB, app1-bundle.tar.001, app1-bundle.tar.002..., and copy the parts to a registry
+    imgpkg copy -b dkalinin/app1-bundle --to-tar /Volumes/app1-bundle.tar --to-tar-chunk-size 2GB
+    imgpkg copy --tar /Volumes/app1-bundle.tar.parts.yml --to-repo internal-registry/app1-bundle
+
+    # Copy bundle from an incomplete tar, retrieving the missing layers from the registries it was exported from
+    imgpkg copy --tar /Volumes/app1-bundle.tar --to-repo internal-registry/app1-bundle --fallback-to-origin
+
+    # Copy version 2 of bundle dkalinin/app1-bundle to a tar with only the layers missing from the tar of version 1, and copy both to a registry
+    imgpkg copy -b dkalinin/app1-bundle:v2 --to-tar /Volumes/app1-bundle-v2.tar --base-tar /Volumes/app1-bundle-v1.tar
+    imgpkg copy --tar /Volumes/app1-bundle-v2.tar --to-repo internal-registry/app1-bundle
+
+    # Copy bundle dkalinin/app1-bundle to the OCI image layout folder /mnt/usb/app1-bundle, and from it to a registry
+    imgpkg copy -b dkalinin/app1-bundle --to-repo file:///mnt/usb/app1-bundle
+    imgpkg copy -b file:///mnt/usb/app1-bundle:latest --to-repo internal-registry/app1-bundle
+
+    # Copy the images and bundles in the OCI image layout folder /tmp/app1-layout to /Volumes/app1-layout, without accessing any registry
+    imgpkg copy --oci /tmp/app1-layout --to-oci /Volumes/app1-layout
+
+    # Copy bundle repo/app1-bundle to a folder to be imported in the Artifactory Docker repository docker-local as docker-local/app1-bundle
+    imgpkg copy -b repo/app1-bundle --to-artifactory-import /tmp/docker-local/app1-bundle
+
+    # Copy image dkalinin/app1-image, with many large layers, uploading 8 of its layers at the same time
+    imgpkg copy -i dkalinin/app1-image --to-repo internal-registry/app1-image --layer-concurrency 8
+
+    # Copy bundle repo/app1-bundle to internal-registry/app1-bundle without using more than 50MB per second of the office link
+    imgpkg copy -b repo/app1-bundle --to-repo internal-registry/app1-bundle --max-bandwidth 50MB
+
+    # Copy bundle repo/app1-bundle to internal-registry/app1-bundle uploading only at night, one image every 30 seconds
+    imgpkg copy -b repo/app1-bundle --to-repo internal-registry/app1-bundle --transfer-window 22:00-06:00 --pause-between-images 30s
+
+    # Copy bundle dkalinin/app1-bundle to another registry, retrieving the images that cannot be retrieved from Docker Hub from mirror.gcr.io
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --registry-mirror index.docker.io=mirror.gcr.io
+
+    # Copy bundle dkalinin/app1-bundle to another registry, retrieving its images from the pull-through caches in mirrors.yml before Docker Hub
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --mirrors-config mirrors.yml
+
+    # Copy bundle dkalinin/app1-bundle to another registry, annotating the copied bundle for registry scanners
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --dst-annotation environment=production
+
+    # Copy bundle dkalinin/app1-bundle to a tarball, only including the linux/amd64 images of its multi-arch images
+    imgpkg copy -b dkalinin/app1-bundle --to-tar /Volumes/app1-bundle.tar --platform linux/amd64
+
+    # Copy bundle dkalinin/app1-bundle to another registry, without its windows images
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --exclude-image 'index.docker.io/dkalinin/*-windows'
+
+    # Copy bundle dkalinin/app1-bundle to another registry, showing a progress bar with the speed and ETA of each image
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --progress bars
+
+    # Copy bundle dkalinin/app1-bundle to another registry, posting the outcome and the copied images to a webhook
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --notify-url https://hooks.example.com/imgpkg
+
     # Copy image dkalinin/app1-image to another registry (or repository)
     # ##########################################################################
     # NOTE: if not using ~/.docker.config for authn, use env vars as described  #
@@ -61,6 +160,12 @@ func NewCopyCmd(o *CopyOptions) *cobra.Command {
     # ##########################################################################
     imgpkg copy -i dkalinin/app1-image --to-repo internal-registry/app1-image
 
+    # Copy all the images listed in stdin, one per line, to another registry (or repository)
+    yq '.images[].image' images.yml | imgpkg copy --images-file - --to-repo internal-registry/app1-images
+
+    # Copy all the tags of dkalinin/app1-image starting with v1.2. to another registry (or repository)
+    imgpkg copy --repo-tags 'dkalinin/app1-image:v1.2.*' --to-repo internal-registry/app1-image
+
     # Copy using image --repo-based-tags flag
     imgpkg copy -i registry.foo.bar/some/application/app \
                 --to-repo other-reg.faz.baz/my-app --repo-based-tags
@@ -75,37 +180,217 @@ func NewCopyCmd(o *CopyOptions) *cobra.Command {
 	o.BundleFlags.SetCopy(cmd)
 	o.LockInputFlags.Set(cmd)
 	o.LockOutputFlags.SetOnCopy(cmd)
+	o.ImagesFileFlags.Set(cmd)
+	o.RepoTagsFlags.Set(cmd)
 	o.TarFlags.Set(cmd)
+	o.OCIFlags.Set(cmd)
 	o.RegistryFlags.Set(cmd)
+	o.BandwidthFlags.Set(cmd)
 	o.SignatureFlags.Set(cmd)
-	cmd.Flags().StringVar(&o.RepoDst, "to-repo", "", "Location to upload assets")
+	o.TrustFlags.Set(cmd)
+	o.MirrorsConfigFlags.Set(cmd)
+	o.OutputTypeFlags.SetCopy(cmd)
+	o.ConditionFlags.Set(cmd)
+	o.NotifyFlags.Set(cmd)
+	o.ImageFilterFlags.Set(cmd)
+	o.ProgressFlags.Set(cmd)
+	cmd.Flags().StringVar(&o.RepoDst, "to-repo", "", "Location to upload assets, or file:// followed by the path of a folder to write them as an OCI image layout (e.g. file:///mnt/usb/app1-bundle)")
+	_ = cmd.RegisterFlagCompletionFunc("to-repo", completeRegistries)
+	cmd.Flags().StringVar(&o.ArtifactoryImportDst, "to-artifactory-import", "",
+		"Location of a folder, representing an image path of an Artifactory Docker repository, to write assets with their checksums to be imported with Artifactory's repository import")
 	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 5, "Concurrency")
+	cmd.Flags().IntVar(&o.LayerConcurrency, "layer-concurrency", 0,
+		"Number of layers uploaded at the same time, including layers of the same image, useful to copy images with many large layers (default: value of --concurrency)")
 	cmd.Flags().BoolVar(&o.IncludeNonDistributable, "include-non-distributable-layers", false,
 		"Include non-distributable layers when copying an image/bundle")
 	cmd.Flags().BoolVar(&o.UseRepoBasedTags, "repo-based-tags", false,
 		"Allow imgpkg to use repository-based tags for convenience")
+	cmd.Flags().BoolVar(&o.StampStats, "stamp-stats", false,
+		"After copying a bundle, tag next to it an image annotated with the statistics of the copy and the imgpkg version used, shown by describe")
+	cmd.Flags().StringVar(&o.LocationsRepo, "locations-repo", "",
+		"Repository where the source bundle was copied to, used to look up the locations of its images (default: repository of the bundle, set when using a registry proxy that changes repository paths)")
+	cmd.Flags().DurationVar(&o.PauseBetweenImages, "pause-between-images", 0,
+		"Time to wait after uploading each image before uploading the next one, images are uploaded one at a time when set (e.g. 30s)")
+	cmd.Flags().StringToStringVar(&o.DstAnnotations, "dst-annotation", nil,
+		"Add an annotation to the manifest of the bundle in the destination, the images of the bundle keep their digests (format: key=value) (can be specified multiple times)")
+	cmd.Flags().BoolVar(&o.Force, "force", false,
+		"Move the tag of the bundle in the destination without checking that the copied bundle can be described and all its images resolved, "+
+			"asks for confirmation when the tag points to a different bundle (use --yes (-y) when not running in a terminal)")
+	cmd.Flags().StringVar(&o.TransferWindow, "transfer-window", "",
+		"Only upload images during this time of the day, in local time, sleeping until it opens otherwise (format: HH:MM-HH:MM, e.g. 22:00-06:00)")
+	cmd.Flags().StringSliceVar(&o.RegistryMirrors, "registry-mirror", nil,
+		"Retrieve the images that cannot be retrieved from the origin registry from a mirror, mirrors of the same origin are tried in the order provided (format: origin=mirror, e.g. index.docker.io=mirror.gcr.io) ($IMGPKG_REGISTRY_MIRRORS) (can be specified multiple times)")
+	cmd.Flags().StringSliceVar(&o.Platforms, "platform", nil,
+		"Only copy the images of multi-arch images for this platform, their index is copied with a new digest, recorded in the locations of the copied bundle (format: os/arch[/variant], e.g. linux/amd64) (can be specified multiple times)")
 	return cmd
 }
 
+// Run Executes the copy command, notifying --notify-url of its outcome
 func (c *CopyOptions) Run() error {
+	return c.RunWithContext(context.Background())
+}
+
+// RunWithContext Same as Run, when ctx is done the transfers in progress are cancelled and the copy stops printing a
+// summary of its progress
+func (c *CopyOptions) RunWithContext(ctx context.Context) error {
+	startedAt := time.Now()
+	err := c.NotifyFlags.Validate()
+	if err != nil {
+		return err
+	}
+
+	notification := newNotification("copy", startedAt)
+	notification.Source = c.notificationSource()
+	notification.Destination = c.RepoDst + c.TarFlags.TarDst + c.OCIFlags.OCIDst + c.ArtifactoryImportDst
+
+	err = c.run(ctx, startedAt)
+	if c.result != nil {
+		notification.Report = c.result
+	}
+	c.NotifyFlags.Notify(util.NewUILevelLogger(util.LogWarn, util.NewLogger(c.OutputTypeFlags.LogsUI(c.ui))), notification, err)
+	return err
+}
+
+func (c *CopyOptions) run(ctx context.Context, startedAt time.Time) error {
 	if !c.hasOneSrc() {
-		return fmt.Errorf("Expected either --lock, --bundle (-b), --image (-i), or --tar as a source")
+		return fmt.Errorf("Expected either --lock, --bundle (-b), --image (-i), --images-file, --repo-tags, --tar, or --oci as a source")
 	}
 	if !c.hasOneDst() {
-		return fmt.Errorf("Expected either --to-tar or --to-repo")
+		return fmt.Errorf("Expected either --to-tar, --to-repo, --to-oci, or --to-artifactory-import")
+	}
+	if err := c.OutputTypeFlags.Validate(); err != nil {
+		return err
+	}
+	if err := c.ProgressFlags.Validate(); err != nil {
+		return err
+	}
+	if err := c.validateRequireDigests(); err != nil {
+		return err
+	}
+	if c.TarFlags.FallbackToOrigin && (!c.TarFlags.IsSrc() || !c.isRepoDst()) {
+		return fmt.Errorf("Flag --fallback-to-origin can only be used when copying from tar (--tar) to a repository (--to-repo)")
+	}
+
+	if c.TarFlags.BaseTar != "" {
+		if !c.TarFlags.IsSrc() && !c.TarFlags.IsDst() {
+			return fmt.Errorf("Flag --base-tar can only be used when copying to tar (--to-tar) or from tar (--tar)")
+		}
+		if c.TarFlags.BaseTar == c.TarFlags.TarDst {
+			return fmt.Errorf("Expected --base-tar to be a different file than --to-tar")
+		}
+	}
+
+	if c.LayerConcurrency < 0 {
+		return fmt.Errorf("Expected --layer-concurrency to be 0 or greater, but got %d", c.LayerConcurrency)
+	}
+
+	if c.LocationsRepo != "" && c.TarFlags.IsSrc() {
+		return fmt.Errorf("Flag --locations-repo cannot be used with tar source (--tar)")
+	}
+
+	platforms, err := c.platforms()
+	if err != nil {
+		return err
+	}
+	if len(platforms) > 0 && (c.TarFlags.IsSrc() || c.OCIFlags.IsSrc()) {
+		return fmt.Errorf("Flag --platform cannot be used with tar (--tar) or OCI image layout (--oci) sources")
+	}
+
+	if c.ImageFilterFlags.IsSet() {
+		if c.BundleFlags.Bundle == "" && c.LockInputFlags.LockFilePath == "" {
+			return fmt.Errorf("Flags --include-image and --exclude-image can only be used when copying a bundle (--bundle) or a lock file (--lock)")
+		}
+		if err := c.ImageFilterFlags.Validate(); err != nil {
+			return err
+		}
+	}
+
+	schedule, err := c.transferSchedule()
+	if err != nil {
+		return err
+	}
+	if c.OCIFlags.IsSrc() || c.OCIFlags.IsDst() {
+		return c.copyOCILayout()
+	}
+	mirrors, err := c.registryMirrors()
+	if err != nil {
+		return err
+	}
+	pullThroughMirrors, err := c.MirrorsConfigFlags.PullThroughMirrors()
+	if err != nil {
+		return err
+	}
+
+	closeFileURLServers, err := c.serveFileURLs()
+	if err != nil {
+		return err
 	}
+	defer closeFileURLServers()
 
-	registryOpts := c.RegistryFlags.AsRegistryOpts()
+	registryOpts, err := c.RegistryFlags.AsRegistryOptsWritingTo(c.RepoDst)
+	if err != nil {
+		return err
+	}
 	registryOpts.IncludeNonDistributableLayers = c.IncludeNonDistributable
+	registryOpts.LayerConcurrency = c.LayerConcurrency
+	registryOpts.MaxBandwidth, err = c.BandwidthFlags.BytesPerSecond()
+	if err != nil {
+		return err
+	}
 
-	reg, err := registry.NewSimpleRegistry(registryOpts)
+	reg, err := registry.NewSimpleRegistryWithContext(ctx, registryOpts)
 	if err != nil {
 		return err
 	}
+	blobUploads := registry.NewBlobUploadsReport()
+	reg = reg.WithBlobUploadsReport(blobUploads)
+
+	var imageRefs []string
+	switch {
+	case c.ImagesFileFlags.IsSet():
+		stdin := c.stdin
+		if stdin == nil {
+			stdin = os.Stdin
+		}
+		imageRefs, err = c.ImagesFileFlags.ImageRefs(stdin)
+		if err != nil {
+			return err
+		}
+		for _, imageRef := range imageRefs {
+			if err := c.RequireDigestsFlags.ValidateRef("--images-file", imageRef); err != nil {
+				return err
+			}
+		}
+	case c.RepoTagsFlags.IsSet():
+		imageRefs, err = c.RepoTagsFlags.ImageRefs(reg)
+		if err != nil {
+			return err
+		}
+	}
 
-	prefixedLogger := util.NewPrefixedLogger("copy | ", util.NewLogger(c.ui))
+	progressFlags := c.ProgressFlags
+	if isQuiet(c.ui) {
+		progressFlags.Progress = progressQuiet
+	}
+	prefixedLogger := util.NewPrefixedLogger("copy | ", util.NewLogger(c.OutputTypeFlags.LogsUI(c.ui)))
 	levelLogger := util.NewUILevelLogger(util.LogWarn, prefixedLogger)
+	operationsLogger := progressFlags.Logger(levelLogger)
 	imagesUploaderLogger := util.NewProgressBar(levelLogger, "done uploading images", "Error uploading images")
+	if progressFlags.Mode() != progressPlain {
+		imagesUploaderLogger = util.NewNoopProgressBar()
+	}
+
+	eventListener := c.EventListener
+	progress := progressFlags.Reporter()
+	if progress != nil {
+		defer progress.Stop()
+		eventListener = ctlimgset.EventListenerFunc(func(event ctlimgset.Event) {
+			if c.EventListener != nil {
+				c.EventListener.OnEvent(event)
+			}
+			progress.OnEvent(event)
+		})
+	}
 
 	var tagGen util.TagGenerator
 	tagGen = util.DefaultTagGenerator{}
@@ -113,51 +398,223 @@ func (c *CopyOptions) Run() error {
 		tagGen = util.RepoBasedTagGenerator{}
 	}
 
-	imageSet := ctlimgset.NewImageSet(c.Concurrency, prefixedLogger, tagGen)
-	tarImageSet := ctlimgset.NewTarImageSet(imageSet, c.Concurrency, prefixedLogger)
+	ws, err := workspace.New("")
+	if err != nil {
+		return err
+	}
+	defer ws.Cleanup()
+
+	progressTracker := newCopyProgressTracker(eventListener)
+	stopInterruptHandler := newInterruptHandler(c.ui, progressTracker, c.TarFlags.TarDst).Start(ctx)
+	defer stopInterruptHandler()
+
+	imageSet := ctlimgset.NewImageSet(c.Concurrency, operationsLogger, tagGen).WithEventListener(progressTracker).WithPlatforms(platforms)
+	tarImageSet := ctlimgset.NewTarImageSet(imageSet, c.Concurrency, operationsLogger).WithWorkspace(ws).WithBaseTar(c.TarFlags.BaseTar)
 
 	var signatureRetriever SignatureRetriever
 	if c.SignatureFlags.CopyCosignSignatures {
-		signatureRetriever = signature.NewSignatures(signature.NewCosign(reg), c.Concurrency)
+		signatureRetriever = signature.NewSignatures(signature.NewCosign(reg), c.Concurrency).
+			WithFinder(signature.NewCosignAttestation(reg)).
+			WithFinder(signature.NewCosignSBOM(reg))
 	} else {
 		signatureRetriever = signature.NewNoop()
 	}
 
+	var imagesVerifier ImagesVerifier
+	trustConfig, err := c.TrustFlags.TrustConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if trustConfig != nil {
+		imagesVerifier = signature.NewVerifier(*trustConfig, reg, levelLogger)
+	}
+
+	var uploadRegistry registry.Registry = reg
+	var mirroredRegistry *registry.WithMirrors
+	if len(mirrors) > 0 || len(pullThroughMirrors) > 0 {
+		mirroredRegistry = registry.NewRegistryWithMirrors(reg, mirrors, levelLogger).WithPullThroughMirrors(pullThroughMirrors)
+		uploadRegistry = mirroredRegistry
+	}
+	if progress != nil {
+		// Below the schedule, that already uploads the images one at a time
+		uploadRegistry = registry.NewRegistryWithImageProgress(uploadRegistry, progress)
+	}
+	if schedule != nil {
+		uploadRegistry = registry.NewRegistryWithSchedule(uploadRegistry, *schedule, levelLogger)
+	}
+
 	repoSrc := CopyRepoSrc{
 		ImageFlags:              c.ImageFlags,
 		BundleFlags:             c.BundleFlags,
 		LockInputFlags:          c.LockInputFlags,
+		ImageRefs:               imageRefs,
+		ImageRefsCanBeBundles:   c.RepoTagsFlags.IsSet(),
 		TarFlags:                c.TarFlags,
 		IncludeNonDistributable: c.IncludeNonDistributable,
 		Concurrency:             c.Concurrency,
+		LocationsRepo:           c.LocationsRepo,
+		DstAnnotations:          c.DstAnnotations,
+		Force:                   c.Force,
+		ImageFilterFlags:        c.ImageFilterFlags,
 
-		logger:             levelLogger,
-		registry:           registry.NewRegistryWithProgress(reg, imagesUploaderLogger),
+		logger:             operationsLogger,
+		registry:           registry.NewRegistryWithProgress(uploadRegistry, imagesUploaderLogger),
 		imageSet:           imageSet,
 		tarImageSet:        tarImageSet,
 		signatureRetriever: signatureRetriever,
+		imagesVerifier:     imagesVerifier,
+		workspace:          ws,
+		confirmation:       newConfirmation(c.ui),
 	}
 
 	switch {
 	case c.TarFlags.IsDst():
+		if c.TarFlags.ToTag != "" {
+			return fmt.Errorf("Flag --to-tag can only be used when copying to a repository (--to-repo)")
+		}
 		if c.TarFlags.IsSrc() {
 			return fmt.Errorf("Cannot use tar source (--tar) with tar destination (--to-tar)")
 		}
 		if c.LockOutputFlags.LockFilePath != "" {
 			return fmt.Errorf("Cannot output lock file with tar destination")
 		}
-		return repoSrc.CopyToTar(c.TarFlags.TarDst, c.TarFlags.Resume)
+		if c.OutputTypeFlags.IsStructured() {
+			return fmt.Errorf("Cannot use --output-type %s with tar destination", c.OutputTypeFlags.OutputType)
+		}
+		if c.ConditionFlags.IsSet() || c.ConditionFlags.MarkRelocationComplete {
+			return fmt.Errorf("Cannot use --if-destination-absent, --if-digest-differs or --mark-relocation-complete with tar destination")
+		}
+		if c.StampStats {
+			return fmt.Errorf("Cannot use --stamp-stats with tar destination")
+		}
+		if len(c.DstAnnotations) > 0 {
+			return fmt.Errorf("Cannot use --dst-annotation with tar destination")
+		}
+		if c.Force {
+			return fmt.Errorf("Cannot use --force with tar destination")
+		}
+		chunkSize, err := c.TarFlags.ChunkSize()
+		if err != nil {
+			return err
+		}
+		err = repoSrc.CopyToTar(c.TarFlags.TarDst, c.TarFlags.Resume)
+		if err != nil || chunkSize == 0 {
+			return err
+		}
+		indexPath, err := imagetar.SplitTar(c.TarFlags.TarDst, chunkSize)
+		if err != nil {
+			return fmt.Errorf("Splitting tar: %s", err)
+		}
+		c.ui.BeginLinef("Split the tar in parts, copy them to a repository with --tar %s\n", indexPath)
+		return nil
+
+	case c.isArtifactoryImportDst():
+		if c.TarFlags.IsSrc() {
+			return fmt.Errorf("Cannot use tar source (--tar) with Artifactory import destination (--to-artifactory-import)")
+		}
+		if c.TarFlags.ToTag != "" || c.TarFlags.Resume || c.TarFlags.ToTarChunkSize != "" {
+			return fmt.Errorf("Cannot use --to-tag, --resume or --to-tar-chunk-size with Artifactory import destination")
+		}
+		if c.LockOutputFlags.LockFilePath != "" || c.StampStats || len(c.DstAnnotations) > 0 || c.Force ||
+			c.ConditionFlags.IsSet() || c.ConditionFlags.MarkRelocationComplete {
+			return fmt.Errorf("Cannot use --lock-output, --stamp-stats, --dst-annotation, --force, --if-destination-absent, --if-digest-differs or --mark-relocation-complete with Artifactory import destination")
+		}
+		if c.OutputTypeFlags.IsStructured() {
+			return fmt.Errorf("Cannot use --output-type %s with Artifactory import destination", c.OutputTypeFlags.OutputType)
+		}
+		err = repoSrc.CopyToArtifactoryImport(c.ArtifactoryImportDst)
+		if err != nil {
+			return err
+		}
+		c.ui.BeginLinef("Wrote the images to %s, import its parent folder in the Artifactory Docker repository\n", c.ArtifactoryImportDst)
+		return nil
 
 	case c.isRepoDst():
 		if c.TarFlags.Resume {
 			return fmt.Errorf("Flag --resume can only be used when copying to tar")
 		}
+		if c.TarFlags.ToTarChunkSize != "" {
+			return fmt.Errorf("Flag --to-tar-chunk-size can only be used when copying to tar")
+		}
+		if c.TarFlags.ToTag != "" {
+			if !c.TarFlags.IsSrc() && c.BundleFlags.Bundle == "" && c.LockInputFlags.LockFilePath == "" {
+				return fmt.Errorf("Flag --to-tag can only be used when copying a bundle, from --bundle (-b), --lock or --tar")
+			}
+			if _, err := regname.NewTag(c.RepoDst + ":" + c.TarFlags.ToTag); err != nil {
+				return fmt.Errorf("Parsing --to-tag: %s", err)
+			}
+		}
+
+		conditions := copyConditions{flags: c.ConditionFlags, registry: reg}
+		srcRef := c.BundleFlags.Bundle + c.ImageFlags.Image
+		if (c.ConditionFlags.IsSet() || c.ConditionFlags.MarkRelocationComplete) && srcRef == "" {
+			return fmt.Errorf("Flags --if-destination-absent, --if-digest-differs and --mark-relocation-complete can only be used with --bundle (-b) or --image (-i)")
+		}
+		if c.ConditionFlags.MarkRelocationComplete {
+			if c.BundleFlags.Bundle == "" {
+				return fmt.Errorf("Flag --mark-relocation-complete can only be used when copying a bundle (--bundle (-b))")
+			}
+			if _, err := regname.NewTag(c.BundleFlags.Bundle); err != nil && c.TarFlags.ToTag == "" {
+				return fmt.Errorf("Flag --mark-relocation-complete can only be used with a bundle referenced by tag or with --to-tag, the annotated bundle is found through its tag in the destination")
+			}
+		}
+
+		skip, reason, err := conditions.ShouldSkip(srcRef, c.RepoDst, c.TarFlags.ToTag)
+		if err != nil {
+			return err
+		}
+		if skip {
+			levelLogger.Logf("Skipping copy: %s\n", reason)
+			progressTracker.OnEvent(ctlimgset.Event{Type: ctlimgset.EventImageSkipped, Source: srcRef, Reason: reason, Time: time.Now().UTC()})
+			c.result = &copyResult{Images: []copiedImage{}, Skipped: reason}
+			if c.OutputTypeFlags.IsStructured() {
+				return c.OutputTypeFlags.WriteResult(c.ui, c.result)
+			}
+			return nil
+		}
+
+		if c.ConditionFlags.MarkRelocationComplete {
+			relocationAnnotations, err := conditions.RelocationAnnotations(srcRef)
+			if err != nil {
+				return err
+			}
+			repoSrc.DstAnnotations = map[string]string{}
+			for key, value := range c.DstAnnotations {
+				repoSrc.DstAnnotations[key] = value
+			}
+			for key, value := range relocationAnnotations {
+				repoSrc.DstAnnotations[key] = value
+			}
+		}
 
 		processedImages, err := repoSrc.CopyToRepo(c.RepoDst)
 		if err != nil {
 			return err
 		}
-		return c.writeLockOutput(processedImages, reg)
+		err = c.writeLockOutput(processedImages, reg)
+		if err != nil {
+			return err
+		}
+		if c.StampStats {
+			err = c.stampCopyStats(processedImages, reg, startedAt, levelLogger)
+			if err != nil {
+				return err
+			}
+		}
+		if c.OutputTypeFlags.IsStructured() || c.NotifyFlags.URL != "" {
+			result, err := c.newCopyResult(processedImages, blobUploads, mirroredRegistry)
+			if err != nil {
+				return err
+			}
+			c.result = &result
+		}
+		if c.OutputTypeFlags.IsStructured() {
+			return c.OutputTypeFlags.WriteResult(c.ui, c.result)
+		}
+		if isQuiet(c.ui) {
+			c.printCopiedDigests(processedImages)
+		}
+		return nil
 
 	default:
 		panic("Unreachable")
@@ -242,18 +699,258 @@ func (c *CopyOptions) informUserIfTarballNeedsToBeRecreated(processedImages *ctl
 	return nil
 }
 
+// printCopiedDigests writes the destination digest of the root bundle or, when copying images, of each copied image
+func (c *CopyOptions) printCopiedDigests(processedImages *ctlimgset.ProcessedImages) {
+	if rootBundle := c.findProcessedImageRootBundle(processedImages); rootBundle != nil {
+		writeResult(c.ui, rootBundle.DigestRef)
+		return
+	}
+
+	var digestRefs []string
+	for _, processedImage := range processedImages.All() {
+		digestRefs = append(digestRefs, processedImage.DigestRef)
+	}
+	sort.Strings(digestRefs)
+	writeResult(c.ui, strings.Join(digestRefs, "\n"))
+}
+
+// copyOCILayout Copies the images and indexes of an OCI image layout folder to another one, without accessing any registry
+func (c *CopyOptions) copyOCILayout() error {
+	if !c.OCIFlags.IsSrc() || !c.OCIFlags.IsDst() {
+		return fmt.Errorf("Flags --oci and --to-oci can only be used together, to copy between OCI image layout folders")
+	}
+	if c.LockOutputFlags.LockFilePath != "" || c.StampStats || c.ConditionFlags.IsSet() || c.ConditionFlags.MarkRelocationComplete {
+		return fmt.Errorf("Cannot use --lock-output, --stamp-stats, --if-destination-absent, --if-digest-differs or --mark-relocation-complete when copying between OCI image layout folders")
+	}
+	if c.OutputTypeFlags.IsStructured() {
+		return fmt.Errorf("Cannot use --output-type %s when copying between OCI image layout folders", c.OutputTypeFlags.OutputType)
+	}
+	if err := c.validateOCILayoutFlags(); err != nil {
+		return err
+	}
+
+	logger := util.NewPrefixedLogger("copy | ", util.NewLogger(c.ui))
+	copied, err := imagetar.CopyOCILayout(c.OCIFlags.OCISrc, c.OCIFlags.OCIDst, logger)
+	if err != nil {
+		return err
+	}
+	logger.Logf("Copied %d images and indexes from '%s' to '%s'\n", len(copied), c.OCIFlags.OCISrc, c.OCIFlags.OCIDst)
+	return nil
+}
+
+// validateOCILayoutFlags Checks that no flag, that only applies when a registry is accessed, is used when copying
+// between OCI image layout folders
+func (c *CopyOptions) validateOCILayoutFlags() error {
+	unsupportedFlags := []struct {
+		name string
+		set  bool
+	}{
+		{"--dst-annotation", len(c.DstAnnotations) > 0},
+		{"--force", c.Force},
+		{"--to-tag", c.TarFlags.ToTag != ""},
+		{"--registry-mirror", len(c.RegistryMirrors) > 0},
+		{"--mirrors-config", c.MirrorsConfigFlags.ConfigPath != ""},
+		{"--verify-signature", c.TrustFlags.VerifySignature},
+		{"--signature-key", len(c.TrustFlags.SignatureKeys) > 0},
+		{"--trust-config", c.TrustFlags.TrustConfigPath != ""},
+	}
+	for _, flag := range unsupportedFlags {
+		if flag.set {
+			return fmt.Errorf("Cannot use %s when copying between OCI image layout folders (--oci and --to-oci)", flag.name)
+		}
+	}
+	return nil
+}
+
+// validateRequireDigests Checks that the images and bundles provided via flags are referenced by digest when required
+func (c *CopyOptions) validateRequireDigests() error {
+	if c.RequireDigestsFlags.RequireDigests && c.RepoTagsFlags.IsSet() {
+		return fmt.Errorf("Cannot use --repo-tags with --require-digests, tags are mutable references")
+	}
+	if err := c.RequireDigestsFlags.ValidateRef("--bundle (-b)", c.BundleFlags.Bundle); err != nil {
+		return err
+	}
+	return c.RequireDigestsFlags.ValidateRef("--image (-i)", c.ImageFlags.Image)
+}
+
+// copyResult the result of the copy command when the output type is json or yaml
+type copyResult struct {
+	Images  []copiedImage `json:"images"`
+	Skipped string        `json:"skipped,omitempty"`
+}
+
+type copiedImage struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Tag         string `json:"tag,omitempty"`
+	RootBundle  bool   `json:"rootBundle,omitempty"`
+	// Mirror registry the image was retrieved from, when it could not be retrieved from the registry of the source
+	Mirror string `json:"mirror,omitempty"`
+	// Size Sum of the sizes of the blobs of the image
+	Size  int64        `json:"size"`
+	Blobs []copiedBlob `json:"blobs"`
+}
+
+const (
+	blobUploadedStatus = "uploaded"
+	blobSkippedStatus  = "skipped"
+)
+
+type copiedBlob struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+	// Status uploaded when the blob was uploaded by this copy, skipped when it was already present in the destination,
+	// was mounted from another repository or is a non-distributable layer that was not copied
+	Status string `json:"status"`
+}
+
+// newCopyResult Returns the images copied to the repository and the blobs uploaded for each of them
+func (c *CopyOptions) newCopyResult(processedImages *ctlimgset.ProcessedImages, blobUploads *registry.BlobUploadsReport, mirrors *registry.WithMirrors) (copyResult, error) {
+	result := copyResult{Images: []copiedImage{}}
+	for _, processedImage := range processedImages.All() {
+		_, isRootBundle := processedImage.Labels[rootBundleLabelKey]
+		image := copiedImage{
+			Source:      processedImage.UnprocessedImageRef.DigestRef,
+			Destination: processedImage.DigestRef,
+			Tag:         processedImage.UnprocessedImageRef.Tag,
+			RootBundle:  isRootBundle,
+			Blobs:       []copiedBlob{},
+		}
+		if srcRef, err := regname.NewDigest(processedImage.UnprocessedImageRef.DigestRef); err == nil {
+			image.Mirror, _ = mirrors.ServedBy(srcRef.DigestStr())
+		}
+
+		dstRef, err := regname.NewDigest(processedImage.DigestRef)
+		if err != nil {
+			return copyResult{}, fmt.Errorf("Parsing '%s': %s", processedImage.DigestRef, err)
+		}
+		blobs, err := processedImageBlobs(processedImage)
+		if err != nil {
+			return copyResult{}, fmt.Errorf("Retrieving blobs of '%s': %s", processedImage.DigestRef, err)
+		}
+		for _, blob := range blobs {
+			blob.Status = blobSkippedStatus
+			if digest, err := regv1.NewHash(blob.Digest); err == nil && blobUploads.Uploaded(dstRef.Context(), digest) {
+				blob.Status = blobUploadedStatus
+			}
+			image.Size += blob.Size
+			image.Blobs = append(image.Blobs, blob)
+		}
+
+		result.Images = append(result.Images, image)
+	}
+	sort.Slice(result.Images, func(i, j int) bool {
+		return result.Images[i].Source < result.Images[j].Source
+	})
+
+	return result, nil
+}
+
+// processedImageBlobs Returns the config and layers of the image, or of all the images of the index
+func processedImageBlobs(processedImage ctlimgset.ProcessedImage) ([]copiedBlob, error) {
+	images, err := processedImageImages(processedImage)
+	if err != nil {
+		return nil, err
+	}
+
+	var blobs []copiedBlob
+	seen := map[regv1.Hash]struct{}{}
+	for _, img := range images {
+		manifest, err := img.Manifest()
+		if err != nil {
+			return nil, err
+		}
+		for _, desc := range append([]regv1.Descriptor{manifest.Config}, manifest.Layers...) {
+			if _, found := seen[desc.Digest]; found {
+				continue
+			}
+			seen[desc.Digest] = struct{}{}
+			blobs = append(blobs, copiedBlob{Digest: desc.Digest.String(), Size: desc.Size})
+		}
+	}
+	return blobs, nil
+}
+
+// transferSchedule Returns when images can be uploaded, nil when they can be uploaded at any time
+func (c *CopyOptions) transferSchedule() (*registry.TransferSchedule, error) {
+	if c.PauseBetweenImages == 0 && c.TransferWindow == "" {
+		return nil, nil
+	}
+	if !c.isRepoDst() {
+		return nil, fmt.Errorf("Flags --pause-between-images and --transfer-window can only be used when copying to a repository (--to-repo)")
+	}
+	if c.PauseBetweenImages < 0 {
+		return nil, fmt.Errorf("Expected --pause-between-images to be a positive duration, got '%s'", c.PauseBetweenImages)
+	}
+
+	schedule := &registry.TransferSchedule{PauseBetweenImages: c.PauseBetweenImages}
+	if c.TransferWindow != "" {
+		window, err := registry.NewTransferWindow(c.TransferWindow)
+		if err != nil {
+			return nil, err
+		}
+		schedule.Window = &window
+	}
+	return schedule, nil
+}
+
+// registryMirrors Returns the mirrors provided with --registry-mirror or, when the flag is not provided, $IMGPKG_REGISTRY_MIRRORS
+func (c *CopyOptions) registryMirrors() (registry.Mirrors, error) {
+	values := c.RegistryMirrors
+	if len(values) == 0 {
+		if envValue := os.Getenv("IMGPKG_REGISTRY_MIRRORS"); envValue != "" {
+			values = strings.Split(envValue, ",")
+		}
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return registry.NewMirrors(values)
+}
+
+// platforms Parses the platforms of the images to copy from multi-arch images
+func (c *CopyOptions) platforms() ([]regv1.Platform, error) {
+	var platforms []regv1.Platform
+	for _, value := range c.Platforms {
+		platform, err := regv1.ParsePlatform(value)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing --platform '%s': %s", value, err)
+		}
+		if platform.OS == "" || platform.Architecture == "" {
+			return nil, fmt.Errorf("Invalid platform '%s' (expected format: os/arch[/variant])", value)
+		}
+		platforms = append(platforms, *platform)
+	}
+	return platforms, nil
+}
+
 func (c *CopyOptions) isRepoDst() bool { return c.RepoDst != "" }
 
+func (c *CopyOptions) isArtifactoryImportDst() bool { return c.ArtifactoryImportDst != "" }
+
 func (c *CopyOptions) hasOneDst() bool {
-	repoSet := c.isRepoDst()
-	tarSet := c.TarFlags.IsDst()
-	return (repoSet || tarSet) && !(repoSet && tarSet)
+	var seen bool
+	for _, set := range []bool{c.isRepoDst(), c.TarFlags.IsDst(), c.OCIFlags.IsDst(), c.isArtifactoryImportDst()} {
+		if set {
+			if seen {
+				return false
+			}
+			seen = true
+		}
+	}
+	return seen
+}
+
+// notificationSource returns the source provided via flags
+func (c *CopyOptions) notificationSource() string {
+	return c.LockInputFlags.LockFilePath + c.TarFlags.TarSrc + c.OCIFlags.OCISrc + c.BundleFlags.Bundle +
+		c.ImageFlags.Image + c.ImagesFileFlags.ImagesFile + c.RepoTagsFlags.RepoTags
 }
 
 func (c *CopyOptions) hasOneSrc() bool {
 	var seen bool
-	for _, ref := range []string{c.LockInputFlags.LockFilePath, c.TarFlags.TarSrc,
-		c.BundleFlags.Bundle, c.ImageFlags.Image} {
+	for _, ref := range []string{c.LockInputFlags.LockFilePath, c.TarFlags.TarSrc, c.OCIFlags.OCISrc,
+		c.BundleFlags.Bundle, c.ImageFlags.Image, c.ImagesFileFlags.ImagesFile, c.RepoTagsFlags.RepoTags} {
 		if ref != "" {
 			if seen {
 				return false
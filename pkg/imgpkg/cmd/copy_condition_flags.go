@@ -0,0 +1,203 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+const (
+	relocationCompleteAnnotationKey string = "dev.carvel.imgpkg.copy.relocation-complete"
+	relocationSourceAnnotationKey   string = "dev.carvel.imgpkg.copy.relocation-source"
+)
+
+// CopyConditionFlags command line flags that make the copy conditional on the state of the destination
+type CopyConditionFlags struct {
+	IfDestinationAbsent    bool
+	IfDigestDiffers        bool
+	MarkRelocationComplete bool
+}
+
+// Set Registers the flags available to the copy command
+func (c *CopyConditionFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&c.IfDestinationAbsent, "if-destination-absent", false,
+		"Only copy when the destination does not have the source tag (or digest)")
+	cmd.Flags().BoolVar(&c.IfDigestDiffers, "if-digest-differs", false,
+		"Only copy when the destination tag does not point to the same digest as the source, or to the source annotated by --mark-relocation-complete")
+	cmd.Flags().BoolVar(&c.MarkRelocationComplete, "mark-relocation-complete", false,
+		"Annotate the bundle in the destination with "+relocationCompleteAnnotationKey+" and the source it was copied from, once all its images are copied. "+
+			"When used with --if-destination-absent or --if-digest-differs, the destination is only considered up to date when it has this annotation")
+}
+
+// IsSet returns true when the copy should only happen under some conditions
+func (c CopyConditionFlags) IsSet() bool {
+	return c.IfDestinationAbsent || c.IfDigestDiffers
+}
+
+// copyConditions checks the state of the destination to decide if a copy is needed
+type copyConditions struct {
+	flags    CopyConditionFlags
+	registry registry.Registry
+}
+
+// ShouldSkip returns true, and the reason, when the destination already contains the source. dstTag is the tag
+// applied in the destination (--to-tag), when empty the source is copied with its own tag (or digest)
+func (c copyConditions) ShouldSkip(srcRef string, repoDst string, dstTag string) (bool, string, error) {
+	if !c.flags.IsSet() {
+		return false, "", nil
+	}
+
+	src, dst, err := c.references(srcRef, repoDst, dstTag)
+	if err != nil {
+		return false, "", err
+	}
+
+	dstDigest, found, err := c.digestIfExists(dst)
+	if err != nil {
+		return false, "", fmt.Errorf("Checking destination %s: %s", dst.Name(), err)
+	}
+	if !found {
+		return false, "", nil
+	}
+
+	srcDigest, err := c.registry.Digest(src)
+	if err != nil {
+		return false, "", fmt.Errorf("Fetching digest of %s: %s", src.Name(), err)
+	}
+
+	// A destination annotated with the relocation information is a copy of the source recorded in it
+	copiedDigest := dstDigest
+	relocationSrcDigest, marked, err := c.relocationSource(dst)
+	if err != nil {
+		return false, "", fmt.Errorf("Checking relocation complete annotation of %s: %s", dst.Name(), err)
+	}
+	if marked {
+		copiedDigest = relocationSrcDigest
+	} else if c.flags.MarkRelocationComplete {
+		return false, "", nil
+	}
+
+	if c.flags.IfDestinationAbsent {
+		return true, fmt.Sprintf("destination %s already exists", dst.Name()), nil
+	}
+	if srcDigest == copiedDigest {
+		return true, fmt.Sprintf("destination %s already points to %s", dst.Name(), srcDigest), nil
+	}
+	return false, "", nil
+}
+
+// RelocationAnnotations returns the annotations added to the bundle in the destination to mark the relocation of
+// srcRef as complete. They do not include a time so that copying the same source always results in the same digest
+func (c copyConditions) RelocationAnnotations(srcRef string) (map[string]string, error) {
+	src, err := regname.ParseReference(srcRef)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing source reference: %s", err)
+	}
+	srcDigest, err := c.registry.Digest(src)
+	if err != nil {
+		return nil, fmt.Errorf("Fetching digest of %s: %s", src.Name(), err)
+	}
+	return map[string]string{
+		relocationCompleteAnnotationKey: "true",
+		relocationSourceAnnotationKey:   src.Context().Digest(srcDigest.String()).Name(),
+	}, nil
+}
+
+// relocationSource returns the digest of the source recorded in the annotations of the manifest of dst, and false when
+// dst was not marked as a complete relocation
+func (c copyConditions) relocationSource(dst regname.Reference) (regv1.Hash, bool, error) {
+	desc, err := c.registry.Get(dst)
+	if err != nil {
+		return regv1.Hash{}, false, err
+	}
+	manifest, err := regv1.ParseManifest(bytes.NewReader(desc.Manifest))
+	if err != nil {
+		return regv1.Hash{}, false, fmt.Errorf("Parsing manifest: %s", err)
+	}
+	if manifest.Annotations[relocationCompleteAnnotationKey] != "true" {
+		return regv1.Hash{}, false, nil
+	}
+
+	src, err := regname.NewDigest(manifest.Annotations[relocationSourceAnnotationKey])
+	if err != nil {
+		return regv1.Hash{}, false, fmt.Errorf("Parsing annotation %s: %s", relocationSourceAnnotationKey, err)
+	}
+	srcDigest, err := regv1.NewHash(src.DigestStr())
+	if err != nil {
+		return regv1.Hash{}, false, fmt.Errorf("Parsing annotation %s: %s", relocationSourceAnnotationKey, err)
+	}
+	return srcDigest, true, nil
+}
+
+// references returns the source reference and the location where it would be copied to
+func (c copyConditions) references(srcRef string, repoDst string, dstTag string) (regname.Reference, regname.Reference, error) {
+	src, err := regname.ParseReference(srcRef)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Parsing source reference: %s", err)
+	}
+	dstRepo, err := regname.NewRepository(repoDst)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Building destination repository ref: %s", err)
+	}
+	if dstTag != "" {
+		return src, dstRepo.Tag(dstTag), nil
+	}
+
+	switch ref := src.(type) {
+	case regname.Tag:
+		return src, dstRepo.Tag(ref.TagStr()), nil
+	case regname.Digest:
+		return src, dstRepo.Digest(ref.DigestStr()), nil
+	default:
+		panic(fmt.Sprintf("Unknown reference type %T", src))
+	}
+}
+
+// destinationAbsentStatusCodes Status codes of the responses for a destination that does not exist. Some registries
+// answer 401 or 403, instead of 404, when the repository does not exist
+var destinationAbsentStatusCodes = map[int]struct{}{
+	http.StatusNotFound:     {},
+	http.StatusUnauthorized: {},
+	http.StatusForbidden:    {},
+}
+
+// destinationAbsentErrorCodes Error codes of the registry API for a destination that does not exist, some registries
+// return them with other status codes
+var destinationAbsentErrorCodes = map[transport.ErrorCode]struct{}{
+	transport.ManifestUnknownErrorCode: {},
+	transport.NameUnknownErrorCode:     {},
+}
+
+func (c copyConditions) digestIfExists(ref regname.Reference) (regv1.Hash, bool, error) {
+	digest, err := c.registry.Digest(ref)
+	if err != nil {
+		var terr *transport.Error
+		if errors.As(err, &terr) && isDestinationAbsent(terr) {
+			return regv1.Hash{}, false, nil
+		}
+		return regv1.Hash{}, false, err
+	}
+	return digest, true, nil
+}
+
+func isDestinationAbsent(terr *transport.Error) bool {
+	if _, found := destinationAbsentStatusCodes[terr.StatusCode]; found {
+		return true
+	}
+	for _, diagnostic := range terr.Errors {
+		if _, found := destinationAbsentErrorCodes[diagnostic.Code]; found {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestCopyConditions(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	img, err := random.Image(500, 1)
+	require.NoError(t, err)
+	otherImg, err := random.Image(500, 1)
+	require.NoError(t, err)
+
+	srcImg := fakeRegistry.WithImage("src/img:1.0", img)
+	fakeRegistry.CopyImage(*srcImg, "same/img:1.0")
+	fakeRegistry.WithImage("different/img:1.0", otherImg)
+	reg := fakeRegistry.Build()
+
+	srcRef := fakeRegistry.ReferenceOnTestServer("src/img:1.0")
+
+	t.Run("when no condition is set it never skips", func(t *testing.T) {
+		subject := copyConditions{registry: reg}
+		skip, _, err := subject.ShouldSkip(srcRef, fakeRegistry.ReferenceOnTestServer("same/img"), "")
+		require.NoError(t, err)
+		assert.False(t, skip)
+	})
+
+	t.Run("with --if-destination-absent", func(t *testing.T) {
+		subject := copyConditions{flags: CopyConditionFlags{IfDestinationAbsent: true}, registry: reg}
+
+		skip, reason, err := subject.ShouldSkip(srcRef, fakeRegistry.ReferenceOnTestServer("different/img"), "")
+		require.NoError(t, err)
+		assert.True(t, skip)
+		assert.Contains(t, reason, "already exists")
+
+		skip, _, err = subject.ShouldSkip(srcRef, fakeRegistry.ReferenceOnTestServer("absent/img"), "")
+		require.NoError(t, err)
+		assert.False(t, skip)
+	})
+
+	t.Run("with --if-digest-differs", func(t *testing.T) {
+		subject := copyConditions{flags: CopyConditionFlags{IfDigestDiffers: true}, registry: reg}
+
+		skip, reason, err := subject.ShouldSkip(srcRef, fakeRegistry.ReferenceOnTestServer("same/img"), "")
+		require.NoError(t, err)
+		assert.True(t, skip)
+		assert.Contains(t, reason, srcImg.Digest)
+
+		skip, _, err = subject.ShouldSkip(srcRef, fakeRegistry.ReferenceOnTestServer("different/img"), "")
+		require.NoError(t, err)
+		assert.False(t, skip)
+	})
+}
+
+func TestCopyMarkRelocationComplete(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	fakeRegistry.WithBundleFromPath("some/bundle", "test_assets/bundle").
+		WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+	fakeRegistry.Build()
+
+	srcRef := fakeRegistry.ReferenceOnTestServer("some/bundle")
+	dstRepo := fakeRegistry.ReferenceOnTestServer("relocated/bundle")
+	reg, err := registry.NewSimpleRegistry(registry.Opts{})
+	require.NoError(t, err)
+	subject := copyConditions{flags: CopyConditionFlags{IfDigestDiffers: true, MarkRelocationComplete: true}, registry: reg}
+
+	skip, _, err := subject.ShouldSkip(srcRef, dstRepo, "")
+	require.NoError(t, err)
+	assert.False(t, skip)
+
+	copyOpts := NewCopyOptions(ui.NewWriterUI(&bytes.Buffer{}, &bytes.Buffer{}, ui.NewNoopLogger()))
+	copyOpts.BundleFlags = BundleFlags{Bundle: srcRef}
+	copyOpts.RepoDst = dstRepo
+	copyOpts.ConditionFlags = CopyConditionFlags{IfDigestDiffers: true, MarkRelocationComplete: true}
+	copyOpts.Concurrency = 1
+	require.NoError(t, copyOpts.Run())
+
+	t.Run("annotates the bundle in the destination with its source", func(t *testing.T) {
+		src, err := regname.ParseReference(srcRef)
+		require.NoError(t, err)
+		srcDigest, err := reg.Digest(src)
+		require.NoError(t, err)
+
+		dst, err := regname.ParseReference(dstRepo + ":latest")
+		require.NoError(t, err)
+		desc, err := reg.Get(dst)
+		require.NoError(t, err)
+		manifest, err := regv1.ParseManifest(bytes.NewReader(desc.Manifest))
+		require.NoError(t, err)
+		assert.Equal(t, "true", manifest.Annotations[relocationCompleteAnnotationKey])
+		assert.Equal(t, src.Context().Digest(srcDigest.String()).Name(), manifest.Annotations[relocationSourceAnnotationKey])
+	})
+
+	t.Run("skips the copy once the destination was marked", func(t *testing.T) {
+		skip, reason, err := subject.ShouldSkip(srcRef, dstRepo, "")
+		require.NoError(t, err)
+		assert.True(t, skip)
+		assert.Contains(t, reason, "already points to")
+	})
+}
+
+func TestCopyConditionsWithToTag(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	fakeRegistry.WithBundleFromPath("some/bundle:v1", "test_assets/bundle").
+		WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+	fakeRegistry.Build()
+
+	// copyWithToTag returns the reason the copy was skipped, empty when the bundle was copied
+	copyWithToTag := func() string {
+		copyOpts := NewCopyOptions(ui.NewWriterUI(&bytes.Buffer{}, &bytes.Buffer{}, ui.NewNoopLogger()))
+		copyOpts.BundleFlags = BundleFlags{Bundle: fakeRegistry.ReferenceOnTestServer("some/bundle:v1")}
+		copyOpts.RepoDst = fakeRegistry.ReferenceOnTestServer("relocated/bundle")
+		copyOpts.TarFlags = TarFlags{ToTag: "build-1"}
+		copyOpts.ConditionFlags = CopyConditionFlags{IfDigestDiffers: true}
+		copyOpts.Concurrency = 1
+		require.NoError(t, copyOpts.Run())
+		if copyOpts.result == nil {
+			return ""
+		}
+		return copyOpts.result.Skipped
+	}
+
+	t.Run("copies when the --to-tag tag is absent, even if the source tag is present in the destination", func(t *testing.T) {
+		reg, err := registry.NewSimpleRegistry(registry.Opts{})
+		require.NoError(t, err)
+		src, err := regname.ParseReference(fakeRegistry.ReferenceOnTestServer("some/bundle:v1"))
+		require.NoError(t, err)
+		img, err := reg.Image(src)
+		require.NoError(t, err)
+		dst, err := regname.NewTag(fakeRegistry.ReferenceOnTestServer("relocated/bundle:v1"))
+		require.NoError(t, err)
+		require.NoError(t, reg.WriteImage(dst, img, nil))
+
+		assert.Empty(t, copyWithToTag())
+	})
+
+	t.Run("skips when the --to-tag tag already points to the source", func(t *testing.T) {
+		assert.Contains(t, copyWithToTag(), "relocated/bundle:build-1")
+	})
+}
+
+func TestCopyConditionsDestinationAbsent(t *testing.T) {
+	testCases := []struct {
+		name       string
+		statusCode int
+		errorCode  transport.ErrorCode
+		absent     bool
+	}{
+		{name: "not found", statusCode: http.StatusNotFound, absent: true},
+		{name: "unauthorized", statusCode: http.StatusUnauthorized, absent: true},
+		{name: "forbidden", statusCode: http.StatusForbidden, absent: true},
+		{name: "manifest unknown error code", statusCode: http.StatusBadRequest, errorCode: transport.ManifestUnknownErrorCode, absent: true},
+		{name: "name unknown error code", statusCode: http.StatusBadRequest, errorCode: transport.NameUnknownErrorCode, absent: true},
+		{name: "other error code", statusCode: http.StatusBadRequest, errorCode: transport.UnsupportedErrorCode, absent: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/v2/" {
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.statusCode)
+				if tc.errorCode != "" && r.Method != http.MethodHead {
+					fmt.Fprintf(w, `{"errors":[{"code":"%s","message":"some message"}]}`, tc.errorCode)
+				}
+			}))
+			defer server.Close()
+
+			reg, err := registry.NewSimpleRegistry(registry.Opts{RetryCount: 1})
+			require.NoError(t, err)
+			subject := copyConditions{registry: reg}
+
+			ref, err := regname.ParseReference(strings.TrimPrefix(server.URL, "http://") + "/some/img:1.0")
+			require.NoError(t, err)
+			_, found, err := subject.digestIfExists(ref)
+			if tc.absent {
+				require.NoError(t, err)
+				assert.False(t, found)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
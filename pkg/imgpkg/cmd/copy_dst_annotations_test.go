@@ -0,0 +1,95 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestCopyDstAnnotations(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	bundleInfo := fakeRegistry.WithBundleFromPath("some/bundle", "test_assets/bundle").
+		WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+	fakeRegistry.Build()
+
+	copyBundle := func(t *testing.T, dstRepo string, annotations map[string]string) copiedImage {
+		stdout := &bytes.Buffer{}
+		copyOpts := NewCopyOptions(ui.NewWriterUI(stdout, &bytes.Buffer{}, ui.NewNoopLogger()))
+		copyOpts.BundleFlags = BundleFlags{Bundle: bundleInfo.RefDigest}
+		copyOpts.RepoDst = fakeRegistry.ReferenceOnTestServer(dstRepo)
+		copyOpts.Concurrency = 1
+		copyOpts.DstAnnotations = annotations
+		copyOpts.OutputTypeFlags = OutputTypeFlags{OutputType: jsonOutputType}
+		require.NoError(t, copyOpts.Run())
+
+		var result copyResult
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &result))
+		for _, img := range result.Images {
+			if img.RootBundle {
+				return img
+			}
+		}
+		require.FailNow(t, "expected the result to contain the bundle")
+		return copiedImage{}
+	}
+
+	describe := func(t *testing.T, bundleRef string) v1.Description {
+		registryFlags := RegistryFlags{}
+		description, err := v1.Describe(bundleRef, v1.DescribeOpts{Logger: util.NewNoopLevelLogger(), Concurrency: 1}, registryFlags.AsRegistryOpts())
+		require.NoError(t, err)
+		return description
+	}
+
+	t.Run("adds the annotations to the bundle in the destination without changing its images", func(t *testing.T) {
+		bundle := copyBundle(t, "annotated/bundle", map[string]string{"environment": "production"})
+
+		dstRef, err := regname.NewDigest(bundle.Destination)
+		require.NoError(t, err)
+		assert.NotEqual(t, bundleInfo.Digest, dstRef.DigestStr())
+
+		manifest, err := remote.Get(dstRef)
+		require.NoError(t, err)
+		img, err := manifest.Image()
+		require.NoError(t, err)
+		rawManifest, err := img.Manifest()
+		require.NoError(t, err)
+		assert.Equal(t, "production", rawManifest.Annotations["environment"])
+
+		description := describe(t, bundle.Destination)
+		original := describe(t, bundleInfo.RefDigest)
+		for digest, img := range original.Content.Images {
+			copiedImg, found := description.Content.Images[digest]
+			require.True(t, found, "image %s", digest)
+			assert.Equal(t, img.ImageType, copiedImg.ImageType)
+		}
+	})
+
+	t.Run("keeps the bundle digest when no annotations are provided", func(t *testing.T) {
+		bundle := copyBundle(t, "not-annotated/bundle", nil)
+
+		dstRef, err := regname.NewDigest(bundle.Destination)
+		require.NoError(t, err)
+		assert.Equal(t, bundleInfo.Digest, dstRef.DigestStr())
+	})
+
+	t.Run("fails when copying to a tar", func(t *testing.T) {
+		copyOpts := NewCopyOptions(ui.NewConfUI(ui.NewNoopLogger()))
+		copyOpts.BundleFlags = BundleFlags{Bundle: bundleInfo.RefDigest}
+		copyOpts.TarFlags = TarFlags{TarDst: "/tmp/bundle.tar"}
+		copyOpts.DstAnnotations = map[string]string{"environment": "production"}
+		require.ErrorContains(t, copyOpts.Run(), "Cannot use --dst-annotation with tar destination")
+	})
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+// serveFileURLs Replaces the sources and destination addressed by file:// URLs with repositories served from the
+// OCI Image Layout folders they point to, so that the rest of the copy treats them like any other repository.
+// The returned function stops the servers and must be called once the copy finishes
+func (c *CopyOptions) serveFileURLs() (func(), error) {
+	var servers []*registry.OCILayoutServer
+	closeServers := func() {
+		for _, server := range servers {
+			server.Close()
+		}
+	}
+
+	serve := func(path string, readOnly bool) (string, error) {
+		server := registry.NewOCILayoutServer(path, readOnly)
+		err := server.Start()
+		if err != nil {
+			return "", err
+		}
+		servers = append(servers, server)
+		return server.Repository(), nil
+	}
+
+	srcs := []struct {
+		flag string
+		ref  *string
+	}{
+		{"--bundle (-b)", &c.BundleFlags.Bundle},
+		{"--image (-i)", &c.ImageFlags.Image},
+	}
+	for _, src := range srcs {
+		path, identifier, isFileURL, err := registry.ParseFileURL(*src.ref)
+		if err != nil {
+			closeServers()
+			return nil, fmt.Errorf("Parsing %s: %s", src.flag, err)
+		}
+		if !isFileURL {
+			continue
+		}
+		if identifier == "" {
+			closeServers()
+			return nil, fmt.Errorf("Expected %s to include a tag or digest of the image in the OCI image layout, e.g. file:///mnt/usb/app:1.0", src.flag)
+		}
+		repo, err := serve(path, true)
+		if err != nil {
+			closeServers()
+			return nil, err
+		}
+		*src.ref = repo + identifier
+	}
+
+	path, identifier, isFileURL, err := registry.ParseFileURL(c.RepoDst)
+	if err != nil {
+		closeServers()
+		return nil, fmt.Errorf("Parsing --to-repo: %s", err)
+	}
+	if isFileURL {
+		if identifier != "" {
+			closeServers()
+			return nil, fmt.Errorf("Expected --to-repo to not include a tag or digest, use --to-tag to tag the copied bundle or image")
+		}
+		c.RepoDst, err = serve(path, false)
+		if err != nil {
+			closeServers()
+			return nil, err
+		}
+	}
+
+	return closeServers, nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestCopyFileURLs(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	bundleInfo := fakeRegistry.WithBundleFromPath("some/bundle", "test_assets/bundle").
+		WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+	fakeRegistry.Build()
+
+	layoutPath := filepath.Join(t.TempDir(), "usb", "app-bundle")
+
+	copyOpts := NewCopyOptions(ui.NewWriterUI(&bytes.Buffer{}, &bytes.Buffer{}, ui.NewNoopLogger()))
+	copyOpts.BundleFlags = BundleFlags{Bundle: bundleInfo.RefDigest}
+	copyOpts.RepoDst = "file://" + layoutPath
+	copyOpts.TarFlags.ToTag = "1.0"
+	copyOpts.Concurrency = 1
+	require.NoError(t, copyOpts.Run())
+
+	path, err := layout.FromPath(layoutPath)
+	require.NoError(t, err)
+	index, err := path.ImageIndex()
+	require.NoError(t, err)
+	indexManifest, err := index.IndexManifest()
+	require.NoError(t, err)
+	tags := map[string]string{}
+	for _, desc := range indexManifest.Manifests {
+		tags[desc.Annotations["org.opencontainers.image.ref.name"]] = desc.Digest.String()
+	}
+	assert.Equal(t, bundleInfo.Digest, tags["1.0"])
+
+	copyOpts = NewCopyOptions(ui.NewWriterUI(&bytes.Buffer{}, &bytes.Buffer{}, ui.NewNoopLogger()))
+	copyOpts.BundleFlags = BundleFlags{Bundle: "file://" + layoutPath + ":1.0"}
+	copyOpts.RepoDst = fakeRegistry.ReferenceOnTestServer("copied/bundle")
+	copyOpts.Concurrency = 1
+	require.NoError(t, copyOpts.Run())
+
+	registryFlags := RegistryFlags{}
+	description, err := v1.Describe(fakeRegistry.ReferenceOnTestServer("copied/bundle")+"@"+bundleInfo.Digest,
+		v1.DescribeOpts{Logger: util.NewNoopLevelLogger(), Concurrency: 1}, registryFlags.AsRegistryOpts())
+	require.NoError(t, err)
+	original, err := v1.Describe(bundleInfo.RefDigest,
+		v1.DescribeOpts{Logger: util.NewNoopLevelLogger(), Concurrency: 1}, registryFlags.AsRegistryOpts())
+	require.NoError(t, err)
+	for digest := range original.Content.Images {
+		assert.Contains(t, description.Content.Images, digest)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+	"sigs.k8s.io/yaml"
+)
+
+func TestCopyStructuredOutput(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	imageInfo := fakeRegistry.WithRandomImage("some/image")
+	fakeRegistry.Build()
+	// Blobs are mounted, instead of uploaded, when copying within the same registry
+	dstRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer dstRegistry.CleanUp()
+	dstRegistry.Build()
+
+	runCopy := func(t *testing.T, outputType string) copyResult {
+		stdout := &bytes.Buffer{}
+		copyOpts := NewCopyOptions(ui.NewWriterUI(stdout, &bytes.Buffer{}, ui.NewNoopLogger()))
+		copyOpts.ImageFlags = ImageFlags{Image: imageInfo.RefDigest}
+		copyOpts.RepoDst = dstRegistry.ReferenceOnTestServer("copied/image")
+		copyOpts.Concurrency = 1
+		copyOpts.OutputTypeFlags = OutputTypeFlags{OutputType: outputType, supportsYAML: true}
+		require.NoError(t, copyOpts.Run())
+
+		var result copyResult
+		if outputType == yamlOutputType {
+			require.NoError(t, yaml.Unmarshal(stdout.Bytes(), &result))
+		} else {
+			require.NoError(t, json.Unmarshal(stdout.Bytes(), &result))
+		}
+		return result
+	}
+
+	t.Run("reports the blobs uploaded by the copy", func(t *testing.T) {
+		result := runCopy(t, yamlOutputType)
+		require.Len(t, result.Images, 1)
+		assert.Equal(t, imageInfo.RefDigest, result.Images[0].Source)
+
+		// The config and the 3 layers of the image
+		require.Len(t, result.Images[0].Blobs, 4)
+		var size int64
+		for _, blob := range result.Images[0].Blobs {
+			assert.Equal(t, blobUploadedStatus, blob.Status, blob.Digest)
+			size += blob.Size
+		}
+		assert.Equal(t, size, result.Images[0].Size)
+	})
+
+	t.Run("reports the blobs already present in the destination as skipped", func(t *testing.T) {
+		result := runCopy(t, jsonOutputType)
+		require.Len(t, result.Images, 1)
+		require.Len(t, result.Images[0].Blobs, 4)
+		for _, blob := range result.Images[0].Blobs {
+			assert.Equal(t, blobSkippedStatus, blob.Status, blob.Digest)
+		}
+	})
+}
+
+func TestCopyStructuredOutputWithRegistryMirror(t *testing.T) {
+	originRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer originRegistry.CleanUp()
+	originRegistry.Build()
+	mirrorRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer mirrorRegistry.CleanUp()
+	imageInfo := mirrorRegistry.WithRandomImage("some/image")
+	mirrorRegistry.Build()
+	dstRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer dstRegistry.CleanUp()
+	dstRegistry.Build()
+
+	srcRef := originRegistry.ReferenceOnTestServer("some/image@" + imageInfo.Digest)
+
+	stdout := &bytes.Buffer{}
+	copyOpts := NewCopyOptions(ui.NewWriterUI(stdout, &bytes.Buffer{}, ui.NewNoopLogger()))
+	copyOpts.ImageFlags = ImageFlags{Image: srcRef}
+	copyOpts.RepoDst = dstRegistry.ReferenceOnTestServer("copied/image")
+	copyOpts.Concurrency = 1
+	copyOpts.RegistryMirrors = []string{originRegistry.Host() + "=" + mirrorRegistry.Host()}
+	copyOpts.OutputTypeFlags = OutputTypeFlags{OutputType: jsonOutputType, supportsYAML: true}
+	require.NoError(t, copyOpts.Run())
+
+	var result copyResult
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &result))
+	require.Len(t, result.Images, 1)
+	assert.Equal(t, srcRef, result.Images[0].Source)
+	assert.Equal(t, mirrorRegistry.Host(), result.Images[0].Mirror)
+}
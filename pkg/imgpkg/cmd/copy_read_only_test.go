@@ -0,0 +1,30 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestCopyAssertSourceReadOnly(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	bundleInfo := fakeRegistry.WithBundleFromPath("some/bundle", "test_assets/bundle").
+		WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+	fakeRegistry.Build()
+
+	t.Run("copies to a repository of the registry of the source, that is only read", func(t *testing.T) {
+		copyOpts := NewCopyOptions(ui.NewWriterUI(&bytes.Buffer{}, &bytes.Buffer{}, ui.NewNoopLogger()))
+		copyOpts.BundleFlags = BundleFlags{Bundle: bundleInfo.RefDigest}
+		copyOpts.RepoDst = fakeRegistry.ReferenceOnTestServer("relocated/bundle")
+		copyOpts.RegistryFlags = RegistryFlags{AssertSourceReadOnly: true}
+		copyOpts.Concurrency = 1
+		require.NoError(t, copyOpts.Run())
+	})
+}
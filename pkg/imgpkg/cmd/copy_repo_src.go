@@ -5,8 +5,12 @@ package cmd
 
 import (
 	"fmt"
+	"net/http"
 
 	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	ctlbundle "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
 	ctlimgset "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
@@ -15,25 +19,49 @@ import (
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/plainimage"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/signature"
+	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/workspace"
+	"golang.org/x/sync/errgroup"
 )
 
 type SignatureRetriever interface {
 	Fetch(images *imageset.UnprocessedImageRefs) (*imageset.UnprocessedImageRefs, error)
 }
 
+// ImagesVerifier Verifies the images before they are copied
+type ImagesVerifier interface {
+	VerifyImages(images *imageset.UnprocessedImageRefs) error
+	Verify(imageRef regname.Digest) error
+	VerifyLevels(levels [][]regname.Digest) ([]signature.LevelResult, error)
+}
+
 type CopyRepoSrc struct {
 	ImageFlags              ImageFlags
 	BundleFlags             BundleFlags
 	LockInputFlags          LockInputFlags
+	ImageRefs               []string
+	ImageRefsCanBeBundles   bool
 	TarFlags                TarFlags
 	IncludeNonDistributable bool
 	Concurrency             int
+	LocationsRepo           string
+	// DstAnnotations when present are added to the manifest of the root bundle in the destination
+	DstAnnotations map[string]string
+	// Force moves the tag of the root bundle in the destination without checking the health of the copied bundle,
+	// once the user confirms it
+	Force bool
+	// ImageFilterFlags select the images of the ImagesLock that are copied, the bundles are always copied
+	ImageFilterFlags ImageFilterFlags
 
 	logger             util.LoggerWithLevels
 	imageSet           ctlimgset.ImageSet
 	tarImageSet        ctlimgset.TarImageSet
 	registry           registry.ImagesReaderWriter
 	signatureRetriever SignatureRetriever
+	imagesVerifier     ImagesVerifier
+	workspace          *workspace.Workspace
+	confirmation       confirmation
 }
 
 // CopyToTar copies image or bundle into the provided path
@@ -57,6 +85,28 @@ func (c CopyRepoSrc) CopyToTar(dstPath string, resume bool) error {
 	return nil
 }
 
+// CopyToArtifactoryImport copies image or bundle into a folder that can be imported by Artifactory
+func (c CopyRepoSrc) CopyToArtifactoryImport(dstPath string) error {
+	c.logger.Tracef("CopyToArtifactoryImport\n")
+
+	unprocessedImageRefs, _, err := c.getAllSourceImages()
+	if err != nil {
+		return err
+	}
+
+	c.logger.Tracef("Exporting images to Artifactory import folder\n")
+	store := imagetar.NewArtifactoryImportBlobStore(dstPath, c.Concurrency, c.logger)
+	ids, err := c.tarImageSet.ExportToBlobStore(unprocessedImageRefs, store, c.registry, imagetar.NewImageLayerWriterCheck(c.IncludeNonDistributable))
+	if err != nil {
+		return err
+	}
+
+	informUserToUseTheNonDistributableFlagWithDescriptors(
+		c.logger, c.IncludeNonDistributable, getNonDistributableLayersFromImageDescriptors(ids))
+
+	return nil
+}
+
 func (c CopyRepoSrc) CopyToRepo(repo string) (*ctlimgset.ProcessedImages, error) {
 	c.logger.Tracef("CopyToRepo(%s)\n", repo)
 
@@ -71,7 +121,12 @@ func (c CopyRepoSrc) CopyToRepo(repo string) (*ctlimgset.ProcessedImages, error)
 			return nil, fmt.Errorf("Cannot use tar source (--tar) with tar destination (--to-tar)")
 		}
 
-		processedImages, err = c.tarImageSet.Import(c.TarFlags.TarSrc, importRepo, c.registry)
+		tarImageSet := c.tarImageSet
+		if c.TarFlags.FallbackToOrigin {
+			tarImageSet = tarImageSet.WithFallbackToOrigin()
+		}
+
+		processedImages, err = tarImageSet.Import(c.TarFlags.TarSrc, importRepo, c.registry)
 		if err != nil {
 			return nil, err
 		}
@@ -95,13 +150,19 @@ func (c CopyRepoSrc) CopyToRepo(repo string) (*ctlimgset.ProcessedImages, error)
 		}
 
 		if foundRootBundle {
-			bundles, _, err := parentBundle.AllImagesLockRefs(c.Concurrency, c.logger)
+			bundles, imageRefs, err := parentBundle.AllImagesLockRefs(c.Concurrency, c.logger)
 			if err != nil {
 				return nil, err
 			}
 
+			// Images that are not in the tar were excluded when it was created
+			excludedImages := missingImages(imageRefs, processedImages)
+			for _, image := range excludedImages {
+				c.logger.Logf("Image %s is not in the tar, recording it as excluded\n", image)
+			}
+
 			for _, bundle := range bundles {
-				if err := bundle.NoteCopy(processedImages, c.registry, c.logger); err != nil {
+				if err := bundle.WithWorkspace(c.workspace).WithExcludedImages(excludedImages).NoteCopy(processedImages, c.registry, c.logger); err != nil {
 					return nil, fmt.Errorf("Creating copy information for bundle %s: %s", bundle.DigestRef(), err)
 				}
 			}
@@ -118,15 +179,34 @@ func (c CopyRepoSrc) CopyToRepo(repo string) (*ctlimgset.ProcessedImages, error)
 		}
 
 		for _, bundle := range bundles {
-			if err := bundle.NoteCopy(processedImages, c.registry, c.logger); err != nil {
+			if err := bundle.WithWorkspace(c.workspace).NoteCopy(processedImages, c.registry, c.logger); err != nil {
 				return nil, fmt.Errorf("Creating copy information for bundle %s: %s", bundle.DigestRef(), err)
 			}
 		}
 	}
 
+	if c.TarFlags.ToTag != "" {
+		processedImages, err = c.retagRootBundle(processedImages, c.TarFlags.ToTag)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(c.DstAnnotations) > 0 {
+		processedImages, err = c.annotateRootBundle(processedImages)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	informUserToUseTheNonDistributableFlagWithDescriptors(
 		c.logger, c.IncludeNonDistributable, processedImagesNonDistLayer(processedImages))
 
+	err = c.checkRootBundleBeforeRetag(processedImages)
+	if err != nil {
+		return nil, err
+	}
+
 	c.logger.Logf("Tagging images\n")
 	err = c.tagAllImages(processedImages)
 	if err != nil {
@@ -142,6 +222,19 @@ func (c CopyRepoSrc) getAllSourceImages() (*ctlimgset.UnprocessedImageRefs, []*c
 		return nil, nil, err
 	}
 
+	if c.imagesVerifier != nil {
+		c.logger.Debugf("Verifying signatures\n")
+
+		if rootBundle := c.rootBundle(unprocessedImageRefs, bundles); rootBundle != nil {
+			err = c.verifyBundleSignatures(rootBundle, bundles)
+		} else {
+			err = c.imagesVerifier.VerifyImages(unprocessedImageRefs)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	c.logger.Debugf("Fetching signatures\n")
 
 	signatures, err := c.signatureRetriever.Fetch(unprocessedImageRefs)
@@ -156,6 +249,35 @@ func (c CopyRepoSrc) getAllSourceImages() (*ctlimgset.UnprocessedImageRefs, []*c
 	return unprocessedImageRefs, bundles, nil
 }
 
+// rootBundle Returns the bundle being copied, nil when the copy is not of a single bundle
+func (c CopyRepoSrc) rootBundle(unprocessedImageRefs *ctlimgset.UnprocessedImageRefs, bundles []*ctlbundle.Bundle) *ctlbundle.Bundle {
+	for _, img := range unprocessedImageRefs.All() {
+		if _, ok := img.Labels[rootBundleLabelKey]; !ok {
+			continue
+		}
+		for _, b := range bundles {
+			if b.DigestRef() == img.DigestRef {
+				return b
+			}
+		}
+	}
+	return nil
+}
+
+// verifyBundleSignatures Verifies the signature of the bundle and then the signatures of its images and nested bundles,
+// reporting the outcome of each nesting level
+func (c CopyRepoSrc) verifyBundleSignatures(rootBundle *ctlbundle.Bundle, bundles []*ctlbundle.Bundle) error {
+	rootRef, err := regname.NewDigest(rootBundle.DigestRef())
+	if err != nil {
+		return fmt.Errorf("Parsing '%s': %s", rootBundle.DigestRef(), err)
+	}
+	err = c.imagesVerifier.Verify(rootRef)
+	if err != nil {
+		return err
+	}
+	return rootBundle.VerifyNestedSignatures(bundles, c.imagesVerifier, c.logger)
+}
+
 func (c CopyRepoSrc) getProvidedSourceImages() (*ctlimgset.UnprocessedImageRefs, []*ctlbundle.Bundle, error) {
 	unprocessedImageRefs := ctlimgset.NewUnprocessedImageRefs()
 	switch {
@@ -173,7 +295,7 @@ func (c CopyRepoSrc) getProvidedSourceImages() (*ctlimgset.UnprocessedImageRefs,
 				return nil, nil, err
 			}
 
-			for _, img := range imagesRef.ImageRefs() {
+			for _, img := range c.filterImageRefs(imagesRef.ImageRefs(), bundles) {
 				unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: img.PrimaryLocation()})
 			}
 
@@ -190,6 +312,10 @@ func (c CopyRepoSrc) getProvidedSourceImages() (*ctlimgset.UnprocessedImageRefs,
 		case imagesLock != nil:
 			c.logger.Tracef("get images from ImagesLock file\n")
 			for _, img := range imagesLock.Images {
+				if c.ImageFilterFlags.Excludes(img.Image) {
+					c.logger.Logf("Excluding image %s\n", img.Image)
+					continue
+				}
 				plainImg := plainimage.NewPlainImage(img.Image, c.registry)
 
 				ok, err := ctlbundle.NewBundleFromPlainImage(plainImg, c.registry).IsBundle()
@@ -208,6 +334,36 @@ func (c CopyRepoSrc) getProvidedSourceImages() (*ctlimgset.UnprocessedImageRefs,
 			panic("Unreachable")
 		}
 
+	case len(c.ImageRefs) > 0:
+		c.logger.Tracef("get images from list of images\n")
+		var allBundles []*ctlbundle.Bundle
+		for _, imageRef := range c.ImageRefs {
+			plainImg := plainimage.NewPlainImage(imageRef, c.registry)
+
+			ok, err := ctlbundle.NewBundleFromPlainImage(plainImg, c.registry).IsBundle()
+			if err != nil {
+				return nil, nil, err
+			}
+			if !ok {
+				unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: plainImg.DigestRef(), Tag: plainImg.Tag()})
+				continue
+			}
+			if !c.ImageRefsCanBeBundles {
+				return nil, nil, fmt.Errorf("Unable to copy bundle '%s' using an images file (hint: Use -b to copy bundles)", imageRef)
+			}
+
+			bundle, bundles, imagesRef, err := c.getBundleImageRefs(imageRef)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, img := range imagesRef.ImageRefs() {
+				unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: img.PrimaryLocation(), OrigRef: img.Image})
+			}
+			unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: bundle.DigestRef(), Tag: bundle.Tag(), OrigRef: bundle.DigestRef()})
+			allBundles = append(allBundles, bundles...)
+		}
+		return unprocessedImageRefs, allBundles, nil
+
 	case c.ImageFlags.Image != "":
 		c.logger.Tracef("copy single image\n")
 		plainImg := plainimage.NewPlainImage(c.ImageFlags.Image, c.registry)
@@ -230,7 +386,7 @@ func (c CopyRepoSrc) getProvidedSourceImages() (*ctlimgset.UnprocessedImageRefs,
 			return nil, nil, err
 		}
 
-		for _, img := range imagesRef.ImageRefs() {
+		for _, img := range c.filterImageRefs(imagesRef.ImageRefs(), allBundles) {
 			unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: img.PrimaryLocation(), OrigRef: img.Image})
 		}
 
@@ -246,9 +402,65 @@ func (c CopyRepoSrc) getProvidedSourceImages() (*ctlimgset.UnprocessedImageRefs,
 	}
 }
 
+// filterImageRefs Returns the images that are copied according to the filters, the excluded images are recorded in the
+// bundles so that they are noted as excluded in the locations of the copied bundles
+func (c CopyRepoSrc) filterImageRefs(imageRefs []ctlbundle.ImageRef, bundles []*ctlbundle.Bundle) []ctlbundle.ImageRef {
+	if !c.ImageFilterFlags.IsSet() {
+		return imageRefs
+	}
+
+	var included []ctlbundle.ImageRef
+	var excluded []string
+	for _, img := range imageRefs {
+		if (img.IsBundle == nil || !*img.IsBundle) && c.ImageFilterFlags.Excludes(img.Image) {
+			c.logger.Logf("Excluding image %s\n", img.Image)
+			excluded = append(excluded, img.Image)
+			continue
+		}
+		included = append(included, img)
+	}
+
+	for _, bundle := range bundles {
+		bundle.WithExcludedImages(excluded)
+	}
+	return included
+}
+
+// missingImages Returns the images, as in the ImagesLock, that are not part of the processed images, e.g. images excluded
+// when the tar was created
+func missingImages(imageRefs ctlbundle.ImageRefs, processedImages *ctlimgset.ProcessedImages) []string {
+	processedRefs := map[string]struct{}{}
+	for _, processedImage := range processedImages.All() {
+		processedRefs[processedImage.UnprocessedImageRef.DigestRef] = struct{}{}
+	}
+
+	var missing []string
+	for _, img := range imageRefs.ImageRefs() {
+		found := false
+		for _, location := range img.Locations() {
+			if _, ok := processedRefs[location]; ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, img.Image)
+		}
+	}
+	return missing
+}
+
 func (c CopyRepoSrc) getBundleImageRefs(bundleRef string) (*ctlbundle.Bundle, []*ctlbundle.Bundle, ctlbundle.ImageRefs, error) {
 	lockReader := ctlbundle.NewImagesLockReader()
 	bundle := ctlbundle.NewBundleFromRef(bundleRef, c.registry, lockReader, ctlbundle.NewRegistryFetcher(c.registry, lockReader))
+	if c.LocationsRepo != "" {
+		locationsRepo, err := regname.NewRepository(c.LocationsRepo)
+		if err != nil {
+			return nil, nil, ctlbundle.ImageRefs{}, fmt.Errorf("Parsing locations repository '%s': %s", c.LocationsRepo, err)
+		}
+		bundle.WithLocationsRepo(locationsRepo)
+	}
+
 	isBundle, err := bundle.IsBundle()
 	if err != nil {
 		return nil, nil, ctlbundle.ImageRefs{}, err
@@ -264,6 +476,176 @@ func (c CopyRepoSrc) getBundleImageRefs(bundleRef string) (*ctlbundle.Bundle, []
 	return bundle, nestedBundles, imageRefs, nil
 }
 
+// retagRootBundle replaces the tag recorded in the tar for the root bundle with the provided tag
+func (c CopyRepoSrc) retagRootBundle(processedImages *ctlimgset.ProcessedImages, tag string) (*ctlimgset.ProcessedImages, error) {
+	result := ctlimgset.NewProcessedImages()
+	foundRootBundle := false
+	for _, processedImage := range processedImages.All() {
+		if _, ok := processedImage.Labels[rootBundleLabelKey]; ok && processedImage.ImageIndex == nil {
+			foundRootBundle = true
+			processedImage.Tag = tag
+		}
+		result.Add(processedImage)
+	}
+
+	if !foundRootBundle {
+		return nil, fmt.Errorf("Expected a bundle to be copied when using --to-tag")
+	}
+	return result, nil
+}
+
+// annotateRootBundle Adds the annotations to the manifest of the root bundle in the destination.
+// The images of the bundle keep their digests, only the digest of the bundle changes
+func (c CopyRepoSrc) annotateRootBundle(processedImages *ctlimgset.ProcessedImages) (*ctlimgset.ProcessedImages, error) {
+	result := ctlimgset.NewProcessedImages()
+	foundRootBundle := false
+	for _, processedImage := range processedImages.All() {
+		if _, ok := processedImage.Labels[rootBundleLabelKey]; ok && processedImage.ImageIndex == nil {
+			foundRootBundle = true
+			annotatedImage, err := c.annotateBundle(processedImage)
+			if err != nil {
+				return nil, err
+			}
+			processedImage = annotatedImage
+		}
+		result.Add(processedImage)
+	}
+
+	if !foundRootBundle {
+		return nil, fmt.Errorf("Expected to copy a bundle when using --dst-annotation")
+	}
+	return result, nil
+}
+
+func (c CopyRepoSrc) annotateBundle(processedImage ctlimgset.ProcessedImage) (ctlimgset.ProcessedImage, error) {
+	bundleRef, err := regname.NewDigest(processedImage.DigestRef)
+	if err != nil {
+		panic(fmt.Sprintf("Internal consistency: %s should be a digest", processedImage.DigestRef))
+	}
+
+	annotated := mutate.Annotations(processedImage.Image, c.DstAnnotations).(regv1.Image)
+	digest, err := annotated.Digest()
+	if err != nil {
+		return ctlimgset.ProcessedImage{}, err
+	}
+	annotatedRef := bundleRef.Context().Digest(digest.String())
+
+	c.logger.Logf("Annotating bundle %s as %s\n", bundleRef.Name(), annotatedRef.Name())
+	err = c.registry.WriteImage(annotatedRef, annotated, nil)
+	if err != nil {
+		return ctlimgset.ProcessedImage{}, fmt.Errorf("Writing annotated bundle %s: %s", annotatedRef.Name(), err)
+	}
+
+	// The locations of the images were recorded for the bundle as copied, the annotated bundle needs them as well
+	locations := ctlbundle.NewLocations(c.logger).WithWorkspace(c.workspace)
+	locationsCfg, err := locations.Fetch(c.registry, bundleRef)
+	if err != nil {
+		return ctlimgset.ProcessedImage{}, fmt.Errorf("Fetching locations of bundle %s: %s", bundleRef.Name(), err)
+	}
+	err = locations.Save(c.registry, annotatedRef, locationsCfg, util.NewNoopLevelLogger())
+	if err != nil {
+		return ctlimgset.ProcessedImage{}, fmt.Errorf("Creating copy information for bundle %s: %s", annotatedRef.Name(), err)
+	}
+
+	processedImage.DigestRef = annotatedRef.Name()
+	processedImage.Image = annotated
+	return processedImage, nil
+}
+
+// checkRootBundleBeforeRetag When the tag of the root bundle already points to a different bundle in the destination,
+// checks that the copied bundle can be described and all its images resolved before the tag is moved, so that a broken
+// bundle does not replace a working one. With --force the check is skipped once the user confirms moving the tag
+func (c CopyRepoSrc) checkRootBundleBeforeRetag(processedImages *ctlimgset.ProcessedImages) error {
+	for _, item := range processedImages.All() {
+		if _, ok := item.Labels[rootBundleLabelKey]; !ok || item.ImageIndex != nil || item.Tag == "" {
+			continue
+		}
+
+		bundleRef, err := regname.NewDigest(item.DigestRef)
+		if err != nil {
+			panic(fmt.Sprintf("Internal consistency: %s should be a digest", item.DigestRef))
+		}
+		tagRef := bundleRef.Tag(item.Tag)
+
+		currentDigest, err := c.registry.Digest(tagRef)
+		if err != nil {
+			if terr, ok := err.(*transport.Error); ok && terr.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return fmt.Errorf("Checking tag %s in the destination: %s", tagRef.Name(), err)
+		}
+		if currentDigest.String() == bundleRef.DigestStr() {
+			continue
+		}
+
+		if c.Force {
+			err = c.confirmation.Confirm(fmt.Sprintf("move tag %s from %s to %s, skipping the health check of the copied bundle", tagRef.Name(), currentDigest, bundleRef.DigestStr()))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		c.logger.Logf("Tag %s points to %s, checking bundle %s before moving it\n", tagRef.Name(), currentDigest, bundleRef.Name())
+		err = c.checkBundleHealth(bundleRef)
+		if err != nil {
+			return fmt.Errorf("Keeping tag %s pointing to %s, the copied bundle failed the health check: %s (hint: use --force to move the tag anyway)", tagRef.Name(), currentDigest, err)
+		}
+	}
+	return nil
+}
+
+// checkBundleHealth Describes the bundle and checks that every image of the bundle and its nested bundles can be resolved
+func (c CopyRepoSrc) checkBundleHealth(bundleRef regname.Digest) error {
+	description, err := v1.DescribeWithRegistryAndSignatureFetcher(bundleRef.Name(),
+		v1.DescribeOpts{Logger: c.logger, Concurrency: c.Concurrency}, c.registry, signature.NewNoop())
+	if err != nil {
+		return err
+	}
+
+	var imageRefs []string
+	var collectImages func(v1.Description) error
+	collectImages = func(desc v1.Description) error {
+		for digest, img := range desc.Content.Images {
+			if img.Error != "" {
+				return fmt.Errorf("Resolving image %s: %s", digest, img.Error)
+			}
+			imageRefs = append(imageRefs, img.Image)
+		}
+		for _, nestedBundle := range desc.Content.Bundles {
+			imageRefs = append(imageRefs, nestedBundle.Image)
+			if err := collectImages(nestedBundle); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := collectImages(description); err != nil {
+		return err
+	}
+
+	throttle := util.NewThrottle(c.Concurrency)
+	var wg errgroup.Group
+	for _, imageRef := range imageRefs {
+		imageRef := imageRef // copy
+		wg.Go(func() error {
+			throttle.Take()
+			defer throttle.Done()
+
+			ref, err := regname.NewDigest(imageRef)
+			if err != nil {
+				return fmt.Errorf("Parsing image %s: %s", imageRef, err)
+			}
+			_, err = c.registry.Digest(ref)
+			if err != nil {
+				return fmt.Errorf("Resolving image %s: %s", imageRef, err)
+			}
+			return nil
+		})
+	}
+	return wg.Wait()
+}
+
 func (c CopyRepoSrc) tagAllImages(processedImages *ctlimgset.ProcessedImages) error {
 	throttle := util.NewThrottle(c.Concurrency)
 
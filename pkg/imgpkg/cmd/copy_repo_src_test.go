@@ -14,11 +14,16 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
 
+	goui "github.com/cppforlife/go-cli-ui/ui"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -336,6 +341,7 @@ func TestToTarImage(t *testing.T) {
 		}
 		err := subject.CopyToTar(imageTarPath, false)
 		require.ErrorContains(t, err, "error verifying sha256 checksum")
+		require.FileExists(t, imageTarPath+".checkpoint", "the layers written before the failure should be recorded")
 		layersInTar, err = imagetar.NewTarReader(imageTarPath).PresentLayers()
 		require.NoError(t, err)
 		require.Greater(t, len(layersInTar), 1)
@@ -343,6 +349,7 @@ func TestToTarImage(t *testing.T) {
 
 		err = subject.CopyToTar(imageTarPath, true)
 		require.NoError(t, err)
+		require.NoFileExists(t, imageTarPath+".checkpoint")
 
 		assertTarballContainsEveryLayer(t, imageTarPath)
 	})
@@ -464,6 +471,222 @@ images:
 		assert.NoError(t, err)
 		require.Len(t, manifest.Manifests, int(expectedNumOfImagesForImgIndex))
 	})
+
+	t.Run("with images from an images file should copy every image to repo", func(t *testing.T) {
+		firstIndexRefDigest := fakeRegistry.WithARandomImageIndex("library/image-3", expectedNumOfImagesForImgIndex).RefDigest
+		secondIndexRefDigest := fakeRegistry.WithARandomImageIndex("library/image-4", expectedNumOfImagesForImgIndex).RefDigest
+
+		subject := subject
+		subject.ImageFlags = ImageFlags{}
+		subject.ImageRefs = []string{firstIndexRefDigest, secondIndexRefDigest}
+		subject.registry = fakeRegistry.Build()
+
+		processedImages, err := subject.CopyToRepo(fakeRegistry.ReferenceOnTestServer(destinationImageName))
+		require.NoError(t, err)
+
+		require.Len(t, processedImages.All(), 2)
+		for _, processedImage := range processedImages.All() {
+			manifest, err := processedImage.ImageIndex.IndexManifest()
+			require.NoError(t, err)
+			require.Len(t, manifest.Manifests, int(expectedNumOfImagesForImgIndex))
+		}
+	})
+}
+
+func TestToRepoImageIndexWithPlatforms(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	reg := fakeRegistry.Build()
+
+	amd64Image, err := random.Image(500, 1)
+	require.NoError(t, err)
+	arm64Image, err := random.Image(500, 1)
+	require.NoError(t, err)
+	index := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{Add: amd64Image, Descriptor: regv1.Descriptor{Platform: &regv1.Platform{OS: "linux", Architecture: "amd64"}}},
+		mutate.IndexAddendum{Add: arm64Image, Descriptor: regv1.Descriptor{Platform: &regv1.Platform{OS: "linux", Architecture: "arm64"}}},
+	)
+	indexRef, err := name.NewTag(fakeRegistry.ReferenceOnTestServer("library/multi-arch:latest"))
+	require.NoError(t, err)
+	require.NoError(t, remote.WriteIndex(indexRef, index))
+	indexDigest, err := index.Digest()
+	require.NoError(t, err)
+	amd64Digest, err := amd64Image.Digest()
+	require.NoError(t, err)
+	arm64Digest, err := arm64Image.Digest()
+	require.NoError(t, err)
+
+	indexDigestRef := indexRef.Context().Digest(indexDigest.String()).Name()
+
+	subject := subject
+	subject.ImageFlags = ImageFlags{indexDigestRef}
+	subject.imageSet = subject.imageSet.WithPlatforms([]regv1.Platform{{OS: "linux", Architecture: "amd64"}})
+	subject.tarImageSet = imageset.NewTarImageSet(subject.imageSet, 1, subject.logger)
+	subject.registry = reg
+
+	t.Run("tar only contains the images for the platforms", func(t *testing.T) {
+		imageTarPath := filepath.Join(t.TempDir(), "image.tar")
+		require.NoError(t, subject.CopyToTar(imageTarPath, false))
+
+		assertTarballContainsEveryImageInImageIndex(t, imageTarPath, 1)
+		imageOrIndex, err := imagetar.NewTarReader(imageTarPath).Read()
+		require.NoError(t, err)
+		manifest, err := (*imageOrIndex[0].Index).IndexManifest()
+		require.NoError(t, err)
+		assert.Equal(t, amd64Digest, manifest.Manifests[0].Digest)
+	})
+
+	t.Run("copies an index with only the images for the platforms", func(t *testing.T) {
+		destination := fakeRegistry.ReferenceOnTestServer("library/copied-multi-arch")
+		processedImages, err := subject.CopyToRepo(destination)
+		require.NoError(t, err)
+
+		require.Len(t, processedImages.All(), 1)
+		copiedIndex := processedImages.All()[0]
+		assert.NotEqual(t, destination+"@"+indexDigest.String(), copiedIndex.DigestRef)
+		manifest, err := copiedIndex.ImageIndex.IndexManifest()
+		require.NoError(t, err)
+		require.Len(t, manifest.Manifests, 1)
+		assert.Equal(t, amd64Digest, manifest.Manifests[0].Digest)
+
+		require.NoError(t, validateImagesPresenceInRegistry(t, []string{copiedIndex.DigestRef, destination + "@" + amd64Digest.String()}))
+		require.Error(t, validateImagesPresenceInRegistry(t, []string{destination + "@" + arm64Digest.String()}))
+	})
+
+	t.Run("records the digest of the copied index in the locations of the bundle", func(t *testing.T) {
+		bundleInfo := fakeRegistry.WithRandomBundleAndImages("library/multi-arch-bundle", []lockconfig.ImageRef{{Image: indexDigestRef}})
+		fakeRegistry.Build()
+
+		subject := subject
+		subject.ImageFlags = ImageFlags{}
+		subject.BundleFlags = BundleFlags{Bundle: bundleInfo.RefDigest}
+		destination := fakeRegistry.ReferenceOnTestServer("library/copied-multi-arch-bundle")
+		processedImages, err := subject.CopyToRepo(destination)
+		require.NoError(t, err)
+
+		var copiedIndexRef string
+		for _, processedImage := range processedImages.All() {
+			if processedImage.ImageIndex != nil {
+				copiedIndexRef = processedImage.DigestRef
+			}
+		}
+		require.NotEmpty(t, copiedIndexRef)
+
+		copiedBundle := bundle.NewBundleFromRef(destination+"@"+bundleInfo.Digest, reg, bundle.NewImagesLockReader(), bundle.NewRegistryFetcher(reg, bundle.NewImagesLockReader()))
+		_, imageRefs, err := copiedBundle.AllImagesLockRefs(1, util.NewNoopLevelLogger())
+		require.NoError(t, err)
+		imageRef, found := imageRefs.Find(indexDigestRef)
+		require.True(t, found)
+		assert.Equal(t, copiedIndexRef, imageRef.PrimaryLocation())
+	})
+}
+
+func TestToRepoBundleWithImageFilters(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegistry := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegistry.CleanUp()
+
+	appImage := fakeRegistry.WithRandomImage("app/app")
+	windowsImage := fakeRegistry.WithRandomImage("app/app-windows")
+	bundleInfo := fakeRegistry.WithRandomBundleAndImages("app/bundle", []lockconfig.ImageRef{{Image: appImage.RefDigest}, {Image: windowsImage.RefDigest}})
+	reg := fakeRegistry.Build()
+
+	subject := subject
+	subject.registry = reg
+	subject.ImageFilterFlags = ImageFilterFlags{Exclude: []string{"*/app/*-windows"}}
+
+	assertExcluded := func(t *testing.T, destination string, processedImages *imageset.ProcessedImages) {
+		for _, processedImage := range processedImages.All() {
+			assert.NotEqual(t, windowsImage.RefDigest, processedImage.UnprocessedImageRef.DigestRef)
+		}
+		require.NoError(t, validateImagesPresenceInRegistry(t, []string{destination + "@" + appImage.Digest}))
+		require.Error(t, validateImagesPresenceInRegistry(t, []string{destination + "@" + windowsImage.Digest}))
+
+		copiedBundle := bundle.NewBundleFromRef(destination+"@"+bundleInfo.Digest, reg, bundle.NewImagesLockReader(), bundle.NewRegistryFetcher(reg, bundle.NewImagesLockReader()))
+		_, imageRefs, err := copiedBundle.AllImagesLockRefs(1, util.NewNoopLevelLogger())
+		require.NoError(t, err)
+		appRef, found := imageRefs.Find(appImage.RefDigest)
+		require.True(t, found)
+		assert.Equal(t, destination+"@"+appImage.Digest, appRef.PrimaryLocation())
+		windowsRef, found := imageRefs.Find(windowsImage.RefDigest)
+		require.True(t, found)
+		assert.Equal(t, windowsImage.RefDigest, windowsRef.PrimaryLocation())
+	}
+
+	t.Run("does not copy the excluded images and records them in the locations of the bundle", func(t *testing.T) {
+		subject := subject
+		subject.BundleFlags = BundleFlags{Bundle: bundleInfo.RefDigest}
+		destination := fakeRegistry.ReferenceOnTestServer("app/copied-bundle")
+		processedImages, err := subject.CopyToRepo(destination)
+		require.NoError(t, err)
+		assertExcluded(t, destination, processedImages)
+	})
+
+	t.Run("when copied through a tar, records the images that are not in the tar as excluded", func(t *testing.T) {
+		subject := subject
+		subject.BundleFlags = BundleFlags{Bundle: bundleInfo.RefDigest}
+		tarPath := filepath.Join(t.TempDir(), "bundle.tar")
+		require.NoError(t, subject.CopyToTar(tarPath, false))
+
+		subject.BundleFlags = BundleFlags{}
+		subject.ImageFilterFlags = ImageFilterFlags{}
+		subject.TarFlags = TarFlags{TarSrc: tarPath}
+		destination := fakeRegistry.ReferenceOnTestServer("app/copied-bundle-from-tar")
+		processedImages, err := subject.CopyToRepo(destination)
+		require.NoError(t, err)
+		assertExcluded(t, destination, processedImages)
+	})
+
+	t.Run("only copies the included images", func(t *testing.T) {
+		subject := subject
+		subject.BundleFlags = BundleFlags{Bundle: bundleInfo.RefDigest}
+		subject.ImageFilterFlags = ImageFilterFlags{Include: []string{"regexp:/app/app$"}}
+		destination := fakeRegistry.ReferenceOnTestServer("app/included-bundle")
+		processedImages, err := subject.CopyToRepo(destination)
+		require.NoError(t, err)
+		assertExcluded(t, destination, processedImages)
+	})
+}
+
+func TestToRepoEvents(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	randomImage := fakeRegistry.WithRandomImage("library/image")
+	randomImage2 := fakeRegistry.WithRandomImage("library/image2")
+
+	var events []imageset.Event
+	eventsLock := &sync.Mutex{}
+	listener := imageset.EventListenerFunc(func(event imageset.Event) {
+		eventsLock.Lock()
+		defer eventsLock.Unlock()
+		events = append(events, event)
+	})
+
+	subject := subject
+	subject.ImageRefs = []string{randomImage.RefDigest, randomImage2.RefDigest}
+	subject.imageSet = subject.imageSet.WithEventListener(listener)
+	subject.registry = fakeRegistry.Build()
+
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/copied-images")
+	_, err := subject.CopyToRepo(destRepo)
+	require.NoError(t, err)
+
+	eventsPerImage := map[string][]imageset.EventType{}
+	destinations := map[string]string{}
+	for _, event := range events {
+		eventsPerImage[event.Source] = append(eventsPerImage[event.Source], event.Type)
+		if event.Type == imageset.EventImageCompleted {
+			destinations[event.Source] = event.Destination
+		}
+	}
+	assert.Equal(t, map[string][]imageset.EventType{
+		randomImage.RefDigest:  {imageset.EventImageStarted, imageset.EventImageCompleted},
+		randomImage2.RefDigest: {imageset.EventImageStarted, imageset.EventImageCompleted},
+	}, eventsPerImage)
+	assert.Equal(t, map[string]string{
+		randomImage.RefDigest:  destRepo + "@" + randomImage.Digest,
+		randomImage2.RefDigest: destRepo + "@" + randomImage2.Digest,
+	}, destinations)
 }
 
 func TestToRepoBundleContainingANestedBundle(t *testing.T) {
@@ -516,6 +739,40 @@ func TestToRepoBundleContainingANestedBundle(t *testing.T) {
 		assert.Equal(t, processedBundle.DigestRef, destRepo+"@"+bundleWithNestedBundle.Digest)
 	})
 
+	t.Run("When the bundle is provided in a list of images that can contain bundles, it copies every image without a root bundle", func(t *testing.T) {
+		subject := subject
+		subject.registry = fakeRegistry.Build()
+		subject.BundleFlags.Bundle = ""
+		subject.ImageRefs = []string{bundleWithNestedBundle.RefDigest, randomImage.RefDigest}
+		subject.ImageRefsCanBeBundles = true
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-list-copy")
+		processedImages, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		processedImageDigest := []string{}
+		for _, processedImage := range processedImages.All() {
+			processedImageDigest = append(processedImageDigest, processedImage.DigestRef)
+			assert.NotContains(t, processedImage.Labels, rootBundleLabelKey)
+		}
+		assert.ElementsMatch(t, processedImageDigest, []string{
+			destRepo + "@" + bundleWithNestedBundle.Digest,
+			destRepo + "@" + bundleWithTwoImages.Digest,
+			destRepo + "@" + randomImage.Digest,
+			destRepo + "@" + randomImage2.Digest,
+		})
+	})
+
+	t.Run("When the bundle is provided in a list of images that cannot contain bundles, it returns an error", func(t *testing.T) {
+		subject := subject
+		subject.registry = fakeRegistry.Build()
+		subject.BundleFlags.Bundle = ""
+		subject.ImageRefs = []string{bundleWithNestedBundle.RefDigest}
+
+		_, err := subject.CopyToRepo(fakeRegistry.ReferenceOnTestServer("library/bundle-list-copy"))
+		require.ErrorContains(t, err, "Unable to copy bundle")
+	})
+
 	t.Run("When user defined tag is provided, it applies it after the upload of the blobs finishes", func(t *testing.T) {
 		subject := subject
 		subject.registry = fakeRegistry.Build()
@@ -868,6 +1125,189 @@ func TestToRepoFromTar(t *testing.T) {
 	})
 }
 
+func TestToRepoFromTarWithToTag(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegistry := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegistry.CleanUp()
+
+	randomImage := fakeRegistry.WithRandomImage("library/image")
+	bundleInfo := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: randomImage.RefDigest},
+		})
+	fakeRegistry.Tag(bundleInfo.RefDigest, "exported-tag")
+
+	assets := &helpers.Assets{T: t}
+	defer assets.CleanCreatedFolders()
+	tarFile := filepath.Join(assets.CreateTempFolder("tar-to-tag"), "bundle.tar")
+
+	subject := subject
+	subject.BundleFlags.Bundle = fakeRegistry.ReferenceOnTestServer("library/bundle:exported-tag")
+	subject.registry = fakeRegistry.Build()
+
+	logger.Section("create Tar file with bundle", func() {
+		err := subject.CopyToTar(tarFile, false)
+		require.NoError(t, err)
+	})
+
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy")
+	logger.Section("copy bundle from Tar file to Repository with a new tag", func() {
+		subject.BundleFlags.Bundle = ""
+		subject.TarFlags.TarSrc = tarFile
+		subject.TarFlags.ToTag = "build-1234"
+		_, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+	})
+
+	newTagRef, err := name.NewTag(destRepo + ":build-1234")
+	require.NoError(t, err)
+	digest, err := subject.registry.Digest(newTagRef)
+	require.NoError(t, err)
+	assert.Equal(t, bundleInfo.Digest, digest.String())
+
+	exportedTagRef, err := name.NewTag(destRepo + ":exported-tag")
+	require.NoError(t, err)
+	_, err = subject.registry.Digest(exportedTagRef)
+	require.Error(t, err)
+}
+
+func TestToRepoBundleWithToTagWritesBundleLock(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegistry := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegistry.CleanUp()
+
+	randomImage := fakeRegistry.WithRandomImage("library/image")
+	bundleInfo := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: randomImage.RefDigest},
+		})
+
+	assets := &helpers.Assets{T: t}
+	defer assets.CleanCreatedFolders()
+	lockOutputPath := filepath.Join(assets.CreateTempFolder("to-tag-lock-output"), "bundle.lock.yml")
+
+	subject := subject
+	subject.BundleFlags.Bundle = bundleInfo.RefDigest
+	subject.TarFlags.ToTag = "v1.0.0"
+	reg := fakeRegistry.Build()
+	subject.registry = reg
+
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy")
+	processedImages, err := subject.CopyToRepo(destRepo)
+	require.NoError(t, err)
+
+	copyOpts := &CopyOptions{LockOutputFlags: LockOutputFlags{LockFilePath: lockOutputPath}}
+	require.NoError(t, copyOpts.writeLockOutput(processedImages, reg))
+
+	bundleLock, err := lockconfig.NewBundleLockFromPath(lockOutputPath)
+	require.NoError(t, err)
+	assert.Equal(t, destRepo+"@"+bundleInfo.Digest, bundleLock.Bundle.Image)
+	assert.Equal(t, "v1.0.0", bundleLock.Bundle.Tag)
+
+	tagRef, err := name.NewTag(destRepo + ":" + bundleLock.Bundle.Tag)
+	require.NoError(t, err)
+	digest, err := subject.registry.Digest(tagRef)
+	require.NoError(t, err)
+	assert.Equal(t, bundleInfo.Digest, digest.String())
+}
+
+func TestToRepoBundleReplacingExistingTag(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegistry := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegistry.CleanUp()
+
+	randomImage := fakeRegistry.WithRandomImage("library/image")
+	previousBundle := fakeRegistry.WithBundleFromPath("library/previous-bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: randomImage.RefDigest},
+		})
+	fakeRegistry.Tag(previousBundle.RefDigest, "v1")
+	bundleInfo := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle").
+		WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+	fakeRegistry.Tag(bundleInfo.RefDigest, "v1")
+
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy")
+	imagesUnresolvable := false
+	fakeRegistry.WithCustomHandler(func(writer http.ResponseWriter, request *http.Request) bool {
+		// Only the bundle can be read from the destination, every image of the bundle is not found
+		if imagesUnresolvable && strings.HasPrefix(request.URL.Path, "/v2/library/bundle-copy/manifests/sha256:") &&
+			!strings.HasSuffix(request.URL.Path, bundleInfo.Digest) {
+			writer.WriteHeader(http.StatusNotFound)
+			return true
+		}
+		return false
+	})
+
+	subject := subject
+	subject.registry = fakeRegistry.Build()
+
+	tagDigest := func(t *testing.T) string {
+		tagRef, err := name.NewTag(destRepo + ":v1")
+		require.NoError(t, err)
+		digest, err := subject.registry.Digest(tagRef)
+		require.NoError(t, err)
+		return digest.String()
+	}
+
+	logger.Section("copy the previous bundle to the destination tag", func() {
+		subject := subject
+		subject.BundleFlags.Bundle = fakeRegistry.ReferenceOnTestServer("library/previous-bundle:v1")
+		_, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+		require.Equal(t, previousBundle.Digest, tagDigest(t))
+	})
+
+	t.Run("keeps the tag when the images of the copied bundle cannot be resolved", func(t *testing.T) {
+		subject := subject
+		subject.BundleFlags.Bundle = fakeRegistry.ReferenceOnTestServer("library/bundle:v1")
+
+		imagesUnresolvable = true
+		_, err := subject.CopyToRepo(destRepo)
+		imagesUnresolvable = false
+		require.ErrorContains(t, err, fmt.Sprintf("Keeping tag %s:v1 pointing to %s, the copied bundle failed the health check", destRepo, previousBundle.Digest))
+		require.ErrorContains(t, err, "(hint: use --force to move the tag anyway)")
+
+		assert.Equal(t, previousBundle.Digest, tagDigest(t))
+	})
+
+	t.Run("keeps the tag when --force is provided but moving the tag is not confirmed", func(t *testing.T) {
+		subject := subject
+		subject.BundleFlags.Bundle = fakeRegistry.ReferenceOnTestServer("library/bundle:v1")
+		subject.Force = true
+		subject.confirmation = newConfirmation(&confirmationUI{UI: goui.NewNoopUI(), interactive: true})
+		subject.confirmation.isTerminal = func() bool { return false }
+
+		_, err := subject.CopyToRepo(destRepo)
+		require.ErrorContains(t, err, fmt.Sprintf("Refusing to move tag %s:v1 from %s to %s, skipping the health check of the copied bundle without confirmation", destRepo, previousBundle.Digest, bundleInfo.Digest))
+
+		assert.Equal(t, previousBundle.Digest, tagDigest(t))
+	})
+
+	t.Run("moves the tag when --force is provided and confirmed", func(t *testing.T) {
+		subject := subject
+		subject.BundleFlags.Bundle = fakeRegistry.ReferenceOnTestServer("library/bundle:v1")
+		subject.Force = true
+		// --yes configures the ui to be non interactive
+		subject.confirmation = newConfirmation(&confirmationUI{UI: goui.NewNoopUI()})
+
+		imagesUnresolvable = true
+		_, err := subject.CopyToRepo(destRepo)
+		imagesUnresolvable = false
+		require.NoError(t, err)
+
+		assert.Equal(t, bundleInfo.Digest, tagDigest(t))
+	})
+
+	t.Run("moves the tag when the copied bundle is healthy", func(t *testing.T) {
+		subject := subject
+		subject.BundleFlags.Bundle = fakeRegistry.ReferenceOnTestServer("library/previous-bundle:v1")
+		_, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		assert.Equal(t, previousBundle.Digest, tagDigest(t))
+	})
+}
+
 func TestToRepoBundleRunTwiceCreatesValidLocationOCI(t *testing.T) {
 	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
 	defer fakeRegistry.CleanUp()
@@ -1368,3 +1808,184 @@ func downloadImagesLocation(t *testing.T, imgRef, location string) {
 	err = ctlimg.NewDirImage(filepath.Join(location), img, util.NewBufferLogger(output)).AsDirectory()
 	require.NoError(t, err)
 }
+
+func TestToRepoFromTarWithFallbackToOrigin(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegistry := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegistry.CleanUp()
+	// images in the same registry are mounted from their origin, so a different registry is used as destination
+	destRegistry := helpers.NewFakeRegistry(t, logger)
+	defer destRegistry.CleanUp()
+
+	randomImage := fakeRegistry.WithRandomImage("library/image")
+	fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: randomImage.RefDigest},
+		})
+
+	assets := &helpers.Assets{T: t}
+	defer assets.CleanCreatedFolders()
+	tarFile := filepath.Join(assets.CreateTempFolder("tar-fallback-to-origin"), "bundle.tar")
+
+	subject := subject
+	subject.BundleFlags.Bundle = fakeRegistry.ReferenceOnTestServer("library/bundle")
+	subject.registry = fakeRegistry.Build()
+
+	logger.Section("create Tar file with bundle", func() {
+		err := subject.CopyToTar(tarFile, false)
+		require.NoError(t, err)
+	})
+
+	layers, err := randomImage.Image.Layers()
+	require.NoError(t, err)
+	missingLayerDigest, err := layers[0].Digest()
+	require.NoError(t, err)
+	removeFileFromTarball(t, tarFile, missingLayerDigest.Algorithm+"-"+missingLayerDigest.Hex+".tar.gz")
+	require.False(t, doesLayerExistInTarball(t, tarFile, missingLayerDigest))
+
+	subject.BundleFlags.Bundle = ""
+	subject.TarFlags.TarSrc = tarFile
+
+	t.Run("fails when the layer is not in the tar", func(t *testing.T) {
+		_, err := subject.CopyToRepo(destRegistry.ReferenceOnTestServer("library/bundle-copy-without-fallback"))
+		require.Error(t, err)
+	})
+
+	t.Run("retrieves the missing layer from the origin registry", func(t *testing.T) {
+		subject := subject
+		subject.TarFlags.FallbackToOrigin = true
+
+		destRepo := destRegistry.ReferenceOnTestServer("library/bundle-copy")
+		_, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		copiedImageRef, err := name.NewDigest(destRepo + "@" + randomImage.Digest)
+		require.NoError(t, err)
+		copiedImage, err := subject.registry.Image(copiedImageRef)
+		require.NoError(t, err)
+		copiedLayer, err := copiedImage.LayerByDigest(missingLayerDigest)
+		require.NoError(t, err)
+		_, err = copiedLayer.Compressed()
+		require.NoError(t, err)
+	})
+}
+
+func TestToRepoFromDeltaTar(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegistry := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegistry.CleanUp()
+	destRegistry := helpers.NewFakeRegistry(t, logger)
+	defer destRegistry.CleanUp()
+
+	sharedImage := fakeRegistry.WithRandomImage("library/shared-image")
+	newImage := fakeRegistry.WithRandomImage("library/new-image")
+	fakeRegistry.WithBundleFromPath("library/bundle-v1", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{{Image: sharedImage.RefDigest}})
+	fakeRegistry.WithBundleFromPath("library/bundle-v2", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{{Image: sharedImage.RefDigest}, {Image: newImage.RefDigest}})
+
+	assets := &helpers.Assets{T: t}
+	defer assets.CleanCreatedFolders()
+	tarFolder := assets.CreateTempFolder("delta-tar")
+	baseTar := filepath.Join(tarFolder, "bundle-v1.tar")
+	deltaTar := filepath.Join(tarFolder, "bundle-v2.tar")
+
+	subject := subject
+	subject.registry = fakeRegistry.Build()
+
+	logger.Section("create the base tar and the delta tar", func() {
+		subject.BundleFlags.Bundle = fakeRegistry.ReferenceOnTestServer("library/bundle-v1")
+		require.NoError(t, subject.CopyToTar(baseTar, false))
+
+		subject.BundleFlags.Bundle = fakeRegistry.ReferenceOnTestServer("library/bundle-v2")
+		subject.tarImageSet = subject.tarImageSet.WithBaseTar(baseTar)
+		require.NoError(t, subject.CopyToTar(deltaTar, false))
+	})
+
+	sharedLayers, err := sharedImage.Image.Layers()
+	require.NoError(t, err)
+	sharedLayerDigest, err := sharedLayers[0].Digest()
+	require.NoError(t, err)
+	newLayers, err := newImage.Image.Layers()
+	require.NoError(t, err)
+	newLayerDigest, err := newLayers[0].Digest()
+	require.NoError(t, err)
+
+	t.Run("the delta tar only contains the layers missing from the base tar", func(t *testing.T) {
+		assert.True(t, doesLayerExistInTarball(t, baseTar, sharedLayerDigest))
+		assert.False(t, doesLayerExistInTarball(t, deltaTar, sharedLayerDigest))
+		assert.True(t, doesLayerExistInTarball(t, deltaTar, newLayerDigest))
+	})
+
+	assertCopiedLayer := func(t *testing.T, destRepo string, imageDigest string, layerDigest regv1.Hash) {
+		copiedImageRef, err := name.NewDigest(destRepo + "@" + imageDigest)
+		require.NoError(t, err)
+		copiedImage, err := subject.registry.Image(copiedImageRef)
+		require.NoError(t, err)
+		copiedLayer, err := copiedImage.LayerByDigest(layerDigest)
+		require.NoError(t, err)
+		_, err = copiedLayer.Compressed()
+		require.NoError(t, err)
+	}
+
+	subject.BundleFlags.Bundle = ""
+	subject.TarFlags.TarSrc = deltaTar
+
+	t.Run("copies the delta tar reading the missing layers from the base tar next to it", func(t *testing.T) {
+		subject := subject
+		subject.tarImageSet = imageset.NewTarImageSet(subject.imageSet, 1, subject.logger)
+
+		destRepo := destRegistry.ReferenceOnTestServer("library/bundle-copy")
+		_, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		assertCopiedLayer(t, destRepo, sharedImage.Digest, sharedLayerDigest)
+		assertCopiedLayer(t, destRepo, newImage.Digest, newLayerDigest)
+	})
+
+	t.Run("copies the delta tar reading the missing layers from the base tar provided", func(t *testing.T) {
+		movedBaseTar := filepath.Join(assets.CreateTempFolder("moved-base-tar"), "base.tar")
+		require.NoError(t, os.Rename(baseTar, movedBaseTar))
+		defer os.Rename(movedBaseTar, baseTar)
+
+		subject := subject
+		subject.tarImageSet = imageset.NewTarImageSet(subject.imageSet, 1, subject.logger)
+		_, err := subject.CopyToRepo(destRegistry.ReferenceOnTestServer("library/bundle-copy-without-base"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "--base-tar")
+
+		subject.tarImageSet = subject.tarImageSet.WithBaseTar(movedBaseTar)
+		destRepo := destRegistry.ReferenceOnTestServer("library/bundle-copy-with-moved-base")
+		_, err = subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+		assertCopiedLayer(t, destRepo, sharedImage.Digest, sharedLayerDigest)
+	})
+}
+
+func removeFileFromTarball(t *testing.T, path string, fileToRemove string) {
+	tmpPath := path + ".tmp"
+	src, err := os.Open(path)
+	require.NoError(t, err)
+	dst, err := os.Create(tmpPath)
+	require.NoError(t, err)
+
+	tr := tar.NewReader(src)
+	tw := tar.NewWriter(dst)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if hdr.Name == fileToRemove {
+			continue
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err = io.Copy(tw, tr)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, dst.Close())
+	require.NoError(t, src.Close())
+	require.NoError(t, os.Rename(tmpPath, path))
+}
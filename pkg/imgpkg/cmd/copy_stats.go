@@ -0,0 +1,121 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	ctlimgset "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+// stampCopyStats records, next to the copied root bundle, the statistics of the copy
+func (c *CopyOptions) stampCopyStats(processedImages *ctlimgset.ProcessedImages, reg registry.Registry, startedAt time.Time, logger util.LoggerWithLevels) error {
+	rootBundle := c.findProcessedImageRootBundle(processedImages)
+	if rootBundle == nil {
+		logger.Warnf("Skipping --stamp-stats: copy statistics are only recorded for bundles\n")
+		return nil
+	}
+
+	bundleRef, err := regname.NewDigest(rootBundle.DigestRef)
+	if err != nil {
+		return fmt.Errorf("Parsing '%s': %s", rootBundle.DigestRef, err)
+	}
+
+	size, err := copiedImagesSize(processedImages)
+	if err != nil {
+		return fmt.Errorf("Calculating size of the copied images: %s", err)
+	}
+
+	finishedAt := time.Now()
+	return bundle.WriteCopyStats(reg, bundleRef, bundle.CopyStats{
+		CopiedAt:      finishedAt,
+		Duration:      finishedAt.Sub(startedAt).Round(time.Second).String(),
+		Source:        c.copySource(),
+		ImgpkgVersion: Version,
+		Images:        len(processedImages.All()),
+		Bytes:         size,
+	})
+}
+
+// copySource returns the location the bundle was copied from
+func (c *CopyOptions) copySource() string {
+	switch {
+	case c.BundleFlags.Bundle != "":
+		return c.BundleFlags.Bundle
+	case c.TarFlags.IsSrc():
+		return c.TarFlags.TarSrc
+	default:
+		return c.LockInputFlags.LockFilePath
+	}
+}
+
+// copiedImagesSize returns the size of the blobs of the images, counting only once the blobs shared between images
+func copiedImagesSize(processedImages *ctlimgset.ProcessedImages) (int64, error) {
+	seen := map[regv1.Hash]struct{}{}
+	var size int64
+	for _, processedImage := range processedImages.All() {
+		images, err := processedImageImages(processedImage)
+		if err != nil {
+			return 0, err
+		}
+		for _, img := range images {
+			manifest, err := img.Manifest()
+			if err != nil {
+				return 0, err
+			}
+			for _, desc := range append([]regv1.Descriptor{manifest.Config}, manifest.Layers...) {
+				if _, found := seen[desc.Digest]; found {
+					continue
+				}
+				seen[desc.Digest] = struct{}{}
+				size += desc.Size
+			}
+		}
+	}
+	return size, nil
+}
+
+// processedImageImages returns the image, or all the images referenced by the index and its nested indexes
+func processedImageImages(processedImage ctlimgset.ProcessedImage) ([]regv1.Image, error) {
+	if processedImage.ImageIndex == nil {
+		return []regv1.Image{processedImage.Image}, nil
+	}
+	return imagesInIndex(processedImage.ImageIndex)
+}
+
+func imagesInIndex(idx regv1.ImageIndex) ([]regv1.Image, error) {
+	idxManifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	var images []regv1.Image
+	for _, desc := range idxManifest.Manifests {
+		switch {
+		case desc.MediaType.IsIndex():
+			childIdx, err := idx.ImageIndex(desc.Digest)
+			if err != nil {
+				return nil, err
+			}
+			childImages, err := imagesInIndex(childIdx)
+			if err != nil {
+				return nil, err
+			}
+			images = append(images, childImages...)
+		case desc.MediaType.IsImage():
+			img, err := idx.Image(desc.Digest)
+			if err != nil {
+				return nil, err
+			}
+			images = append(images, img)
+		}
+	}
+	return images, nil
+}
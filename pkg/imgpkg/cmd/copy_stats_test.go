@@ -0,0 +1,75 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestCopyStampStats(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	bundleInfo := fakeRegistry.WithBundleFromPath("some/bundle", "test_assets/bundle").
+		WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+	fakeRegistry.Build()
+
+	confUI := ui.NewConfUI(ui.NewNoopLogger())
+	defer confUI.Flush()
+
+	describe := func(t *testing.T, bundleRef string) v1.Description {
+		registryFlags := RegistryFlags{}
+		description, err := v1.Describe(bundleRef, v1.DescribeOpts{Logger: util.NewNoopLevelLogger(), Concurrency: 1}, registryFlags.AsRegistryOpts())
+		require.NoError(t, err)
+		return description
+	}
+
+	t.Run("when --stamp-stats is provided, describe shows the statistics of the copy", func(t *testing.T) {
+		srcRef := fakeRegistry.ReferenceOnTestServer("some/bundle")
+		copyOpts := NewCopyOptions(confUI)
+		copyOpts.BundleFlags = BundleFlags{Bundle: srcRef}
+		copyOpts.RepoDst = fakeRegistry.ReferenceOnTestServer("stamped/bundle")
+		copyOpts.Concurrency = 1
+		copyOpts.StampStats = true
+
+		before := time.Now().Add(-time.Second)
+		require.NoError(t, copyOpts.Run())
+
+		description := describe(t, fakeRegistry.ReferenceOnTestServer("stamped/bundle@"+bundleInfo.Digest))
+		require.NotNil(t, description.CopyStats)
+		assert.Equal(t, srcRef, description.CopyStats.Source)
+		assert.Equal(t, Version, description.CopyStats.ImgpkgVersion)
+		// The bundle and the image in its images lock
+		assert.Equal(t, 2, description.CopyStats.Images)
+		assert.Greater(t, description.CopyStats.Bytes, int64(0))
+		assert.True(t, description.CopyStats.CopiedAt.After(before))
+		assert.NotEmpty(t, description.CopyStats.Duration)
+	})
+
+	t.Run("when --stamp-stats is not provided, describe does not show statistics", func(t *testing.T) {
+		copyOpts := NewCopyOptions(confUI)
+		copyOpts.BundleFlags = BundleFlags{Bundle: fakeRegistry.ReferenceOnTestServer("some/bundle")}
+		copyOpts.RepoDst = fakeRegistry.ReferenceOnTestServer("not-stamped/bundle")
+		copyOpts.Concurrency = 1
+		require.NoError(t, copyOpts.Run())
+
+		description := describe(t, fakeRegistry.ReferenceOnTestServer("not-stamped/bundle@"+bundleInfo.Digest))
+		assert.Nil(t, description.CopyStats)
+	})
+
+	t.Run("fails when copying to a tar", func(t *testing.T) {
+		copyOpts := NewCopyOptions(confUI)
+		copyOpts.BundleFlags = BundleFlags{Bundle: fakeRegistry.ReferenceOnTestServer("some/bundle")}
+		copyOpts.TarFlags = TarFlags{TarDst: "/tmp/bundle.tar"}
+		copyOpts.StampStats = true
+		require.ErrorContains(t, copyOpts.Run(), "Cannot use --stamp-stats with tar destination")
+	})
+}
@@ -14,7 +14,7 @@ func TestMultiDest(t *testing.T) {
 		t.Fatalf("Expected Run() to err")
 	}
 
-	if !strings.Contains(err.Error(), "Expected either --to-tar or --to-repo") {
+	if !strings.Contains(err.Error(), "Expected either --to-tar, --to-repo, --to-oci, or --to-artifactory-import") {
 		t.Fatalf("Expected error message related to destinations, got: %s", err)
 	}
 }
@@ -25,7 +25,7 @@ func TestNoDest(t *testing.T) {
 		t.Fatalf("Expected Run() to err")
 	}
 
-	if !strings.Contains(err.Error(), "Expected either --to-tar or --to-repo") {
+	if !strings.Contains(err.Error(), "Expected either --to-tar, --to-repo, --to-oci, or --to-artifactory-import") {
 		t.Fatalf("Expected error message related to destinations, got: %s", err)
 	}
 }
@@ -36,7 +36,7 @@ func TestMultiSrc(t *testing.T) {
 		t.Fatalf("Expected Run() to err")
 	}
 
-	if !strings.Contains(err.Error(), "Expected either --lock, --bundle (-b), --image (-i), or --tar as a source") {
+	if !strings.Contains(err.Error(), "Expected either --lock, --bundle (-b), --image (-i), --images-file, --repo-tags, --tar, or --oci as a source") {
 		t.Fatalf("Expected error message related to destinations, got: %s", err)
 	}
 }
@@ -47,7 +47,7 @@ func TestNoSrc(t *testing.T) {
 		t.Fatalf("Expected Run() to err")
 	}
 
-	if !strings.Contains(err.Error(), "Expected either --lock, --bundle (-b), --image (-i), or --tar as a source") {
+	if !strings.Contains(err.Error(), "Expected either --lock, --bundle (-b), --image (-i), --images-file, --repo-tags, --tar, or --oci as a source") {
 		t.Fatalf("Expected error message related to destinations, got: %s", err)
 	}
 }
@@ -62,3 +62,242 @@ func TestTarSrcWithTarDst(t *testing.T) {
 		t.Fatalf("Expected error message related to destinations, got: %s", err)
 	}
 }
+
+func TestJSONOutputWithTarDst(t *testing.T) {
+	err := (&CopyOptions{ImageFlags: ImageFlags{Image: "foo"}, TarFlags: TarFlags{TarDst: "bar"}, OutputTypeFlags: OutputTypeFlags{OutputType: "json"}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Cannot use --output-type json with tar destination") {
+		t.Fatalf("Expected error message related to output type, got: %s", err)
+	}
+}
+
+func TestLocationsRepoWithTarSrc(t *testing.T) {
+	err := (&CopyOptions{TarFlags: TarFlags{TarSrc: "foo"}, RepoDst: "bar", LocationsRepo: "baz"}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --locations-repo cannot be used with tar source (--tar)") {
+		t.Fatalf("Expected error message related to locations repo, got: %s", err)
+	}
+}
+
+func TestPlatformWithTarSrc(t *testing.T) {
+	err := (&CopyOptions{TarFlags: TarFlags{TarSrc: "foo"}, RepoDst: "bar", Platforms: []string{"linux/amd64"}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --platform cannot be used with tar (--tar) or OCI image layout (--oci) sources") {
+		t.Fatalf("Expected error message related to platform, got: %s", err)
+	}
+}
+
+func TestInvalidPlatform(t *testing.T) {
+	err := (&CopyOptions{ImageFlags: ImageFlags{Image: "foo"}, RepoDst: "bar", Platforms: []string{"amd64"}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Invalid platform 'amd64'") {
+		t.Fatalf("Expected error message related to platform, got: %s", err)
+	}
+}
+
+func TestImageFilterWithoutBundleSrc(t *testing.T) {
+	err := (&CopyOptions{ImageFlags: ImageFlags{Image: "foo"}, RepoDst: "bar", ImageFilterFlags: ImageFilterFlags{Exclude: []string{"*"}}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flags --include-image and --exclude-image can only be used when copying a bundle (--bundle) or a lock file (--lock)") {
+		t.Fatalf("Expected error message related to image filters, got: %s", err)
+	}
+}
+
+func TestInvalidProgress(t *testing.T) {
+	err := (&CopyOptions{ImageFlags: ImageFlags{Image: "foo"}, RepoDst: "bar", ProgressFlags: ProgressFlags{Progress: "fancy"}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Expected --progress to be one of quiet, plain, bars or json, but got 'fancy'") {
+		t.Fatalf("Expected error message related to progress, got: %s", err)
+	}
+}
+
+func TestFallbackToOriginWithoutTarSrc(t *testing.T) {
+	err := (&CopyOptions{ImageFlags: ImageFlags{Image: "foo"}, RepoDst: "bar", TarFlags: TarFlags{FallbackToOrigin: true}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --fallback-to-origin can only be used when copying from tar (--tar) to a repository (--to-repo)") {
+		t.Fatalf("Expected error message related to fallback to origin, got: %s", err)
+	}
+}
+
+func TestBaseTarWithoutTar(t *testing.T) {
+	err := (&CopyOptions{ImageFlags: ImageFlags{Image: "foo"}, RepoDst: "bar", TarFlags: TarFlags{BaseTar: "base.tar"}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --base-tar can only be used when copying to tar (--to-tar) or from tar (--tar)") {
+		t.Fatalf("Expected error message related to base tar, got: %s", err)
+	}
+}
+
+func TestTransferScheduleWithTarDst(t *testing.T) {
+	err := (&CopyOptions{ImageFlags: ImageFlags{Image: "foo"}, TarFlags: TarFlags{TarDst: "bar"}, TransferWindow: "22:00-06:00"}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flags --pause-between-images and --transfer-window can only be used when copying to a repository (--to-repo)") {
+		t.Fatalf("Expected error message related to transfer schedule, got: %s", err)
+	}
+}
+
+func TestInvalidTransferWindow(t *testing.T) {
+	err := (&CopyOptions{ImageFlags: ImageFlags{Image: "foo"}, RepoDst: "bar", TransferWindow: "22:00"}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Expected transfer window '22:00' to be in the format HH:MM-HH:MM") {
+		t.Fatalf("Expected error message related to transfer window, got: %s", err)
+	}
+}
+
+func TestOCISrcWithoutOCIDst(t *testing.T) {
+	err := (&CopyOptions{OCIFlags: OCIFlags{OCISrc: "foo"}, RepoDst: "bar"}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flags --oci and --to-oci can only be used together, to copy between OCI image layout folders") {
+		t.Fatalf("Expected error message related to OCI layout folders, got: %s", err)
+	}
+}
+
+func TestOCILayoutWithRegistryFlags(t *testing.T) {
+	ociFlags := OCIFlags{OCISrc: "foo", OCIDst: "bar"}
+	testCases := map[string]*CopyOptions{
+		"--dst-annotation":   {OCIFlags: ociFlags, DstAnnotations: map[string]string{"some": "annotation"}},
+		"--force":            {OCIFlags: ociFlags, Force: true},
+		"--to-tag":           {OCIFlags: ociFlags, TarFlags: TarFlags{ToTag: "v1"}},
+		"--registry-mirror":  {OCIFlags: ociFlags, RegistryMirrors: []string{"mirror.io"}},
+		"--mirrors-config":   {OCIFlags: ociFlags, MirrorsConfigFlags: MirrorsConfigFlags{ConfigPath: "mirrors.yml"}},
+		"--verify-signature": {OCIFlags: ociFlags, TrustFlags: TrustFlags{VerifySignature: true}},
+		"--signature-key":    {OCIFlags: ociFlags, TrustFlags: TrustFlags{SignatureKeys: []string{"cosign.pub"}}},
+		"--trust-config":     {OCIFlags: ociFlags, TrustFlags: TrustFlags{TrustConfigPath: "trust.yml"}},
+	}
+
+	for flag, copyOptions := range testCases {
+		err := copyOptions.Run()
+		if err == nil {
+			t.Fatalf("Expected Run() with %s to err", flag)
+		}
+
+		if !strings.Contains(err.Error(), "Cannot use "+flag+" when copying between OCI image layout folders (--oci and --to-oci)") {
+			t.Fatalf("Expected error message related to %s with OCI layout folders, got: %s", flag, err)
+		}
+	}
+}
+
+func TestToTagWithImageSrc(t *testing.T) {
+	err := (&CopyOptions{ImageFlags: ImageFlags{Image: "foo"}, RepoDst: "bar", TarFlags: TarFlags{ToTag: "v1"}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --to-tag can only be used when copying a bundle, from --bundle (-b), --lock or --tar") {
+		t.Fatalf("Expected error message related to to-tag, got: %s", err)
+	}
+}
+
+func TestToTarChunkSizeWithRepoDst(t *testing.T) {
+	err := (&CopyOptions{ImageFlags: ImageFlags{Image: "foo"}, RepoDst: "bar", TarFlags: TarFlags{ToTarChunkSize: "2GB"}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Flag --to-tar-chunk-size can only be used when copying to tar") {
+		t.Fatalf("Expected error message related to to-tar-chunk-size, got: %s", err)
+	}
+}
+
+func TestArtifactoryImportDstWithTarSrc(t *testing.T) {
+	err := (&CopyOptions{ArtifactoryImportDst: "foo", TarFlags: TarFlags{TarSrc: "bar"}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Cannot use tar source (--tar) with Artifactory import destination (--to-artifactory-import)") {
+		t.Fatalf("Expected error message related to tar source, got: %s", err)
+	}
+}
+
+func TestNegativeLayerConcurrency(t *testing.T) {
+	err := (&CopyOptions{ImageFlags: ImageFlags{Image: "foo"}, RepoDst: "bar", LayerConcurrency: -1}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Expected --layer-concurrency to be 0 or greater, but got -1") {
+		t.Fatalf("Expected error message related to layer-concurrency, got: %s", err)
+	}
+}
+
+func TestInvalidMaxBandwidth(t *testing.T) {
+	err := (&CopyOptions{ImageFlags: ImageFlags{Image: "foo"}, RepoDst: "bar", BandwidthFlags: BandwidthFlags{MaxBandwidth: "fast"}}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Parsing --max-bandwidth: Expected a positive size like 2GB or 700MB, but got 'fast'") {
+		t.Fatalf("Expected error message related to max-bandwidth, got: %s", err)
+	}
+}
+
+func TestToTarChunkSize(t *testing.T) {
+	testCases := map[string]int64{
+		"":       0,
+		"1024":   1024,
+		"700MB":  700 * 1000 * 1000,
+		"2GB":    2 * 1000 * 1000 * 1000,
+		"4GiB":   4 * 1024 * 1024 * 1024,
+		"512 kb": 512 * 1000,
+	}
+	for value, expected := range testCases {
+		size, err := TarFlags{ToTarChunkSize: value}.ChunkSize()
+		if err != nil {
+			t.Fatalf("Expected '%s' to be parsed, got: %s", value, err)
+		}
+		if size != expected {
+			t.Fatalf("Expected '%s' to be %d bytes, got: %d", value, expected, size)
+		}
+	}
+
+	for _, value := range []string{"2XB", "-1GB", "0", "GB"} {
+		_, err := TarFlags{ToTarChunkSize: value}.ChunkSize()
+		if err == nil || !strings.Contains(err.Error(), "Expected a positive size like 2GB or 700MB, but got '"+value+"'") {
+			t.Fatalf("Expected '%s' to fail to parse, got: %v", value, err)
+		}
+	}
+}
+
+func TestRelativeFileURLRepoDst(t *testing.T) {
+	err := (&CopyOptions{ImageFlags: ImageFlags{Image: "foo"}, RepoDst: "file://relative/layout"}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Expected an absolute path after file://") {
+		t.Fatalf("Expected error message related to file:// URL, got: %s", err)
+	}
+}
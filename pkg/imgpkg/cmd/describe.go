@@ -4,37 +4,63 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"os"
 	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	goui "github.com/cppforlife/go-cli-ui/ui"
 	regname "github.com/google/go-containerregistry/pkg/name"
 	"github.com/spf13/cobra"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
 	"sigs.k8s.io/yaml"
 )
 
 var (
 	// DescribeOutputType Possible output options
-	DescribeOutputType = []string{"text", "yaml"}
+	DescribeOutputType = []string{"text", "yaml", "json", dotOutputType, mermaidOutputType}
+	// DescribeSortBy Possible options to sort the images in the output
+	DescribeSortBy = []string{"origin", "name", "size"}
+	// DescribeSchemaVersions Possible versions of the schema of the yaml and json output
+	DescribeSchemaVersions = []int{1, 2}
 )
 
+// describeOrderedSchemaVersion Version of the yaml and json schema that lists the bundles and images in the order selected by --sort-by
+const describeOrderedSchemaVersion = 2
+
 // DescribeOptions Command Line options that can be provided to the describe command
 type DescribeOptions struct {
 	ui goui.UI
 
-	BundleFlags   BundleFlags
-	RegistryFlags RegistryFlags
+	BundleFlags         BundleFlags
+	LockInputFlags      LockInputFlags
+	RegistryFlags       RegistryFlags
+	RequireDigestsFlags RequireDigestsFlags
 
 	Concurrency            int
+	Depth                  int
 	OutputType             string
+	OutputFile             string
 	IncludeCosignArtifacts bool
+	DedupReport            bool
+	Summary                bool
+	Sizes                  bool
+	SortBy                 string
+	SchemaVersion          int
+	SummarizeAnnotations   []string
+	PreflightAuth          bool
 }
 
 // NewDescribeOptions constructor for building a DescribeOptions, holding values derived via flags
-func NewDescribeOptions(ui *goui.ConfUI) *DescribeOptions {
+func NewDescribeOptions(ui goui.UI) *DescribeOptions {
 	return &DescribeOptions{ui: ui}
 }
 
@@ -43,67 +69,346 @@ func NewDescribeCmd(o *DescribeOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "describe",
 		Short: "Describe the images and bundles associated with a give bundle",
-		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			o.RequireDigestsFlags.SetFromCmd(cmd)
+			return o.RunWithContext(cmd.Context())
+		},
 		Example: `
     # Describe a bundle
-    imgpkg describe -b carvel.dev/app1-bundle`,
+    imgpkg describe -b carvel.dev/app1-bundle
+
+    # Describe a bundle without retrieving the images of the bundles nested in its nested bundles
+    imgpkg describe -b carvel.dev/app1-bundle --depth 2
+
+    # Describe the bundle recorded in a BundleLock file
+    imgpkg describe --lock bundle.lock.yml
+
+    # Render the graph of nested bundles and images of a bundle with graphviz
+    imgpkg describe -b carvel.dev/app1-bundle -o dot | dot -Tsvg > app1-bundle.svg
+
+    # Show which registries of the bundle require credentials before copying it
+    imgpkg describe -b carvel.dev/app1-bundle --preflight-auth
+
+    # Write the description as yaml and json files in a single execution
+    imgpkg describe -b carvel.dev/app1-bundle -o yaml=app1-bundle.yml,json=app1-bundle.json
+
+    # Describe a bundle as json with the images listed by name
+    imgpkg describe -b carvel.dev/app1-bundle -o json --schema-version 2 --sort-by name`,
 	}
 
 	o.BundleFlags.SetCopy(cmd)
+	o.LockInputFlags.SetOnDescribe(cmd)
 	o.RegistryFlags.Set(cmd)
 	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 5, "Concurrency")
-	cmd.Flags().StringVarP(&o.OutputType, "output-type", "o", "text", "Type of output possible values: [text, yaml]")
-	cmd.Flags().BoolVar(&o.IncludeCosignArtifacts, "cosign-artifacts", true, "Retrieve cosign artifact information (Default: true)")
+	cmd.Flags().IntVar(&o.Depth, "depth", 0, "Number of levels of bundles, starting with the described bundle, whose images are retrieved. Bundles nested deeper are shown as not expanded (Default: 0, all levels)")
+	cmd.Flags().StringVarP(&o.OutputType, "output-type", "o", "text", "Type of output possible values: [text, yaml, json, dot, mermaid]. dot and mermaid render the graph of bundles and images. "+
+		"Multiple outputs can be written to files in one execution (format: type=path,type=path)")
+	cmd.Flags().StringVar(&o.OutputFile, "output-file", "", "Write the output to this file instead of stdout, only a summary of the bundle is written to stdout")
+	cmd.Flags().StringVar(&o.SortBy, "sort-by", "origin", "Order of the bundles and images possible values: [origin, name, size]. "+
+		"Applies to the text output and to the yaml and json output with --schema-version 2, annotations are always ordered by key")
+	cmd.Flags().IntVar(&o.SchemaVersion, "schema-version", 1, "Version of the schema of the yaml and json output possible values: [1, 2]. "+
+		"Version 1 keys the bundles and images by digest, version 2 lists them in the order selected by --sort-by")
+	_ = cmd.RegisterFlagCompletionFunc("output-type", completeValues(DescribeOutputType...))
+	_ = cmd.RegisterFlagCompletionFunc("sort-by", completeValues(DescribeSortBy...))
+	_ = cmd.RegisterFlagCompletionFunc("schema-version", completeValues("1", "2"))
+	cmd.Flags().BoolVar(&o.IncludeCosignArtifacts, "cosign-artifacts", true, "Retrieve the cosign signatures, attestations and SBOMs attached to the bundle and its images (Default: true)")
+	cmd.Flags().BoolVar(&o.DedupReport, "dedup-report", false, "Report the layers shared between the images of the bundle and the size saved by sharing them")
+	cmd.Flags().BoolVar(&o.Summary, "summary", false, "Include the number of bundles and images and their total and unique size")
+	cmd.Flags().BoolVar(&o.Sizes, "sizes", false, "Include the compressed size and number of layers of each bundle and image, and the size transferred when copying the bundle")
+	cmd.Flags().StringSliceVar(&o.SummarizeAnnotations, "summarize-annotation", nil, "Show the value of this annotation for the bundle and all nested bundles at the top of the output (can be specified multiple times)")
+	cmd.Flags().BoolVar(&o.PreflightAuth, "preflight-auth", false, "Instead of the description, show the registries of the bundle and its images, whether each repository can be pulled from without credentials, "+
+		"and the credentials configured for each registry. Only the manifest of one image per repository is requested, without credentials")
 	return cmd
 }
 
 // Run functions called when the describe command is provided in the command line
 func (d *DescribeOptions) Run() error {
+	return d.RunWithContext(context.Background())
+}
+
+// RunWithContext Same as Run, the requests to the registries are cancelled when ctx is done
+func (d *DescribeOptions) RunWithContext(ctx context.Context) error {
 	err := d.validateFlags()
 	if err != nil {
 		return err
 	}
 	logLevel := util.LogWarn
 
+	bundleRef := d.BundleFlags.Bundle
+	if d.LockInputFlags.LockFilePath != "" {
+		bundleRef, err = d.LockInputFlags.BundleImage()
+		if err != nil {
+			return err
+		}
+	}
+
 	levelLogger := util.NewUILevelLogger(logLevel, util.NewLogger(d.ui))
-	description, err := v1.Describe(
-		d.BundleFlags.Bundle,
+	description, err := v1.DescribeWithContext(
+		ctx,
+		bundleRef,
 		v1.DescribeOpts{
 			Logger:                 levelLogger,
 			Concurrency:            d.Concurrency,
 			IncludeCosignArtifacts: d.IncludeCosignArtifacts,
+			Depth:                  d.Depth,
+			IncludeSizes:           d.Sizes,
 		},
 		d.RegistryFlags.AsRegistryOpts())
 	if err != nil {
 		return err
 	}
 
-	if d.OutputType == "text" {
-		p := bundleTextPrinter{logger: levelLogger}
+	if d.PreflightAuth {
+		preflight, err := registry.PreflightAuth(d.RegistryFlags.AsRegistryOpts(), preflightAuthImages(description))
+		if err != nil {
+			return err
+		}
+		return bundlePreflightAuthPrinter{logger: util.NewUILevelLogger(logLevel, util.NewLoggerNoTTY(d.ui))}.Print(d.OutputType, preflightAuthReport{Registries: preflight})
+	}
+
+	outputs, err := d.outputs()
+	if err != nil {
+		return err
+	}
+	hasSortedOutput := false
+	for _, output := range outputs {
+		hasSortedOutput = hasSortedOutput || output.outputType == "text" ||
+			(d.SchemaVersion == describeOrderedSchemaVersion && (output.outputType == "yaml" || output.outputType == "json"))
+	}
+
+	var dedupReport *v1.DedupReport
+	var summary *v1.Summary
+	if d.DedupReport || d.Summary || (hasSortedOutput && d.SortBy == "size") {
+		report, err := v1.NewDedupReport(description, d.RegistryFlags.AsRegistryOpts())
+		if err != nil {
+			return err
+		}
+		if d.Summary {
+			bundleSummary := v1.NewSummary(description, report)
+			summary = &bundleSummary
+		}
+		if d.DedupReport || hasSortedOutput {
+			dedupReport = &report
+		}
+	}
+
+	var annotationsSummary []v1.BundleAnnotations
+	if len(d.SummarizeAnnotations) > 0 {
+		annotationsSummary = v1.SummarizeAnnotations(description, d.SummarizeAnnotations)
+	}
+
+	writtenToStdout := false
+	for _, output := range outputs {
+		if output.path == "" {
+			var logger Logger = util.NewUILevelLogger(logLevel, util.NewLoggerNoTTY(d.ui))
+			if output.outputType == "text" {
+				logger = levelLogger
+			}
+			err = d.print(output.outputType, logger, description, dedupReport, summary, annotationsSummary)
+			if err != nil {
+				return err
+			}
+			writtenToStdout = true
+			continue
+		}
+
+		outputBuf := bytes.NewBufferString("")
+		err = d.print(output.outputType, util.NewBufferLogger(outputBuf), description, dedupReport, summary, annotationsSummary)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(output.path, outputBuf.Bytes(), 0600)
+		if err != nil {
+			return fmt.Errorf("Writing %s output to '%s': %s", output.outputType, output.path, err)
+		}
+	}
+
+	if !writtenToStdout {
+		printDescriptionSummary(levelLogger, description, outputs)
+	}
+	return nil
+}
+
+// describeOutput Format of the description and the file it is written to, when path is empty it is written to stdout
+type describeOutput struct {
+	outputType string
+	path       string
+}
+
+// outputs Parses --output-type and --output-file into the outputs that need to be written
+func (d *DescribeOptions) outputs() ([]describeOutput, error) {
+	var outputs []describeOutput
+	stdoutOutputs := 0
+	for _, entry := range strings.Split(d.OutputType, ",") {
+		outputType, path, hasPath := strings.Cut(strings.TrimSpace(entry), "=")
+
+		knownType := false
+		for _, s := range DescribeOutputType {
+			knownType = knownType || s == outputType
+		}
+		if !knownType {
+			return nil, fmt.Errorf("--output-type can only have the following values [text, yaml, json, dot, mermaid]")
+		}
+		if hasPath && path == "" {
+			return nil, fmt.Errorf("Expected a file path after '%s=' in --output-type", outputType)
+		}
+		if !hasPath {
+			stdoutOutputs++
+		}
+		outputs = append(outputs, describeOutput{outputType: outputType, path: path})
+	}
+
+	if d.OutputFile != "" {
+		if len(outputs) > 1 || outputs[0].path != "" {
+			return nil, fmt.Errorf("Flag --output-file can only be used with a single --output-type without a path (hint: use --output-type type=path to write multiple outputs)")
+		}
+		outputs[0].path = d.OutputFile
+		stdoutOutputs = 0
+	}
+	if stdoutOutputs > 1 {
+		return nil, fmt.Errorf("Expected at most one --output-type without a path, only one output can be written to stdout")
+	}
+	return outputs, nil
+}
+
+func (d *DescribeOptions) print(outputType string, logger Logger, description v1.Description, dedupReport *v1.DedupReport, summary *v1.Summary, annotationsSummary []v1.BundleAnnotations) error {
+	sorter := describeSorter{sortBy: d.SortBy}
+	if dedupReport != nil {
+		sorter.sizes = map[string]int64{}
+		for _, img := range dedupReport.Images {
+			sorter.sizes[img.Image] = img.Size
+		}
+	}
+
+	switch outputType {
+	case "text":
+		p := bundleTextPrinter{logger: logger, sortBy: sorter.sortBy, sizes: sorter.sizes, summarizeAnnotations: d.SummarizeAnnotations, annotationsSummary: annotationsSummary}
 		p.Print(description)
-	} else if d.OutputType == "yaml" {
-		p := bundleYAMLPrinter{logger: util.NewUILevelLogger(logLevel, util.NewLoggerNoTTY(d.ui))}
-		return p.Print(description)
+		if d.DedupReport {
+			p.PrintDedupReport(*dedupReport)
+		}
+		if summary != nil {
+			p.PrintSummary(*summary)
+		}
+	case "json":
+		p := bundleJSONPrinter{logger: logger, schemaVersion: d.SchemaVersion, sorter: sorter}
+		if !d.DedupReport {
+			dedupReport = nil
+		}
+		return p.Print(description, dedupReport, summary, annotationsSummary)
+	case dotOutputType, mermaidOutputType:
+		bundleGraphPrinter{logger: logger, format: outputType}.Print(description)
+	case "yaml":
+		p := bundleYAMLPrinter{logger: logger, schemaVersion: d.SchemaVersion, sorter: sorter}
+		err := p.Print(description)
+		if err != nil {
+			return err
+		}
+		if annotationsSummary != nil {
+			err = p.PrintAnnotationsSummary(annotationsSummary)
+			if err != nil {
+				return err
+			}
+		}
+		if dedupReport != nil && d.DedupReport {
+			err = p.PrintDedupReport(*dedupReport)
+			if err != nil {
+				return err
+			}
+		}
+		if summary != nil {
+			return p.PrintSummary(*summary)
+		}
 	}
 	return nil
 }
 
+// printDescriptionSummary Prints the number of bundles and images and where the outputs were written
+func printDescriptionSummary(logger Logger, description v1.Description, outputs []describeOutput) {
+	bundleRef, err := regname.ParseReference(description.Image)
+	if err != nil {
+		panic(fmt.Sprintf("Internal consistency: expected %s to be a digest reference", description.Image))
+	}
+
+	bundles, images := countBundlesAndImages(description, map[string]struct{}{}, map[string]struct{}{})
+	logger.Logf("Bundle SHA: %s\n", bundleRef.Identifier())
+	logger.Logf("Bundles: %d, Images: %d\n", bundles, images)
+	for _, output := range outputs {
+		logger.Logf("Wrote %s output to %s\n", output.outputType, output.path)
+	}
+}
+
+// countBundlesAndImages Returns the number of unique bundles, including the described one, and images
+func countBundlesAndImages(description v1.Description, bundles, images map[string]struct{}) (int, int) {
+	bundles[description.Image] = struct{}{}
+	for _, nested := range description.Content.Bundles {
+		countBundlesAndImages(nested, bundles, images)
+	}
+	for _, img := range description.Content.Images {
+		images[img.Image] = struct{}{}
+	}
+	return len(bundles), len(images)
+}
+
 func (d *DescribeOptions) validateFlags() error {
-	outputType := ""
-	for _, s := range DescribeOutputType {
-		if s == d.OutputType {
-			outputType = s
+	if d.BundleFlags.Bundle != "" && d.LockInputFlags.LockFilePath != "" {
+		return fmt.Errorf("Expected only one of --bundle (-b) or --lock")
+	}
+	if d.BundleFlags.Bundle == "" && d.LockInputFlags.LockFilePath == "" {
+		return fmt.Errorf("Expected either --bundle (-b) or --lock")
+	}
+	if err := d.RequireDigestsFlags.ValidateRef("--bundle (-b)", d.BundleFlags.Bundle); err != nil {
+		return err
+	}
+	if d.Depth < 0 {
+		return fmt.Errorf("Expected --depth to be greater than or equal to 0")
+	}
+
+	outputs, err := d.outputs()
+	if err != nil {
+		return err
+	}
+	if d.PreflightAuth && (len(outputs) > 1 || outputs[0].path != "" || outputs[0].outputType == dotOutputType || outputs[0].outputType == mermaidOutputType) {
+		return fmt.Errorf("Flag --preflight-auth can only be used with a single --output-type text, yaml or json written to stdout")
+	}
+	if d.PreflightAuth && (d.DedupReport || d.Summary || d.Sizes || len(d.SummarizeAnnotations) > 0) {
+		return fmt.Errorf("Flag --preflight-auth cannot be used with --dedup-report, --summary, --sizes or --summarize-annotation")
+	}
+	for _, output := range outputs {
+		if (output.outputType == dotOutputType || output.outputType == mermaidOutputType) && (d.DedupReport || d.Summary || len(d.SummarizeAnnotations) > 0) {
+			return fmt.Errorf("Flags --dedup-report, --summary and --summarize-annotation cannot be used with --output-type %s", output.outputType)
+		}
+	}
+
+	sortBy := ""
+	for _, s := range DescribeSortBy {
+		if s == d.SortBy {
+			sortBy = s
 			break
 		}
 	}
-	if outputType == "" {
-		return fmt.Errorf("--output-type can only have the following values [text, yaml]")
+	if sortBy == "" {
+		return fmt.Errorf("--sort-by can only have the following values [origin, name, size]")
+	}
+
+	knownSchemaVersion := false
+	for _, v := range DescribeSchemaVersions {
+		knownSchemaVersion = knownSchemaVersion || v == d.SchemaVersion
+	}
+	if !knownSchemaVersion {
+		return fmt.Errorf("--schema-version can only have the following values [1, 2]")
 	}
 	return nil
 }
 
 type bundleTextPrinter struct {
 	logger Logger
+	sortBy string
+	// sizes Size of each image, only present when sorting by size
+	sizes map[string]int64
+	// summarizeAnnotations Annotations that are shown for all the bundles at the top of the output
+	summarizeAnnotations []string
+	annotationsSummary   []v1.BundleAnnotations
 }
 
 func (p bundleTextPrinter) Print(description v1.Description) {
@@ -112,6 +417,21 @@ func (p bundleTextPrinter) Print(description v1.Description) {
 		panic(fmt.Sprintf("Internal consistency: expected %s to be a digest reference", description.Image))
 	}
 	p.logger.Logf("Bundle SHA: %s\n", bundleRef.Identifier())
+	if len(description.Platforms) > 0 {
+		p.logger.Logf("Platforms: %s\n", strings.Join(description.Platforms, ", "))
+	}
+	if stats := description.CopyStats; stats != nil {
+		p.logger.Logf("Copied: %s from %s in %s (%d images, %s) with imgpkg %s\n",
+			stats.CopiedAt.Format(time.RFC3339), stats.Source, stats.Duration, stats.Images, formatSize(stats.Bytes), stats.ImgpkgVersion)
+	}
+	if description.Layers > 0 {
+		p.logger.Logf("Size: %s\n", formatSizeAndLayers(description.Size, description.Layers))
+		p.logger.Logf("Transfer size: %s\n", formatSize(description.TransferSize))
+	}
+	p.printCosignArtifacts(description.CosignArtifacts, p.logger)
+	if len(p.annotationsSummary) > 0 {
+		p.printAnnotationsSummary()
+	}
 
 	p.logger.Logf("\n")
 	p.printerRec(description, p.logger, p.logger)
@@ -128,7 +448,7 @@ func (p bundleTextPrinter) printerRec(description v1.Description, originalLogger
 		indentLogger.Logf("Images:\n")
 	}
 	firstBundle := true
-	for _, b := range description.Content.Bundles {
+	for _, b := range p.sortedBundles(description.Content.Bundles) {
 		if !firstBundle {
 			originalLogger.Logf("\n")
 		} else {
@@ -137,9 +457,19 @@ func (p bundleTextPrinter) printerRec(description v1.Description, originalLogger
 		indentLogger.Logf("- Image: %s\n", b.Image)
 		indentLogger.Logf("  Type: Bundle\n")
 		indentLogger.Logf("  Origin: %s\n", b.Origin)
+		if len(b.Platforms) > 0 {
+			indentLogger.Logf("  Platforms: %s\n", strings.Join(b.Platforms, ", "))
+		}
+		if b.Layers > 0 {
+			indentLogger.Logf("  Size: %s\n", formatSizeAndLayers(b.Size, b.Layers))
+		}
+		if b.NotExpanded {
+			indentLogger.Logf("  Content: not expanded, nested deeper than --depth\n")
+		}
 		annotations := b.Annotations
 
 		p.printAnnotations(annotations, util.NewIndentedLogger(indentLogger))
+		p.printCosignArtifacts(b.CosignArtifacts, util.NewIndentedLogger(indentLogger))
 		p.printerRec(b, originalLogger, indentLogger)
 	}
 
@@ -148,7 +478,7 @@ func (p bundleTextPrinter) printerRec(description v1.Description, originalLogger
 	}
 
 	firstImage := true
-	for _, image := range description.Content.Images {
+	for _, image := range p.sortedImages(description.Content.Images) {
 		if !firstImage {
 			originalLogger.Logf("")
 		} else {
@@ -159,8 +489,143 @@ func (p bundleTextPrinter) printerRec(description v1.Description, originalLogger
 		if image.ImageType == bundle.ContentImage {
 			indentLogger.Logf("  Origin: %s\n", image.Origin)
 		}
+		if image.Layers > 0 {
+			indentLogger.Logf("  Size: %s\n", formatSizeAndLayers(image.Size, image.Layers))
+		}
 		annotations := image.Annotations
 		p.printAnnotations(annotations, util.NewIndentedLogger(indentLogger))
+		p.printCosignArtifacts(image.CosignArtifacts, util.NewIndentedLogger(indentLogger))
+	}
+}
+
+// sortedBundles returns the bundles in the order selected by --sort-by, ties are broken by digest
+func (p bundleTextPrinter) sortedBundles(bundles map[string]v1.Description) []v1.Description {
+	var result []v1.Description
+	for _, digest := range p.sorter().sortedBundleDigests(bundles) {
+		result = append(result, bundles[digest])
+	}
+	return result
+}
+
+// sortedImages returns the images in the order selected by --sort-by, ties are broken by digest
+func (p bundleTextPrinter) sortedImages(images map[string]v1.ImageInfo) []v1.ImageInfo {
+	var result []v1.ImageInfo
+	for _, digest := range p.sorter().sortedImageDigests(images) {
+		result = append(result, images[digest])
+	}
+	return result
+}
+
+func (p bundleTextPrinter) sorter() describeSorter {
+	return describeSorter{sortBy: p.sortBy, sizes: p.sizes}
+}
+
+// describeSorter Orders the bundles and images of a description as selected by --sort-by
+type describeSorter struct {
+	sortBy string
+	// sizes Size of each image, only present when sorting by size
+	sizes map[string]int64
+}
+
+// sortedBundleDigests returns the digests of the bundles in the order selected by --sort-by, ties are broken by digest
+func (s describeSorter) sortedBundleDigests(bundles map[string]v1.Description) []string {
+	var digests []string
+	for digest := range bundles {
+		digests = append(digests, digest)
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		left, right := bundles[digests[i]], bundles[digests[j]]
+		return s.less(left.Image, left.Origin, digests[i], right.Image, right.Origin, digests[j])
+	})
+	return digests
+}
+
+// sortedImageDigests returns the digests of the images in the order selected by --sort-by, ties are broken by digest
+func (s describeSorter) sortedImageDigests(images map[string]v1.ImageInfo) []string {
+	var digests []string
+	for digest := range images {
+		digests = append(digests, digest)
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		left, right := images[digests[i]], images[digests[j]]
+		return s.less(left.Image, left.Origin, digests[i], right.Image, right.Origin, digests[j])
+	})
+	return digests
+}
+
+func (s describeSorter) less(leftImage, leftOrigin, leftDigest, rightImage, rightOrigin, rightDigest string) bool {
+	switch s.sortBy {
+	case "name":
+		if leftImage != rightImage {
+			return leftImage < rightImage
+		}
+	case "size":
+		if s.sizes[leftImage] != s.sizes[rightImage] {
+			return s.sizes[leftImage] > s.sizes[rightImage]
+		}
+	default:
+		if leftOrigin != rightOrigin {
+			return leftOrigin < rightOrigin
+		}
+	}
+	return leftDigest < rightDigest
+}
+
+// orderedDescription Description of a bundle in the version 2 schema, its bundles and images are lists in the order selected by --sort-by
+type orderedDescription struct {
+	// Digest of the bundle, only present for the nested bundles
+	Digest string `json:"digest,omitempty"`
+	v1.Description
+	// Content Replaces the content of the description, that is keyed by digest
+	Content orderedContent `json:"content"`
+}
+
+// orderedContent Contents of a bundle in the version 2 schema
+type orderedContent struct {
+	Bundles []orderedDescription `json:"bundles,omitempty"`
+	Images  []orderedImageInfo   `json:"images,omitempty"`
+}
+
+// orderedImageInfo Image of a bundle in the version 2 schema
+type orderedImageInfo struct {
+	Digest string `json:"digest"`
+	v1.ImageInfo
+}
+
+// ordered Converts the description to the version 2 schema
+func (s describeSorter) ordered(digest string, description v1.Description) orderedDescription {
+	result := orderedDescription{Digest: digest, Description: description}
+	for _, bundleDigest := range s.sortedBundleDigests(description.Content.Bundles) {
+		result.Content.Bundles = append(result.Content.Bundles, s.ordered(bundleDigest, description.Content.Bundles[bundleDigest]))
+	}
+	for _, imageDigest := range s.sortedImageDigests(description.Content.Images) {
+		result.Content.Images = append(result.Content.Images, orderedImageInfo{Digest: imageDigest, ImageInfo: description.Content.Images[imageDigest]})
+	}
+	return result
+}
+
+func (p bundleTextPrinter) printAnnotationsSummary() {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Bundle\t%s\n", strings.Join(p.summarizeAnnotations, "\t"))
+	for _, bundleAnnotations := range p.annotationsSummary {
+		var values []string
+		for _, key := range p.summarizeAnnotations {
+			value, found := bundleAnnotations.Annotations[key]
+			if !found {
+				value = "-"
+			}
+			values = append(values, value)
+		}
+		fmt.Fprintf(w, "%s\t%s\n", bundleAnnotations.Bundle, strings.Join(values, "\t"))
+	}
+	w.Flush()
+
+	p.logger.Logf("\n")
+	p.logger.Logf("Annotations summary:\n")
+	indentLogger := util.NewIndentedLogger(p.logger)
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		indentLogger.Logf("%s\n", strings.TrimRight(line, " "))
 	}
 }
 
@@ -180,8 +645,62 @@ func (p bundleTextPrinter) printAnnotations(annotations map[string]string, inden
 	}
 }
 
+func (p bundleTextPrinter) printCosignArtifacts(artifacts []v1.CosignArtifact, indentLogger Logger) {
+	if len(artifacts) > 0 {
+		indentLogger.Logf("Cosign artifacts:\n")
+		artifactIndentLogger := util.NewIndentedLogger(indentLogger)
+		for _, artifact := range artifacts {
+			artifactIndentLogger.Logf("%s: %s\n", artifact.Type, artifact.Image)
+		}
+	}
+}
+
+func (p bundleTextPrinter) PrintDedupReport(report v1.DedupReport) {
+	p.logger.Logf("\n")
+	p.logger.Logf("Deduplication report:\n")
+	indentLogger := util.NewIndentedLogger(p.logger)
+	indentLogger.Logf("Total size: %s\n", formatSize(report.TotalSize))
+	indentLogger.Logf("Unique size: %s\n", formatSize(report.UniqueSize))
+	indentLogger.Logf("Saved by shared layers: %s\n", formatSize(report.SavedSize))
+
+	if len(report.SharedLayers) > 0 {
+		indentLogger.Logf("Shared layers:\n")
+		layerLogger := util.NewIndentedLogger(indentLogger)
+		for _, layer := range report.SharedLayers {
+			layerLogger.Logf("- Layer: %s\n", layer.Digest)
+			layerLogger.Logf("  Size: %s\n", formatSize(layer.Size))
+			layerLogger.Logf("  Images:\n")
+			for _, img := range layer.Images {
+				layerLogger.Logf("  - %s\n", img)
+			}
+		}
+	}
+
+	if len(report.Images) > 0 {
+		indentLogger.Logf("Size not shared with other images:\n")
+		imgLogger := util.NewIndentedLogger(indentLogger)
+		for _, img := range report.Images {
+			imgLogger.Logf("- Image: %s\n", img.Image)
+			imgLogger.Logf("  Size: %s\n", formatSize(img.Size))
+			imgLogger.Logf("  Not shared: %s\n", formatSize(img.ExclusiveSize))
+		}
+	}
+}
+
+func (p bundleTextPrinter) PrintSummary(summary v1.Summary) {
+	p.logger.Logf("\n")
+	p.logger.Logf("Summary:\n")
+	indentLogger := util.NewIndentedLogger(p.logger)
+	indentLogger.Logf("Bundles: %d\n", summary.Bundles)
+	indentLogger.Logf("Images: %d\n", summary.Images)
+	indentLogger.Logf("Total size: %s\n", formatSize(summary.TotalSize))
+	indentLogger.Logf("Unique size: %s\n", formatSize(summary.UniqueSize))
+}
+
 type bundleYAMLPrinter struct {
-	logger Logger
+	logger        Logger
+	schemaVersion int
+	sorter        describeSorter
 }
 
 func (p bundleYAMLPrinter) Print(description v1.Description) error {
@@ -190,13 +709,139 @@ func (p bundleYAMLPrinter) Print(description v1.Description) error {
 		panic(fmt.Sprintf("Internal consistency: expected %s to be a digest reference", description.Image))
 	}
 
-	yamlDesc, err := yaml.Marshal(description)
+	var yamlDesc []byte
+	if p.schemaVersion == describeOrderedSchemaVersion {
+		yamlDesc, err = yaml.Marshal(p.sorter.ordered("", description))
+	} else {
+		yamlDesc, err = yaml.Marshal(description)
+	}
 	if err != nil {
 		return err
 	}
 
+	if p.schemaVersion == describeOrderedSchemaVersion {
+		p.logger.Logf("schemaVersion: %d\n", p.schemaVersion)
+	}
 	p.logger.Logf("sha: %s\n", bundleRef.Identifier())
 	p.logger.Logf(string(yamlDesc))
 
 	return nil
 }
+
+func (p bundleYAMLPrinter) PrintDedupReport(report v1.DedupReport) error {
+	yamlReport, err := yaml.Marshal(map[string]v1.DedupReport{"dedupReport": report})
+	if err != nil {
+		return err
+	}
+
+	p.logger.Logf(string(yamlReport))
+
+	return nil
+}
+
+func (p bundleYAMLPrinter) PrintAnnotationsSummary(annotationsSummary []v1.BundleAnnotations) error {
+	yamlSummary, err := yaml.Marshal(map[string][]v1.BundleAnnotations{"annotationsSummary": annotationsSummary})
+	if err != nil {
+		return err
+	}
+
+	p.logger.Logf(string(yamlSummary))
+
+	return nil
+}
+
+func (p bundleYAMLPrinter) PrintSummary(summary v1.Summary) error {
+	yamlSummary, err := yaml.Marshal(map[string]v1.Summary{"summary": summary})
+	if err != nil {
+		return err
+	}
+
+	p.logger.Logf(string(yamlSummary))
+
+	return nil
+}
+
+// formatSize returns the size in a human readable format using binary prefixes
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// formatSizeAndLayers Formats the compressed size of an image followed by its number of layers
+func formatSizeAndLayers(size int64, layers int) string {
+	if layers == 1 {
+		return fmt.Sprintf("%s (1 layer)", formatSize(size))
+	}
+	return fmt.Sprintf("%s (%d layers)", formatSize(size), layers)
+}
+
+type bundleJSONPrinter struct {
+	logger        Logger
+	schemaVersion int
+	sorter        describeSorter
+}
+
+// bundleJSONDescription Description of the bundle written when the output type is json
+type bundleJSONDescription struct {
+	SHA string `json:"sha"`
+	v1.Description
+	DedupReport *v1.DedupReport `json:"dedupReport,omitempty"`
+	Summary     *v1.Summary     `json:"summary,omitempty"`
+	// AnnotationsSummary Values of the annotations requested with --summarize-annotation
+	AnnotationsSummary []v1.BundleAnnotations `json:"annotationsSummary,omitempty"`
+}
+
+// bundleOrderedJSONDescription Description of the bundle written when the output type is json and the schema version is 2
+type bundleOrderedJSONDescription struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	SHA           string `json:"sha"`
+	orderedDescription
+	DedupReport *v1.DedupReport `json:"dedupReport,omitempty"`
+	Summary     *v1.Summary     `json:"summary,omitempty"`
+	// AnnotationsSummary Values of the annotations requested with --summarize-annotation
+	AnnotationsSummary []v1.BundleAnnotations `json:"annotationsSummary,omitempty"`
+}
+
+func (p bundleJSONPrinter) Print(description v1.Description, dedupReport *v1.DedupReport, summary *v1.Summary, annotationsSummary []v1.BundleAnnotations) error {
+	bundleRef, err := regname.ParseReference(description.Image)
+	if err != nil {
+		panic(fmt.Sprintf("Internal consistency: expected %s to be a digest reference", description.Image))
+	}
+
+	// Maps are marshaled with their keys sorted, which keeps the output stable between executions
+	var jsonDesc interface{} = bundleJSONDescription{
+		SHA:         bundleRef.Identifier(),
+		Description: description,
+		DedupReport: dedupReport,
+		Summary:     summary,
+
+		AnnotationsSummary: annotationsSummary,
+	}
+	if p.schemaVersion == describeOrderedSchemaVersion {
+		jsonDesc = bundleOrderedJSONDescription{
+			SchemaVersion:      p.schemaVersion,
+			SHA:                bundleRef.Identifier(),
+			orderedDescription: p.sorter.ordered("", description),
+			DedupReport:        dedupReport,
+			Summary:            summary,
+
+			AnnotationsSummary: annotationsSummary,
+		}
+	}
+	jsonBytes, err := json.MarshalIndent(jsonDesc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	p.logger.Logf("%s\n", jsonBytes)
+
+	return nil
+}
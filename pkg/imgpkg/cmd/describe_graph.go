@@ -0,0 +1,156 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+)
+
+const (
+	dotOutputType     = "dot"
+	mermaidOutputType = "mermaid"
+)
+
+// graphNode Bundle or image in the dependency graph of a bundle
+type graphNode struct {
+	id        string
+	label     string
+	imageType bundle.ImageType
+}
+
+// graphEdge Relationship between a bundle and one of the bundles or images it references
+type graphEdge struct {
+	from string
+	to   string
+}
+
+// bundleGraphPrinter Prints the relationships between a bundle and its nested bundles and images as a graph
+// in the DOT or mermaid formats
+type bundleGraphPrinter struct {
+	logger Logger
+	format string
+}
+
+func (p bundleGraphPrinter) Print(description v1.Description) {
+	nodes, edges := p.graph(description)
+
+	switch p.format {
+	case dotOutputType:
+		p.logger.Logf("digraph bundle {\n")
+		p.logger.Logf("  rankdir=LR;\n")
+		for _, node := range nodes {
+			shape := "ellipse"
+			if node.imageType == bundle.BundleImage {
+				shape = "box"
+			}
+			style := ""
+			if node.imageType == bundle.SignatureImage || node.imageType == bundle.InternalImage {
+				style = ", style=dashed"
+			}
+			p.logger.Logf("  %s [label=\"%s\", shape=%s%s];\n", node.id, node.label, shape, style)
+		}
+		for _, edge := range edges {
+			p.logger.Logf("  %s -> %s;\n", edge.from, edge.to)
+		}
+		p.logger.Logf("}\n")
+
+	case mermaidOutputType:
+		p.logger.Logf("graph LR\n")
+		for _, node := range nodes {
+			if node.imageType == bundle.BundleImage {
+				p.logger.Logf("  %s[\"%s\"]\n", node.id, node.label)
+			} else {
+				p.logger.Logf("  %s(\"%s\")\n", node.id, node.label)
+			}
+		}
+		for _, edge := range edges {
+			p.logger.Logf("  %s --> %s\n", edge.from, edge.to)
+		}
+
+	default:
+		panic(fmt.Sprintf("Internal inconsistency: unknown graph format %s", p.format))
+	}
+}
+
+// graph Walks the description, in digest order, and returns every bundle and image once, even when they are
+// referenced by multiple bundles
+func (p bundleGraphPrinter) graph(description v1.Description) ([]graphNode, []graphEdge) {
+	var nodes []graphNode
+	var edges []graphEdge
+	nodeIDs := map[string]string{}
+
+	addNode := func(image string, imageType bundle.ImageType) (string, bool) {
+		key := graphNodeKey(image)
+		if id, found := nodeIDs[key]; found {
+			return id, false
+		}
+		id := fmt.Sprintf("n%d", len(nodeIDs))
+		nodeIDs[key] = id
+		nodes = append(nodes, graphNode{id: id, label: graphNodeLabel(image, imageType), imageType: imageType})
+		return id, true
+	}
+
+	var walk func(parentID string, description v1.Description)
+	walk = func(parentID string, description v1.Description) {
+		var bundleDigests []string
+		for digest := range description.Content.Bundles {
+			bundleDigests = append(bundleDigests, digest)
+		}
+		sort.Strings(bundleDigests)
+		for _, digest := range bundleDigests {
+			nestedBundle := description.Content.Bundles[digest]
+			id, isNew := addNode(nestedBundle.Image, bundle.BundleImage)
+			edges = append(edges, graphEdge{from: parentID, to: id})
+			if isNew {
+				walk(id, nestedBundle)
+			}
+		}
+		var imageDigests []string
+		for digest := range description.Content.Images {
+			imageDigests = append(imageDigests, digest)
+		}
+		sort.Strings(imageDigests)
+		for _, digest := range imageDigests {
+			image := description.Content.Images[digest]
+			id, _ := addNode(image.Image, image.ImageType)
+			edges = append(edges, graphEdge{from: parentID, to: id})
+		}
+	}
+
+	rootID, _ := addNode(description.Image, bundle.BundleImage)
+	walk(rootID, description)
+
+	return nodes, edges
+}
+
+// graphNodeKey Identifies an image by its digest, the same image can be referenced from different repositories
+func graphNodeKey(image string) string {
+	digestRef, err := regname.NewDigest(image)
+	if err != nil {
+		return image
+	}
+	return digestRef.DigestStr()
+}
+
+// graphNodeLabel Shortens the digest of the image to keep the graph readable
+func graphNodeLabel(image string, imageType bundle.ImageType) string {
+	label := image
+	digestRef, err := regname.NewDigest(image)
+	if err == nil {
+		digest := digestRef.DigestStr()
+		if len(digest) > 19 {
+			digest = digest[:19]
+		}
+		label = digestRef.Context().Name() + "@" + digest
+	}
+	if imageType == bundle.SignatureImage || imageType == bundle.InternalImage {
+		label = fmt.Sprintf("%s (%s)", label, imageType)
+	}
+	return label
+}
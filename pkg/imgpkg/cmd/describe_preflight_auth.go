@@ -0,0 +1,116 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// preflightAuthReport Registries of the images of a bundle, written when using --preflight-auth
+type preflightAuthReport struct {
+	Registries []registry.RegistryPreflight `json:"registries"`
+}
+
+// preflightAuthImages Returns the references of the bundle, its nested bundles and all their images, as they are
+// retrieved when copying the bundle
+func preflightAuthImages(description v1.Description) []string {
+	refs := map[string]struct{}{}
+	var collect func(v1.Description)
+	collect = func(desc v1.Description) {
+		refs[desc.Image] = struct{}{}
+		for _, img := range desc.Content.Images {
+			refs[img.Image] = struct{}{}
+		}
+		for _, nested := range desc.Content.Bundles {
+			collect(nested)
+		}
+	}
+	collect(description)
+
+	var result []string
+	for ref := range refs {
+		if ref != "" {
+			result = append(result, ref)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// bundlePreflightAuthPrinter Prints which registries of a bundle require credentials
+type bundlePreflightAuthPrinter struct {
+	logger Logger
+}
+
+func (p bundlePreflightAuthPrinter) Print(outputType string, report preflightAuthReport) error {
+	switch outputType {
+	case "json":
+		bs, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		p.logger.Logf("%s\n", bs)
+	case "yaml":
+		bs, err := yaml.Marshal(report)
+		if err != nil {
+			return err
+		}
+		p.logger.Logf(string(bs))
+	default:
+		p.printText(report)
+	}
+	return nil
+}
+
+func (p bundlePreflightAuthPrinter) printText(report preflightAuthReport) {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Registry\tCredentials\tRepository\tImages\tAnonymous pull\n")
+	for _, reg := range report.Registries {
+		credentials := reg.Credentials
+		if credentials == "" {
+			credentials = "none"
+		}
+		for _, repo := range reg.Repositories {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", reg.Registry, credentials, repo.Repository, repo.Images, preflightAnonymousPull(repo))
+		}
+	}
+	w.Flush()
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		p.logger.Logf("%s\n", strings.TrimRight(line, " "))
+	}
+
+	var missing []string
+	for _, reg := range report.Registries {
+		if reg.RequiresCredentials() && reg.Credentials == "" {
+			missing = append(missing, reg.Registry)
+		}
+	}
+	p.logger.Logf("\n")
+	if len(missing) == 0 {
+		p.logger.Logf("Every registry that requires credentials has credentials configured\n")
+		return
+	}
+	p.logger.Logf("Registries that require credentials and have none configured: %s\n", strings.Join(missing, ", "))
+}
+
+func preflightAnonymousPull(repo registry.RepositoryPreflight) string {
+	switch {
+	case repo.Anonymous:
+		return "allowed"
+	case repo.RequiresCredentials:
+		return "denied, requires credentials"
+	default:
+		return fmt.Sprintf("unknown (%s)", repo.Error)
+	}
+}
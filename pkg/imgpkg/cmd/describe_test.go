@@ -0,0 +1,590 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+)
+
+func TestBundleTextPrinterSorting(t *testing.T) {
+	digestA := "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	digestB := "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	digestC := "sha256:cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc"
+	description := v1.Description{
+		Image: "registry.io/bundle@" + digestA,
+		Content: v1.Content{
+			Images: map[string]v1.ImageInfo{
+				digestA: {Image: "registry.io/z-img@" + digestA, Origin: "origin.io/b-img@" + digestA, ImageType: bundle.ContentImage},
+				digestB: {Image: "registry.io/y-img@" + digestB, Origin: "origin.io/a-img@" + digestB, ImageType: bundle.ContentImage},
+				digestC: {Image: "registry.io/x-img@" + digestC, Origin: "origin.io/a-img@" + digestC, ImageType: bundle.ContentImage},
+			},
+		},
+	}
+
+	imagesOrder := func(output string) []string {
+		var images []string
+		for _, line := range strings.Split(output, "\n") {
+			if strings.Contains(line, "- Image: ") {
+				images = append(images, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "- Image: ")))
+			}
+		}
+		return images
+	}
+
+	t.Run("by default sorts by origin and then digest", func(t *testing.T) {
+		output := bytes.NewBufferString("")
+		bundleTextPrinter{logger: util.NewBufferLogger(output)}.Print(description)
+		assert.Equal(t, []string{"registry.io/y-img@" + digestB, "registry.io/x-img@" + digestC, "registry.io/z-img@" + digestA}, imagesOrder(output.String()))
+	})
+
+	t.Run("when sorting by name", func(t *testing.T) {
+		output := bytes.NewBufferString("")
+		bundleTextPrinter{logger: util.NewBufferLogger(output), sortBy: "name"}.Print(description)
+		assert.Equal(t, []string{"registry.io/x-img@" + digestC, "registry.io/y-img@" + digestB, "registry.io/z-img@" + digestA}, imagesOrder(output.String()))
+	})
+
+	t.Run("when sorting by size it shows the biggest images first", func(t *testing.T) {
+		output := bytes.NewBufferString("")
+		bundleTextPrinter{logger: util.NewBufferLogger(output), sortBy: "size", sizes: map[string]int64{
+			"registry.io/z-img@" + digestA: 10,
+			"registry.io/y-img@" + digestB: 30,
+			"registry.io/x-img@" + digestC: 20,
+		}}.Print(description)
+		assert.Equal(t, []string{"registry.io/y-img@" + digestB, "registry.io/x-img@" + digestC, "registry.io/z-img@" + digestA}, imagesOrder(output.String()))
+	})
+}
+
+func TestBundleTextPrinterAnnotationsSummary(t *testing.T) {
+	digestA := "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	digestB := "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	description := v1.Description{
+		Image:    "registry.io/bundle@" + digestA,
+		Metadata: v1.Metadata{Metadata: map[string]string{"version": "2.0.0"}},
+		Content: v1.Content{
+			Bundles: map[string]v1.Description{
+				digestB: {
+					Image:       "registry.io/nested@" + digestB,
+					Annotations: map[string]string{"version": "1.0.0", "package": "nested.carvel.dev"},
+				},
+			},
+		},
+	}
+	keys := []string{"package", "version"}
+
+	output := bytes.NewBufferString("")
+	bundleTextPrinter{
+		logger:               util.NewBufferLogger(output),
+		summarizeAnnotations: keys,
+		annotationsSummary:   v1.SummarizeAnnotations(description, keys),
+	}.Print(description)
+
+	header := "Bundle" + strings.Repeat(" ", len("registry.io/bundle@"+digestA)-len("Bundle"))
+	assert.Contains(t, output.String(), `Annotations summary:
+  `+header+`  package            version
+  registry.io/bundle@`+digestA+`  -                  2.0.0
+  registry.io/nested@`+digestB+`  nested.carvel.dev  1.0.0
+`)
+}
+
+func TestBundleTextPrinterCosignArtifacts(t *testing.T) {
+	digestA := "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	digestB := "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	digestC := "sha256:cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc"
+	digestD := "sha256:dddddddddddddddddddddddddddddddddddddddddddddddddddddddddddddddd"
+	description := v1.Description{
+		Image: "registry.io/bundle@" + digestA,
+		CosignArtifacts: []v1.CosignArtifact{
+			{Type: "signature", Image: "registry.io/bundle@" + digestD, Digest: digestD},
+		},
+		Content: v1.Content{
+			Images: map[string]v1.ImageInfo{
+				digestB: {
+					Image:     "registry.io/img@" + digestB,
+					Origin:    "origin.io/img@" + digestB,
+					ImageType: bundle.ContentImage,
+					CosignArtifacts: []v1.CosignArtifact{
+						{Type: "sbom", Image: "registry.io/img@" + digestC, Digest: digestC},
+					},
+				},
+			},
+		},
+	}
+
+	output := bytes.NewBufferString("")
+	bundleTextPrinter{logger: util.NewBufferLogger(output)}.Print(description)
+	assert.Equal(t, `Bundle SHA: `+digestA+`
+Cosign artifacts:
+  signature: registry.io/bundle@`+digestD+`
+
+Images:
+  - Image: registry.io/img@`+digestB+`
+    Type: Image
+    Origin: origin.io/img@`+digestB+`
+    Cosign artifacts:
+      sbom: registry.io/img@`+digestC+`
+`, output.String())
+}
+
+func TestBundleTextPrinterSizes(t *testing.T) {
+	digestA := "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	digestB := "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	description := v1.Description{
+		Image:        "registry.io/bundle@" + digestA,
+		Size:         2048,
+		Layers:       1,
+		TransferSize: 3 * 1024 * 1024,
+		Content: v1.Content{
+			Images: map[string]v1.ImageInfo{
+				digestB: {
+					Image:     "registry.io/img@" + digestB,
+					Origin:    "origin.io/img@" + digestB,
+					ImageType: bundle.ContentImage,
+					Size:      3*1024*1024 - 2048,
+					Layers:    3,
+				},
+			},
+		},
+	}
+
+	output := bytes.NewBufferString("")
+	bundleTextPrinter{logger: util.NewBufferLogger(output)}.Print(description)
+	assert.Equal(t, `Bundle SHA: `+digestA+`
+Size: 2.0 KiB (1 layer)
+Transfer size: 3.0 MiB
+
+Images:
+  - Image: registry.io/img@`+digestB+`
+    Type: Image
+    Origin: origin.io/img@`+digestB+`
+    Size: 3.0 MiB (3 layers)
+`, output.String())
+}
+
+func TestDescribeValidateFlags(t *testing.T) {
+	t.Run("fails when no bundle or lock file are provided", func(t *testing.T) {
+		describe := DescribeOptions{OutputType: "text", SortBy: "origin", SchemaVersion: 1}
+		err := describe.validateFlags()
+		assert.EqualError(t, err, "Expected either --bundle (-b) or --lock")
+	})
+
+	t.Run("fails when both bundle and lock file are provided", func(t *testing.T) {
+		describe := DescribeOptions{
+			BundleFlags:    BundleFlags{Bundle: "my-bundle"},
+			LockInputFlags: LockInputFlags{LockFilePath: "bundle.lock.yml"},
+			OutputType:     "text",
+			SortBy:         "origin",
+			SchemaVersion:  1,
+		}
+		err := describe.validateFlags()
+		assert.EqualError(t, err, "Expected only one of --bundle (-b) or --lock")
+	})
+
+	t.Run("succeeds when only the lock file is provided", func(t *testing.T) {
+		describe := DescribeOptions{
+			LockInputFlags: LockInputFlags{LockFilePath: "bundle.lock.yml"},
+			OutputType:     "text",
+			SortBy:         "origin",
+			SchemaVersion:  1,
+		}
+		assert.NoError(t, describe.validateFlags())
+	})
+
+	t.Run("fails when a graph output is combined with reports", func(t *testing.T) {
+		describe := DescribeOptions{
+			BundleFlags:   BundleFlags{Bundle: "my-bundle"},
+			OutputType:    "mermaid",
+			SortBy:        "origin",
+			SchemaVersion: 1,
+			Summary:       true,
+		}
+		err := describe.validateFlags()
+		assert.EqualError(t, err, "Flags --dedup-report, --summary and --summarize-annotation cannot be used with --output-type mermaid")
+	})
+
+	t.Run("fails when the depth is negative", func(t *testing.T) {
+		describe := DescribeOptions{
+			BundleFlags:   BundleFlags{Bundle: "my-bundle"},
+			OutputType:    "text",
+			SortBy:        "origin",
+			SchemaVersion: 1,
+			Depth:         -1,
+		}
+		err := describe.validateFlags()
+		assert.EqualError(t, err, "Expected --depth to be greater than or equal to 0")
+	})
+
+	t.Run("fails when preflight auth is combined with a graph output", func(t *testing.T) {
+		describe := DescribeOptions{
+			BundleFlags:   BundleFlags{Bundle: "my-bundle"},
+			OutputType:    "dot",
+			SortBy:        "origin",
+			SchemaVersion: 1,
+			PreflightAuth: true,
+		}
+		err := describe.validateFlags()
+		assert.EqualError(t, err, "Flag --preflight-auth can only be used with a single --output-type text, yaml or json written to stdout")
+	})
+
+	t.Run("fails when the schema version is unknown", func(t *testing.T) {
+		describe := DescribeOptions{
+			BundleFlags:   BundleFlags{Bundle: "my-bundle"},
+			OutputType:    "yaml",
+			SortBy:        "origin",
+			SchemaVersion: 3,
+		}
+		err := describe.validateFlags()
+		assert.EqualError(t, err, "--schema-version can only have the following values [1, 2]")
+	})
+}
+
+func TestBundlePreflightAuthPrinter(t *testing.T) {
+	digestA := "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	digestB := "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+
+	t.Run("collects the bundle, its nested bundles and their images", func(t *testing.T) {
+		description := v1.Description{
+			Image: "registry.io/bundle@" + digestA,
+			Content: v1.Content{
+				Images: map[string]v1.ImageInfo{
+					digestB: {Image: "other.io/img@" + digestB, ImageType: bundle.ContentImage},
+				},
+				Bundles: map[string]v1.Description{
+					digestB: {
+						Image: "registry.io/nested@" + digestB,
+						Content: v1.Content{Images: map[string]v1.ImageInfo{
+							digestB: {Image: "other.io/img@" + digestB, ImageType: bundle.ContentImage},
+						}},
+					},
+				},
+			},
+		}
+		assert.Equal(t, []string{"other.io/img@" + digestB, "registry.io/bundle@" + digestA, "registry.io/nested@" + digestB}, preflightAuthImages(description))
+	})
+
+	t.Run("reports the registries that require credentials and have none", func(t *testing.T) {
+		output := bytes.NewBufferString("")
+		err := bundlePreflightAuthPrinter{logger: util.NewBufferLogger(output)}.Print("text", preflightAuthReport{Registries: []registry.RegistryPreflight{
+			{Registry: "other.io", Repositories: []registry.RepositoryPreflight{{Repository: "other.io/img", Images: 1, RequiresCredentials: true}}},
+			{Registry: "registry.io", Credentials: "username 'reader'", Repositories: []registry.RepositoryPreflight{{Repository: "registry.io/bundle", Images: 2, Anonymous: true}}},
+		}})
+		assert.NoError(t, err)
+		assert.Equal(t, `Registry     Credentials        Repository          Images  Anonymous pull
+other.io     none               other.io/img        1       denied, requires credentials
+registry.io  username 'reader'  registry.io/bundle  2       allowed
+
+Registries that require credentials and have none configured: other.io
+`, output.String())
+	})
+}
+
+func TestBundleGraphPrinter(t *testing.T) {
+	digestA := "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	digestB := "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	digestC := "sha256:cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc"
+	digestD := "sha256:dddddddddddddddddddddddddddddddddddddddddddddddddddddddddddddddd"
+	sharedImage := v1.ImageInfo{Image: "registry.io/bundle@" + digestD, Origin: "origin.io/img@" + digestD, ImageType: bundle.ContentImage}
+	description := v1.Description{
+		Image: "registry.io/bundle@" + digestA,
+		Content: v1.Content{
+			Bundles: map[string]v1.Description{
+				digestB: {
+					Image:   "registry.io/bundle@" + digestB,
+					Content: v1.Content{Images: map[string]v1.ImageInfo{digestD: sharedImage}},
+				},
+			},
+			Images: map[string]v1.ImageInfo{
+				digestC: {Image: "registry.io/bundle@" + digestC, ImageType: bundle.SignatureImage},
+				digestD: sharedImage,
+			},
+		},
+	}
+
+	t.Run("when the format is dot, it prints every bundle and image once", func(t *testing.T) {
+		output := bytes.NewBufferString("")
+		bundleGraphPrinter{logger: util.NewBufferLogger(output), format: "dot"}.Print(description)
+		assert.Equal(t, `digraph bundle {
+  rankdir=LR;
+  n0 [label="registry.io/bundle@sha256:aaaaaaaaaaaa", shape=box];
+  n1 [label="registry.io/bundle@sha256:bbbbbbbbbbbb", shape=box];
+  n2 [label="registry.io/bundle@sha256:dddddddddddd", shape=ellipse];
+  n3 [label="registry.io/bundle@sha256:cccccccccccc (Signature)", shape=ellipse, style=dashed];
+  n0 -> n1;
+  n1 -> n2;
+  n0 -> n3;
+  n0 -> n2;
+}
+`, output.String())
+	})
+
+	t.Run("when the format is mermaid, it prints bundles as boxes and images as rounded boxes", func(t *testing.T) {
+		output := bytes.NewBufferString("")
+		bundleGraphPrinter{logger: util.NewBufferLogger(output), format: "mermaid"}.Print(description)
+		assert.Equal(t, `graph LR
+  n0["registry.io/bundle@sha256:aaaaaaaaaaaa"]
+  n1["registry.io/bundle@sha256:bbbbbbbbbbbb"]
+  n2("registry.io/bundle@sha256:dddddddddddd")
+  n3("registry.io/bundle@sha256:cccccccccccc (Signature)")
+  n0 --> n1
+  n1 --> n2
+  n0 --> n3
+  n0 --> n2
+`, output.String())
+	})
+}
+
+func TestBundleJSONPrinter(t *testing.T) {
+	digestA := "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	digestB := "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	description := v1.Description{
+		Image:       "registry.io/bundle@" + digestA,
+		Origin:      "origin.io/bundle@" + digestA,
+		Annotations: map[string]string{"some.annotation": "some-value"},
+		Content: v1.Content{
+			Images: map[string]v1.ImageInfo{
+				digestB: {Image: "registry.io/bundle@" + digestB, Origin: "origin.io/img@" + digestB, ImageType: bundle.ContentImage},
+			},
+		},
+	}
+
+	t.Run("the field names are part of the output contract and do not change", func(t *testing.T) {
+		output := bytes.NewBufferString("")
+		err := bundleJSONPrinter{logger: util.NewBufferLogger(output)}.Print(description, nil, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, `{
+  "sha": "`+digestA+`",
+  "image": "registry.io/bundle@`+digestA+`",
+  "origin": "origin.io/bundle@`+digestA+`",
+  "annotations": {
+    "some.annotation": "some-value"
+  },
+  "metadata": {},
+  "content": {
+    "images": {
+      "`+digestB+`": {
+        "image": "registry.io/bundle@`+digestB+`",
+        "origin": "origin.io/img@`+digestB+`",
+        "imageType": "Image"
+      }
+    }
+  }
+}
+`, output.String())
+	})
+
+	t.Run("with schema version 2 the images are listed in the order selected by --sort-by", func(t *testing.T) {
+		digestC := "sha256:cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc"
+		description.Content.Images = map[string]v1.ImageInfo{
+			digestB: {Image: "registry.io/bundle@" + digestB, Origin: "origin.io/z-img@" + digestB, ImageType: bundle.ContentImage},
+			digestC: {Image: "registry.io/bundle@" + digestC, Origin: "origin.io/a-img@" + digestC, ImageType: bundle.ContentImage},
+		}
+
+		output := bytes.NewBufferString("")
+		err := bundleJSONPrinter{logger: util.NewBufferLogger(output), schemaVersion: 2}.Print(description, nil, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, `{
+  "schemaVersion": 2,
+  "sha": "`+digestA+`",
+  "image": "registry.io/bundle@`+digestA+`",
+  "origin": "origin.io/bundle@`+digestA+`",
+  "annotations": {
+    "some.annotation": "some-value"
+  },
+  "metadata": {},
+  "content": {
+    "images": [
+      {
+        "digest": "`+digestC+`",
+        "image": "registry.io/bundle@`+digestC+`",
+        "origin": "origin.io/a-img@`+digestC+`",
+        "imageType": "Image"
+      },
+      {
+        "digest": "`+digestB+`",
+        "image": "registry.io/bundle@`+digestB+`",
+        "origin": "origin.io/z-img@`+digestB+`",
+        "imageType": "Image"
+      }
+    ]
+  }
+}
+`, output.String())
+	})
+}
+
+func TestBundleYAMLPrinter(t *testing.T) {
+	digestA := "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	digestB := "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	digestC := "sha256:cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc"
+	description := v1.Description{
+		Image:  "registry.io/bundle@" + digestA,
+		Origin: "origin.io/bundle@" + digestA,
+		Content: v1.Content{
+			Bundles: map[string]v1.Description{
+				digestB: {
+					Image:  "registry.io/nested@" + digestB,
+					Origin: "origin.io/nested@" + digestB,
+					Content: v1.Content{
+						Images: map[string]v1.ImageInfo{
+							digestA: {Image: "registry.io/x-img@" + digestA, Origin: "origin.io/b-img@" + digestA, ImageType: bundle.ContentImage},
+							digestC: {Image: "registry.io/y-img@" + digestC, Origin: "origin.io/a-img@" + digestC, ImageType: bundle.ContentImage},
+						},
+					},
+				},
+			},
+			Images: map[string]v1.ImageInfo{
+				digestB: {Image: "registry.io/nested@" + digestB, Origin: "origin.io/nested@" + digestB, ImageType: bundle.BundleImage},
+			},
+		},
+	}
+
+	t.Run("with schema version 1 the bundles and images are keyed by digest", func(t *testing.T) {
+		output := bytes.NewBufferString("")
+		err := bundleYAMLPrinter{logger: util.NewBufferLogger(output), schemaVersion: 1}.Print(description)
+		assert.NoError(t, err)
+		assert.Equal(t, `sha: `+digestA+`
+content:
+  bundles:
+    `+digestB+`:
+      content:
+        images:
+          `+digestA+`:
+            image: registry.io/x-img@`+digestA+`
+            imageType: Image
+            origin: origin.io/b-img@`+digestA+`
+          `+digestC+`:
+            image: registry.io/y-img@`+digestC+`
+            imageType: Image
+            origin: origin.io/a-img@`+digestC+`
+      image: registry.io/nested@`+digestB+`
+      metadata: {}
+      origin: origin.io/nested@`+digestB+`
+  images:
+    `+digestB+`:
+      image: registry.io/nested@`+digestB+`
+      imageType: Bundle
+      origin: origin.io/nested@`+digestB+`
+image: registry.io/bundle@`+digestA+`
+metadata: {}
+origin: origin.io/bundle@`+digestA+`
+`, output.String())
+	})
+
+	t.Run("with schema version 2 the bundles and images are listed in the order selected by --sort-by", func(t *testing.T) {
+		output := bytes.NewBufferString("")
+		err := bundleYAMLPrinter{logger: util.NewBufferLogger(output), schemaVersion: 2}.Print(description)
+		assert.NoError(t, err)
+		assert.Equal(t, `schemaVersion: 2
+sha: `+digestA+`
+content:
+  bundles:
+  - content:
+      images:
+      - digest: `+digestC+`
+        image: registry.io/y-img@`+digestC+`
+        imageType: Image
+        origin: origin.io/a-img@`+digestC+`
+      - digest: `+digestA+`
+        image: registry.io/x-img@`+digestA+`
+        imageType: Image
+        origin: origin.io/b-img@`+digestA+`
+    digest: `+digestB+`
+    image: registry.io/nested@`+digestB+`
+    metadata: {}
+    origin: origin.io/nested@`+digestB+`
+  images:
+  - digest: `+digestB+`
+    image: registry.io/nested@`+digestB+`
+    imageType: Bundle
+    origin: origin.io/nested@`+digestB+`
+image: registry.io/bundle@`+digestA+`
+metadata: {}
+origin: origin.io/bundle@`+digestA+`
+`, output.String())
+
+		output = bytes.NewBufferString("")
+		err = bundleYAMLPrinter{logger: util.NewBufferLogger(output), schemaVersion: 2, sorter: describeSorter{sortBy: "name"}}.Print(description)
+		assert.NoError(t, err)
+		assert.Less(t, strings.Index(output.String(), "image: registry.io/x-img@"), strings.Index(output.String(), "image: registry.io/y-img@"))
+	})
+}
+
+func TestDescribeOutputs(t *testing.T) {
+	t.Run("a single output type is written to stdout", func(t *testing.T) {
+		outputs, err := (&DescribeOptions{OutputType: "yaml"}).outputs()
+		assert.NoError(t, err)
+		assert.Equal(t, []describeOutput{{outputType: "yaml"}}, outputs)
+	})
+
+	t.Run("--output-file writes the output type to the file", func(t *testing.T) {
+		outputs, err := (&DescribeOptions{OutputType: "json", OutputFile: "desc.json"}).outputs()
+		assert.NoError(t, err)
+		assert.Equal(t, []describeOutput{{outputType: "json", path: "desc.json"}}, outputs)
+	})
+
+	t.Run("multiple output types can be written to files and one to stdout", func(t *testing.T) {
+		outputs, err := (&DescribeOptions{OutputType: "yaml=desc.yml, json=desc.json,text"}).outputs()
+		assert.NoError(t, err)
+		assert.Equal(t, []describeOutput{
+			{outputType: "yaml", path: "desc.yml"},
+			{outputType: "json", path: "desc.json"},
+			{outputType: "text"},
+		}, outputs)
+	})
+
+	t.Run("fails when more than one output is written to stdout", func(t *testing.T) {
+		_, err := (&DescribeOptions{OutputType: "yaml,json=desc.json,text"}).outputs()
+		assert.EqualError(t, err, "Expected at most one --output-type without a path, only one output can be written to stdout")
+	})
+
+	t.Run("fails when --output-file is used with multiple outputs", func(t *testing.T) {
+		_, err := (&DescribeOptions{OutputType: "yaml=desc.yml,json", OutputFile: "desc.json"}).outputs()
+		assert.EqualError(t, err, "Flag --output-file can only be used with a single --output-type without a path (hint: use --output-type type=path to write multiple outputs)")
+	})
+
+	t.Run("fails when the output type is unknown or the path is missing", func(t *testing.T) {
+		_, err := (&DescribeOptions{OutputType: "xml=desc.xml"}).outputs()
+		assert.EqualError(t, err, "--output-type can only have the following values [text, yaml, json, dot, mermaid]")
+
+		_, err = (&DescribeOptions{OutputType: "yaml="}).outputs()
+		assert.EqualError(t, err, "Expected a file path after 'yaml=' in --output-type")
+	})
+}
+
+func TestPrintDescriptionSummary(t *testing.T) {
+	digestA := "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	digestB := "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	digestC := "sha256:cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc"
+	sharedImage := v1.ImageInfo{Image: "registry.io/bundle@" + digestC, ImageType: bundle.ContentImage}
+	description := v1.Description{
+		Image: "registry.io/bundle@" + digestA,
+		Content: v1.Content{
+			Bundles: map[string]v1.Description{
+				digestB: {
+					Image:   "registry.io/bundle@" + digestB,
+					Content: v1.Content{Images: map[string]v1.ImageInfo{digestC: sharedImage}},
+				},
+			},
+			Images: map[string]v1.ImageInfo{digestC: sharedImage},
+		},
+	}
+
+	output := bytes.NewBufferString("")
+	printDescriptionSummary(util.NewBufferLogger(output), description, []describeOutput{
+		{outputType: "yaml", path: "desc.yml"},
+		{outputType: "json", path: "desc.json"},
+	})
+	assert.Equal(t, `Bundle SHA: `+digestA+`
+Bundles: 2, Images: 1
+Wrote yaml output to desc.yml
+Wrote json output to desc.json
+`, output.String())
+}
@@ -0,0 +1,246 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/spf13/cobra"
+	ctlimgset "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+)
+
+// DiffOptions Command Line options that can be provided to the diff command
+type DiffOptions struct {
+	ui ui.UI
+
+	RegistryFlags   RegistryFlags
+	OutputTypeFlags OutputTypeFlags
+
+	OldBundle   string
+	NewBundle   string
+	Concurrency int
+}
+
+// NewDiffOptions constructor for building a DiffOptions, holding values derived via flags
+func NewDiffOptions(ui ui.UI) *DiffOptions {
+	return &DiffOptions{ui: ui}
+}
+
+// NewDiffCmd Creates the diff command
+func NewDiffCmd(o *DiffOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff OLD-BUNDLE NEW-BUNDLE",
+		Short: "Show the images and annotations that changed between two versions of a bundle",
+		Long: `Show the images and annotations that changed between two versions of a bundle.
+The images of both bundles, and of their nested bundles, are matched by the repository they originally came from and
+reported as added, removed or changed, when their digest or the annotations in the ImagesLock are different.
+The annotations of both bundles are compared as well.
+Each bundle can be a reference to a bundle in a registry or the path to a tar created by copy --to-tar.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			o.OldBundle = args[0]
+			o.NewBundle = args[1]
+			return o.Run()
+		},
+		Example: `
+    # Show what changed between two versions of bundle repo/app1-bundle
+    imgpkg diff repo/app1-bundle:1.0.0 repo/app1-bundle:1.1.0
+
+    # Show what changed between the bundle in a tar and the one in the registry, as JSON
+    imgpkg diff /Volumes/app1-bundle.tar repo/app1-bundle:1.1.0 --output-type json`,
+	}
+
+	o.RegistryFlags.Set(cmd)
+	o.OutputTypeFlags.Set(cmd)
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 5, "Concurrency")
+	return cmd
+}
+
+// Run Describes both bundles and prints the difference between them
+func (d *DiffOptions) Run() error {
+	err := d.OutputTypeFlags.Validate()
+	if err != nil {
+		return err
+	}
+	logsUI := d.OutputTypeFlags.LogsUI(d.ui)
+	logger := util.NewUILevelLogger(util.LogWarn, util.NewLogger(logsUI))
+
+	registryOpts := d.RegistryFlags.AsRegistryOpts()
+	var descriptions []v1.Description
+	for _, bundleRef := range []string{d.OldBundle, d.NewBundle} {
+		ref, cleanUp, err := d.bundleFromTar(bundleRef, registryOpts, logger)
+		if err != nil {
+			return err
+		}
+
+		description, err := v1.Describe(ref, v1.DescribeOpts{
+			Logger:      logger,
+			Concurrency: d.Concurrency,
+		}, registryOpts)
+		cleanUp()
+		if err != nil {
+			return err
+		}
+		descriptions = append(descriptions, description)
+	}
+
+	diff, err := v1.NewBundleDiff(descriptions[0], descriptions[1])
+	if err != nil {
+		return err
+	}
+
+	if d.OutputTypeFlags.IsJSON() {
+		return d.OutputTypeFlags.WriteJSON(d.ui, diff)
+	}
+	d.printText(diff)
+	return nil
+}
+
+// bundleFromTar When the bundle is the path to a tar, imports the tar into a temporary OCI Image Layout and returns
+// the location of the bundle in it. The returned function removes the OCI Image Layout and must be called once the
+// bundle is described
+func (d *DiffOptions) bundleFromTar(bundleRef string, registryOpts registry.Opts, logger util.LoggerWithLevels) (string, func(), error) {
+	noop := func() {}
+	if info, err := os.Stat(bundleRef); err != nil || !info.Mode().IsRegular() {
+		return bundleRef, noop, nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "imgpkg-diff-")
+	if err != nil {
+		return "", noop, fmt.Errorf("Creating temporary folder: %s", err)
+	}
+	server := registry.NewOCILayoutServer(filepath.Join(tmpDir, "bundle"), false)
+	cleanUp := func() {
+		server.Close()
+		os.RemoveAll(tmpDir)
+	}
+
+	err = server.Start()
+	if err != nil {
+		cleanUp()
+		return "", noop, err
+	}
+
+	reg, err := registry.NewSimpleRegistry(registryOpts)
+	if err != nil {
+		cleanUp()
+		return "", noop, err
+	}
+
+	importRepo, err := regname.NewRepository(server.Repository())
+	if err != nil {
+		cleanUp()
+		return "", noop, fmt.Errorf("Building import repository ref: %s", err)
+	}
+
+	imageSet := ctlimgset.NewImageSet(d.Concurrency, logger, util.DefaultTagGenerator{})
+	tarImageSet := ctlimgset.NewTarImageSet(imageSet, d.Concurrency, logger)
+	processedImages, err := tarImageSet.Import(bundleRef, importRepo, reg)
+	if err != nil {
+		cleanUp()
+		return "", noop, fmt.Errorf("Reading tar '%s': %s", bundleRef, err)
+	}
+
+	for _, processedImage := range processedImages.All() {
+		if _, ok := processedImage.Labels[rootBundleLabelKey]; ok && processedImage.ImageIndex == nil {
+			return processedImage.DigestRef, cleanUp, nil
+		}
+	}
+
+	cleanUp()
+	return "", noop, fmt.Errorf("Expected tar '%s' to contain a bundle, copy it with copy --bundle (-b) --to-tar", bundleRef)
+}
+
+func (d *DiffOptions) printText(diff v1.BundleDiff) {
+	imagesTable := uitable.Table{
+		Title:   "Images",
+		Content: "images",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Repository"),
+			uitable.NewHeader("Type"),
+			uitable.NewHeader("Change"),
+			uitable.NewHeader("Old"),
+			uitable.NewHeader("New"),
+		},
+	}
+
+	for _, img := range diff.Added {
+		imagesTable.Rows = append(imagesTable.Rows, bundleDiffImageRow(img, "added"))
+	}
+	for _, img := range diff.Removed {
+		imagesTable.Rows = append(imagesTable.Rows, bundleDiffImageRow(img, "removed"))
+	}
+	for _, img := range diff.Changed {
+		imagesTable.Rows = append(imagesTable.Rows, bundleDiffImageRow(img, "changed"))
+	}
+	imagesTable.SortBy = []uitable.ColumnSort{{Column: 0, Asc: true}}
+	d.ui.PrintTable(imagesTable)
+
+	annotationsTable := uitable.Table{
+		Title:   "Annotations",
+		Content: "annotations",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Image"),
+			uitable.NewHeader("Key"),
+			uitable.NewHeader("Old value"),
+			uitable.NewHeader("New value"),
+		},
+	}
+
+	for _, change := range diff.Annotations {
+		annotationsTable.Rows = append(annotationsTable.Rows, bundleDiffAnnotationRow("bundle", change))
+	}
+	for _, img := range diff.Changed {
+		for _, change := range img.Annotations {
+			annotationsTable.Rows = append(annotationsTable.Rows, bundleDiffAnnotationRow(img.Repository, change))
+		}
+	}
+	d.ui.PrintTable(annotationsTable)
+
+	d.ui.BeginLinef("%d added, %d removed, %d changed\n", len(diff.Added), len(diff.Removed), len(diff.Changed))
+}
+
+func bundleDiffImageRow(img v1.BundleImageDiff, change string) []uitable.Value {
+	return []uitable.Value{
+		uitable.NewValueString(img.Repository),
+		uitable.NewValueString(string(img.ImageType)),
+		uitable.NewValueString(change),
+		uitable.NewValueString(bundleDiffImages(img.OldImages)),
+		uitable.NewValueString(bundleDiffImages(img.NewImages)),
+	}
+}
+
+func bundleDiffAnnotationRow(image string, change v1.AnnotationChange) []uitable.Value {
+	return []uitable.Value{
+		uitable.NewValueString(image),
+		uitable.NewValueString(change.Key),
+		uitable.NewValueString(bundleDiffValue(change.OldValue)),
+		uitable.NewValueString(bundleDiffValue(change.NewValue)),
+	}
+}
+
+func bundleDiffImages(images []string) string {
+	if len(images) == 0 {
+		return "-"
+	}
+	return strings.Join(images, "\n")
+}
+
+func bundleDiffValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
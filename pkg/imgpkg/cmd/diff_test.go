@@ -0,0 +1,96 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestDiff(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegistry := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegistry.CleanUp()
+
+	oldApp := fakeRegistry.WithRandomImage("app/app")
+	newApp := fakeRegistry.WithRandomImage("app/app")
+	db := fakeRegistry.WithRandomImage("app/db")
+	cache := fakeRegistry.WithRandomImage("app/cache")
+	oldBundle := fakeRegistry.WithRandomBundleAndImages("app/bundle", []lockconfig.ImageRef{{Image: oldApp.RefDigest}, {Image: db.RefDigest}})
+	newBundle := fakeRegistry.WithRandomBundleAndImages("app/bundle", []lockconfig.ImageRef{{Image: newApp.RefDigest}, {Image: cache.RefDigest}})
+	fakeRegistry.Build()
+
+	runDiff := func(t *testing.T, oldRef, newRef string) v1.BundleDiff {
+		output := &bytes.Buffer{}
+		subject := NewDiffOptions(ui.NewWriterUI(output, output, ui.NewNoopLogger()))
+		subject.OutputTypeFlags.OutputType = "json"
+		subject.Concurrency = 2
+		subject.OldBundle = oldRef
+		subject.NewBundle = newRef
+		require.NoError(t, subject.Run())
+
+		var diff v1.BundleDiff
+		require.NoError(t, json.Unmarshal(output.Bytes(), &diff), output.String())
+		return diff
+	}
+
+	assertDiff := func(t *testing.T, diff v1.BundleDiff) {
+		require.Len(t, diff.Added, 1)
+		assert.Equal(t, fakeRegistry.ReferenceOnTestServer("app/cache"), diff.Added[0].Repository)
+		require.Len(t, diff.Removed, 1)
+		assert.Equal(t, fakeRegistry.ReferenceOnTestServer("app/db"), diff.Removed[0].Repository)
+		require.Len(t, diff.Changed, 1)
+		assert.Equal(t, fakeRegistry.ReferenceOnTestServer("app/app"), diff.Changed[0].Repository)
+		assert.Equal(t, []string{newApp.RefDigest}, diff.Changed[0].NewImages)
+	}
+
+	t.Run("compares two bundles in a registry", func(t *testing.T) {
+		diff := runDiff(t, oldBundle.RefDigest, newBundle.RefDigest)
+		assertDiff(t, diff)
+		assert.Equal(t, []string{oldApp.RefDigest}, diff.Changed[0].OldImages)
+	})
+
+	t.Run("compares a bundle in a tar with a bundle in a registry", func(t *testing.T) {
+		tarPath := filepath.Join(t.TempDir(), "bundle.tar")
+		err := v1.CopyToArchive(oldBundle.RefDigest, v1.FileArchiveWriter{Path: tarPath}, v1.CopyToArchiveOpts{Logger: logger, Concurrency: 2}, registry.Opts{EnvironFunc: os.Environ})
+		require.NoError(t, err)
+
+		diff := runDiff(t, tarPath, newBundle.RefDigest)
+		assertDiff(t, diff)
+		require.Len(t, diff.Changed[0].OldImages, 1)
+		assert.Contains(t, diff.Changed[0].OldImages[0], oldApp.Digest)
+	})
+
+	t.Run("fails when the tar does not contain a bundle", func(t *testing.T) {
+		tarPath := filepath.Join(t.TempDir(), "image.tar")
+		err := v1.CopyToArchive(db.RefDigest, v1.FileArchiveWriter{Path: tarPath}, v1.CopyToArchiveOpts{Logger: logger, Concurrency: 2}, registry.Opts{EnvironFunc: os.Environ})
+		require.NoError(t, err)
+
+		subject := NewDiffOptions(ui.NewWriterUI(&bytes.Buffer{}, &bytes.Buffer{}, ui.NewNoopLogger()))
+		subject.OldBundle = tarPath
+		subject.NewBundle = newBundle.RefDigest
+		subject.Concurrency = 2
+		require.ErrorContains(t, subject.Run(), "to contain a bundle")
+	})
+
+	t.Run("accepts the bundles as arguments of the command", func(t *testing.T) {
+		confUI := ui.NewConfUI(ui.NewNoopLogger())
+		defer confUI.Flush()
+
+		imgpkgCmd := NewDefaultImgpkgCmd(confUI)
+		imgpkgCmd.SetArgs([]string{"diff", oldBundle.RefDigest, newBundle.RefDigest, "--output-type", "json"})
+		require.NoError(t, imgpkgCmd.Execute())
+	})
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// exampleScenarios Scenarios printed by the examples command. The imgpkg commands in them are checked against the
+// commands and flags of imgpkg by the tests, so that they keep working as the CLI changes
+//
+//go:embed examples/*.yml
+var exampleScenarios embed.FS
+
+// ExampleScenario Sequence of commands that accomplish a task with imgpkg
+type ExampleScenario struct {
+	Name        string        `json:"name"`
+	Title       string        `json:"title"`
+	Keywords    []string      `json:"keywords"`
+	Description string        `json:"description"`
+	Steps       []ExampleStep `json:"steps"`
+}
+
+// ExampleStep Command, and what it does, of a scenario
+type ExampleStep struct {
+	Description string `json:"description,omitempty"`
+	Command     string `json:"command"`
+}
+
+// Matches Returns true when the keyword is part of the name or title of the scenario, or is one of its keywords
+func (e ExampleScenario) Matches(keyword string) bool {
+	keyword = strings.ToLower(keyword)
+	if strings.Contains(strings.ToLower(e.Name), keyword) || strings.Contains(strings.ToLower(e.Title), keyword) {
+		return true
+	}
+	for _, k := range e.Keywords {
+		if strings.ToLower(k) == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+// String Returns the scenario as a script, with the descriptions as comments
+func (e ExampleScenario) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n", e.Title)
+	for _, line := range strings.Split(strings.TrimSpace(e.Description), "\n") {
+		fmt.Fprintf(&sb, "# %s\n", line)
+	}
+	for _, step := range e.Steps {
+		sb.WriteString("\n")
+		if step.Description != "" {
+			fmt.Fprintf(&sb, "# %s\n", step.Description)
+		}
+		fmt.Fprintf(&sb, "%s\n", step.Command)
+	}
+	return sb.String()
+}
+
+// ExamplesOptions Command Line options that can be provided to the examples command
+type ExamplesOptions struct {
+	ui ui.UI
+
+	Keyword string
+}
+
+// NewExamplesOptions constructor for building an ExamplesOptions, holding values derived via flags
+func NewExamplesOptions(ui ui.UI) *ExamplesOptions {
+	return &ExamplesOptions{ui: ui}
+}
+
+// NewExamplesCmd Creates the examples command
+func NewExamplesCmd(o *ExamplesOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "examples [KEYWORD]",
+		Short: "Print the commands used in common scenarios",
+		Long: `Print the sequence of commands used in common scenarios, such as relocating a bundle to an air-gapped
+environment, bundling a Helm chart or copying signed bundles.
+Without a keyword, the available scenarios are listed. With a keyword, the scenarios that match it are printed.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				o.Keyword = args[0]
+			}
+			return o.Run()
+		},
+		Example: `
+    # List the available scenarios
+    imgpkg examples
+
+    # Print the commands to relocate a bundle to an air-gapped environment
+    imgpkg examples airgap
+
+    # Print the scenarios that use signatures
+    imgpkg examples cosign`,
+	}
+	return cmd
+}
+
+// Run Lists the scenarios or prints the ones matching the keyword
+func (e *ExamplesOptions) Run() error {
+	scenarios, err := ExampleScenarios()
+	if err != nil {
+		return err
+	}
+
+	if e.Keyword == "" {
+		e.printList(scenarios)
+		return nil
+	}
+
+	var matched []ExampleScenario
+	for _, scenario := range scenarios {
+		if scenario.Matches(e.Keyword) {
+			matched = append(matched, scenario)
+		}
+	}
+	if len(matched) == 0 {
+		return fmt.Errorf("Expected keyword '%s' to match an example, run imgpkg examples to list the available ones", e.Keyword)
+	}
+
+	var blocks []string
+	for _, scenario := range matched {
+		blocks = append(blocks, scenario.String())
+	}
+	// Printed as a block, so that the commands are also printed when the output is not a terminal
+	e.ui.PrintBlock([]byte(strings.Join(blocks, "\n")))
+	return nil
+}
+
+func (e *ExamplesOptions) printList(scenarios []ExampleScenario) {
+	table := uitable.Table{
+		Title:   "Examples",
+		Content: "examples",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Name"),
+			uitable.NewHeader("Title"),
+			uitable.NewHeader("Keywords"),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		table.Rows = append(table.Rows, []uitable.Value{
+			uitable.NewValueString(scenario.Name),
+			uitable.NewValueString(scenario.Title),
+			uitable.NewValueString(strings.Join(scenario.Keywords, ", ")),
+		})
+	}
+	e.ui.PrintTable(table)
+}
+
+// ExampleScenarios Returns the scenarios printed by the examples command, sorted by name
+func ExampleScenarios() ([]ExampleScenario, error) {
+	files, err := exampleScenarios.ReadDir("examples")
+	if err != nil {
+		return nil, err
+	}
+
+	var scenarios []ExampleScenario
+	for _, file := range files {
+		bs, err := exampleScenarios.ReadFile(path.Join("examples", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var scenario ExampleScenario
+		err = yaml.UnmarshalStrict(bs, &scenario)
+		if err != nil {
+			return nil, fmt.Errorf("Unmarshaling example '%s': %s", file.Name(), err)
+		}
+		scenarios = append(scenarios, scenario)
+	}
+
+	sort.Slice(scenarios, func(i, j int) bool { return scenarios[i].Name < scenarios[j].Name })
+	return scenarios, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExamples(t *testing.T) {
+	runExamples := func(keyword string) (string, error) {
+		output := &bytes.Buffer{}
+		subject := NewExamplesOptions(ui.NewWriterUI(output, output, ui.NewNoopLogger()))
+		subject.Keyword = keyword
+		err := subject.Run()
+		return output.String(), err
+	}
+
+	t.Run("lists the scenarios when no keyword is provided", func(t *testing.T) {
+		output, err := runExamples("")
+		require.NoError(t, err)
+		assert.Contains(t, output, "airgap")
+		assert.Contains(t, output, "helm-chart")
+		assert.Contains(t, output, "signatures")
+	})
+
+	t.Run("prints the scenarios matching the keyword", func(t *testing.T) {
+		output, err := runExamples("COSIGN")
+		require.NoError(t, err)
+		assert.Contains(t, output, "# Copy and verify signed bundles")
+		assert.Contains(t, output, "--verify-signature")
+		assert.NotContains(t, output, "helm")
+	})
+
+	t.Run("fails when the keyword does not match any scenario", func(t *testing.T) {
+		_, err := runExamples("not-a-scenario")
+		require.EqualError(t, err, "Expected keyword 'not-a-scenario' to match an example, run imgpkg examples to list the available ones")
+	})
+}
+
+// TestExampleCommandsAreValid Checks that the imgpkg commands of the scenarios, and of the examples in --help,
+// use commands and flags that exist
+func TestExampleCommandsAreValid(t *testing.T) {
+	scenarios, err := ExampleScenarios()
+	require.NoError(t, err)
+	require.NotEmpty(t, scenarios)
+
+	for _, scenario := range scenarios {
+		require.NotEmpty(t, scenario.Name)
+		require.NotEmpty(t, scenario.Steps, "scenario '%s'", scenario.Name)
+		for _, step := range scenario.Steps {
+			assertValidImgpkgCommand(t, step.Command)
+		}
+	}
+
+	var visit func(cmd *cobra.Command)
+	visit = func(cmd *cobra.Command) {
+		for _, line := range strings.Split(cmd.Example, "\n") {
+			assertValidImgpkgCommand(t, line)
+		}
+		for _, child := range cmd.Commands() {
+			visit(child)
+		}
+	}
+	visit(NewDefaultImgpkgCmd(ui.NewConfUI(ui.NewNoopLogger())))
+}
+
+// assertValidImgpkgCommand Parses each imgpkg command of the line, the other commands of a pipe are ignored
+func assertValidImgpkgCommand(t *testing.T, line string) {
+	for _, command := range strings.Split(line, "|") {
+		args := strings.Fields(command)
+		if len(args) == 0 || args[0] != "imgpkg" {
+			continue
+		}
+
+		// A new command tree is used for every command, since parsing the flags changes their values
+		root := NewDefaultImgpkgCmd(ui.NewConfUI(ui.NewNoopLogger()))
+		cmd, flags, err := root.Find(args[1:])
+		if !assert.NoError(t, err, "command: %s", command) {
+			continue
+		}
+		if !assert.NoError(t, cmd.ParseFlags(flags), "command: %s", command) {
+			continue
+		}
+		assert.NoError(t, cmd.ValidateArgs(cmd.Flags().Args()), "command: %s", command)
+	}
+}
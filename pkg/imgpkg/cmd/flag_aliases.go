@@ -0,0 +1,115 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const strictFlagsFlagName = "strict-flags"
+
+// FlagAlias Previous name of a flag that is still accepted, with a warning, after the flag was renamed, so that
+// existing pipelines keep working while they are updated to the new name
+type FlagAlias struct {
+	// Command Path of the command with the flag, e.g. "imgpkg copy". When empty, every command with the flag accepts the alias
+	Command string
+	Old     string
+	New     string
+	// RemovedIn Version of imgpkg that stops accepting the alias, included in the warning when provided
+	RemovedIn string
+}
+
+// appliesTo returns true when the command, or the command that its flags are inherited from, has the alias
+func (f FlagAlias) appliesTo(cmd *cobra.Command) bool {
+	return f.Command == "" || cmd.CommandPath() == f.Command || strings.HasPrefix(cmd.CommandPath(), f.Command+" ")
+}
+
+// flagAliases Flags that were renamed. Add an entry when renaming a flag instead of removing the old name
+var flagAliases = []FlagAlias{
+	{Old: "registry-upload-chunk-size", New: "upload-chunk-size"},
+}
+
+// StrictFlags command line flag that turns the use of deprecated flags into errors
+type StrictFlags struct {
+	StrictFlags bool
+}
+
+// Set Registers the flag in the imgpkg command, so that it is available to every command
+func (s *StrictFlags) Set(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&s.StrictFlags, strictFlagsFlagName, os.Getenv("IMGPKG_STRICT_FLAGS") == "true",
+		"Fail when a deprecated flag, or the previous name of a renamed flag, is used ($IMGPKG_STRICT_FLAGS)")
+}
+
+// addFlagAliases Registers, as hidden flags sharing the value of the new flag, the previous names of the flags of the command
+func addFlagAliases(aliases []FlagAlias) func(cmd *cobra.Command) {
+	return func(cmd *cobra.Command) {
+		for _, alias := range aliases {
+			if !alias.appliesTo(cmd) {
+				continue
+			}
+
+			flags := cmd.PersistentFlags()
+			newFlag := flags.Lookup(alias.New)
+			if newFlag == nil {
+				flags = cmd.Flags()
+				newFlag = flags.Lookup(alias.New)
+			}
+			if newFlag == nil || flags.Lookup(alias.Old) != nil {
+				continue
+			}
+
+			flags.AddFlag(&pflag.Flag{
+				Name:        alias.Old,
+				Usage:       fmt.Sprintf("Previous name of --%s", alias.New),
+				Value:       newFlag.Value,
+				DefValue:    newFlag.DefValue,
+				NoOptDefVal: newFlag.NoOptDefVal,
+				Hidden:      true,
+			})
+		}
+	}
+}
+
+// checkDeprecatedFlags Warns about the deprecated flags provided to the command, or fails when --strict-flags is set
+func checkDeprecatedFlags(ui ui.UI, aliases []FlagAlias) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, _ []string) error {
+		var warnings, deprecated []string
+		cmd.Flags().Visit(func(flag *pflag.Flag) {
+			for _, alias := range aliases {
+				if alias.Old != flag.Name || !alias.appliesTo(cmd) {
+					continue
+				}
+				warning := fmt.Sprintf("Flag --%s was renamed to --%s", alias.Old, alias.New)
+				if alias.RemovedIn != "" {
+					warning += fmt.Sprintf(", --%s will not be accepted starting in %s", alias.Old, alias.RemovedIn)
+				}
+				warnings = append(warnings, warning)
+				deprecated = append(deprecated, "--"+flag.Name)
+				return
+			}
+			// pflag already warns about the flags marked as deprecated
+			if flag.Deprecated != "" {
+				deprecated = append(deprecated, "--"+flag.Name)
+			}
+		})
+
+		strict, err := cmd.Flags().GetBool(strictFlagsFlagName)
+		if err == nil && strict && len(deprecated) > 0 {
+			sort.Strings(deprecated)
+			return fmt.Errorf("Expected no deprecated flags to be used with --%s, but got %s", strictFlagsFlagName, strings.Join(deprecated, ", "))
+		}
+
+		for _, warning := range warnings {
+			ui.ErrorLinef("Warning: %s", warning)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cppforlife/cobrautil"
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlagAliases(t *testing.T) {
+	aliases := []FlagAlias{
+		{Command: "imgpkg copy", Old: "dest-repo", New: "to-repo", RemovedIn: "v1.0.0"},
+		{Old: "silent", New: "quiet"},
+	}
+
+	type result struct {
+		repo   string
+		quiet  bool
+		stderr string
+	}
+
+	run := func(t *testing.T, args ...string) (result, error) {
+		var res result
+		stderr := &bytes.Buffer{}
+		testUI := ui.NewWriterUI(&bytes.Buffer{}, stderr, ui.NewNoopLogger())
+
+		root := &cobra.Command{Use: "imgpkg", SilenceErrors: true, SilenceUsage: true}
+		(&StrictFlags{}).Set(root)
+		root.PersistentFlags().BoolVarP(&res.quiet, "quiet", "q", false, "")
+		copyCmd := &cobra.Command{Use: "copy", RunE: func(*cobra.Command, []string) error { return nil }}
+		copyCmd.Flags().StringVar(&res.repo, "to-repo", "", "")
+		copyCmd.Flags().Bool("old-flag", false, "")
+		copyCmd.Flags().MarkDeprecated("old-flag", "use '--to-repo' instead")
+		root.AddCommand(copyCmd)
+		root.RunE = func(*cobra.Command, []string) error { return nil }
+
+		cobrautil.VisitCommands(root, addFlagAliases(aliases))
+		cobrautil.VisitCommands(root, cobrautil.WrapRunEForCmd(checkDeprecatedFlags(testUI, aliases)))
+
+		root.SetArgs(args)
+		root.SetErr(&bytes.Buffer{})
+		err := root.Execute()
+		res.stderr = stderr.String()
+		return res, err
+	}
+
+	t.Run("sets the renamed flag when the previous name is used, warning about it", func(t *testing.T) {
+		res, err := run(t, "copy", "--dest-repo", "some/repo", "--silent")
+		require.NoError(t, err)
+		assert.Equal(t, "some/repo", res.repo)
+		assert.True(t, res.quiet)
+		assert.Contains(t, res.stderr, "Warning: Flag --dest-repo was renamed to --to-repo, --dest-repo will not be accepted starting in v1.0.0")
+		assert.Contains(t, res.stderr, "Warning: Flag --silent was renamed to --quiet")
+	})
+
+	t.Run("does not warn when the new names are used", func(t *testing.T) {
+		res, err := run(t, "copy", "--to-repo", "some/repo", "--quiet")
+		require.NoError(t, err)
+		assert.Equal(t, "some/repo", res.repo)
+		assert.Empty(t, res.stderr)
+	})
+
+	t.Run("when --strict-flags is set, it fails when deprecated flags are used", func(t *testing.T) {
+		_, err := run(t, "copy", "--strict-flags", "--silent", "--old-flag")
+		require.Error(t, err)
+		assert.Equal(t, "Expected no deprecated flags to be used with --strict-flags, but got --old-flag, --silent", err.Error())
+
+		_, err = run(t, "copy", "--strict-flags", "--quiet")
+		require.NoError(t, err)
+	})
+}
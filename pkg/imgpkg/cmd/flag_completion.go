@@ -0,0 +1,70 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	dockerconfig "github.com/docker/cli/cli/config"
+	"github.com/spf13/cobra"
+)
+
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// completeValues Completes the flag with a fixed list of values
+func completeValues(values ...string) completionFunc {
+	return func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeRegistries Completes image references with the registries the user has credentials for
+func completeRegistries(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var result []string
+	for _, registry := range knownRegistries(os.Environ(), dockerconfig.Dir()) {
+		if strings.HasPrefix(registry+"/", toComplete) {
+			result = append(result, registry+"/")
+		}
+	}
+	return result, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+}
+
+// knownRegistries Returns the registries configured in the IMGPKG_REGISTRY_HOSTNAME environment variables
+// and in the docker configuration
+func knownRegistries(environ []string, dockerConfigDir string) []string {
+	registries := map[string]struct{}{}
+	addRegistry := func(hostname string) {
+		hostname = strings.TrimPrefix(strings.TrimPrefix(hostname, "https://"), "http://")
+		hostname = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(hostname, "/"), "/v1"), "/v2")
+		if hostname != "" {
+			registries[hostname] = struct{}{}
+		}
+	}
+
+	for _, env := range environ {
+		name, value, found := strings.Cut(env, "=")
+		if found && strings.HasPrefix(name, "IMGPKG_REGISTRY_HOSTNAME") {
+			addRegistry(value)
+		}
+	}
+
+	config, err := dockerconfig.Load(dockerConfigDir)
+	if err == nil {
+		for hostname := range config.AuthConfigs {
+			addRegistry(hostname)
+		}
+		for hostname := range config.CredentialHelpers {
+			addRegistry(hostname)
+		}
+	}
+
+	var result []string
+	for registry := range registries {
+		result = append(result, registry)
+	}
+	sort.Strings(result)
+	return result
+}
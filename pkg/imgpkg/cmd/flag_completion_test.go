@@ -0,0 +1,61 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlagCompletion(t *testing.T) {
+	complete := func(t *testing.T, args ...string) []string {
+		imgpkgCmd := NewDefaultImgpkgCmd(ui.NewConfUI(ui.NewNoopLogger()))
+		output := bytes.NewBufferString("")
+		imgpkgCmd.SetOut(output)
+		imgpkgCmd.SetArgs(append([]string{"__complete"}, args...))
+		require.NoError(t, imgpkgCmd.Execute())
+
+		var values []string
+		for _, line := range strings.Split(output.String(), "\n") {
+			if line != "" && !strings.HasPrefix(line, ":") && !strings.HasPrefix(line, "Completion ended") {
+				values = append(values, line)
+			}
+		}
+		return values
+	}
+
+	t.Run("completes the output types of describe", func(t *testing.T) {
+		assert.Equal(t, DescribeOutputType, complete(t, "describe", "-o", ""))
+	})
+
+	t.Run("completes the output types of commands with text and json results", func(t *testing.T) {
+		assert.Equal(t, []string{"text", "json"}, complete(t, "push", "--output-type", ""))
+	})
+
+	t.Run("completes image references with the registries from the environment", func(t *testing.T) {
+		t.Setenv("IMGPKG_REGISTRY_HOSTNAME_0", "https://completion-test.other.io")
+		t.Setenv("IMGPKG_REGISTRY_HOSTNAME_1", "completion-test.registry.io")
+
+		assert.Subset(t, complete(t, "copy", "--to-repo", ""), []string{"completion-test.other.io/", "completion-test.registry.io/"})
+		assert.Equal(t, []string{"completion-test.registry.io/"}, complete(t, "pull", "-b", "completion-test.reg"))
+	})
+
+	t.Run("finds the registries in the docker configuration", func(t *testing.T) {
+		configDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{
+  "auths": {"https://index.docker.io/v1/": {}, "registry.io": {}},
+  "credHelpers": {"gcr.io": "gcloud"}
+}`), 0600))
+
+		registries := knownRegistries([]string{"IMGPKG_REGISTRY_HOSTNAME=https://other.registry.io/", "OTHER_ENV=value"}, configDir)
+		assert.Equal(t, []string{"gcr.io", "index.docker.io", "other.registry.io", "registry.io"}, registries)
+	})
+}
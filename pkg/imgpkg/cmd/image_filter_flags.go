@@ -0,0 +1,86 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const imageFilterRegexpPrefix = "regexp:"
+
+// ImageFilterFlags command line flags to select which images of an ImagesLock are copied
+type ImageFilterFlags struct {
+	Include []string
+	Exclude []string
+}
+
+// Set Registers the flags available to the provided command
+func (i *ImageFilterFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&i.Include, "include-image", nil,
+		"Only copy the images of the ImagesLock whose repository matches this glob, or regular expression when prefixed with regexp: (e.g. index.docker.io/org/app-*, regexp:.*-linux$) (can be specified multiple times)")
+	cmd.Flags().StringSliceVar(&i.Exclude, "exclude-image", nil,
+		"Do not copy the images of the ImagesLock whose repository matches this glob, or regular expression when prefixed with regexp:, the excluded images are recorded in the locations of the copied bundle (e.g. index.docker.io/org/*-windows, regexp:debug) (can be specified multiple times)")
+}
+
+// IsSet returns true when any filter was provided
+func (i ImageFilterFlags) IsSet() bool { return len(i.Include) > 0 || len(i.Exclude) > 0 }
+
+// Validate Checks that all the patterns are valid globs or regular expressions
+func (i ImageFilterFlags) Validate() error {
+	for _, pattern := range i.Include {
+		if _, err := imageFilterMatches(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid --include-image '%s': %s", pattern, err)
+		}
+	}
+	for _, pattern := range i.Exclude {
+		if _, err := imageFilterMatches(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid --exclude-image '%s': %s", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Excludes Returns true when the image, as in the ImagesLock, should not be copied. An image is copied when it matches
+// one of the --include-image patterns, if any was provided, and none of the --exclude-image patterns
+func (i ImageFilterFlags) Excludes(image string) bool {
+	repo := image
+	if idx := strings.Index(image, "@"); idx != -1 {
+		repo = image[:idx]
+	}
+
+	// The patterns were validated so no error is possible
+	if len(i.Include) > 0 {
+		included := false
+		for _, pattern := range i.Include {
+			if matched, _ := imageFilterMatches(pattern, repo); matched {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return true
+		}
+	}
+	for _, pattern := range i.Exclude {
+		if matched, _ := imageFilterMatches(pattern, repo); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func imageFilterMatches(pattern, repo string) (bool, error) {
+	if strings.HasPrefix(pattern, imageFilterRegexpPrefix) {
+		return regexp.MatchString(strings.TrimPrefix(pattern, imageFilterRegexpPrefix), repo)
+	}
+	if pattern == "" {
+		return false, fmt.Errorf("Expected a glob")
+	}
+	return path.Match(pattern, repo)
+}
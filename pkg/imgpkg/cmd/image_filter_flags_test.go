@@ -0,0 +1,47 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageFilterFlags(t *testing.T) {
+	appImage := "my.registry.io/org/app@sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	windowsImage := "my.registry.io/org/app-windows@sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	debugImage := "my.registry.io/org/app-debug@sha256:cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc"
+
+	t.Run("excludes the images matching a glob", func(t *testing.T) {
+		flags := ImageFilterFlags{Exclude: []string{"my.registry.io/org/*-windows"}}
+		require.NoError(t, flags.Validate())
+		assert.False(t, flags.Excludes(appImage))
+		assert.True(t, flags.Excludes(windowsImage))
+		assert.False(t, flags.Excludes(debugImage))
+	})
+
+	t.Run("excludes the images matching a regular expression", func(t *testing.T) {
+		flags := ImageFilterFlags{Exclude: []string{"regexp:-(windows|debug)$"}}
+		require.NoError(t, flags.Validate())
+		assert.False(t, flags.Excludes(appImage))
+		assert.True(t, flags.Excludes(windowsImage))
+		assert.True(t, flags.Excludes(debugImage))
+	})
+
+	t.Run("excludes the images not matching any include", func(t *testing.T) {
+		flags := ImageFilterFlags{Include: []string{"my.registry.io/org/app*"}, Exclude: []string{"regexp:debug"}}
+		require.NoError(t, flags.Validate())
+		assert.False(t, flags.Excludes(appImage))
+		assert.False(t, flags.Excludes(windowsImage))
+		assert.True(t, flags.Excludes(debugImage))
+		assert.True(t, flags.Excludes("other.registry.io/app@sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"))
+	})
+
+	t.Run("fails when a pattern is not valid", func(t *testing.T) {
+		require.ErrorContains(t, ImageFilterFlags{Include: []string{"org/[app"}}.Validate(), "Invalid --include-image 'org/[app'")
+		require.ErrorContains(t, ImageFilterFlags{Exclude: []string{"regexp:(app"}}.Validate(), "Invalid --exclude-image 'regexp:(app'")
+	})
+}
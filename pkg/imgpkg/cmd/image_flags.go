@@ -13,8 +13,10 @@ type ImageFlags struct {
 
 func (i *ImageFlags) Set(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&i.Image, "image", "i", "", "Set image (example: docker.io/dkalinin/test-content)")
+	_ = cmd.RegisterFlagCompletionFunc("image", completeRegistries)
 }
 
 func (i *ImageFlags) SetCopy(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&i.Image, "image", "i", "", "Image reference for copying a generic image (example: docker.io/dkalinin/test-content)")
+	_ = cmd.RegisterFlagCompletionFunc("image", completeRegistries)
 }
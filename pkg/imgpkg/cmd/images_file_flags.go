@@ -0,0 +1,68 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/spf13/cobra"
+)
+
+const stdinImagesFile = "-"
+
+// ImagesFileFlags command line flags to provide a list of images
+type ImagesFileFlags struct {
+	ImagesFile string
+}
+
+// Set Registers the flags available to the provided command
+func (i *ImagesFileFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&i.ImagesFile, "images-file", "",
+		"File with the image references to copy, one per line. Use - to read the references from stdin")
+}
+
+// IsSet returns true when a file with images was provided
+func (i ImagesFileFlags) IsSet() bool { return i.ImagesFile != "" }
+
+// ImageRefs Reads the image references from the file, or from stdin when the file is -.
+// Empty lines and lines starting with # are ignored
+func (i ImagesFileFlags) ImageRefs(stdin io.Reader) ([]string, error) {
+	reader := stdin
+	if i.ImagesFile != stdinImagesFile {
+		file, err := os.Open(i.ImagesFile)
+		if err != nil {
+			return nil, fmt.Errorf("Opening images file: %s", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var refs []string
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := regname.ParseReference(line); err != nil {
+			return nil, fmt.Errorf("Parsing image reference '%s' in line %d: %s", line, lineNumber, err)
+		}
+		refs = append(refs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Reading images file: %s", err)
+	}
+
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("Expected images file to contain at least one image reference")
+	}
+	return refs, nil
+}
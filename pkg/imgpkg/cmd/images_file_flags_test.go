@@ -0,0 +1,50 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestImagesFileFlags(t *testing.T) {
+	content := `
+# images to relocate
+my.registry.io/image1:v1
+  my.registry.io/image2@sha256:703218c0465075f4425e58fac086e09e1de5c340b12976ab9eb8ad26615c3715
+
+`
+
+	t.Run("reads the references from stdin ignoring empty lines and comments", func(t *testing.T) {
+		refs, err := ImagesFileFlags{ImagesFile: "-"}.ImageRefs(strings.NewReader(content))
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"my.registry.io/image1:v1",
+			"my.registry.io/image2@sha256:703218c0465075f4425e58fac086e09e1de5c340b12976ab9eb8ad26615c3715",
+		}, refs)
+	})
+
+	t.Run("reads the references from a file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "images.txt")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+		refs, err := ImagesFileFlags{ImagesFile: path}.ImageRefs(strings.NewReader("not used"))
+		require.NoError(t, err)
+		require.Len(t, refs, 2)
+	})
+
+	t.Run("fails when a reference is not valid", func(t *testing.T) {
+		_, err := ImagesFileFlags{ImagesFile: "-"}.ImageRefs(strings.NewReader("my.registry.io/image1:v1\nnot a ref\n"))
+		require.ErrorContains(t, err, "Parsing image reference 'not a ref' in line 2")
+	})
+
+	t.Run("fails when there are no references", func(t *testing.T) {
+		_, err := ImagesFileFlags{ImagesFile: "-"}.ImageRefs(strings.NewReader("# nothing to copy\n"))
+		require.ErrorContains(t, err, "Expected images file to contain at least one image reference")
+	})
+}
@@ -10,13 +10,16 @@ import (
 	"github.com/cppforlife/cobrautil"
 	"github.com/cppforlife/go-cli-ui/ui"
 	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/tracing"
 )
 
 type ImgpkgOptions struct {
 	ui *ui.ConfUI
 
-	UIFlags    UIFlags
-	DebugFlags DebugFlags
+	UIFlags             UIFlags
+	DebugFlags          DebugFlags
+	RequireDigestsFlags RequireDigestsFlags
+	StrictFlags         StrictFlags
 }
 
 func NewImgpkgOptions(ui *ui.ConfUI) *ImgpkgOptions {
@@ -45,28 +48,66 @@ func NewImgpkgCmd(o *ImgpkgOptions) *cobra.Command {
 
 	o.UIFlags.Set(cmd)
 	o.DebugFlags.Set(cmd)
-
-	cmd.AddCommand(NewPushCmd(NewPushOptions(o.ui)))
-	cmd.AddCommand(NewPullCmd(NewPullOptions(o.ui)))
-	cmd.AddCommand(NewVersionCmd(NewVersionOptions(o.ui)))
-	cmd.AddCommand(NewCopyCmd(NewCopyOptions(o.ui)))
-	cmd.AddCommand(NewDescribeCmd(NewDescribeOptions(o.ui)))
+	o.RequireDigestsFlags.Set(cmd)
+	o.StrictFlags.Set(cmd)
+
+	quietUI := NewQuietUI(o.ui)
+
+	cmd.AddCommand(NewPushCmd(NewPushOptions(quietUI)))
+	cmd.AddCommand(NewPullCmd(NewPullOptions(quietUI)))
+	cmd.AddCommand(NewVersionCmd(NewVersionOptions(quietUI)))
+	cmd.AddCommand(NewCopyCmd(NewCopyOptions(quietUI)))
+	cmd.AddCommand(NewDescribeCmd(NewDescribeOptions(quietUI)))
+	cmd.AddCommand(NewWarmCmd(NewWarmOptions(quietUI)))
+	cmd.AddCommand(NewMirrorCheckCmd(NewMirrorCheckOptions(quietUI)))
+	cmd.AddCommand(NewPrepullCmd(NewPrepullOptions(quietUI)))
+	cmd.AddCommand(NewConvertCmd(NewConvertOptions(quietUI)))
+	cmd.AddCommand(NewCheckPinningCmd(NewCheckPinningOptions(quietUI)))
 
 	tagCmd := NewTagCmd()
-	tagCmd.AddCommand(NewTagListCmd(NewTagListOptions(o.ui)))
-	tagCmd.AddCommand(NewTagResolveCmd(NewTagResolveOptions(o.ui)))
+	tagCmd.AddCommand(NewTagListCmd(NewTagListOptions(quietUI)))
+	tagCmd.AddCommand(NewTagResolveCmd(NewTagResolveOptions(quietUI)))
 	cmd.AddCommand(tagCmd)
 
 	// Last one runs first
 	cobrautil.VisitCommands(cmd, cobrautil.ReconfigureCmdWithSubcmd)
 	cobrautil.VisitCommands(cmd, cobrautil.DisallowExtraArgs)
 
+	// Auth check receives the registry as argument, so it is added after DisallowExtraArgs
+	authCmd := NewAuthCmd()
+	authCmd.AddCommand(NewAuthCheckCmd(NewAuthCheckOptions(quietUI)))
+	cobrautil.VisitCommands(authCmd, cobrautil.ReconfigureCmdWithSubcmd)
+	cmd.AddCommand(authCmd)
+
+	// SBOM diff receives the bundles as arguments, so it is added after DisallowExtraArgs
+	sbomCmd := NewSBOMCmd()
+	sbomCmd.AddCommand(NewSBOMDiffCmd(NewSBOMDiffOptions(quietUI)))
+	cobrautil.VisitCommands(sbomCmd, cobrautil.ReconfigureCmdWithSubcmd)
+	cmd.AddCommand(sbomCmd)
+
+	// Diff receives the bundles as arguments, so it is added after DisallowExtraArgs
+	cmd.AddCommand(NewDiffCmd(NewDiffOptions(quietUI)))
+
+	// Examples receives the keyword as argument, so it is added after DisallowExtraArgs
+	cmd.AddCommand(NewExamplesCmd(NewExamplesOptions(quietUI)))
+
+	// Run receives the jobs file as argument, so it is added after DisallowExtraArgs
+	cmd.AddCommand(NewRunCmd(NewRunOptions(quietUI)))
+
 	// Completion command have to be added after the DisallowExtraArgs
 	// This configurations forces all nodes to do not accept extra args, but the completion requires 1 extra arg
 	cmd.AddCommand(NewCompletionCmd())
 
+	cobrautil.VisitCommands(cmd, addFlagAliases(flagAliases))
+	cobrautil.VisitCommands(cmd, cobrautil.WrapRunEForCmd(checkDeprecatedFlags(o.ui, flagAliases)))
+
+	cobrautil.VisitCommands(cmd, traceRunE)
+
 	cobrautil.VisitCommands(cmd, cobrautil.WrapRunEForCmd(func(*cobra.Command, []string) error {
 		o.UIFlags.ConfigureUI(o.ui)
+		if o.UIFlags.Quiet {
+			quietUI.EnableQuiet()
+		}
 		o.DebugFlags.ConfigureDebug()
 		return nil
 	}))
@@ -76,6 +117,27 @@ func NewImgpkgCmd(o *ImgpkgOptions) *cobra.Command {
 	return cmd
 }
 
+// traceRunE wraps the command execution in a span that is exported when IMGPKG_OTEL_EXPORTER is set
+func traceRunE(cmd *cobra.Command) {
+	if cmd.RunE == nil {
+		return
+	}
+
+	origRunE := cmd.RunE
+	cmd.RunE = func(cmd2 *cobra.Command, args []string) error {
+		shutdown, err := tracing.Setup(os.LookupEnv, Version)
+		if err != nil {
+			return err
+		}
+		defer shutdown()
+
+		endSpan := tracing.StartCommand(cmd2.CommandPath())
+		err = origRunE(cmd2, args)
+		endSpan(err)
+		return err
+	}
+}
+
 type uiBlockWriter struct {
 	ui ui.UI
 }
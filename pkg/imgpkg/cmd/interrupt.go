@@ -0,0 +1,144 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	ctlimgset "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
+)
+
+// copyProgressTracker Keeps track of the images that were copied, to be able to report them if the copy is interrupted
+type copyProgressTracker struct {
+	next ctlimgset.EventListener
+
+	started   map[string]struct{}
+	completed map[string]struct{}
+	lock      sync.Mutex
+}
+
+func newCopyProgressTracker(next ctlimgset.EventListener) *copyProgressTracker {
+	if next == nil {
+		next = ctlimgset.NoopEventListener{}
+	}
+	return &copyProgressTracker{next: next, started: map[string]struct{}{}, completed: map[string]struct{}{}}
+}
+
+// OnEvent records the state of the image and forwards the event
+func (c *copyProgressTracker) OnEvent(event ctlimgset.Event) {
+	c.lock.Lock()
+	switch event.Type {
+	case ctlimgset.EventImageStarted:
+		c.started[event.Source] = struct{}{}
+	case ctlimgset.EventImageCompleted, ctlimgset.EventImageSkipped:
+		c.completed[event.Source] = struct{}{}
+	}
+	c.lock.Unlock()
+
+	c.next.OnEvent(event)
+}
+
+// Progress returns the number of completed images and the images that did not complete yet
+func (c *copyProgressTracker) Progress() (int, []string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var pending []string
+	for image := range c.started {
+		if _, found := c.completed[image]; !found {
+			pending = append(pending, image)
+		}
+	}
+	sort.Strings(pending)
+	return len(c.completed), pending
+}
+
+// interruptHandler Prints a summary of the state of the copy when the context of the command is done, i.e. when the
+// process receives SIGINT or SIGTERM. The copy then fails with the cancelled requests and returns as usual, removing its
+// temporary files
+type interruptHandler struct {
+	ui      ui.UI
+	tracker *copyProgressTracker
+	tarDst  string
+	args    []string
+}
+
+func newInterruptHandler(ui ui.UI, tracker *copyProgressTracker, tarDst string) interruptHandler {
+	return interruptHandler{ui: ui, tracker: tracker, tarDst: tarDst, args: os.Args}
+}
+
+// Start waits for ctx to be done until the returned function is called. The returned function waits for the summary
+// to be printed, so that it is written before the error of the copy
+func (h interruptHandler) Start(ctx context.Context) func() {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		if ctx.Err() != nil {
+			h.printSummary()
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+func (h interruptHandler) printSummary() {
+	h.ui.ErrorLinef("\nCopy interrupted")
+
+	completed, pending := h.tracker.Progress()
+	if completed > 0 || len(pending) > 0 {
+		h.ui.ErrorLinef("Completed images: %d", completed)
+		h.ui.ErrorLinef("Pending images: %d", len(pending))
+		for _, image := range pending {
+			h.ui.ErrorLinef("  %s", image)
+		}
+	}
+
+	if h.tarDst != "" {
+		h.ui.ErrorLinef("Partially written tar file: %s", h.tarDst)
+	}
+	if len(h.args) > 0 {
+		h.ui.ErrorLinef("Resume with: %s", h.resumeCommand())
+	}
+}
+
+// resumeCommand returns the command used to start the copy, adding --resume when copying to a tar.
+// Blobs already present in a destination repository are not uploaded again, so the same command can be used
+func (h interruptHandler) resumeCommand() string {
+	args := append([]string{}, h.args...)
+	if h.tarDst != "" && !containsFlag(args, "--resume") {
+		args = append(args, "--resume")
+	}
+
+	var quoted []string
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"$*?") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		quoted = append(quoted, arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func containsFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag || strings.HasPrefix(arg, flag+"=") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/stretchr/testify/assert"
+	ctlimgset "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
+)
+
+func TestInterruptHandler(t *testing.T) {
+	t.Run("prints completed and pending images and the resume command when the context is done", func(t *testing.T) {
+		stderr := &bytes.Buffer{}
+		tracker := newCopyProgressTracker(nil)
+		for _, image := range []string{"repo/img1@sha256:1", "repo/img2@sha256:2", "repo/img3@sha256:3"} {
+			tracker.OnEvent(ctlimgset.Event{Type: ctlimgset.EventImageStarted, Source: image})
+		}
+		tracker.OnEvent(ctlimgset.Event{Type: ctlimgset.EventImageCompleted, Source: "repo/img2@sha256:2"})
+
+		subject := interruptHandler{
+			ui:      ui.NewWriterUI(&bytes.Buffer{}, stderr, ui.NewNoopLogger()),
+			tracker: tracker,
+			tarDst:  "/tmp/my bundle.tar",
+			args:    []string{"imgpkg", "copy", "-b", "repo/bundle", "--to-tar", "/tmp/my bundle.tar"},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		stop := subject.Start(ctx)
+		cancel()
+		stop()
+
+		assert.Contains(t, stderr.String(), "Copy interrupted")
+		assert.Contains(t, stderr.String(), "Completed images: 1")
+		assert.Contains(t, stderr.String(), "Pending images: 2\n  repo/img1@sha256:1\n  repo/img3@sha256:3\n")
+		assert.Contains(t, stderr.String(), "Partially written tar file: /tmp/my bundle.tar")
+		assert.Contains(t, stderr.String(), "Resume with: imgpkg copy -b repo/bundle --to-tar '/tmp/my bundle.tar' --resume")
+	})
+
+	t.Run("does not print anything when it is stopped before the context is done", func(t *testing.T) {
+		stderr := &bytes.Buffer{}
+		subject := interruptHandler{
+			ui:      ui.NewWriterUI(&bytes.Buffer{}, stderr, ui.NewNoopLogger()),
+			tracker: newCopyProgressTracker(nil),
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		subject.Start(ctx)()
+
+		assert.Empty(t, stderr.String())
+	})
+
+	t.Run("does not add --resume when it is already present", func(t *testing.T) {
+		subject := interruptHandler{tarDst: "b.tar", args: []string{"imgpkg", "copy", "--to-tar", "b.tar", "--resume"}}
+		assert.Equal(t, "imgpkg copy --to-tar b.tar --resume", subject.resumeCommand())
+	})
+
+	t.Run("repeats the same command when copying to a repository", func(t *testing.T) {
+		subject := interruptHandler{args: []string{"imgpkg", "copy", "-b", "repo/bundle", "--to-repo", "other/repo"}}
+		assert.Equal(t, "imgpkg copy -b repo/bundle --to-repo other/repo", subject.resumeCommand())
+	})
+}
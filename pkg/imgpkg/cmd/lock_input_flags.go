@@ -4,7 +4,10 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
 )
 
 type LockInputFlags struct {
@@ -15,3 +18,39 @@ func (l *LockInputFlags) Set(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&l.LockFilePath, "lock", "",
 		"Lock file with asset references to copy to destination")
 }
+
+// SetOnPull Sets the lock flag for Pull command
+func (l *LockInputFlags) SetOnPull(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&l.LockFilePath, "lock", "",
+		"BundleLock file, generated with --lock-output, with the bundle to pull")
+}
+
+// SetOnDescribe Sets the lock flag for Describe command
+func (l *LockInputFlags) SetOnDescribe(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&l.LockFilePath, "lock", "",
+		"BundleLock file, generated with --lock-output, with the bundle to describe")
+}
+
+// SetOnPrepull Sets the lock flag for Prepull command
+func (l *LockInputFlags) SetOnPrepull(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&l.LockFilePath, "lock", "",
+		"BundleLock file, generated with --lock-output, with the bundle whose images are pulled onto the nodes")
+}
+
+// SetOnWarm Sets the lock flag for Warm command
+func (l *LockInputFlags) SetOnWarm(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&l.LockFilePath, "lock", "",
+		"ImagesLock file with the images to prepare in the destination repository")
+}
+
+// BundleImage Reads the BundleLock file and returns the location of the bundle
+func (l LockInputFlags) BundleImage() (string, error) {
+	bundleLock, imagesLock, err := lockconfig.NewLockFromPath(l.LockFilePath)
+	if err != nil {
+		return "", err
+	}
+	if imagesLock != nil {
+		return "", fmt.Errorf("Expected a BundleLock in '%s' but found an ImagesLock (hint: use the lock file generated with --lock-output when pushing or copying a bundle)", l.LockFilePath)
+	}
+	return bundleLock.Bundle.Image, nil
+}
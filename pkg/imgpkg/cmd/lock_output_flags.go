@@ -14,7 +14,7 @@ type LockOutputFlags struct {
 // SetOnCopy Sets the lock-output flag for Copy command
 func (l *LockOutputFlags) SetOnCopy(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&l.LockFilePath, "lock-output", "",
-		"Location to output the generated lockfile. Option only available when using --bundle or --lock flags")
+		"Location to output the generated lockfile. When copying a bundle it is a BundleLock with the reference and tag of the bundle in the destination repository")
 }
 
 // SetOnPush Sets the lock-output flag for Push command
@@ -22,3 +22,9 @@ func (l *LockOutputFlags) SetOnPush(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&l.LockFilePath, "lock-output", "",
 		"Location to output the generated lockfile. Option only available when using --bundle flag")
 }
+
+// SetOnPull Sets the lock-output flag for Pull command
+func (l *LockOutputFlags) SetOnPull(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&l.LockFilePath, "lock-output", "",
+		"Location to output a lockfile recording the digest of the pulled bundle or image, and the tag it was pulled with")
+}
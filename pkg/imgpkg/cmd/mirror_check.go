@@ -0,0 +1,232 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	mirrorCheckStatusMissing     = "missing"
+	mirrorCheckStatusUnreachable = "unreachable"
+
+	mirrorCheckWebhookTimeout = 30 * time.Second
+)
+
+// MirrorCheckOptions Command Line options that can be provided to the mirror-check command
+type MirrorCheckOptions struct {
+	ui ui.UI
+
+	RegistryFlags RegistryFlags
+
+	MappingPath   string
+	Interval      time.Duration
+	WebhookURL    string
+	ExitOnFailure bool
+	Concurrency   int
+
+	sleep func(time.Duration)
+	now   func() time.Time
+}
+
+// mirrorCheckRegistry Registry operations used to check the destinations
+type mirrorCheckRegistry interface {
+	Digest(regname.Reference) (regv1.Hash, error)
+}
+
+// mirrorCheckFailure Mapped image that could not be found in the destination
+type mirrorCheckFailure struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Status      string `json:"status"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// mirrorCheckReport Result of a check, sent to the webhook when any mapped image could not be found
+type mirrorCheckReport struct {
+	CheckedAt string               `json:"checkedAt"`
+	Checked   int                  `json:"checked"`
+	Failures  []mirrorCheckFailure `json:"failures"`
+}
+
+// NewMirrorCheckOptions constructor for building a MirrorCheckOptions, holding values derived via flags
+func NewMirrorCheckOptions(ui ui.UI) *MirrorCheckOptions {
+	return &MirrorCheckOptions{ui: ui, sleep: time.Sleep, now: time.Now}
+}
+
+// NewMirrorCheckCmd Creates the mirror-check command
+func NewMirrorCheckCmd(o *MirrorCheckOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mirror-check",
+		Short: "Verify that copied images still exist in their destination",
+		Long: `Verify that copied images still exist in their destination.
+The mapping is the structured output of copy (--output-type yaml or json), every destination digest in it is checked,
+catching images deleted by a registry garbage collection or retention policy. With --interval the check is repeated
+until the command is stopped, the images that are not found are logged and, with --webhook, posted as JSON.`,
+		RunE: func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+    # Record the images copied to internal-registry/app1-bundle and verify every hour that they are still there
+    imgpkg copy -b repo/app1-bundle --to-repo internal-registry/app1-bundle --output-type yaml > map.yml
+    imgpkg mirror-check --mapping map.yml --interval 1h --webhook https://hooks.example.com/imgpkg`,
+	}
+	o.RegistryFlags.Set(cmd)
+	cmd.Flags().StringVar(&o.MappingPath, "mapping", "", "File with the result of copy, written with --output-type yaml or json, listing the images to verify")
+	cmd.Flags().DurationVar(&o.Interval, "interval", 0, "Repeat the check at this interval until the command is stopped, the check runs once when not provided (e.g. 1h)")
+	cmd.Flags().StringVar(&o.WebhookURL, "webhook", "", "URL that receives, as a JSON POST, the images that were not found in the destination")
+	cmd.Flags().BoolVar(&o.ExitOnFailure, "exit-on-failure", false, "Stop repeating the check, with a non zero exit code, when any image is not found (only used with --interval)")
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 5, "Concurrency")
+	return cmd
+}
+
+// Run Executes the mirror-check command
+func (m *MirrorCheckOptions) Run() error {
+	if m.MappingPath == "" {
+		return fmt.Errorf("Expected --mapping with the result of copy")
+	}
+	if m.Interval < 0 {
+		return fmt.Errorf("Expected --interval to be a positive duration, got '%s'", m.Interval)
+	}
+	if m.Concurrency < 1 {
+		return fmt.Errorf("Expected --concurrency to be 1 or greater, but got %d", m.Concurrency)
+	}
+
+	mapping, err := m.readMapping()
+	if err != nil {
+		return err
+	}
+
+	reg, err := registry.NewSimpleRegistry(m.RegistryFlags.AsRegistryOpts())
+	if err != nil {
+		return err
+	}
+
+	logger := util.NewUILevelLogger(util.LogWarn, util.NewPrefixedLogger("mirror-check | ", util.NewLogger(m.ui)))
+	for {
+		report := m.check(mapping, reg)
+		for _, failure := range report.Failures {
+			if failure.Reason != "" {
+				logger.Logf("%s: %s (source %s): %s\n", failure.Destination, failure.Status, failure.Source, failure.Reason)
+			} else {
+				logger.Logf("%s: %s (source %s)\n", failure.Destination, failure.Status, failure.Source)
+			}
+		}
+		logger.Logf("Checked %d image(s): %d not found\n", report.Checked, len(report.Failures))
+
+		if len(report.Failures) > 0 && m.WebhookURL != "" {
+			if err := m.notify(report); err != nil {
+				logger.Warnf("Notifying %s: %s\n", m.WebhookURL, err)
+			}
+		}
+
+		if len(report.Failures) > 0 && (m.Interval == 0 || m.ExitOnFailure) {
+			return fmt.Errorf("Expected %d image(s) to exist in the destination, but %d were not found", report.Checked, len(report.Failures))
+		}
+		if m.Interval == 0 {
+			return nil
+		}
+		m.sleep(m.Interval)
+	}
+}
+
+// readMapping Returns the images listed in the result of copy
+func (m *MirrorCheckOptions) readMapping() ([]copiedImage, error) {
+	return readCopyMapping(m.MappingPath)
+}
+
+// readCopyMapping Returns the images listed in the result of copy written to path
+func readCopyMapping(path string) ([]copiedImage, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Reading mapping: %s", err)
+	}
+
+	var mapping copyResult
+	err = yaml.Unmarshal(bs, &mapping)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing mapping '%s': %s", path, err)
+	}
+	if len(mapping.Images) == 0 {
+		return nil, fmt.Errorf("Expected mapping '%s' to list images, write it with copy --output-type yaml", path)
+	}
+	for _, image := range mapping.Images {
+		if _, err := regname.NewDigest(image.Destination); err != nil {
+			return nil, fmt.Errorf("Expected destination '%s' in mapping '%s' to be a digest reference: %s", image.Destination, path, err)
+		}
+	}
+	return mapping.Images, nil
+}
+
+// check Verifies that the destination of each image exists
+func (m *MirrorCheckOptions) check(mapping []copiedImage, reg mirrorCheckRegistry) mirrorCheckReport {
+	throttle := util.NewThrottle(m.Concurrency)
+
+	failureCh := make(chan *mirrorCheckFailure, len(mapping))
+	for _, image := range mapping {
+		image := image // copy
+
+		go func() {
+			throttle.Take()
+			defer throttle.Done()
+
+			// Destinations were validated when reading the mapping
+			dstRef, _ := regname.NewDigest(image.Destination)
+			_, err := reg.Digest(dstRef)
+			if err == nil {
+				failureCh <- nil
+				return
+			}
+
+			failure := &mirrorCheckFailure{Source: image.Source, Destination: image.Destination, Status: mirrorCheckStatusUnreachable, Reason: err.Error()}
+			if terr, ok := err.(*transport.Error); ok && terr.StatusCode == http.StatusNotFound {
+				failure.Status = mirrorCheckStatusMissing
+				failure.Reason = ""
+			}
+			failureCh <- failure
+		}()
+	}
+
+	report := mirrorCheckReport{CheckedAt: m.now().UTC().Format(time.RFC3339), Checked: len(mapping), Failures: []mirrorCheckFailure{}}
+	for range mapping {
+		if failure := <-failureCh; failure != nil {
+			report.Failures = append(report.Failures, *failure)
+		}
+	}
+	sort.Slice(report.Failures, func(i, j int) bool { return report.Failures[i].Destination < report.Failures[j].Destination })
+	return report
+}
+
+// notify Posts the report to the webhook
+func (m *MirrorCheckOptions) notify(report mirrorCheckReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: mirrorCheckWebhookTimeout}
+	resp, err := client.Post(m.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Expected a 2xx response, but got %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+	"sigs.k8s.io/yaml"
+)
+
+func TestMirrorCheck(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	presentImage := fakeRegistry.WithRandomImage("mirror/present")
+	deletedImage := fakeRegistry.WithRandomImage("other/deleted")
+	fakeRegistry.Build()
+
+	missingRef := fakeRegistry.ReferenceOnTestServer("mirror/present@" + deletedImage.Digest)
+
+	writeMapping := func(t *testing.T, images ...copiedImage) string {
+		bs, err := yaml.Marshal(copyResult{Images: images})
+		require.NoError(t, err)
+		path := filepath.Join(t.TempDir(), "map.yml")
+		require.NoError(t, os.WriteFile(path, bs, 0600))
+		return path
+	}
+
+	newSubject := func(mappingPath string) (*MirrorCheckOptions, *bytes.Buffer) {
+		output := &bytes.Buffer{}
+		subject := NewMirrorCheckOptions(ui.NewWriterUI(output, output, ui.NewNoopLogger()))
+		subject.MappingPath = mappingPath
+		subject.Concurrency = 2
+		return subject, output
+	}
+
+	t.Run("succeeds when every destination exists", func(t *testing.T) {
+		subject, output := newSubject(writeMapping(t, copiedImage{Source: "src/present@" + presentImage.Digest, Destination: presentImage.RefDigest}))
+		require.NoError(t, subject.Run())
+		assert.Contains(t, output.String(), "Checked 1 image(s): 0 not found")
+	})
+
+	t.Run("fails and notifies the webhook when a destination was deleted", func(t *testing.T) {
+		var received mirrorCheckReport
+		webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		}))
+		defer webhook.Close()
+
+		subject, output := newSubject(writeMapping(t,
+			copiedImage{Source: "src/present@" + presentImage.Digest, Destination: presentImage.RefDigest},
+			copiedImage{Source: "src/deleted@" + deletedImage.Digest, Destination: missingRef},
+		))
+		subject.WebhookURL = webhook.URL
+
+		err := subject.Run()
+		require.EqualError(t, err, "Expected 2 image(s) to exist in the destination, but 1 were not found")
+		assert.Contains(t, output.String(), missingRef+": missing (source src/deleted@"+deletedImage.Digest+")")
+
+		assert.Equal(t, 2, received.Checked)
+		require.Len(t, received.Failures, 1)
+		assert.Equal(t, mirrorCheckFailure{Source: "src/deleted@" + deletedImage.Digest, Destination: missingRef, Status: mirrorCheckStatusMissing}, received.Failures[0])
+	})
+
+	t.Run("repeats the check at the interval until a destination is not found", func(t *testing.T) {
+		subject, _ := newSubject(writeMapping(t, copiedImage{Source: "src/deleted@" + deletedImage.Digest, Destination: missingRef}))
+		subject.Interval = time.Hour
+		subject.ExitOnFailure = true
+		var sleeps int
+		subject.sleep = func(time.Duration) { sleeps++ }
+
+		require.Error(t, subject.Run())
+		assert.Equal(t, 0, sleeps)
+	})
+
+	t.Run("fails when the mapping does not list images", func(t *testing.T) {
+		subject, _ := newSubject(writeMapping(t))
+		require.ErrorContains(t, subject.Run(), "to list images, write it with copy --output-type yaml")
+	})
+
+	t.Run("fails when a destination is not a digest reference", func(t *testing.T) {
+		subject, _ := newSubject(writeMapping(t, copiedImage{Source: "src/image:v1", Destination: "dst/image:v1"}))
+		require.ErrorContains(t, subject.Run(), "Expected destination 'dst/image:v1'")
+	})
+}
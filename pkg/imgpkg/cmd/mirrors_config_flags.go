@@ -0,0 +1,50 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+// MirrorsConfigFlags Location of the Config with the pull-through mirrors of the registries
+type MirrorsConfigFlags struct {
+	ConfigPath string
+}
+
+// Set Registers the flags in the command
+func (m *MirrorsConfigFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&m.ConfigPath, "mirrors-config", "",
+		"Config with the mirrors, e.g. pull-through caches, the images of each registry are retrieved from before falling back to the registry "+
+			"(default: $IMGPKG_CONFIG, or ~/.imgpkg/config.yml when present)")
+}
+
+// PullThroughMirrors Loads the mirrors from the Config in the flag, the environment or the home directory.
+// Returns nil when there is no Config to load
+func (m MirrorsConfigFlags) PullThroughMirrors() (registry.Mirrors, error) {
+	path := m.ConfigPath
+	if path == "" {
+		path = os.Getenv("IMGPKG_CONFIG")
+	}
+	if path == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		defaultPath := filepath.Join(homeDir, ".imgpkg", "config.yml")
+		if _, err := os.Stat(defaultPath); err != nil {
+			return nil, nil
+		}
+		path = defaultPath
+	}
+
+	config, err := registry.NewConfigFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.PullThroughMirrors()
+}
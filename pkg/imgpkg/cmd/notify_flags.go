@@ -0,0 +1,132 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+)
+
+const (
+	notificationSucceeded = "succeeded"
+	notificationFailed    = "failed"
+
+	notifyTimeout = 30 * time.Second
+)
+
+// NotifyFlags command line flags to notify a URL when a command finishes
+type NotifyFlags struct {
+	URL          string
+	TemplatePath string
+
+	template *template.Template
+}
+
+// notification Payload sent to the URL when the command finishes, and data provided to the template
+type notification struct {
+	Command     string `json:"command"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	StartedAt   string `json:"startedAt"`
+	FinishedAt  string `json:"finishedAt"`
+	// Report Structured result of the command, the same written with --output-type json, when available
+	Report interface{} `json:"report,omitempty"`
+}
+
+// Set Registers the flags available to the provided command
+func (n *NotifyFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&n.URL, "notify-url", "",
+		"URL that receives, as a JSON POST, the outcome of the command and its structured report when it finishes, successfully or not")
+	cmd.Flags().StringVar(&n.TemplatePath, "notify-template", "",
+		"File with a Go text/template rendering the body sent to --notify-url, with the fields .Command, .Status, .Error, .Source, .Destination, .StartedAt, .FinishedAt and .Report (use {{ json .Error }} to quote values)")
+}
+
+// Validate Checks that the template is only provided together with the URL and can be parsed
+func (n *NotifyFlags) Validate() error {
+	if n.TemplatePath == "" {
+		return nil
+	}
+	if n.URL == "" {
+		return fmt.Errorf("Expected --notify-template to be used with --notify-url")
+	}
+
+	bs, err := os.ReadFile(n.TemplatePath)
+	if err != nil {
+		return fmt.Errorf("Reading --notify-template: %s", err)
+	}
+	n.template, err = template.New("notify").Funcs(template.FuncMap{"json": notifyTemplateJSON}).Option("missingkey=error").Parse(string(bs))
+	if err != nil {
+		return fmt.Errorf("Parsing --notify-template '%s': %s", n.TemplatePath, err)
+	}
+	return nil
+}
+
+// Notify Posts the outcome of the command to the URL. Failing to notify does not change the result of the command,
+// so errors are only logged
+func (n NotifyFlags) Notify(logger util.LoggerWithLevels, notification notification, cmdErr error) {
+	if n.URL == "" {
+		return
+	}
+
+	notification.Status = notificationSucceeded
+	if cmdErr != nil {
+		notification.Status = notificationFailed
+		notification.Error = cmdErr.Error()
+	}
+	notification.FinishedAt = time.Now().UTC().Format(time.RFC3339)
+
+	err := n.post(notification)
+	if err != nil {
+		logger.Warnf("Notifying %s: %s\n", n.URL, err)
+	}
+}
+
+func (n NotifyFlags) post(notification notification) error {
+	var body bytes.Buffer
+	if n.template != nil {
+		if err := n.template.Execute(&body, notification); err != nil {
+			return fmt.Errorf("Rendering --notify-template: %s", err)
+		}
+	} else {
+		if err := json.NewEncoder(&body).Encode(notification); err != nil {
+			return err
+		}
+	}
+
+	client := &http.Client{Timeout: notifyTimeout}
+	resp, err := client.Post(n.URL, "application/json", &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Expected a 2xx response, but got %s", resp.Status)
+	}
+	return nil
+}
+
+// notifyTemplateJSON Encodes the value as JSON, so that it can be embedded in a JSON payload
+func notifyTemplateJSON(value interface{}) (string, error) {
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
+
+// newNotification Creates the notification for a command that started at the provided time
+func newNotification(command string, startedAt time.Time) notification {
+	return notification{Command: command, StartedAt: startedAt.UTC().Format(time.RFC3339)}
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+// notifyServer Records the bodies posted to it
+func notifyServer(t *testing.T) (*httptest.Server, *[][]byte) {
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		bodies = append(bodies, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &bodies
+}
+
+func TestCopyNotify(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	imageInfo := fakeRegistry.WithRandomImage("some/image")
+	fakeRegistry.Build()
+
+	newCopyOpts := func(notifyURL string) *CopyOptions {
+		copyOpts := NewCopyOptions(ui.NewWriterUI(&bytes.Buffer{}, &bytes.Buffer{}, ui.NewNoopLogger()))
+		copyOpts.ImageFlags = ImageFlags{Image: imageInfo.RefDigest}
+		copyOpts.RepoDst = fakeRegistry.ReferenceOnTestServer("copied/image")
+		copyOpts.Concurrency = 1
+		copyOpts.NotifyFlags = NotifyFlags{URL: notifyURL}
+		return copyOpts
+	}
+
+	t.Run("posts the copied images when the copy succeeds", func(t *testing.T) {
+		server, bodies := notifyServer(t)
+		require.NoError(t, newCopyOpts(server.URL).Run())
+
+		require.Len(t, *bodies, 1)
+		var received struct {
+			notification
+			Report copyResult `json:"report"`
+		}
+		require.NoError(t, json.Unmarshal((*bodies)[0], &received))
+		assert.Equal(t, "copy", received.Command)
+		assert.Equal(t, notificationSucceeded, received.Status)
+		assert.Equal(t, imageInfo.RefDigest, received.Source)
+		require.Len(t, received.Report.Images, 1)
+		assert.Equal(t, imageInfo.RefDigest, received.Report.Images[0].Source)
+	})
+
+	t.Run("posts the error when the copy fails", func(t *testing.T) {
+		server, bodies := notifyServer(t)
+		copyOpts := newCopyOpts(server.URL)
+		copyOpts.ImageFlags = ImageFlags{Image: fakeRegistry.ReferenceOnTestServer("some/missing:v1")}
+		copyErr := copyOpts.Run()
+		require.Error(t, copyErr)
+
+		require.Len(t, *bodies, 1)
+		var received notification
+		require.NoError(t, json.Unmarshal((*bodies)[0], &received))
+		assert.Equal(t, notificationFailed, received.Status)
+		assert.Equal(t, copyErr.Error(), received.Error)
+		assert.Nil(t, received.Report)
+	})
+
+	t.Run("renders the payload with the template", func(t *testing.T) {
+		server, bodies := notifyServer(t)
+		templatePath := filepath.Join(t.TempDir(), "notify.tmpl")
+		require.NoError(t, os.WriteFile(templatePath, []byte(`{"text": {{ json (printf "%s %s: %d images" .Command .Status (len .Report.Images)) }}}`), 0600))
+
+		copyOpts := newCopyOpts(server.URL)
+		copyOpts.NotifyFlags.TemplatePath = templatePath
+		require.NoError(t, copyOpts.Run())
+
+		require.Len(t, *bodies, 1)
+		assert.JSONEq(t, `{"text": "copy succeeded: 1 images"}`, string((*bodies)[0]))
+	})
+}
+
+func TestPushNotify(t *testing.T) {
+	t.Run("posts the error when the push fails", func(t *testing.T) {
+		server, bodies := notifyServer(t)
+		push := PushOptions{ui: ui.NewWriterUI(&bytes.Buffer{}, &bytes.Buffer{}, ui.NewNoopLogger()), NotifyFlags: NotifyFlags{URL: server.URL}}
+		err := push.Run()
+		require.EqualError(t, err, "Expected either image or bundle")
+
+		require.Len(t, *bodies, 1)
+		var received notification
+		require.NoError(t, json.Unmarshal((*bodies)[0], &received))
+		assert.Equal(t, "push", received.Command)
+		assert.Equal(t, notificationFailed, received.Status)
+		assert.Equal(t, "Expected either image or bundle", received.Error)
+	})
+
+	t.Run("fails before pushing when the template cannot be parsed", func(t *testing.T) {
+		templatePath := filepath.Join(t.TempDir(), "notify.tmpl")
+		require.NoError(t, os.WriteFile(templatePath, []byte(`{{ .Status `), 0600))
+
+		push := PushOptions{NotifyFlags: NotifyFlags{URL: "http://localhost", TemplatePath: templatePath}}
+		require.ErrorContains(t, push.Run(), "Parsing --notify-template")
+	})
+
+	t.Run("fails when the template is provided without the URL", func(t *testing.T) {
+		push := PushOptions{NotifyFlags: NotifyFlags{TemplatePath: "notify.tmpl"}}
+		require.EqualError(t, push.Run(), "Expected --notify-template to be used with --notify-url")
+	})
+}
@@ -0,0 +1,26 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// OCIFlags command line flags to copy between folders following the OCI Image Layout specification
+type OCIFlags struct {
+	OCISrc string
+	OCIDst string
+}
+
+// Set Registers the flags available to the provided command
+func (o *OCIFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.OCISrc, "oci", "", "Path to an OCI image layout folder which contains assets to be copied to another OCI image layout folder (--to-oci)")
+	cmd.Flags().StringVar(&o.OCIDst, "to-oci", "", "Location of an OCI image layout folder, created when it does not exist, to write the assets of an OCI image layout folder (--oci)")
+}
+
+// IsSrc returns true when copying from an OCI image layout folder
+func (o OCIFlags) IsSrc() bool { return o.OCISrc != "" }
+
+// IsDst returns true when copying to an OCI image layout folder
+func (o OCIFlags) IsDst() bool { return o.OCIDst != "" }
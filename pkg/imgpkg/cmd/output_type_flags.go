@@ -0,0 +1,112 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	goui "github.com/cppforlife/go-cli-ui/ui"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	textOutputType = "text"
+	jsonOutputType = "json"
+	yamlOutputType = "yaml"
+)
+
+// OutputTypeFlags command line flags to configure the format of the result of a command
+type OutputTypeFlags struct {
+	OutputType string
+
+	supportsYAML bool
+}
+
+// Set Registers the flags available to the provided command
+func (o *OutputTypeFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.OutputType, "output-type", "o", textOutputType, "Type of output possible values: [text, json]. When json, only the result is written to stdout and logs are written to stderr")
+	_ = cmd.RegisterFlagCompletionFunc("output-type", completeValues(textOutputType, jsonOutputType))
+}
+
+// SetCopy Registers the flags available to the copy command, which can also write its result as yaml
+func (o *OutputTypeFlags) SetCopy(cmd *cobra.Command) {
+	o.supportsYAML = true
+	cmd.Flags().StringVarP(&o.OutputType, "output-type", "o", textOutputType, "Type of output possible values: [text, json, yaml]. When json or yaml, only the result is written to stdout and logs are written to stderr")
+	_ = cmd.RegisterFlagCompletionFunc("output-type", completeValues(textOutputType, jsonOutputType, yamlOutputType))
+}
+
+// Validate checks if the output type is supported
+func (o OutputTypeFlags) Validate() error {
+	switch {
+	case o.OutputType == "", o.OutputType == textOutputType, o.OutputType == jsonOutputType:
+		return nil
+	case o.OutputType == yamlOutputType && o.supportsYAML:
+		return nil
+	case o.supportsYAML:
+		return fmt.Errorf("--output-type can only have the following values [text, json, yaml]")
+	default:
+		return fmt.Errorf("--output-type can only have the following values [text, json]")
+	}
+}
+
+// IsJSON returns true when the result should be written as JSON
+func (o OutputTypeFlags) IsJSON() bool { return o.OutputType == jsonOutputType }
+
+// IsStructured returns true when the result should be written as JSON or YAML
+func (o OutputTypeFlags) IsStructured() bool {
+	return o.OutputType == jsonOutputType || o.OutputType == yamlOutputType
+}
+
+// LogsUI returns the UI that should be used to write logs.
+// When the result is written as JSON, logs are written to stderr, so that stdout only contains the result
+func (o OutputTypeFlags) LogsUI(ui goui.UI) goui.UI {
+	if !o.IsStructured() {
+		return ui
+	}
+
+	stderrUI := NewQuietUI(goui.NewWriterUI(os.Stderr, os.Stderr, goui.NewNoopLogger()))
+	if isQuiet(ui) {
+		stderrUI.EnableQuiet()
+	}
+	return stderrUI
+}
+
+// WriteJSON writes the result to stdout as a single JSON object
+func (o OutputTypeFlags) WriteJSON(ui goui.UI, result interface{}) error {
+	bs, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Marshaling result: %s", err)
+	}
+	writeResult(ui, string(bs))
+	return nil
+}
+
+// WriteResult writes the result to stdout as a single JSON or YAML document, depending on the output type
+func (o OutputTypeFlags) WriteResult(ui goui.UI, result interface{}) error {
+	if o.OutputType != yamlOutputType {
+		return o.WriteJSON(ui, result)
+	}
+
+	bs, err := yaml.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("Marshaling result: %s", err)
+	}
+	writeResult(ui, strings.TrimSuffix(string(bs), "\n"))
+	return nil
+}
+
+// IsMachineReadableOutput checks if the executed command was configured to only write its result to stdout
+func IsMachineReadableOutput(cmd *cobra.Command) bool {
+	if quiet, err := cmd.Flags().GetBool("quiet"); err == nil && quiet {
+		return true
+	}
+	if outputType, err := cmd.Flags().GetString("output-type"); err == nil && (outputType == jsonOutputType || outputType == yamlOutputType) {
+		return true
+	}
+	return false
+}
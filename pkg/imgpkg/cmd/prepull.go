@@ -0,0 +1,323 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	prepullBinDir     = "/imgpkg-prepull"
+	prepullBinPath    = prepullBinDir + "/true"
+	prepullVolumeName = "imgpkg-prepull"
+	prepullNameLabel  = "app.kubernetes.io/name"
+	prepullNodeLabel  = "imgpkg.carvel.dev/prepull-node"
+)
+
+// prepullImagePullFailures Reasons of a waiting container whose image cannot be pulled
+var prepullImagePullFailures = map[string]struct{}{
+	"ErrImagePull":     {},
+	"ImagePullBackOff": {},
+	"InvalidImageName": {},
+}
+
+// PrepullOptions Command Line options that can be provided to the prepull command
+type PrepullOptions struct {
+	ui ui.UI
+
+	BundleFlags    BundleFlags
+	LockInputFlags LockInputFlags
+	RegistryFlags  RegistryFlags
+
+	Concurrency      int
+	Kubeconfig       string
+	Context          string
+	Name             string
+	Namespace        string
+	NodeSelector     map[string]string
+	ImagePullSecrets []string
+	HelperImage      string
+	Timeout          time.Duration
+
+	// pollInterval Time between the checks of the status of the pods
+	pollInterval time.Duration
+}
+
+// NewPrepullOptions constructor for building a PrepullOptions, holding values derived via flags
+func NewPrepullOptions(ui ui.UI) *PrepullOptions {
+	return &PrepullOptions{ui: ui, pollInterval: 2 * time.Second}
+}
+
+// NewPrepullCmd Creates the prepull command
+func NewPrepullCmd(o *PrepullOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prepull",
+		Short: "Pull all the images of a bundle onto the nodes of a Kubernetes cluster",
+		Long: `Pull all the images of a bundle onto the nodes of a Kubernetes cluster.
+A short-lived pod is created on each selected node. Every image of the bundle, and of its nested bundles, is an init
+container of the pod, so that the kubelet of the node pulls it. The init containers run a static binary copied from the
+helper image instead of their own entrypoint, so images without a shell are pulled as well. Once every pod completed
+the pods are deleted, the images stay in the cache of the nodes.`,
+		RunE: func(cmd *cobra.Command, _ []string) error { return o.RunWithContext(cmd.Context()) },
+		Example: `
+    # Pull the images of bundle repo/app1-bundle onto the nodes labeled pool=workers before an upgrade window
+    imgpkg prepull -b repo/app1-bundle --node-selector pool=workers
+
+    # Use the credentials of a context of a kubeconfig and a pull secret of the namespace of the pods
+    imgpkg prepull -b repo/app1-bundle --kubeconfig ~/.kube/prod --context prod -n apps --image-pull-secret regcred`,
+	}
+
+	o.BundleFlags.Set(cmd)
+	o.LockInputFlags.SetOnPrepull(cmd)
+	o.RegistryFlags.Set(cmd)
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 5, "Concurrency")
+	cmd.Flags().StringVar(&o.Kubeconfig, "kubeconfig", "", "Path to the kubeconfig of the cluster (default: $KUBECONFIG or ~/.kube/config)")
+	cmd.Flags().StringVar(&o.Context, "context", "", "Context of the kubeconfig to use (default: the current context)")
+	cmd.Flags().StringVar(&o.Name, "name", "imgpkg-prepull", "Prefix of the name of the pods")
+	cmd.Flags().StringVarP(&o.Namespace, "namespace", "n", "", "Namespace of the pods (default: the namespace of the context, or default)")
+	cmd.Flags().StringToStringVar(&o.NodeSelector, "node-selector", nil,
+		"Only pull the images onto nodes with this label (format: key=value) (can be specified multiple times)")
+	cmd.Flags().StringSliceVar(&o.ImagePullSecrets, "image-pull-secret", nil,
+		"Secret, in the namespace of the pods, used by the nodes to pull the images (can be specified multiple times)")
+	cmd.Flags().StringVar(&o.HelperImage, "helper-image", "busybox:1.36",
+		"Image containing a static busybox binary, run by the init containers instead of the entrypoint of the pulled images")
+	cmd.Flags().DurationVar(&o.Timeout, "timeout", 15*time.Minute, "Maximum time to wait for the images to be pulled onto all the nodes (ms|s|m|h)")
+	return cmd
+}
+
+// Run Pulls the images of the bundle onto the nodes of the cluster
+func (p *PrepullOptions) Run() error {
+	return p.RunWithContext(context.Background())
+}
+
+// RunWithContext Same as Run, the requests to the registries and the cluster are cancelled when ctx is done
+func (p *PrepullOptions) RunWithContext(ctx context.Context) error {
+	bundleRef := p.BundleFlags.Bundle
+	switch {
+	case p.BundleFlags.Bundle != "" && p.LockInputFlags.LockFilePath != "":
+		return fmt.Errorf("Expected either --lock or --bundle (-b), not both")
+	case p.LockInputFlags.LockFilePath != "":
+		var err error
+		bundleRef, err = p.LockInputFlags.BundleImage()
+		if err != nil {
+			return err
+		}
+	case bundleRef == "":
+		return fmt.Errorf("Expected either --lock or --bundle (-b) with the bundle whose images are pulled")
+	}
+
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: p.Kubeconfig, Precedence: clientcmd.NewDefaultClientConfigLoadingRules().Precedence},
+		&clientcmd.ConfigOverrides{CurrentContext: p.Context})
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return fmt.Errorf("Loading kubeconfig: %s", err)
+	}
+	if p.Namespace == "" {
+		p.Namespace, _, err = clientConfig.Namespace()
+		if err != nil {
+			return fmt.Errorf("Loading kubeconfig: %s", err)
+		}
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("Building Kubernetes client: %s", err)
+	}
+
+	description, err := v1.DescribeWithContext(ctx, bundleRef, v1.DescribeOpts{
+		Logger:      util.NewUILevelLogger(util.LogWarn, util.NewLogger(p.ui)),
+		Concurrency: p.Concurrency,
+	}, p.RegistryFlags.AsRegistryOpts())
+	if err != nil {
+		return err
+	}
+
+	images := prepullImages(description)
+	if len(images) == 0 {
+		return fmt.Errorf("Expected bundle '%s' to contain at least one image to pull", bundleRef)
+	}
+
+	return p.prepull(ctx, client, images)
+}
+
+// prepull Creates a pod pulling the images on each selected node, waits for all of them to complete and deletes them
+func (p *PrepullOptions) prepull(ctx context.Context, client kubernetes.Interface, images []string) error {
+	logger := util.NewUILevelLogger(util.LogWarn, util.NewLogger(p.ui))
+
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(p.NodeSelector).String()})
+	if err != nil {
+		return fmt.Errorf("Listing nodes: %s", err)
+	}
+	if len(nodes.Items) == 0 {
+		return fmt.Errorf("Expected at least one node matching --node-selector '%s'", labels.SelectorFromSet(p.NodeSelector).String())
+	}
+
+	var pods []*corev1.Pod
+	defer func() {
+		// The pods are deleted even when ctx is done, otherwise they would be left behind in the cluster
+		for _, pod := range pods {
+			err := client.CoreV1().Pods(p.Namespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+			if err != nil {
+				logger.Warnf("Deleting pod '%s/%s': %s\n", p.Namespace, pod.Name, err)
+			}
+		}
+	}()
+
+	for _, node := range nodes.Items {
+		pod, err := client.CoreV1().Pods(p.Namespace).Create(ctx, p.pod(node.Name, images), metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("Creating pod on node '%s': %s", node.Name, err)
+		}
+		pods = append(pods, pod)
+	}
+	logger.Logf("Pulling %d image(s) onto %d node(s)\n", len(images), len(pods))
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	var failures []string
+	pending := pods
+	for len(pending) > 0 {
+		var stillPending []*corev1.Pod
+		for _, pod := range pending {
+			nodeName := pod.Spec.NodeName
+			current, err := client.CoreV1().Pods(p.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("Getting pod '%s/%s': %s", p.Namespace, pod.Name, err)
+			}
+
+			done, failure := prepullPodResult(current)
+			switch {
+			case !done:
+				stillPending = append(stillPending, pod)
+			case failure != "":
+				failures = append(failures, fmt.Sprintf("%s: %s", nodeName, failure))
+			default:
+				logger.Logf("Pulled the images onto node '%s'\n", nodeName)
+			}
+		}
+		pending = stillPending
+		if len(pending) == 0 {
+			break
+		}
+
+		select {
+		case <-timeoutCtx.Done():
+			for _, pod := range pending {
+				failures = append(failures, fmt.Sprintf("%s: Images not pulled after %s", pod.Spec.NodeName, p.Timeout))
+			}
+			pending = nil
+		case <-time.After(p.pollInterval):
+		}
+	}
+
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return fmt.Errorf("Pulling the images onto %d node(s) failed:\n- %s", len(failures), strings.Join(failures, "\n- "))
+	}
+	return nil
+}
+
+// prepullPodResult Checks if the pod completed, failure is the reason when it failed or one of its images cannot be pulled
+func prepullPodResult(pod *corev1.Pod) (bool, string) {
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded:
+		return true, ""
+	case corev1.PodFailed:
+		return true, fmt.Sprintf("Pod failed: %s %s", pod.Status.Reason, pod.Status.Message)
+	}
+
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		if status.State.Waiting == nil {
+			continue
+		}
+		if _, found := prepullImagePullFailures[status.State.Waiting.Reason]; found {
+			return true, fmt.Sprintf("Pulling image '%s': %s %s", status.Image, status.State.Waiting.Reason, status.State.Waiting.Message)
+		}
+	}
+	return false, ""
+}
+
+// prepullImages returns the images, excluding bundles and signatures, of the bundle and all its nested bundles
+func prepullImages(description v1.Description) []string {
+	seen := map[string]struct{}{}
+	var collect func(v1.Description)
+	collect = func(desc v1.Description) {
+		for _, img := range desc.Content.Images {
+			if img.ImageType == bundle.ContentImage && img.Error == "" {
+				seen[img.Image] = struct{}{}
+			}
+		}
+		for _, nested := range desc.Content.Bundles {
+			collect(nested)
+		}
+	}
+	collect(description)
+
+	var images []string
+	for img := range seen {
+		images = append(images, img)
+	}
+	sort.Strings(images)
+	return images
+}
+
+// pod Builds the pod that pulls the images onto the node, it is bound to the node without going through the scheduler
+func (p *PrepullOptions) pod(nodeName string, images []string) *corev1.Pod {
+	mount := []corev1.VolumeMount{{Name: prepullVolumeName, MountPath: prepullBinDir}}
+
+	// busybox runs the applet named as the file it is invoked with, true in this case
+	initContainers := []corev1.Container{{
+		Name:         "copy-true",
+		Image:        p.HelperImage,
+		Command:      []string{"cp", "/bin/busybox", prepullBinPath},
+		VolumeMounts: mount,
+	}}
+	for i, img := range images {
+		initContainers = append(initContainers, corev1.Container{
+			Name:            fmt.Sprintf("image-%d", i),
+			Image:           img,
+			Command:         []string{prepullBinPath},
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			VolumeMounts:    mount,
+		})
+	}
+
+	var pullSecrets []corev1.LocalObjectReference
+	for _, secret := range p.ImagePullSecrets {
+		pullSecrets = append(pullSecrets, corev1.LocalObjectReference{Name: secret})
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: p.Name + "-",
+			Namespace:    p.Namespace,
+			Labels:       map[string]string{prepullNameLabel: p.Name, prepullNodeLabel: nodeName},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:         nodeName,
+			RestartPolicy:    corev1.RestartPolicyNever,
+			ImagePullSecrets: pullSecrets,
+			InitContainers:   initContainers,
+			// The helper image is already on the node, its container completes as soon as it starts
+			Containers: []corev1.Container{{Name: "done", Image: p.HelperImage, Command: []string{"true"}}},
+			Volumes:    []corev1.Volume{{Name: prepullVolumeName, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+		},
+	}
+}
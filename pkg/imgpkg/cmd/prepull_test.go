@@ -0,0 +1,172 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestPrepullNoBundle(t *testing.T) {
+	err := (&PrepullOptions{}).Run()
+	require.EqualError(t, err, "Expected either --lock or --bundle (-b) with the bundle whose images are pulled")
+}
+
+func TestPrepullPod(t *testing.T) {
+	description := v1.Description{
+		Image: "registry.io/app@sha256:aaa",
+		Content: v1.Content{
+			Images: map[string]v1.ImageInfo{
+				"sha256:bbb": {Image: "registry.io/app@sha256:bbb", ImageType: bundle.ContentImage},
+				"sha256:ccc": {Image: "registry.io/app@sha256:ccc", ImageType: bundle.SignatureImage},
+			},
+			Bundles: map[string]v1.Description{
+				"sha256:ddd": {
+					Image: "registry.io/app@sha256:ddd",
+					Content: v1.Content{Images: map[string]v1.ImageInfo{
+						"sha256:bbb": {Image: "registry.io/app@sha256:bbb", ImageType: bundle.ContentImage},
+						"sha256:eee": {Image: "registry.io/app@sha256:eee", ImageType: bundle.ContentImage},
+					}},
+				},
+			},
+		},
+	}
+
+	t.Run("pulls the images of the bundle and its nested bundles, without signatures", func(t *testing.T) {
+		assert.Equal(t, []string{"registry.io/app@sha256:bbb", "registry.io/app@sha256:eee"}, prepullImages(description))
+	})
+
+	t.Run("each image is an init container running the binary copied from the helper image", func(t *testing.T) {
+		subject := PrepullOptions{Name: "prepull", Namespace: "apps", HelperImage: "busybox", ImagePullSecrets: []string{"regcred"}}
+		pod := subject.pod("node-1", prepullImages(description))
+
+		assert.Equal(t, "prepull-", pod.GenerateName)
+		assert.Equal(t, "apps", pod.Namespace)
+		assert.Equal(t, "node-1", pod.Spec.NodeName)
+		assert.Equal(t, corev1.RestartPolicyNever, pod.Spec.RestartPolicy)
+		assert.Equal(t, []corev1.LocalObjectReference{{Name: "regcred"}}, pod.Spec.ImagePullSecrets)
+
+		require.Len(t, pod.Spec.InitContainers, 3)
+		assert.Equal(t, "busybox", pod.Spec.InitContainers[0].Image)
+		assert.Equal(t, []string{"cp", "/bin/busybox", "/imgpkg-prepull/true"}, pod.Spec.InitContainers[0].Command)
+		for i, img := range []string{"registry.io/app@sha256:bbb", "registry.io/app@sha256:eee"} {
+			assert.Equal(t, img, pod.Spec.InitContainers[i+1].Image)
+			assert.Equal(t, []string{"/imgpkg-prepull/true"}, pod.Spec.InitContainers[i+1].Command)
+		}
+		require.Len(t, pod.Spec.Containers, 1)
+		assert.Equal(t, "busybox", pod.Spec.Containers[0].Image)
+	})
+}
+
+func TestPrepullPods(t *testing.T) {
+	node := func(name string, labels map[string]string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+
+	// newClient returns a fake cluster in which the pods get the status returned by podStatus when they are created
+	newClient := func(podStatus func(nodeName string) corev1.PodStatus) *fake.Clientset {
+		client := fake.NewSimpleClientset(
+			node("worker-1", map[string]string{"pool": "workers"}),
+			node("worker-2", map[string]string{"pool": "workers"}),
+			node("control-plane", map[string]string{"pool": "control-plane"}),
+		)
+		client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+			pod.Name = pod.GenerateName + pod.Spec.NodeName
+			pod.Status = podStatus(pod.Spec.NodeName)
+			return false, nil, nil
+		})
+		return client
+	}
+
+	prepullOpts := func(timeout time.Duration) *PrepullOptions {
+		return &PrepullOptions{
+			ui:           ui.NewConfUI(ui.NewNoopLogger()),
+			Name:         "prepull",
+			Namespace:    "apps",
+			NodeSelector: map[string]string{"pool": "workers"},
+			HelperImage:  "busybox",
+			Timeout:      timeout,
+			pollInterval: time.Millisecond,
+		}
+	}
+
+	createdPodNodes := func(client *fake.Clientset) []string {
+		var nodes []string
+		for _, action := range client.Actions() {
+			if action.Matches("create", "pods") {
+				nodes = append(nodes, action.(k8stesting.CreateAction).GetObject().(*corev1.Pod).Spec.NodeName)
+			}
+		}
+		return nodes
+	}
+
+	t.Run("creates a pod on each selected node and deletes the pods once they completed", func(t *testing.T) {
+		client := newClient(func(string) corev1.PodStatus { return corev1.PodStatus{Phase: corev1.PodSucceeded} })
+
+		err := prepullOpts(time.Minute).prepull(context.Background(), client, []string{"registry.io/app@sha256:bbb"})
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []string{"worker-1", "worker-2"}, createdPodNodes(client))
+		pods, err := client.CoreV1().Pods("apps").List(context.Background(), metav1.ListOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, pods.Items)
+	})
+
+	t.Run("reports the nodes onto which an image cannot be pulled", func(t *testing.T) {
+		client := newClient(func(nodeName string) corev1.PodStatus {
+			if nodeName == "worker-1" {
+				return corev1.PodStatus{Phase: corev1.PodSucceeded}
+			}
+			return corev1.PodStatus{
+				Phase: corev1.PodPending,
+				InitContainerStatuses: []corev1.ContainerStatus{{
+					Image: "registry.io/app@sha256:bbb",
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "unauthorized"}},
+				}},
+			}
+		})
+
+		err := prepullOpts(time.Minute).prepull(context.Background(), client, []string{"registry.io/app@sha256:bbb"})
+		require.EqualError(t, err, "Pulling the images onto 1 node(s) failed:\n- worker-2: Pulling image 'registry.io/app@sha256:bbb': ImagePullBackOff unauthorized")
+
+		pods, err := client.CoreV1().Pods("apps").List(context.Background(), metav1.ListOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, pods.Items)
+	})
+
+	t.Run("fails and deletes the pods when they do not complete before the timeout", func(t *testing.T) {
+		client := newClient(func(string) corev1.PodStatus { return corev1.PodStatus{Phase: corev1.PodPending} })
+
+		err := prepullOpts(10*time.Millisecond).prepull(context.Background(), client, []string{"registry.io/app@sha256:bbb"})
+		require.ErrorContains(t, err, "Pulling the images onto 2 node(s) failed:")
+		require.ErrorContains(t, err, "worker-1: Images not pulled after 10ms")
+
+		pods, err := client.CoreV1().Pods("apps").List(context.Background(), metav1.ListOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, pods.Items)
+	})
+
+	t.Run("fails when no node matches the node selector", func(t *testing.T) {
+		client := newClient(func(string) corev1.PodStatus { return corev1.PodStatus{Phase: corev1.PodSucceeded} })
+		subject := prepullOpts(time.Minute)
+		subject.NodeSelector = map[string]string{"pool": "gpu"}
+
+		err := subject.prepull(context.Background(), client, []string{"registry.io/app@sha256:bbb"})
+		require.EqualError(t, err, "Expected at least one node matching --node-selector 'pool=gpu'")
+		assert.Empty(t, createdPodNodes(client))
+	})
+}
@@ -0,0 +1,266 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/cheggaaa/pb/v3"
+	"github.com/cheggaaa/pb/v3/termutil"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	ctlimgset "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+const (
+	// progressEventType Type of the JSON events reporting the bytes transferred
+	progressEventType = "progress"
+	// jsonProgressInterval Minimum time between two JSON events reporting the bytes transferred of the same digest
+	jsonProgressInterval = time.Second
+	// barsRefreshRate Time between two renders of the progress bars
+	barsRefreshRate = 200 * time.Millisecond
+)
+
+// progressReporter Reports the events of the copy and the bytes transferred for each image, or blob
+type progressReporter interface {
+	ctlimgset.EventListener
+	registry.TransferProgressListener
+	// Stop Writes the final progress, no more progress is reported after it
+	Stop()
+}
+
+// progressSources Source references of the images being copied, by digest
+type progressSources struct {
+	lock    sync.Mutex
+	sources map[string]string
+}
+
+// Record keeps the source of the images that start being copied
+func (p *progressSources) Record(event ctlimgset.Event) {
+	if event.Type != ctlimgset.EventImageStarted {
+		return
+	}
+	idx := strings.LastIndex(event.Source, "@")
+	if idx == -1 {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.sources == nil {
+		p.sources = map[string]string{}
+	}
+	p.sources[event.Source[idx+1:]] = event.Source
+}
+
+// Source Returns the source reference of the digest, empty when it is not known
+func (p *progressSources) Source(digest string) string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.sources[digest]
+}
+
+// progressEvent JSON event reporting the bytes transferred for a digest
+type progressEvent struct {
+	Type     string    `json:"type"`
+	Digest   string    `json:"digest"`
+	Source   string    `json:"source,omitempty"`
+	Complete int64     `json:"complete"`
+	Total    int64     `json:"total"`
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// jsonProgressReporter Writes a JSON object per line for each event, and at most one progress event per second for
+// each digest, apart from the first and the last one
+type jsonProgressReporter struct {
+	output  io.Writer
+	now     func() time.Time
+	sources progressSources
+
+	lock     sync.Mutex
+	reported map[string]time.Time
+}
+
+func newJSONProgressReporter(output io.Writer) *jsonProgressReporter {
+	return &jsonProgressReporter{output: output, now: time.Now, reported: map[string]time.Time{}}
+}
+
+// OnEvent writes the event
+func (j *jsonProgressReporter) OnEvent(event ctlimgset.Event) {
+	j.sources.Record(event)
+	j.write(event)
+}
+
+// OnTransferProgress writes the bytes transferred for the digest
+func (j *jsonProgressReporter) OnTransferProgress(digest string, update regv1.Update) {
+	now := j.now()
+	finished := update.Error != nil || (update.Total > 0 && update.Complete >= update.Total)
+
+	j.lock.Lock()
+	last, found := j.reported[digest]
+	if found && !finished && now.Sub(last) < jsonProgressInterval {
+		j.lock.Unlock()
+		return
+	}
+	j.reported[digest] = now
+	j.lock.Unlock()
+
+	event := progressEvent{
+		Type:     progressEventType,
+		Digest:   digest,
+		Source:   j.sources.Source(digest),
+		Complete: update.Complete,
+		Total:    update.Total,
+		Time:     now.UTC(),
+	}
+	if update.Error != nil {
+		event.Error = update.Error.Error()
+	}
+	j.write(event)
+}
+
+// Stop does nothing, every event was already written
+func (j *jsonProgressReporter) Stop() {}
+
+func (j *jsonProgressReporter) write(event interface{}) {
+	bs, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	fmt.Fprintf(j.output, "%s\n", bs)
+}
+
+// barsProgressReporter Renders a progress bar for each digest being transferred, with the bytes transferred, the
+// speed and the ETA, labeled with the source of the image when it is known.
+// Finished bars are written once, above the bars that are still being redrawn
+type barsProgressReporter struct {
+	output  io.Writer
+	sources progressSources
+
+	lock     sync.Mutex
+	bars     map[string]*pb.ProgressBar
+	active   []string
+	rendered int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newBarsProgressReporter(output io.Writer) *barsProgressReporter {
+	b := &barsProgressReporter{output: output, bars: map[string]*pb.ProgressBar{}, stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(b.done)
+		ticker := time.NewTicker(barsRefreshRate)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.render()
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+	return b
+}
+
+// OnEvent records the source of the images
+func (b *barsProgressReporter) OnEvent(event ctlimgset.Event) {
+	b.sources.Record(event)
+}
+
+// OnTransferProgress updates the bar of the digest, creating it when it does not exist
+func (b *barsProgressReporter) OnTransferProgress(digest string, update regv1.Update) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	bar, found := b.bars[digest]
+	if !found {
+		if update.Error != nil {
+			return
+		}
+		bar = pb.New64(update.Total).SetTemplate(pb.Full)
+		bar.Set(pb.Bytes, true)
+		bar.Set(pb.Static, true)
+		bar.Set("prefix", b.label(digest))
+		bar.Start()
+		b.bars[digest] = bar
+		b.active = append(b.active, digest)
+	}
+
+	if update.Error != nil {
+		bar.Set("suffix", "failed")
+		bar.Finish()
+		return
+	}
+	bar.SetTotal(update.Total)
+	bar.SetCurrent(update.Complete)
+	if update.Total > 0 && update.Complete >= update.Total {
+		bar.Finish()
+	}
+}
+
+// Stop renders the bars a last time
+func (b *barsProgressReporter) Stop() {
+	close(b.stop)
+	<-b.done
+	b.render()
+}
+
+// render Writes the bars that finished since the last render followed by the active ones, over the active bars
+// written before
+func (b *barsProgressReporter) render() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	width, err := termutil.TerminalWidth()
+	if err != nil {
+		width = 0
+	}
+
+	out := &strings.Builder{}
+	if b.rendered > 0 {
+		fmt.Fprintf(out, "\033[%dA", b.rendered)
+	}
+	var finished, active []string
+	for _, digest := range b.active {
+		if b.bars[digest].IsFinished() {
+			finished = append(finished, digest)
+		} else {
+			active = append(active, digest)
+		}
+	}
+	for _, digest := range append(finished, active...) {
+		bar := b.bars[digest]
+		if width > 0 {
+			bar.SetWidth(width)
+		}
+		fmt.Fprintf(out, "\r%s\033[K\n", bar.String())
+	}
+	b.active = active
+	b.rendered = len(active)
+	fmt.Fprint(b.output, out.String())
+}
+
+// label Returns the repository of the source of the image followed by the shortened digest, only the shortened digest
+// when the source is not known
+func (b *barsProgressReporter) label(digest string) string {
+	shortDigest := digest
+	if algorithm, hex, found := strings.Cut(digest, ":"); found && len(hex) > 12 {
+		shortDigest = algorithm + ":" + hex[:12]
+	}
+	if source := b.sources.Source(digest); source != "" {
+		return source[:strings.LastIndex(source, "@")+1] + shortDigest
+	}
+	return shortDigest
+}
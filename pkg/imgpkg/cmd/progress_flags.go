@@ -0,0 +1,89 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+)
+
+const (
+	progressQuiet = "quiet"
+	progressPlain = "plain"
+	progressBars  = "bars"
+	progressJSON  = "json"
+)
+
+// ProgressFlags command line flag to select how the progress of the images being transferred is reported
+type ProgressFlags struct {
+	Progress string
+}
+
+// Set Registers the flags available to the provided command, the progress is reported per image uploaded
+func (p *ProgressFlags) Set(cmd *cobra.Command) {
+	p.set(cmd, "image uploaded")
+}
+
+// SetOnPull Registers the flags available to the pull command, the progress is reported per layer downloaded
+func (p *ProgressFlags) SetOnPull(cmd *cobra.Command) {
+	p.set(cmd, "layer downloaded")
+}
+
+func (p *ProgressFlags) set(cmd *cobra.Command, unit string) {
+	cmd.Flags().StringVar(&p.Progress, "progress", progressPlain,
+		"How the progress is reported: quiet (only warnings and errors), plain (a line per operation), "+
+			"bars (a progress bar per "+unit+" with the bytes transferred, speed and ETA, plain when stderr is not a terminal) "+
+			"or json (a JSON event per line written to stderr, with the bytes transferred per "+unit+")")
+}
+
+// Validate Checks that the progress mode is known
+func (p ProgressFlags) Validate() error {
+	switch p.Progress {
+	case "", progressQuiet, progressPlain, progressBars, progressJSON:
+		return nil
+	}
+	return fmt.Errorf("Expected --progress to be one of quiet, plain, bars or json, but got '%s'", p.Progress)
+}
+
+// Mode Returns the progress mode that is used, bars are replaced by plain lines when stderr is not a terminal
+func (p ProgressFlags) Mode() string {
+	switch {
+	case p.Progress == "":
+		return progressPlain
+	case p.Progress == progressBars && !util.IsTerminal(os.Stderr):
+		return progressPlain
+	}
+	return p.Progress
+}
+
+// Logger Returns the logger used to report the operations. Only the plain mode writes a line per operation,
+// the other modes keep the warnings, errors and debug messages
+func (p ProgressFlags) Logger(logger util.LoggerWithLevels) util.LoggerWithLevels {
+	if p.Mode() == progressPlain {
+		return logger
+	}
+	return progressLevelLogger{logger}
+}
+
+// Reporter Returns the reporter of the progress of each image, nil when the progress is not reported per image
+func (p ProgressFlags) Reporter() progressReporter {
+	switch p.Mode() {
+	case progressBars:
+		return newBarsProgressReporter(os.Stderr)
+	case progressJSON:
+		return newJSONProgressReporter(os.Stderr)
+	}
+	return nil
+}
+
+// progressLevelLogger Drops the lines reporting the operations, that are replaced by the progress reporter
+type progressLevelLogger struct {
+	util.LoggerWithLevels
+}
+
+// Logf does nothing
+func (progressLevelLogger) Logf(string, ...interface{}) {}
@@ -0,0 +1,95 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ctlimgset "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+)
+
+const progressTestDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestProgressFlags(t *testing.T) {
+	t.Run("accepts the known modes", func(t *testing.T) {
+		for _, mode := range []string{"", "quiet", "plain", "bars", "json"} {
+			require.NoError(t, ProgressFlags{Progress: mode}.Validate(), mode)
+		}
+		require.EqualError(t, ProgressFlags{Progress: "fancy"}.Validate(), "Expected --progress to be one of quiet, plain, bars or json, but got 'fancy'")
+	})
+
+	t.Run("only writes the operations in plain mode", func(t *testing.T) {
+		for mode, expected := range map[string]string{"plain": "Tagging images\nWarning: slow\n", "json": "Warning: slow\n", "quiet": "Warning: slow\n"} {
+			output := &bytes.Buffer{}
+			logger := ProgressFlags{Progress: mode}.Logger(util.NewUILevelLogger(util.LogWarn, util.NewBufferLogger(output)))
+			logger.Logf("Tagging images\n")
+			logger.Warnf("slow\n")
+			assert.Equal(t, expected, output.String(), mode)
+		}
+	})
+
+	t.Run("does not report the progress per image in plain and quiet modes", func(t *testing.T) {
+		assert.Equal(t, "plain", ProgressFlags{}.Mode())
+		assert.Nil(t, ProgressFlags{}.Reporter())
+		assert.Nil(t, ProgressFlags{Progress: "quiet"}.Reporter())
+	})
+}
+
+func TestJSONProgressReporter(t *testing.T) {
+	output := &bytes.Buffer{}
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	subject := newJSONProgressReporter(output)
+	subject.now = func() time.Time { return now }
+
+	subject.OnEvent(ctlimgset.Event{Type: ctlimgset.EventImageStarted, Source: "index.docker.io/org/app@" + progressTestDigest, Time: now})
+	subject.OnTransferProgress(progressTestDigest, regv1.Update{Total: 100})
+	subject.OnTransferProgress(progressTestDigest, regv1.Update{Total: 100, Complete: 10})
+	now = now.Add(time.Second)
+	subject.OnTransferProgress(progressTestDigest, regv1.Update{Total: 100, Complete: 50})
+	subject.OnTransferProgress(progressTestDigest, regv1.Update{Total: 100, Complete: 100})
+	subject.OnTransferProgress("sha256:other", regv1.Update{Error: errors.New("denied")})
+	subject.Stop()
+
+	var events []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		event := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &event), line)
+		events = append(events, event)
+	}
+
+	require.Len(t, events, 5, "the update received less than a second after the previous one is not written")
+	assert.Equal(t, "image-started", events[0]["type"])
+	for i, complete := range []float64{0, 50, 100} {
+		assert.Equal(t, "progress", events[i+1]["type"])
+		assert.Equal(t, progressTestDigest, events[i+1]["digest"])
+		assert.Equal(t, "index.docker.io/org/app@"+progressTestDigest, events[i+1]["source"])
+		assert.Equal(t, complete, events[i+1]["complete"])
+		assert.Equal(t, float64(100), events[i+1]["total"])
+	}
+	assert.Equal(t, "sha256:other", events[4]["digest"])
+	assert.Equal(t, "denied", events[4]["error"])
+}
+
+func TestBarsProgressReporter(t *testing.T) {
+	output := &bytes.Buffer{}
+	subject := newBarsProgressReporter(output)
+
+	subject.OnEvent(ctlimgset.Event{Type: ctlimgset.EventImageStarted, Source: "index.docker.io/org/app@" + progressTestDigest})
+	subject.OnTransferProgress(progressTestDigest, regv1.Update{Total: 2048, Complete: 1024})
+	subject.OnTransferProgress("sha256:fedcba9876543210fedcba", regv1.Update{Total: 2048, Complete: 2048})
+	subject.Stop()
+
+	assert.Contains(t, output.String(), "index.docker.io/org/app@sha256:0123456789ab ")
+	assert.Contains(t, output.String(), "sha256:fedcba987654 ")
+	assert.Contains(t, output.String(), "1.00 KiB / 2.00 KiB")
+}
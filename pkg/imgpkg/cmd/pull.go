@@ -4,13 +4,19 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
 	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	ctlimg "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/image"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
 	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
 )
 
@@ -20,10 +26,23 @@ type PullOptions struct {
 	ImageFlags           ImageFlags
 	ImageIsBundleCheck   bool
 	RegistryFlags        RegistryFlags
+	BandwidthFlags       BandwidthFlags
 	BundleFlags          BundleFlags
 	LockInputFlags       LockInputFlags
 	BundleRecursiveFlags BundleRecursiveFlags
+	TrustFlags           TrustFlags
+	RequireDigestsFlags  RequireDigestsFlags
+	LockOutputFlags      LockOutputFlags
+	ProgressFlags        ProgressFlags
+	MirrorsConfigFlags   MirrorsConfigFlags
 	OutputPath           string
+	ToOCIPath            string
+	ConfigOnly           bool
+	ExtractLinkMode      string
+
+	WarnOnContentsMismatch bool
+	LocationsRepo          string
+	ExpectedTagDigest      string
 }
 
 func NewPullOptions(ui ui.UI) *PullOptions {
@@ -34,42 +53,99 @@ func NewPullCmd(o *PullOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "pull",
 		Short: "Pull files from bundle, image, or bundle lock file",
-		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			o.RequireDigestsFlags.SetFromCmd(cmd)
+			return o.RunWithContext(cmd.Context())
+		},
 		Example: `
   # Pull bundle repo/app1-bundle and extract into /tmp/app1-bundle
   imgpkg pull -b repo/app1-bundle -o /tmp/app1-bundle
 
   # Pull image repo/app1-image and extract into /tmp/app1-image
-  imgpkg pull -i repo/app1-image -o /tmp/app1-image`,
+  imgpkg pull -i repo/app1-image -o /tmp/app1-image
+
+  # Pull bundle repo/app1-bundle and every bundle nested in it, each extracted into /tmp/app1-bundle/.imgpkg/bundles/sha256-<digest>
+  imgpkg pull -b repo/app1-bundle -o /tmp/app1-bundle --recursive
+
+  # Pull bundle repo/app1-bundle and its nested bundles, rewriting the ImagesLock of the nested bundles to the destinations recorded by copy --output-type yaml
+  imgpkg pull -b repo/app1-bundle -o /tmp/app1-bundle --recursive --nested-images-lock mapping --nested-images-lock-mapping copy-result.yml
+
+  # Pull the bundle recorded in bundle.lock.yml, generated with --lock-output, and extract into /tmp/app1-bundle
+  imgpkg pull --lock bundle.lock.yml -o /tmp/app1-bundle
+
+  # Pull bundle repo/app1-bundle:v1.0.0 only when the tag still references the reviewed digest, and record it in bundle.lock.yml
+  imgpkg pull -b repo/app1-bundle:v1.0.0 -o /tmp/app1-bundle --expected-tag-digest sha256:... --lock-output bundle.lock.yml
+
+  # Pull bundle repo/app1-bundle only when it, and all the images it references, are signed with cosign by the key in cosign.pub
+  imgpkg pull -b repo/app1-bundle -o /tmp/app1-bundle --verify-signature --signature-key cosign.pub
+
+  # Pull bundle repo/app1-bundle over a shared link, using at most 5MB per second
+  imgpkg pull -b repo/app1-bundle -o /tmp/app1-bundle --max-bandwidth 5MB
+
+  # Pull bundle repo/app1-bundle retrieving its images from the pull-through caches in mirrors.yml before the registry of each image
+  imgpkg pull -b repo/app1-bundle -o /tmp/app1-bundle --mirrors-config mirrors.yml
+
+  # Pull bundle repo/app1-bundle writing the progress of each layer as JSON events to stderr
+  imgpkg pull -b repo/app1-bundle -o /tmp/app1-bundle --progress json
+
+  # Pull only the .imgpkg directory, labels and annotations of bundle repo/app1-bundle into /tmp/app1-bundle-config
+  imgpkg pull -b repo/app1-bundle -o /tmp/app1-bundle-config --config-only
+
+  # Write bundle repo/app1-bundle, and all the images it references, as an OCI image layout into /tmp/app1-bundle-oci
+  imgpkg pull -b repo/app1-bundle --to-oci /tmp/app1-bundle-oci --recursive`,
 	}
 	o.ImageFlags.Set(cmd)
 	cmd.Flags().BoolVar(&o.ImageIsBundleCheck, "image-is-bundle-check", true, "Error when image is a bundle (disable pulling bundles via -i)")
 	o.RegistryFlags.Set(cmd)
+	o.BandwidthFlags.Set(cmd)
 	o.BundleFlags.Set(cmd)
 	o.BundleRecursiveFlags.Set(cmd)
-	o.LockInputFlags.Set(cmd)
+	o.LockInputFlags.SetOnPull(cmd)
+	o.TrustFlags.Set(cmd)
+	o.MirrorsConfigFlags.Set(cmd)
+	o.LockOutputFlags.SetOnPull(cmd)
+	o.ProgressFlags.SetOnPull(cmd)
 	cmd.Flags().StringVarP(&o.OutputPath, "output", "o", "", "Output directory path")
-	cmd.MarkFlagRequired("output")
+	cmd.Flags().StringVar(&o.ToOCIPath, "to-oci", "",
+		"Write the bundle, or image, as an OCI image layout into this directory instead of extracting its files (with --recursive the images of the bundle are included)")
+	cmd.Flags().StringVar(&o.ExtractLinkMode, "extract-link-mode", string(ctlimg.LinkModeReflink),
+		"How bundle files already extracted in --registry-cache-dir are placed in the output directory (copy, reflink, hardlink). "+
+			"Hard linked files share their content with the cache and should not be modified")
+	cmd.Flags().BoolVar(&o.ConfigOnly, "config-only", false,
+		"Only extract the bundle configuration: the .imgpkg directory and the labels and annotations of the bundle image (written to "+bundle.ImageMetadataFile+")")
+	cmd.Flags().BoolVar(&o.WarnOnContentsMismatch, "warn-on-contents-mismatch", false,
+		"Warn, instead of failing, when the extracted files do not match the contents manifest recorded in the bundle with push --contents-manifest")
+	cmd.Flags().StringVar(&o.LocationsRepo, "locations-repo", "",
+		"Repository where the bundle was copied to, used to look up the locations of its images (default: repository of the bundle, set when using a registry proxy that changes repository paths)")
+	cmd.Flags().StringVar(&o.ExpectedTagDigest, "expected-tag-digest", "",
+		"Fail when the tag of the bundle or image does not reference this digest, the digest is pulled otherwise (format: sha256:...)")
 
 	return cmd
 }
 
+// Run Executes the pull command
 func (po *PullOptions) Run() error {
+	return po.RunWithContext(context.Background())
+}
+
+// RunWithContext Same as Run, the downloads, including the external artifacts of a bundle, are cancelled when ctx is done
+func (po *PullOptions) RunWithContext(ctx context.Context) error {
 	err := po.validate()
 	if err != nil {
 		return err
 	}
 
-	levelLogger := util.NewUILevelLogger(util.LogWarn, util.NewLogger(po.ui))
+	progressFlags := po.ProgressFlags
+	if isQuiet(po.ui) {
+		progressFlags.Progress = progressQuiet
+	}
+	levelLogger := progressFlags.Logger(util.NewUILevelLogger(util.LogWarn, util.NewLogger(po.ui)))
 	imageRef := ""
 	switch {
 	case len(po.LockInputFlags.LockFilePath) > 0:
-		if len(po.LockInputFlags.LockFilePath) > 0 {
-			bundleLock, err := lockconfig.NewBundleLockFromPath(po.LockInputFlags.LockFilePath)
-			if err != nil {
-				return err
-			}
-			imageRef = bundleLock.Bundle.Image
+		imageRef, err = po.LockInputFlags.BundleImage()
+		if err != nil {
+			return err
 		}
 	case len(po.BundleFlags.Bundle) > 0:
 		imageRef = po.BundleFlags.Bundle
@@ -79,15 +155,78 @@ func (po *PullOptions) Run() error {
 		panic("Unreachable code")
 	}
 
+	pulledTag := ""
+	if tagRef, err := regname.NewTag(imageRef); err == nil {
+		pulledTag = tagRef.TagStr()
+	}
+	if po.ExpectedTagDigest != "" {
+		imageRef, err = po.pinExpectedTagDigest(ctx, imageRef)
+		if err != nil {
+			return err
+		}
+	}
+
+	trustConfig, err := po.TrustFlags.TrustConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	nestedImagesLock, nestedImagesLockMapping, err := po.BundleRecursiveFlags.NestedImagesLockOpts()
+	if err != nil {
+		return err
+	}
+
 	pullOpts := v1.PullOpts{
-		Logger:   levelLogger,
-		AsImage:  !po.ImageIsBundleCheck,
-		IsBundle: len(po.ImageFlags.Image) == 0,
+		Logger:     levelLogger,
+		AsImage:    !po.ImageIsBundleCheck,
+		IsBundle:   len(po.ImageFlags.Image) == 0,
+		ConfigOnly: po.ConfigOnly,
+		CacheDir:   po.RegistryFlags.CacheDir,
+		LinkMode:   ctlimg.LinkMode(po.ExtractLinkMode),
+
+		WarnOnContentsMismatch: po.WarnOnContentsMismatch,
+		LocationsRepo:          po.LocationsRepo,
+		TrustConfig:            trustConfig,
+
+		NestedImagesLock:        nestedImagesLock,
+		NestedImagesLockMapping: nestedImagesLockMapping,
 	}
-	if po.BundleRecursiveFlags.Recursive {
-		_, err = v1.PullRecursive(imageRef, po.OutputPath, pullOpts, po.RegistryFlags.AsRegistryOpts())
+	registryOpts, err := po.registryOpts()
+	if err != nil {
+		return err
+	}
+	if progress := progressFlags.Reporter(); progress != nil {
+		defer progress.Stop()
+		registryOpts.DownloadProgress = progress
+	}
+	reg, err := po.registry(ctx, registryOpts, levelLogger)
+	if err != nil {
+		return err
+	}
+	pullOpts.HTTPClient, err = registry.NewHTTPClient(ctx, registryOpts)
+	if err != nil {
+		return err
+	}
+	var status v1.PullStatus
+	if po.ToOCIPath != "" {
+		status, err = v1.PullToOCILayoutWithRegistry(imageRef, po.ToOCIPath, v1.PullToOCILayoutOpts{
+			Logger:      levelLogger,
+			Concurrency: 5,
+			AsImage:     pullOpts.AsImage,
+			IsBundle:    pullOpts.IsBundle,
+			Recursive:   po.BundleRecursiveFlags.Recursive,
+			TrustConfig: trustConfig,
+		}, reg)
+	} else if po.BundleRecursiveFlags.Recursive {
+		status, err = v1.PullRecursiveWithRegistry(imageRef, po.OutputPath, pullOpts, reg)
 	} else {
-		_, err = v1.Pull(imageRef, po.OutputPath, pullOpts, po.RegistryFlags.AsRegistryOpts())
+		status, err = v1.PullWithRegistry(imageRef, po.OutputPath, pullOpts, reg)
+	}
+	if err == nil && isQuiet(po.ui) {
+		writeResult(po.ui, status.ImageRef)
+	}
+	if err == nil {
+		err = po.writeLockOutput(status, pulledTag)
 	}
 
 	if errors.Is(err, &v1.ErrIsBundle{}) {
@@ -106,13 +245,31 @@ func (po *PullOptions) Run() error {
 }
 
 func (po *PullOptions) validate() error {
-	if po.OutputPath == "" {
+	if po.OutputPath == "" && po.ToOCIPath == "" {
 		return fmt.Errorf("Expected --output to be none empty")
 	}
+	if po.OutputPath != "" && po.ToOCIPath != "" {
+		return fmt.Errorf("Expected only one of --output (-o) or --to-oci")
+	}
 
 	if po.OutputPath == "/" || po.OutputPath == "." || po.OutputPath == ".." {
 		return fmt.Errorf("Disallowed output directory (trying to avoid accidental deletion)")
 	}
+	if err := po.ProgressFlags.Validate(); err != nil {
+		return err
+	}
+	if po.ToOCIPath != "" {
+		if po.ConfigOnly {
+			return fmt.Errorf("Cannot use --config-only flag with --to-oci, the OCI image layout contains the whole bundle")
+		}
+		if po.LocationsRepo != "" || po.WarnOnContentsMismatch {
+			return fmt.Errorf("Cannot use --locations-repo or --warn-on-contents-mismatch flags with --to-oci, the files of the bundle are not extracted")
+		}
+		nestedImagesLock := po.BundleRecursiveFlags.NestedImagesLock
+		if po.BundleRecursiveFlags.NestedImagesLockMapping != "" || (nestedImagesLock != "" && nestedImagesLock != string(bundle.NestedImagesLockCollocated)) {
+			return fmt.Errorf("Cannot use --nested-images-lock flags with --to-oci, the ImagesLock of nested bundles is not written")
+		}
+	}
 
 	presentInputParams := 0
 	for _, inputParam := range []string{po.LockInputFlags.LockFilePath, po.BundleFlags.Bundle, po.ImageFlags.Image} {
@@ -127,8 +284,129 @@ func (po *PullOptions) validate() error {
 		return fmt.Errorf("Expected either image or bundle reference")
 	}
 
+	if err := po.RequireDigestsFlags.ValidateRef("--bundle (-b)", po.BundleFlags.Bundle); err != nil {
+		return err
+	}
+	if err := po.RequireDigestsFlags.ValidateRef("--image (-i)", po.ImageFlags.Image); err != nil {
+		return err
+	}
+
 	if po.BundleRecursiveFlags.Recursive && len(po.ImageFlags.Image) > 0 {
 		return fmt.Errorf("Cannot use --recursive (-r) flag when pulling a bundle")
 	}
+	if err := po.BundleRecursiveFlags.Validate(); err != nil {
+		return err
+	}
+	if !po.isValidLinkMode() {
+		return fmt.Errorf("Expected --extract-link-mode to be one of %v, got '%s'", ctlimg.LinkModes, po.ExtractLinkMode)
+	}
+	if po.ExpectedTagDigest != "" {
+		if len(po.LockInputFlags.LockFilePath) > 0 {
+			return fmt.Errorf("Cannot use --expected-tag-digest flag when pulling from a lock file, lock files reference digests")
+		}
+		if _, err := regv1.NewHash(po.ExpectedTagDigest); err != nil {
+			return fmt.Errorf("Parsing --expected-tag-digest: %s", err)
+		}
+	}
+	if po.ConfigOnly && len(po.ImageFlags.Image) > 0 {
+		return fmt.Errorf("Cannot use --config-only flag when pulling an image (hint: Use -b instead of -i for bundles)")
+	}
 	return nil
 }
+
+// registryOpts Options used to access the registries, limited to the bandwidth provided with --max-bandwidth
+func (po *PullOptions) registryOpts() (registry.Opts, error) {
+	maxBandwidth, err := po.BandwidthFlags.BytesPerSecond()
+	if err != nil {
+		return registry.Opts{}, err
+	}
+	opts := po.RegistryFlags.AsRegistryOpts()
+	opts.MaxBandwidth = maxBandwidth
+	return opts, nil
+}
+
+// registry Returns the registry the bundle, or image, is pulled from, retrieving the images from the pull-through
+// mirrors of the --mirrors-config before their registry
+func (po *PullOptions) registry(ctx context.Context, registryOpts registry.Opts, logger registry.MirrorsLogger) (registry.Registry, error) {
+	pullThroughMirrors, err := po.MirrorsConfigFlags.PullThroughMirrors()
+	if err != nil {
+		return nil, err
+	}
+	reg, err := registry.NewSimpleRegistryWithContext(ctx, registryOpts)
+	if err != nil {
+		return nil, err
+	}
+	if len(pullThroughMirrors) == 0 {
+		return reg, nil
+	}
+	return registry.NewRegistryWithMirrors(reg, nil, logger).WithPullThroughMirrors(pullThroughMirrors), nil
+}
+
+// pinExpectedTagDigest Returns the reference to the digest the tag in imageRef resolves to, failing when it is not
+// the expected digest, so that the tag cannot be moved between the check and the pull
+func (po *PullOptions) pinExpectedTagDigest(ctx context.Context, imageRef string) (string, error) {
+	tagRef, err := regname.NewTag(imageRef)
+	if err != nil {
+		return "", fmt.Errorf("Expected a tag reference when using --expected-tag-digest but got '%s'", imageRef)
+	}
+
+	registryOpts, err := po.registryOpts()
+	if err != nil {
+		return "", err
+	}
+	reg, err := registry.NewSimpleRegistryWithContext(ctx, registryOpts)
+	if err != nil {
+		return "", err
+	}
+	digest, err := reg.Digest(tagRef)
+	if err != nil {
+		return "", fmt.Errorf("Resolving tag '%s': %s", tagRef.Name(), err)
+	}
+	if digest.String() != po.ExpectedTagDigest {
+		return "", fmt.Errorf("Expected tag '%s' to reference digest '%s' but it references '%s'", tagRef.Name(), po.ExpectedTagDigest, digest)
+	}
+
+	return tagRef.Context().Digest(digest.String()).Name(), nil
+}
+
+// writeLockOutput Records the digest of the pulled bundle, or image, in a BundleLock, or ImagesLock, file
+func (po *PullOptions) writeLockOutput(status v1.PullStatus, tag string) error {
+	if po.LockOutputFlags.LockFilePath == "" {
+		return nil
+	}
+
+	if len(po.ImageFlags.Image) == 0 {
+		bundleLock := lockconfig.BundleLock{
+			LockVersion: lockconfig.LockVersion{
+				APIVersion: lockconfig.BundleLockAPIVersion,
+				Kind:       lockconfig.BundleLockKind,
+			},
+			Bundle: lockconfig.BundleRef{
+				Image: status.ImageRef,
+				Tag:   tag,
+			},
+		}
+		return bundleLock.WriteToPath(po.LockOutputFlags.LockFilePath)
+	}
+
+	imagesLock := lockconfig.ImagesLock{
+		LockVersion: lockconfig.LockVersion{
+			APIVersion: lockconfig.ImagesLockAPIVersion,
+			Kind:       lockconfig.ImagesLockKind,
+		},
+		Images: []lockconfig.ImageRef{{Image: status.ImageRef}},
+	}
+	return imagesLock.WriteToPath(po.LockOutputFlags.LockFilePath)
+}
+
+func (po *PullOptions) isValidLinkMode() bool {
+	if po.ExtractLinkMode == "" {
+		return true
+	}
+	for _, mode := range ctlimg.LinkModes {
+		if po.ExtractLinkMode == string(mode) {
+			return true
+		}
+	}
+	return false
+}
@@ -4,10 +4,28 @@
 package cmd
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	fakekms "github.com/sigstore/sigstore/pkg/signature/kms/fake"
 	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
 	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
 )
 
@@ -26,6 +44,21 @@ func TestPullErrors(t *testing.T) {
 		require.ErrorContains(t, err, "Expected only one of image, bundle, or lock")
 	})
 
+	t.Run("fails when the lock file is an ImagesLock", func(t *testing.T) {
+		lockPath := filepath.Join(t.TempDir(), "images.lock.yml")
+		require.NoError(t, os.WriteFile(lockPath, []byte(`---
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images:
+- image: index.docker.io/k8slt/image@sha256:703218c0465075f4425e58fac086e09e1de5c340b12976ab9eb8ad26615c3715
+`), 0600))
+
+		pull := PullOptions{OutputPath: "/tmp/some/place", LockInputFlags: LockInputFlags{LockFilePath: lockPath}}
+		err := pull.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Expected a BundleLock in '"+lockPath+"' but found an ImagesLock")
+	})
+
 	t.Run("fails when recursive flag is provided but not the bundle flag", func(t *testing.T) {
 		pull := PullOptions{OutputPath: "/tmp/some/place", ImageFlags: ImageFlags{"image@123456"}, BundleRecursiveFlags: BundleRecursiveFlags{Recursive: true}}
 		err := pull.Run()
@@ -33,6 +66,27 @@ func TestPullErrors(t *testing.T) {
 		require.ErrorContains(t, err, "Cannot use --recursive (-r) flag when pulling a bundle")
 	})
 
+	t.Run("fails when the ImagesLock of nested bundles is written without recursive flag", func(t *testing.T) {
+		pull := PullOptions{OutputPath: "/tmp/some/place", BundleFlags: BundleFlags{"my-bundle"}, BundleRecursiveFlags: BundleRecursiveFlags{NestedImagesLock: "origin"}}
+		err := pull.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Expected --recursive (-r) when using --nested-images-lock")
+	})
+
+	t.Run("fails when the ImagesLock of nested bundles is written with a mapping but no mapping is provided", func(t *testing.T) {
+		pull := PullOptions{OutputPath: "/tmp/some/place", BundleFlags: BundleFlags{"my-bundle"}, BundleRecursiveFlags: BundleRecursiveFlags{Recursive: true, NestedImagesLock: "mapping"}}
+		err := pull.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Expected --nested-images-lock-mapping when using --nested-images-lock mapping")
+	})
+
+	t.Run("fails when the way of writing the ImagesLock of nested bundles is unknown", func(t *testing.T) {
+		pull := PullOptions{OutputPath: "/tmp/some/place", BundleFlags: BundleFlags{"my-bundle"}, BundleRecursiveFlags: BundleRecursiveFlags{Recursive: true, NestedImagesLock: "somewhere"}}
+		err := pull.Run()
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Expected --nested-images-lock to be one of [collocated origin mapping], got 'somewhere'")
+	})
+
 	t.Run("fails when arguments are provided without a flag", func(t *testing.T) {
 		confUI := ui.NewConfUI(ui.NewNoopLogger())
 		defer confUI.Flush()
@@ -89,3 +143,450 @@ func TestPullErrors(t *testing.T) {
 		require.ErrorContains(t, err, "Expected bundle image but found plain image (hint: Did you use -i instead of -b?)")
 	})
 }
+
+func TestPullExternalArtifacts(t *testing.T) {
+	artifactContent := []byte("large dataset that is not stored in the registry")
+	servedContent := artifactContent
+	artifactServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(servedContent)
+	}))
+	defer artifactServer.Close()
+
+	bundleDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(bundleDir, ".imgpkg"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(bundleDir, ".imgpkg", "images.yml"), []byte("apiVersion: imgpkg.carvel.dev/v1alpha1\nkind: ImagesLock\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(bundleDir, "config.yml"), []byte("some: config"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(bundleDir, "data"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(bundleDir, "data", "dataset.bin"), artifactContent, 0600))
+
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	fakeRegistry.Build()
+
+	confUI := ui.NewConfUI(ui.NewNoopLogger())
+	defer confUI.Flush()
+
+	bundleRef := fakeRegistry.ReferenceOnTestServer("thin/bundle")
+	push := PushOptions{
+		ui:                confUI,
+		FileFlags:         FileFlags{Files: []string{bundleDir}},
+		BundleFlags:       BundleFlags{Bundle: bundleRef},
+		ExternalArtifacts: []string{"data/dataset.bin=" + artifactServer.URL + "/dataset.bin"},
+	}
+	require.NoError(t, push.Run())
+
+	t.Run("downloads the external artifacts into the bundle", func(t *testing.T) {
+		outputPath := t.TempDir()
+		pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, OutputPath: outputPath, ImageIsBundleCheck: true}
+		require.NoError(t, pull.Run())
+
+		content, err := os.ReadFile(filepath.Join(outputPath, "data", "dataset.bin"))
+		require.NoError(t, err)
+		require.Equal(t, artifactContent, content)
+		require.FileExists(t, filepath.Join(outputPath, "config.yml"))
+	})
+
+	t.Run("fails when the external artifact does not match its digest", func(t *testing.T) {
+		servedContent = []byte("tampered dataset")
+		defer func() { servedContent = artifactContent }()
+
+		outputPath := t.TempDir()
+		pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, OutputPath: outputPath, ImageIsBundleCheck: true}
+		err := pull.Run()
+		require.ErrorContains(t, err, "Fetching external artifact 'data/dataset.bin'")
+		require.ErrorContains(t, err, "Expected digest sha256:")
+		require.NoFileExists(t, filepath.Join(outputPath, "data", "dataset.bin"))
+	})
+
+	t.Run("downloads the external artifacts trusting the registry CA certificates", func(t *testing.T) {
+		tlsArtifactServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(artifactContent)
+		}))
+		defer tlsArtifactServer.Close()
+		caCertPath := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(caCertPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsArtifactServer.Certificate().Raw}), 0600))
+
+		tlsBundleRef := fakeRegistry.ReferenceOnTestServer("thin/tls-bundle")
+		push := PushOptions{
+			ui:                confUI,
+			FileFlags:         FileFlags{Files: []string{bundleDir}},
+			BundleFlags:       BundleFlags{Bundle: tlsBundleRef},
+			ExternalArtifacts: []string{"data/dataset.bin=" + tlsArtifactServer.URL + "/dataset.bin"},
+		}
+		require.NoError(t, push.Run())
+
+		pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: tlsBundleRef}, OutputPath: t.TempDir(), ImageIsBundleCheck: true,
+			RegistryFlags: RegistryFlags{VerifyCerts: true}}
+		err := pull.Run()
+		require.ErrorContains(t, err, "Fetching external artifact 'data/dataset.bin'")
+		require.ErrorContains(t, err, "certificate")
+
+		outputPath := t.TempDir()
+		pull = PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: tlsBundleRef}, OutputPath: outputPath, ImageIsBundleCheck: true,
+			RegistryFlags: RegistryFlags{VerifyCerts: true, CACertPaths: []string{caCertPath}}}
+		require.NoError(t, pull.Run())
+		content, err := os.ReadFile(filepath.Join(outputPath, "data", "dataset.bin"))
+		require.NoError(t, err)
+		require.Equal(t, artifactContent, content)
+	})
+}
+
+func TestPullContentsManifest(t *testing.T) {
+	bundleDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(bundleDir, ".imgpkg"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(bundleDir, ".imgpkg", "images.yml"), []byte("apiVersion: imgpkg.carvel.dev/v1alpha1\nkind: ImagesLock\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(bundleDir, "config.yml"), []byte("some: config"), 0600))
+
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	reg := fakeRegistry.Build()
+
+	confUI := ui.NewConfUI(ui.NewNoopLogger())
+	defer confUI.Flush()
+
+	bundleRef := fakeRegistry.ReferenceOnTestServer("verified/bundle")
+	push := PushOptions{
+		ui:                        confUI,
+		FileFlags:                 FileFlags{Files: []string{bundleDir}},
+		BundleFlags:               BundleFlags{Bundle: bundleRef},
+		ContentsManifest:          true,
+		ContentsManifestAlgorithm: "sha512",
+	}
+	require.NoError(t, push.Run())
+
+	// Simulates a layer that no longer matches the files hashed when the bundle was pushed
+	corruptedRef := fakeRegistry.ReferenceOnTestServer("corrupted/bundle:1.0")
+	{
+		pushedRef, err := regname.ParseReference(bundleRef)
+		require.NoError(t, err)
+		img, err := reg.Image(pushedRef)
+		require.NoError(t, err)
+		cfg, err := img.ConfigFile()
+		require.NoError(t, err)
+		cfg = cfg.DeepCopy()
+		var manifest bundle.ContentsManifest
+		require.NoError(t, json.Unmarshal([]byte(cfg.Config.Labels[bundle.BundleContentsManifestLabel]), &manifest))
+		manifest.Files["config.yml"] = strings.Repeat("0", 128)
+		manifestJSON, err := json.Marshal(manifest)
+		require.NoError(t, err)
+		cfg.Config.Labels[bundle.BundleContentsManifestLabel] = string(manifestJSON)
+		img, err = mutate.ConfigFile(img, cfg)
+		require.NoError(t, err)
+
+		dstRef, err := regname.ParseReference(corruptedRef)
+		require.NoError(t, err)
+		require.NoError(t, reg.WriteImage(dstRef, img, nil))
+	}
+
+	t.Run("when the files match the contents manifest, it pulls the bundle", func(t *testing.T) {
+		outputPath := t.TempDir()
+		pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}, OutputPath: outputPath, ImageIsBundleCheck: true}
+		require.NoError(t, pull.Run())
+		require.FileExists(t, filepath.Join(outputPath, "config.yml"))
+	})
+
+	t.Run("when a file does not match the contents manifest, it fails", func(t *testing.T) {
+		pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: corruptedRef}, OutputPath: t.TempDir(), ImageIsBundleCheck: true}
+		err := pull.Run()
+		require.ErrorContains(t, err, "1 file(s) do not match the contents manifest")
+		require.ErrorContains(t, err, "config.yml: expected sha512 "+strings.Repeat("0", 128))
+	})
+
+	t.Run("when only the configuration is pulled, only the .imgpkg directory is verified", func(t *testing.T) {
+		pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: corruptedRef}, OutputPath: t.TempDir(), ImageIsBundleCheck: true, ConfigOnly: true}
+		require.NoError(t, pull.Run())
+	})
+
+	t.Run("when --warn-on-contents-mismatch is provided, it pulls the bundle", func(t *testing.T) {
+		outputPath := t.TempDir()
+		pull := PullOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: corruptedRef}, OutputPath: outputPath, ImageIsBundleCheck: true, WarnOnContentsMismatch: true}
+		require.NoError(t, pull.Run())
+		require.FileExists(t, filepath.Join(outputPath, "config.yml"))
+	})
+}
+
+func TestPullTrustConfig(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	unsignedBundle := fakeRegistry.WithRandomBundle("trusted/bundle")
+	fakeRegistry.Build()
+
+	trustDir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(trustDir, "cosign.pub"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: keyDER}), 0600))
+
+	trustConfigPath := filepath.Join(trustDir, "trust.yml")
+	require.NoError(t, os.WriteFile(trustConfigPath, []byte(`
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: TrustConfig
+origins:
+- repository: `+fakeRegistry.ReferenceOnTestServer("trusted")+`
+  publicKeys: [cosign.pub]
+`), 0600))
+
+	confUI := ui.NewConfUI(ui.NewNoopLogger())
+	defer confUI.Flush()
+
+	pull := PullOptions{
+		ui:                 confUI,
+		BundleFlags:        BundleFlags{Bundle: unsignedBundle.RefDigest},
+		OutputPath:         t.TempDir(),
+		ImageIsBundleCheck: true,
+		TrustFlags:         TrustFlags{TrustConfigPath: trustConfigPath},
+	}
+	require.ErrorContains(t, pull.Run(), "Verifying signature of")
+}
+
+func TestPullVerifySignature(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	unsignedBundle := fakeRegistry.WithRandomBundle("some/bundle")
+	fakeRegistry.Build()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	keyPath := filepath.Join(t.TempDir(), "cosign.pub")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: keyDER}), 0600))
+
+	confUI := ui.NewConfUI(ui.NewNoopLogger())
+	defer confUI.Flush()
+
+	pullWithTrustFlags := func(trustFlags TrustFlags) error {
+		pull := PullOptions{
+			ui:                 confUI,
+			BundleFlags:        BundleFlags{Bundle: unsignedBundle.RefDigest},
+			OutputPath:         t.TempDir(),
+			ImageIsBundleCheck: true,
+			TrustFlags:         trustFlags,
+		}
+		return pull.Run()
+	}
+
+	t.Run("fails when the bundle is not signed by the key", func(t *testing.T) {
+		err := pullWithTrustFlags(TrustFlags{VerifySignature: true, SignatureKeys: []string{keyPath}})
+		require.ErrorContains(t, err, "Verifying signature of '"+unsignedBundle.RefDigest+"': Image is not signed")
+	})
+
+	t.Run("fails when no key is provided", func(t *testing.T) {
+		err := pullWithTrustFlags(TrustFlags{VerifySignature: true})
+		require.EqualError(t, err, "Expected --signature-key when using --verify-signature")
+	})
+
+	t.Run("verifies with the public key of a key in a KMS", func(t *testing.T) {
+		pull := PullOptions{
+			ui:                 confUI,
+			BundleFlags:        BundleFlags{Bundle: unsignedBundle.RefDigest},
+			OutputPath:         t.TempDir(),
+			ImageIsBundleCheck: true,
+			TrustFlags:         TrustFlags{VerifySignature: true, SignatureKeys: []string{fakekms.ReferenceScheme + "some-key"}},
+		}
+		err := pull.RunWithContext(context.WithValue(context.Background(), fakekms.KmsCtxKey{}, key))
+		require.ErrorContains(t, err, "Verifying signature of '"+unsignedBundle.RefDigest+"': Image is not signed")
+	})
+
+	t.Run("fails when there is no provider for the KMS of the key", func(t *testing.T) {
+		err := pullWithTrustFlags(TrustFlags{VerifySignature: true, SignatureKeys: []string{"unknownkms://some-key"}})
+		require.ErrorContains(t, err, "Loading key 'unknownkms://some-key' from KMS: no kms provider found")
+	})
+
+	t.Run("fails when a key is provided without --verify-signature", func(t *testing.T) {
+		err := pullWithTrustFlags(TrustFlags{SignatureKeys: []string{keyPath}})
+		require.EqualError(t, err, "Flag --signature-key can only be used with --verify-signature")
+	})
+}
+
+func TestPullExpectedTagDigest(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	bundleInfo := fakeRegistry.WithBundleFromPath("some/bundle", "test_assets/bundle").
+		WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+	fakeRegistry.Tag(bundleInfo.RefDigest, "v1")
+	image := fakeRegistry.WithRandomImage("some/image")
+	fakeRegistry.Build()
+
+	confUI := ui.NewConfUI(ui.NewNoopLogger())
+	defer confUI.Flush()
+
+	bundleTagRef := fakeRegistry.ReferenceOnTestServer("some/bundle:v1")
+
+	t.Run("pulls the bundle and records its digest and tag in the lock output", func(t *testing.T) {
+		lockPath := filepath.Join(t.TempDir(), "bundle.lock.yml")
+		pull := PullOptions{
+			ui:                 confUI,
+			BundleFlags:        BundleFlags{Bundle: bundleTagRef},
+			OutputPath:         t.TempDir(),
+			ImageIsBundleCheck: true,
+			ExpectedTagDigest:  bundleInfo.Digest,
+			LockOutputFlags:    LockOutputFlags{LockFilePath: lockPath},
+		}
+		require.NoError(t, pull.Run())
+
+		bundleLock, err := lockconfig.NewBundleLockFromPath(lockPath)
+		require.NoError(t, err)
+		require.Equal(t, bundleInfo.RefDigest, bundleLock.Bundle.Image)
+		require.Equal(t, "v1", bundleLock.Bundle.Tag)
+	})
+
+	t.Run("records the digest of the pulled image in the lock output", func(t *testing.T) {
+		lockPath := filepath.Join(t.TempDir(), "images.lock.yml")
+		pull := PullOptions{
+			ui:                 confUI,
+			ImageFlags:         ImageFlags{Image: fakeRegistry.ReferenceOnTestServer("some/image")},
+			OutputPath:         t.TempDir(),
+			ImageIsBundleCheck: true,
+			LockOutputFlags:    LockOutputFlags{LockFilePath: lockPath},
+		}
+		require.NoError(t, pull.Run())
+
+		imagesLock, err := lockconfig.NewImagesLockFromPath(lockPath)
+		require.NoError(t, err)
+		require.Len(t, imagesLock.Images, 1)
+		require.Equal(t, image.RefDigest, imagesLock.Images[0].Image)
+	})
+
+	t.Run("fails when the tag references a different digest", func(t *testing.T) {
+		pull := PullOptions{
+			ui:                 confUI,
+			BundleFlags:        BundleFlags{Bundle: bundleTagRef},
+			OutputPath:         t.TempDir(),
+			ImageIsBundleCheck: true,
+			ExpectedTagDigest:  image.Digest,
+		}
+		err := pull.Run()
+		require.EqualError(t, err, "Expected tag '"+bundleTagRef+"' to reference digest '"+image.Digest+"' but it references '"+bundleInfo.Digest+"'")
+	})
+
+	t.Run("fails when the bundle is referenced by digest", func(t *testing.T) {
+		pull := PullOptions{
+			ui:                 confUI,
+			BundleFlags:        BundleFlags{Bundle: bundleInfo.RefDigest},
+			OutputPath:         t.TempDir(),
+			ImageIsBundleCheck: true,
+			ExpectedTagDigest:  bundleInfo.Digest,
+		}
+		require.ErrorContains(t, pull.Run(), "Expected a tag reference when using --expected-tag-digest")
+	})
+
+	t.Run("fails when the expected digest is not a digest", func(t *testing.T) {
+		pull := PullOptions{
+			ui:                 confUI,
+			BundleFlags:        BundleFlags{Bundle: bundleTagRef},
+			OutputPath:         t.TempDir(),
+			ImageIsBundleCheck: true,
+			ExpectedTagDigest:  "v1",
+		}
+		require.ErrorContains(t, pull.Run(), "Parsing --expected-tag-digest")
+	})
+}
+
+func TestPullToOCI(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	bundleInfo := fakeRegistry.WithBundleFromPath("some/bundle", "test_assets/bundle").
+		WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+	image := fakeRegistry.WithRandomImage("some/image")
+	fakeRegistry.Build()
+
+	confUI := ui.NewConfUI(ui.NewNoopLogger())
+	defer confUI.Flush()
+
+	manifestDigests := func(t *testing.T, ociPath string) []string {
+		index, err := layout.ImageIndexFromPath(ociPath)
+		require.NoError(t, err)
+		indexManifest, err := index.IndexManifest()
+		require.NoError(t, err)
+		var digests []string
+		for _, desc := range indexManifest.Manifests {
+			digests = append(digests, desc.Digest.String())
+		}
+		return digests
+	}
+
+	t.Run("writes only the bundle image to the OCI image layout", func(t *testing.T) {
+		ociPath := t.TempDir()
+		pull := PullOptions{
+			ui:                 confUI,
+			BundleFlags:        BundleFlags{Bundle: bundleInfo.RefDigest},
+			ToOCIPath:          ociPath,
+			ImageIsBundleCheck: true,
+		}
+		require.NoError(t, pull.Run())
+
+		require.Equal(t, []string{bundleInfo.Digest}, manifestDigests(t, ociPath))
+		require.NoDirExists(t, filepath.Join(ociPath, ".imgpkg"))
+	})
+
+	t.Run("writes the bundle and its images to the OCI image layout when recursive", func(t *testing.T) {
+		ociPath := t.TempDir()
+		pull := PullOptions{
+			ui:                   confUI,
+			BundleFlags:          BundleFlags{Bundle: bundleInfo.RefDigest},
+			ToOCIPath:            ociPath,
+			ImageIsBundleCheck:   true,
+			BundleRecursiveFlags: BundleRecursiveFlags{Recursive: true},
+		}
+		require.NoError(t, pull.Run())
+
+		digests := manifestDigests(t, ociPath)
+		require.Contains(t, digests, bundleInfo.Digest)
+		require.Greater(t, len(digests), 1)
+	})
+
+	t.Run("writes an image to the OCI image layout", func(t *testing.T) {
+		ociPath := t.TempDir()
+		pull := PullOptions{
+			ui:                 confUI,
+			ImageFlags:         ImageFlags{Image: image.RefDigest},
+			ToOCIPath:          ociPath,
+			ImageIsBundleCheck: true,
+		}
+		require.NoError(t, pull.Run())
+
+		require.Equal(t, []string{image.Digest}, manifestDigests(t, ociPath))
+	})
+
+	t.Run("fails when pulling a bundle with -i", func(t *testing.T) {
+		pull := PullOptions{
+			ui:                 confUI,
+			ImageFlags:         ImageFlags{Image: bundleInfo.RefDigest},
+			ToOCIPath:          t.TempDir(),
+			ImageIsBundleCheck: true,
+		}
+		require.ErrorContains(t, pull.Run(), "Expected bundle flag when pulling a bundle")
+	})
+
+	t.Run("fails when pulling an image with -b", func(t *testing.T) {
+		pull := PullOptions{
+			ui:                 confUI,
+			BundleFlags:        BundleFlags{Bundle: image.RefDigest},
+			ToOCIPath:          t.TempDir(),
+			ImageIsBundleCheck: true,
+		}
+		require.ErrorContains(t, pull.Run(), "Expected bundle image but found plain image")
+	})
+
+	t.Run("fails when --output is also provided", func(t *testing.T) {
+		pull := PullOptions{
+			ui:          confUI,
+			BundleFlags: BundleFlags{Bundle: bundleInfo.RefDigest},
+			ToOCIPath:   t.TempDir(),
+			OutputPath:  t.TempDir(),
+		}
+		require.EqualError(t, pull.Run(), "Expected only one of --output (-o) or --to-oci")
+	})
+
+	t.Run("fails when --config-only is also provided", func(t *testing.T) {
+		pull := PullOptions{
+			ui:          confUI,
+			BundleFlags: BundleFlags{Bundle: bundleInfo.RefDigest},
+			ToOCIPath:   t.TempDir(),
+			ConfigOnly:  true,
+		}
+		require.ErrorContains(t, pull.Run(), "Cannot use --config-only flag with --to-oci")
+	})
+}
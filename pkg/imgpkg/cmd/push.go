@@ -4,16 +4,21 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/google/go-containerregistry/pkg/compression"
 	regname "github.com/google/go-containerregistry/pkg/name"
 	"github.com/spf13/cobra"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	ctlimg "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/image"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
-	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/plainimage"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
 )
 
 type PushOptions struct {
@@ -24,6 +29,29 @@ type PushOptions struct {
 	LockOutputFlags LockOutputFlags
 	FileFlags       FileFlags
 	RegistryFlags   RegistryFlags
+	OutputTypeFlags OutputTypeFlags
+	NotifyFlags     NotifyFlags
+
+	Platforms         []string
+	FormatVersion     int
+	ExternalArtifacts []string
+
+	ContentsManifest          bool
+	ContentsManifestAlgorithm string
+
+	SBOMFormat string
+
+	Compression      string
+	CompressionLevel int
+
+	PushAsArtifact bool
+}
+
+// pushResult the result of the push command when the output type is json, also sent in the notification
+type pushResult struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
+	Tag    string `json:"tag"`
 }
 
 func NewPushOptions(ui ui.UI) *PushOptions {
@@ -34,28 +62,113 @@ func NewPushCmd(o *PushOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "push",
 		Short: "Push files as image",
-		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
+		RunE:  func(cmd *cobra.Command, _ []string) error { return o.RunWithContext(cmd.Context()) },
 		Example: `
   # Push bundle repo/app1-config with contents of config/ directory
   imgpkg push -b repo/app1-config -f config/
 
   # Push image repo/app1-config with contents from multiple locations
-  imgpkg push -i repo/app1-config -f config/ -f additional-config.yml`,
+  imgpkg push -i repo/app1-config -f config/ -f additional-config.yml
+
+  # Push bundle repo/app1-config with its layer compressed with zstd
+  imgpkg push -b repo/app1-config -f config/ --compression zstd
+
+  # Push bundle repo/app1-config with a manifest whose artifactType identifies it as a bundle
+  imgpkg push -b repo/app1-config -f config/ --push-as-artifact
+
+  # Push bundle repo/app1-config and post the outcome to a chat webhook, using a template for the payload
+  imgpkg push -b repo/app1-config -f config/ --notify-url https://hooks.example.com/imgpkg --notify-template slack.tmpl
+
+  # Push bundle repo/app1-config and attach an SPDX SBOM of its files and images to it
+  imgpkg push -b repo/app1-config -f config/ --sbom spdx`,
 	}
 	o.ImageFlags.Set(cmd)
 	o.BundleFlags.Set(cmd)
 	o.LockOutputFlags.SetOnPush(cmd)
 	o.FileFlags.Set(cmd)
 	o.RegistryFlags.Set(cmd)
+	o.OutputTypeFlags.Set(cmd)
+	o.NotifyFlags.Set(cmd)
+	cmd.Flags().StringSliceVar(&o.Platforms, "platform-annotation", nil,
+		"Record the platforms the bundle content is intended for, shown by describe (format: os/arch[/variant] or any) (can be specified multiple times)")
+	cmd.Flags().IntVar(&o.FormatVersion, "format-version", 0,
+		"Bundle format version to write, older versions of imgpkg refuse to pull bundles with newer format versions (default: oldest version that supports the bundle contents)")
+	cmd.Flags().StringSliceVar(&o.ExternalArtifacts, "external-artifact", nil,
+		"Keep the file out of the bundle and reference it by digest instead, it is downloaded from the url when the bundle is pulled; "+
+			"the file has to be uploaded to the url separately (format: path/in/bundle=https://url) (can be specified multiple times)")
+	cmd.Flags().BoolVar(&o.ContentsManifest, "contents-manifest", false,
+		"Record the hash of each file of the bundle, pull verifies the extracted files against them")
+	cmd.Flags().StringVar(&o.ContentsManifestAlgorithm, "contents-manifest-algorithm", bundle.DefaultContentsManifestAlgorithm,
+		fmt.Sprintf("Hash algorithm used by --contents-manifest (%s)", strings.Join(bundle.ContentsManifestAlgorithms(), ", ")))
+	cmd.Flags().StringVar(&o.SBOMFormat, "sbom", "",
+		fmt.Sprintf("Generate an SBOM of the files of the bundle and the images in its ImagesLock, and attach it to the bundle as an OCI artifact (%s)", strings.Join(bundle.SBOMFormats(), ", ")))
+	cmd.Flags().StringVar(&o.Compression, "compression", string(compression.GZip),
+		fmt.Sprintf("Compression of the layer created from the files (%s), zstd and none require registries and runtimes that support OCI zstd or uncompressed layers", compressionNames()))
+	cmd.Flags().IntVar(&o.CompressionLevel, "compression-level", 0,
+		"Level used to compress the layer, from 1 (fastest) to 9 for gzip and to 22 for zstd (default: the default level of the compression)")
+	cmd.Flags().BoolVar(&o.PushAsArtifact, "push-as-artifact", false,
+		"Push the bundle with an OCI image manifest whose artifactType is "+bundle.BundleArtifactType+", so that registries and scanners can identify it as a bundle without retrieving its config")
 	return cmd
 }
 
+// Run Executes the push command, notifying --notify-url of its outcome
 func (po *PushOptions) Run() error {
-	reg, err := registry.NewSimpleRegistry(po.RegistryFlags.AsRegistryOpts())
+	return po.RunWithContext(context.Background())
+}
+
+// RunWithContext Same as Run, the uploads to the registry are cancelled when ctx is done
+func (po *PushOptions) RunWithContext(ctx context.Context) error {
+	err := po.NotifyFlags.Validate()
 	if err != nil {
 		return err
 	}
 
+	notification := newNotification("push", time.Now())
+	notification.Source = strings.Join(po.FileFlags.Files, ",")
+	notification.Destination = po.BundleFlags.Bundle + po.ImageFlags.Image
+
+	result, err := po.run(ctx)
+	if err == nil {
+		notification.Report = result
+	}
+	po.NotifyFlags.Notify(util.NewUILevelLogger(util.LogWarn, util.NewLogger(po.OutputTypeFlags.LogsUI(po.ui))), notification, err)
+	return err
+}
+
+func (po *PushOptions) run(ctx context.Context) (pushResult, error) {
+	err := po.OutputTypeFlags.Validate()
+	if err != nil {
+		return pushResult{}, err
+	}
+	err = po.validatePlatforms()
+	if err != nil {
+		return pushResult{}, err
+	}
+	if po.FormatVersion != 0 {
+		err = bundle.ValidateFormatVersion(po.FormatVersion)
+		if err != nil {
+			return pushResult{}, err
+		}
+	}
+	err = po.layerCompression().Validate()
+	if err != nil {
+		return pushResult{}, err
+	}
+
+	destination := po.BundleFlags.Bundle
+	if destination == "" {
+		destination = po.ImageFlags.Image
+	}
+	registryOpts, err := po.RegistryFlags.AsRegistryOptsWritingTo(destination)
+	if err != nil {
+		return pushResult{}, err
+	}
+
+	reg, err := registry.NewSimpleRegistryWithContext(ctx, registryOpts)
+	if err != nil {
+		return pushResult{}, err
+	}
+
 	var imageURL string
 
 	isBundle := po.BundleFlags.Bundle != ""
@@ -63,43 +176,107 @@ func (po *PushOptions) Run() error {
 
 	switch {
 	case isBundle && isImage:
-		return fmt.Errorf("Expected only one of image or bundle")
+		return pushResult{}, fmt.Errorf("Expected only one of image or bundle")
 
 	case !isBundle && !isImage:
-		return fmt.Errorf("Expected either image or bundle")
+		return pushResult{}, fmt.Errorf("Expected either image or bundle")
 
 	case isBundle:
 		imageURL, err = po.pushBundle(reg)
 		if err != nil {
-			return err
+			return pushResult{}, err
 		}
 
 	case isImage:
 		imageURL, err = po.pushImage(reg)
 		if err != nil {
-			return err
+			return pushResult{}, err
 		}
 
 	default:
 		panic("Unreachable code")
 	}
 
-	po.ui.BeginLinef("Pushed '%s'", imageURL)
+	result, err := po.newPushResult(imageURL)
+	if err != nil {
+		return pushResult{}, err
+	}
+
+	if po.OutputTypeFlags.IsJSON() {
+		return result, po.OutputTypeFlags.WriteJSON(po.ui, result)
+	}
+
+	printResult(po.ui, imageURL, "Pushed '%s'", imageURL)
+
+	return result, nil
+}
 
+func (po *PushOptions) validatePlatforms() error {
+	for _, platform := range po.Platforms {
+		if platform == "any" {
+			continue
+		}
+		parts := strings.Split(platform, "/")
+		if len(parts) < 2 || len(parts) > 3 {
+			return fmt.Errorf("Invalid platform annotation '%s' (expected format: os/arch[/variant] or any)", platform)
+		}
+		for _, part := range parts {
+			if part == "" {
+				return fmt.Errorf("Invalid platform annotation '%s' (expected format: os/arch[/variant] or any)", platform)
+			}
+		}
+	}
 	return nil
 }
 
+// newPushResult Returns the location, digest and tag of the pushed image
+func (po *PushOptions) newPushResult(imageURL string) (pushResult, error) {
+	digestRef, err := regname.NewDigest(imageURL)
+	if err != nil {
+		return pushResult{}, fmt.Errorf("Parsing '%s': %s", imageURL, err)
+	}
+
+	ref := po.BundleFlags.Bundle
+	if ref == "" {
+		ref = po.ImageFlags.Image
+	}
+	uploadRef, err := regname.NewTag(ref, regname.WeakValidation)
+	if err != nil {
+		return pushResult{}, fmt.Errorf("Parsing '%s': %s", ref, err)
+	}
+
+	return pushResult{
+		Image:  imageURL,
+		Digest: digestRef.DigestStr(),
+		Tag:    uploadRef.TagStr(),
+	}, nil
+}
+
 func (po *PushOptions) pushBundle(registry registry.Registry) (string, error) {
 	uploadRef, err := regname.NewTag(po.BundleFlags.Bundle, regname.WeakValidation)
 	if err != nil {
 		return "", fmt.Errorf("Parsing '%s': %s", po.BundleFlags.Bundle, err)
 	}
 
-	logger := util.NewUILevelLogger(util.LogWarn, util.NewLogger(po.ui))
-	imageURL, err := bundle.NewContents(po.FileFlags.Files, po.FileFlags.ExcludedFilePaths, po.FileFlags.PreservePermissions).Push(uploadRef, registry, logger)
+	var externalArtifacts []bundle.ExternalArtifactSource
+	for _, value := range po.ExternalArtifacts {
+		artifact, err := bundle.NewExternalArtifactSource(value)
+		if err != nil {
+			return "", err
+		}
+		externalArtifacts = append(externalArtifacts, artifact)
+	}
+
+	opts := po.v1PushOpts(true)
+	opts.ExternalArtifacts = externalArtifacts
+	if po.ContentsManifest {
+		opts.ContentsManifestAlgorithm = po.ContentsManifestAlgorithm
+	}
+	status, err := v1.PushWithRegistry(po.BundleFlags.Bundle, po.FileFlags.Files, opts, registry)
 	if err != nil {
 		return "", err
 	}
+	imageURL := status.ImageRef
 
 	if po.LockOutputFlags.LockFilePath != "" {
 		bundleLock := lockconfig.BundleLock{
@@ -126,10 +303,23 @@ func (po *PushOptions) pushImage(registry registry.Registry) (string, error) {
 	if po.LockOutputFlags.LockFilePath != "" {
 		return "", fmt.Errorf("Lock output is not compatible with image, use bundle for lock output")
 	}
-
-	uploadRef, err := regname.NewTag(po.ImageFlags.Image, regname.WeakValidation)
-	if err != nil {
-		return "", fmt.Errorf("Parsing '%s': %s", po.ImageFlags.Image, err)
+	if len(po.Platforms) > 0 {
+		return "", fmt.Errorf("Platform annotations can only be recorded in bundles, use --bundle (-b) option")
+	}
+	if po.FormatVersion != 0 {
+		return "", fmt.Errorf("Format version can only be recorded in bundles, use --bundle (-b) option")
+	}
+	if len(po.ExternalArtifacts) > 0 {
+		return "", fmt.Errorf("External artifacts can only be referenced by bundles, use --bundle (-b) option")
+	}
+	if po.ContentsManifest {
+		return "", fmt.Errorf("Contents manifest can only be recorded in bundles, use --bundle (-b) option")
+	}
+	if po.SBOMFormat != "" {
+		return "", fmt.Errorf("SBOMs can only be generated for bundles, use --bundle (-b) option")
+	}
+	if po.PushAsArtifact {
+		return "", fmt.Errorf("Only bundles can be pushed as artifacts, use --bundle (-b) option")
 	}
 
 	isBundle, err := bundle.NewContents(po.FileFlags.Files, po.FileFlags.ExcludedFilePaths, po.FileFlags.PreservePermissions).PresentsAsBundle()
@@ -140,6 +330,39 @@ func (po *PushOptions) pushImage(registry registry.Registry) (string, error) {
 		return "", fmt.Errorf("Images cannot be pushed with '.imgpkg' directories, consider using --bundle (-b) option")
 	}
 
-	logger := util.NewUILevelLogger(util.LogWarn, util.NewLogger(po.ui))
-	return plainimage.NewContents(po.FileFlags.Files, po.FileFlags.ExcludedFilePaths, po.FileFlags.PreservePermissions).Push(uploadRef, nil, registry, logger)
+	status, err := v1.PushWithRegistry(po.ImageFlags.Image, po.FileFlags.Files, po.v1PushOpts(false), registry)
+	if err != nil {
+		return "", err
+	}
+	return status.ImageRef, nil
+}
+
+// v1PushOpts Returns the options of the v1 API push shared by bundles and images
+func (po *PushOptions) v1PushOpts(isBundle bool) v1.PushOpts {
+	opts := v1.PushOpts{
+		Logger:              util.NewUILevelLogger(util.LogWarn, util.NewLogger(po.OutputTypeFlags.LogsUI(po.ui))),
+		IsBundle:            isBundle,
+		ExcludedPaths:       po.FileFlags.ExcludedFilePaths,
+		PreservePermissions: po.FileFlags.PreservePermissions,
+		Compression:         po.layerCompression(),
+	}
+	if isBundle {
+		opts.Platforms = po.Platforms
+		opts.FormatVersion = po.FormatVersion
+		opts.SBOMFormat = po.SBOMFormat
+		opts.AsArtifact = po.PushAsArtifact
+	}
+	return opts
+}
+
+func (po *PushOptions) layerCompression() ctlimg.LayerCompression {
+	return ctlimg.LayerCompression{Algorithm: compression.Compression(po.Compression), Level: po.CompressionLevel}
+}
+
+func compressionNames() string {
+	var names []string
+	for _, algorithm := range ctlimg.Compressions {
+		names = append(names, string(algorithm))
+	}
+	return strings.Join(names, ", ")
 }
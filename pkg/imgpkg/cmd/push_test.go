@@ -235,6 +235,18 @@ func TestImageAndBundleLockError(t *testing.T) {
 	}
 }
 
+func TestInvalidOutputTypeError(t *testing.T) {
+	push := PushOptions{ImageFlags: ImageFlags{"my-image"}, OutputTypeFlags: OutputTypeFlags{OutputType: "yaml"}}
+	err := push.Run()
+	if err == nil {
+		t.Fatalf("Expected validations to err, but did not")
+	}
+
+	if !strings.Contains(err.Error(), "--output-type can only have the following values [text, json]") {
+		t.Fatalf("Expected error to contain message about invalid flags, got: %s", err)
+	}
+}
+
 func Cleanup(dirs ...string) {
 	for _, dir := range dirs {
 		os.RemoveAll(dir)
@@ -258,3 +270,107 @@ func createEmptyBundleDir(loc string) error {
 	bundleDir := filepath.Join(loc, ".imgpkg")
 	return os.Mkdir(bundleDir, 0700)
 }
+
+func TestImageAndFormatVersionError(t *testing.T) {
+	push := PushOptions{ImageFlags: ImageFlags{"image@123456"}, FormatVersion: 1}
+	err := push.Run()
+	if err == nil {
+		t.Fatalf("Expected validations to err, but did not")
+	}
+
+	if !strings.Contains(err.Error(), "Format version can only be recorded in bundles") {
+		t.Fatalf("Expected error to contain message about invalid flags, got: %s", err)
+	}
+}
+
+func TestUnsupportedFormatVersionError(t *testing.T) {
+	push := PushOptions{BundleFlags: BundleFlags{"my-bundle"}, FormatVersion: 99}
+	err := push.Run()
+	if err == nil {
+		t.Fatalf("Expected validations to err, but did not")
+	}
+
+	if !strings.Contains(err.Error(), "Unsupported bundle format version 99") {
+		t.Fatalf("Expected error to contain message about the format version, got: %s", err)
+	}
+}
+
+func TestInvalidExternalArtifactError(t *testing.T) {
+	testCases := map[string]string{
+		"missing url":       "data/dataset.bin",
+		"not an http url":   "data/dataset.bin=s3://bucket/dataset.bin",
+		"outside of bundle": "../dataset.bin=https://some.storage/dataset.bin",
+		"in .imgpkg":        ".imgpkg/images.yml=https://some.storage/images.yml",
+	}
+	for name, value := range testCases {
+		t.Run(name, func(t *testing.T) {
+			push := PushOptions{BundleFlags: BundleFlags{"my-bundle"}, ExternalArtifacts: []string{value}}
+			err := push.Run()
+			if err == nil {
+				t.Fatalf("Expected validations to err, but did not")
+			}
+
+			if !strings.Contains(err.Error(), fmt.Sprintf("Invalid external artifact '%s'", value)) {
+				t.Fatalf("Expected error to contain message about the external artifact, got: %s", err)
+			}
+		})
+	}
+}
+
+func TestImageAndExternalArtifactError(t *testing.T) {
+	push := PushOptions{ImageFlags: ImageFlags{"image@123456"}, ExternalArtifacts: []string{"data.bin=https://some.storage/data.bin"}}
+	err := push.Run()
+	if err == nil {
+		t.Fatalf("Expected validations to err, but did not")
+	}
+
+	if !strings.Contains(err.Error(), "External artifacts can only be referenced by bundles") {
+		t.Fatalf("Expected error to contain message about invalid flags, got: %s", err)
+	}
+}
+
+func TestImageAndContentsManifestError(t *testing.T) {
+	push := PushOptions{ImageFlags: ImageFlags{"image@123456"}, ContentsManifest: true}
+	err := push.Run()
+	if err == nil {
+		t.Fatalf("Expected validations to err, but did not")
+	}
+
+	if !strings.Contains(err.Error(), "Contents manifest can only be recorded in bundles") {
+		t.Fatalf("Expected error to contain message about invalid flags, got: %s", err)
+	}
+}
+
+func TestImageAndSBOMError(t *testing.T) {
+	push := PushOptions{ImageFlags: ImageFlags{"image@123456"}, SBOMFormat: "spdx"}
+	err := push.Run()
+	if err == nil {
+		t.Fatalf("Expected validations to err, but did not")
+	}
+
+	if !strings.Contains(err.Error(), "SBOMs can only be generated for bundles") {
+		t.Fatalf("Expected error to contain message about invalid flags, got: %s", err)
+	}
+}
+
+func TestInvalidCompressionError(t *testing.T) {
+	testCases := map[string]struct {
+		compression string
+		level       int
+		expectedErr string
+	}{
+		"unknown compression":       {compression: "lz4", expectedErr: "Unknown compression 'lz4' (known: gzip, zstd, none)"},
+		"gzip level out of range":   {compression: "gzip", level: 10, expectedErr: "Expected gzip compression level to be between 1 and 9, but got 10"},
+		"zstd level out of range":   {compression: "zstd", level: 23, expectedErr: "Expected zstd compression level to be between 1 and 22, but got 23"},
+		"level without compression": {compression: "none", level: 1, expectedErr: "Expected no compression level when layers are not compressed"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			push := PushOptions{BundleFlags: BundleFlags{"my-bundle"}, Compression: tc.compression, CompressionLevel: tc.level}
+			err := push.Run()
+			if err == nil || err.Error() != tc.expectedErr {
+				t.Fatalf("Expected error '%s', got: %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
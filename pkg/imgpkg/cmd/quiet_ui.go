@@ -0,0 +1,71 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	goui "github.com/cppforlife/go-cli-ui/ui"
+)
+
+// QuietUI UI that, when quiet mode is enabled, only writes errors and the result of the command
+type QuietUI struct {
+	goui.UI
+	quiet bool
+}
+
+var _ goui.UI = &QuietUI{}
+
+// NewQuietUI constructor for QuietUI, by default all the output is written
+func NewQuietUI(parent goui.UI) *QuietUI {
+	return &QuietUI{UI: parent}
+}
+
+// EnableQuiet stop writing progress and informational output
+func (q *QuietUI) EnableQuiet() { q.quiet = true }
+
+// IsQuiet returns true when quiet mode is enabled
+func (q *QuietUI) IsQuiet() bool { return q.quiet }
+
+// PrintLinef writes the line when quiet mode is disabled
+func (q *QuietUI) PrintLinef(pattern string, args ...interface{}) {
+	if !q.quiet {
+		q.UI.PrintLinef(pattern, args...)
+	}
+}
+
+// BeginLinef writes the line when quiet mode is disabled
+func (q *QuietUI) BeginLinef(pattern string, args ...interface{}) {
+	if !q.quiet {
+		q.UI.BeginLinef(pattern, args...)
+	}
+}
+
+// EndLinef writes the line when quiet mode is disabled
+func (q *QuietUI) EndLinef(pattern string, args ...interface{}) {
+	if !q.quiet {
+		q.UI.EndLinef(pattern, args...)
+	}
+}
+
+// isQuiet checks if the ui was configured to only write errors and results
+func isQuiet(ui goui.UI) bool {
+	quietUI, ok := ui.(*QuietUI)
+	return ok && quietUI.IsQuiet()
+}
+
+// printResult writes the final result of a command.
+// In quiet mode only the result is written, so that it can be consumed by other tools
+func printResult(ui goui.UI, result string, pattern string, args ...interface{}) {
+	if isQuiet(ui) {
+		writeResult(ui, result)
+		return
+	}
+	ui.BeginLinef(pattern, args...)
+}
+
+// writeResult writes the result of a command in a way that is not suppressed by quiet mode or by the lack of a TTY
+func writeResult(ui goui.UI, result string) {
+	ui.PrintBlock([]byte(fmt.Sprintf("%s\n", result)))
+}
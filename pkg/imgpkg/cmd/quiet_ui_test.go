@@ -0,0 +1,42 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuietUI(t *testing.T) {
+	t.Run("when quiet is not enabled it writes all output", func(t *testing.T) {
+		stdout := bytes.NewBufferString("")
+		stderr := bytes.NewBufferString("")
+		subject := NewQuietUI(ui.NewWriterUI(stdout, stderr, ui.NewNoopLogger()))
+
+		subject.BeginLinef("some progress\n")
+		printResult(subject, "repo@sha256:123", "Pushed '%s'\n", "repo@sha256:123")
+		subject.ErrorLinef("some error")
+
+		require.Equal(t, "some progress\nPushed 'repo@sha256:123'\n", stdout.String())
+		require.Equal(t, "some error\n", stderr.String())
+	})
+
+	t.Run("when quiet is enabled it only writes errors and the result", func(t *testing.T) {
+		stdout := bytes.NewBufferString("")
+		stderr := bytes.NewBufferString("")
+		subject := NewQuietUI(ui.NewWriterUI(stdout, stderr, ui.NewNoopLogger()))
+		subject.EnableQuiet()
+
+		subject.BeginLinef("some progress\n")
+		subject.PrintLinef("some information")
+		printResult(subject, "repo@sha256:123", "Pushed '%s'\n", "repo@sha256:123")
+		subject.ErrorLinef("some error")
+
+		require.Equal(t, "repo@sha256:123\n", stdout.String())
+		require.Equal(t, "some error\n", stderr.String())
+	})
+}
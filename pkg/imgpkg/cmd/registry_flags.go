@@ -4,11 +4,16 @@
 package cmd
 
 import (
+	"fmt"
+	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	regname "github.com/google/go-containerregistry/pkg/name"
 	"github.com/spf13/cobra"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry/auth"
 	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
 )
 
@@ -23,10 +28,27 @@ type RegistryFlags struct {
 	Token    string
 	Anon     bool
 
-	RetryCount int
+	RetryCount   int
+	RetryBackoff time.Duration
 
 	ResponseHeaderTimeout time.Duration
-	ActiveKeychains       string
+	ActiveKeychains       []string
+
+	UseDockerCredentialHelpers bool
+
+	DisableHTTP2        bool
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	KeepAlive           time.Duration
+	UploadChunkSize     int64
+	MaxRequestsPerHost  int
+
+	UserAgentSuffix string
+	Headers         map[string]string
+
+	CacheDir string
+
+	AssertSourceReadOnly bool
 }
 
 // Set Registers the flags available to the provided command
@@ -39,9 +61,29 @@ func (r *RegistryFlags) Set(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&r.Password, "registry-password", "", "Set password for auth ($IMGPKG_PASSWORD)")
 	cmd.Flags().StringVar(&r.Token, "registry-token", "", "Set token for auth ($IMGPKG_TOKEN)")
 	cmd.Flags().BoolVar(&r.Anon, "registry-anon", false, "Set anonymous auth ($IMGPKG_ANON)")
+	cmd.Flags().StringSliceVar(&r.ActiveKeychains, "activate-keychain", nil,
+		"Activate a keychain that retrieves credentials from a cloud provider (aks or acr, ecr, gke or gcr, github) ($IMGPKG_ACTIVE_KEYCHAINS) (can be specified multiple times). The aks, ecr and gke keychains are also activated when $AZURE_*, $AWS_* or $GOOGLE_APPLICATION_CREDENTIALS credentials are found in the environment")
+	cmd.Flags().BoolVar(&r.UseDockerCredentialHelpers, "registry-use-docker-credential-helpers", true,
+		"Retrieve credentials with the docker-credential-* helpers configured in credsStore and credHelpers of the docker config file, set to false to only use the credentials stored in the file ($IMGPKG_REGISTRY_USE_DOCKER_CREDENTIAL_HELPERS)")
 
 	cmd.Flags().DurationVar(&r.ResponseHeaderTimeout, "registry-response-header-timeout", 30*time.Second, "Maximum time to allow a request to wait for a server's response headers from the registry (ms|s|m|h)")
 	cmd.Flags().IntVar(&r.RetryCount, "registry-retry-count", 5, "Set the number of times imgpkg retries to send requests to the registry in case of an error")
+	cmd.Flags().DurationVar(&r.RetryBackoff, "registry-retry-backoff", registry.DefaultRetryBackoff,
+		"Time to wait before retrying a request that failed with a network error, 429 or 5xx response, doubled on each retry up to 1m. A Retry-After sent by the registry is waited instead (ms|s|m|h)")
+
+	cmd.Flags().BoolVar(&r.DisableHTTP2, "registry-disable-http2", true, "Only use HTTP/1.1 when interacting with registries, set to false to allow HTTP/2 to be negotiated")
+	cmd.Flags().IntVar(&r.MaxIdleConnsPerHost, "registry-max-idle-conns-per-host", http.DefaultMaxIdleConnsPerHost, "Maximum number of idle connections kept open to each registry for reuse")
+	cmd.Flags().DurationVar(&r.IdleConnTimeout, "registry-idle-conn-timeout", 90*time.Second, "Maximum time an idle connection to a registry is kept open for reuse (ms|s|m|h)")
+	cmd.Flags().DurationVar(&r.KeepAlive, "registry-keep-alive", 30*time.Second, "Interval between TCP keep-alive probes sent to registries, a negative value disables them (ms|s|m|h)")
+	cmd.Flags().Int64Var(&r.UploadChunkSize, "upload-chunk-size", 0, "Size in bytes of the chunks used to upload layers. When 0 the size is detected based on the registry responses, when negative layers are uploaded in a single request")
+	cmd.Flags().IntVar(&r.MaxRequestsPerHost, "max-requests-per-host", 0, "Maximum number of requests sent at the same time to each registry, independently of --concurrency. When 0 there is no limit")
+
+	cmd.Flags().StringVar(&r.UserAgentSuffix, "registry-user-agent-suffix", "", "Append suffix to the User-Agent sent to the registry ($IMGPKG_REGISTRY_USER_AGENT_SUFFIX)")
+	cmd.Flags().StringToStringVar(&r.Headers, "registry-header", nil, "Add static HTTP header to all registry requests (format: key=value) ($IMGPKG_REGISTRY_HEADERS) (can be specified multiple times)")
+
+	cmd.Flags().BoolVar(&r.AssertSourceReadOnly, "assert-source-read-only", false, "Refuse any request that would change the content of a repository other than the destination of the command")
+
+	cmd.Flags().StringVar(&r.CacheDir, "registry-cache-dir", "", "Cache manifests retrieved from registries in this folder to speed up subsequent executions, manifests are kept apart for each registry and credentials ($IMGPKG_REGISTRY_CACHE_DIR)")
 }
 
 // AsRegistryOpts convert command flags and environment variables into registry.Opts
@@ -57,10 +99,59 @@ func (r *RegistryFlags) AsRegistryOpts() registry.Opts {
 		Anon:     r.Anon,
 
 		RetryCount:            r.RetryCount,
+		RetryBackoff:          r.RetryBackoff,
 		ResponseHeaderTimeout: r.ResponseHeaderTimeout,
 
-		EnvironFunc: os.Environ,
+		EnableHTTP2:         !r.DisableHTTP2,
+		MaxIdleConnsPerHost: r.MaxIdleConnsPerHost,
+		IdleConnTimeout:     r.IdleConnTimeout,
+		KeepAlive:           r.KeepAlive,
+		UploadChunkSize:     r.UploadChunkSize,
+		MaxRequestsPerHost:  r.MaxRequestsPerHost,
+
+		Headers:  r.Headers,
+		CacheDir: r.CacheDir,
+		ReadOnly: r.AssertSourceReadOnly,
+
+		EnvironFunc:                    os.Environ,
+		DisableDockerCredentialHelpers: !r.UseDockerCredentialHelpers,
+	}
+	for _, keychain := range r.ActiveKeychains {
+		opts.ActiveKeychains = append(opts.ActiveKeychains, auth.IAASKeychain(strings.TrimSpace(keychain)))
+	}
+
+	opts = v1.OptsFromEnv(opts, os.LookupEnv)
+
+	userAgentSuffix := r.UserAgentSuffix
+	if userAgentSuffix == "" {
+		userAgentSuffix, _ = os.LookupEnv("IMGPKG_REGISTRY_USER_AGENT_SUFFIX")
+	}
+	opts.UserAgent = fmt.Sprintf("imgpkg/%s", Version)
+	if userAgentSuffix != "" {
+		opts.UserAgent = fmt.Sprintf("%s %s", opts.UserAgent, userAgentSuffix)
 	}
 
-	return v1.OptsFromEnv(opts, os.LookupEnv)
+	return opts
+}
+
+// AsRegistryOptsWritingTo convert the flags into registry.Opts that, when --assert-source-read-only is set, only allow
+// changes to the repository of the destination
+func (r *RegistryFlags) AsRegistryOptsWritingTo(destination string) (registry.Opts, error) {
+	opts := r.AsRegistryOpts()
+	if !opts.ReadOnly || destination == "" {
+		return opts, nil
+	}
+
+	var repo regname.Repository
+	ref, err := regname.ParseReference(destination, regname.WeakValidation)
+	if err == nil {
+		repo = ref.Context()
+	} else {
+		repo, err = regname.NewRepository(destination, regname.WeakValidation)
+		if err != nil {
+			return registry.Opts{}, fmt.Errorf("Parsing destination '%s': %s", destination, err)
+		}
+	}
+	opts.WritableRepositories = []string{repo.RegistryStr() + "/" + repo.RepositoryStr()}
+	return opts, nil
 }
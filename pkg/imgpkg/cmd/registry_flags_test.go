@@ -0,0 +1,54 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsRegistryOptsWritingTo(t *testing.T) {
+	t.Run("does not restrict the registries when --assert-source-read-only is not set", func(t *testing.T) {
+		opts, err := (&RegistryFlags{}).AsRegistryOptsWritingTo("registry.io/some/repo")
+		require.NoError(t, err)
+		assert.False(t, opts.ReadOnly)
+		assert.Empty(t, opts.WritableRepositories)
+	})
+
+	t.Run("only allows changes to the repository of the destination", func(t *testing.T) {
+		for _, destination := range []string{"registry.io:5000/some/repo", "registry.io:5000/some/repo:v1", "registry.io:5000/some/repo@sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"} {
+			opts, err := (&RegistryFlags{AssertSourceReadOnly: true}).AsRegistryOptsWritingTo(destination)
+			require.NoError(t, err)
+			assert.True(t, opts.ReadOnly)
+			assert.Equal(t, []string{"registry.io:5000/some/repo"}, opts.WritableRepositories)
+		}
+	})
+
+	t.Run("uses the repository path of Docker Hub images", func(t *testing.T) {
+		opts, err := (&RegistryFlags{AssertSourceReadOnly: true}).AsRegistryOptsWritingTo("ubuntu")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"index.docker.io/library/ubuntu"}, opts.WritableRepositories)
+	})
+
+	t.Run("does not allow changes to any registry when there is no destination", func(t *testing.T) {
+		opts, err := (&RegistryFlags{AssertSourceReadOnly: true}).AsRegistryOptsWritingTo("")
+		require.NoError(t, err)
+		assert.True(t, opts.ReadOnly)
+		assert.Empty(t, opts.WritableRepositories)
+	})
+}
+
+func TestRegistryFlagsUploadChunkSize(t *testing.T) {
+	t.Run("accepts --upload-chunk-size and its previous name --registry-upload-chunk-size", func(t *testing.T) {
+		for _, flag := range []string{"--upload-chunk-size", "--registry-upload-chunk-size"} {
+			copyCmd, _, err := NewDefaultImgpkgCmd(ui.NewConfUI(ui.NewNoopLogger())).Find([]string{"copy"})
+			require.NoError(t, err)
+			require.NoError(t, copyCmd.ParseFlags([]string{flag, "5242880"}))
+			assert.Equal(t, "5242880", copyCmd.Flags().Lookup("upload-chunk-size").Value.String(), flag)
+		}
+	})
+}
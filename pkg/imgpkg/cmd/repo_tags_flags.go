@@ -0,0 +1,78 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/spf13/cobra"
+)
+
+// TagsLister Interface to list the tags of a repository
+type TagsLister interface {
+	ListTags(repo regname.Repository) ([]string, error)
+}
+
+// RepoTagsFlags command line flags to select all the tags of a repository that match a glob
+type RepoTagsFlags struct {
+	RepoTags string
+}
+
+// Set Registers the flags available to the provided command
+func (r *RepoTagsFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&r.RepoTags, "repo-tags", "",
+		"Copy all the tags of a repository that match a glob, tags are preserved (example: docker.io/dkalinin/app1:v1.2.*)")
+}
+
+// IsSet returns true when a repository was provided
+func (r RepoTagsFlags) IsSet() bool { return r.RepoTags != "" }
+
+// ImageRefs Lists the tags of the repository and returns the references of the ones matching the glob, sorted by tag
+func (r RepoTagsFlags) ImageRefs(lister TagsLister) ([]string, error) {
+	repo, pattern, err := r.repositoryAndPattern()
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := lister.ListTags(repo)
+	if err != nil {
+		return nil, fmt.Errorf("Listing tags of %s: %s", repo.Name(), err)
+	}
+	sort.Strings(tags)
+
+	var refs []string
+	for _, tag := range tags {
+		// The pattern was validated so no error is possible
+		if matched, _ := path.Match(pattern, tag); matched {
+			refs = append(refs, repo.Tag(tag).Name())
+		}
+	}
+
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("Expected at least one tag in %s to match '%s'", repo.Name(), pattern)
+	}
+	return refs, nil
+}
+
+func (r RepoTagsFlags) repositoryAndPattern() (regname.Repository, string, error) {
+	idx := strings.LastIndex(r.RepoTags, ":")
+	if idx == -1 || idx < strings.LastIndex(r.RepoTags, "/") {
+		return regname.Repository{}, "", fmt.Errorf("Expected --repo-tags to have the format repository:glob (example: docker.io/dkalinin/app1:v1.2.*)")
+	}
+
+	repo, err := regname.NewRepository(r.RepoTags[:idx])
+	if err != nil {
+		return regname.Repository{}, "", fmt.Errorf("Parsing repository of --repo-tags: %s", err)
+	}
+
+	pattern := r.RepoTags[idx+1:]
+	if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+		return regname.Repository{}, "", fmt.Errorf("Invalid tag glob '%s' in --repo-tags", pattern)
+	}
+	return repo, pattern, nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTagsLister struct {
+	tags map[string][]string
+}
+
+func (f fakeTagsLister) ListTags(repo regname.Repository) ([]string, error) {
+	return f.tags[repo.Name()], nil
+}
+
+func TestRepoTagsFlags(t *testing.T) {
+	lister := fakeTagsLister{tags: map[string][]string{
+		"my.registry.io/app": {"v1.2.1", "latest", "v1.3.0", "v1.2.0", "v1.2.0-rc.1"},
+	}}
+
+	t.Run("returns the references of the tags that match the glob sorted by tag", func(t *testing.T) {
+		refs, err := RepoTagsFlags{RepoTags: "my.registry.io/app:v1.2.*"}.ImageRefs(lister)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"my.registry.io/app:v1.2.0",
+			"my.registry.io/app:v1.2.0-rc.1",
+			"my.registry.io/app:v1.2.1",
+		}, refs)
+	})
+
+	t.Run("supports registries with a port", func(t *testing.T) {
+		lister := fakeTagsLister{tags: map[string][]string{"localhost:5000/app": {"v1", "v2"}}}
+		refs, err := RepoTagsFlags{RepoTags: "localhost:5000/app:v[12]"}.ImageRefs(lister)
+		require.NoError(t, err)
+		require.Equal(t, []string{"localhost:5000/app:v1", "localhost:5000/app:v2"}, refs)
+	})
+
+	t.Run("fails when no tag matches", func(t *testing.T) {
+		_, err := RepoTagsFlags{RepoTags: "my.registry.io/app:v2.*"}.ImageRefs(lister)
+		require.ErrorContains(t, err, "Expected at least one tag in my.registry.io/app to match 'v2.*'")
+	})
+
+	t.Run("fails when the glob is missing", func(t *testing.T) {
+		_, err := RepoTagsFlags{RepoTags: "localhost:5000/app"}.ImageRefs(lister)
+		require.ErrorContains(t, err, "Expected --repo-tags to have the format repository:glob")
+	})
+
+	t.Run("fails when the glob is not valid", func(t *testing.T) {
+		_, err := RepoTagsFlags{RepoTags: "my.registry.io/app:v1.[2"}.ImageRefs(lister)
+		require.ErrorContains(t, err, "Invalid tag glob 'v1.[2' in --repo-tags")
+	})
+}
@@ -0,0 +1,44 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/spf13/cobra"
+)
+
+const requireDigestsFlagName = "require-digests"
+
+// RequireDigestsFlags command line flag that forbids referencing images and bundles by tag
+type RequireDigestsFlags struct {
+	RequireDigests bool
+}
+
+// Set Registers the flag in the imgpkg command, so that it is available to every command
+func (r *RequireDigestsFlags) Set(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&r.RequireDigests, requireDigestsFlagName, false,
+		"Fail when an image or bundle is referenced by tag instead of digest, only digest-pinned content is processed")
+}
+
+// SetFromCmd Reads the flag registered in the imgpkg command from the command being executed
+func (r *RequireDigestsFlags) SetFromCmd(cmd *cobra.Command) {
+	if requireDigests, err := cmd.Flags().GetBool(requireDigestsFlagName); err == nil {
+		r.RequireDigests = requireDigests
+	}
+}
+
+// ValidateRef Errors when digests are required and the reference, provided via the flag, points to a tag.
+// Lock files and the ImagesLock of bundles always reference images by digest
+func (r RequireDigestsFlags) ValidateRef(flag, ref string) error {
+	if !r.RequireDigests || ref == "" {
+		return nil
+	}
+	if _, err := regname.NewDigest(ref); err != nil {
+		return fmt.Errorf("Expected %s to reference a digest but got '%s' "+
+			"(hint: --require-digests only allows references in the format repo@sha256:...)", flag, ref)
+	}
+	return nil
+}
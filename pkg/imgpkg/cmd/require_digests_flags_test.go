@@ -0,0 +1,53 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireDigestsFlags(t *testing.T) {
+	const digestRef = "repo/app@sha256:669e010b58baf5beb2836b253c1fd5768333f0d1dbcb834f7c07a4dc93f474be"
+
+	t.Run("allows references by digest", func(t *testing.T) {
+		flags := RequireDigestsFlags{RequireDigests: true}
+		assert.NoError(t, flags.ValidateRef("--bundle (-b)", digestRef))
+		assert.NoError(t, flags.ValidateRef("--bundle (-b)", ""))
+	})
+
+	t.Run("errors on references by tag", func(t *testing.T) {
+		flags := RequireDigestsFlags{RequireDigests: true}
+		assert.EqualError(t, flags.ValidateRef("--bundle (-b)", "repo/app:v1"), "Expected --bundle (-b) to reference a digest but got 'repo/app:v1' "+
+			"(hint: --require-digests only allows references in the format repo@sha256:...)")
+		assert.Error(t, flags.ValidateRef("--image (-i)", "repo/app"))
+	})
+
+	t.Run("allows references by tag when digests are not required", func(t *testing.T) {
+		assert.NoError(t, RequireDigestsFlags{}.ValidateRef("--bundle (-b)", "repo/app:v1"))
+	})
+
+	run := func(args ...string) error {
+		imgpkgCmd := NewDefaultImgpkgCmd(ui.NewConfUI(ui.NewNoopLogger()))
+		imgpkgCmd.SetArgs(args)
+		return imgpkgCmd.Execute()
+	}
+
+	t.Run("is available to every command", func(t *testing.T) {
+		err := run("copy", "-b", "repo/app:v1", "--to-tar", "/tmp/app.tar", "--require-digests")
+		require.ErrorContains(t, err, "Expected --bundle (-b) to reference a digest but got 'repo/app:v1'")
+
+		err = run("--require-digests", "pull", "-i", "repo/app:v1", "-o", "/tmp/app")
+		require.ErrorContains(t, err, "Expected --image (-i) to reference a digest but got 'repo/app:v1'")
+
+		err = run("describe", "-b", "repo/app", "--require-digests")
+		require.ErrorContains(t, err, "Expected --bundle (-b) to reference a digest but got 'repo/app'")
+
+		err = run("copy", "--repo-tags", "repo/app:v1.*", "--to-repo", "other/app", "--require-digests")
+		require.ErrorContains(t, err, "Cannot use --repo-tags with --require-digests")
+	})
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/spf13/cobra"
+)
+
+// RunOptions Command Line options that can be provided to the run command
+type RunOptions struct {
+	ui ui.UI
+
+	JobsFilePath string
+	JobNames     []string
+	DryRun       bool
+
+	lookupEnv func(string) (string, bool)
+}
+
+// NewRunOptions constructor for building a RunOptions, holding values derived via flags
+func NewRunOptions(ui ui.UI) *RunOptions {
+	return &RunOptions{ui: ui, lookupEnv: os.LookupEnv}
+}
+
+// NewRunCmd Creates the run command
+func NewRunCmd(o *RunOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run JOBS-FILE",
+		Short: "Run the copy and push jobs described in a file",
+		Long: `Run the copy and push jobs described in a file.
+The jobs run in the order of the file and the run stops at the first job that fails. Registry settings, credentials and
+tag filters in the defaults section are shared by all the jobs, each job can override them. Credentials are referenced
+by the name of the environment variables that hold them. Paths are relative to the folder of the file.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.JobsFilePath = args[0]
+			return o.RunWithContext(cmd.Context())
+		},
+		Example: `
+    # Run all the jobs in jobs.yml
+    imgpkg run jobs.yml
+
+    # Run only the relocate-app job
+    imgpkg run jobs.yml --job relocate-app
+
+    # Example of a jobs file
+    apiVersion: imgpkg.carvel.dev/v1alpha1
+    kind: Jobs
+    defaults:
+      registry:
+        credentials:
+          usernameEnv: REGISTRY_USERNAME
+          passwordEnv: REGISTRY_PASSWORD
+      filter:
+        tags: "v1.*"
+    jobs:
+    - name: relocate-app
+      copy:
+        bundle: index.docker.io/dkalinin/app1-bundle:v1.0.0
+        toRepo: internal-registry/app1-bundle
+    - name: relocate-releases
+      copy:
+        repository: index.docker.io/dkalinin/app1
+        toRepo: internal-registry/app1`,
+	}
+
+	cmd.Flags().StringSliceVar(&o.JobNames, "job", nil, "Run only the job with this name (can be specified multiple times)")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", false, "Validate the file and print the jobs that would run without running them")
+	return cmd
+}
+
+// Run Executes the run command
+func (r *RunOptions) Run() error {
+	return r.RunWithContext(context.Background())
+}
+
+// RunWithContext Same as Run, ctx is given to every job so that the transfers of the job in progress are cancelled, and
+// the remaining jobs are not started, when it is done
+func (r *RunOptions) RunWithContext(ctx context.Context) error {
+	jobs, err := NewJobsFromPath(r.JobsFilePath)
+	if err != nil {
+		return err
+	}
+
+	selected, err := jobs.Select(r.JobNames)
+	if err != nil {
+		return err
+	}
+
+	// Build the options of every job before running any of them, so that mistakes are reported before any change is made
+	var runs []func(context.Context) error
+	for _, job := range selected {
+		run, err := r.jobRun(job, jobs.Defaults)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
+	}
+
+	for i, job := range selected {
+		r.ui.BeginLinef("Job '%s' (%d/%d): %s\n", job.Name, i+1, len(selected), job.Description())
+		if r.DryRun {
+			continue
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("Job '%s' not started: %s", job.Name, ctx.Err())
+		}
+
+		err := runs[i](ctx)
+		if err != nil {
+			return fmt.Errorf("Job '%s' failed: %s", job.Name, err)
+		}
+	}
+
+	if !r.DryRun {
+		r.ui.BeginLinef("\nSucceeded %d job(s)\n", len(selected))
+	}
+	return nil
+}
+
+func (r *RunOptions) jobRun(job Job, defaults JobDefaults) (func(context.Context) error, error) {
+	if job.Push != nil {
+		pushOpts, err := job.PushOptions(r.ui, defaults, r.lookupEnv)
+		if err != nil {
+			return nil, err
+		}
+		return pushOpts.RunWithContext, nil
+	}
+
+	copyOpts, err := job.CopyOptions(r.ui, defaults, r.lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	return copyOpts.RunWithContext, nil
+}
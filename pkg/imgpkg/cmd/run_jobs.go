@@ -0,0 +1,350 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// JobsAPIVersion API version of the file read by the run command
+	JobsAPIVersion = "imgpkg.carvel.dev/v1alpha1"
+	// JobsKind Kind of the file read by the run command
+	JobsKind = "Jobs"
+)
+
+// Jobs List of copy and push operations, and the defaults shared by them, that are executed by the run command
+type Jobs struct {
+	APIVersion string      `json:"apiVersion"`
+	Kind       string      `json:"kind"`
+	Defaults   JobDefaults `json:"defaults,omitempty"`
+	Jobs       []Job       `json:"jobs"`
+}
+
+// JobDefaults Configuration used by every job that does not override it
+type JobDefaults struct {
+	Registry    JobRegistry `json:"registry,omitempty"`
+	Concurrency int         `json:"concurrency,omitempty"`
+	Filter      JobFilter   `json:"filter,omitempty"`
+}
+
+// JobRegistry Connection to the registries, fields that are not set keep the defaults of the command line flags
+type JobRegistry struct {
+	CACertPaths []string        `json:"caCertPaths,omitempty"`
+	VerifyCerts *bool           `json:"verifyCerts,omitempty"`
+	Insecure    *bool           `json:"insecure,omitempty"`
+	RetryCount  *int            `json:"retryCount,omitempty"`
+	Credentials *JobCredentials `json:"credentials,omitempty"`
+}
+
+// JobCredentials References to the environment variables that hold the credentials, so that they are not stored in the file
+type JobCredentials struct {
+	UsernameEnv string `json:"usernameEnv,omitempty"`
+	PasswordEnv string `json:"passwordEnv,omitempty"`
+	TokenEnv    string `json:"tokenEnv,omitempty"`
+	Anon        bool   `json:"anon,omitempty"`
+}
+
+// JobFilter Selects the tags copied from a repository
+type JobFilter struct {
+	Tags string `json:"tags,omitempty"`
+}
+
+// Job Single copy or push operation
+type Job struct {
+	Name     string      `json:"name"`
+	Registry JobRegistry `json:"registry,omitempty"`
+	Copy     *CopyJob    `json:"copy,omitempty"`
+	Push     *PushJob    `json:"push,omitempty"`
+}
+
+// CopyJob Equivalent of the copy command, only one source and one destination can be provided
+type CopyJob struct {
+	Bundle     string     `json:"bundle,omitempty"`
+	Image      string     `json:"image,omitempty"`
+	Lock       string     `json:"lock,omitempty"`
+	Repository string     `json:"repository,omitempty"`
+	Tar        string     `json:"tar,omitempty"`
+	Filter     *JobFilter `json:"filter,omitempty"`
+
+	ToRepo string `json:"toRepo,omitempty"`
+	ToTar  string `json:"toTar,omitempty"`
+
+	Concurrency                   int    `json:"concurrency,omitempty"`
+	IncludeNonDistributableLayers bool   `json:"includeNonDistributableLayers,omitempty"`
+	CosignSignatures              bool   `json:"cosignSignatures,omitempty"`
+	LockOutput                    string `json:"lockOutput,omitempty"`
+}
+
+// PushJob Equivalent of the push command
+type PushJob struct {
+	Bundle     string   `json:"bundle,omitempty"`
+	Image      string   `json:"image,omitempty"`
+	Files      []string `json:"files"`
+	LockOutput string   `json:"lockOutput,omitempty"`
+}
+
+// NewJobsFromPath Reads the jobs file, paths in the file are relative to the folder of the file
+func NewJobsFromPath(path string) (Jobs, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return Jobs{}, fmt.Errorf("Reading path %s: %s", path, err)
+	}
+
+	var jobs Jobs
+	err = yaml.UnmarshalStrict(bs, &jobs)
+	if err != nil {
+		return Jobs{}, fmt.Errorf("Unmarshaling jobs: %s", err)
+	}
+
+	err = jobs.Validate()
+	if err != nil {
+		return Jobs{}, fmt.Errorf("Validating jobs: %s", err)
+	}
+
+	jobs.resolvePaths(filepath.Dir(path))
+	return jobs, nil
+}
+
+// Validate Checks the kind of the file and that each job describes exactly one operation
+func (j Jobs) Validate() error {
+	if j.APIVersion != JobsAPIVersion {
+		return fmt.Errorf("Validating apiVersion: Unknown version (known: %s)", JobsAPIVersion)
+	}
+	if j.Kind != JobsKind {
+		return fmt.Errorf("Validating kind: Unknown kind (known: %s)", JobsKind)
+	}
+	if len(j.Jobs) == 0 {
+		return fmt.Errorf("Expected at least one job")
+	}
+
+	names := map[string]struct{}{}
+	for i, job := range j.Jobs {
+		if job.Name == "" {
+			return fmt.Errorf("Expected job %d to have a name", i+1)
+		}
+		if _, found := names[job.Name]; found {
+			return fmt.Errorf("Expected job names to be unique, but found '%s' more than once", job.Name)
+		}
+		names[job.Name] = struct{}{}
+
+		if (job.Copy == nil) == (job.Push == nil) {
+			return fmt.Errorf("Expected job '%s' to have either copy or push", job.Name)
+		}
+		if job.Push != nil && len(job.Push.Files) == 0 {
+			return fmt.Errorf("Expected push job '%s' to have at least one file", job.Name)
+		}
+	}
+	return nil
+}
+
+// Select Returns the jobs with the provided names, in the order of the file. All the jobs are returned when no names are provided
+func (j Jobs) Select(names []string) ([]Job, error) {
+	if len(names) == 0 {
+		return j.Jobs, nil
+	}
+
+	selected := map[string]bool{}
+	for _, name := range names {
+		selected[name] = false
+	}
+
+	var jobs []Job
+	for _, job := range j.Jobs {
+		if _, found := selected[job.Name]; found {
+			selected[job.Name] = true
+			jobs = append(jobs, job)
+		}
+	}
+
+	for _, name := range names {
+		if !selected[name] {
+			return nil, fmt.Errorf("Expected to find job '%s' in the jobs file", name)
+		}
+	}
+	return jobs, nil
+}
+
+func (j *Jobs) resolvePaths(dir string) {
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+
+	for i, path := range j.Defaults.Registry.CACertPaths {
+		j.Defaults.Registry.CACertPaths[i] = resolve(path)
+	}
+	for i := range j.Jobs {
+		job := &j.Jobs[i]
+		for k, path := range job.Registry.CACertPaths {
+			job.Registry.CACertPaths[k] = resolve(path)
+		}
+		if job.Copy != nil {
+			job.Copy.Lock = resolve(job.Copy.Lock)
+			job.Copy.Tar = resolve(job.Copy.Tar)
+			job.Copy.ToTar = resolve(job.Copy.ToTar)
+			job.Copy.LockOutput = resolve(job.Copy.LockOutput)
+		}
+		if job.Push != nil {
+			for k, path := range job.Push.Files {
+				job.Push.Files[k] = resolve(path)
+			}
+			job.Push.LockOutput = resolve(job.Push.LockOutput)
+		}
+	}
+}
+
+// CopyOptions Builds the options of the copy command for the job, starting from the defaults of the command line flags
+func (j Job) CopyOptions(ui ui.UI, defaults JobDefaults, lookupEnv func(string) (string, bool)) (*CopyOptions, error) {
+	copyOpts := NewCopyOptions(ui)
+	NewCopyCmd(copyOpts)
+
+	err := j.configureRegistry(&copyOpts.RegistryFlags, defaults, lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	copyJob := j.Copy
+	copyOpts.BundleFlags.Bundle = copyJob.Bundle
+	copyOpts.ImageFlags.Image = copyJob.Image
+	copyOpts.LockInputFlags.LockFilePath = copyJob.Lock
+	copyOpts.TarFlags.TarSrc = copyJob.Tar
+	copyOpts.TarFlags.TarDst = copyJob.ToTar
+	copyOpts.RepoDst = copyJob.ToRepo
+	copyOpts.LockOutputFlags.LockFilePath = copyJob.LockOutput
+	copyOpts.IncludeNonDistributable = copyJob.IncludeNonDistributableLayers
+	copyOpts.SignatureFlags.CopyCosignSignatures = copyJob.CosignSignatures
+
+	if copyJob.Repository != "" {
+		filter := defaults.Filter
+		if copyJob.Filter != nil {
+			filter = *copyJob.Filter
+		}
+		tags := filter.Tags
+		if tags == "" {
+			tags = "*"
+		}
+		copyOpts.RepoTagsFlags.RepoTags = copyJob.Repository + ":" + tags
+	} else if copyJob.Filter != nil {
+		return nil, fmt.Errorf("Expected job '%s' to copy a repository when using a filter", j.Name)
+	}
+
+	switch {
+	case copyJob.Concurrency > 0:
+		copyOpts.Concurrency = copyJob.Concurrency
+	case defaults.Concurrency > 0:
+		copyOpts.Concurrency = defaults.Concurrency
+	}
+
+	return copyOpts, nil
+}
+
+// PushOptions Builds the options of the push command for the job, starting from the defaults of the command line flags
+func (j Job) PushOptions(ui ui.UI, defaults JobDefaults, lookupEnv func(string) (string, bool)) (*PushOptions, error) {
+	pushOpts := NewPushOptions(ui)
+	NewPushCmd(pushOpts)
+
+	err := j.configureRegistry(&pushOpts.RegistryFlags, defaults, lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	pushOpts.BundleFlags.Bundle = j.Push.Bundle
+	pushOpts.ImageFlags.Image = j.Push.Image
+	pushOpts.FileFlags.Files = j.Push.Files
+	pushOpts.LockOutputFlags.LockFilePath = j.Push.LockOutput
+	return pushOpts, nil
+}
+
+// configureRegistry Applies the registry defaults and then the overrides of the job
+func (j Job) configureRegistry(flags *RegistryFlags, defaults JobDefaults, lookupEnv func(string) (string, bool)) error {
+	for _, registry := range []JobRegistry{defaults.Registry, j.Registry} {
+		if len(registry.CACertPaths) > 0 {
+			flags.CACertPaths = registry.CACertPaths
+		}
+		if registry.VerifyCerts != nil {
+			flags.VerifyCerts = *registry.VerifyCerts
+		}
+		if registry.Insecure != nil {
+			flags.Insecure = *registry.Insecure
+		}
+		if registry.RetryCount != nil {
+			flags.RetryCount = *registry.RetryCount
+		}
+		if registry.Credentials != nil {
+			err := registry.Credentials.configure(flags, lookupEnv)
+			if err != nil {
+				return fmt.Errorf("Configuring credentials of job '%s': %s", j.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c JobCredentials) configure(flags *RegistryFlags, lookupEnv func(string) (string, bool)) error {
+	lookup := func(name string) (string, error) {
+		if name == "" {
+			return "", nil
+		}
+		value, found := lookupEnv(name)
+		if !found {
+			return "", fmt.Errorf("Expected environment variable '%s' to be set", name)
+		}
+		return value, nil
+	}
+
+	var err error
+	flags.Username, err = lookup(c.UsernameEnv)
+	if err != nil {
+		return err
+	}
+	flags.Password, err = lookup(c.PasswordEnv)
+	if err != nil {
+		return err
+	}
+	flags.Token, err = lookup(c.TokenEnv)
+	if err != nil {
+		return err
+	}
+	flags.Anon = c.Anon
+	return nil
+}
+
+// Description Short description of the operation of the job, used when reporting progress
+func (j Job) Description() string {
+	if j.Push != nil {
+		target := j.Push.Bundle
+		if target == "" {
+			target = j.Push.Image
+		}
+		return fmt.Sprintf("push %s to %s", strings.Join(j.Push.Files, ", "), target)
+	}
+
+	var source string
+	switch {
+	case j.Copy.Bundle != "":
+		source = j.Copy.Bundle
+	case j.Copy.Image != "":
+		source = j.Copy.Image
+	case j.Copy.Lock != "":
+		source = j.Copy.Lock
+	case j.Copy.Repository != "":
+		source = j.Copy.Repository
+	default:
+		source = j.Copy.Tar
+	}
+	destination := j.Copy.ToRepo
+	if destination == "" {
+		destination = j.Copy.ToTar
+	}
+	return fmt.Sprintf("copy %s to %s", source, destination)
+}
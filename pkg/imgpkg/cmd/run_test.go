@@ -0,0 +1,245 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestRun(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	bundleInfo := fakeRegistry.WithBundleFromPath("some/bundle", "test_assets/bundle").
+		WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+	fakeRegistry.WithImage("some/app:v1.0.0", randomTestImage(t))
+	fakeRegistry.WithImage("some/app:v1.1.0", randomTestImage(t))
+	fakeRegistry.WithImage("some/app:v2.0.0", randomTestImage(t))
+	fakeRegistry.Build()
+
+	writeJobs := func(t *testing.T, jobs string) string {
+		path := filepath.Join(t.TempDir(), "jobs.yml")
+		require.NoError(t, os.WriteFile(path, []byte(jobs), 0600))
+		return path
+	}
+
+	runJobs := func(path string, opts func(*RunOptions)) (string, error) {
+		stdout := &bytes.Buffer{}
+		runOpts := NewRunOptions(ui.NewWriterUI(stdout, &bytes.Buffer{}, ui.NewNoopLogger()))
+		runOpts.JobsFilePath = path
+		runOpts.lookupEnv = func(name string) (string, bool) {
+			if name == "SOME_USERNAME" {
+				return "some-user", true
+			}
+			return "", false
+		}
+		if opts != nil {
+			opts(runOpts)
+		}
+		err := runOpts.Run()
+		return stdout.String(), err
+	}
+
+	t.Run("runs the copy jobs in order using the defaults", func(t *testing.T) {
+		path := writeJobs(t, `
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: Jobs
+defaults:
+  concurrency: 1
+  filter:
+    tags: "v1.*"
+jobs:
+- name: relocate-bundle
+  copy:
+    bundle: `+bundleInfo.RefDigest+`
+    toRepo: `+fakeRegistry.ReferenceOnTestServer("relocated/bundle")+`
+    lockOutput: bundle.lock.yml
+- name: relocate-app
+  copy:
+    repository: `+fakeRegistry.ReferenceOnTestServer("some/app")+`
+    toRepo: `+fakeRegistry.ReferenceOnTestServer("relocated/app")+`
+`)
+		output, err := runJobs(path, nil)
+		require.NoError(t, err)
+		assert.Contains(t, output, "Job 'relocate-bundle' (1/2): copy "+bundleInfo.RefDigest)
+		assert.Contains(t, output, "Job 'relocate-app' (2/2): copy "+fakeRegistry.ReferenceOnTestServer("some/app"))
+		assert.Contains(t, output, "Succeeded 2 job(s)")
+
+		bundleLock, err := lockconfig.NewBundleLockFromPath(filepath.Join(filepath.Dir(path), "bundle.lock.yml"))
+		require.NoError(t, err)
+		bundleRef, err := regname.NewDigest(bundleLock.Bundle.Image)
+		require.NoError(t, err)
+		assert.Equal(t, fakeRegistry.ReferenceOnTestServer("relocated/bundle"), bundleRef.Context().Name())
+
+		tags, err := listTags(t, fakeRegistry.ReferenceOnTestServer("relocated/app"))
+		require.NoError(t, err)
+		assert.Subset(t, tags, []string{"v1.0.0", "v1.1.0"})
+		assert.NotContains(t, tags, "v2.0.0")
+	})
+
+	t.Run("only prints the jobs when running with --dry-run", func(t *testing.T) {
+		path := writeJobs(t, `
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: Jobs
+jobs:
+- name: relocate-bundle
+  copy:
+    bundle: `+bundleInfo.RefDigest+`
+    toRepo: `+fakeRegistry.ReferenceOnTestServer("dry-run/bundle")+`
+- name: push-config
+  push:
+    bundle: `+fakeRegistry.ReferenceOnTestServer("dry-run/config")+`
+    files: [config/]
+`)
+		output, err := runJobs(path, func(o *RunOptions) {
+			o.DryRun = true
+			o.JobNames = []string{"push-config"}
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Job 'push-config' (1/1): push "+filepath.Join(filepath.Dir(path), "config")+" to "+fakeRegistry.ReferenceOnTestServer("dry-run/config")+"\n", output)
+
+		_, err = listTags(t, fakeRegistry.ReferenceOnTestServer("dry-run/bundle"))
+		require.Error(t, err)
+	})
+
+	t.Run("fails before running any job when the credentials are not in the environment", func(t *testing.T) {
+		path := writeJobs(t, `
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: Jobs
+defaults:
+  registry:
+    credentials:
+      usernameEnv: SOME_USERNAME
+jobs:
+- name: first
+  copy:
+    bundle: `+bundleInfo.RefDigest+`
+    toRepo: `+fakeRegistry.ReferenceOnTestServer("not-run/bundle")+`
+- name: second
+  registry:
+    credentials:
+      usernameEnv: SOME_USERNAME
+      passwordEnv: MISSING_PASSWORD
+  copy:
+    bundle: `+bundleInfo.RefDigest+`
+    toRepo: `+fakeRegistry.ReferenceOnTestServer("not-run/bundle")+`
+`)
+		_, err := runJobs(path, nil)
+		require.EqualError(t, err, "Configuring credentials of job 'second': Expected environment variable 'MISSING_PASSWORD' to be set")
+
+		_, err = listTags(t, fakeRegistry.ReferenceOnTestServer("not-run/bundle"))
+		require.Error(t, err)
+	})
+
+	t.Run("reports the job that failed", func(t *testing.T) {
+		path := writeJobs(t, `
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: Jobs
+jobs:
+- name: no-destination
+  copy:
+    bundle: `+bundleInfo.RefDigest+`
+`)
+		_, err := runJobs(path, nil)
+		require.EqualError(t, err, "Job 'no-destination' failed: Expected either --to-tar, --to-repo, --to-oci, or --to-artifactory-import")
+	})
+
+	t.Run("cancels the running job and does not start the next ones when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// Cancels the run as soon as the first job starts uploading to the destination
+		fakeRegistry.WithCustomHandler(func(_ http.ResponseWriter, request *http.Request) bool {
+			if strings.HasPrefix(request.URL.Path, "/v2/cancelled/") {
+				cancel()
+			}
+			return false
+		})
+
+		path := writeJobs(t, `
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: Jobs
+jobs:
+- name: cancelled-copy
+  copy:
+    bundle: `+bundleInfo.RefDigest+`
+    toRepo: `+fakeRegistry.ReferenceOnTestServer("cancelled/bundle")+`
+- name: next-copy
+  copy:
+    bundle: `+bundleInfo.RefDigest+`
+    toRepo: `+fakeRegistry.ReferenceOnTestServer("next/bundle")+`
+`)
+		stdout := &bytes.Buffer{}
+		runOpts := NewRunOptions(ui.NewWriterUI(stdout, &bytes.Buffer{}, ui.NewNoopLogger()))
+		runOpts.JobsFilePath = path
+		err := runOpts.RunWithContext(ctx)
+		require.ErrorContains(t, err, "Job 'cancelled-copy' failed")
+		require.ErrorContains(t, err, "context canceled")
+		assert.NotContains(t, stdout.String(), "Job 'next-copy'")
+
+		_, err = listTags(t, fakeRegistry.ReferenceOnTestServer("next/bundle"))
+		require.Error(t, err)
+	})
+
+	t.Run("validates the jobs file", func(t *testing.T) {
+		testCases := map[string]struct {
+			jobs        string
+			expectedErr string
+		}{
+			"unknown kind": {
+				jobs:        "apiVersion: imgpkg.carvel.dev/v1alpha1\nkind: Other\njobs: [{name: a, copy: {}}]",
+				expectedErr: "Validating kind: Unknown kind (known: Jobs)",
+			},
+			"unknown field": {
+				jobs:        "apiVersion: imgpkg.carvel.dev/v1alpha1\nkind: Jobs\njobs: [{name: a, move: {}}]",
+				expectedErr: "Unmarshaling jobs",
+			},
+			"copy and push in the same job": {
+				jobs:        "apiVersion: imgpkg.carvel.dev/v1alpha1\nkind: Jobs\njobs: [{name: a, copy: {}, push: {files: [a]}}]",
+				expectedErr: "Expected job 'a' to have either copy or push",
+			},
+			"duplicated names": {
+				jobs:        "apiVersion: imgpkg.carvel.dev/v1alpha1\nkind: Jobs\njobs: [{name: a, copy: {}}, {name: a, copy: {}}]",
+				expectedErr: "Expected job names to be unique, but found 'a' more than once",
+			},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				_, err := runJobs(writeJobs(t, tc.jobs), nil)
+				require.ErrorContains(t, err, tc.expectedErr)
+			})
+		}
+
+		_, err := runJobs(writeJobs(t, "apiVersion: imgpkg.carvel.dev/v1alpha1\nkind: Jobs\njobs: [{name: a, copy: {}}]"), func(o *RunOptions) {
+			o.JobNames = []string{"b"}
+		})
+		require.EqualError(t, err, "Expected to find job 'b' in the jobs file")
+	})
+}
+
+func randomTestImage(t *testing.T) regv1.Image {
+	img, err := random.Image(100, 1)
+	require.NoError(t, err)
+	return img
+}
+
+func listTags(t *testing.T, repo string) ([]string, error) {
+	repository, err := regname.NewRepository(repo)
+	require.NoError(t, err)
+	return regremote.List(repository)
+}
@@ -0,0 +1,17 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewSBOMCmd Creates the sbom command that groups the commands to inspect the SBOMs attached to images
+func NewSBOMCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sbom",
+		Short: "SBOMs attached to images",
+	}
+	return cmd
+}
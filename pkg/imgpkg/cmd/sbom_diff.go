@@ -0,0 +1,135 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+)
+
+// SBOMDiffOptions Command Line options that can be provided to the sbom diff command
+type SBOMDiffOptions struct {
+	ui ui.UI
+
+	RegistryFlags   RegistryFlags
+	OutputTypeFlags OutputTypeFlags
+
+	OldBundle   string
+	NewBundle   string
+	Concurrency int
+}
+
+// NewSBOMDiffOptions constructor for building an SBOMDiffOptions, holding values derived via flags
+func NewSBOMDiffOptions(ui ui.UI) *SBOMDiffOptions {
+	return &SBOMDiffOptions{ui: ui}
+}
+
+// NewSBOMDiffCmd Creates the sbom diff command
+func NewSBOMDiffCmd(o *SBOMDiffOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff OLD-BUNDLE NEW-BUNDLE",
+		Short: "Show the packages that changed between the SBOMs of the images of two versions of a bundle",
+		Long: `Show the packages that changed between the SBOMs of the images of two versions of a bundle.
+The images of both bundles, and of their nested bundles, are matched by the repository they originally came from.
+For each image whose digest changed, the packages listed in the SBOMs cosign attached to the old and new images are
+compared, reporting the packages added, removed and with a different version. SBOMs in the SPDX and CycloneDX JSON
+formats are supported.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			o.OldBundle = args[0]
+			o.NewBundle = args[1]
+			return o.Run()
+		},
+		Example: `
+    # Show the packages that changed between two versions of bundle repo/app1-bundle
+    imgpkg sbom diff repo/app1-bundle:1.0.0 repo/app1-bundle:1.1.0`,
+	}
+
+	o.RegistryFlags.Set(cmd)
+	o.OutputTypeFlags.Set(cmd)
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 5, "Concurrency")
+	return cmd
+}
+
+// Run Describes both bundles and prints the difference between the SBOMs of their images
+func (s *SBOMDiffOptions) Run() error {
+	err := s.OutputTypeFlags.Validate()
+	if err != nil {
+		return err
+	}
+	logsUI := s.OutputTypeFlags.LogsUI(s.ui)
+
+	registryOpts := s.RegistryFlags.AsRegistryOpts()
+	descriptions := map[string]v1.Description{}
+	for _, bundleRef := range []string{s.OldBundle, s.NewBundle} {
+		description, err := v1.Describe(bundleRef, v1.DescribeOpts{
+			Logger:                 util.NewUILevelLogger(util.LogWarn, util.NewLogger(logsUI)),
+			Concurrency:            s.Concurrency,
+			IncludeCosignArtifacts: true,
+		}, registryOpts)
+		if err != nil {
+			return err
+		}
+		descriptions[bundleRef] = description
+	}
+
+	diff, err := v1.NewSBOMDiff(descriptions[s.OldBundle], descriptions[s.NewBundle], registryOpts)
+	if err != nil {
+		return err
+	}
+
+	if s.OutputTypeFlags.IsJSON() {
+		return s.OutputTypeFlags.WriteJSON(s.ui, diff)
+	}
+	s.printText(diff)
+	return nil
+}
+
+func (s *SBOMDiffOptions) printText(diff v1.SBOMDiff) {
+	table := uitable.Table{
+		Title:   "Packages",
+		Content: "packages",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Repository"),
+			uitable.NewHeader("Package"),
+			uitable.NewHeader("Old version"),
+			uitable.NewHeader("New version"),
+		},
+	}
+
+	for _, img := range diff.Images {
+		for _, missing := range img.MissingSBOMs {
+			s.ui.BeginLinef("Warning: Image '%s' has no SBOM attached, its packages are not compared\n", missing)
+		}
+
+		var rows [][]uitable.Value
+		for _, component := range img.Removed {
+			rows = append(rows, sbomDiffRow(img.Repository, component.Name, component.Version, "-"))
+		}
+		for _, component := range img.Added {
+			rows = append(rows, sbomDiffRow(img.Repository, component.Name, "-", component.Version))
+		}
+		for _, change := range img.Changed {
+			rows = append(rows, sbomDiffRow(img.Repository, change.Name, change.OldVersion, change.NewVersion))
+		}
+		table.Rows = append(table.Rows, rows...)
+	}
+
+	table.SortBy = []uitable.ColumnSort{{Column: 0, Asc: true}, {Column: 1, Asc: true}}
+	s.ui.PrintTable(table)
+	s.ui.BeginLinef("%d images changed\n", len(diff.Images))
+}
+
+func sbomDiffRow(repository, name, oldVersion, newVersion string) []uitable.Value {
+	return []uitable.Value{
+		uitable.NewValueString(repository),
+		uitable.NewValueString(name),
+		uitable.NewValueString(oldVersion),
+		uitable.NewValueString(newVersion),
+	}
+}
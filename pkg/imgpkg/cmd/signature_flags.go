@@ -10,5 +10,5 @@ type SignatureFlags struct {
 }
 
 func (s *SignatureFlags) Set(cmd *cobra.Command) {
-	cmd.Flags().BoolVar(&s.CopyCosignSignatures, "cosign-signatures", false, "Find and copy cosign signatures for images")
+	cmd.Flags().BoolVar(&s.CopyCosignSignatures, "cosign-signatures", false, "Find and copy cosign signatures, attestations (.att) and SBOMs (.sbom) for images")
 }
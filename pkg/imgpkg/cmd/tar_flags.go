@@ -4,6 +4,10 @@
 package cmd
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/spf13/cobra"
 )
 
@@ -11,13 +15,65 @@ type TarFlags struct {
 	TarSrc string
 	TarDst string
 	Resume bool
+	ToTag  string
+
+	ToTarChunkSize string
+
+	FallbackToOrigin bool
+
+	BaseTar string
 }
 
 func (t *TarFlags) Set(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&t.TarDst, "to-tar", "", "Location to write a tar file containing assets")
-	cmd.Flags().StringVar(&t.TarSrc, "tar", "", "Path to tar file which contains assets to be copied to a registry")
-	cmd.Flags().BoolVar(&t.Resume, "resume", false, "Resume the copy to tar. When set to true will try to read the tar and only download the missing blobs")
+	cmd.Flags().StringVar(&t.TarSrc, "tar", "", "Path to tar file which contains assets to be copied to a registry, or to the index of a tar split with --to-tar-chunk-size")
+	cmd.Flags().BoolVar(&t.Resume, "resume", false, "Resume the copy to tar. When set to true will try to read the tar and only download the missing blobs. The layers completely written by an interrupted copy are recorded in <tar>.checkpoint, removed once the tar is complete")
+	cmd.Flags().StringVar(&t.ToTag, "to-tag", "", "Tag applied to the bundle in the destination repository (--to-repo) instead of the tag of the source bundle. Recorded in the BundleLock written with --lock-output")
+	cmd.Flags().StringVar(&t.ToTarChunkSize, "to-tar-chunk-size", "", "Split the tar (--to-tar) in numbered parts of at most this size, plus an index file that is provided to --tar to copy them (format: 2GB, 700MB, 4GiB)")
+	cmd.Flags().BoolVar(&t.FallbackToOrigin, "fallback-to-origin", false, "Retrieve the layers missing from the tar (--tar) from the registries the images were exported from")
+	cmd.Flags().StringVar(&t.BaseTar, "base-tar", "", "Tar previously exported, only the layers that are not present in it are written to the tar (--to-tar), "+
+		"which references it and needs it to be copied with --tar. When copying from a tar (--tar) created this way, location of the base tar (default: next to the tar, with its original name)")
 }
 
 func (t TarFlags) IsSrc() bool { return t.TarSrc != "" }
 func (t TarFlags) IsDst() bool { return t.TarDst != "" }
+
+// ChunkSize Returns the maximum size in bytes of the parts of the tar, 0 when the tar is not split
+func (t TarFlags) ChunkSize() (int64, error) {
+	if t.ToTarChunkSize == "" {
+		return 0, nil
+	}
+	size, err := parseByteSize(t.ToTarChunkSize)
+	if err != nil {
+		return 0, fmt.Errorf("Parsing --to-tar-chunk-size: %s", err)
+	}
+	return size, nil
+}
+
+// parseByteSize Parses sizes like 700MB or 4GiB, units without i are powers of 1000 and with i powers of 1024
+func parseByteSize(value string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+		{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000}, {"TB", 1000 * 1000 * 1000 * 1000},
+		{"B", 1},
+	}
+
+	number := strings.TrimSpace(value)
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(strings.ToUpper(number), strings.ToUpper(unit.suffix)) {
+			number = strings.TrimSpace(number[:len(number)-len(unit.suffix)])
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	size, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("Expected a positive size like 2GB or 700MB, but got '%s'", value)
+	}
+	return size * multiplier, nil
+}
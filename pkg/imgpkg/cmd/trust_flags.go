@@ -0,0 +1,87 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	// Register the KMS providers that can hold the keys provided in --signature-key
+	_ "github.com/sigstore/sigstore/pkg/signature/kms/aws"
+	_ "github.com/sigstore/sigstore/pkg/signature/kms/azure"
+	_ "github.com/sigstore/sigstore/pkg/signature/kms/gcp"
+	_ "github.com/sigstore/sigstore/pkg/signature/kms/hashivault"
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/signature"
+)
+
+// TrustFlags Location of the TrustConfig used to verify the signatures of the images
+type TrustFlags struct {
+	TrustConfigPath string
+	VerifySignature bool
+	SignatureKeys   []string
+}
+
+// Set Registers the flags in the command
+func (t *TrustFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&t.TrustConfigPath, "trust-config", "",
+		"TrustConfig with the public keys that must have signed the images of each origin registry "+
+			"(default: $IMGPKG_TRUST_CONFIG, or ~/.imgpkg/trust.yml when present)")
+	cmd.Flags().BoolVar(&t.VerifySignature, "verify-signature", false,
+		"Verify that the bundle, or image, and all the images it references are signed with cosign by one of the --signature-key keys")
+	cmd.Flags().StringSliceVar(&t.SignatureKeys, "signature-key", nil,
+		"Path to a PEM encoded public key, or URI of a key in a KMS (awskms://, azurekms://, gcpkms:// or hashivault://), used with --verify-signature. "+
+			"The KMS credentials are read from the environment, as cosign does (can be specified multiple times)")
+}
+
+// TrustConfig Loads the TrustConfig from the flag, the environment or the home directory.
+// The public keys in a KMS are retrieved using ctx. Returns nil when there is no TrustConfig to load
+func (t TrustFlags) TrustConfig(ctx context.Context) (*signature.TrustConfig, error) {
+	if t.VerifySignature {
+		return t.signatureKeysTrustConfig(ctx)
+	}
+	if len(t.SignatureKeys) > 0 {
+		return nil, fmt.Errorf("Flag --signature-key can only be used with --verify-signature")
+	}
+
+	path := t.TrustConfigPath
+	if path == "" {
+		path = os.Getenv("IMGPKG_TRUST_CONFIG")
+	}
+	if path == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		defaultPath := filepath.Join(homeDir, ".imgpkg", "trust.yml")
+		if _, err := os.Stat(defaultPath); err != nil {
+			return nil, nil
+		}
+		path = defaultPath
+	}
+
+	config, err := signature.NewTrustConfigFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// signatureKeysTrustConfig Builds the TrustConfig that requires every image to be signed by one of the --signature-key keys
+func (t TrustFlags) signatureKeysTrustConfig(ctx context.Context) (*signature.TrustConfig, error) {
+	if t.TrustConfigPath != "" {
+		return nil, fmt.Errorf("Cannot use --verify-signature with --trust-config")
+	}
+	if len(t.SignatureKeys) == 0 {
+		return nil, fmt.Errorf("Expected --signature-key when using --verify-signature")
+	}
+
+	config, err := signature.NewTrustConfigForKeysWithContext(ctx, t.SignatureKeys)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
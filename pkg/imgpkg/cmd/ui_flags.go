@@ -4,6 +4,8 @@
 package cmd
 
 import (
+	"os"
+
 	"github.com/cppforlife/go-cli-ui/ui"
 	uitable "github.com/cppforlife/go-cli-ui/ui/table"
 	"github.com/spf13/cobra"
@@ -16,6 +18,8 @@ type Logger interface {
 type UIFlags struct {
 	TTY            bool
 	Color          bool
+	NoColor        bool
+	Quiet          bool
 	JSON           bool
 	NonInteractive bool
 	Columns        []string
@@ -24,6 +28,8 @@ type UIFlags struct {
 func (f *UIFlags) Set(cmd *cobra.Command) {
 	cmd.PersistentFlags().BoolVar(&f.TTY, "tty", false, "Force TTY-like output")
 	cmd.PersistentFlags().BoolVar(&f.Color, "color", true, "Set color output")
+	cmd.PersistentFlags().BoolVar(&f.NoColor, "no-color", false, "Disable color output ($NO_COLOR)")
+	cmd.PersistentFlags().BoolVarP(&f.Quiet, "quiet", "q", false, "Only output errors and the result of the command (e.g. the pushed image digest)")
 	cmd.PersistentFlags().BoolVar(&f.JSON, "json", false, "Output as JSON")
 	cmd.PersistentFlags().BoolVarP(&f.NonInteractive, "yes", "y", false, "Assume yes for any prompt")
 	cmd.PersistentFlags().StringSliceVar(&f.Columns, "column", nil, "Filter to show only given columns")
@@ -32,7 +38,7 @@ func (f *UIFlags) Set(cmd *cobra.Command) {
 func (f *UIFlags) ConfigureUI(ui *ui.ConfUI) {
 	ui.EnableTTY(f.TTY)
 
-	if f.Color {
+	if f.Color && !f.NoColor && os.Getenv("NO_COLOR") == "" {
 		ui.EnableColor()
 	}
 
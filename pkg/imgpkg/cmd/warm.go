@@ -0,0 +1,268 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+const (
+	warmStatusPresent          = "present"
+	warmStatusMounted          = "mounted"
+	warmStatusPartiallyMounted = "partially mounted"
+	warmStatusSkipped          = "skipped"
+)
+
+// WarmOptions Command Line options that can be provided to the warm command
+type WarmOptions struct {
+	ui ui.UI
+
+	LockInputFlags LockInputFlags
+	RegistryFlags  RegistryFlags
+
+	RepoDst     string
+	Concurrency int
+}
+
+// NewWarmOptions constructor for building a WarmOptions, holding values derived via flags
+func NewWarmOptions(ui ui.UI) *WarmOptions {
+	return &WarmOptions{ui: ui}
+}
+
+// NewWarmCmd Creates the warm command
+func NewWarmCmd(o *WarmOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "warm",
+		Short: "Prepare a repository for a later copy without copying any image data",
+		Long: `Prepare a repository for a later copy without copying any image data.
+Checks which images are already present in the destination repository and, for images stored in the same registry,
+mounts their blobs into the destination repository. No blobs are downloaded from the source or uploaded to the destination.`,
+		RunE: func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+    # Mount, into internal-registry/app1, the blobs of the images in images.yml that are stored in internal-registry
+    imgpkg warm --lock images.yml --repo internal-registry/app1`,
+	}
+
+	o.LockInputFlags.SetOnWarm(cmd)
+	o.RegistryFlags.Set(cmd)
+	cmd.Flags().StringVar(&o.RepoDst, "repo", "", "Repository that will receive the images")
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 5, "Concurrency")
+	return cmd
+}
+
+// Run Executes the warm command
+func (w *WarmOptions) Run() error {
+	if w.LockInputFlags.LockFilePath == "" {
+		return fmt.Errorf("Expected --lock with the images to warm")
+	}
+	if w.RepoDst == "" {
+		return fmt.Errorf("Expected --repo with the repository to warm")
+	}
+
+	bundleLock, imagesLock, err := lockconfig.NewLockFromPath(w.LockInputFlags.LockFilePath)
+	if err != nil {
+		return err
+	}
+	if bundleLock != nil {
+		return fmt.Errorf("Expected an ImagesLock in '%s' but found a BundleLock", w.LockInputFlags.LockFilePath)
+	}
+
+	dstRepo, err := regname.NewRepository(w.RepoDst)
+	if err != nil {
+		return fmt.Errorf("Building destination repository ref: %s", err)
+	}
+
+	reg, err := registry.NewSimpleRegistry(w.RegistryFlags.AsRegistryOpts())
+	if err != nil {
+		return err
+	}
+
+	logger := util.NewUILevelLogger(util.LogWarn, util.NewPrefixedLogger("warm | ", util.NewLogger(w.ui)))
+	results, err := imagesWarmer{registry: reg, concurrency: w.Concurrency}.Warm(imagesLock.Images, dstRepo)
+	if err != nil {
+		return err
+	}
+
+	counts := map[string]int{}
+	for _, result := range results {
+		counts[result.Status]++
+		switch result.Status {
+		case warmStatusPresent:
+			logger.Logf("%s: already present in destination\n", result.Image)
+		case warmStatusMounted, warmStatusPartiallyMounted:
+			logger.Logf("%s: %s %d/%d blobs\n", result.Image, result.Status, result.MountedBlobs, result.TotalBlobs)
+		default:
+			logger.Logf("%s: %s (%s)\n", result.Image, result.Status, result.Reason)
+		}
+	}
+	logger.Logf("Warmed %d image(s): %d present, %d mounted, %d partially mounted, %d skipped\n", len(results),
+		counts[warmStatusPresent], counts[warmStatusMounted], counts[warmStatusPartiallyMounted], counts[warmStatusSkipped])
+
+	return nil
+}
+
+// warmRegistry Registry operations used to warm a repository
+type warmRegistry interface {
+	Get(regname.Reference) (*regremote.Descriptor, error)
+	Digest(regname.Reference) (regv1.Hash, error)
+	MountBlob(blob regname.Digest, dst regname.Repository) (bool, error)
+}
+
+// warmResult State of an image in the destination repository after warming it
+type warmResult struct {
+	Image        string
+	Status       string
+	Reason       string
+	MountedBlobs int
+	TotalBlobs   int
+}
+
+// imagesWarmer Mounts the blobs of images into a repository
+type imagesWarmer struct {
+	registry    warmRegistry
+	concurrency int
+}
+
+// Warm checks if each image is present in the destination repository and mounts the blobs of the missing ones
+func (w imagesWarmer) Warm(images []lockconfig.ImageRef, dst regname.Repository) ([]warmResult, error) {
+	throttle := util.NewThrottle(w.concurrency)
+
+	type resultOrErr struct {
+		result warmResult
+		err    error
+	}
+	resultCh := make(chan resultOrErr, len(images))
+	for _, img := range images {
+		img := img // copy
+
+		go func() {
+			throttle.Take()
+			defer throttle.Done()
+
+			result, err := w.warmImage(img.Image, dst)
+			resultCh <- resultOrErr{result, err}
+		}()
+	}
+
+	var results []warmResult
+	for range images {
+		res := <-resultCh
+		if res.err != nil {
+			return nil, res.err
+		}
+		results = append(results, res.result)
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Image < results[j].Image })
+	return results, nil
+}
+
+func (w imagesWarmer) warmImage(image string, dst regname.Repository) (warmResult, error) {
+	result := warmResult{Image: image}
+
+	src, err := regname.NewDigest(image)
+	if err != nil {
+		return result, fmt.Errorf("Expected image '%s' to be a digest reference: %s", image, err)
+	}
+
+	_, err = w.registry.Digest(dst.Digest(src.DigestStr()))
+	if err == nil {
+		result.Status = warmStatusPresent
+		return result, nil
+	}
+	if terr, ok := err.(*transport.Error); !ok || terr.StatusCode != http.StatusNotFound {
+		return result, fmt.Errorf("Checking destination %s: %s", dst.Digest(src.DigestStr()).Name(), err)
+	}
+
+	if src.Context().RegistryStr() != dst.RegistryStr() {
+		result.Status = warmStatusSkipped
+		result.Reason = "blobs can only be mounted between repositories of the same registry"
+		return result, nil
+	}
+
+	blobs, err := w.blobs(src)
+	if err != nil {
+		return result, fmt.Errorf("Listing blobs of %s: %s", image, err)
+	}
+
+	result.TotalBlobs = len(blobs)
+	for _, blob := range blobs {
+		mounted, err := w.registry.MountBlob(src.Context().Digest(blob.String()), dst)
+		if err != nil {
+			return result, err
+		}
+		if mounted {
+			result.MountedBlobs++
+		}
+	}
+
+	switch {
+	case result.MountedBlobs == result.TotalBlobs:
+		result.Status = warmStatusMounted
+	case result.MountedBlobs > 0:
+		result.Status = warmStatusPartiallyMounted
+	default:
+		result.Status = warmStatusSkipped
+		result.Reason = "registry does not support mounting blobs"
+	}
+	return result, nil
+}
+
+// blobs returns the digests of the config and distributable layers of the image, or of all the images in the index
+func (w imagesWarmer) blobs(ref regname.Digest) ([]regv1.Hash, error) {
+	desc, err := w.registry.Get(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if desc.MediaType.IsIndex() {
+		idx, err := desc.ImageIndex()
+		if err != nil {
+			return nil, err
+		}
+		idxManifest, err := idx.IndexManifest()
+		if err != nil {
+			return nil, err
+		}
+
+		var blobs []regv1.Hash
+		for _, child := range idxManifest.Manifests {
+			childBlobs, err := w.blobs(ref.Context().Digest(child.Digest.String()))
+			if err != nil {
+				return nil, err
+			}
+			blobs = append(blobs, childBlobs...)
+		}
+		return blobs, nil
+	}
+
+	img, err := desc.Image()
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := []regv1.Hash{manifest.Config.Digest}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType.IsDistributable() {
+			blobs = append(blobs, layer.Digest)
+		}
+	}
+	return blobs, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"io"
+	"testing"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestImagesWarmer(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistryWithRepoSeparation(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	missingImage := fakeRegistry.WithRandomImage("library/image")
+	presentImage := fakeRegistry.WithRandomImage("library/other-image")
+	fakeRegistry.CopyImage(*presentImage, "library/warm")
+	reg := fakeRegistry.Build().(*registry.SimpleRegistry)
+
+	dstRepo, err := regname.NewRepository(fakeRegistry.ReferenceOnTestServer("library/warm"))
+	require.NoError(t, err)
+
+	subject := imagesWarmer{registry: reg, concurrency: 2}
+	results, err := subject.Warm([]lockconfig.ImageRef{
+		{Image: missingImage.RefDigest},
+		{Image: presentImage.RefDigest},
+	}, dstRepo)
+	require.NoError(t, err)
+
+	resultsByImage := map[string]warmResult{}
+	for _, result := range results {
+		resultsByImage[result.Image] = result
+	}
+	assert.Equal(t, warmStatusPresent, resultsByImage[presentImage.RefDigest].Status)
+
+	missingResult := resultsByImage[missingImage.RefDigest]
+	assert.Equal(t, warmStatusMounted, missingResult.Status)
+	assert.Equal(t, missingResult.TotalBlobs, missingResult.MountedBlobs)
+
+	t.Run("blobs are available in the destination repository", func(t *testing.T) {
+		layers, err := missingImage.Image.Layers()
+		require.NoError(t, err)
+		for _, layer := range layers {
+			digest, err := layer.Digest()
+			require.NoError(t, err)
+
+			dstLayer, err := regremote.Layer(dstRepo.Digest(digest.String()))
+			require.NoError(t, err)
+			rc, err := dstLayer.Compressed()
+			require.NoError(t, err)
+			_, err = io.Copy(io.Discard, rc)
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+		}
+	})
+
+	t.Run("manifest is not written to the destination repository", func(t *testing.T) {
+		_, err := reg.Digest(dstRepo.Digest(missingImage.Digest))
+		require.Error(t, err)
+	})
+
+	t.Run("images from other registries are skipped", func(t *testing.T) {
+		fakeDstRegistry := helpers.NewFakeRegistryWithRepoSeparation(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeDstRegistry.CleanUp()
+		dstRepo, err := regname.NewRepository(fakeDstRegistry.ReferenceOnTestServer("library/warm"))
+		require.NoError(t, err)
+
+		results, err := subject.Warm([]lockconfig.ImageRef{{Image: missingImage.RefDigest}}, dstRepo)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, warmStatusSkipped, results[0].Status)
+	})
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// ociMediaTypes OCI equivalent of the Docker media types of the blobs of an image
+var ociMediaTypes = map[types.MediaType]types.MediaType{
+	types.DockerConfigJSON:        types.OCIConfigJSON,
+	types.DockerLayer:             types.OCILayer,
+	types.DockerUncompressedLayer: types.OCIUncompressedLayer,
+}
+
+// artifactManifest OCI image manifest that records the type of the artifact it represents
+type artifactManifest struct {
+	SchemaVersion int64             `json:"schemaVersion"`
+	MediaType     types.MediaType   `json:"mediaType,omitempty"`
+	ArtifactType  string            `json:"artifactType"`
+	Config        v1.Descriptor     `json:"config"`
+	Layers        []v1.Descriptor   `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+	Subject       *v1.Descriptor    `json:"subject,omitempty"`
+}
+
+// ArtifactImage Image pushed with an OCI image manifest that records its artifactType, so that registries and
+// scanners can identify it without retrieving its config
+type ArtifactImage struct {
+	v1.Image
+	artifactType string
+
+	rawManifestOnce sync.Once
+	rawManifest     []byte
+	rawManifestErr  error
+}
+
+// NewArtifactImage Wraps the image converting its manifest to an OCI image manifest with the provided artifactType.
+// The config and layers are kept, only their media types are converted to the OCI ones
+func NewArtifactImage(img v1.Image, artifactType string) *ArtifactImage {
+	return &ArtifactImage{Image: img, artifactType: artifactType}
+}
+
+// MediaType Returns the OCI image manifest media type
+func (a *ArtifactImage) MediaType() (types.MediaType, error) {
+	return types.OCIManifestSchema1, nil
+}
+
+// ArtifactType Returns the type of the artifact recorded in the manifest
+func (a *ArtifactImage) ArtifactType() (string, error) {
+	return a.artifactType, nil
+}
+
+// RawManifest Returns the OCI image manifest with the artifactType
+func (a *ArtifactImage) RawManifest() ([]byte, error) {
+	a.rawManifestOnce.Do(func() {
+		a.rawManifest, a.rawManifestErr = a.buildRawManifest()
+	})
+	return a.rawManifest, a.rawManifestErr
+}
+
+// Manifest Returns the OCI image manifest, v1.Manifest does not have a field for the artifactType
+func (a *ArtifactImage) Manifest() (*v1.Manifest, error) {
+	rawManifest, err := a.RawManifest()
+	if err != nil {
+		return nil, err
+	}
+	return v1.ParseManifest(bytes.NewReader(rawManifest))
+}
+
+// Digest Returns the digest of the OCI image manifest
+func (a *ArtifactImage) Digest() (v1.Hash, error) {
+	rawManifest, err := a.RawManifest()
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	digest, _, err := v1.SHA256(bytes.NewReader(rawManifest))
+	return digest, err
+}
+
+// Size Returns the size of the OCI image manifest
+func (a *ArtifactImage) Size() (int64, error) {
+	rawManifest, err := a.RawManifest()
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(rawManifest)), nil
+}
+
+func (a *ArtifactImage) buildRawManifest() ([]byte, error) {
+	manifest, err := a.Image.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	result := artifactManifest{
+		SchemaVersion: manifest.SchemaVersion,
+		MediaType:     types.OCIManifestSchema1,
+		ArtifactType:  a.artifactType,
+		Config:        manifest.Config,
+		Layers:        []v1.Descriptor{},
+		Annotations:   manifest.Annotations,
+		Subject:       manifest.Subject,
+	}
+	if ociMediaType, found := ociMediaTypes[result.Config.MediaType]; found {
+		result.Config.MediaType = ociMediaType
+	}
+	for _, layer := range manifest.Layers {
+		if ociMediaType, found := ociMediaTypes[layer.MediaType]; found {
+			layer.MediaType = ociMediaType
+		}
+		result.Layers = append(result.Layers, layer)
+	}
+	return json.Marshal(result)
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -25,24 +26,40 @@ type DirImage struct {
 	img         regv1.Image
 	shouldChown bool
 	logger      Logger
+	onlyPaths   []string
+	layerCache  *LayerCache
+	concurrency int
 }
 
 // NewDirImage given an OCI Image representation creates a struct that will allow that image to be
 // extracted into the provided directory
 func NewDirImage(dirPath string, img regv1.Image, logger Logger) *DirImage {
-	return &DirImage{dirPath, img, os.Getuid() == 0, logger}
+	return &DirImage{dirPath: dirPath, img: img, shouldChown: os.Getuid() == 0, logger: logger, concurrency: runtime.NumCPU()}
+}
+
+// WithConcurrency sets the maximum number of files written at the same time
+func (i *DirImage) WithConcurrency(concurrency int) *DirImage {
+	i.concurrency = concurrency
+	return i
+}
+
+// OnlyPaths restricts the extraction to the provided paths, and their content, ignoring all other files
+func (i *DirImage) OnlyPaths(paths ...string) *DirImage {
+	i.onlyPaths = paths
+	return i
+}
+
+// WithLayerCache uses the cache to extract images with a single layer
+func (i *DirImage) WithLayerCache(cache *LayerCache) *DirImage {
+	i.layerCache = cache
+	return i
 }
 
 // AsDirectory extracts the OCI image to the provided location in disk
 func (i *DirImage) AsDirectory() error {
-	err := os.RemoveAll(i.dirPath)
+	err := i.createOutputDirectory()
 	if err != nil {
-		return fmt.Errorf("Removing output directory: %s", err)
-	}
-
-	err = os.MkdirAll(i.dirPath, 0777)
-	if err != nil {
-		return fmt.Errorf("Creating output directory: %s", err)
+		return err
 	}
 
 	layers, err := i.img.Layers()
@@ -50,6 +67,25 @@ func (i *DirImage) AsDirectory() error {
 		return err
 	}
 
+	if i.layerCache != nil && len(layers) == 1 && len(i.onlyPaths) == 0 {
+		digest, err := layers[0].Digest()
+		if err != nil {
+			return err
+		}
+
+		i.logger.Logf("Extracting layer '%s' (1/1) using cache\n", digest)
+		err = i.layerCache.extract(layers[0], i.dirPath, i.shouldChown, i.writeLayerTo)
+		if err == nil {
+			return nil
+		}
+
+		i.logger.Logf("Unable to use cache, extracting without it: %s\n", err)
+		err = i.createOutputDirectory()
+		if err != nil {
+			return err
+		}
+	}
+
 	for idx, imgLayer := range layers {
 		digest, err := imgLayer.Digest()
 		if err != nil {
@@ -74,10 +110,29 @@ func (i *DirImage) AsDirectory() error {
 	return nil
 }
 
+func (i *DirImage) createOutputDirectory() error {
+	err := os.RemoveAll(i.dirPath)
+	if err != nil {
+		return fmt.Errorf("Removing output directory: %s", err)
+	}
+
+	err = os.MkdirAll(i.dirPath, 0777)
+	if err != nil {
+		return fmt.Errorf("Creating output directory: %s", err)
+	}
+	return nil
+}
+
+// writeLayerTo extracts the layer into a directory other than the output directory
+func (i *DirImage) writeLayerTo(dirPath string, stream io.Reader) error {
+	return (&DirImage{dirPath: dirPath, img: i.img, shouldChown: i.shouldChown, logger: i.logger, concurrency: i.concurrency}).writeLayer(stream)
+}
+
 // Taken from https://github.com/concourse/registry-image-resource/blob/b5481130ad61bc74e0a74f9b00b287b3a24bab88/cmd/in/unpack.go
 
 func (i *DirImage) writeLayer(stream io.Reader) error {
 	tarReader := tar.NewReader(stream)
+	writer := newParallelEntryWriter(i.concurrency, i.extractTarEntry)
 
 	for {
 		hdr, err := tarReader.Next()
@@ -85,9 +140,14 @@ func (i *DirImage) writeLayer(stream io.Reader) error {
 			if err == io.EOF {
 				break
 			}
+			_ = writer.Wait()
 			return err
 		}
 
+		if !i.isIncluded(hdr.Name) {
+			continue
+		}
+
 		path := i.hydrateFilepath(hdr.Name)
 		base := filepath.Base(path)
 
@@ -96,6 +156,11 @@ func (i *DirImage) writeLayer(stream io.Reader) error {
 		)
 
 		if strings.HasPrefix(base, whiteoutPrefix) {
+			// Files being written might be removed by the whiteout
+			if err := writer.Wait(); err != nil {
+				return err
+			}
+
 			dir := filepath.Dir(path)
 
 			err := os.RemoveAll(filepath.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)))
@@ -105,24 +170,36 @@ func (i *DirImage) writeLayer(stream io.Reader) error {
 			continue
 		}
 
+		if writer.IsPending(path) {
+			// The same path is present multiple times in the layer, the last entry needs to win
+			if err := writer.Wait(); err != nil {
+				return err
+			}
+		}
+
 		if fi, err := os.Lstat(path); err == nil {
 			if fi.IsDir() && hdr.Name == "." {
 				continue
 			}
 			if !(fi.IsDir() && hdr.Typeflag == tar.TypeDir) {
+				// Files being written might be inside the folder being removed
+				if err := writer.Wait(); err != nil {
+					return err
+				}
 				if err := os.RemoveAll(path); err != nil {
 					return err
 				}
 			}
 		}
 
-		err = i.extractTarEntry(hdr, tarReader)
+		err = writer.Write(path, hdr, tarReader)
 		if err != nil {
+			_ = writer.Wait()
 			return err
 		}
 	}
 
-	return nil
+	return writer.Wait()
 }
 
 // Taken from https://github.com/concourse/go-archive/blob/f26802964d15194bddb07bf116ea567c56af973f/tarfs/extract.go
@@ -209,6 +286,22 @@ func lchtimes(header *tar.Header, path string) error {
 	return nil
 }
 
+// isIncluded checks if the tar entry is part of the paths that should be extracted
+func (i *DirImage) isIncluded(fPath string) bool {
+	if len(i.onlyPaths) == 0 {
+		return true
+	}
+
+	entryPath := strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(fPath, "\\", "/")), "/")
+	for _, onlyPath := range i.onlyPaths {
+		onlyPath = strings.TrimPrefix(path.Clean("/"+onlyPath), "/")
+		if entryPath == onlyPath || strings.HasPrefix(entryPath, onlyPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 // hydrateFilepath ensures that the file is correct based on the OS.
 func (i *DirImage) hydrateFilepath(fPath string) string {
 	var lPath string
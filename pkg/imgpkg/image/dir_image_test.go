@@ -4,7 +4,10 @@
 package image_test
 
 import (
+	"archive/tar"
+	"bytes"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -12,6 +15,10 @@ import (
 	"strings"
 	"testing"
 
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/image"
@@ -100,3 +107,137 @@ func TestDirImage(t *testing.T) {
 		})
 	})
 }
+
+func TestDirImageWithLayerCache(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions values are different in windows because of umask")
+	}
+	img, err := image.NewFileImage(filepath.Join("test_assets", "img_tar_with_permissions.tar"), nil)
+	require.NoError(t, err)
+
+	expectedFolder := t.TempDir()
+	require.NoError(t, image.NewDirImage(expectedFolder, img, util.NewNoopLogger()).AsDirectory())
+	expectedFiles := filesInFolder(t, expectedFolder)
+
+	for _, mode := range image.LinkModes {
+		t.Run(fmt.Sprintf("extracts the same files as without cache when using link mode %s", mode), func(t *testing.T) {
+			cacheDir := t.TempDir()
+			cache := image.NewLayerCache(cacheDir, mode)
+
+			firstFolder := t.TempDir()
+			require.NoError(t, image.NewDirImage(firstFolder, img, util.NewNoopLogger()).WithLayerCache(cache).AsDirectory())
+			assert.Equal(t, expectedFiles, filesInFolder(t, firstFolder))
+
+			cachedLayers, err := os.ReadDir(filepath.Join(cacheDir, "layers"))
+			require.NoError(t, err)
+			require.Len(t, cachedLayers, 1)
+
+			secondFolder := t.TempDir()
+			require.NoError(t, image.NewDirImage(secondFolder, img, util.NewNoopLogger()).WithLayerCache(cache).AsDirectory())
+			assert.Equal(t, expectedFiles, filesInFolder(t, secondFolder))
+
+			cachedFile, err := os.Stat(filepath.Join(cacheDir, "layers", cachedLayers[0].Name(), "folder_all", "some_file.txt"))
+			require.NoError(t, err)
+			extractedFile, err := os.Stat(filepath.Join(secondFolder, "folder_all", "some_file.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, mode == image.LinkModeHardlink, os.SameFile(cachedFile, extractedFile))
+		})
+	}
+}
+
+func filesInFolder(t *testing.T, folder string) map[string]string {
+	files := map[string]string{}
+	err := filepath.WalkDir(folder, func(path string, _ fs.DirEntry, err error) error {
+		require.NoError(t, err)
+		if path == folder {
+			return nil
+		}
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+
+		content := ""
+		if info.Mode().IsRegular() {
+			bs, err := os.ReadFile(path)
+			require.NoError(t, err)
+			content = string(bs)
+		}
+		files[strings.TrimPrefix(path, folder)] = info.Mode().String() + " " + content
+		return nil
+	})
+	require.NoError(t, err)
+	return files
+}
+
+func TestDirImageParallelExtraction(t *testing.T) {
+	files := map[string]string{}
+	var entries []tarEntry
+	for idx := 0; idx < 200; idx++ {
+		name := fmt.Sprintf("folder-%d/file-%d.yml", idx%10, idx)
+		files[name] = fmt.Sprintf("content %d", idx)
+		entries = append(entries, tarEntry{name: name, content: files[name]})
+	}
+	// Entries present multiple times in the layer, the last one should be extracted
+	entries = append(entries, tarEntry{name: "folder-1/file-1.yml", content: "overwritten"})
+	files["folder-1/file-1.yml"] = "overwritten"
+	// Whiteouts remove the files written previously
+	entries = append(entries, tarEntry{name: "folder-2/.wh.file-2.yml"})
+	delete(files, "folder-2/file-2.yml")
+	// Big files are not buffered in memory
+	bigContent := strings.Repeat("a", 2*1024*1024)
+	entries = append(entries, tarEntry{name: "big-file", content: bigContent})
+	files["big-file"] = bigContent
+
+	img := imageWithLayer(t, entries)
+
+	for _, concurrency := range []int{1, 8} {
+		t.Run(fmt.Sprintf("extracts all files with concurrency %d", concurrency), func(t *testing.T) {
+			folder := t.TempDir()
+			require.NoError(t, image.NewDirImage(folder, img, util.NewNoopLogger()).WithConcurrency(concurrency).AsDirectory())
+
+			extracted := map[string]string{}
+			err := filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+				require.NoError(t, err)
+				if d.IsDir() {
+					return nil
+				}
+				bs, err := os.ReadFile(path)
+				require.NoError(t, err)
+				relPath, err := filepath.Rel(folder, path)
+				require.NoError(t, err)
+				extracted[filepath.ToSlash(relPath)] = string(bs)
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, files, extracted)
+		})
+	}
+}
+
+type tarEntry struct {
+	name    string
+	content string
+}
+
+func imageWithLayer(t *testing.T, entries []tarEntry) regv1.Image {
+	buf := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(buf)
+	for _, entry := range entries {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{
+			Name:     entry.name,
+			Typeflag: tar.TypeReg,
+			Mode:     0600,
+			Size:     int64(len(entry.content)),
+		}))
+		_, err := tarWriter.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	})
+	require.NoError(t, err)
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	require.NoError(t, err)
+	return img
+}
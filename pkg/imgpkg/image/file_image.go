@@ -9,30 +9,96 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
+	"github.com/google/go-containerregistry/pkg/compression"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 )
 
+// Compressions All the algorithms that can be used to compress the layer of a FileImage
+var Compressions = []compression.Compression{compression.GZip, compression.ZStd, compression.None}
+
+// LayerCompression Algorithm and level used to compress the layer of a FileImage. The zero value uses gzip with its
+// default level
+type LayerCompression struct {
+	Algorithm compression.Compression
+	// Level when 0 the default level of the algorithm is used
+	Level int
+}
+
+// Validate Checks that the algorithm is known and that the level is supported by it
+func (c LayerCompression) Validate() error {
+	switch c.Algorithm {
+	case "", compression.GZip:
+		if c.Level < 0 || c.Level > 9 {
+			return fmt.Errorf("Expected gzip compression level to be between 1 and 9, but got %d", c.Level)
+		}
+	case compression.ZStd:
+		if c.Level < 0 || c.Level > 22 {
+			return fmt.Errorf("Expected zstd compression level to be between 1 and 22, but got %d", c.Level)
+		}
+	case compression.None:
+		if c.Level != 0 {
+			return fmt.Errorf("Expected no compression level when layers are not compressed")
+		}
+	default:
+		var known []string
+		for _, algorithm := range Compressions {
+			known = append(known, string(algorithm))
+		}
+		return fmt.Errorf("Unknown compression '%s' (known: %s)", c.Algorithm, strings.Join(known, ", "))
+	}
+	return nil
+}
+
 type FileImage struct {
 	v1.Image
 	path string
 }
 
 func NewFileImage(path string, labels map[string]string) (*FileImage, error) {
-	sha256, err := sha256Path(path)
+	return NewCompressedFileImage(path, labels, LayerCompression{})
+}
+
+// NewCompressedFileImage Creates an image with a single layer, built from the tarball in path and compressed with
+// the provided compression. Images with zstd or uncompressed layers use the OCI media types, since Docker manifests
+// do not support them
+func NewCompressedFileImage(path string, labels map[string]string, layerCompression LayerCompression) (*FileImage, error) {
+	err := layerCompression.Validate()
 	if err != nil {
 		return nil, err
 	}
 
-	layer, err := partial.UncompressedToLayer(&UncompressedFileLayer{
-		diffID:    v1.Hash{Algorithm: "sha256", Hex: sha256},
-		mediaType: types.DockerLayer,
-		path:      path,
-	})
+	sha256, err := sha256Path(path)
+	if err != nil {
+		return nil, err
+	}
+	diffID := v1.Hash{Algorithm: "sha256", Hex: sha256}
+
+	var layer v1.Layer
+	switch {
+	case layerCompression.Algorithm == compression.None:
+		layer, err = partial.CompressedToLayer(&NotCompressedFileLayer{diffID: diffID, path: path})
+	case layerCompression.Algorithm == compression.ZStd:
+		opts := []tarball.LayerOption{tarball.WithCompression(compression.ZStd), tarball.WithMediaType(types.OCILayerZStd)}
+		if layerCompression.Level > 0 {
+			opts = append(opts, tarball.WithCompressionLevel(layerCompression.Level))
+		}
+		layer, err = tarball.LayerFromFile(path, opts...)
+	case layerCompression.Level > 0:
+		layer, err = tarball.LayerFromFile(path, tarball.WithCompressionLevel(layerCompression.Level))
+	default:
+		layer, err = partial.UncompressedToLayer(&UncompressedFileLayer{
+			diffID:    diffID,
+			mediaType: types.DockerLayer,
+			path:      path,
+		})
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -50,6 +116,9 @@ func NewFileImage(path string, labels map[string]string) (*FileImage, error) {
 	if err != nil {
 		return nil, err
 	}
+	if layerCompression.Algorithm == compression.ZStd || layerCompression.Algorithm == compression.None {
+		img = mutate.ConfigMediaType(mutate.MediaType(img, types.OCIManifestSchema1), types.OCIConfigJSON)
+	}
 
 	if len(labels) > 0 {
 		cfg, err := img.ConfigFile()
@@ -0,0 +1,78 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package image_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/compression"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/image"
+)
+
+func TestNewCompressedFileImage(t *testing.T) {
+	tarPath := filepath.Join("test_assets", "img_tar_with_permissions.tar")
+	tarContent, err := os.ReadFile(tarPath)
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		compression       image.LayerCompression
+		manifestMediaType types.MediaType
+		layerMediaType    types.MediaType
+	}{
+		"gzip by default":      {image.LayerCompression{}, types.DockerManifestSchema2, types.DockerLayer},
+		"gzip with a level":    {image.LayerCompression{Algorithm: compression.GZip, Level: 9}, types.DockerManifestSchema2, types.DockerLayer},
+		"zstd":                 {image.LayerCompression{Algorithm: compression.ZStd}, types.OCIManifestSchema1, types.OCILayerZStd},
+		"zstd with a level":    {image.LayerCompression{Algorithm: compression.ZStd, Level: 19}, types.OCIManifestSchema1, types.OCILayerZStd},
+		"not compressed layer": {image.LayerCompression{Algorithm: compression.None}, types.OCIManifestSchema1, types.OCIUncompressedLayer},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			img, err := image.NewCompressedFileImage(tarPath, map[string]string{"some": "label"}, tc.compression)
+			require.NoError(t, err)
+
+			mediaType, err := img.MediaType()
+			require.NoError(t, err)
+			assert.Equal(t, tc.manifestMediaType, mediaType)
+
+			layers, err := img.Layers()
+			require.NoError(t, err)
+			require.Len(t, layers, 1)
+			layerMediaType, err := layers[0].MediaType()
+			require.NoError(t, err)
+			assert.Equal(t, tc.layerMediaType, layerMediaType)
+
+			uncompressed, err := layers[0].Uncompressed()
+			require.NoError(t, err)
+			defer uncompressed.Close()
+			content, err := io.ReadAll(uncompressed)
+			require.NoError(t, err)
+			assert.Equal(t, tarContent, content, "the layer content should be the tarball")
+
+			cfg, err := img.ConfigFile()
+			require.NoError(t, err)
+			assert.Equal(t, map[string]string{"some": "label"}, cfg.Config.Labels)
+		})
+	}
+
+	t.Run("the layer is stored as is when not compressed", func(t *testing.T) {
+		img, err := image.NewCompressedFileImage(tarPath, nil, image.LayerCompression{Algorithm: compression.None})
+		require.NoError(t, err)
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		digest, err := layers[0].Digest()
+		require.NoError(t, err)
+		diffID, err := layers[0].DiffID()
+		require.NoError(t, err)
+		assert.Equal(t, diffID, digest)
+		size, err := layers[0].Size()
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(tarContent)), size)
+	})
+}
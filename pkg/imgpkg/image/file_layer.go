@@ -32,3 +32,40 @@ func (ul *UncompressedFileLayer) Uncompressed() (io.ReadCloser, error) {
 func (ul *UncompressedFileLayer) MediaType() (regtypes.MediaType, error) {
 	return ul.mediaType, nil
 }
+
+// NotCompressedFileLayer Layer that is stored and transferred without compression, so its digest is its diffID
+type NotCompressedFileLayer struct {
+	diffID regv1.Hash
+	path   string
+}
+
+var _ regpartial.CompressedLayer = (*NotCompressedFileLayer)(nil)
+
+// Digest Returns the digest of the tarball
+func (l *NotCompressedFileLayer) Digest() (regv1.Hash, error) {
+	return l.diffID, nil
+}
+
+// DiffID Returns the digest of the tarball
+func (l *NotCompressedFileLayer) DiffID() (regv1.Hash, error) {
+	return l.diffID, nil
+}
+
+// Compressed Returns the content of the tarball
+func (l *NotCompressedFileLayer) Compressed() (io.ReadCloser, error) {
+	return os.Open(l.path)
+}
+
+// Size Returns the size of the tarball
+func (l *NotCompressedFileLayer) Size() (int64, error) {
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// MediaType Returns the media type of uncompressed OCI layers
+func (l *NotCompressedFileLayer) MediaType() (regtypes.MediaType, error) {
+	return regtypes.OCIUncompressedLayer, nil
+}
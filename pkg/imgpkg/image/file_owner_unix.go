@@ -0,0 +1,21 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package image
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// chownLike changes the owner of path to the owner of the file described by info
+func chownLike(path string, info fs.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	return os.Lchown(path, int(stat.Uid), int(stat.Gid))
+}
@@ -0,0 +1,13 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"io/fs"
+)
+
+// chownLike does nothing, files in windows are not owned by a uid and gid
+func chownLike(_ string, _ fs.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// LinkMode How files are placed in the output directory when they are already present in the LayerCache
+type LinkMode string
+
+const (
+	// LinkModeCopy Files are copied from the cache
+	LinkModeCopy LinkMode = "copy"
+	// LinkModeReflink Files are cloned from the cache (btrfs, xfs, APFS), falling back to copying them
+	LinkModeReflink LinkMode = "reflink"
+	// LinkModeHardlink Files are cloned or, when not possible, hard linked to the cache. Changing a hard linked file
+	// also changes the file in the cache, so it should only be used when the extracted files are not modified
+	LinkModeHardlink LinkMode = "hardlink"
+)
+
+// LinkModes All the supported link modes
+var LinkModes = []LinkMode{LinkModeCopy, LinkModeReflink, LinkModeHardlink}
+
+var errReflinkNotSupported = errors.New("reflinks are not supported on this platform")
+
+// LayerCache Keeps the extracted content of layers on disk so that following extractions of the same layer
+// do not need to download and decompress it again
+type LayerCache struct {
+	dir  string
+	mode LinkMode
+}
+
+// NewLayerCache constructor for LayerCache, the extracted layers are stored in the layers folder inside cacheDir
+func NewLayerCache(cacheDir string, mode LinkMode) *LayerCache {
+	return &LayerCache{dir: filepath.Join(cacheDir, "layers"), mode: mode}
+}
+
+// extract places the content of the layer in dirPath, extracting the layer into the cache if not present yet.
+// When shouldChown is true the files keep the owner they have in the cache
+func (c *LayerCache) extract(layer regv1.Layer, dirPath string, shouldChown bool, writeLayer func(dirPath string, stream io.Reader) error) error {
+	digest, err := layer.Digest()
+	if err != nil {
+		return err
+	}
+
+	cachedPath := filepath.Join(c.dir, digest.Algorithm+"-"+digest.Hex)
+	if _, err := os.Stat(cachedPath); err != nil {
+		err := c.populate(layer, cachedPath, writeLayer)
+		if err != nil {
+			return fmt.Errorf("Caching layer %s: %s", digest, err)
+		}
+	}
+
+	return c.linkTree(cachedPath, dirPath, shouldChown)
+}
+
+// populate extracts the layer into a temporary folder that is renamed when complete, to ensure the cache
+// never contains partially extracted layers
+func (c *LayerCache) populate(layer regv1.Layer, cachedPath string, writeLayer func(dirPath string, stream io.Reader) error) error {
+	err := os.MkdirAll(c.dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp(c.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	layerStream, err := layer.Uncompressed()
+	if err != nil {
+		return err
+	}
+	defer layerStream.Close()
+
+	err = writeLayer(tmpDir, layerStream)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpDir, cachedPath)
+	if err != nil {
+		if _, statErr := os.Stat(cachedPath); statErr == nil {
+			// Another process cached the same layer in the meantime
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (c *LayerCache) linkTree(cachedPath, dirPath string, shouldChown bool) error {
+	return filepath.WalkDir(cachedPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(cachedPath, path)
+		if err != nil {
+			return err
+		}
+		dstPath := filepath.Join(dirPath, relPath)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.IsDir():
+			err = os.MkdirAll(dstPath, info.Mode().Perm())
+		case info.Mode().IsRegular():
+			err = c.linkFile(path, dstPath, info)
+		default:
+			return nil
+		}
+		if err != nil || !shouldChown {
+			return err
+		}
+		return chownLike(dstPath, info)
+	})
+}
+
+func (c *LayerCache) linkFile(src, dst string, info fs.FileInfo) error {
+	if c.mode == LinkModeReflink || c.mode == LinkModeHardlink {
+		if err := reflink(src, dst); err == nil {
+			return restoreFileInfo(dst, info)
+		}
+		// Some implementations leave an empty file behind when cloning is not supported
+		_ = os.Remove(dst)
+	}
+
+	if c.mode == LinkModeHardlink {
+		if err := os.Link(src, dst); err == nil {
+			return nil
+		}
+	}
+
+	return copyFile(src, dst, info)
+}
+
+func copyFile(src, dst string, info fs.FileInfo) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dstFile, srcFile)
+	if err != nil {
+		_ = dstFile.Close()
+		return err
+	}
+
+	err = dstFile.Close()
+	if err != nil {
+		return err
+	}
+	return restoreFileInfo(dst, info)
+}
+
+func restoreFileInfo(path string, info fs.FileInfo) error {
+	err := os.Chmod(path, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(path, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// maxBufferedEntrySize Files up to this size are read into memory and written in parallel,
+// bigger files are written as they are read from the layer
+const maxBufferedEntrySize = 1024 * 1024
+
+// parallelEntryWriter Writes the small files of a layer concurrently while the layer is still being read.
+// Folders and big files are written immediately, in the order they appear in the layer
+type parallelEntryWriter struct {
+	group      *errgroup.Group
+	pending    map[string]struct{}
+	writeEntry func(header *tar.Header, input io.Reader) error
+	parallel   bool
+}
+
+func newParallelEntryWriter(concurrency int, writeEntry func(header *tar.Header, input io.Reader) error) *parallelEntryWriter {
+	group := &errgroup.Group{}
+	if concurrency > 1 {
+		group.SetLimit(concurrency)
+	}
+	return &parallelEntryWriter{
+		group:      group,
+		pending:    map[string]struct{}{},
+		writeEntry: writeEntry,
+		parallel:   concurrency > 1,
+	}
+}
+
+// Write writes the entry to path, possibly after returning
+func (p *parallelEntryWriter) Write(path string, header *tar.Header, input io.Reader) error {
+	isSmallFile := (header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA) && header.Size <= maxBufferedEntrySize
+	if !p.parallel || !isSmallFile {
+		return p.writeEntry(header, input)
+	}
+
+	content := make([]byte, header.Size)
+	_, err := io.ReadFull(input, content)
+	if err != nil {
+		return err
+	}
+
+	p.pending[path] = struct{}{}
+	p.group.Go(func() error {
+		return p.writeEntry(header, bytes.NewReader(content))
+	})
+	return nil
+}
+
+// IsPending checks if path is still being written
+func (p *parallelEntryWriter) IsPending(path string) bool {
+	_, found := p.pending[path]
+	return found
+}
+
+// Wait blocks until all the files are written and returns the first error that happened
+func (p *parallelEntryWriter) Wait() error {
+	err := p.group.Wait()
+	p.pending = map[string]struct{}{}
+	return err
+}
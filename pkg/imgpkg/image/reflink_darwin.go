@@ -0,0 +1,13 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// reflink creates dst sharing the data blocks of src, only supported by APFS
+func reflink(src, dst string) error {
+	return unix.Clonefile(src, dst, unix.CLONE_NOFOLLOW)
+}
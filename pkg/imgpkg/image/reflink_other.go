@@ -0,0 +1,11 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux && !darwin
+
+package image
+
+// reflink is not supported on this platform
+func reflink(_, _ string) error {
+	return errReflinkNotSupported
+}
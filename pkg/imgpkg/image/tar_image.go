@@ -7,12 +7,13 @@ import (
 	"archive/tar"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/workspace"
 )
 
 type TarImage struct {
@@ -20,16 +21,30 @@ type TarImage struct {
 	excludePaths    []string
 	logger          Logger
 	keepPermissions bool
+	workspace       *workspace.Workspace
+	compression     LayerCompression
 }
 
 // NewTarImage creates a struct that will allow users to create a representation of a set of paths as an OCI Image
 func NewTarImage(files []string, excludePaths []string, logger Logger, keepPermissions bool) *TarImage {
-	return &TarImage{files, excludePaths, logger, keepPermissions}
+	return &TarImage{files: files, excludePaths: excludePaths, logger: logger, keepPermissions: keepPermissions}
+}
+
+// WithWorkspace Creates the temporary tarball of the image in the workspace
+func (i *TarImage) WithWorkspace(ws *workspace.Workspace) *TarImage {
+	i.workspace = ws
+	return i
+}
+
+// WithCompression Compresses the layer of the image with the provided compression instead of gzip
+func (i *TarImage) WithCompression(compression LayerCompression) *TarImage {
+	i.compression = compression
+	return i
 }
 
 // AsFileImage Creates an OCI Image representation of the provided folders
 func (i *TarImage) AsFileImage(labels map[string]string) (*FileImage, error) {
-	tmpFile, err := ioutil.TempFile("", "imgpkg-tar-image")
+	tmpFile, err := i.workspace.CreateTemp("imgpkg-tar-image")
 	if err != nil {
 		return nil, err
 	}
@@ -48,7 +63,7 @@ func (i *TarImage) AsFileImage(labels map[string]string) (*FileImage, error) {
 		return nil, err
 	}
 
-	fileImg, err := NewFileImage(tmpFile.Name(), labels)
+	fileImg, err := NewCompressedFileImage(tmpFile.Name(), labels, i.compression)
 	if err != nil {
 		_ = os.Remove(tmpFile.Name())
 		return nil, err
@@ -4,6 +4,7 @@
 package imagedesc
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -16,6 +17,7 @@ import (
 	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
 	regtran "github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	regtypes "github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/tracing"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
 	"golang.org/x/sync/errgroup"
 )
@@ -36,6 +38,8 @@ type Metadata struct {
 
 type ImageRefDescriptors struct {
 	registry Registry
+	// platforms when present limits the images of an index to the ones for these platforms
+	platforms []regv1.Platform
 
 	descs []ImageOrImageIndexDescriptor
 
@@ -55,10 +59,17 @@ func NewImageRefDescriptorsFromBytes(data []byte) (*ImageRefDescriptors, error)
 }
 
 func NewImageRefDescriptors(refs []Metadata, registry Registry) (*ImageRefDescriptors, error) {
+	return NewImageRefDescriptorsWithPlatforms(refs, registry, nil)
+}
+
+// NewImageRefDescriptorsWithPlatforms Describes the images, removing from the indexes the images that are not for the
+// provided platforms. Indexes that had images removed have a different digest than in the registry
+func NewImageRefDescriptorsWithPlatforms(refs []Metadata, registry Registry, platforms []regv1.Platform) (*ImageRefDescriptors, error) {
 	registry = errRegistry{registry}
 
 	imageRefDescs := &ImageRefDescriptors{
 		registry:    registry,
+		platforms:   platforms,
 		imageLayers: map[ImageLayerDescriptor]regv1.Layer{},
 	}
 
@@ -69,10 +80,13 @@ func NewImageRefDescriptors(refs []Metadata, registry Registry) (*ImageRefDescri
 	for _, ref := range refs {
 		ref := ref //copy
 
-		wg.Go(func() error {
+		wg.Go(func() (err error) {
 			buildThrottle.Take()
 			defer buildThrottle.Done()
 
+			span := tracing.Start("imgpkg.image.describe", tracing.ImageRefKey.String(ref.Ref.Name()))
+			defer func() { tracing.End(span, err) }()
+
 			regDesc, err := registry.Get(ref.Ref)
 			if err != nil {
 				return err
@@ -141,6 +155,8 @@ func (ids *ImageRefDescriptors) buildImageIndex(ref Metadata, regDesc regv1.Desc
 		return td, err
 	}
 
+	thinned := false
+	var manifests []regv1.Descriptor
 	for _, manDesc := range imgIndexManifest.Manifests {
 		if ids.isImageIndex(manDesc) {
 			imgIndexTd, err := ids.buildImageIndex(Metadata{ids.buildRef(ref.Ref, manDesc.Digest.String()), ref.Tag, ref.Labels, ref.OrigRef}, manDesc)
@@ -148,15 +164,56 @@ func (ids *ImageRefDescriptors) buildImageIndex(ref Metadata, regDesc regv1.Desc
 				return ImageIndexDescriptor{}, err
 			}
 			td.Indexes = append(td.Indexes, imgIndexTd)
+
+			if imgIndexTd.Digest != manDesc.Digest.String() {
+				thinned = true
+				manDesc.Digest, err = regv1.NewHash(imgIndexTd.Digest)
+				if err != nil {
+					return ImageIndexDescriptor{}, err
+				}
+				manDesc.Size = int64(len(imgIndexTd.Raw))
+			}
 		} else {
+			if !ids.matchesPlatforms(manDesc) {
+				thinned = true
+				continue
+			}
 			imgTd, err := ids.buildImage(Metadata{ids.buildRef(ref.Ref, manDesc.Digest.String()), ref.Tag, ref.Labels, ref.OrigRef})
 			if err != nil {
 				return ImageIndexDescriptor{}, err
 			}
 			td.Images = append(td.Images, imgTd)
 		}
+		manifests = append(manifests, manDesc)
 	}
 
+	if thinned {
+		return ids.thinImageIndex(td, imgIndexManifest, manifests)
+	}
+	return td, nil
+}
+
+// thinImageIndex Replaces the manifest of the index with one that only references the provided manifests,
+// the result has a different digest than the index in the registry
+func (ids *ImageRefDescriptors) thinImageIndex(td ImageIndexDescriptor, imgIndexManifest *regv1.IndexManifest, manifests []regv1.Descriptor) (ImageIndexDescriptor, error) {
+	if len(manifests) == 0 {
+		return ImageIndexDescriptor{}, fmt.Errorf("Expected index '%s' to contain images for the platforms %s", td.Refs[0], ids.platformsString())
+	}
+
+	thinnedManifest := *imgIndexManifest
+	thinnedManifest.Manifests = manifests
+	rawManifest, err := json.Marshal(thinnedManifest)
+	if err != nil {
+		return ImageIndexDescriptor{}, err
+	}
+
+	digest, _, err := regv1.SHA256(bytes.NewReader(rawManifest))
+	if err != nil {
+		return ImageIndexDescriptor{}, err
+	}
+
+	td.Raw = string(rawManifest)
+	td.Digest = digest.String()
 	return td, nil
 }
 
@@ -248,6 +305,28 @@ func (ids *ImageRefDescriptors) buildImage(ref Metadata) (ImageDescriptor, error
 	return td, nil
 }
 
+// matchesPlatforms checks if the image of an index is for one of the requested platforms,
+// images without a platform are always included
+func (ids *ImageRefDescriptors) matchesPlatforms(manDesc regv1.Descriptor) bool {
+	if len(ids.platforms) == 0 || manDesc.Platform == nil {
+		return true
+	}
+	for _, platform := range ids.platforms {
+		if manDesc.Platform.Satisfies(platform) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ids *ImageRefDescriptors) platformsString() string {
+	var platforms []string
+	for _, platform := range ids.platforms {
+		platforms = append(platforms, platform.String())
+	}
+	return strings.Join(platforms, ", ")
+}
+
 func (*ImageRefDescriptors) isImageIndex(regDesc regv1.Descriptor) bool {
 	switch regDesc.MediaType {
 	case regtypes.OCIImageIndex, regtypes.DockerManifestList:
@@ -0,0 +1,56 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"time"
+)
+
+// EventType Stage of the copy of an image. The values are stable and can be used by other tools
+type EventType string
+
+const (
+	// EventImageStarted The image is going to be copied to the destination
+	EventImageStarted EventType = "image-started"
+	// EventImageSkipped The image was not copied, Event.Reason contains the reason
+	EventImageSkipped EventType = "image-skipped"
+	// EventImageCompleted The image was copied and its digest verified in the destination
+	EventImageCompleted EventType = "image-completed"
+	// EventImageFailed The image could not be copied, Event.Error contains the reason
+	EventImageFailed EventType = "image-failed"
+)
+
+// Event Change in the state of the copy of a single image
+type Event struct {
+	Type EventType `json:"type"`
+	// Source Digest reference of the image being copied
+	Source string `json:"source"`
+	// Destination Digest reference of the image in the destination, only present when the copy completed
+	Destination string    `json:"destination,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// EventListener Receives the events generated while copying images.
+// OnEvent can be called concurrently from multiple goroutines
+type EventListener interface {
+	OnEvent(Event)
+}
+
+// EventListenerFunc Adapter to allow the use of functions as EventListener
+type EventListenerFunc func(Event)
+
+// OnEvent calls f(event)
+func (f EventListenerFunc) OnEvent(event Event) { f(event) }
+
+// NoopEventListener Ignores all events
+type NoopEventListener struct{}
+
+// OnEvent does nothing
+func (NoopEventListener) OnEvent(Event) {}
+
+func newEvent(eventType EventType, source string) Event {
+	return Event{Type: eventType, Source: source, Time: time.Now().UTC()}
+}
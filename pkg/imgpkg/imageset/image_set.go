@@ -12,6 +12,7 @@ import (
 	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagedesc"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagedigest"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/tracing"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
 )
@@ -24,11 +25,39 @@ type ImageSet struct {
 	concurrency int
 	logger      Logger
 	tagGen      util.TagGenerator
+	listener    EventListener
+	platforms   []regv1.Platform
 }
 
 // NewImageSet constructor for creating an ImageSet
 func NewImageSet(concurrency int, logger Logger, tagGen util.TagGenerator) ImageSet {
-	return ImageSet{concurrency, logger, tagGen}
+	return ImageSet{concurrency: concurrency, logger: logger, tagGen: tagGen, listener: NoopEventListener{}}
+}
+
+// WithEventListener returns a copy of the ImageSet that reports the progress of the copy of each image to listener
+func (i ImageSet) WithEventListener(listener EventListener) ImageSet {
+	i.listener = listener
+	return i
+}
+
+// WithPlatforms returns a copy of the ImageSet that, from each index, only exports the images for the provided platforms
+func (i ImageSet) WithPlatforms(platforms []regv1.Platform) ImageSet {
+	i.platforms = platforms
+	return i
+}
+
+func (i ImageSet) emit(event Event) {
+	if i.listener != nil {
+		i.listener.OnEvent(event)
+	}
+}
+
+func (i ImageSet) emitFailed(imgOrIndexes []imagedesc.ImageOrIndex, err error) {
+	for _, item := range imgOrIndexes {
+		event := newEvent(EventImageFailed, item.Ref())
+		event.Error = err.Error()
+		i.emit(event)
+	}
 }
 
 func (i ImageSet) Relocate(foundImages *UnprocessedImageRefs,
@@ -63,7 +92,7 @@ func (i ImageSet) Export(foundImages *UnprocessedImageRefs,
 		refs = append(refs, imagedesc.Metadata{Ref: ref, Tag: img.Tag, Labels: img.Labels, OrigRef: img.OrigRef})
 	}
 
-	ids, err := imagedesc.NewImageRefDescriptors(refs, imagesMetadata)
+	ids, err := imagedesc.NewImageRefDescriptorsWithPlatforms(refs, imagesMetadata, i.platforms)
 	if err != nil {
 		return nil, fmt.Errorf("Collecting packaging metadata: %s", err)
 	}
@@ -86,6 +115,8 @@ func (i *ImageSet) Import(imgOrIndexes []imagedesc.ImageOrIndex,
 	for _, item := range imgOrIndexes {
 		item := item // copy
 
+		i.emit(newEvent(EventImageStarted, item.Ref()))
+
 		go func() {
 			importThrottle.Take()
 			defer importThrottle.Done()
@@ -104,11 +135,15 @@ func (i *ImageSet) Import(imgOrIndexes []imagedesc.ImageOrIndex,
 
 	err := checkForAnyAsyncErrors(imgOrIndexes, errCh)
 	if err != nil {
+		i.emitFailed(imgOrIndexes, err)
 		return nil, err
 	}
 
+	writeSpan := tracing.Start("imgpkg.images.write", tracing.ImagesCountKey.Int(len(imageOrIndexesToWrite)))
 	err = registry.MultiWrite(imageOrIndexesToWrite, i.concurrency, nil)
+	tracing.End(writeSpan, err)
 	if err != nil {
+		i.emitFailed(imgOrIndexes, err)
 		return nil, err
 	}
 
@@ -120,9 +155,18 @@ func (i *ImageSet) Import(imgOrIndexes []imagedesc.ImageOrIndex,
 			importThrottle.Take()
 			defer importThrottle.Done()
 
+			span := tracing.Start("imgpkg.image.verify", tracing.ImageRefKey.String(item.Ref()))
 			processedImage, err := i.verifyImageOrIndex(item, importRepo, registry)
+			tracing.End(span, err)
 			if err == nil {
 				importedImages.Add(processedImage)
+				event := newEvent(EventImageCompleted, item.Ref())
+				event.Destination = processedImage.DigestRef
+				i.emit(event)
+			} else {
+				event := newEvent(EventImageFailed, item.Ref())
+				event.Error = err.Error()
+				i.emit(event)
 			}
 			errChVerifyImages <- err
 		}()
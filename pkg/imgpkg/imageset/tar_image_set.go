@@ -13,21 +13,53 @@ import (
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagedesc"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagetar"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/workspace"
 )
 
 type TarImageSet struct {
 	imageSet    ImageSet
 	concurrency int
 	logger      Logger
+
+	fallbackToOrigin bool
+	workspace        *workspace.Workspace
+	baseTar          string
 }
 
 // NewTarImageSet provides export/import operations on a tarball for a set of images
 func NewTarImageSet(imageSet ImageSet, concurrency int, logger Logger) TarImageSet {
-	return TarImageSet{imageSet, concurrency, logger}
+	return TarImageSet{imageSet: imageSet, concurrency: concurrency, logger: logger}
+}
+
+// WithFallbackToOrigin When importing, retrieves the layers missing from the tar from the repositories the images were exported from
+func (i TarImageSet) WithFallbackToOrigin() TarImageSet {
+	i.fallbackToOrigin = true
+	return i
+}
+
+// WithBaseTar When exporting, only writes the layers that are not present in the tar in path, previously exported,
+// creating a delta tar. When importing, reads the layers that are not present in a delta tar from the tar in path
+func (i TarImageSet) WithBaseTar(path string) TarImageSet {
+	i.baseTar = path
+	return i
+}
+
+// WithWorkspace Creates the temporary files used during the export in the workspace
+func (i TarImageSet) WithWorkspace(ws *workspace.Workspace) TarImageSet {
+	i.workspace = ws
+	return i
 }
 
 // Export Creates a Tar with the provided Images
 func (i TarImageSet) Export(foundImages *UnprocessedImageRefs, outputPath string, registry registry.ImagesReaderWriter, imageLayerWriterCheck imagetar.ImageLayerWriterFilter, resume bool) (d *imagedesc.ImageRefDescriptors, err error) {
+	var base *imagetar.BaseTar
+	if i.baseTar != "" {
+		base, err = imagetar.OpenBaseTar(i.baseTar)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	ids, err := i.imageSet.Export(foundImages, registry)
 	if err != nil {
 		return nil, err
@@ -36,6 +68,12 @@ func (i TarImageSet) Export(foundImages *UnprocessedImageRefs, outputPath string
 	var outputFile *os.File
 	var alreadyDownloadedLayers []v1.Layer
 
+	checkpoint := imagetar.NewTarCheckpoint(outputPath)
+	previousCheckpoint, err := checkpoint.Read()
+	if err != nil {
+		return nil, err
+	}
+
 	// this temporary file is used only in the case were we are resuming the copy of an image to a tar
 	// we are creating a temporary copy of the existing tar. This is done to be able to read the layers
 	// when we are filling up the destination tar.
@@ -45,7 +83,7 @@ func (i TarImageSet) Export(foundImages *UnprocessedImageRefs, outputPath string
 		// This will just follow the normal path of resume == false
 		outputFile, err = os.Open(outputPath)
 		if err == nil {
-			tmpFile, err = os.CreateTemp("", "imgpkg-tar-imageset-")
+			tmpFile, err = i.workspace.CreateTemp("imgpkg-tar-imageset-")
 			if err != nil {
 				return nil, fmt.Errorf("Creating tmp folder: %s", err)
 			}
@@ -65,7 +103,12 @@ func (i TarImageSet) Export(foundImages *UnprocessedImageRefs, outputPath string
 				return nil, err
 			}
 
-			alreadyDownloadedLayers, err = imagetar.NewTarReader(tmpFile.Name()).PresentLayers()
+			if previousCheckpoint != nil {
+				// The checkpoint lists the layers that were completely written, so they do not need to be verified
+				alreadyDownloadedLayers, err = checkpoint.Layers(tmpFile.Name())
+			} else {
+				alreadyDownloadedLayers, err = imagetar.NewTarReader(tmpFile.Name()).WithBaseTar(i.baseTar).PresentLayers()
+			}
 			if err != nil {
 				return nil, fmt.Errorf("Reading previously created tar '%s': %s", outputPath, err)
 			}
@@ -74,6 +117,12 @@ func (i TarImageSet) Export(foundImages *UnprocessedImageRefs, outputPath string
 		}
 	}
 
+	// The layers of the previous tar are recorded again when they are written to the new tar
+	err = checkpoint.Remove()
+	if err != nil {
+		return nil, err
+	}
+
 	outputFile, err = os.Create(outputPath)
 	if err != nil {
 		return nil, fmt.Errorf("Creating file '%s': %s", outputPath, err)
@@ -107,6 +156,13 @@ func (i TarImageSet) Export(foundImages *UnprocessedImageRefs, outputPath string
 				err = fmt.Errorf("original error: %s, post exit error: %s", err, err1)
 				return
 			}
+
+			// The checkpoint needs to match the restored tar
+			err1 = checkpoint.Restore(previousCheckpoint)
+			if err1 != nil {
+				err = fmt.Errorf("original error: %s, post exit error: %s", err, err1)
+				return
+			}
 		}
 	}()
 
@@ -116,15 +172,39 @@ func (i TarImageSet) Export(foundImages *UnprocessedImageRefs, outputPath string
 
 	i.logger.Logf("writing layers...\n")
 
-	opts := imagetar.TarWriterOpts{Concurrency: i.concurrency}
+	opts := imagetar.TarWriterOpts{Concurrency: i.concurrency, Checkpoint: checkpoint, Base: base}
 
 	err = imagetar.NewTarWriter(ids, outputFileOpener, opts, i.logger, imageLayerWriterCheck, alreadyDownloadedLayers).Write()
-	return ids, err
+	if err != nil {
+		return ids, err
+	}
+	return ids, checkpoint.Remove()
+}
+
+// ExportToBlobStore Exports the provided Images to a BlobStore, e.g. a folder that can be imported by a registry
+func (i TarImageSet) ExportToBlobStore(foundImages *UnprocessedImageRefs, store imagetar.BlobStore, registry registry.ImagesReaderWriter, imageLayerWriterCheck imagetar.ImageLayerWriterFilter) (*imagedesc.ImageRefDescriptors, error) {
+	ids, err := i.imageSet.Export(foundImages, registry)
+	if err != nil {
+		return nil, err
+	}
+
+	i.logger.Logf("writing layers...\n")
+
+	err = imagetar.NewWriter(ids, store, imageLayerWriterCheck, nil).Write()
+	if err != nil {
+		return ids, err
+	}
+	return ids, nil
 }
 
 // Import Copy tar with Images to the Registry
 func (i *TarImageSet) Import(path string, importRepo regname.Repository, registry registry.ImagesReaderWriter) (*ProcessedImages, error) {
-	imgOrIndexes, err := imagetar.NewTarReader(path).Read()
+	tarReader := imagetar.NewTarReader(path).WithBaseTar(i.baseTar)
+	if i.fallbackToOrigin {
+		tarReader = tarReader.WithOriginFallback(registry, i.logger)
+	}
+
+	imgOrIndexes, err := tarReader.Read()
 	if err != nil {
 		return nil, err
 	}
@@ -11,6 +11,9 @@ import (
 	regname "github.com/google/go-containerregistry/pkg/name"
 )
 
+// RootBundleLabelKey Label that marks the bundle being copied, so that it can be found among the copied images
+const RootBundleLabelKey = "dev.carvel.imgpkg.copy.root-bundle"
+
 type UnprocessedImageRef struct {
 	DigestRef string
 	Tag       string
@@ -0,0 +1,269 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagedesc"
+)
+
+// ArtifactoryImportBlobStore BlobStore that writes the images to a folder following the layout Artifactory uses to
+// store a repository of a Docker registry, so that the folder can be imported with the "Import Repository Content"
+// function of Artifactory instead of pushing every blob through the registry API.
+//
+// The folder represents a single image path inside the Artifactory Docker repository. Each image is stored in a folder
+// named after its tag, containing manifest.json (list.manifest.json for indexes), the config and the layers, named
+// sha256__<hex>. Every image is tagged with the same tag used by imgpkg when copying to a repository, and with its
+// original tag when it has one. Images that are only referenced by an index are stored in a folder named after their
+// digest. Every file is accompanied by its .sha1 and .md5 checksums that Artifactory verifies when importing.
+type ArtifactoryImportBlobStore struct {
+	path        string
+	concurrency int
+	logger      Logger
+
+	// layerFolders folders where each layer needs to be written, by digest
+	layerFolders map[string][]string
+	// taggedDigests digest of the image or index stored in each tag folder
+	taggedDigests map[string]string
+}
+
+var _ BlobStore = &ArtifactoryImportBlobStore{}
+
+// NewArtifactoryImportBlobStore constructor for ArtifactoryImportBlobStore
+func NewArtifactoryImportBlobStore(path string, concurrency int, logger Logger) *ArtifactoryImportBlobStore {
+	return &ArtifactoryImportBlobStore{path: path, concurrency: concurrency, logger: logger,
+		layerFolders: map[string][]string{}, taggedDigests: map[string]string{}}
+}
+
+// WriteDescriptors writes the manifests and configurations of all the images to the folders of their tags
+func (a *ArtifactoryImportBlobStore) WriteDescriptors(ids *imagedesc.ImageRefDescriptors) error {
+	err := os.MkdirAll(a.path, 0700)
+	if err != nil {
+		return fmt.Errorf("Creating folder '%s': %s", a.path, err)
+	}
+
+	for _, td := range ids.Descriptors() {
+		switch {
+		case td.Image != nil:
+			folders, err := a.tagFolders(td.Image.Manifest.Digest, td.Image.Tag)
+			if err != nil {
+				return err
+			}
+			err = a.writeImage(*td.Image, folders)
+			if err != nil {
+				return err
+			}
+
+		case td.ImageIndex != nil:
+			folders, err := a.tagFolders(td.ImageIndex.Digest, td.ImageIndex.Tag)
+			if err != nil {
+				return err
+			}
+			err = a.writeImageIndex(*td.ImageIndex, folders)
+			if err != nil {
+				return err
+			}
+
+		default:
+			panic("Unknown item")
+		}
+	}
+	return nil
+}
+
+// WriteBlobs writes each blob once, in parallel, and links it to every other folder of an image that uses it
+func (a *ArtifactoryImportBlobStore) WriteBlobs(blobs []Blob) error {
+	return writeBlobsInParallel(blobs, a.concurrency, func(blob Blob) error {
+		folders := a.layerFolders[blob.Digest.String()]
+		if len(folders) == 0 {
+			return nil
+		}
+
+		name := artifactoryBlobFileName(blob.Digest)
+		firstPath := filepath.Join(folders[0], name)
+		err := writeBlobToFile(firstPath, blob, a.logger)
+		if err != nil {
+			return err
+		}
+		err = writeChecksumFiles(firstPath)
+		if err != nil {
+			return err
+		}
+
+		for _, folder := range folders[1:] {
+			path := filepath.Join(folder, name)
+			err := linkOrCopyFile(firstPath, path)
+			if err != nil {
+				return err
+			}
+			err = linkOrCopyFile(firstPath+".sha1", path+".sha1")
+			if err != nil {
+				return err
+			}
+			err = linkOrCopyFile(firstPath+".md5", path+".md5")
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close does nothing since every file is closed after being written
+func (a *ArtifactoryImportBlobStore) Close() error { return nil }
+
+// tagFolders returns the folders where an image or index that was selected to be exported is stored.
+// The original tag is skipped when another image, coming from a different repository, already uses it
+func (a *ArtifactoryImportBlobStore) tagFolders(digestStr, origTag string) ([]string, error) {
+	digest, err := regv1.NewHash(digestStr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Same tag as the one created by util.DefaultTagGenerator when copying to a repository
+	folders := []string{filepath.Join(a.path, fmt.Sprintf("%s-%s.imgpkg", digest.Algorithm, digest.Hex))}
+	if origTag == "" {
+		return folders, nil
+	}
+
+	if taggedDigest, found := a.taggedDigests[origTag]; found && taggedDigest != digestStr {
+		a.logger.Logf("skipping tag '%s' of '%s', already used by '%s'\n", origTag, digestStr, taggedDigest)
+		return folders, nil
+	}
+	a.taggedDigests[origTag] = digestStr
+	return append(folders, filepath.Join(a.path, origTag)), nil
+}
+
+func (a *ArtifactoryImportBlobStore) writeImageIndex(td imagedesc.ImageIndexDescriptor, folders []string) error {
+	for _, idx := range td.Indexes {
+		err := a.writeImageIndex(idx, []string{filepath.Join(a.path, idx.Digest)})
+		if err != nil {
+			return err
+		}
+	}
+	for _, img := range td.Images {
+		err := a.writeImage(img, []string{filepath.Join(a.path, img.Manifest.Digest)})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, folder := range folders {
+		err := writeFileWithChecksums(folder, "list.manifest.json", []byte(td.Raw))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *ArtifactoryImportBlobStore) writeImage(td imagedesc.ImageDescriptor, folders []string) error {
+	configDigest, err := regv1.NewHash(td.Config.Digest)
+	if err != nil {
+		return err
+	}
+
+	for _, folder := range folders {
+		err := writeFileWithChecksums(folder, "manifest.json", []byte(td.Manifest.Raw))
+		if err != nil {
+			return err
+		}
+		err = writeFileWithChecksums(folder, artifactoryBlobFileName(configDigest), []byte(td.Config.Raw))
+		if err != nil {
+			return err
+		}
+		for _, layer := range td.Layers {
+			a.addLayerFolder(layer.Digest, folder)
+		}
+	}
+	return nil
+}
+
+func (a *ArtifactoryImportBlobStore) addLayerFolder(digest, folder string) {
+	for _, existing := range a.layerFolders[digest] {
+		if existing == folder {
+			return
+		}
+	}
+	a.layerFolders[digest] = append(a.layerFolders[digest], folder)
+	sort.Strings(a.layerFolders[digest])
+}
+
+// artifactoryBlobFileName name used by Artifactory to store configs and layers next to the manifest
+func artifactoryBlobFileName(digest regv1.Hash) string {
+	return digest.Algorithm + "__" + digest.Hex
+}
+
+func writeFileWithChecksums(folder, name string, content []byte) error {
+	err := os.MkdirAll(folder, 0700)
+	if err != nil {
+		return fmt.Errorf("Creating folder '%s': %s", folder, err)
+	}
+
+	path := filepath.Join(folder, name)
+	err = os.WriteFile(path, content, 0600)
+	if err != nil {
+		return err
+	}
+	return writeChecksumFiles(path)
+}
+
+// writeChecksumFiles writes the <file>.sha1 and <file>.md5 files of the file in path
+func writeChecksumFiles(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	sha1Hash := sha1.New()
+	md5Hash := md5.New()
+	_, err = io.Copy(io.MultiWriter(sha1Hash, md5Hash), file)
+	if err != nil {
+		return fmt.Errorf("Calculating checksums of '%s': %s", path, err)
+	}
+
+	for ext, h := range map[string]hash.Hash{".sha1": sha1Hash, ".md5": md5Hash} {
+		err = os.WriteFile(path+ext, []byte(hex.EncodeToString(h.Sum(nil))), 0600)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkOrCopyFile hard links src to dst, copying it when the filesystem does not support hard links
+func linkOrCopyFile(src, dst string) error {
+	err := os.Link(src, dst)
+	if err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return fmt.Errorf("Copying '%s' to '%s': %s", src, dst, err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,184 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagedesc"
+)
+
+// Blob Layer that needs to be written to a BlobStore
+type Blob struct {
+	Digest regv1.Hash
+	Size   int64
+	// Open returns the compressed content of the layer
+	Open func() (io.ReadCloser, error)
+}
+
+// BlobStore Destination where images are exported to
+type BlobStore interface {
+	// WriteDescriptors stores the description of all the images that are exported
+	WriteDescriptors(ids *imagedesc.ImageRefDescriptors) error
+	// WriteBlobs stores the layers of the exported images. Blobs are sorted by digest and do not contain duplicates
+	WriteBlobs(blobs []Blob) error
+	// Close finishes the export and releases all the resources
+	Close() error
+}
+
+// Writer Exports images and their layers to a BlobStore
+type Writer struct {
+	ids   *imagedesc.ImageRefDescriptors
+	store BlobStore
+
+	imageLayerWriterCheck ImageLayerWriterFilter
+	layersFromOtherSource []regv1.Layer
+	layersToWrite         []imagedesc.ImageLayerDescriptor
+}
+
+// NewWriter constructor for Writer. Layers present in layersFromOtherSource are read from there instead of
+// being retrieved from the location described in ids
+func NewWriter(ids *imagedesc.ImageRefDescriptors, store BlobStore, imageLayerWriterCheck ImageLayerWriterFilter, layersFromOtherSource []regv1.Layer) *Writer {
+	return &Writer{
+		ids:                   ids,
+		store:                 store,
+		imageLayerWriterCheck: imageLayerWriterCheck,
+		layersFromOtherSource: layersFromOtherSource,
+	}
+}
+
+// Write exports all the images and layers to the BlobStore
+func (w *Writer) Write() error {
+	err := w.write()
+	closeErr := w.store.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+func (w *Writer) write() error {
+	err := w.store.WriteDescriptors(w.ids)
+	if err != nil {
+		return err
+	}
+
+	for _, td := range w.ids.Descriptors() {
+		switch {
+		case td.Image != nil:
+			err := w.writeImage(*td.Image)
+			if err != nil {
+				return err
+			}
+
+		case td.ImageIndex != nil:
+			err := w.writeImageIndex(*td.ImageIndex)
+			if err != nil {
+				return err
+			}
+
+		default:
+			panic("Unknown item")
+		}
+	}
+
+	blobs, err := w.blobs()
+	if err != nil {
+		return err
+	}
+
+	return w.store.WriteBlobs(blobs)
+}
+
+func (w *Writer) writeImageIndex(td imagedesc.ImageIndexDescriptor) error {
+	for _, idx := range td.Indexes {
+		err := w.writeImageIndex(idx)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, img := range td.Images {
+		err := w.writeImage(img)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) writeImage(td imagedesc.ImageDescriptor) error {
+	for _, imgLayer := range td.Layers {
+		shouldLayerBeIncluded, err := w.imageLayerWriterCheck.ShouldLayerBeIncluded(imagedesc.NewDescribedCompressedLayer(imgLayer, nil))
+		if err != nil {
+			return err
+		}
+		if shouldLayerBeIncluded {
+			w.layersToWrite = append(w.layersToWrite, imgLayer)
+		}
+	}
+	return nil
+}
+
+// blobs returns the layers that need to be written sorted by digest, to have a deterministic result, and deduplicated
+func (w *Writer) blobs() ([]Blob, error) {
+	sort.Slice(w.layersToWrite, func(i, j int) bool {
+		return w.layersToWrite[i].Digest < w.layersToWrite[j].Digest
+	})
+
+	var blobs []Blob
+	seen := map[string]struct{}{}
+	for _, imgLayer := range w.layersToWrite {
+		imgLayer := imgLayer // copy
+
+		if _, found := seen[imgLayer.Digest]; found {
+			continue
+		}
+		seen[imgLayer.Digest] = struct{}{}
+
+		digest, err := regv1.NewHash(imgLayer.Digest)
+		if err != nil {
+			return nil, err
+		}
+
+		blobs = append(blobs, Blob{
+			Digest: digest,
+			Size:   imgLayer.Size,
+			Open:   func() (io.ReadCloser, error) { return w.openLayer(imgLayer) },
+		})
+	}
+	return blobs, nil
+}
+
+func (w *Writer) openLayer(imgLayer imagedesc.ImageLayerDescriptor) (io.ReadCloser, error) {
+	for _, layer := range w.layersFromOtherSource {
+		d, err := layer.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("Retrieving digest: %s", err)
+		}
+		if d.String() == imgLayer.Digest {
+			stream, err := layer.Compressed()
+			if err != nil {
+				return nil, fmt.Errorf("Retrieve layer from file: %s", err)
+			}
+			return stream, nil
+		}
+	}
+
+	foundLayer, err := w.ids.FindLayer(imgLayer)
+	if err != nil {
+		return nil, err
+	}
+
+	return foundLayer.Open()
+}
+
+// blobFileName name used to store the blob in tar files and folders
+func blobFileName(digest regv1.Hash) string {
+	return digest.Algorithm + "-" + digest.Hex + ".tar.gz"
+}
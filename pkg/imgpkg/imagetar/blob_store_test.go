@@ -0,0 +1,239 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar_test
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagedesc"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagetar"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+type blobStoreTest struct {
+	name string
+	// newStore creates the store that writes to path
+	newStore func(path string, concurrency int) imagetar.BlobStore
+	// readBlob returns the content of the blob with the provided digest that was written to path
+	readBlob func(t *testing.T, path string, digest regv1.Hash) []byte
+}
+
+func TestBlobStores(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegistry := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegistry.CleanUp()
+	img := fakeRegistry.WithRandomImageWithLayers("library/img", 3)
+	otherImg := fakeRegistry.WithRandomImageWithLayers("library/other-img", 2)
+	reg := fakeRegistry.Build()
+
+	var refs []imagedesc.Metadata
+	for _, ref := range []string{img.RefDigest, otherImg.RefDigest} {
+		parsedRef, err := regname.ParseReference(ref)
+		require.NoError(t, err)
+		refs = append(refs, imagedesc.Metadata{Ref: parsedRef, Tag: "some-tag"})
+	}
+	ids, err := imagedesc.NewImageRefDescriptors(refs, reg)
+	require.NoError(t, err)
+
+	var expectedLayers []regv1.Layer
+	for _, image := range []regv1.Image{img.Image, otherImg.Image} {
+		layers, err := image.Layers()
+		require.NoError(t, err)
+		expectedLayers = append(expectedLayers, layers...)
+	}
+
+	allTests := []blobStoreTest{
+		{
+			name: "tar",
+			newStore: func(path string, concurrency int) imagetar.BlobStore {
+				tarPath := filepath.Join(path, "images.tar")
+				_, err := os.Create(tarPath)
+				require.NoError(t, err)
+				return imagetar.NewTarBlobStore(func() (io.WriteCloser, error) {
+					return os.OpenFile(tarPath, os.O_RDWR, 0600)
+				}, imagetar.TarWriterOpts{Concurrency: concurrency}, logger)
+			},
+			readBlob: func(t *testing.T, path string, digest regv1.Hash) []byte {
+				imgs, err := imagetar.NewTarReader(filepath.Join(path, "images.tar")).PresentLayers()
+				require.NoError(t, err)
+				for _, layer := range imgs {
+					layerDigest, err := layer.Digest()
+					require.NoError(t, err)
+					if layerDigest == digest {
+						return readAll(t, layer.Compressed)
+					}
+				}
+				require.Failf(t, "blob not found", "%s", digest)
+				return nil
+			},
+		},
+		{
+			name: "directory",
+			newStore: func(path string, concurrency int) imagetar.BlobStore {
+				return imagetar.NewDirBlobStore(path, concurrency, logger)
+			},
+			readBlob: func(t *testing.T, path string, digest regv1.Hash) []byte {
+				bs, err := os.ReadFile(filepath.Join(path, digest.Algorithm+"-"+digest.Hex+".tar.gz"))
+				require.NoError(t, err)
+				return bs
+			},
+		},
+		{
+			name: "oci layout",
+			newStore: func(path string, concurrency int) imagetar.BlobStore {
+				return imagetar.NewOCILayoutBlobStore(path, concurrency, logger)
+			},
+			readBlob: func(t *testing.T, path string, digest regv1.Hash) []byte {
+				bs, err := os.ReadFile(filepath.Join(path, "blobs", digest.Algorithm, digest.Hex))
+				require.NoError(t, err)
+				return bs
+			},
+		},
+		{
+			name: "artifactory import",
+			newStore: func(path string, concurrency int) imagetar.BlobStore {
+				return imagetar.NewArtifactoryImportBlobStore(path, concurrency, logger)
+			},
+			readBlob: func(t *testing.T, path string, digest regv1.Hash) []byte {
+				matches, err := filepath.Glob(filepath.Join(path, "*", digest.Algorithm+"__"+digest.Hex))
+				require.NoError(t, err)
+				require.NotEmpty(t, matches)
+				bs, err := os.ReadFile(matches[0])
+				require.NoError(t, err)
+				return bs
+			},
+		},
+	}
+
+	for _, test := range allTests {
+		for _, concurrency := range []int{1, 3} {
+			test, concurrency := test, concurrency
+			t.Run(fmt.Sprintf("%s with concurrency %d", test.name, concurrency), func(t *testing.T) {
+				path := t.TempDir()
+
+				err := imagetar.NewWriter(ids, test.newStore(path, concurrency), imagetar.NewImageLayerWriterCheck(false), nil).Write()
+				require.NoError(t, err)
+
+				for _, layer := range expectedLayers {
+					digest, err := layer.Digest()
+					require.NoError(t, err)
+					assert.Equal(t, readAll(t, layer.Compressed), test.readBlob(t, path, digest), "layer %s", digest)
+				}
+			})
+		}
+	}
+
+	t.Run("oci layout lists all images in index.json", func(t *testing.T) {
+		path := t.TempDir()
+		err := imagetar.NewWriter(ids, imagetar.NewOCILayoutBlobStore(path, 1, logger), imagetar.NewImageLayerWriterCheck(false), nil).Write()
+		require.NoError(t, err)
+
+		indexBytes, err := os.ReadFile(filepath.Join(path, "index.json"))
+		require.NoError(t, err)
+		var index regv1.IndexManifest
+		require.NoError(t, json.Unmarshal(indexBytes, &index))
+
+		require.Len(t, index.Manifests, 2)
+		for _, desc := range index.Manifests {
+			assert.Equal(t, "some-tag", desc.Annotations["org.opencontainers.image.ref.name"])
+			_, err := os.Stat(filepath.Join(path, "blobs", desc.Digest.Algorithm, desc.Digest.Hex))
+			assert.NoError(t, err)
+		}
+	})
+}
+
+func TestArtifactoryImportBlobStore(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegistry := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegistry.CleanUp()
+	img := fakeRegistry.WithRandomImageWithLayers("library/img", 2)
+	otherImg := fakeRegistry.WithRandomImageWithLayers("library/other-img", 1)
+	reg := fakeRegistry.Build()
+
+	var refs []imagedesc.Metadata
+	for _, ref := range []string{img.RefDigest, otherImg.RefDigest} {
+		parsedRef, err := regname.ParseReference(ref)
+		require.NoError(t, err)
+		refs = append(refs, imagedesc.Metadata{Ref: parsedRef, Tag: "some-tag"})
+	}
+	ids, err := imagedesc.NewImageRefDescriptors(refs, reg)
+	require.NoError(t, err)
+
+	path := t.TempDir()
+	err = imagetar.NewWriter(ids, imagetar.NewArtifactoryImportBlobStore(path, 1, logger), imagetar.NewImageLayerWriterCheck(false), nil).Write()
+	require.NoError(t, err)
+
+	assertImageFolder := func(t *testing.T, folder string, image regv1.Image) {
+		manifest, err := image.RawManifest()
+		require.NoError(t, err)
+		assertFileWithChecksums(t, filepath.Join(folder, "manifest.json"), manifest)
+
+		config, err := image.RawConfigFile()
+		require.NoError(t, err)
+		configDigest, err := image.ConfigName()
+		require.NoError(t, err)
+		assertFileWithChecksums(t, filepath.Join(folder, "sha256__"+configDigest.Hex), config)
+
+		layers, err := image.Layers()
+		require.NoError(t, err)
+		for _, layer := range layers {
+			digest, err := layer.Digest()
+			require.NoError(t, err)
+			assertFileWithChecksums(t, filepath.Join(folder, "sha256__"+digest.Hex), readAll(t, layer.Compressed))
+		}
+	}
+
+	t.Run("each image is stored in the folder of the tag imgpkg uses when copying to a repository", func(t *testing.T) {
+		for _, image := range []regv1.Image{img.Image, otherImg.Image} {
+			digest, err := image.Digest()
+			require.NoError(t, err)
+			assertImageFolder(t, filepath.Join(path, "sha256-"+digest.Hex+".imgpkg"), image)
+		}
+	})
+
+	t.Run("the original tag is only used by one of the images with that tag", func(t *testing.T) {
+		manifest, err := os.ReadFile(filepath.Join(path, "some-tag", "manifest.json"))
+		require.NoError(t, err)
+
+		taggedImage := img.Image
+		if otherManifest, _ := otherImg.Image.RawManifest(); string(otherManifest) == string(manifest) {
+			taggedImage = otherImg.Image
+		}
+		assertImageFolder(t, filepath.Join(path, "some-tag"), taggedImage)
+	})
+}
+
+func assertFileWithChecksums(t *testing.T, path string, expected []byte) {
+	bs, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, expected, bs, "content of %s", path)
+
+	sha1Sum, err := os.ReadFile(path + ".sha1")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%x", sha1.Sum(expected)), string(sha1Sum))
+
+	md5Sum, err := os.ReadFile(path + ".md5")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%x", md5.Sum(expected)), string(md5Sum))
+}
+
+func readAll(t *testing.T, open func() (io.ReadCloser, error)) []byte {
+	reader, err := open()
+	require.NoError(t, err)
+	defer reader.Close()
+	bs, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return bs
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagedesc"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/tracing"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+)
+
+// DirBlobStore BlobStore that writes each layer to a separate file in a folder.
+// The folder has the same structure as the tarball created by TarBlobStore
+type DirBlobStore struct {
+	path        string
+	concurrency int
+	logger      Logger
+}
+
+var _ BlobStore = DirBlobStore{}
+
+// NewDirBlobStore constructor for DirBlobStore
+func NewDirBlobStore(path string, concurrency int, logger Logger) DirBlobStore {
+	return DirBlobStore{path: path, concurrency: concurrency, logger: logger}
+}
+
+// WriteDescriptors writes the format version and the images descriptors to their files
+func (d DirBlobStore) WriteDescriptors(ids *imagedesc.ImageRefDescriptors) error {
+	err := os.MkdirAll(d.path, 0700)
+	if err != nil {
+		return fmt.Errorf("Creating folder '%s': %s", d.path, err)
+	}
+
+	formatBytes, err := tarFormatBytes(tarFormatVersionDefault)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(d.path, TarFormatFileName), formatBytes, 0600)
+	if err != nil {
+		return err
+	}
+
+	idsBytes, err := ids.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(d.path, "manifest.json"), idsBytes, 0600)
+}
+
+// WriteBlobs writes each blob to its own file, in parallel
+func (d DirBlobStore) WriteBlobs(blobs []Blob) error {
+	return writeBlobsInParallel(blobs, d.concurrency, func(blob Blob) error {
+		return writeBlobToFile(filepath.Join(d.path, blobFileName(blob.Digest)), blob, d.logger)
+	})
+}
+
+// Close does nothing since every file is closed after being written
+func (d DirBlobStore) Close() error { return nil }
+
+func writeBlobsInParallel(blobs []Blob, concurrency int, writeFunc func(Blob) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	errCh := make(chan error, len(blobs))
+	throttle := util.NewThrottle(concurrency)
+
+	for _, blob := range blobs {
+		blob := blob // copy
+
+		go func() {
+			throttle.Take()
+			defer throttle.Done()
+
+			errCh <- util.Retry(func() error { return writeFunc(blob) })
+		}()
+	}
+
+	for i := 0; i < len(blobs); i++ {
+		err := <-errCh
+		if err != nil {
+			return fmt.Errorf("Writing blob: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func writeBlobToFile(path string, blob Blob, logger Logger) error {
+	stream, err := blob.Open()
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	span := tracing.Start("imgpkg.blob.write", tracing.BlobDigestKey.String(blob.Digest.String()))
+	t1 := time.Now()
+	_, err = io.Copy(file, stream)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("Copying data: %s", err)
+	}
+
+	logger.Logf("done: file '%s' (%s)\n", filepath.Base(path), time.Now().Sub(t1))
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagedesc"
+)
+
+const ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+
+// OCILayoutBlobStore BlobStore that writes the images to a folder following the OCI Image Layout specification
+type OCILayoutBlobStore struct {
+	path        string
+	concurrency int
+	logger      Logger
+}
+
+var _ BlobStore = OCILayoutBlobStore{}
+
+// NewOCILayoutBlobStore constructor for OCILayoutBlobStore
+func NewOCILayoutBlobStore(path string, concurrency int, logger Logger) OCILayoutBlobStore {
+	return OCILayoutBlobStore{path: path, concurrency: concurrency, logger: logger}
+}
+
+// WriteDescriptors writes the manifests and configurations of all images as blobs and lists them in index.json
+func (o OCILayoutBlobStore) WriteDescriptors(ids *imagedesc.ImageRefDescriptors) error {
+	err := os.MkdirAll(o.path, 0700)
+	if err != nil {
+		return fmt.Errorf("Creating folder '%s': %s", o.path, err)
+	}
+
+	err = os.WriteFile(filepath.Join(o.path, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0600)
+	if err != nil {
+		return err
+	}
+
+	index := regv1.IndexManifest{SchemaVersion: 2, MediaType: types.OCIImageIndex}
+	for _, td := range ids.Descriptors() {
+		var desc regv1.Descriptor
+		var tag string
+		switch {
+		case td.Image != nil:
+			desc, err = o.writeImage(*td.Image)
+			tag = td.Image.Tag
+		case td.ImageIndex != nil:
+			desc, err = o.writeImageIndex(*td.ImageIndex)
+			tag = td.ImageIndex.Tag
+		default:
+			panic("Unknown item")
+		}
+		if err != nil {
+			return err
+		}
+		if tag != "" {
+			desc.Annotations = map[string]string{ociRefNameAnnotation: tag}
+		}
+		index.Manifests = append(index.Manifests, desc)
+	}
+
+	indexBytes, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(o.path, "index.json"), indexBytes, 0600)
+}
+
+// WriteBlobs writes each blob to blobs/<algorithm>/<hex>, in parallel
+func (o OCILayoutBlobStore) WriteBlobs(blobs []Blob) error {
+	return writeBlobsInParallel(blobs, o.concurrency, func(blob Blob) error {
+		path, err := o.blobPath(blob.Digest)
+		if err != nil {
+			return err
+		}
+		return writeBlobToFile(path, blob, o.logger)
+	})
+}
+
+// Close does nothing since every file is closed after being written
+func (o OCILayoutBlobStore) Close() error { return nil }
+
+func (o OCILayoutBlobStore) writeImageIndex(td imagedesc.ImageIndexDescriptor) (regv1.Descriptor, error) {
+	for _, idx := range td.Indexes {
+		if _, err := o.writeImageIndex(idx); err != nil {
+			return regv1.Descriptor{}, err
+		}
+	}
+	for _, img := range td.Images {
+		if _, err := o.writeImage(img); err != nil {
+			return regv1.Descriptor{}, err
+		}
+	}
+
+	return o.writeRawBlob(td.Digest, types.MediaType(td.MediaType), []byte(td.Raw))
+}
+
+func (o OCILayoutBlobStore) writeImage(td imagedesc.ImageDescriptor) (regv1.Descriptor, error) {
+	_, err := o.writeRawBlob(td.Config.Digest, "", []byte(td.Config.Raw))
+	if err != nil {
+		return regv1.Descriptor{}, err
+	}
+
+	return o.writeRawBlob(td.Manifest.Digest, types.MediaType(td.Manifest.MediaType), []byte(td.Manifest.Raw))
+}
+
+func (o OCILayoutBlobStore) writeRawBlob(digestStr string, mediaType types.MediaType, content []byte) (regv1.Descriptor, error) {
+	digest, err := regv1.NewHash(digestStr)
+	if err != nil {
+		return regv1.Descriptor{}, err
+	}
+
+	path, err := o.blobPath(digest)
+	if err != nil {
+		return regv1.Descriptor{}, err
+	}
+
+	err = os.WriteFile(path, content, 0600)
+	if err != nil {
+		return regv1.Descriptor{}, err
+	}
+
+	return regv1.Descriptor{MediaType: mediaType, Digest: digest, Size: int64(len(content))}, nil
+}
+
+func (o OCILayoutBlobStore) blobPath(digest regv1.Hash) (string, error) {
+	dir := filepath.Join(o.path, "blobs", digest.Algorithm)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, digest.Hex), nil
+}
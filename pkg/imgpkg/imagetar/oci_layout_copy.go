@@ -0,0 +1,102 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"fmt"
+	"os"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+)
+
+// CopyOCILayout Copies every image and index listed in the index.json of the OCI Image Layout in srcPath to the
+// OCI Image Layout in dstPath, which is created when it does not exist. The annotations of each entry, e.g. the tag
+// in org.opencontainers.image.ref.name, are preserved. Blobs already present in the destination are not copied again,
+// and entries already listed in the destination index.json are replaced instead of duplicated
+func CopyOCILayout(srcPath, dstPath string, logger Logger) ([]regv1.Descriptor, error) {
+	src, err := layout.FromPath(srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("Reading OCI layout '%s': %s", srcPath, err)
+	}
+	srcIndex, err := src.ImageIndex()
+	if err != nil {
+		return nil, fmt.Errorf("Reading OCI layout '%s': %s", srcPath, err)
+	}
+	srcIndexManifest, err := srcIndex.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("Reading index.json of OCI layout '%s': %s", srcPath, err)
+	}
+
+	dst, err := openOrCreateOCILayout(dstPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var copied []regv1.Descriptor
+	for _, desc := range srcIndexManifest.Manifests {
+		opts := []layout.Option{layout.WithAnnotations(desc.Annotations)}
+		if desc.Platform != nil {
+			opts = append(opts, layout.WithPlatform(*desc.Platform))
+		}
+
+		switch {
+		case desc.MediaType.IsIndex():
+			idx, err := srcIndex.ImageIndex(desc.Digest)
+			if err != nil {
+				return nil, fmt.Errorf("Reading index '%s': %s", desc.Digest, err)
+			}
+			err = dst.ReplaceIndex(idx, sameOCILayoutEntry(desc), opts...)
+			if err != nil {
+				return nil, fmt.Errorf("Writing index '%s' to OCI layout '%s': %s", desc.Digest, dstPath, err)
+			}
+		case desc.MediaType.IsImage():
+			img, err := srcIndex.Image(desc.Digest)
+			if err != nil {
+				return nil, fmt.Errorf("Reading image '%s': %s", desc.Digest, err)
+			}
+			err = dst.ReplaceImage(img, sameOCILayoutEntry(desc), opts...)
+			if err != nil {
+				return nil, fmt.Errorf("Writing image '%s' to OCI layout '%s': %s", desc.Digest, dstPath, err)
+			}
+		default:
+			logger.Logf("Skipping '%s' with unsupported media type '%s'\n", desc.Digest, desc.MediaType)
+			continue
+		}
+
+		logger.Logf("Copied '%s'\n", desc.Digest)
+		copied = append(copied, desc)
+	}
+
+	return copied, nil
+}
+
+func openOrCreateOCILayout(path string) (layout.Path, error) {
+	dst, err := layout.FromPath(path)
+	if err == nil {
+		return dst, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("Reading folder '%s': %s", path, err)
+	}
+	if len(entries) > 0 {
+		return "", fmt.Errorf("Expected '%s' to be an OCI layout or an empty folder", path)
+	}
+
+	dst, err = layout.Write(path, empty.Index)
+	if err != nil {
+		return "", fmt.Errorf("Creating OCI layout '%s': %s", path, err)
+	}
+	return dst, nil
+}
+
+// sameOCILayoutEntry Matches the entries of index.json with the same digest and tag as the provided descriptor
+func sameOCILayoutEntry(desc regv1.Descriptor) func(regv1.Descriptor) bool {
+	return func(other regv1.Descriptor) bool {
+		return other.Digest == desc.Digest && other.Annotations[ociRefNameAnnotation] == desc.Annotations[ociRefNameAnnotation]
+	}
+}
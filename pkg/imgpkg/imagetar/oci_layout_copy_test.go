@@ -0,0 +1,97 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagetar"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestCopyOCILayout(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+
+	srcPath := t.TempDir()
+	src, err := layout.Write(srcPath, empty.Index)
+	require.NoError(t, err)
+	img, err := random.Image(500, 2)
+	require.NoError(t, err)
+	require.NoError(t, src.AppendImage(img, layout.WithAnnotations(map[string]string{"org.opencontainers.image.ref.name": "v1"})))
+	idx, err := random.Index(500, 1, 2)
+	require.NoError(t, err)
+	require.NoError(t, src.AppendIndex(idx))
+
+	imgDigest, err := img.Digest()
+	require.NoError(t, err)
+	idxDigest, err := idx.Digest()
+	require.NoError(t, err)
+
+	readIndex := func(t *testing.T, path string) []regv1.Descriptor {
+		ii, err := layout.ImageIndexFromPath(path)
+		require.NoError(t, err)
+		manifest, err := ii.IndexManifest()
+		require.NoError(t, err)
+		return manifest.Manifests
+	}
+
+	t.Run("creates the destination with all the images and indexes of the source", func(t *testing.T) {
+		dstPath := filepath.Join(t.TempDir(), "layout")
+
+		copied, err := imagetar.CopyOCILayout(srcPath, dstPath, logger)
+		require.NoError(t, err)
+		assert.Len(t, copied, 2)
+
+		manifests := readIndex(t, dstPath)
+		require.Len(t, manifests, 2)
+		assert.Equal(t, imgDigest, manifests[0].Digest)
+		assert.Equal(t, "v1", manifests[0].Annotations["org.opencontainers.image.ref.name"])
+		assert.Equal(t, idxDigest, manifests[1].Digest)
+
+		dst, err := layout.FromPath(dstPath)
+		require.NoError(t, err)
+		copiedImg, err := dst.Image(imgDigest)
+		require.NoError(t, err)
+		layers, err := copiedImg.Layers()
+		require.NoError(t, err)
+		for _, layer := range layers {
+			digest, err := layer.Digest()
+			require.NoError(t, err)
+			_, err = os.Stat(filepath.Join(dstPath, "blobs", digest.Algorithm, digest.Hex))
+			assert.NoError(t, err, "layer %s", digest)
+		}
+	})
+
+	t.Run("does not duplicate the entries already present in the destination", func(t *testing.T) {
+		dstPath := t.TempDir()
+
+		_, err := imagetar.CopyOCILayout(srcPath, dstPath, logger)
+		require.NoError(t, err)
+		_, err = imagetar.CopyOCILayout(srcPath, dstPath, logger)
+		require.NoError(t, err)
+
+		assert.Len(t, readIndex(t, dstPath), 2)
+	})
+
+	t.Run("fails when the destination is not an OCI layout", func(t *testing.T) {
+		dstPath := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dstPath, "some-file"), []byte("content"), 0600))
+
+		_, err := imagetar.CopyOCILayout(srcPath, dstPath, logger)
+		require.EqualError(t, err, "Expected '"+dstPath+"' to be an OCI layout or an empty folder")
+	})
+
+	t.Run("fails when the source is not an OCI layout", func(t *testing.T) {
+		_, err := imagetar.CopyOCILayout(t.TempDir(), t.TempDir(), logger)
+		require.ErrorContains(t, err, "Reading OCI layout")
+	})
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagedesc"
+)
+
+// OriginRegistry Registry used to retrieve the layers that are missing from the tar from the location the images
+// were exported from
+type OriginRegistry interface {
+	Image(regname.Reference) (regv1.Image, error)
+}
+
+// originFallbackLayerProvider Provides the layers that are present in the tar and retrieves the missing ones,
+// e.g. when the tar was not completely written, from the repositories the images were exported from
+type originFallbackLayerProvider struct {
+	tar           imagedesc.LayerProvider
+	presentLayers map[string]struct{}
+	// origins images, in their original location, that contain each layer
+	origins  map[string][]regname.Digest
+	registry OriginRegistry
+	logger   Logger
+}
+
+var _ imagedesc.LayerProvider = originFallbackLayerProvider{}
+
+func newOriginFallbackLayerProvider(tar imagedesc.LayerProvider, presentLayers []regv1.Layer, ids *imagedesc.ImageRefDescriptors, registry OriginRegistry, logger Logger) (originFallbackLayerProvider, error) {
+	provider := originFallbackLayerProvider{
+		tar:           tar,
+		presentLayers: map[string]struct{}{},
+		origins:       map[string][]regname.Digest{},
+		registry:      registry,
+		logger:        logger,
+	}
+
+	for _, layer := range presentLayers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return originFallbackLayerProvider{}, err
+		}
+		provider.presentLayers[digest.String()] = struct{}{}
+	}
+
+	for _, td := range ids.Descriptors() {
+		var err error
+		switch {
+		case td.Image != nil:
+			err = provider.addOrigins(*td.Image)
+		case td.ImageIndex != nil:
+			err = provider.addIndexOrigins(*td.ImageIndex)
+		}
+		if err != nil {
+			return originFallbackLayerProvider{}, err
+		}
+	}
+
+	return provider, nil
+}
+
+func (p originFallbackLayerProvider) addIndexOrigins(indexTD imagedesc.ImageIndexDescriptor) error {
+	for _, imageTD := range indexTD.Images {
+		err := p.addOrigins(imageTD)
+		if err != nil {
+			return err
+		}
+	}
+	for _, nestedIndexTD := range indexTD.Indexes {
+		err := p.addIndexOrigins(nestedIndexTD)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p originFallbackLayerProvider) addOrigins(imageTD imagedesc.ImageDescriptor) error {
+	for _, ref := range imageTD.Refs {
+		parsedRef, err := regname.ParseReference(ref)
+		if err != nil {
+			return fmt.Errorf("Parsing origin of image '%s': %s", ref, err)
+		}
+		imageRef := parsedRef.Context().Digest(imageTD.Manifest.Digest)
+		for _, layerTD := range imageTD.Layers {
+			p.origins[layerTD.Digest] = append(p.origins[layerTD.Digest], imageRef)
+		}
+	}
+	return nil
+}
+
+// FindLayer Returns the layer from the tar when it is present, otherwise from its origin
+func (p originFallbackLayerProvider) FindLayer(layerTD imagedesc.ImageLayerDescriptor) (imagedesc.LayerContents, error) {
+	if _, found := p.presentLayers[layerTD.Digest]; found {
+		return p.tar.FindLayer(layerTD)
+	}
+	return originLayerContents{digest: layerTD.Digest, images: p.origins[layerTD.Digest], registry: p.registry, logger: p.logger}, nil
+}
+
+type originLayerContents struct {
+	digest   string
+	images   []regname.Digest
+	registry OriginRegistry
+	logger   Logger
+}
+
+var _ imagedesc.LayerContents = originLayerContents{}
+
+// Open Retrieves the layer from the first image, in its original location, that is available
+func (c originLayerContents) Open() (io.ReadCloser, error) {
+	hash, err := regv1.NewHash(c.digest)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []string
+	for _, imageRef := range c.images {
+		img, err := c.registry.Image(imageRef)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", imageRef.Name(), err))
+			continue
+		}
+		layer, err := img.LayerByDigest(hash)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", imageRef.Name(), err))
+			continue
+		}
+		rc, err := layer.Compressed()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", imageRef.Name(), err))
+			continue
+		}
+
+		c.logger.Logf("layer '%s' is missing from the tar, fetching it from '%s'\n", c.digest, imageRef.Context().Name())
+		return rc, nil
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("Layer '%s' is missing from the tar and no origin is known for it", c.digest)
+	}
+	return nil, fmt.Errorf("Layer '%s' is missing from the tar and could not be fetched from its origin:\n  %s", c.digest, strings.Join(errs, "\n  "))
+}
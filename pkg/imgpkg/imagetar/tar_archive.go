@@ -0,0 +1,108 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagedesc"
+)
+
+// TarArchive Random access to the descriptors and blobs of a tar created by imgpkg.
+// The location of every file is found when the archive is opened, so a blob can be read without scanning the tar again
+type TarArchive struct {
+	file    tarSource
+	entries map[string]tarArchiveEntry
+}
+
+type tarArchiveEntry struct {
+	offset int64
+	size   int64
+}
+
+// OpenTarArchive Opens the tar and finds the location of its files, the archive needs to be closed after use
+func OpenTarArchive(path string) (*TarArchive, error) {
+	file, err := openTar(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]tarArchiveEntry{}
+	tf := tar.NewReader(file)
+	for {
+		hdr, err := tf.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("Reading tar '%s': %s", path, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// The tar reader consumes the header blocks exactly, so the file is positioned at the start of the contents
+		offset, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("Finding the position of '%s' in tar '%s': %s", hdr.Name, path, err)
+		}
+		entries[hdr.Name] = tarArchiveEntry{offset: offset, size: hdr.Size}
+	}
+
+	// Tars created before the format was versioned do not have the format entry
+	if format, found := entries[TarFormatFileName]; found {
+		err := validateTarFormat(io.NewSectionReader(file, format.offset, format.size))
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	return &TarArchive{file: file, entries: entries}, nil
+}
+
+// Descriptors Returns the descriptors of the images and indexes in the archive
+func (a *TarArchive) Descriptors() ([]imagedesc.ImageOrImageIndexDescriptor, error) {
+	contents, err := a.open("manifest.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var descs []imagedesc.ImageOrImageIndexDescriptor
+	err = json.NewDecoder(contents).Decode(&descs)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing manifest.json: %s", err)
+	}
+	return descs, nil
+}
+
+// Blob Returns the contents of the blob, errors when the blob was not included in the archive
+func (a *TarArchive) Blob(digest regv1.Hash) (*io.SectionReader, error) {
+	return a.open(blobFileName(digest))
+}
+
+// HasBlob Checks if the blob was included in the archive
+func (a *TarArchive) HasBlob(digest regv1.Hash) bool {
+	_, found := a.entries[blobFileName(digest)]
+	return found
+}
+
+// Close Closes the underlying file
+func (a *TarArchive) Close() error {
+	return a.file.Close()
+}
+
+func (a *TarArchive) open(name string) (*io.SectionReader, error) {
+	entry, found := a.entries[name]
+	if !found {
+		return nil, fmt.Errorf("Expected to find '%s' in the tar", name)
+	}
+	return io.NewSectionReader(a.file, entry.offset, entry.size), nil
+}
@@ -0,0 +1,238 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagedesc"
+)
+
+const (
+	// TarBaseFileName Name of the entry of a delta tar that references the tar it was exported on top of
+	TarBaseFileName = "imgpkg-base.json"
+)
+
+// TarBaseReference Contents of the base entry of a delta tar. The layers of the images of the delta tar that were
+// already present in the base tar are not written to the delta tar, they are read from the base tar when importing
+type TarBaseReference struct {
+	// Name file name of the base tar, it is looked for in the folder of the delta tar when importing
+	Name string `json:"name"`
+	// ManifestDigest digest of the manifest.json of the base tar, identifies the base tar the delta was exported with
+	ManifestDigest string `json:"manifestDigest"`
+	// Layers digests of the layers that are read from the base tar
+	Layers []string `json:"layers"`
+}
+
+// BaseTar Tar previously exported that a new export is written on top of, only the layers that are not present in it
+// are written to the new tar
+type BaseTar struct {
+	path           string
+	manifestDigest string
+	// layers present in the tar, or in its own base tar when it is also a delta tar
+	layers map[string]struct{}
+}
+
+// OpenBaseTar Reads the layers present in the tar in path, it can be a delta tar or the index of a split tar
+func OpenBaseTar(path string) (*BaseTar, error) {
+	_, found, err := NewTarCheckpoint(path).Completed()
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return nil, fmt.Errorf("Expected base tar '%s' to be complete, but its copy was interrupted (hint: resume it with --resume first)", path)
+	}
+
+	err = ValidateTarFormat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := openTar(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	base := &BaseTar{path: path, layers: map[string]struct{}{}}
+	tf := tar.NewReader(file)
+	for {
+		hdr, err := tf.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Reading base tar '%s': %s", path, err)
+		}
+
+		switch {
+		case hdr.Name == "manifest.json":
+			base.manifestDigest, err = sha256Digest(tf)
+		case hdr.Name == TarBaseFileName:
+			var ref TarBaseReference
+			err = json.NewDecoder(tf).Decode(&ref)
+			for _, layer := range ref.Layers {
+				base.layers[layer] = struct{}{}
+			}
+		case strings.HasSuffix(hdr.Name, ".tar.gz"):
+			base.layers[layerDigestFromFileName(hdr.Name)] = struct{}{}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Reading %s of base tar '%s': %s", hdr.Name, path, err)
+		}
+	}
+	if base.manifestDigest == "" {
+		return nil, fmt.Errorf("Expected base tar '%s' to contain manifest.json", path)
+	}
+	return base, nil
+}
+
+// Contains Returns true when the layer is present in the base tar
+func (b *BaseTar) Contains(digest regv1.Hash) bool {
+	_, found := b.layers[digest.String()]
+	return found
+}
+
+// reference Returns the base entry of a delta tar with the images in ids, it lists the layers of the images that are
+// present in the base tar
+func (b *BaseTar) reference(ids *imagedesc.ImageRefDescriptors) TarBaseReference {
+	ref := TarBaseReference{Name: filepath.Base(b.path), ManifestDigest: b.manifestDigest, Layers: []string{}}
+	seen := map[string]struct{}{}
+	for _, layer := range imageRefDescriptorsLayers(ids) {
+		if _, found := b.layers[layer.Digest]; !found {
+			continue
+		}
+		if _, found := seen[layer.Digest]; found {
+			continue
+		}
+		seen[layer.Digest] = struct{}{}
+		ref.Layers = append(ref.Layers, layer.Digest)
+	}
+	sort.Strings(ref.Layers)
+	return ref
+}
+
+// readTarBaseReference Reads the base entry of the tar in path, returns false when the tar is not a delta tar
+func readTarBaseReference(path string) (TarBaseReference, bool, error) {
+	file, err := openTar(path)
+	if err != nil {
+		return TarBaseReference{}, false, err
+	}
+	defer file.Close()
+
+	tf := tar.NewReader(file)
+	for {
+		hdr, err := tf.Next()
+		if err == io.EOF {
+			return TarBaseReference{}, false, nil
+		}
+		if err != nil {
+			return TarBaseReference{}, false, fmt.Errorf("Reading tar '%s': %s", path, err)
+		}
+
+		switch hdr.Name {
+		case TarBaseFileName:
+			var ref TarBaseReference
+			err = json.NewDecoder(tf).Decode(&ref)
+			if err != nil {
+				return TarBaseReference{}, false, fmt.Errorf("Parsing %s: %s", TarBaseFileName, err)
+			}
+			return ref, true, nil
+		case "manifest.json":
+			// The base entry is always written before the descriptors of the images
+			return TarBaseReference{}, false, nil
+		}
+	}
+}
+
+// baseTarLayerProvider Provides the layers of a delta tar, reading from the base tar the layers that were not written
+// to the delta tar
+type baseTarLayerProvider struct {
+	delta      imagedesc.LayerProvider
+	base       imagedesc.LayerProvider
+	baseLayers map[string]struct{}
+}
+
+var _ imagedesc.LayerProvider = baseTarLayerProvider{}
+
+// newBaseTarLayerProvider Checks that the tar in basePath is the one the delta tar was exported with
+func newBaseTarLayerProvider(delta imagedesc.LayerProvider, ref TarBaseReference, basePath string) (baseTarLayerProvider, error) {
+	manifest, err := tarFile{basePath}.openChunk("manifest.json")
+	if err != nil {
+		return baseTarLayerProvider{}, fmt.Errorf("Reading base tar '%s' (hint: provide the tar the delta tar was exported with, using --base-tar): %s", basePath, err)
+	}
+	manifestDigest, err := sha256Digest(manifest)
+	manifest.Close()
+	if err != nil {
+		return baseTarLayerProvider{}, err
+	}
+	if manifestDigest != ref.ManifestDigest {
+		return baseTarLayerProvider{}, fmt.Errorf("Expected base tar '%s' to be the tar '%s' the delta tar was exported with, but its images are different", basePath, ref.Name)
+	}
+
+	base, err := NewTarReader(basePath).layerProvider()
+	if err != nil {
+		return baseTarLayerProvider{}, err
+	}
+
+	provider := baseTarLayerProvider{delta: delta, base: base, baseLayers: map[string]struct{}{}}
+	for _, layer := range ref.Layers {
+		provider.baseLayers[layer] = struct{}{}
+	}
+	return provider, nil
+}
+
+// FindLayer Returns the layer from the base tar when it was not written to the delta tar
+func (p baseTarLayerProvider) FindLayer(layerTD imagedesc.ImageLayerDescriptor) (imagedesc.LayerContents, error) {
+	if _, found := p.baseLayers[layerTD.Digest]; found {
+		return p.base.FindLayer(layerTD)
+	}
+	return p.delta.FindLayer(layerTD)
+}
+
+// imageRefDescriptorsLayers Returns the layers of all the images, including the ones of the indexes
+func imageRefDescriptorsLayers(ids *imagedesc.ImageRefDescriptors) []imagedesc.ImageLayerDescriptor {
+	var layers []imagedesc.ImageLayerDescriptor
+	var addIndex func(td imagedesc.ImageIndexDescriptor)
+	addIndex = func(td imagedesc.ImageIndexDescriptor) {
+		for _, img := range td.Images {
+			layers = append(layers, img.Layers...)
+		}
+		for _, idx := range td.Indexes {
+			addIndex(idx)
+		}
+	}
+
+	for _, td := range ids.Descriptors() {
+		switch {
+		case td.Image != nil:
+			layers = append(layers, td.Image.Layers...)
+		case td.ImageIndex != nil:
+			addIndex(*td.ImageIndex)
+		}
+	}
+	return layers
+}
+
+// layerDigestFromFileName Returns the digest of the layer stored in the entry, the opposite of blobFileName
+func layerDigestFromFileName(name string) string {
+	return strings.Replace(strings.TrimSuffix(name, ".tar.gz"), "-", ":", 1)
+}
+
+func sha256Digest(r io.Reader) (string, error) {
+	hash := sha256.New()
+	_, err := io.Copy(hash, r)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", hash.Sum(nil)), nil
+}
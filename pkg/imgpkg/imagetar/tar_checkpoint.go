@@ -0,0 +1,190 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"archive/tar"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// TarCheckpoint Records, in a file next to the tar, the digests of the layers that were completely written to the tar.
+// When the copy to tar is interrupted, the layers in the checkpoint are reused by the next copy with --resume
+// instead of being downloaded again. The checkpoint is removed once the tar is complete
+type TarCheckpoint struct {
+	path string
+	lock sync.Mutex
+}
+
+// NewTarCheckpoint constructor for the checkpoint of the tar in tarPath
+func NewTarCheckpoint(tarPath string) *TarCheckpoint {
+	return &TarCheckpoint{path: tarPath + ".checkpoint"}
+}
+
+// Record Appends the digest of a layer that was completely written to the tar
+func (c *TarCheckpoint) Record(digest regv1.Hash) error {
+	if c == nil {
+		return nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	file, err := os.OpenFile(c.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("Opening checkpoint '%s': %s", c.path, err)
+	}
+	_, err = fmt.Fprintln(file, digest.String())
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("Writing checkpoint '%s': %s", c.path, err)
+	}
+	return file.Close()
+}
+
+// Completed Returns the digests of the layers recorded in the checkpoint, false when there is no checkpoint
+func (c *TarCheckpoint) Completed() (map[regv1.Hash]struct{}, bool, error) {
+	file, err := os.Open(c.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("Opening checkpoint '%s': %s", c.path, err)
+	}
+	defer file.Close()
+
+	completed := map[regv1.Hash]struct{}{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		digest, err := regv1.NewHash(line)
+		if err != nil {
+			// The last line is incomplete when the copy was interrupted while writing it
+			continue
+		}
+		completed[digest] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, false, fmt.Errorf("Reading checkpoint '%s': %s", c.path, err)
+	}
+	return completed, true, nil
+}
+
+// Read Returns the contents of the checkpoint file, nil when there is no checkpoint
+func (c *TarCheckpoint) Read() ([]byte, error) {
+	bs, err := os.ReadFile(c.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("Reading checkpoint '%s': %s", c.path, err)
+	}
+	return bs, nil
+}
+
+// Restore Replaces the contents of the checkpoint file, removing it when contents is nil
+func (c *TarCheckpoint) Restore(contents []byte) error {
+	if contents == nil {
+		return c.Remove()
+	}
+	return os.WriteFile(c.path, contents, 0600)
+}
+
+// Remove Deletes the checkpoint file
+func (c *TarCheckpoint) Remove() error {
+	err := os.Remove(c.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Removing checkpoint '%s': %s", c.path, err)
+	}
+	return nil
+}
+
+// Layers Returns the layers of the tar in tarPath that are recorded in the checkpoint, without reading their contents.
+// The tar is only scanned until the first incomplete entry, so that the tar of an interrupted copy can be used
+func (c *TarCheckpoint) Layers(tarPath string) ([]regv1.Layer, error) {
+	completed, found, err := c.Completed()
+	if err != nil || !found {
+		return nil, err
+	}
+
+	file, err := os.Open(tarPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	completedNames := map[string]regv1.Hash{}
+	for digest := range completed {
+		completedNames[blobFileName(digest)] = digest
+	}
+
+	var layers []regv1.Layer
+	tf := tar.NewReader(file)
+	for {
+		hdr, err := tf.Next()
+		if err != nil {
+			// Either the end of the tar or the entry that was being written when the copy was interrupted
+			break
+		}
+
+		offset, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, fmt.Errorf("Finding the position of '%s' in tar '%s': %s", hdr.Name, tarPath, err)
+		}
+		if offset+hdr.Size > info.Size() {
+			break
+		}
+
+		digest, isCompleted := completedNames[hdr.Name]
+		if !isCompleted {
+			continue
+		}
+
+		layer, err := partial.CompressedToLayer(checkpointedLayer{path: tarPath, digest: digest, offset: offset, size: hdr.Size})
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
+	}
+	return layers, nil
+}
+
+// checkpointedLayer Layer stored in a tar entry, the contents are read directly from the tar file
+type checkpointedLayer struct {
+	path   string
+	digest regv1.Hash
+	offset int64
+	size   int64
+}
+
+func (l checkpointedLayer) Digest() (regv1.Hash, error) { return l.digest, nil }
+
+func (l checkpointedLayer) Size() (int64, error) { return l.size, nil }
+
+func (l checkpointedLayer) MediaType() (types.MediaType, error) { return types.DockerLayer, nil }
+
+func (l checkpointedLayer) Compressed() (io.ReadCloser, error) {
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, err
+	}
+	return sectionReadCloser{SectionReader: io.NewSectionReader(file, l.offset, l.size), file: file}, nil
+}
+
+type sectionReadCloser struct {
+	*io.SectionReader
+	file *os.File
+}
+
+func (s sectionReadCloser) Close() error { return s.file.Close() }
@@ -0,0 +1,120 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagetar"
+)
+
+func TestTarCheckpoint(t *testing.T) {
+	blobA := bytes.Repeat([]byte("a"), 1000)
+	blobB := bytes.Repeat([]byte("b"), 1000)
+	blobC := bytes.Repeat([]byte("c"), 1000)
+	digestA, _, err := regv1.SHA256(bytes.NewReader(blobA))
+	require.NoError(t, err)
+	digestB, _, err := regv1.SHA256(bytes.NewReader(blobB))
+	require.NoError(t, err)
+	digestC, _, err := regv1.SHA256(bytes.NewReader(blobC))
+	require.NoError(t, err)
+
+	// writeInterruptedTar writes a tar whose last entry, blob C, was only partially written
+	writeInterruptedTar := func(t *testing.T) string {
+		buf := &bytes.Buffer{}
+		tw := tar.NewWriter(buf)
+		for _, entry := range []struct {
+			digest regv1.Hash
+			blob   []byte
+		}{{digestA, blobA}, {digestB, blobB}, {digestC, blobC}} {
+			require.NoError(t, tw.WriteHeader(&tar.Header{
+				Name: entry.digest.Algorithm + "-" + entry.digest.Hex + ".tar.gz", Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(entry.blob)),
+			}))
+			_, err := tw.Write(entry.blob)
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Flush())
+
+		path := filepath.Join(t.TempDir(), "bundle.tar")
+		require.NoError(t, os.WriteFile(path, buf.Bytes()[:buf.Len()-1024], 0600))
+		return path
+	}
+
+	t.Run("returns the layers recorded in the checkpoint that are complete in the tar", func(t *testing.T) {
+		tarPath := writeInterruptedTar(t)
+		checkpoint := imagetar.NewTarCheckpoint(tarPath)
+		require.NoError(t, checkpoint.Record(digestA))
+		require.NoError(t, checkpoint.Record(digestC))
+
+		layers, err := checkpoint.Layers(tarPath)
+		require.NoError(t, err)
+		require.Len(t, layers, 1)
+
+		digest, err := layers[0].Digest()
+		require.NoError(t, err)
+		assert.Equal(t, digestA, digest)
+		contents, err := layers[0].Compressed()
+		require.NoError(t, err)
+		defer contents.Close()
+		bs, err := io.ReadAll(contents)
+		require.NoError(t, err)
+		assert.Equal(t, blobA, bs)
+	})
+
+	t.Run("ignores the digest that was being recorded when the copy was interrupted", func(t *testing.T) {
+		tarPath := writeInterruptedTar(t)
+		checkpoint := imagetar.NewTarCheckpoint(tarPath)
+		require.NoError(t, checkpoint.Record(digestB))
+		file, err := os.OpenFile(tarPath+".checkpoint", os.O_APPEND|os.O_WRONLY, 0600)
+		require.NoError(t, err)
+		_, err = file.WriteString(digestA.String()[:20])
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		completed, found, err := checkpoint.Completed()
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, map[regv1.Hash]struct{}{digestB: {}}, completed)
+	})
+
+	t.Run("returns no layers when there is no checkpoint", func(t *testing.T) {
+		tarPath := writeInterruptedTar(t)
+		checkpoint := imagetar.NewTarCheckpoint(tarPath)
+
+		_, found, err := checkpoint.Completed()
+		require.NoError(t, err)
+		assert.False(t, found)
+
+		layers, err := checkpoint.Layers(tarPath)
+		require.NoError(t, err)
+		assert.Empty(t, layers)
+	})
+
+	t.Run("restores the previous contents of the checkpoint", func(t *testing.T) {
+		tarPath := writeInterruptedTar(t)
+		checkpoint := imagetar.NewTarCheckpoint(tarPath)
+		require.NoError(t, checkpoint.Record(digestA))
+
+		previous, err := checkpoint.Read()
+		require.NoError(t, err)
+		require.NoError(t, checkpoint.Remove())
+		require.NoError(t, checkpoint.Record(digestB))
+		require.NoError(t, checkpoint.Restore(previous))
+
+		completed, _, err := checkpoint.Completed()
+		require.NoError(t, err)
+		assert.Equal(t, map[regv1.Hash]struct{}{digestA: {}}, completed)
+
+		require.NoError(t, checkpoint.Restore(nil))
+		assert.NoFileExists(t, tarPath+".checkpoint")
+	})
+}
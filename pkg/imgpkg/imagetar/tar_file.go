@@ -7,7 +7,6 @@ import (
 	"archive/tar"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
@@ -51,7 +50,7 @@ func (f tarFileChunk) Open() (io.ReadCloser, error) {
 }
 
 func (f tarFile) openChunk(path string) (io.ReadCloser, error) {
-	file, err := os.Open(f.path)
+	file, err := openTar(f.path)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,86 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Layout of the tars created by imgpkg, version 1:
+//
+//	imgpkg-format.json               Version of the format, always the first entry
+//	manifest.json                    Descriptors of the images and indexes, including their manifests and configs
+//	<algorithm>-<hex>.tar.gz         One entry per layer, named after its digest (e.g. sha256-abc...def.tar.gz)
+//
+// Version 2 is used by delta tars, exported on top of a base tar. They have an imgpkg-base.json entry, before
+// manifest.json, that references the base tar, and the layers present in the base tar are not written to them.
+// Tars that are not delta tars keep being written with version 1, so that older imgpkg can still read them.
+//
+// Tars created before the format was versioned do not have imgpkg-format.json and are read as version 1.
+// Any change that an older imgpkg would not read correctly, e.g. compressing layers with zstd or splitting the
+// tar in multiple files, needs to increase TarFormatVersion so that older imgpkg fail instead of importing
+// corrupted images
+
+const (
+	// TarFormatFileName Name of the entry that records the version of the format of the tar
+	TarFormatFileName = "imgpkg-format.json"
+	// TarFormatVersion Newest version of the format that can be read
+	TarFormatVersion = 2
+
+	// tarFormatVersionDefault Version of the format of the tars that are not delta tars
+	tarFormatVersionDefault = 1
+	// tarFormatVersionDelta Version of the format of the delta tars, exported on top of a base tar
+	tarFormatVersionDelta = 2
+)
+
+// TarFormat Contents of the format entry of the tar
+type TarFormat struct {
+	Version int `json:"version"`
+}
+
+func tarFormatBytes(version int) ([]byte, error) {
+	return json.Marshal(TarFormat{Version: version})
+}
+
+// ValidateTarFormat Checks that the tar uses a version of the format that can be read
+func ValidateTarFormat(path string) error {
+	file, err := openTar(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	tf := tar.NewReader(file)
+	hdr, err := tf.Next()
+	if err != nil {
+		return fmt.Errorf("Reading tar '%s': %s", path, err)
+	}
+
+	if hdr.Name != TarFormatFileName {
+		// Created before the format was versioned
+		return nil
+	}
+
+	return validateTarFormat(tf)
+}
+
+func validateTarFormat(contents io.Reader) error {
+	var format TarFormat
+	err := json.NewDecoder(contents).Decode(&format)
+	if err != nil {
+		return fmt.Errorf("Parsing %s: %s", TarFormatFileName, err)
+	}
+
+	if format.Version > TarFormatVersion {
+		return fmt.Errorf("Expected tar format version to be at most %d but was %d "+
+			"(hint: the tar was created by a newer version of imgpkg, upgrade imgpkg to read it)", TarFormatVersion, format.Version)
+	}
+	if format.Version < 1 {
+		return fmt.Errorf("Expected tar format version to be between 1 and %d but was %d", TarFormatVersion, format.Version)
+	}
+	return nil
+}
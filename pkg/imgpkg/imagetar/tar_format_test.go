@@ -0,0 +1,90 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar_test
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagedesc"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagetar"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestTarFormat(t *testing.T) {
+	t.Run("the format version is the first entry of the tars created", func(t *testing.T) {
+		tarPath := filepath.Join(t.TempDir(), "images.tar")
+		ids, err := imagedesc.NewImageRefDescriptorsFromBytes([]byte("[]"))
+		require.NoError(t, err)
+
+		store := imagetar.NewTarBlobStore(func() (io.WriteCloser, error) {
+			return os.Create(tarPath)
+		}, imagetar.TarWriterOpts{Concurrency: 1}, &helpers.Logger{LogLevel: helpers.LogDebug})
+		require.NoError(t, store.WriteDescriptors(ids))
+		require.NoError(t, store.Close())
+
+		file, err := os.Open(tarPath)
+		require.NoError(t, err)
+		defer file.Close()
+
+		hdr, err := tar.NewReader(file).Next()
+		require.NoError(t, err)
+		assert.Equal(t, imagetar.TarFormatFileName, hdr.Name)
+
+		_, err = imagetar.NewTarReader(tarPath).Read()
+		require.NoError(t, err)
+	})
+
+	t.Run("tars created before the format was versioned can be read", func(t *testing.T) {
+		tarPath := writeTar(t, map[string]string{"manifest.json": "[]"}, []string{"manifest.json"})
+
+		require.NoError(t, imagetar.ValidateTarFormat(tarPath))
+		_, err := imagetar.NewTarReader(tarPath).Read()
+		require.NoError(t, err)
+	})
+
+	t.Run("tars created with a newer format cannot be read", func(t *testing.T) {
+		tarPath := writeTar(t, map[string]string{
+			imagetar.TarFormatFileName: `{"version": 3}`,
+			"manifest.json":            "[]",
+		}, []string{imagetar.TarFormatFileName, "manifest.json"})
+
+		_, err := imagetar.NewTarReader(tarPath).Read()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Expected tar format version to be at most 2 but was 3")
+		assert.Contains(t, err.Error(), "upgrade imgpkg")
+	})
+
+	t.Run("tars with an invalid format version cannot be read", func(t *testing.T) {
+		tarPath := writeTar(t, map[string]string{
+			imagetar.TarFormatFileName: `{"version": 0}`,
+			"manifest.json":            "[]",
+		}, []string{imagetar.TarFormatFileName, "manifest.json"})
+
+		err := imagetar.ValidateTarFormat(tarPath)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Expected tar format version to be between 1 and 2 but was 0")
+	})
+}
+
+func writeTar(t *testing.T, files map[string]string, order []string) string {
+	tarPath := filepath.Join(t.TempDir(), "images.tar")
+	file, err := os.Create(tarPath)
+	require.NoError(t, err)
+	defer file.Close()
+
+	tw := tar.NewWriter(file)
+	for _, name := range order {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(files[name]))}))
+		_, err := tw.Write([]byte(files[name]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return tarPath
+}
@@ -0,0 +1,254 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// TarPartsAPIVersion API version of the index of a tar split in parts
+	TarPartsAPIVersion = "imgpkg.carvel.dev/v1alpha1"
+	// TarPartsKind Kind of the index of a tar split in parts
+	TarPartsKind = "TarParts"
+
+	// maxTarPartsIndexSize The index is small, bigger files are not even parsed when checking if a file is an index
+	maxTarPartsIndexSize = 1024 * 1024
+)
+
+// TarParts Index of a tar that was split in parts, so that it fits on media with a maximum file size.
+// The parts are stored in the same folder as the index, concatenated in order they form the original tar
+type TarParts struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Parts      []TarPart `json:"parts"`
+}
+
+// TarPart Single part of a tar, the name is relative to the folder of the index
+type TarPart struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// TarPartsIndexPath Returns the path of the index written when splitting the tar in tarPath
+func TarPartsIndexPath(tarPath string) string {
+	return tarPath + ".parts.yml"
+}
+
+// SplitTar Splits the tar in parts of at most partSize bytes, named <tar>.001, <tar>.002 and so on, writes their
+// index next to them and removes the tar. Returns the path of the index
+func SplitTar(tarPath string, partSize int64) (string, error) {
+	if partSize <= 0 {
+		return "", fmt.Errorf("Expected the size of the tar parts to be greater than 0")
+	}
+
+	file, err := os.Open(tarPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	index := TarParts{APIVersion: TarPartsAPIVersion, Kind: TarPartsKind}
+	for i := 1; ; i++ {
+		partPath := fmt.Sprintf("%s.%03d", tarPath, i)
+		size, err := writeTarPart(partPath, io.LimitReader(file, partSize))
+		if err != nil {
+			return "", err
+		}
+		if size == 0 {
+			err = os.Remove(partPath)
+			if err != nil {
+				return "", err
+			}
+			break
+		}
+		index.Parts = append(index.Parts, TarPart{Name: filepath.Base(partPath), Size: size})
+		if size < partSize {
+			break
+		}
+	}
+
+	indexBytes, err := yaml.Marshal(index)
+	if err != nil {
+		return "", err
+	}
+	indexPath := TarPartsIndexPath(tarPath)
+	err = os.WriteFile(indexPath, indexBytes, 0600)
+	if err != nil {
+		return "", fmt.Errorf("Writing tar parts index '%s': %s", indexPath, err)
+	}
+
+	file.Close()
+	err = os.Remove(tarPath)
+	if err != nil {
+		return "", err
+	}
+	return indexPath, nil
+}
+
+func writeTarPart(path string, contents io.Reader) (int64, error) {
+	part, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("Creating tar part '%s': %s", path, err)
+	}
+	size, err := io.Copy(part, contents)
+	if err != nil {
+		part.Close()
+		return 0, fmt.Errorf("Writing tar part '%s': %s", path, err)
+	}
+	return size, part.Close()
+}
+
+// tarSource Random access to the contents of a tar, either a single file or all the parts of a split tar
+type tarSource interface {
+	io.ReadSeeker
+	io.ReaderAt
+	io.Closer
+}
+
+// openTar Opens the tar in path. When path is the index of a split tar, its parts are read as a single tar
+func openTar(path string) (tarSource, error) {
+	index, isIndex, err := readTarPartsIndex(path)
+	if err != nil {
+		return nil, err
+	}
+	if !isIndex {
+		return os.Open(path)
+	}
+
+	tar := &tarPartsFile{}
+	for _, part := range index.Parts {
+		partPath := filepath.Join(filepath.Dir(path), part.Name)
+		file, err := os.Open(partPath)
+		if err != nil {
+			tar.Close()
+			return nil, fmt.Errorf("Opening tar part: %s", err)
+		}
+		tar.files = append(tar.files, file)
+
+		info, err := file.Stat()
+		if err != nil {
+			tar.Close()
+			return nil, err
+		}
+		if info.Size() != part.Size {
+			tar.Close()
+			return nil, fmt.Errorf("Expected tar part '%s' to have %d bytes, but it has %d bytes", partPath, part.Size, info.Size())
+		}
+		tar.offsets = append(tar.offsets, tar.size)
+		tar.size += part.Size
+	}
+	return tar, nil
+}
+
+// readTarPartsIndex Reads the index in path, returns false when the file is not an index
+func readTarPartsIndex(path string) (TarParts, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return TarParts{}, false, err
+	}
+	if info.IsDir() || info.Size() > maxTarPartsIndexSize {
+		return TarParts{}, false, nil
+	}
+
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return TarParts{}, false, err
+	}
+	var index TarParts
+	err = yaml.Unmarshal(bs, &index)
+	if err != nil || index.Kind != TarPartsKind {
+		return TarParts{}, false, nil
+	}
+	if index.APIVersion != TarPartsAPIVersion {
+		return TarParts{}, false, fmt.Errorf("Validating tar parts index '%s': Unknown version (known: %s)", path, TarPartsAPIVersion)
+	}
+	if len(index.Parts) == 0 {
+		return TarParts{}, false, fmt.Errorf("Expected tar parts index '%s' to have at least one part", path)
+	}
+	return index, true, nil
+}
+
+// tarPartsFile Reads the parts of a split tar as if they were a single file
+type tarPartsFile struct {
+	files   []*os.File
+	offsets []int64
+	size    int64
+	pos     int64
+}
+
+var _ tarSource = &tarPartsFile{}
+
+// Read Reads from the current position, moving to the next part when the end of a part is reached
+func (t *tarPartsFile) Read(p []byte) (int, error) {
+	n, err := t.ReadAt(p, t.pos)
+	t.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt Reads from the parts that contain the requested range
+func (t *tarPartsFile) ReadAt(p []byte, off int64) (int, error) {
+	if off >= t.size {
+		return 0, io.EOF
+	}
+
+	var read int
+	for read < len(p) && off < t.size {
+		i := len(t.offsets) - 1
+		for t.offsets[i] > off {
+			i--
+		}
+		n, err := t.files[i].ReadAt(p[read:], off-t.offsets[i])
+		read += n
+		off += int64(n)
+		if err != nil && err != io.EOF {
+			return read, err
+		}
+		if n == 0 {
+			return read, io.ErrUnexpectedEOF
+		}
+	}
+	if read < len(p) {
+		return read, io.EOF
+	}
+	return read, nil
+}
+
+// Seek Changes the position of the next Read
+func (t *tarPartsFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += t.pos
+	case io.SeekEnd:
+		offset += t.size
+	default:
+		return 0, fmt.Errorf("Unknown whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("Seeking to negative position %d", offset)
+	}
+	t.pos = offset
+	return offset, nil
+}
+
+// Close Closes all the parts
+func (t *tarPartsFile) Close() error {
+	var firstErr error
+	for _, file := range t.files {
+		err := file.Close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
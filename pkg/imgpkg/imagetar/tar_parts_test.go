@@ -0,0 +1,110 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagetar"
+)
+
+func TestSplitTar(t *testing.T) {
+	blobA := bytes.Repeat([]byte("a"), 1500)
+	blobB := bytes.Repeat([]byte("b"), 3000)
+	digestA, _, err := regv1.SHA256(bytes.NewReader(blobA))
+	require.NoError(t, err)
+	digestB, _, err := regv1.SHA256(bytes.NewReader(blobB))
+	require.NoError(t, err)
+
+	writeTar := func(t *testing.T) (string, []byte) {
+		buf := &bytes.Buffer{}
+		tw := tar.NewWriter(buf)
+		for _, entry := range []struct {
+			name     string
+			contents []byte
+		}{
+			{"manifest.json", []byte("[]")},
+			{digestA.Algorithm + "-" + digestA.Hex + ".tar.gz", blobA},
+			{digestB.Algorithm + "-" + digestB.Hex + ".tar.gz", blobB},
+		} {
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: entry.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(entry.contents))}))
+			_, err := tw.Write(entry.contents)
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+
+		path := filepath.Join(t.TempDir(), "bundle.tar")
+		require.NoError(t, os.WriteFile(path, buf.Bytes(), 0600))
+		return path, buf.Bytes()
+	}
+
+	t.Run("splits the tar in numbered parts and writes their index", func(t *testing.T) {
+		tarPath, tarBytes := writeTar(t)
+
+		indexPath, err := imagetar.SplitTar(tarPath, 2000)
+		require.NoError(t, err)
+		assert.Equal(t, tarPath+".parts.yml", indexPath)
+		assert.NoFileExists(t, tarPath)
+
+		// 7680 bytes: the headers and contents of the 3 entries, padded to 512 bytes, and the footer
+		var joined []byte
+		for i, expectedSize := range []int{2000, 2000, 2000, 1680} {
+			part, err := os.ReadFile(fmt.Sprintf("%s.%03d", tarPath, i+1))
+			require.NoError(t, err)
+			assert.Len(t, part, expectedSize)
+			joined = append(joined, part...)
+		}
+		assert.True(t, bytes.Equal(tarBytes, joined), "the parts should form the tar")
+		assert.NoFileExists(t, tarPath+".005")
+
+		index, err := os.ReadFile(indexPath)
+		require.NoError(t, err)
+		assert.Contains(t, string(index), "kind: TarParts")
+		assert.Contains(t, string(index), "name: bundle.tar.001")
+	})
+
+	t.Run("reads the blobs of a split tar from its index", func(t *testing.T) {
+		tarPath, _ := writeTar(t)
+		indexPath, err := imagetar.SplitTar(tarPath, 700)
+		require.NoError(t, err)
+
+		archive, err := imagetar.OpenTarArchive(indexPath)
+		require.NoError(t, err)
+		defer archive.Close()
+
+		descriptors, err := archive.Descriptors()
+		require.NoError(t, err)
+		assert.Empty(t, descriptors)
+
+		for digest, expected := range map[regv1.Hash][]byte{digestA: blobA, digestB: blobB} {
+			require.True(t, archive.HasBlob(digest))
+			blob, err := archive.Blob(digest)
+			require.NoError(t, err)
+			contents, err := io.ReadAll(blob)
+			require.NoError(t, err)
+			assert.Equal(t, expected, contents)
+		}
+
+		require.NoError(t, imagetar.ValidateTarFormat(indexPath))
+	})
+
+	t.Run("fails when a part does not have the size recorded in the index", func(t *testing.T) {
+		tarPath, _ := writeTar(t)
+		indexPath, err := imagetar.SplitTar(tarPath, 2000)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(tarPath+".002", []byte("truncated"), 0600))
+
+		_, err = imagetar.OpenTarArchive(indexPath)
+		require.EqualError(t, err, "Expected tar part '"+tarPath+".002' to have 2000 bytes, but it has 9 bytes")
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"path/filepath"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -16,10 +17,29 @@ import (
 
 type TarReader struct {
 	path string
+	// basePath location of the base tar of a delta tar, when empty it is looked for next to the tar
+	basePath string
+
+	originRegistry OriginRegistry
+	logger         Logger
 }
 
 func NewTarReader(path string) TarReader {
-	return TarReader{path}
+	return TarReader{path: path}
+}
+
+// WithOriginFallback Retrieves the layers that are missing from the tar from the repositories the images were exported from
+func (r TarReader) WithOriginFallback(registry OriginRegistry, logger Logger) TarReader {
+	r.originRegistry = registry
+	r.logger = logger
+	return r
+}
+
+// WithBaseTar Reads the layers that are not present in a delta tar from the base tar in path, instead of the file next
+// to the delta tar with the name of the base tar it was exported with
+func (r TarReader) WithBaseTar(path string) TarReader {
+	r.basePath = path
+	return r
 }
 
 func (r TarReader) Read() ([]imagedesc.ImageOrIndex, error) {
@@ -30,7 +50,46 @@ func (r TarReader) Read() ([]imagedesc.ImageOrIndex, error) {
 		return nil, err
 	}
 
-	return imagedesc.NewDescribedReader(ids, file).Read(), nil
+	layerProvider, err := r.layerProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	if r.originRegistry == nil {
+		return imagedesc.NewDescribedReader(ids, layerProvider).Read(), nil
+	}
+
+	presentLayers, err := NewTarReader(r.path).WithBaseTar(r.basePath).PresentLayers()
+	if err != nil {
+		return nil, err
+	}
+
+	fallbackLayerProvider, err := newOriginFallbackLayerProvider(layerProvider, presentLayers, ids, r.originRegistry, r.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return imagedesc.NewDescribedReader(ids, fallbackLayerProvider).Read(), nil
+}
+
+// layerProvider Returns the provider of the layers of the tar, when it is a delta tar the layers that were not written
+// to it are read from its base tar
+func (r TarReader) layerProvider() (imagedesc.LayerProvider, error) {
+	file := tarFile{r.path}
+
+	ref, isDelta, err := readTarBaseReference(r.path)
+	if err != nil {
+		return nil, err
+	}
+	if !isDelta {
+		return file, nil
+	}
+
+	basePath := r.basePath
+	if basePath == "" {
+		basePath = filepath.Join(filepath.Dir(r.path), ref.Name)
+	}
+	return newBaseTarLayerProvider(file, ref, basePath)
 }
 
 // PresentLayers retrieves all the layers that are present in a tar file
@@ -132,6 +191,11 @@ func (r TarReader) presentLayersForIndex(indexRef string, idx v1.ImageIndex) ([]
 }
 
 func (r TarReader) getIdsFromManifest(file tarFile) (*imagedesc.ImageRefDescriptors, error) {
+	err := ValidateTarFormat(file.path)
+	if err != nil {
+		return nil, err
+	}
+
 	manifestFile, err := file.Chunk("manifest.json").Open()
 	if err != nil {
 		return nil, err
@@ -6,6 +6,7 @@ package imagetar
 import (
 	"archive/tar"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -14,6 +15,7 @@ import (
 
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagedesc"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/tracing"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
 )
 
@@ -23,20 +25,15 @@ type Logger interface {
 
 type TarWriterOpts struct {
 	Concurrency int
+	// Checkpoint when provided records the layers that were completely written to the tar
+	Checkpoint *TarCheckpoint
+	// Base when provided the tar is a delta tar, the layers present in the base tar are not written to it
+	Base *BaseTar
 }
 
+// TarWriter Exports images and their layers to a single tarball
 type TarWriter struct {
-	ids       *imagedesc.ImageRefDescriptors
-	dstOpener func() (io.WriteCloser, error)
-
-	dst           io.WriteCloser
-	tf            *tar.Writer
-	layersToWrite []imagedesc.ImageLayerDescriptor
-
-	opts                  TarWriterOpts
-	logger                Logger
-	imageLayerWriterCheck ImageLayerWriterFilter
-	layersFromOtherSource []regv1.Layer
+	*Writer
 }
 
 // NewTarWriter constructor returning a mechanism to write image refs / layers to a tarball on disk.
@@ -44,128 +41,106 @@ func NewTarWriter(ids *imagedesc.ImageRefDescriptors, dstOpener func() (io.Write
 	opts TarWriterOpts, logger Logger, imageLayerWriterCheck ImageLayerWriterFilter,
 	layersFromOtherSource []regv1.Layer) *TarWriter {
 	return &TarWriter{
-		ids:                   ids,
-		dstOpener:             dstOpener,
-		opts:                  opts,
-		logger:                logger,
-		imageLayerWriterCheck: imageLayerWriterCheck,
-		layersFromOtherSource: layersFromOtherSource,
+		Writer: NewWriter(ids, NewTarBlobStore(dstOpener, opts, logger), imageLayerWriterCheck, layersFromOtherSource),
 	}
 }
 
-func (w *TarWriter) Write() error {
+// TarBlobStore BlobStore that writes all the images and layers to a single tarball
+type TarBlobStore struct {
+	dstOpener func() (io.WriteCloser, error)
+	opts      TarWriterOpts
+	logger    Logger
+
+	dst io.WriteCloser
+	tf  *tar.Writer
+}
+
+var _ BlobStore = &TarBlobStore{}
+
+// NewTarBlobStore constructor for TarBlobStore.
+// When the destination is a file and concurrency is greater than 1 the layers are written in parallel
+func NewTarBlobStore(dstOpener func() (io.WriteCloser, error), opts TarWriterOpts, logger Logger) *TarBlobStore {
+	return &TarBlobStore{dstOpener: dstOpener, opts: opts, logger: logger}
+}
+
+// WriteDescriptors writes the format version and the images descriptors to the first entries of the tarball
+func (w *TarBlobStore) WriteDescriptors(ids *imagedesc.ImageRefDescriptors) error {
 	var err error
 
 	w.dst, err = w.dstOpener()
 	if err != nil {
 		return err
 	}
-	defer w.dst.Close()
 
 	w.tf = tar.NewWriter(w.dst)
-	defer w.tf.Close()
 
-	idsBytes, err := w.ids.AsBytes()
+	formatVersion := tarFormatVersionDefault
+	if w.opts.Base != nil {
+		formatVersion = tarFormatVersionDelta
+	}
+	formatBytes, err := tarFormatBytes(formatVersion)
 	if err != nil {
 		return err
 	}
 
-	err = w.writeTarEntry(w.tf, "manifest.json", bytes.NewReader(idsBytes), int64(len(idsBytes)))
+	err = w.writeTarEntry(w.tf, TarFormatFileName, bytes.NewReader(formatBytes), int64(len(formatBytes)))
 	if err != nil {
 		return err
 	}
 
-	for _, td := range w.ids.Descriptors() {
-		switch {
-		case td.Image != nil:
-			err := w.writeImage(*td.Image)
-			if err != nil {
-				return err
-			}
-
-		case td.ImageIndex != nil:
-			err := w.writeImageIndex(*td.ImageIndex)
-			if err != nil {
-				return err
-			}
-
-		default:
-			panic("Unknown item")
-		}
-	}
-
-	return w.writeLayers()
-}
-
-func (w *TarWriter) writeImageIndex(td imagedesc.ImageIndexDescriptor) error {
-	for _, idx := range td.Indexes {
-		err := w.writeImageIndex(idx)
+	if w.opts.Base != nil {
+		baseBytes, err := json.Marshal(w.opts.Base.reference(ids))
 		if err != nil {
 			return err
 		}
-	}
-
-	for _, img := range td.Images {
-		err := w.writeImage(img)
+		err = w.writeTarEntry(w.tf, TarBaseFileName, bytes.NewReader(baseBytes), int64(len(baseBytes)))
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	idsBytes, err := ids.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	return w.writeTarEntry(w.tf, "manifest.json", bytes.NewReader(idsBytes), int64(len(idsBytes)))
 }
 
-func (w *TarWriter) writeImage(td imagedesc.ImageDescriptor) error {
-	for _, imgLayer := range td.Layers {
-		shouldLayerBeIncluded, err := w.imageLayerWriterCheck.ShouldLayerBeIncluded(imagedesc.NewDescribedCompressedLayer(imgLayer, nil))
-		if err != nil {
-			return err
-		}
-		if shouldLayerBeIncluded {
-			w.layersToWrite = append(w.layersToWrite, imgLayer)
-		}
+// Close finishes the tarball
+func (w *TarBlobStore) Close() error {
+	if w.dst == nil {
+		return nil
 	}
-	return nil
+	defer w.dst.Close()
+	return w.tf.Close()
 }
 
 type writtenLayer struct {
 	Name   string
 	Offset int64
-	Layer  imagedesc.ImageLayerDescriptor
+	Blob   Blob
 }
 
-func (w *TarWriter) writeLayers() error {
-	// Sort layers by digest to have deterministic archive
-	sort.Slice(w.layersToWrite, func(i, j int) bool {
-		return w.layersToWrite[i].Digest < w.layersToWrite[j].Digest
-	})
-
+// WriteBlobs writes each blob as an entry of the tarball
+func (w *TarBlobStore) WriteBlobs(blobs []Blob) error {
 	seekableDst, isSeekable := w.dst.(*os.File)
 	isInflatable := (w.opts.Concurrency > 1) && isSeekable
-	writtenLayers := map[string]writtenLayer{}
+	var writtenLayers []writtenLayer
 
 	// Inflate tar file so that multiple writes can happen in parallel
-	for _, imgLayer := range w.layersToWrite {
-		digest, err := regv1.NewHash(imgLayer.Digest)
-		if err != nil {
-			return err
-		}
-
-		name := digest.Algorithm + "-" + digest.Hex + ".tar.gz"
-
-		// Dedup layers
-		if _, found := writtenLayers[name]; found {
+	for _, blob := range blobs {
+		if w.opts.Base != nil && w.opts.Base.Contains(blob.Digest) {
 			continue
 		}
+		name := blobFileName(blob.Digest)
 
-		err = w.tf.Flush()
+		err := w.tf.Flush()
 		if err != nil {
 			return err
 		}
 
-		var stream io.Reader
 		var currPos int64
-
 		if isSeekable {
 			currPos, err = seekableDst.Seek(0, 1)
 			if err != nil {
@@ -174,45 +149,22 @@ func (w *TarWriter) writeLayers() error {
 		}
 
 		if isInflatable {
-			stream = nil
+			err = w.writeTarEntry(w.tf, name, nil, blob.Size)
 		} else {
-			for _, layer := range w.layersFromOtherSource {
-				d, err := layer.Digest()
-				if err != nil {
-					return fmt.Errorf("Retrieving digest: %s", err)
-				}
-				if d.String() == imgLayer.Digest {
-					stream, err = layer.Compressed()
-					if err != nil {
-						return fmt.Errorf("Retrieve layer from file: %s", err)
-					}
-					break
-				}
-			}
-
-			if stream == nil {
-				foundLayer, err := w.ids.FindLayer(imgLayer)
-				if err != nil {
-					return err
-				}
-
-				stream, err = foundLayer.Open()
-				if err != nil {
-					return err
-				}
+			err = w.writeBlob(w.tf, name, blob)
+			if err == nil {
+				err = w.opts.Checkpoint.Record(blob.Digest)
 			}
 		}
-
-		err = w.writeTarEntry(w.tf, name, stream, imgLayer.Size)
 		if err != nil {
 			return fmt.Errorf("Writing tar entry: %s", err)
 		}
 
-		writtenLayers[name] = writtenLayer{
+		writtenLayers = append(writtenLayers, writtenLayer{
 			Name:   name,
-			Layer:  imgLayer,
+			Blob:   blob,
 			Offset: currPos,
-		}
+		})
 	}
 
 	err := w.tf.Flush()
@@ -227,16 +179,12 @@ func (w *TarWriter) writeLayers() error {
 	return nil
 }
 
-func (w *TarWriter) fillInLayers(writtenLayers map[string]writtenLayer) error {
-	var sortedWrittenLayers []writtenLayer
-
-	for _, writtenLayer := range writtenLayers {
-		sortedWrittenLayers = append(sortedWrittenLayers, writtenLayer)
-	}
+func (w *TarBlobStore) fillInLayers(writtenLayers []writtenLayer) error {
+	sortedWrittenLayers := append([]writtenLayer{}, writtenLayers...)
 
 	// Prefer larger sizes first
 	sort.Slice(sortedWrittenLayers, func(i, j int) bool {
-		return sortedWrittenLayers[i].Layer.Size >= sortedWrittenLayers[j].Layer.Size
+		return sortedWrittenLayers[i].Blob.Size >= sortedWrittenLayers[j].Blob.Size
 	})
 
 	errCh := make(chan error, len(writtenLayers))
@@ -266,7 +214,7 @@ func (w *TarWriter) fillInLayers(writtenLayers map[string]writtenLayer) error {
 	return nil
 }
 
-func (w *TarWriter) fillInLayer(wl writtenLayer) error {
+func (w *TarBlobStore) fillInLayer(wl writtenLayer) error {
 	file, err := w.dstOpener()
 	if err != nil {
 		return err
@@ -282,25 +230,32 @@ func (w *TarWriter) fillInLayer(wl writtenLayer) error {
 	tw := tar.NewWriter(file)
 	// Do not close tar writer as it would add unwanted footer
 
-	foundLayer, err := w.ids.FindLayer(wl.Layer)
+	err = w.writeBlob(tw, wl.Name, wl.Blob)
 	if err != nil {
-		return err
+		return fmt.Errorf("Rewriting tar entry (%s): %s", wl.Name, err)
 	}
 
-	stream, err := foundLayer.Open()
+	err = tw.Flush()
 	if err != nil {
 		return err
 	}
+	return w.opts.Checkpoint.Record(wl.Blob.Digest)
+}
 
-	err = w.writeTarEntry(tw, wl.Name, stream, wl.Layer.Size)
+func (w *TarBlobStore) writeBlob(tw *tar.Writer, name string, blob Blob) error {
+	stream, err := blob.Open()
 	if err != nil {
-		return fmt.Errorf("Rewriting tar entry (%s): %s", wl.Name, err)
+		return err
 	}
+	defer stream.Close()
 
-	return tw.Flush()
+	span := tracing.Start("imgpkg.blob.write", tracing.BlobDigestKey.String(blob.Digest.String()), tracing.TarEntryKey.String(name))
+	err = w.writeTarEntry(tw, name, stream, blob.Size)
+	tracing.End(span, err)
+	return err
 }
 
-func (w *TarWriter) writeTarEntry(tw *tar.Writer, path string, r io.Reader, size int64) error {
+func (w *TarBlobStore) writeTarEntry(tw *tar.Writer, path string, r io.Reader, size int64) error {
 	var zerosFill bool
 
 	if r == nil {
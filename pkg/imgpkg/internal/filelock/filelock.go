@@ -0,0 +1,108 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package filelock provides cross-process advisory locks on files so that
+// parallel imgpkg invocations do not corrupt state shared on the same host
+package filelock
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// File is a file that is locked by the current process
+type File struct {
+	*os.File
+	// unlocked when true the filesystem does not support locks, e.g. some NFS or FUSE mounts, and the file is
+	// used without a lock
+	unlocked bool
+}
+
+// Unlock releases the lock and closes the file
+func (f *File) Unlock() error {
+	var unlockErr error
+	if !f.unlocked {
+		unlockErr = unlock(f.File)
+	}
+	closeErr := f.File.Close()
+	if unlockErr != nil {
+		return fmt.Errorf("Unlocking %s: %s", f.Name(), unlockErr)
+	}
+	return closeErr
+}
+
+// OpenShared opens the file for reading and blocks until a shared lock is acquired.
+// Multiple processes can hold a shared lock on the same file at the same time.
+// When the filesystem does not support locks the file is opened without a lock
+func OpenShared(path string) (*File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lockShared(file)
+	if err != nil {
+		if lockNotSupported(err) {
+			return &File{File: file, unlocked: true}, nil
+		}
+		file.Close()
+		return nil, fmt.Errorf("Locking %s: %s", path, err)
+	}
+
+	return &File{File: file}, nil
+}
+
+// OpenExclusive opens, or creates, the file for writing and blocks until an exclusive lock is acquired.
+// The content of the file is not truncated. When the filesystem does not support locks the file is opened without a lock
+func OpenExclusive(path string, perm os.FileMode) (*File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lockExclusive(file)
+	if err != nil {
+		if lockNotSupported(err) {
+			return &File{File: file, unlocked: true}, nil
+		}
+		file.Close()
+		return nil, fmt.Errorf("Locking %s: %s", path, err)
+	}
+
+	return &File{File: file}, nil
+}
+
+// ReadFile reads the file while holding a shared lock
+func ReadFile(path string) ([]byte, error) {
+	file, err := OpenShared(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Unlock()
+
+	return io.ReadAll(file)
+}
+
+// WriteFile replaces the content of the file while holding an exclusive lock, so that
+// concurrent readers never see a partially written file
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	file, err := OpenExclusive(path, perm)
+	if err != nil {
+		return err
+	}
+
+	err = file.Truncate(0)
+	if err == nil {
+		_, err = file.Write(data)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+
+	unlockErr := file.Unlock()
+	if err != nil {
+		return err
+	}
+	return unlockErr
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package filelock_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/filelock"
+)
+
+func TestWriteFile(t *testing.T) {
+	t.Run("replaces the content of the file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.yml")
+		require.NoError(t, os.WriteFile(path, []byte("some long previous content"), 0600))
+
+		require.NoError(t, filelock.WriteFile(path, []byte("new content"), 0600))
+
+		bs, err := filelock.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "new content", string(bs))
+	})
+
+	t.Run("readers wait for the writer to release the lock", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.yml")
+		writer, err := filelock.OpenExclusive(path, 0600)
+		require.NoError(t, err)
+
+		readResult := make(chan string)
+		go func() {
+			bs, err := filelock.ReadFile(path)
+			assert.NoError(t, err)
+			readResult <- string(bs)
+		}()
+
+		_, err = writer.Write([]byte("written while locked"))
+		require.NoError(t, err)
+
+		select {
+		case <-readResult:
+			t.Fatal("expected read to block while the exclusive lock is held")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		require.NoError(t, writer.Unlock())
+		assert.Equal(t, "written while locked", <-readResult)
+	})
+}
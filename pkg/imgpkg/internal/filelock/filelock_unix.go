@@ -0,0 +1,39 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package filelock
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func lockShared(file *os.File) error {
+	return flock(file, unix.LOCK_SH)
+}
+
+func lockExclusive(file *os.File) error {
+	return flock(file, unix.LOCK_EX)
+}
+
+func unlock(file *os.File) error {
+	return flock(file, unix.LOCK_UN)
+}
+
+func flock(file *os.File, how int) error {
+	for {
+		err := unix.Flock(int(file.Fd()), how)
+		if err != unix.EINTR {
+			return err
+		}
+	}
+}
+
+// lockNotSupported Returns true when the filesystem of the file does not support locks
+func lockNotSupported(err error) bool {
+	return errors.Is(err, unix.ENOLCK) || errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EOPNOTSUPP)
+}
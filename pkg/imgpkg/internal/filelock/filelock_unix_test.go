@@ -0,0 +1,20 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package filelock
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestLockNotSupported(t *testing.T) {
+	assert.True(t, lockNotSupported(unix.ENOLCK))
+	assert.True(t, lockNotSupported(fmt.Errorf("wrapped: %w", unix.EOPNOTSUPP)))
+	assert.False(t, lockNotSupported(unix.EBADF))
+}
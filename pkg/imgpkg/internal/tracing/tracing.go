@@ -0,0 +1,135 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tracing contains the OpenTelemetry instrumentation used by imgpkg
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ExporterEnvVar Environment variable that activates the export of traces
+	ExporterEnvVar = "IMGPKG_OTEL_EXPORTER"
+	// OTLPExporter Export traces using OTLP over HTTP. The endpoint and headers are configured using the
+	// standard OTEL_EXPORTER_OTLP_* environment variables
+	OTLPExporter = "otlp"
+
+	tracerName = "github.com/vmware-tanzu/carvel-imgpkg"
+)
+
+const (
+	// BlobDigestKey Attribute that holds the digest of the blob being transferred
+	BlobDigestKey = attribute.Key("imgpkg.blob.digest")
+	// ImageRefKey Attribute that holds the reference of the image being processed
+	ImageRefKey = attribute.Key("imgpkg.image.ref")
+	// ImagesCountKey Attribute that holds the number of images being processed
+	ImagesCountKey = attribute.Key("imgpkg.images.count")
+	// TarEntryKey Attribute that holds the name of the tar entry being written
+	TarEntryKey = attribute.Key("imgpkg.tar.entry")
+)
+
+var (
+	rootCtx     = context.Background()
+	rootCtxLock = &sync.RWMutex{}
+)
+
+// Setup Configures the global TracerProvider when IMGPKG_OTEL_EXPORTER is set.
+// The returned function flushes all the pending spans and needs to be called before the process exits
+func Setup(lookupEnv func(string) (string, bool), version string) (func(), error) {
+	exporterType, found := lookupEnv(ExporterEnvVar)
+	if !found || exporterType == "" {
+		return func() {}, nil
+	}
+
+	switch strings.ToLower(exporterType) {
+	case OTLPExporter:
+	default:
+		return nil, fmt.Errorf("Unsupported value '%s' for %s (supported values: %s)", exporterType, ExporterEnvVar, OTLPExporter)
+	}
+
+	exporter, err := otlptracehttp.New(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("Creating OTLP trace exporter: %s", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName("imgpkg"),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("Creating trace resource: %s", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func() {
+		// Errors are ignored because tracing should never make a command fail
+		_ = provider.Shutdown(context.Background())
+	}, nil
+}
+
+// StartCommand Starts the span that is the parent of all spans created during the execution of a command.
+// The returned function ends the span and records the error, if any
+func StartCommand(name string) func(error) {
+	ctx, span := otel.Tracer(tracerName).Start(context.Background(), name)
+
+	rootCtxLock.Lock()
+	rootCtx = ctx
+	rootCtxLock.Unlock()
+
+	return func(err error) {
+		End(span, err)
+
+		rootCtxLock.Lock()
+		rootCtx = context.Background()
+		rootCtxLock.Unlock()
+	}
+}
+
+// Start Starts a new span that is a child of the current command span
+func Start(name string, attrs ...attribute.KeyValue) trace.Span {
+	_, span := StartWithContext(commandContext(), name, attrs...)
+	return span
+}
+
+// StartWithContext Starts a new span that is a child of the span present in ctx.
+// When ctx does not contain a span, the current command span is used as the parent
+func StartWithContext(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = trace.ContextWithSpan(ctx, trace.SpanFromContext(commandContext()))
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End Ends the span and records the error when it is not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func commandContext() context.Context {
+	rootCtxLock.RLock()
+	defer rootCtxLock.RUnlock()
+	return rootCtx
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup(t *testing.T) {
+	t.Run("when IMGPKG_OTEL_EXPORTER is not set it does not fail", func(t *testing.T) {
+		shutdown, err := tracing.Setup(envFake{}.Value, "develop")
+		require.NoError(t, err)
+		shutdown()
+	})
+
+	t.Run("when IMGPKG_OTEL_EXPORTER has an unsupported value it returns an error", func(t *testing.T) {
+		_, err := tracing.Setup(envFake{values: map[string]string{"IMGPKG_OTEL_EXPORTER": "zipkin"}}.Value, "develop")
+		require.EqualError(t, err, "Unsupported value 'zipkin' for IMGPKG_OTEL_EXPORTER (supported values: otlp)")
+	})
+}
+
+func TestRoundTripper(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(sdktrace.NewTracerProvider())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/not-found" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	endCommand := tracing.StartCommand("imgpkg copy")
+
+	client := http.Client{Transport: tracing.NewRoundTripper(http.DefaultTransport)}
+	resp, err := client.Get(server.URL + "/v2/repo/blobs/sha256:477c34d98f9e090a4441cf82d2f1f03e64c8eb730e8c1ef39a8595e685d4df65")
+	require.NoError(t, err)
+	resp.Body.Close()
+	resp, err = client.Get(server.URL + "/v2/not-found")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	endCommand(errors.New("some error"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	commandSpan := spans[2]
+	assert.Equal(t, "imgpkg copy", commandSpan.Name())
+	assert.Equal(t, codes.Error, commandSpan.Status().Code)
+
+	blobSpan := spans[0]
+	assert.Equal(t, "HTTP GET", blobSpan.Name())
+	assert.Equal(t, commandSpan.SpanContext().SpanID(), blobSpan.Parent().SpanID())
+	assert.Contains(t, blobSpan.Attributes(), tracing.BlobDigestKey.String("sha256:477c34d98f9e090a4441cf82d2f1f03e64c8eb730e8c1ef39a8595e685d4df65"))
+	assert.Equal(t, codes.Unset, blobSpan.Status().Code)
+
+	notFoundSpan := spans[1]
+	assert.Equal(t, codes.Error, notFoundSpan.Status().Code)
+}
+
+type envFake struct {
+	values map[string]string
+}
+
+func (e envFake) Value(s string) (string, bool) {
+	res, found := e.values[s]
+	return res, found
+}
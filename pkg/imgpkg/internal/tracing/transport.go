@@ -0,0 +1,53 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+var blobDigestMatcher = regexp.MustCompile(`/blobs/(sha256:[a-f0-9]{64})`)
+
+// NewRoundTripper Creates a RoundTripper that records a span for each HTTP request
+func NewRoundTripper(inner http.RoundTripper) http.RoundTripper {
+	return &roundTripper{inner: inner}
+}
+
+type roundTripper struct {
+	inner http.RoundTripper
+}
+
+// RoundTrip Executes the request inside a new span
+func (r *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	attrs := []attribute.KeyValue{
+		semconv.HTTPMethod(req.Method),
+		semconv.HTTPURL(req.URL.Redacted()),
+		semconv.NetPeerName(req.URL.Hostname()),
+	}
+	if match := blobDigestMatcher.FindStringSubmatch(req.URL.Path); len(match) > 1 {
+		attrs = append(attrs, BlobDigestKey.String(match[1]))
+	}
+
+	ctx, span := StartWithContext(req.Context(), fmt.Sprintf("HTTP %s", req.Method), attrs...)
+
+	resp, err := r.inner.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		End(span, err)
+		return resp, err
+	}
+
+	span.SetAttributes(semconv.HTTPStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		End(span, fmt.Errorf("Received status code %d", resp.StatusCode))
+		return resp, err
+	}
+	End(span, nil)
+
+	return resp, nil
+}
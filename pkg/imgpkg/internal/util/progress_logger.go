@@ -20,9 +20,9 @@ type ProgressLogger interface {
 }
 
 // NewProgressBar constructor to build a ProgressLogger responsible for printing out a progress bar using updates when
-// writing to a registry via ggcr
+// writing to a registry via ggcr. The progress bar is only rendered when both stdout and stderr are terminals
 func NewProgressBar(logger LoggerWithLevels, finalMessage, errorMessagePrefix string) ProgressLogger {
-	if isatty.IsTerminal(os.Stdout.Fd()) {
+	if IsTerminal(os.Stdout) && IsTerminal(os.Stderr) {
 		return &ProgressBarLogger{logger: logger, finalMessage: finalMessage, errorMessagePrefix: errorMessagePrefix}
 	}
 
@@ -48,7 +48,7 @@ func (l *ProgressBarLogger) Start(ctx context.Context, progressChan <-chan regv1
 	ctx, cancelFunc := context.WithCancel(ctx)
 	l.cancelFunc = cancelFunc
 	// Add a new empty line to separate the progress bar from prior output
-	fmt.Println()
+	fmt.Fprintln(os.Stderr)
 	l.bar = pb.New64(0)
 	l.bar.Set(pb.Bytes, true)
 
@@ -117,3 +117,8 @@ func (l *ProgressBarNoTTYLogger) End() {
 		l.logger.Logf(l.finalMessage)
 	}
 }
+
+// IsTerminal returns true when the file is connected to a terminal
+func IsTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
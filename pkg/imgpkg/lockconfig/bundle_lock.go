@@ -5,9 +5,9 @@ package lockconfig
 
 import (
 	"fmt"
-	"io/ioutil"
 
 	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/filelock"
 	"sigs.k8s.io/yaml"
 )
 
@@ -27,7 +27,7 @@ type BundleRef struct {
 }
 
 func NewBundleLockFromPath(path string) (BundleLock, error) {
-	bs, err := ioutil.ReadFile(path)
+	bs, err := filelock.ReadFile(path)
 	if err != nil {
 		return BundleLock{}, fmt.Errorf("Reading path %s: %s", path, err)
 	}
@@ -84,7 +84,7 @@ func (b BundleLock) WriteToPath(path string) error {
 		return err
 	}
 
-	err = ioutil.WriteFile(path, bs, 0600)
+	err = filelock.WriteFile(path, bs, 0600)
 	if err != nil {
 		return fmt.Errorf("Writing bundle config: %s", err)
 	}
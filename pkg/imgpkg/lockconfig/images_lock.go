@@ -5,9 +5,9 @@ package lockconfig
 
 import (
 	"fmt"
-	"io/ioutil"
 
 	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/filelock"
 	"sigs.k8s.io/yaml"
 )
 
@@ -37,7 +37,7 @@ func NewEmptyImagesLock() ImagesLock {
 }
 
 func NewImagesLockFromPath(path string) (ImagesLock, error) {
-	bs, err := ioutil.ReadFile(path)
+	bs, err := filelock.ReadFile(path)
 	if err != nil {
 		return ImagesLock{}, fmt.Errorf("Reading path %s: %s", path, err)
 	}
@@ -125,7 +125,7 @@ func (i ImagesLock) WriteToPath(path string) error {
 		return err
 	}
 
-	err = ioutil.WriteFile(path, bs, 0600)
+	err = filelock.WriteFile(path, bs, 0600)
 	if err != nil {
 		return fmt.Errorf("Writing images config: %s", err)
 	}
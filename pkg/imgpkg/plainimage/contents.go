@@ -13,7 +13,9 @@ import (
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
 	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
 	ctlimg "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/image"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/tracing"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/workspace"
 )
 
 // Contents of the OCI Image
@@ -21,6 +23,10 @@ type Contents struct {
 	paths               []string
 	excludedPaths       []string
 	preservePermissions bool
+	workspace           *workspace.Workspace
+	compression         ctlimg.LayerCompression
+	// artifactType when provided the image is pushed with an OCI image manifest that records it
+	artifactType string
 }
 
 // ImagesWriter defines the needed functions to write to the registry
@@ -34,14 +40,39 @@ func NewContents(paths []string, excludedPaths []string, preservePermissions boo
 	return Contents{paths: paths, excludedPaths: excludedPaths, preservePermissions: preservePermissions}
 }
 
+// WithWorkspace Creates the temporary files used to build the image in the workspace
+func (i Contents) WithWorkspace(ws *workspace.Workspace) Contents {
+	i.workspace = ws
+	return i
+}
+
+// WithCompression Compresses the layer of the image with the provided compression instead of gzip
+func (i Contents) WithCompression(compression ctlimg.LayerCompression) Contents {
+	i.compression = compression
+	return i
+}
+
+// WithArtifactType Pushes the image with an OCI image manifest whose artifactType is the provided one
+func (i Contents) WithArtifactType(artifactType string) Contents {
+	i.artifactType = artifactType
+	return i
+}
+
 // Push the OCI Image to the registry
 func (i Contents) Push(uploadRef regname.Tag, labels map[string]string, writer ImagesWriter, logger Logger) (string, error) {
+	span := tracing.Start("imgpkg.image.push", tracing.ImageRefKey.String(uploadRef.Name()))
+	digestRef, err := i.push(uploadRef, labels, writer, logger)
+	tracing.End(span, err)
+	return digestRef, err
+}
+
+func (i Contents) push(uploadRef regname.Tag, labels map[string]string, writer ImagesWriter, logger Logger) (string, error) {
 	err := i.validate()
 	if err != nil {
 		return "", err
 	}
 
-	tarImg := ctlimg.NewTarImage(i.paths, i.excludedPaths, logger, i.preservePermissions)
+	tarImg := ctlimg.NewTarImage(i.paths, i.excludedPaths, logger, i.preservePermissions).WithWorkspace(i.workspace).WithCompression(i.compression)
 
 	img, err := tarImg.AsFileImage(labels)
 	if err != nil {
@@ -50,23 +81,28 @@ func (i Contents) Push(uploadRef regname.Tag, labels map[string]string, writer I
 
 	defer img.Remove()
 
-	err = writer.WriteImage(uploadRef, img, nil)
+	var imgToPush regv1.Image = img
+	if i.artifactType != "" {
+		imgToPush = ctlimg.NewArtifactImage(img, i.artifactType)
+	}
+
+	err = writer.WriteImage(uploadRef, imgToPush, nil)
 
 	if err != nil {
 		return "", fmt.Errorf("Writing '%s': %s", uploadRef.Name(), err)
 	}
 
-	digest, err := img.Digest()
+	digest, err := imgToPush.Digest()
 	if err != nil {
 		return "", err
 	}
 
-	uploadTagRef, err := util.BuildDefaultUploadTagRef(img, uploadRef.Repository)
+	uploadTagRef, err := util.BuildDefaultUploadTagRef(imgToPush, uploadRef.Repository)
 	if err != nil {
 		return "", fmt.Errorf("Building default upload tag image ref: %s", err)
 	}
 
-	err = writer.WriteTag(uploadTagRef, img)
+	err = writer.WriteTag(uploadTagRef, imgToPush)
 	if err != nil {
 		return "", fmt.Errorf("Writing Tag '%s': %s", uploadRef.Name(), err)
 	}
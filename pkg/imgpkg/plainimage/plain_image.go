@@ -11,6 +11,7 @@ import (
 	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	ctlimg "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/image"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/tracing"
 )
 
 type ImagesDescriptor interface {
@@ -164,6 +165,13 @@ func (i *PlainImage) IsImage() (bool, error) {
 
 // Pull the OCI Image to disk
 func (i *PlainImage) Pull(outputPath string, logger Logger) error {
+	span := tracing.Start("imgpkg.image.pull", tracing.ImageRefKey.String(i.unparsedRef))
+	err := i.pull(outputPath, logger)
+	tracing.End(span, err)
+	return err
+}
+
+func (i *PlainImage) pull(outputPath string, logger Logger) error {
 	img, err := i.Fetch()
 	if err != nil {
 		return err
@@ -0,0 +1,95 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/chrismellard/docker-credential-acr-env/pkg/credhelper"
+	acrregistry "github.com/chrismellard/docker-credential-acr-env/pkg/registry"
+	regauthn "github.com/google/go-containerregistry/pkg/authn"
+)
+
+const acrTokenUsername = "<token>"
+
+var acrHostRegexp = regexp.MustCompile(`^.*\.azurecr\.(io|cn|de|us)$`)
+
+// NewACRKeychain Keychain that retrieves credentials for the Azure Container Registries using the Azure identity
+// configured in the environment. Workload identity ($AZURE_FEDERATED_TOKEN_FILE) is used when configured, otherwise
+// the service principal ($AZURE_CLIENT_SECRET) or the managed identity of the machine
+func NewACRKeychain(environFunc func() []string) regauthn.Keychain {
+	if environFunc == nil {
+		environFunc = os.Environ
+	}
+	return regauthn.NewKeychainFromHelper(acrHelper{environFunc: environFunc})
+}
+
+type acrHelper struct {
+	environFunc func() []string
+}
+
+// Get Returns the refresh token of the registry in serverURL
+func (a acrHelper) Get(serverURL string) (string, string, error) {
+	env := map[string]string{}
+	for _, keyValue := range a.environFunc() {
+		key, value, _ := strings.Cut(keyValue, "=")
+		env[key] = value
+	}
+	if env["AZURE_FEDERATED_TOKEN_FILE"] == "" {
+		return credhelper.NewACRCredentialsHelper().Get(serverURL)
+	}
+
+	registryURL, err := url.Parse("https://" + serverURL)
+	if err != nil || !acrHostRegexp.MatchString(registryURL.Hostname()) {
+		return "", "", fmt.Errorf("Registry '%s' is not an Azure Container Registry", serverURL)
+	}
+
+	tenantID, clientID := env["AZURE_TENANT_ID"], env["AZURE_CLIENT_ID"]
+	if tenantID == "" || clientID == "" {
+		return "", "", fmt.Errorf("Expected $AZURE_TENANT_ID and $AZURE_CLIENT_ID to be set to use the workload identity in $AZURE_FEDERATED_TOKEN_FILE")
+	}
+	authorityHost := env["AZURE_AUTHORITY_HOST"]
+	if authorityHost == "" {
+		authorityHost = azure.PublicCloud.ActiveDirectoryEndpoint
+	}
+
+	oauthConfig, err := adal.NewOAuthConfig(authorityHost, tenantID)
+	if err != nil {
+		return "", "", fmt.Errorf("Configuring Azure authentication: %s", err)
+	}
+	spToken, err := adal.NewServicePrincipalTokenWithSecret(*oauthConfig, clientID, azure.PublicCloud.ResourceManagerEndpoint,
+		federatedTokenSecret{path: env["AZURE_FEDERATED_TOKEN_FILE"]})
+	if err != nil {
+		return "", "", fmt.Errorf("Configuring Azure workload identity: %s", err)
+	}
+
+	refreshToken, err := acrregistry.GetRegistryRefreshTokenFromAADExchange(serverURL, spToken, tenantID)
+	if err != nil {
+		return "", "", fmt.Errorf("Retrieving token for '%s' using Azure workload identity: %s", serverURL, err)
+	}
+	return acrTokenUsername, refreshToken, nil
+}
+
+// federatedTokenSecret Authenticates with the token projected by the Azure workload identity webhook, reading it on
+// every request because it is rotated
+type federatedTokenSecret struct {
+	path string
+}
+
+// SetAuthenticationValues Adds the federated token as the client assertion of the request
+func (f federatedTokenSecret) SetAuthenticationValues(_ *adal.ServicePrincipalToken, values *url.Values) error {
+	token, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("Reading Azure federated token: %s", err)
+	}
+	values.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+	values.Set("client_assertion", strings.TrimSpace(string(token)))
+	return nil
+}
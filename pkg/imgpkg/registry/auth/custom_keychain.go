@@ -6,10 +6,14 @@ package auth
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	dockerconfig "github.com/docker/cli/cli/config"
 	regauthn "github.com/google/go-containerregistry/pkg/authn"
+	regname "github.com/google/go-containerregistry/pkg/name"
 )
 
 var _ regauthn.Keychain = CustomRegistryKeychain{}
@@ -36,6 +40,9 @@ type KeychainOpts struct {
 	Anon                    bool
 	EnableIaasAuthProviders bool
 	ActiveKeychains         []IAASKeychain
+	// DisableDockerCredentialHelpers when true only the credentials stored in the docker config file are used, the
+	// docker-credential-* helpers configured in credsStore and credHelpers are not executed
+	DisableDockerCredentialHelpers bool
 }
 
 // NewSingleAuthKeychain Builds a SingleAuthKeychain struct
@@ -69,11 +76,63 @@ func (k CustomRegistryKeychain) Resolve(res regauthn.Resource) (regauthn.Authent
 		return regauthn.Anonymous, nil
 	default:
 		return k.retryDefaultKeychain(func() (regauthn.Authenticator, error) {
+			if k.Opts.DisableDockerCredentialHelpers {
+				return resolveFromDockerConfigAuths(res)
+			}
 			return regauthn.DefaultKeychain.Resolve(res)
 		})
 	}
 }
 
+// Description returns where the credentials provided by the keychain come from
+func (k CustomRegistryKeychain) Description() string {
+	switch {
+	case len(k.Opts.Username) > 0:
+		return "username and password (--registry-username or $IMGPKG_USERNAME)"
+	case len(k.Opts.Token) > 0:
+		return "token (--registry-token or $IMGPKG_TOKEN)"
+	case k.Opts.Anon:
+		return "anonymous (--registry-anon or $IMGPKG_ANON)"
+	case k.Opts.DisableDockerCredentialHelpers:
+		return fmt.Sprintf("docker config without credential helpers (%s)", filepath.Join(dockerconfig.Dir(), dockerconfig.ConfigFileName))
+	default:
+		return fmt.Sprintf("docker config (%s)", filepath.Join(dockerconfig.Dir(), dockerconfig.ConfigFileName))
+	}
+}
+
+// resolveFromDockerConfigAuths Looks up the credentials of the target in the auths of the docker config file,
+// ignoring the credential helpers configured in it
+func resolveFromDockerConfigAuths(res regauthn.Resource) (regauthn.Authenticator, error) {
+	configFile, err := dockerconfig.Load(os.Getenv("DOCKER_CONFIG"))
+	if err != nil {
+		return nil, err
+	}
+	configFile.CredentialsStore = ""
+	configFile.CredentialHelpers = nil
+
+	for _, key := range []string{res.String(), res.RegistryStr()} {
+		if key == regname.DefaultRegistry {
+			key = regauthn.DefaultAuthKey
+		}
+
+		authConfig, err := configFile.GetAuthConfig(key)
+		if err != nil {
+			return nil, err
+		}
+		if authConfig.Username != "" || authConfig.Password != "" || authConfig.Auth != "" ||
+			authConfig.IdentityToken != "" || authConfig.RegistryToken != "" {
+			return regauthn.FromConfig(regauthn.AuthConfig{
+				Username:      authConfig.Username,
+				Password:      authConfig.Password,
+				Auth:          authConfig.Auth,
+				IdentityToken: authConfig.IdentityToken,
+				RegistryToken: authConfig.RegistryToken,
+			}), nil
+		}
+	}
+	return regauthn.Anonymous, nil
+}
+
 func (k CustomRegistryKeychain) retryDefaultKeychain(doFunc func() (regauthn.Authenticator, error)) (regauthn.Authenticator, error) {
 	// constants copied from https://github.com/vmware-tanzu/carvel-imgpkg/blob/c8b1bc196e5f1af82e6df8c36c290940169aa896/vendor/github.com/docker/docker-credential-helpers/credentials/error.go#L4-L11
 
@@ -0,0 +1,235 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	regauthn "github.com/google/go-containerregistry/pkg/authn"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// AuthResolution Result of asking one keychain for the credentials of a registry
+type AuthResolution struct {
+	Keychain string
+	// Identity describes the credentials returned by the keychain, empty when the keychain has no credentials
+	Identity string
+	Error    error
+	// Selected is true for the keychain whose credentials are used
+	Selected bool
+	// Tried is false for the keychains that are not asked because a previous keychain was selected or failed
+	Tried bool
+}
+
+// AccessCheck Result of accessing the registry with the selected credentials
+type AccessCheck struct {
+	Scope   string
+	Allowed bool
+	Error   error
+}
+
+// AuthCheck Keychains asked for credentials and what the credentials they provided allow
+type AuthCheck struct {
+	Registry    string
+	Resolutions []AuthResolution
+	Access      []AccessCheck
+}
+
+// Allowed returns true when all the access checks succeeded
+func (a AuthCheck) Allowed() bool {
+	for _, access := range a.Access {
+		if !access.Allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckAuth Resolves the credentials of the registry the same way the other commands do, recording the answer of
+// each keychain, and uses them to access the registry and, when repo is provided, to pull from and push to it
+func CheckAuth(opts Opts, registryName string, repoName string) (AuthCheck, error) {
+	var refOpts []regname.Option
+	if opts.Insecure {
+		refOpts = append(refOpts, regname.Insecure)
+	}
+
+	reg, err := regname.NewRegistry(registryName, refOpts...)
+	if err != nil {
+		return AuthCheck{}, fmt.Errorf("Parsing registry '%s': %s", registryName, err)
+	}
+	var repo *regname.Repository
+	if repoName != "" {
+		parsedRepo, err := regname.NewRepository(repoName, refOpts...)
+		if err != nil {
+			return AuthCheck{}, fmt.Errorf("Parsing repository '%s': %s", repoName, err)
+		}
+		if parsedRepo.RegistryStr() != reg.RegistryStr() {
+			return AuthCheck{}, fmt.Errorf("Expected repository '%s' to be in registry '%s'", repoName, reg.RegistryStr())
+		}
+		repo = &parsedRepo
+	}
+
+	sources, err := KeychainSources(opts.keychainOpts(), opts.EnvironFunc)
+	if err != nil {
+		return AuthCheck{}, fmt.Errorf("Creating registry keychain: %s", err)
+	}
+
+	result := AuthCheck{Registry: reg.RegistryStr()}
+	var selectedAuth regauthn.Authenticator = regauthn.Anonymous
+	resolved := false
+	for _, source := range sources {
+		resolution := AuthResolution{Keychain: source.Name}
+		if resolved {
+			result.Resolutions = append(result.Resolutions, resolution)
+			continue
+		}
+
+		resolution.Tried = true
+		auth, err := source.Keychain.Resolve(reg)
+		switch {
+		case err != nil:
+			// Same as the multi keychain, an error stops the resolution
+			resolution.Error = err
+			resolved = true
+		case auth != regauthn.Anonymous:
+			resolution.Identity, resolution.Error = describeAuth(auth)
+			resolution.Selected = resolution.Error == nil
+			selectedAuth = auth
+			resolved = true
+		}
+		result.Resolutions = append(result.Resolutions, resolution)
+	}
+
+	simpleRegistry, err := NewSimpleRegistry(opts)
+	if err != nil {
+		return AuthCheck{}, err
+	}
+	baseRoundTripper := simpleRegistry.roundTrippers.BaseRoundTripper()
+	if baseRoundTripper == nil {
+		baseRoundTripper = http.DefaultTransport
+	}
+	checker := accessChecker{registry: reg, auth: selectedAuth, baseRoundTripper: baseRoundTripper}
+
+	result.Access = append(result.Access, checker.checkRegistry())
+	if repo != nil {
+		result.Access = append(result.Access, checker.checkPull(*repo), checker.checkPush(*repo))
+	}
+	return result, nil
+}
+
+func describeAuth(auth regauthn.Authenticator) (string, error) {
+	authConfig, err := auth.Authorization()
+	if err != nil {
+		return "", fmt.Errorf("Retrieving credentials: %s", err)
+	}
+
+	switch {
+	case authConfig.Username != "" && authConfig.IdentityToken != "":
+		return fmt.Sprintf("username '%s' with identity token", authConfig.Username), nil
+	case authConfig.Username != "":
+		return fmt.Sprintf("username '%s'", authConfig.Username), nil
+	case authConfig.IdentityToken != "":
+		return "identity token", nil
+	case authConfig.RegistryToken != "":
+		return "registry token", nil
+	case authConfig.Auth != "":
+		return "encoded basic auth", nil
+	default:
+		return "anonymous", nil
+	}
+}
+
+// accessChecker Makes requests to the registry that require a specific scope
+type accessChecker struct {
+	registry         regname.Registry
+	auth             regauthn.Authenticator
+	baseRoundTripper http.RoundTripper
+}
+
+func (a accessChecker) checkRegistry() AccessCheck {
+	check := AccessCheck{Scope: "registry " + a.registry.RegistryStr()}
+	check.Error = a.request(nil, http.MethodGet, "/v2/", http.StatusOK)
+	check.Allowed = check.Error == nil
+	return check
+}
+
+func (a accessChecker) checkPull(repo regname.Repository) AccessCheck {
+	check := AccessCheck{Scope: repo.Scope(transport.PullScope)}
+	// A repository that does not exist yet can still be pulled from once something is pushed to it
+	check.Error = a.request([]string{check.Scope}, http.MethodGet, fmt.Sprintf("/v2/%s/tags/list", repo.RepositoryStr()), http.StatusOK, http.StatusNotFound)
+	check.Allowed = check.Error == nil
+	return check
+}
+
+func (a accessChecker) checkPush(repo regname.Repository) AccessCheck {
+	check := AccessCheck{Scope: repo.Scope(transport.PushScope)}
+
+	rt, err := a.roundTripper([]string{check.Scope})
+	if err != nil {
+		check.Error = err
+		return check
+	}
+
+	// Starting an upload session requires push access, the session is cancelled right away so nothing is written
+	uploadURL := url.URL{Scheme: a.registry.Scheme(), Host: a.registry.RegistryStr(), Path: fmt.Sprintf("/v2/%s/blobs/uploads/", repo.RepositoryStr())}
+	req, err := http.NewRequest(http.MethodPost, uploadURL.String(), nil)
+	if err != nil {
+		check.Error = err
+		return check
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		check.Error = err
+		return check
+	}
+	defer resp.Body.Close()
+
+	check.Error = transport.CheckError(resp, http.StatusAccepted)
+	check.Allowed = check.Error == nil
+	if check.Allowed {
+		location, err := resp.Location()
+		if err == nil {
+			cancelReq, err := http.NewRequest(http.MethodDelete, location.String(), nil)
+			if err == nil {
+				if cancelResp, err := rt.RoundTrip(cancelReq); err == nil {
+					cancelResp.Body.Close()
+				}
+			}
+		}
+	}
+	return check
+}
+
+func (a accessChecker) request(scopes []string, method, path string, expectedCodes ...int) error {
+	rt, err := a.roundTripper(scopes)
+	if err != nil {
+		return err
+	}
+
+	reqURL := url.URL{Scheme: a.registry.Scheme(), Host: a.registry.RegistryStr(), Path: path}
+	req, err := http.NewRequest(method, reqURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return transport.CheckError(resp, expectedCodes...)
+}
+
+// roundTripper Authenticates with the registry, exchanging the credentials for a token with the scopes when needed
+func (a accessChecker) roundTripper(scopes []string) (http.RoundTripper, error) {
+	rt, err := transport.NewWithContext(context.Background(), a.registry, a.auth, a.baseRoundTripper, scopes)
+	if err != nil {
+		return nil, fmt.Errorf("Authenticating: %s", err)
+	}
+	return rt, nil
+}
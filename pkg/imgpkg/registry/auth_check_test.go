@@ -0,0 +1,113 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+func TestCheckAuth(t *testing.T) {
+	var canceledUploads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/tags/list"):
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/blobs/uploads/"):
+			if username != "writer" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Location", "/v2/repo/blobs/uploads/session")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodDelete:
+			canceledUploads = append(canceledUploads, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	t.Run("when credentials come from the environment, selects them and checks the access to the repository", func(t *testing.T) {
+		canceledUploads = nil
+		opts := registry.Opts{
+			Insecure: true,
+			EnvironFunc: func() []string {
+				return []string{"IMGPKG_REGISTRY_HOSTNAME=" + host, "IMGPKG_REGISTRY_USERNAME=writer", "IMGPKG_REGISTRY_PASSWORD=secret"}
+			},
+		}
+
+		result, err := registry.CheckAuth(opts, host, host+"/repo")
+		require.NoError(t, err)
+
+		require.Len(t, result.Resolutions, 2)
+		assert.True(t, result.Resolutions[0].Selected)
+		assert.Equal(t, "username 'writer'", result.Resolutions[0].Identity)
+		assert.False(t, result.Resolutions[1].Tried)
+
+		require.Len(t, result.Access, 3)
+		assert.True(t, result.Allowed())
+		assert.Equal(t, []string{"/v2/repo/blobs/uploads/session"}, canceledUploads)
+	})
+
+	t.Run("when credentials do not allow pushing, reports the push as denied", func(t *testing.T) {
+		opts := registry.Opts{
+			Insecure:    true,
+			Username:    "reader",
+			Password:    "secret",
+			EnvironFunc: func() []string { return nil },
+		}
+
+		result, err := registry.CheckAuth(opts, host, host+"/repo")
+		require.NoError(t, err)
+
+		assert.True(t, result.Resolutions[0].Tried)
+		assert.False(t, result.Resolutions[0].Selected)
+		assert.Equal(t, "username and password (--registry-username or $IMGPKG_USERNAME)", result.Resolutions[1].Keychain)
+		assert.True(t, result.Resolutions[1].Selected)
+
+		require.Len(t, result.Access, 3)
+		assert.True(t, result.Access[0].Allowed)
+		assert.True(t, result.Access[1].Allowed)
+		assert.False(t, result.Access[2].Allowed)
+		assert.ErrorContains(t, result.Access[2].Error, "403")
+		assert.False(t, result.Allowed())
+	})
+
+	t.Run("when credentials are wrong, reports the access to the registry as denied", func(t *testing.T) {
+		opts := registry.Opts{
+			Insecure:    true,
+			Username:    "writer",
+			Password:    "wrong",
+			EnvironFunc: func() []string { return nil },
+		}
+
+		result, err := registry.CheckAuth(opts, host, "")
+		require.NoError(t, err)
+
+		require.Len(t, result.Access, 1)
+		assert.False(t, result.Allowed())
+		assert.ErrorContains(t, result.Access[0].Error, "401")
+	})
+
+	t.Run("when the repository is in a different registry, it fails", func(t *testing.T) {
+		_, err := registry.CheckAuth(registry.Opts{Insecure: true}, host, "other.io/repo")
+		require.ErrorContains(t, err, "Expected repository 'other.io/repo' to be in registry")
+	})
+}
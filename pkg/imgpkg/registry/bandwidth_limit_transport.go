@@ -0,0 +1,112 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// NewBandwidthLimitRoundTripper Creates a RoundTripper that transfers at most bytesPerSecond, counting both the bodies
+// sent and received, shared by all the requests executed through it
+func NewBandwidthLimitRoundTripper(inner http.RoundTripper, bytesPerSecond int64) *BandwidthLimitRoundTripper {
+	return &BandwidthLimitRoundTripper{inner: inner, bucket: newTokenBucket(bytesPerSecond, time.Now)}
+}
+
+// BandwidthLimitRoundTripper Limits the bandwidth used to transfer data from and to the registries, so that a copy does
+// not saturate a link shared with other users. Request and response bodies take tokens from a single bucket as they
+// are read, so concurrent transfers share the bandwidth between them
+type BandwidthLimitRoundTripper struct {
+	inner  http.RoundTripper
+	bucket *tokenBucket
+}
+
+// RoundTrip Executes the request reading its body, and the body of the response, at the allowed rate
+func (b *BandwidthLimitRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(req.Context())
+		req.Body = &bandwidthLimitedBody{ctx: req.Context(), body: req.Body, bucket: b.bucket}
+	}
+
+	resp, err := b.inner.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Body != nil && resp.Body != http.NoBody {
+		resp.Body = &bandwidthLimitedBody{ctx: req.Context(), body: resp.Body, bucket: b.bucket}
+	}
+	return resp, nil
+}
+
+// bandwidthLimitedBody Waits for the bucket to have enough tokens before returning the bytes read
+type bandwidthLimitedBody struct {
+	ctx    context.Context
+	body   io.ReadCloser
+	bucket *tokenBucket
+}
+
+// Read Reads at most the capacity of the bucket at a time, waiting until the bytes can be transferred
+func (b *bandwidthLimitedBody) Read(p []byte) (int, error) {
+	if int64(len(p)) > b.bucket.capacity {
+		p = p[:b.bucket.capacity]
+	}
+	n, err := b.body.Read(p)
+	if n > 0 {
+		waitErr := b.bucket.wait(b.ctx, int64(n))
+		if waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// Close Closes the wrapped body
+func (b *bandwidthLimitedBody) Close() error { return b.body.Close() }
+
+// tokenBucket Token bucket filled at rate tokens per second, up to a capacity of one second worth of tokens.
+// Tokens are reserved when requested, leaving the bucket in debt, so that waiting callers are served in order
+type tokenBucket struct {
+	rate     int64
+	capacity int64
+	now      func() time.Time
+
+	lock   sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int64, now func() time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, capacity: rate, now: now, tokens: float64(rate), last: now()}
+}
+
+// wait Takes n tokens from the bucket, waiting until the bucket had enough of them
+func (t *tokenBucket) wait(ctx context.Context, n int64) error {
+	delay := t.reserve(n)
+	if delay <= 0 {
+		return nil
+	}
+	return sleepWithContext(ctx, delay)
+}
+
+// reserve Takes n tokens from the bucket and returns how long the caller needs to wait for them to be available
+func (t *tokenBucket) reserve(n int64) time.Duration {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := t.now()
+	t.tokens += now.Sub(t.last).Seconds() * float64(t.rate)
+	if t.tokens > float64(t.capacity) {
+		t.tokens = float64(t.capacity)
+	}
+	t.last = now
+
+	t.tokens -= float64(n)
+	if t.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-t.tokens / float64(t.rate) * float64(time.Second))
+}
@@ -0,0 +1,62 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+func TestBandwidthLimitRoundTripper(t *testing.T) {
+	content := bytes.Repeat([]byte("a"), 100000)
+	var received int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write(content)
+			return
+		}
+		bs, _ := io.ReadAll(r.Body)
+		received = len(bs)
+	}))
+	defer server.Close()
+
+	t.Run("limits the bandwidth used to download the response", func(t *testing.T) {
+		subject := registry.NewBandwidthLimitRoundTripper(http.DefaultTransport, 50000)
+
+		start := time.Now()
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, err := subject.RoundTrip(req)
+		require.NoError(t, err)
+		bs, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, content, bs)
+		// The first second worth of bytes is available immediately, the rest takes a second
+		assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+	})
+
+	t.Run("limits the bandwidth used to upload the request body", func(t *testing.T) {
+		subject := registry.NewBandwidthLimitRoundTripper(http.DefaultTransport, 50000)
+
+		start := time.Now()
+		req, err := http.NewRequest(http.MethodPut, server.URL, bytes.NewReader(content))
+		require.NoError(t, err)
+		resp, err := subject.RoundTrip(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, len(content), received)
+		assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+	})
+}
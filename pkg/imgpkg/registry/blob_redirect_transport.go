@@ -0,0 +1,183 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const (
+	// maxBlobRedirects maximum number of redirects followed to download a blob, the same as net/http
+	maxBlobRedirects = 10
+	// maxBlobRedirectRefreshes maximum number of times a fresh redirect is requested when the storage service
+	// denies the download, because the signed URL expired
+	maxBlobRedirectRefreshes = 3
+	// maxBlobResumes maximum number of times in a row a blob download is resumed without reading any byte
+	maxBlobResumes = 5
+)
+
+// NewBlobRedirectRoundTripper Creates a RoundTripper that follows the redirects of the blob downloads itself, so that
+// expired redirects can be refreshed and interrupted downloads resumed
+func NewBlobRedirectRoundTripper(inner http.RoundTripper) *BlobRedirectRoundTripper {
+	return &BlobRedirectRoundTripper{inner: inner}
+}
+
+// BlobRedirectRoundTripper Downloads the blobs of registries that redirect to a storage service, like S3 or
+// CloudFront, with signed URLs that expire. A redirect that was denied (403) is requested again from the registry,
+// and a download that fails while reading the blob is resumed from the last byte read, with a Range request sent
+// through a fresh redirect, so that downloads that take longer than the validity of the URLs complete.
+// The requests to the registry are resent with the credentials of the original request
+type BlobRedirectRoundTripper struct {
+	inner http.RoundTripper
+}
+
+// RoundTrip Executes the request, when it retrieves a blob the redirects are followed and the body is resumable
+func (b *BlobRedirectRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" || !registryAPIBlob.MatchString(req.URL.Path) {
+		return b.inner.RoundTrip(req)
+	}
+
+	resp, err := b.download(req, 0)
+	if err != nil || resp.StatusCode != http.StatusOK || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, err
+	}
+	resp.Body = &resumableBlobBody{transport: b, req: req, body: resp.Body}
+	return resp, nil
+}
+
+// download Requests the blob from the registry, starting at offset, following the redirects. When the last redirect
+// is denied a fresh one is requested from the registry
+func (b *BlobRedirectRoundTripper) download(req *http.Request, offset int64) (*http.Response, error) {
+	for refresh := 0; ; refresh++ {
+		resp, redirected, err := b.followRedirects(withRange(req, offset))
+		if err != nil || !redirected || resp.StatusCode != http.StatusForbidden || refresh >= maxBlobRedirectRefreshes {
+			return resp, err
+		}
+		drainAndClose(resp)
+	}
+}
+
+// followRedirects Sends the request following the redirects, returns true when the response is from a redirect.
+// The redirects keep the headers of the request, including its Range
+func (b *BlobRedirectRoundTripper) followRedirects(req *http.Request) (*http.Response, bool, error) {
+	for redirects := 0; ; redirects++ {
+		resp, err := b.inner.RoundTrip(req)
+		if err != nil {
+			return nil, redirects > 0, err
+		}
+
+		location := resp.Header.Get("Location")
+		if !isRedirect(resp.StatusCode) || location == "" {
+			return resp, redirects > 0, nil
+		}
+		if redirects >= maxBlobRedirects {
+			drainAndClose(resp)
+			return nil, true, fmt.Errorf("Downloading blob %s: stopped after %d redirects", req.URL.Path, maxBlobRedirects)
+		}
+
+		next, err := req.URL.Parse(location)
+		if err != nil {
+			drainAndClose(resp)
+			return nil, true, fmt.Errorf("Parsing redirect of blob %s: %s", req.URL.Path, err)
+		}
+		drainAndClose(resp)
+
+		redirectReq := req.Clone(req.Context())
+		redirectReq.URL = next
+		redirectReq.Host = ""
+		// The same as net/http, the credentials of the registry are not sent to other hosts. Storage services
+		// refuse requests that have both a signed URL and an Authorization header
+		if next.Host != req.URL.Host {
+			redirectReq.Header.Del("Authorization")
+			redirectReq.Header.Del("Cookie")
+		}
+		req = redirectReq
+	}
+}
+
+// resumableBlobBody Resumes the download of the blob when reading it fails
+type resumableBlobBody struct {
+	transport *BlobRedirectRoundTripper
+	req       *http.Request
+	body      io.ReadCloser
+	offset    int64
+	resumes   int
+}
+
+// Read Reads the blob, resuming the download from the last byte read when the connection fails
+func (r *resumableBlobBody) Read(p []byte) (int, error) {
+	for {
+		n, err := r.body.Read(p)
+		r.offset += int64(n)
+		if n > 0 {
+			r.resumes = 0
+		}
+		if err == nil || err == io.EOF || r.req.Context().Err() != nil || r.resumes >= maxBlobResumes {
+			return n, err
+		}
+
+		r.resumes++
+		if resumeErr := r.resume(); resumeErr != nil {
+			return n, fmt.Errorf("%s (resuming the download: %s)", err, resumeErr)
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// resume Replaces the body with the rest of the blob, from a new download
+func (r *resumableBlobBody) resume() error {
+	r.body.Close()
+	r.body = http.NoBody
+
+	resp, err := r.transport.download(r.req, r.offset)
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		r.body = resp.Body
+		return nil
+	case http.StatusOK:
+		// Ranges are not supported, the bytes already read are skipped
+		if _, err := io.CopyN(io.Discard, resp.Body, r.offset); err != nil {
+			resp.Body.Close()
+			return err
+		}
+		r.body = resp.Body
+		return nil
+	}
+	drainAndClose(resp)
+	return fmt.Errorf("Expected status code 206 or 200 but got %d", resp.StatusCode)
+}
+
+// Close Closes the body being read
+func (r *resumableBlobBody) Close() error { return r.body.Close() }
+
+// withRange Returns the request asking for the bytes after offset, the request itself when offset is 0
+func withRange(req *http.Request, offset int64) *http.Request {
+	if offset == 0 {
+		return req
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	return req
+}
+
+func isRedirect(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// drainAndClose Reads what is left of a small body, so that the connection can be reused, and closes it
+func drainAndClose(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+}
@@ -0,0 +1,138 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+// signedURLStorage Storage service that serves the blob with signed URLs, only the most recent one is valid
+type signedURLStorage struct {
+	content []byte
+
+	lock sync.Mutex
+	// validToken is the only token accepted, older ones expired
+	validToken int
+	// interruptAfter when not 0, the next download is interrupted after this number of bytes
+	interruptAfter int
+	// expireNext when true, the next token issued is already expired
+	expireNext      bool
+	requests        []*http.Request
+	redirectsIssued int
+}
+
+func (s *signedURLStorage) redirect(w http.ResponseWriter, r *http.Request, storageURL string) {
+	s.lock.Lock()
+	s.redirectsIssued++
+	token := s.redirectsIssued
+	if s.expireNext {
+		s.expireNext = false
+	} else {
+		s.validToken = token
+	}
+	s.lock.Unlock()
+
+	http.Redirect(w, r, fmt.Sprintf("%s/blob?token=%d", storageURL, token), http.StatusTemporaryRedirect)
+}
+
+func (s *signedURLStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	s.requests = append(s.requests, r)
+	valid := r.URL.Query().Get("token") == strconv.Itoa(s.validToken)
+	interruptAfter := s.interruptAfter
+	s.interruptAfter = 0
+	s.lock.Unlock()
+
+	if !valid {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if interruptAfter > 0 {
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.content)))
+		w.Write(s.content[:interruptAfter])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(s.content))
+}
+
+func TestBlobRedirectRoundTripper(t *testing.T) {
+	const blobPath = "/v2/repo/blobs/sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	content := bytes.Repeat([]byte("0123456789"), 100000)
+
+	setup := func(t *testing.T) (*signedURLStorage, string) {
+		storage := &signedURLStorage{content: content}
+		storageServer := httptest.NewServer(storage)
+		t.Cleanup(storageServer.Close)
+
+		registryServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != blobPath || r.Header.Get("Authorization") != "Bearer registry-token" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			storage.redirect(w, r, storageServer.URL)
+		}))
+		t.Cleanup(registryServer.Close)
+		return storage, registryServer.URL + blobPath
+	}
+
+	download := func(t *testing.T, blobURL string) ([]byte, error) {
+		client := &http.Client{Transport: registry.NewBlobRedirectRoundTripper(http.DefaultTransport)}
+		req, err := http.NewRequest(http.MethodGet, blobURL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer registry-token")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return io.ReadAll(resp.Body)
+	}
+
+	t.Run("follows the redirect without sending the registry credentials to the storage service", func(t *testing.T) {
+		storage, blobURL := setup(t)
+
+		bs, err := download(t, blobURL)
+		require.NoError(t, err)
+		assert.Equal(t, content, bs)
+
+		require.Len(t, storage.requests, 1)
+		assert.Empty(t, storage.requests[0].Header.Get("Authorization"))
+	})
+
+	t.Run("requests a fresh redirect when the signed URL expired", func(t *testing.T) {
+		storage, blobURL := setup(t)
+		storage.expireNext = true
+
+		bs, err := download(t, blobURL)
+		require.NoError(t, err)
+		assert.Equal(t, content, bs)
+		assert.Equal(t, 2, storage.redirectsIssued)
+	})
+
+	t.Run("resumes an interrupted download from the last byte read with a fresh redirect", func(t *testing.T) {
+		storage, blobURL := setup(t)
+		storage.interruptAfter = 300000
+
+		bs, err := download(t, blobURL)
+		require.NoError(t, err)
+		assert.Equal(t, content, bs)
+
+		require.Len(t, storage.requests, 2)
+		assert.Equal(t, 2, storage.redirectsIssued)
+		assert.Regexp(t, `^bytes=\d+-$`, storage.requests[1].Header.Get("Range"))
+	})
+}
@@ -0,0 +1,260 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"golang.org/x/sync/errgroup"
+)
+
+// scheduledBlob Blob that will be uploaded by uploadBlobsLargestFirst
+type scheduledBlob struct {
+	layer  regv1.Layer
+	digest regv1.Hash
+	size   int64
+}
+
+// uploadBlobsLargestFirst Uploads the unique blobs of the images to the repository, handing them to the workers
+// from the largest to the smallest. Starting the big blobs first ensures they are not picked last and left uploading
+// alone while the other workers are idle, which reduces the total time of the copy
+func (r *SimpleRegistry) uploadBlobsLargestFirst(repo regname.Repository, taggables []regremote.Taggable, concurrency int, opts []regremote.Option, updatesCh chan regv1.Update) error {
+	blobs, err := blobsLargestFirst(taggables, r.includeNonDistributable)
+	if err != nil {
+		return err
+	}
+	if r.layerConcurrency > 0 {
+		concurrency = r.layerConcurrency
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	progress := newBlobsProgress(updatesCh, blobs)
+	// The uploads fail when the context of the registry is done, which stops the scheduling of the remaining blobs
+	g, ctx := errgroup.WithContext(context.Background())
+	g.SetLimit(concurrency)
+	for _, blob := range blobs {
+		blob := blob // copy
+
+		// Go blocks until a worker is available, so blobs start uploading in the order they are scheduled
+		g.Go(func() error {
+			uploaded, err := r.blobUploads.Do(repo, blob.digest, func() error {
+				blobOpts := append(append([]regremote.Option{}, opts...), regremote.WithContext(ctx))
+				done := progress.track(blob, &blobOpts)
+				defer done()
+
+				streamed := false
+				err := regremote.WriteLayer(repo, newStreamedLayer(blob.layer, &streamed), blobOpts...)
+				if err == nil && streamed {
+					r.blobUploadsReport.recordUpload(repo, blob.digest)
+				}
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("Uploading blob %s: %s", blob.digest, err)
+			}
+			if !uploaded {
+				progress.completed(blob)
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return g.Wait()
+}
+
+// putManifests Uploads the manifests of the images, their blobs must already be present in the repository
+func putManifests(images map[regname.Reference]regremote.Taggable, concurrency int, opts []regremote.Option) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+	for ref, img := range images {
+		ref, img := ref, img // copy
+
+		g.Go(func() error {
+			return regremote.Put(ref, img, opts...)
+		})
+	}
+	return g.Wait()
+}
+
+// blobsLargestFirst returns the unique blobs of the images and indexes sorted by size, largest first.
+// Non-distributable layers are only included when includeNonDistributable is true
+func blobsLargestFirst(taggables []regremote.Taggable, includeNonDistributable bool) ([]scheduledBlob, error) {
+	var blobs []scheduledBlob
+	seen := map[regv1.Hash]struct{}{}
+	addLayer := func(layer regv1.Layer) error {
+		digest, err := layer.Digest()
+		if err != nil {
+			return err
+		}
+		if _, found := seen[digest]; found {
+			return nil
+		}
+		seen[digest] = struct{}{}
+
+		size, err := layer.Size()
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, scheduledBlob{layer: layer, digest: digest, size: size})
+		return nil
+	}
+
+	for _, taggable := range taggables {
+		images, err := imagesInTaggable(taggable)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, img := range images {
+			configLayer, err := partial.ConfigLayer(img)
+			if err != nil {
+				return nil, err
+			}
+			err = addLayer(configLayer)
+			if err != nil {
+				return nil, err
+			}
+
+			layers, err := img.Layers()
+			if err != nil {
+				return nil, err
+			}
+			for _, layer := range layers {
+				mediaType, err := layer.MediaType()
+				if err != nil {
+					return nil, err
+				}
+				if !mediaType.IsDistributable() && !includeNonDistributable {
+					continue
+				}
+				err = addLayer(layer)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(blobs, func(i, j int) bool { return blobs[i].size > blobs[j].size })
+	return blobs, nil
+}
+
+// imagesInTaggable returns the image or all the images that are part of the index and its nested indexes
+func imagesInTaggable(taggable regremote.Taggable) ([]regv1.Image, error) {
+	switch t := taggable.(type) {
+	case regv1.Image:
+		return []regv1.Image{t}, nil
+	case regv1.ImageIndex:
+		idxManifest, err := t.IndexManifest()
+		if err != nil {
+			return nil, err
+		}
+
+		var images []regv1.Image
+		for _, desc := range idxManifest.Manifests {
+			switch {
+			case desc.MediaType.IsIndex():
+				childIdx, err := t.ImageIndex(desc.Digest)
+				if err != nil {
+					return nil, err
+				}
+				childImages, err := imagesInTaggable(childIdx)
+				if err != nil {
+					return nil, err
+				}
+				images = append(images, childImages...)
+			case desc.MediaType.IsImage():
+				img, err := t.Image(desc.Digest)
+				if err != nil {
+					return nil, err
+				}
+				images = append(images, img)
+			}
+		}
+		return images, nil
+	default:
+		return nil, nil
+	}
+}
+
+// blobsProgress Combines the progress of the upload of each blob into a single progress for all the blobs
+type blobsProgress struct {
+	updatesCh chan regv1.Update
+	total     int64
+	complete  int64
+	perBlob   map[regv1.Hash]int64
+	lock      sync.Mutex
+}
+
+func newBlobsProgress(updatesCh chan regv1.Update, blobs []scheduledBlob) *blobsProgress {
+	progress := &blobsProgress{updatesCh: updatesCh, perBlob: map[regv1.Hash]int64{}}
+	for _, blob := range blobs {
+		progress.total += blob.size
+	}
+	return progress
+}
+
+// track adds to opts the option that reports the progress of the upload of the blob.
+// The returned function must be called once the upload finishes
+func (p *blobsProgress) track(blob scheduledBlob, opts *[]regremote.Option) func() {
+	if p.updatesCh == nil {
+		return func() {}
+	}
+
+	blobUpdatesCh := make(chan regv1.Update)
+	uploadDone := make(chan struct{})
+	forwarderDone := make(chan struct{})
+	*opts = append(*opts, regremote.WithProgress(blobUpdatesCh))
+
+	go func() {
+		defer close(forwarderDone)
+		for {
+			select {
+			case update, ok := <-blobUpdatesCh:
+				if !ok {
+					return
+				}
+				p.update(blob.digest, update.Complete)
+			case <-uploadDone:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(uploadDone)
+		<-forwarderDone
+	}
+}
+
+// completed marks the blob as uploaded when it was uploaded by someone else
+func (p *blobsProgress) completed(blob scheduledBlob) {
+	if p.updatesCh != nil {
+		p.update(blob.digest, blob.size)
+	}
+}
+
+func (p *blobsProgress) update(digest regv1.Hash, complete int64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.complete += complete - p.perBlob[digest]
+	p.perBlob[digest] = complete
+	p.updatesCh <- regv1.Update{Total: p.total, Complete: p.complete}
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"sync"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// blobUploads Coordinates the uploads of blobs so that a blob is checked and uploaded only once to each repository,
+// even when multiple images that share it are written at the same time.
+// Without it, every worker that finds the blob missing uploads it, which some registries reject with a 409
+type blobUploads struct {
+	lock    sync.Mutex
+	uploads map[string]*blobUpload
+}
+
+type blobUpload struct {
+	done chan struct{}
+	err  error
+}
+
+func newBlobUploads() *blobUploads {
+	return &blobUploads{uploads: map[string]*blobUpload{}}
+}
+
+// Do calls upload unless the blob was already uploaded to the repository. When another caller is uploading the same
+// blob, waits for it to finish and only calls upload if that upload failed.
+// Returns true when upload was called
+func (b *blobUploads) Do(repo regname.Repository, digest regv1.Hash, upload func() error) (bool, error) {
+	if b == nil {
+		return true, upload()
+	}
+
+	key := repo.Name() + "@" + digest.String()
+	for {
+		b.lock.Lock()
+		inProgress, found := b.uploads[key]
+		if !found {
+			current := &blobUpload{done: make(chan struct{})}
+			b.uploads[key] = current
+			b.lock.Unlock()
+
+			current.err = upload()
+			if current.err != nil {
+				// Let the next caller try again
+				b.lock.Lock()
+				delete(b.uploads, key)
+				b.lock.Unlock()
+			}
+			close(current.done)
+			return true, current.err
+		}
+		b.lock.Unlock()
+
+		<-inProgress.done
+		if inProgress.err == nil {
+			return false, nil
+		}
+	}
+}
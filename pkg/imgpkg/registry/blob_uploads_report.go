@@ -0,0 +1,64 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"io"
+	"sync"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// BlobUploadsReport Records the blobs that were uploaded to each repository.
+// Blobs that were already present in the repository, or were mounted from another repository, are not uploaded
+type BlobUploadsReport struct {
+	lock     sync.Mutex
+	uploaded map[string]struct{}
+}
+
+// NewBlobUploadsReport Constructor for BlobUploadsReport
+func NewBlobUploadsReport() *BlobUploadsReport {
+	return &BlobUploadsReport{uploaded: map[string]struct{}{}}
+}
+
+// Uploaded Checks if the blob was uploaded to the repository
+func (b *BlobUploadsReport) Uploaded(repo regname.Repository, digest regv1.Hash) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	_, found := b.uploaded[repo.Name()+"@"+digest.String()]
+	return found
+}
+
+func (b *BlobUploadsReport) recordUpload(repo regname.Repository, digest regv1.Hash) {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.uploaded[repo.Name()+"@"+digest.String()] = struct{}{}
+}
+
+// streamedLayer Records if the contents of the layer were read, which only happens when the layer is uploaded
+type streamedLayer struct {
+	regv1.Layer
+	streamed *bool
+}
+
+// Compressed Returns the contents of the layer and records that they were read
+func (l streamedLayer) Compressed() (io.ReadCloser, error) {
+	*l.streamed = true
+	return l.Layer.Compressed()
+}
+
+// newStreamedLayer Wraps the layer keeping it mountable, so that blobs can still be mounted from other repositories
+func newStreamedLayer(layer regv1.Layer, streamed *bool) regv1.Layer {
+	if mountable, ok := layer.(*regremote.MountableLayer); ok {
+		return &regremote.MountableLayer{Layer: streamedLayer{Layer: mountable.Layer, streamed: streamed}, Reference: mountable.Reference}
+	}
+	return streamedLayer{Layer: layer, streamed: streamed}
+}
@@ -0,0 +1,260 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	regauthn "github.com/google/go-containerregistry/pkg/authn"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/filelock"
+)
+
+var manifestPathRegexp = regexp.MustCompile(`^/v2/(.+)/manifests/([^/]+)$`)
+
+// cachedHeaders Response headers that are stored alongside the manifest
+var cachedHeaders = []string{"Content-Type", "Docker-Content-Digest", "Etag"}
+
+// NewCacheRoundTripper Creates a RoundTripper that caches manifest responses in cacheDir.
+// Manifests requested by digest are immutable and are served from the cache without contacting the registry.
+// Manifests requested by tag are revalidated using the ETag returned by the registry.
+// The responses are stored apart for each identity, the registry and credentials set by NewCacheIdentityRoundTripper,
+// so that a manifest retrieved with some credentials is never served to a request made with other credentials.
+// Requests without identity are cached as anonymous, unless they carry an Authorization header, then they are not cached
+func NewCacheRoundTripper(inner http.RoundTripper, cacheDir string) *CacheRoundTripper {
+	return &CacheRoundTripper{inner: inner, dir: filepath.Join(cacheDir, "manifests")}
+}
+
+// CacheRoundTripper Caches manifest GET responses on disk
+type CacheRoundTripper struct {
+	inner http.RoundTripper
+	dir   string
+}
+
+type cacheEntry struct {
+	Headers map[string]string `json:"headers"`
+	Body    []byte            `json:"body"`
+}
+
+// RoundTrip Returns the cached manifest when possible, otherwise executes the request and caches the response
+func (c *CacheRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return c.inner.RoundTrip(req)
+	}
+	matches := manifestPathRegexp.FindStringSubmatch(req.URL.Path)
+	if matches == nil {
+		return c.inner.RoundTrip(req)
+	}
+
+	identity, known := requestIdentity(req)
+	if !known {
+		return c.inner.RoundTrip(req)
+	}
+
+	reference := matches[2]
+	if _, err := regv1.NewHash(reference); err == nil {
+		if entry, found := c.read(identity, reference); found {
+			return entry.response(req), nil
+		}
+		return c.fetchAndStore(req, identity, reference)
+	}
+
+	// Registries can return a different manifest for the same tag depending on the accepted media types
+	tagKey := req.URL.Host + req.URL.Path + " " + req.Header.Get("Accept")
+	entry, found := c.read(identity, tagKey)
+	if found && entry.Headers["Etag"] != "" {
+		// RoundTrippers should not modify the original request
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", entry.Headers["Etag"])
+	}
+
+	resp, err := c.inner.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if found && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return entry.response(req), nil
+	}
+	return c.store(resp, identity, tagKey)
+}
+
+func (c *CacheRoundTripper) fetchAndStore(req *http.Request, identity string, digest string) (*http.Response, error) {
+	resp, err := c.inner.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	return c.store(resp, identity, digest)
+}
+
+// store saves the response in the cache when the registry allows it and returns a response that can still be read
+func (c *CacheRoundTripper) store(resp *http.Response, identity string, key string) (*http.Response, error) {
+	if resp.StatusCode != http.StatusOK || !isCacheable(resp.Header) {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry := cacheEntry{Headers: map[string]string{}, Body: body}
+	for _, header := range cachedHeaders {
+		if value := resp.Header.Get(header); value != "" {
+			entry.Headers[header] = value
+		}
+	}
+
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	if _, err := regv1.NewHash(key); err == nil && key != digest {
+		// Only cache manifests that match the requested digest
+		return resp, nil
+	}
+
+	// Failing to write the cache should never fail the request
+	_ = c.write(identity, digest, entry)
+	if key != digest && entry.Headers["Etag"] != "" {
+		_ = c.write(identity, key, entry)
+	}
+
+	return resp, nil
+}
+
+func (c *CacheRoundTripper) read(identity string, key string) (cacheEntry, bool) {
+	bs, err := filelock.ReadFile(c.path(identity, key))
+	if err != nil {
+		return cacheEntry{}, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(bs, &entry); err != nil {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *CacheRoundTripper) write(identity string, key string, entry cacheEntry) error {
+	bs, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Join(c.dir, identity), 0700)
+	if err != nil {
+		return err
+	}
+	return filelock.WriteFile(c.path(identity, key), bs, 0600)
+}
+
+func (c *CacheRoundTripper) path(identity string, key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, identity, hex.EncodeToString(hash[:]))
+}
+
+func (e cacheEntry) response(req *http.Request) *http.Response {
+	header := http.Header{}
+	for key, value := range e.Headers {
+		header.Set(key, value)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(e.Body)))
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// isCacheable checks the Cache-Control header returned by the registry
+func isCacheable(header http.Header) bool {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "no-store":
+			return false
+		}
+	}
+	return true
+}
+
+type cacheIdentityContextKey struct{}
+
+// NewCacheIdentityRoundTripper Creates a RoundTripper that records, in each request, the identity used to access the
+// registry, so that the CacheRoundTripper keeps the responses of each identity apart. It has to be wrapped by the
+// RoundTripper that authenticates the requests with auth
+func NewCacheIdentityRoundTripper(inner http.RoundTripper, reg regname.Registry, auth regauthn.Authenticator) *CacheIdentityRoundTripper {
+	return &CacheIdentityRoundTripper{inner: inner, registry: reg.RegistryStr(), auth: auth, once: &sync.Once{}}
+}
+
+// CacheIdentityRoundTripper Adds the identity of the credentials used to access a registry to the context of requests
+type CacheIdentityRoundTripper struct {
+	inner    http.RoundTripper
+	registry string
+	auth     regauthn.Authenticator
+
+	once     *sync.Once
+	identity string
+}
+
+// RoundTrip Executes the request with the identity in its context
+func (c *CacheIdentityRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.once.Do(func() {
+		authConfig := &regauthn.AuthConfig{}
+		if c.auth != nil {
+			var err error
+			authConfig, err = c.auth.Authorization()
+			if err != nil {
+				// Leave the identity unknown, so that the requests are not cached
+				return
+			}
+		}
+		c.identity = cacheIdentity(c.registry, *authConfig)
+	})
+
+	// RoundTrippers should not modify the original request
+	req = req.WithContext(context.WithValue(req.Context(), cacheIdentityContextKey{}, c.identity))
+	return c.inner.RoundTrip(req)
+}
+
+// requestIdentity Returns the identity under which the response to the request can be cached, false when unknown
+func requestIdentity(req *http.Request) (string, bool) {
+	if identity, found := req.Context().Value(cacheIdentityContextKey{}).(string); found {
+		return identity, identity != ""
+	}
+	if req.Header.Get("Authorization") != "" {
+		return "", false
+	}
+	return cacheIdentity(req.URL.Host, regauthn.AuthConfig{}), true
+}
+
+// cacheIdentity Hash of the registry and the credentials, credentials are never stored in the cache
+func cacheIdentity(registry string, authConfig regauthn.AuthConfig) string {
+	bs, _ := json.Marshal(struct {
+		Registry string
+		Auth     regauthn.AuthConfig
+	}{registry, authConfig})
+	hash := sha256.Sum256(bs)
+	return hex.EncodeToString(hash[:])
+}
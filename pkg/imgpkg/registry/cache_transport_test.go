@@ -0,0 +1,132 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	regauthn "github.com/google/go-containerregistry/pkg/authn"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+func TestCacheRoundTripper(t *testing.T) {
+	manifest := []byte(`{"schemaVersion":2}`)
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(manifest))
+
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.URL.Path == "/v2/repo/manifests/no-store" {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		if r.Header.Get("If-None-Match") == `"etag-1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Etag", `"etag-1"`)
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.Write(manifest)
+	}))
+	defer server.Close()
+
+	get := func(t *testing.T, client http.Client, path string) []byte {
+		resp, err := client.Get(server.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return body
+	}
+
+	client := func(cacheDir string) http.Client {
+		return http.Client{Transport: registry.NewCacheRoundTripper(http.DefaultTransport, cacheDir)}
+	}
+
+	t.Run("manifests requested by digest are only fetched once", func(t *testing.T) {
+		requests = nil
+		client := http.Client{Transport: registry.NewCacheRoundTripper(http.DefaultTransport, t.TempDir())}
+
+		assert.Equal(t, manifest, get(t, client, "/v2/repo/manifests/"+digest))
+		assert.Equal(t, manifest, get(t, client, "/v2/repo/manifests/"+digest))
+		assert.Len(t, requests, 1)
+	})
+
+	t.Run("manifests requested by tag are revalidated using the ETag", func(t *testing.T) {
+		requests = nil
+		client := http.Client{Transport: registry.NewCacheRoundTripper(http.DefaultTransport, t.TempDir())}
+
+		assert.Equal(t, manifest, get(t, client, "/v2/repo/manifests/latest"))
+		assert.Equal(t, manifest, get(t, client, "/v2/repo/manifests/latest"))
+		require.Len(t, requests, 2)
+		assert.Equal(t, "", requests[0].Header.Get("If-None-Match"))
+		assert.Equal(t, `"etag-1"`, requests[1].Header.Get("If-None-Match"))
+
+		// The tag response is also cached by digest
+		assert.Equal(t, manifest, get(t, client, "/v2/repo/manifests/"+digest))
+		assert.Len(t, requests, 2)
+	})
+
+	t.Run("responses with Cache-Control no-store are not cached", func(t *testing.T) {
+		requests = nil
+		client := http.Client{Transport: registry.NewCacheRoundTripper(http.DefaultTransport, t.TempDir())}
+
+		get(t, client, "/v2/repo/manifests/no-store")
+		get(t, client, "/v2/repo/manifests/no-store")
+		require.Len(t, requests, 2)
+		assert.Equal(t, "", requests[1].Header.Get("If-None-Match"))
+	})
+
+	t.Run("manifests retrieved with some credentials are not served to other credentials", func(t *testing.T) {
+		requests = nil
+		cacheDir := t.TempDir()
+		reg, err := regname.NewRegistry(server.Listener.Addr().String())
+		require.NoError(t, err)
+		clientFor := func(username string) http.Client {
+			cache := registry.NewCacheRoundTripper(http.DefaultTransport, cacheDir)
+			return http.Client{Transport: registry.NewCacheIdentityRoundTripper(cache, reg, regauthn.FromConfig(regauthn.AuthConfig{Username: username, Password: "password"}))}
+		}
+
+		get(t, clientFor("tenant-1"), "/v2/repo/manifests/"+digest)
+		get(t, clientFor("tenant-1"), "/v2/repo/manifests/"+digest)
+		require.Len(t, requests, 1)
+
+		get(t, clientFor("tenant-2"), "/v2/repo/manifests/"+digest)
+		require.Len(t, requests, 2)
+		get(t, client(cacheDir), "/v2/repo/manifests/"+digest)
+		assert.Len(t, requests, 3, "anonymous requests should not be served the manifests of other identities")
+	})
+
+	t.Run("requests with credentials but without identity are not cached", func(t *testing.T) {
+		requests = nil
+		client := client(t.TempDir())
+
+		for i := 0; i < 2; i++ {
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/v2/repo/manifests/"+digest, nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Basic c29tZTp1c2Vy")
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+		assert.Len(t, requests, 2)
+	})
+
+	t.Run("blobs are not cached", func(t *testing.T) {
+		requests = nil
+		client := http.Client{Transport: registry.NewCacheRoundTripper(http.DefaultTransport, t.TempDir())}
+
+		get(t, client, "/v2/repo/blobs/"+digest)
+		get(t, client, "/v2/repo/blobs/"+digest)
+		assert.Len(t, requests, 2)
+	})
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+)
+
+// CertPoolProvider Returns the CA certificates that should be trusted when connecting to a registry.
+// It is called every time a new TLS connection is established, which allows long-running processes to pick up
+// rotated CA certificates without being restarted. When it returns a nil pool the system pool, together with
+// the certificates in Opts.CACertPaths, is used
+type CertPoolProvider func() (*x509.CertPool, error)
+
+// tlsConfigWithCertPoolProvider creates a TLS configuration that verifies the registry certificates
+// against the pool returned by the provider instead of a pool fixed when the transport was created
+func tlsConfigWithCertPoolProvider(provider CertPoolProvider, defaultPool *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		// RootCAs cannot change after the transport is created, so the verification is done in VerifyConnection
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			pool, err := provider()
+			if err != nil {
+				return fmt.Errorf("Retrieving CA certificates: %s", err)
+			}
+			if pool == nil {
+				pool = defaultPool
+			}
+			return verifyPeerCertificates(state, pool)
+		},
+	}
+}
+
+func verifyPeerCertificates(state tls.ConnectionState, pool *x509.CertPool) error {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("Registry did not provide any certificate")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       state.ServerName,
+		Roots:         pool,
+		Intermediates: intermediates,
+	})
+	return err
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+func TestRegistry_CertPoolProvider(t *testing.T) {
+	expectedDigest := "sha256:477c34d98f9e090a4441cf82d2f1f03e64c8eb730e8c1ef39a8595e685d4df65"
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", string(types.DockerManifestSchema2))
+		w.Header().Set("Docker-Content-Digest", expectedDigest)
+		w.Write([]byte("doesn't matter"))
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	imgRef, err := name.ParseReference(fmt.Sprintf("%s/repo:latest", u.Host))
+	require.NoError(t, err)
+
+	serverCAPool := x509.NewCertPool()
+	serverCAPool.AddCert(server.Certificate())
+
+	t.Run("uses the CA certificates returned by the provider on every new connection", func(t *testing.T) {
+		currentPool := x509.NewCertPool()
+		numberOfCalls := 0
+		subject, err := registry.NewSimpleRegistry(registry.Opts{
+			VerifyCerts: true,
+			CertPoolProvider: func() (*x509.CertPool, error) {
+				numberOfCalls++
+				return currentPool, nil
+			},
+		})
+		require.NoError(t, err)
+
+		_, err = subject.Digest(imgRef)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "certificate signed by unknown authority")
+
+		// Simulate the rotation of the CA certificates
+		currentPool = serverCAPool
+
+		digest, err := subject.Digest(imgRef)
+		require.NoError(t, err)
+		require.Equal(t, expectedDigest, digest.String())
+		require.Greater(t, numberOfCalls, 1)
+	})
+
+	t.Run("when the provider fails, it returns an error", func(t *testing.T) {
+		subject, err := registry.NewSimpleRegistry(registry.Opts{
+			VerifyCerts: true,
+			CertPoolProvider: func() (*x509.CertPool, error) {
+				return nil, fmt.Errorf("some error")
+			},
+		})
+		require.NoError(t, err)
+
+		_, err = subject.Digest(imgRef)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Retrieving CA certificates: some error")
+	})
+}
@@ -0,0 +1,231 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// chunkMinLengthHeader Header returned by registries, when an upload is initiated, with the minimum chunk size they accept
+	chunkMinLengthHeader = "OCI-Chunk-Min-Length"
+	// defaultAutoChunkSize Chunk size used after a registry rejects a layer for being too big, S3 backed registries
+	// do not accept chunks smaller than 5MiB
+	defaultAutoChunkSize int64 = 5 * 1024 * 1024
+	// minAutoChunkSize Smallest chunk size that is used when chunks are rejected for being too big
+	minAutoChunkSize int64 = 256 * 1024
+)
+
+// NewChunkedUploadRoundTripper Creates a RoundTripper that splits the upload of layers in multiple PATCH requests.
+// When chunkSize is positive all layers are uploaded in chunks of that size.
+// When chunkSize is 0 layers are uploaded in a single request until the registry asks for a minimum chunk size
+// or rejects the upload for being too big, in which case the error is reported as temporary so that the upload is
+// retried using chunks.
+// When chunkSize is negative layers are always uploaded in a single request
+func NewChunkedUploadRoundTripper(inner http.RoundTripper, chunkSize int64) *ChunkedUploadRoundTripper {
+	return &ChunkedUploadRoundTripper{
+		inner:         inner,
+		fixedSize:     chunkSize,
+		chunkSizes:    map[string]int64{},
+		minChunkSizes: map[string]int64{},
+		lock:          &sync.Mutex{},
+	}
+}
+
+// ChunkedUploadRoundTripper Uploads layers in chunks with a size that is adjusted per registry
+type ChunkedUploadRoundTripper struct {
+	inner     http.RoundTripper
+	fixedSize int64
+
+	chunkSizes    map[string]int64
+	minChunkSizes map[string]int64
+	lock          *sync.Mutex
+}
+
+// chunkSizeAdjustedError Reported when the chunk size used for a registry changes, so that the upload is retried
+type chunkSizeAdjustedError struct {
+	host       string
+	statusCode int
+	chunkSize  int64
+}
+
+func (e chunkSizeAdjustedError) Error() string {
+	return fmt.Sprintf("Registry %s rejected the upload with status %d, retrying with chunks of %d bytes", e.host, e.statusCode, e.chunkSize)
+}
+
+// Temporary allows go-containerregistry to retry the upload of the layer
+func (e chunkSizeAdjustedError) Temporary() bool { return true }
+
+// RoundTrip Executes the request, splitting the body of layer uploads when needed
+func (c *ChunkedUploadRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if c.fixedSize < 0 {
+		return c.inner.RoundTrip(req)
+	}
+
+	switch {
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/blobs/uploads/"):
+		resp, err := c.inner.RoundTrip(req)
+		if err == nil {
+			c.recordMinChunkSize(req.URL.Host, resp.Header.Get(chunkMinLengthHeader))
+		}
+		return resp, err
+
+	case req.Method == http.MethodPatch:
+		chunkSize := c.chunkSize(req.URL.Host)
+		if chunkSize == 0 {
+			resp, err := c.inner.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			if resp.StatusCode == http.StatusRequestEntityTooLarge {
+				return c.adjustChunkSize(req.URL.Host, resp, defaultAutoChunkSize)
+			}
+			return resp, nil
+		}
+		return c.uploadInChunks(req, chunkSize)
+
+	default:
+		return c.inner.RoundTrip(req)
+	}
+}
+
+func (c *ChunkedUploadRoundTripper) uploadInChunks(req *http.Request, chunkSize int64) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	} else {
+		req.Body = http.NoBody
+	}
+
+	location := req.URL
+	buf := make([]byte, chunkSize)
+	var offset int64
+	var resp *http.Response
+
+	for {
+		n, readErr := io.ReadFull(req.Body, buf)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return nil, readErr
+		}
+		if n == 0 && offset > 0 {
+			break
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		chunk := buf[:n]
+		chunkReq := req.Clone(req.Context())
+		chunkReq.URL = location
+		chunkReq.Host = location.Host
+		chunkReq.ContentLength = int64(n)
+		chunkReq.Body = io.NopCloser(bytes.NewReader(chunk))
+		chunkReq.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(chunk)), nil }
+		if n > 0 {
+			chunkReq.Header.Set("Content-Range", fmt.Sprintf("%d-%d", offset, offset+int64(n)-1))
+		}
+
+		var err error
+		resp, err = c.inner.RoundTrip(chunkReq)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusAccepted {
+			return c.handleRejectedChunk(req.URL.Host, resp, chunkSize)
+		}
+		offset += int64(n)
+
+		if readErr != nil {
+			break
+		}
+
+		nextLocation, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("Parsing upload location: %s", err)
+		}
+		location = location.ResolveReference(nextLocation)
+	}
+
+	return resp, nil
+}
+
+// handleRejectedChunk changes the chunk size used for the registry when the error is caused by the size of the chunk
+func (c *ChunkedUploadRoundTripper) handleRejectedChunk(host string, resp *http.Response, chunkSize int64) (*http.Response, error) {
+	if c.fixedSize > 0 {
+		return resp, nil
+	}
+
+	switch resp.StatusCode {
+	case http.StatusRequestEntityTooLarge:
+		newSize := chunkSize / 2
+		if newSize < minAutoChunkSize || newSize < c.minChunkSize(host) {
+			return resp, nil
+		}
+		return c.adjustChunkSize(host, resp, newSize)
+
+	case http.StatusBadRequest, http.StatusRequestedRangeNotSatisfiable:
+		// Some registries reject chunks that are too small without saying what the minimum is
+		if chunkSize >= defaultAutoChunkSize {
+			return resp, nil
+		}
+		return c.adjustChunkSize(host, resp, defaultAutoChunkSize)
+
+	default:
+		return resp, nil
+	}
+}
+
+func (c *ChunkedUploadRoundTripper) adjustChunkSize(host string, resp *http.Response, chunkSize int64) (*http.Response, error) {
+	resp.Body.Close()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if chunkSize < c.minChunkSizes[host] {
+		chunkSize = c.minChunkSizes[host]
+	}
+	c.chunkSizes[host] = chunkSize
+
+	return nil, chunkSizeAdjustedError{host: host, statusCode: resp.StatusCode, chunkSize: chunkSize}
+}
+
+func (c *ChunkedUploadRoundTripper) recordMinChunkSize(host string, headerValue string) {
+	if headerValue == "" || c.fixedSize > 0 {
+		return
+	}
+	minSize, err := strconv.ParseInt(headerValue, 10, 64)
+	if err != nil || minSize <= 0 {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.minChunkSizes[host] = minSize
+	if c.chunkSizes[host] < minSize {
+		c.chunkSizes[host] = minSize
+	}
+}
+
+func (c *ChunkedUploadRoundTripper) chunkSize(host string) int64 {
+	if c.fixedSize > 0 {
+		return c.fixedSize
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.chunkSizes[host]
+}
+
+func (c *ChunkedUploadRoundTripper) minChunkSize(host string) int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.minChunkSizes[host]
+}
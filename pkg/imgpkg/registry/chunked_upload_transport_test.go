@@ -0,0 +1,141 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+type uploadRequest struct {
+	path         string
+	contentRange string
+	body         string
+}
+
+func TestChunkedUploadRoundTripper(t *testing.T) {
+	var uploads []uploadRequest
+	minChunkLength := ""
+	rejectMonolithic := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			if minChunkLength != "" {
+				w.Header().Set("OCI-Chunk-Min-Length", minChunkLength)
+			}
+			w.Header().Set("Location", "/v2/repo/blobs/uploads/session-0")
+			w.WriteHeader(http.StatusAccepted)
+		case http.MethodPatch:
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			if rejectMonolithic && r.Header.Get("Content-Range") == "" {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			uploads = append(uploads, uploadRequest{path: r.URL.Path, contentRange: r.Header.Get("Content-Range"), body: string(body)})
+			w.Header().Set("Location", fmt.Sprintf("/v2/repo/blobs/uploads/session-%d", len(uploads)))
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer server.Close()
+
+	post := func(t *testing.T, client http.Client) {
+		resp, err := client.Post(server.URL+"/v2/repo/blobs/uploads/", "application/json", nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	patch := func(client http.Client, body string) (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodPatch, server.URL+"/v2/repo/blobs/uploads/session-0", strings.NewReader(body))
+		require.NoError(t, err)
+		return client.Do(req)
+	}
+
+	t.Run("when a chunk size is provided, uploads the layer in chunks following the upload location", func(t *testing.T) {
+		uploads = nil
+		client := http.Client{Transport: registry.NewChunkedUploadRoundTripper(http.DefaultTransport, 4)}
+
+		resp, err := patch(client, "0123456789")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, "/v2/repo/blobs/uploads/session-3", resp.Header.Get("Location"))
+		assert.Equal(t, []uploadRequest{
+			{path: "/v2/repo/blobs/uploads/session-0", contentRange: "0-3", body: "0123"},
+			{path: "/v2/repo/blobs/uploads/session-1", contentRange: "4-7", body: "4567"},
+			{path: "/v2/repo/blobs/uploads/session-2", contentRange: "8-9", body: "89"},
+		}, uploads)
+	})
+
+	t.Run("when the registry provides a minimum chunk size, uploads the layer in chunks of that size", func(t *testing.T) {
+		uploads = nil
+		minChunkLength = "6"
+		defer func() { minChunkLength = "" }()
+		client := http.Client{Transport: registry.NewChunkedUploadRoundTripper(http.DefaultTransport, 0)}
+
+		post(t, client)
+		resp, err := patch(client, "0123456789")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, []uploadRequest{
+			{path: "/v2/repo/blobs/uploads/session-0", contentRange: "0-5", body: "012345"},
+			{path: "/v2/repo/blobs/uploads/session-1", contentRange: "6-9", body: "6789"},
+		}, uploads)
+	})
+
+	t.Run("when the registry rejects the layer for being too big, returns a temporary error and uses chunks afterwards", func(t *testing.T) {
+		uploads = nil
+		rejectMonolithic = true
+		defer func() { rejectMonolithic = false }()
+		client := http.Client{Transport: registry.NewChunkedUploadRoundTripper(http.DefaultTransport, 0)}
+
+		_, err := patch(client, "0123456789")
+		require.Error(t, err)
+		temporaryErr, ok := err.(interface{ Temporary() bool })
+		require.True(t, ok)
+		assert.True(t, temporaryErr.Temporary())
+		assert.Contains(t, err.Error(), "rejected the upload with status 413")
+
+		resp, err := patch(client, "0123456789")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, []uploadRequest{
+			{path: "/v2/repo/blobs/uploads/session-0", contentRange: "0-9", body: "0123456789"},
+		}, uploads)
+	})
+
+	t.Run("when the chunk size is negative, always uploads the layer in a single request", func(t *testing.T) {
+		uploads = nil
+		minChunkLength = "6"
+		defer func() { minChunkLength = "" }()
+		client := http.Client{Transport: registry.NewChunkedUploadRoundTripper(http.DefaultTransport, -1)}
+
+		post(t, client)
+		resp, err := patch(client, "0123456789")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, []uploadRequest{
+			{path: "/v2/repo/blobs/uploads/session-0", body: "0123456789"},
+		}, uploads)
+	})
+
+	t.Run("when the layer is empty, sends a single request", func(t *testing.T) {
+		uploads = nil
+		client := http.Client{Transport: registry.NewChunkedUploadRoundTripper(http.DefaultTransport, 4)}
+
+		req, err := http.NewRequest(http.MethodPatch, server.URL+"/v2/repo/blobs/uploads/session-0", bytes.NewReader(nil))
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, []uploadRequest{{path: "/v2/repo/blobs/uploads/session-0"}}, uploads)
+	})
+}
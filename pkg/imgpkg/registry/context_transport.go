@@ -0,0 +1,75 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// NewContextRoundTripper Creates a RoundTripper that cancels the requests, and the download of their bodies, when ctx
+// is done
+func NewContextRoundTripper(inner http.RoundTripper, ctx context.Context) *ContextRoundTripper {
+	return &ContextRoundTripper{inner: inner, ctx: ctx}
+}
+
+// ContextRoundTripper Cancels the requests when the context of the registry is done, independently of the context of
+// each request. Requests created without a context, like the ones retrieving tokens, are also cancelled
+type ContextRoundTripper struct {
+	inner http.RoundTripper
+	ctx   context.Context
+}
+
+// RoundTrip Executes the request, it fails right away when the context is already done
+func (c *ContextRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			cancel()
+		case <-stop:
+		}
+	}()
+	release := func() {
+		close(stop)
+		cancel()
+	}
+
+	resp, err := c.inner.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		release()
+		if c.ctx.Err() != nil {
+			return nil, c.ctx.Err()
+		}
+		return nil, err
+	}
+	if resp.Body == nil {
+		release()
+		return resp, nil
+	}
+	// The request is only done when its body is closed, the download of the body is also cancelled
+	resp.Body = &contextBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// contextBody Releases the resources of the request when the body is closed
+type contextBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+// Close Closes the body
+func (c *contextBody) Close() error {
+	err := c.ReadCloser.Close()
+	c.once.Do(c.release)
+	return err
+}
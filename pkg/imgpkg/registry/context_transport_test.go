@@ -0,0 +1,87 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+func TestContextRoundTripper(t *testing.T) {
+	// The server sends the headers and then the body slowly, until the client goes away
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+				w.Write([]byte("chunk"))
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	defer server.Close()
+
+	t.Run("when the context is done, it cancels the download of the body", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		client := &http.Client{Transport: registry.NewContextRoundTripper(http.DefaultTransport, ctx)}
+
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		time.AfterFunc(50*time.Millisecond, cancel)
+		done := make(chan error, 1)
+		go func() {
+			_, err := io.ReadAll(resp.Body)
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			require.Error(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the download to be cancelled")
+		}
+	})
+
+	t.Run("when the context is already done, it does not send the request", func(t *testing.T) {
+		requests := 0
+		counting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { requests++ }))
+		defer counting.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		client := &http.Client{Transport: registry.NewContextRoundTripper(http.DefaultTransport, ctx)}
+
+		_, err := client.Get(counting.URL)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, context.Canceled), err.Error())
+		assert.Equal(t, 0, requests)
+	})
+
+	t.Run("when the registry is created with a context that is done, its operations fail", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		reg, err := registry.NewSimpleRegistryWithContext(ctx, registry.Opts{Anon: true, Insecure: true})
+		require.NoError(t, err)
+
+		ref, err := regname.ParseReference(server.Listener.Addr().String()+"/repo:tag", regname.Insecure)
+		require.NoError(t, err)
+		_, err = reg.Get(ref)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "context canceled")
+	})
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// registryAPIBlob Path of the registry API used to retrieve a blob, the digest is the first submatch
+var registryAPIBlob = regexp.MustCompile(`^/v2/.+/blobs/([a-z0-9]+:[a-f0-9]+)$`)
+
+// NewDownloadProgressRoundTripper Creates a RoundTripper that reports the bytes downloaded for each blob
+func NewDownloadProgressRoundTripper(inner http.RoundTripper, listener TransferProgressListener) *DownloadProgressRoundTripper {
+	return &DownloadProgressRoundTripper{inner: inner, listener: listener}
+}
+
+// DownloadProgressRoundTripper Reports the progress of the blob downloads as their bodies are read.
+// Registries that redirect the downloads to a storage service are supported, the blob is identified by the request
+// that was redirected
+type DownloadProgressRoundTripper struct {
+	inner    http.RoundTripper
+	listener TransferProgressListener
+}
+
+// RoundTrip Executes the request and, when it retrieves a blob, reports the progress of reading the response
+func (d *DownloadProgressRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := d.inner.RoundTrip(req)
+	if err != nil || req.Method != http.MethodGet {
+		return resp, err
+	}
+
+	digest := blobDigest(req)
+	if digest == "" || resp.StatusCode != http.StatusOK || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, err
+	}
+
+	// The total is 0 when the registry does not provide the size of the blob
+	total := resp.ContentLength
+	if total < 0 {
+		total = 0
+	}
+	d.listener.OnTransferProgress(digest, regv1.Update{Total: total})
+	resp.Body = &downloadProgressBody{body: resp.Body, digest: digest, total: total, listener: d.listener}
+	return resp, nil
+}
+
+// blobDigest Returns the digest of the blob retrieved by the request, empty when it does not retrieve a blob
+func blobDigest(req *http.Request) string {
+	for current := req; current != nil; {
+		if matches := registryAPIBlob.FindStringSubmatch(current.URL.Path); matches != nil {
+			return matches[1]
+		}
+		if current.Response == nil {
+			break
+		}
+		current = current.Response.Request
+	}
+	return ""
+}
+
+// downloadProgressBody Reports the bytes read from the body
+type downloadProgressBody struct {
+	body     io.ReadCloser
+	digest   string
+	total    int64
+	complete int64
+	listener TransferProgressListener
+}
+
+// Read Reads from the wrapped body and reports the bytes read so far
+func (d *downloadProgressBody) Read(p []byte) (int, error) {
+	n, err := d.body.Read(p)
+	if n > 0 {
+		d.complete += int64(n)
+		d.listener.OnTransferProgress(d.digest, regv1.Update{Total: d.total, Complete: d.complete})
+	}
+	return n, err
+}
+
+// Close Closes the wrapped body
+func (d *downloadProgressBody) Close() error { return d.body.Close() }
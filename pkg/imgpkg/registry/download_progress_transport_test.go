@@ -0,0 +1,68 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+func TestDownloadProgressRoundTripper(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	content := bytes.Repeat([]byte("a"), 100000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/repo/blobs/" + digest:
+			http.Redirect(w, r, "/storage/blob", http.StatusTemporaryRedirect)
+		case "/v2/repo/blobs/sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210", "/storage/blob", "/v2/repo/manifests/latest":
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	download := func(progress *recordedProgress, path string) {
+		client := &http.Client{Transport: registry.NewDownloadProgressRoundTripper(http.DefaultTransport, progress)}
+		resp, err := client.Get(server.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		bs, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, content, bs)
+	}
+
+	t.Run("reports the bytes downloaded for each blob", func(t *testing.T) {
+		progress := &recordedProgress{}
+		download(progress, "/v2/repo/blobs/sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210")
+
+		update := progress.updates["sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"]
+		assert.Equal(t, int64(len(content)), update.Total)
+		assert.Equal(t, int64(len(content)), update.Complete)
+	})
+
+	t.Run("identifies blobs redirected to a storage service by the redirected request", func(t *testing.T) {
+		progress := &recordedProgress{}
+		download(progress, "/v2/repo/blobs/"+digest)
+
+		require.Len(t, progress.updates, 1)
+		assert.Equal(t, int64(len(content)), progress.updates[digest].Complete)
+	})
+
+	t.Run("does not report requests that do not retrieve blobs", func(t *testing.T) {
+		progress := &recordedProgress{}
+		download(progress, "/v2/repo/manifests/latest")
+
+		assert.Empty(t, progress.updates)
+	})
+}
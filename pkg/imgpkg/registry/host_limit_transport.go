@@ -0,0 +1,57 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"net/http"
+	"sync"
+)
+
+// NewHostLimitRoundTripper Creates a RoundTripper that sends at most maxRequests requests at the same time to each host
+func NewHostLimitRoundTripper(inner http.RoundTripper, maxRequests int) *HostLimitRoundTripper {
+	return &HostLimitRoundTripper{
+		inner:       inner,
+		maxRequests: maxRequests,
+		slots:       map[string]chan struct{}{},
+		lock:        &sync.Mutex{},
+	}
+}
+
+// HostLimitRoundTripper Limits the number of concurrent requests sent to each host, so that copies that read from many
+// registries can still run in parallel without going over the rate limits of any of them.
+// A request holds its slot until the response headers are received, the body is read outside the limit so that a
+// blob can be streamed from a registry to the same registry without waiting on itself
+type HostLimitRoundTripper struct {
+	inner       http.RoundTripper
+	maxRequests int
+
+	slots map[string]chan struct{}
+	lock  *sync.Mutex
+}
+
+// RoundTrip Waits for a free slot for the host of the request and executes it
+func (h *HostLimitRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	slots := h.hostSlots(req.URL.Host)
+
+	select {
+	case slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	defer func() { <-slots }()
+
+	return h.inner.RoundTrip(req)
+}
+
+func (h *HostLimitRoundTripper) hostSlots(host string) chan struct{} {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	slots, found := h.slots[host]
+	if !found {
+		slots = make(chan struct{}, h.maxRequests)
+		h.slots[host] = slots
+	}
+	return slots
+}
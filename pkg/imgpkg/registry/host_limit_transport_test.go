@@ -0,0 +1,106 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+// concurrencyServer Server that records the maximum number of requests it handled at the same time
+type concurrencyServer struct {
+	*httptest.Server
+	current int32
+	max     int32
+}
+
+func newConcurrencyServer() *concurrencyServer {
+	s := &concurrencyServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&s.current, 1)
+		defer atomic.AddInt32(&s.current, -1)
+		for {
+			max := atomic.LoadInt32(&s.max)
+			if current <= max || atomic.CompareAndSwapInt32(&s.max, max, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	return s
+}
+
+func TestHostLimitRoundTripper(t *testing.T) {
+	t.Run("limits the concurrent requests to each host independently", func(t *testing.T) {
+		server1 := newConcurrencyServer()
+		defer server1.Close()
+		server2 := newConcurrencyServer()
+		defer server2.Close()
+
+		subject := registry.NewHostLimitRoundTripper(http.DefaultTransport, 2)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			for _, server := range []*concurrencyServer{server1, server2} {
+				wg.Add(1)
+				go func(url string) {
+					defer wg.Done()
+					req, err := http.NewRequest(http.MethodGet, url, nil)
+					require.NoError(t, err)
+					resp, err := subject.RoundTrip(req)
+					require.NoError(t, err)
+					resp.Body.Close()
+				}(server.URL)
+			}
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(2), atomic.LoadInt32(&server1.max))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&server2.max))
+	})
+
+	t.Run("stops waiting for a slot when the request is canceled", func(t *testing.T) {
+		server := newConcurrencyServer()
+		defer server.Close()
+
+		subject := registry.NewHostLimitRoundTripper(http.DefaultTransport, 1)
+
+		blocker := make(chan struct{})
+		blockingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-blocker }))
+		defer blockingServer.Close()
+		defer close(blocker)
+
+		go func() {
+			req, _ := http.NewRequest(http.MethodGet, blockingServer.URL, nil)
+			resp, err := subject.RoundTrip(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+		time.Sleep(20 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, blockingServer.URL, nil)
+		require.NoError(t, err)
+		_, err = subject.RoundTrip(req)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// Other hosts are not affected
+		req, err = http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, err := subject.RoundTrip(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	})
+}
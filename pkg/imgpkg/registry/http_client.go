@@ -0,0 +1,29 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// defaultHTTPClientResponseHeaderTimeout time to wait for the headers of a response when Opts does not provide one
+const defaultHTTPClientResponseHeaderTimeout = time.Minute
+
+// NewHTTPClient Creates a client for the requests that are not sent to a registry, e.g. the download of external
+// artifacts of a bundle. It trusts the same certificates as the registries, and its requests are cancelled when ctx
+// is done
+func NewHTTPClient(ctx context.Context, opts Opts) (*http.Client, error) {
+	httpTran, err := newHTTPTransport(opts)
+	if err != nil {
+		return nil, fmt.Errorf("Creating HTTP transport: %s", err)
+	}
+	if httpTran.ResponseHeaderTimeout == 0 {
+		httpTran.ResponseHeaderTimeout = defaultHTTPClientResponseHeaderTimeout
+	}
+
+	return &http.Client{Transport: NewContextRoundTripper(httpTran, ctx)}, nil
+}
@@ -6,9 +6,10 @@ package registry
 import (
 	"fmt"
 	"io"
+	"os"
+	"strings"
 
 	"github.com/awslabs/amazon-ecr-credential-helper/ecr-login"
-	"github.com/chrismellard/docker-credential-acr-env/pkg/credhelper"
 	regauthn "github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/authn/github"
 	"github.com/google/go-containerregistry/pkg/v1/google"
@@ -20,19 +21,48 @@ import (
 // keychains that contain credentials for 'any' target. i.e. env keychain takes precedence over the custom keychain.
 // Since env keychain contains credentials per HOSTNAME, and custom keychain doesn't.
 func Keychain(keychainOpts auth.KeychainOpts, environFunc func() []string) (regauthn.Keychain, error) {
+	sources, err := KeychainSources(keychainOpts, environFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	var keychain []regauthn.Keychain
+	for _, source := range sources {
+		keychain = append(keychain, source.Keychain)
+	}
+	return regauthn.NewMultiKeychain(keychain...), nil
+}
+
+// KeychainSource Keychain that can provide credentials and a description of where the credentials come from
+type KeychainSource struct {
+	Name     string
+	Keychain regauthn.Keychain
+}
+
+// KeychainSources returns the keychains used by Keychain in the order they are asked for credentials
+func KeychainSources(keychainOpts auth.KeychainOpts, environFunc func() []string) ([]KeychainSource, error) {
 	// env keychain comes first
-	keychain := []regauthn.Keychain{auth.NewEnvKeychain(environFunc)}
+	sources := []KeychainSource{{Name: "environment variables ($IMGPKG_REGISTRY_*)", Keychain: auth.NewEnvKeychain(environFunc)}}
 
 	if keychainOpts.EnableIaasAuthProviders {
 		// if enabled, fall back to iaas keychains
-		keychain = append(keychain,
-			google.Keychain,
-			regauthn.NewKeychainFromHelper(ecr.NewECRHelper(ecr.WithLogger(io.Discard))),
-			regauthn.NewKeychainFromHelper(credhelper.NewACRCredentialsHelper()),
-			github.Keychain,
+		sources = append(sources,
+			KeychainSource{Name: "gke", Keychain: google.Keychain},
+			KeychainSource{Name: "ecr", Keychain: regauthn.NewKeychainFromHelper(ecr.NewECRHelper(ecr.WithLogger(io.Discard)))},
+			KeychainSource{Name: "aks", Keychain: auth.NewACRKeychain(environFunc)},
+			KeychainSource{Name: "github", Keychain: github.Keychain},
 		)
 	} else {
+		activeKeychains := map[auth.IAASKeychain]struct{}{}
 		for _, activeKeychain := range keychainOpts.ActiveKeychains {
+			if alias, found := keychainAliases[activeKeychain]; found {
+				activeKeychain = alias
+			}
+			if _, found := activeKeychains[activeKeychain]; found {
+				continue
+			}
+			activeKeychains[activeKeychain] = struct{}{}
+
 			var k regauthn.Keychain
 			switch activeKeychain {
 			case auth.GKEKeychain:
@@ -40,18 +70,90 @@ func Keychain(keychainOpts auth.KeychainOpts, environFunc func() []string) (rega
 			case auth.ECRKeychain:
 				k = regauthn.NewKeychainFromHelper(ecr.NewECRHelper(ecr.WithLogger(io.Discard)))
 			case auth.AKSKeychain:
-				k = regauthn.NewKeychainFromHelper(credhelper.NewACRCredentialsHelper())
+				k = auth.NewACRKeychain(environFunc)
 			case auth.GithubKeychain:
 				k = github.Keychain
 			default:
-				return nil, fmt.Errorf("Unable to load keychain for %s, available keychains [aks, ecr, gke, github]]", string(activeKeychain))
+				return nil, fmt.Errorf("Unable to load keychain for %s, available keychains [aks, ecr, gke, github], or the aliases acr and gcr", string(activeKeychain))
+			}
+			sources = append(sources, KeychainSource{Name: string(activeKeychain), Keychain: k})
+		}
+
+		// The cloud keychains only provide credentials for the registries of their cloud, so they are safe to use
+		// whenever credentials for the cloud are available in the environment
+		for _, detected := range detectedKeychains {
+			if _, found := activeKeychains[detected.keychain]; found || !envVarSet(environFunc, detected.envVars) {
+				continue
 			}
-			keychain = append(keychain, k)
+			sources = append(sources, KeychainSource{Name: detected.name, Keychain: detected.newKeychain(environFunc)})
 		}
 	}
 
 	// command-line flags and docker keychain comes last
-	keychain = append(keychain, auth.CustomRegistryKeychain{Opts: keychainOpts})
+	customKeychain := auth.CustomRegistryKeychain{Opts: keychainOpts}
+	sources = append(sources, KeychainSource{Name: customKeychain.Description(), Keychain: customKeychain})
 
-	return regauthn.NewMultiKeychain(keychain...), nil
+	return sources, nil
+}
+
+// keychainAliases Alternative names of the keychains, named after the registries they provide credentials for
+var keychainAliases = map[auth.IAASKeychain]auth.IAASKeychain{
+	"gcr": auth.GKEKeychain,
+	"acr": auth.AKSKeychain,
+}
+
+// detectedKeychains Keychains activated when the environment configures credentials for their cloud
+var detectedKeychains = []struct {
+	keychain    auth.IAASKeychain
+	name        string
+	envVars     []string
+	newKeychain func(environFunc func() []string) regauthn.Keychain
+}{
+	{
+		keychain: auth.GKEKeychain,
+		name:     "gke (detected from $GOOGLE_APPLICATION_CREDENTIALS)",
+		envVars:  []string{"GOOGLE_APPLICATION_CREDENTIALS"},
+		newKeychain: func(func() []string) regauthn.Keychain {
+			return google.Keychain
+		},
+	},
+	{
+		keychain: auth.ECRKeychain,
+		name:     "ecr (detected from $AWS_* environment variables)",
+		envVars: []string{
+			"AWS_ACCESS_KEY_ID",
+			"AWS_PROFILE",
+			"AWS_WEB_IDENTITY_TOKEN_FILE",
+			"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI",
+			"AWS_CONTAINER_CREDENTIALS_FULL_URI",
+		},
+		newKeychain: func(func() []string) regauthn.Keychain {
+			return regauthn.NewKeychainFromHelper(ecr.NewECRHelper(ecr.WithLogger(io.Discard)))
+		},
+	},
+	{
+		keychain:    auth.AKSKeychain,
+		name:        "aks (detected from $AZURE_* environment variables)",
+		envVars:     []string{"AZURE_FEDERATED_TOKEN_FILE", "AZURE_CLIENT_SECRET"},
+		newKeychain: auth.NewACRKeychain,
+	},
+}
+
+// envVarSet returns true when any of the environment variables is set to a non-empty value
+func envVarSet(environFunc func() []string, envVars []string) bool {
+	if environFunc == nil {
+		environFunc = os.Environ
+	}
+	for _, env := range environFunc() {
+		key, value, _ := strings.Cut(env, "=")
+		if value == "" {
+			continue
+		}
+		for _, envVar := range envVars {
+			if key == envVar {
+				return true
+			}
+		}
+	}
+	return false
 }
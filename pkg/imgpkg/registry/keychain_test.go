@@ -0,0 +1,123 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	regauthn "github.com/google/go-containerregistry/pkg/authn"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry/auth"
+)
+
+func TestKeychainSources(t *testing.T) {
+	sourceNames := func(t *testing.T, opts auth.KeychainOpts, env []string) []string {
+		sources, err := registry.KeychainSources(opts, func() []string { return env })
+		require.NoError(t, err)
+		var names []string
+		for _, source := range sources {
+			names = append(names, source.Name)
+		}
+		return names
+	}
+
+	t.Run("when AWS credentials are in the environment, it activates the ecr keychain", func(t *testing.T) {
+		for _, env := range []string{"AWS_ACCESS_KEY_ID=some-key", "AWS_PROFILE=some-profile", "AWS_WEB_IDENTITY_TOKEN_FILE=/some/token"} {
+			names := sourceNames(t, auth.KeychainOpts{}, []string{env})
+			require.Len(t, names, 3)
+			assert.Equal(t, "ecr (detected from $AWS_* environment variables)", names[1], "it is asked before the docker config")
+		}
+	})
+
+	t.Run("when no AWS credentials are in the environment, it does not activate the ecr keychain", func(t *testing.T) {
+		names := sourceNames(t, auth.KeychainOpts{}, []string{"AWS_REGION=us-east-1", "AWS_PROFILE="})
+		assert.Len(t, names, 2)
+		assert.NotContains(t, names, "ecr (detected from $AWS_* environment variables)")
+	})
+
+	t.Run("when the ecr keychain is activated explicitly, it is only used once", func(t *testing.T) {
+		names := sourceNames(t, auth.KeychainOpts{ActiveKeychains: []auth.IAASKeychain{auth.ECRKeychain, auth.ECRKeychain}},
+			[]string{"AWS_ACCESS_KEY_ID=some-key"})
+		require.Len(t, names, 3)
+		assert.Equal(t, "ecr", names[1])
+	})
+
+	t.Run("when an unknown keychain is activated, it fails", func(t *testing.T) {
+		_, err := registry.KeychainSources(auth.KeychainOpts{ActiveKeychains: []auth.IAASKeychain{"some-cloud"}}, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Unable to load keychain for some-cloud")
+	})
+
+	t.Run("when Google or Azure credentials are in the environment, it activates the gke or aks keychain", func(t *testing.T) {
+		names := sourceNames(t, auth.KeychainOpts{}, []string{"GOOGLE_APPLICATION_CREDENTIALS=/some/key.json", "AZURE_FEDERATED_TOKEN_FILE=/some/token"})
+		require.Len(t, names, 4)
+		assert.Equal(t, []string{
+			"environment variables ($IMGPKG_REGISTRY_*)",
+			"gke (detected from $GOOGLE_APPLICATION_CREDENTIALS)",
+			"aks (detected from $AZURE_* environment variables)",
+		}, names[:3])
+	})
+
+	t.Run("when a keychain is activated by its alias, it is activated once with its name", func(t *testing.T) {
+		names := sourceNames(t, auth.KeychainOpts{ActiveKeychains: []auth.IAASKeychain{"gcr", "acr", auth.GKEKeychain}},
+			[]string{"GOOGLE_APPLICATION_CREDENTIALS=/some/key.json", "AZURE_CLIENT_SECRET=some-secret"})
+		require.Len(t, names, 4)
+		assert.Equal(t, []string{"environment variables ($IMGPKG_REGISTRY_*)", "gke", "aks"}, names[:3])
+	})
+}
+
+func TestACRKeychain(t *testing.T) {
+	t.Run("when using workload identity, it only provides credentials for Azure Container Registries when fully configured", func(t *testing.T) {
+		for _, tc := range []struct {
+			registryHost string
+			env          []string
+		}{
+			{"index.docker.io", []string{"AZURE_FEDERATED_TOKEN_FILE=/some/token", "AZURE_TENANT_ID=some-tenant", "AZURE_CLIENT_ID=some-client"}},
+			{"some.azurecr.io", []string{"AZURE_FEDERATED_TOKEN_FILE=/some/token", "AZURE_TENANT_ID=some-tenant"}},
+		} {
+			keychain := auth.NewACRKeychain(func() []string { return tc.env })
+			resource, err := regname.NewRegistry(tc.registryHost)
+			require.NoError(t, err)
+			authenticator, err := keychain.Resolve(resource)
+			require.NoError(t, err)
+			assert.Equal(t, regauthn.Anonymous, authenticator, tc.registryHost)
+		}
+	})
+}
+
+func TestCustomRegistryKeychainWithoutDockerCredentialHelpers(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", configDir)
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{
+  "credsStore": "imgpkg-test-does-not-exist",
+  "credHelpers": {"helper.io": "imgpkg-test-does-not-exist"},
+  "auths": {"helper.io": {"auth": "c29tZS11c2VyOnNvbWUtcGFzc3dvcmQ="}}
+}`), 0600))
+
+	keychain := auth.CustomRegistryKeychain{Opts: auth.KeychainOpts{DisableDockerCredentialHelpers: true}}
+
+	t.Run("uses the credentials stored in the docker config file without executing the helpers", func(t *testing.T) {
+		resource, err := regname.NewRegistry("helper.io")
+		require.NoError(t, err)
+		authenticator, err := keychain.Resolve(resource)
+		require.NoError(t, err)
+		authConfig, err := authenticator.Authorization()
+		require.NoError(t, err)
+		assert.Equal(t, "some-user", authConfig.Username)
+		assert.Equal(t, "some-password", authConfig.Password)
+	})
+
+	t.Run("when the registry has no credentials in the file, it is accessed anonymously", func(t *testing.T) {
+		resource, err := regname.NewRegistry("other.io")
+		require.NoError(t, err)
+		authenticator, err := keychain.Resolve(resource)
+		require.NoError(t, err)
+		assert.Equal(t, regauthn.Anonymous, authenticator)
+	})
+}
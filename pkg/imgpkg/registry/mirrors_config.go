@@ -0,0 +1,97 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"fmt"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/filelock"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	ConfigKind       = "Config"
+	ConfigAPIVersion = "imgpkg.carvel.dev/v1alpha1"
+)
+
+// Config Configuration of imgpkg, usually in ~/.imgpkg/config.yml
+type Config struct {
+	APIVersion string         `json:"apiVersion"` // This generated yaml, but due to lib we need to use `json`
+	Kind       string         `json:"kind"`       // This generated yaml, but due to lib we need to use `json`
+	Mirrors    []MirrorConfig `json:"mirrors"`    // This generated yaml, but due to lib we need to use `json`
+}
+
+// MirrorConfig Pull-through caches of a registry, similar to the mirrors of containerd
+type MirrorConfig struct {
+	// Registry Registry of the images that are mirrored, e.g. index.docker.io
+	Registry string `json:"registry"`
+	// Endpoints Registries serving the images of Registry, they are tried in order before Registry, that is only
+	// used when every endpoint fails
+	Endpoints []string `json:"endpoints"`
+}
+
+// NewConfigFromPath Reads the Config in path
+func NewConfigFromPath(path string) (Config, error) {
+	bs, err := filelock.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("Reading path %s: %s", path, err)
+	}
+
+	return NewConfigFromBytes(bs)
+}
+
+// NewConfigFromBytes Parses the Config
+func NewConfigFromBytes(data []byte) (Config, error) {
+	var config Config
+
+	err := yaml.UnmarshalStrict(data, &config)
+	if err != nil {
+		return config, fmt.Errorf("Unmarshaling config: %s", err)
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return config, fmt.Errorf("Validating config: %s", err)
+	}
+	return config, nil
+}
+
+// Validate Checks the version and kind of the Config and that every mirror has endpoints
+func (c Config) Validate() error {
+	if c.APIVersion != ConfigAPIVersion {
+		return fmt.Errorf("Validating apiVersion: Unknown version (known: %s)", ConfigAPIVersion)
+	}
+	if c.Kind != ConfigKind {
+		return fmt.Errorf("Validating kind: Unknown kind (known: %s)", ConfigKind)
+	}
+	for _, mirror := range c.Mirrors {
+		if mirror.Registry == "" {
+			return fmt.Errorf("Expected registry of mirror to be provided")
+		}
+		if len(mirror.Endpoints) == 0 {
+			return fmt.Errorf("Expected at least one endpoint for mirror of '%s'", mirror.Registry)
+		}
+	}
+	return nil
+}
+
+// PullThroughMirrors Returns the endpoints of the mirrors of each registry, in the order they are tried
+func (c Config) PullThroughMirrors() (Mirrors, error) {
+	mirrors := Mirrors{}
+	for _, mirror := range c.Mirrors {
+		origin, err := regname.NewRegistry(mirror.Registry)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing registry of mirror '%s': %s", mirror.Registry, err)
+		}
+		for _, endpoint := range mirror.Endpoints {
+			endpointRegistry, err := regname.NewRegistry(endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("Parsing endpoint '%s' of mirror '%s': %s", endpoint, mirror.Registry, err)
+			}
+			mirrors[origin.RegistryStr()] = append(mirrors[origin.RegistryStr()], endpointRegistry.RegistryStr())
+		}
+	}
+	return mirrors, nil
+}
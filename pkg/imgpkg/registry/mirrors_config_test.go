@@ -0,0 +1,57 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+func TestNewConfigFromBytes(t *testing.T) {
+	t.Run("returns the endpoints of the mirrors of each registry in order", func(t *testing.T) {
+		config, err := registry.NewConfigFromBytes([]byte(`---
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: Config
+mirrors:
+- registry: docker.io
+  endpoints:
+  - pull-through.internal:5000
+  - mirror.gcr.io
+- registry: quay.io
+  endpoints:
+  - quay-cache.internal
+`))
+		require.NoError(t, err)
+
+		mirrors, err := config.PullThroughMirrors()
+		require.NoError(t, err)
+		assert.Equal(t, registry.Mirrors{
+			"index.docker.io": {"pull-through.internal:5000", "mirror.gcr.io"},
+			"quay.io":         {"quay-cache.internal"},
+		}, mirrors)
+	})
+
+	t.Run("invalid configs", func(t *testing.T) {
+		for name, config := range map[string]string{
+			"unknown kind":     "apiVersion: imgpkg.carvel.dev/v1alpha1\nkind: TrustConfig\n",
+			"unknown field":    "apiVersion: imgpkg.carvel.dev/v1alpha1\nkind: Config\nmirror: []\n",
+			"missing registry": "apiVersion: imgpkg.carvel.dev/v1alpha1\nkind: Config\nmirrors:\n- endpoints: [mirror.gcr.io]\n",
+			"no endpoints":     "apiVersion: imgpkg.carvel.dev/v1alpha1\nkind: Config\nmirrors:\n- registry: docker.io\n",
+		} {
+			_, err := registry.NewConfigFromBytes([]byte(config))
+			require.Error(t, err, name)
+		}
+	})
+
+	t.Run("fails when an endpoint is not a registry", func(t *testing.T) {
+		config, err := registry.NewConfigFromBytes([]byte("apiVersion: imgpkg.carvel.dev/v1alpha1\nkind: Config\nmirrors:\n- registry: docker.io\n  endpoints: [mirror/gcr]\n"))
+		require.NoError(t, err)
+		_, err = config.PullThroughMirrors()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Parsing endpoint 'mirror/gcr' of mirror 'docker.io'")
+	})
+}
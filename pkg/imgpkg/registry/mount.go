@@ -0,0 +1,100 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	regauthn "github.com/google/go-containerregistry/pkg/authn"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// MountBlob Asks the registry to make the blob, present in the repository of blob, available in the destination
+// repository without uploading its content.
+// Returns false when the registry does not support mounting the blob, in which case nothing was changed.
+// Blobs can only be mounted between repositories of the same registry
+func (r *SimpleRegistry) MountBlob(blob regname.Digest, dst regname.Repository) (bool, error) {
+	src, err := regname.NewRepository(blob.Context().Name(), r.refOpts...)
+	if err != nil {
+		return false, err
+	}
+	dst, err = regname.NewRepository(dst.Name(), r.refOpts...)
+	if err != nil {
+		return false, err
+	}
+	if src.RegistryStr() != dst.RegistryStr() {
+		return false, fmt.Errorf("Unable to mount blob %s from registry %s into registry %s", blob.DigestStr(), src.RegistryStr(), dst.RegistryStr())
+	}
+
+	rt, err := r.mountTransport(src, dst)
+	if err != nil {
+		return false, err
+	}
+
+	mountURL := url.URL{
+		Scheme:   dst.Scheme(),
+		Host:     dst.RegistryStr(),
+		Path:     fmt.Sprintf("/v2/%s/blobs/uploads/", dst.RepositoryStr()),
+		RawQuery: url.Values{"mount": {blob.DigestStr()}, "from": {src.RepositoryStr()}}.Encode(),
+	}
+	req, err := http.NewRequest(http.MethodPost, mountURL.String(), nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return false, fmt.Errorf("Mounting blob %s: %s", blob.DigestStr(), err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return true, nil
+
+	case http.StatusAccepted:
+		// The registry started an upload session instead of mounting the blob, cancel it since no data will be sent
+		location, err := resp.Location()
+		if err == nil {
+			cancelReq, err := http.NewRequest(http.MethodDelete, location.String(), nil)
+			if err == nil {
+				if cancelResp, err := rt.RoundTrip(cancelReq); err == nil {
+					cancelResp.Body.Close()
+				}
+			}
+		}
+		return false, nil
+
+	default:
+		return false, transport.CheckError(resp, http.StatusCreated, http.StatusAccepted)
+	}
+}
+
+// mountTransport Creates a RoundTripper that can pull from the source and push to the destination repository
+func (r *SimpleRegistry) mountTransport(src, dst regname.Repository) (http.RoundTripper, error) {
+	baseRoundTripper := r.roundTrippers.BaseRoundTripper()
+	if baseRoundTripper == nil {
+		baseRoundTripper = http.DefaultTransport
+	}
+
+	var auth regauthn.Authenticator = regauthn.Anonymous
+	if r.keychain != nil {
+		resolvedAuth, err := r.keychain.Resolve(dst)
+		if err != nil {
+			return nil, fmt.Errorf("Unable retrieve credentials for registry: %s", err)
+		}
+		auth = resolvedAuth
+	}
+
+	rt, err := transport.NewWithContext(context.Background(), dst.Registry, auth, baseRoundTripper,
+		[]string{dst.Scope(transport.PushScope), src.Scope(transport.PullScope)})
+	if err != nil {
+		return nil, fmt.Errorf("Error while preparing a transport to talk with the registry: %s", err)
+	}
+	return rt, nil
+}
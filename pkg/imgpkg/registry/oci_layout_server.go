@@ -0,0 +1,451 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const (
+	// ociRefNameAnnotation Annotation of the entries of index.json that holds their tag
+	ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+	// ociLayoutUploadsDir Folder, inside the layout, where the blobs being uploaded are kept until they are complete
+	ociLayoutUploadsDir = ".imgpkg-uploads"
+	// FileURLPrefix Prefix of the locations that refer to an OCI Image Layout folder instead of a repository
+	FileURLPrefix = "file://"
+)
+
+var invalidRepositoryChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// OCILayoutServer Serves the folder of an OCI Image Layout as a single repository of a registry, listening on the
+// loopback interface, so that the folder can be used as source or destination of a copy like any other repository.
+// Tags are stored in the org.opencontainers.image.ref.name annotation of the entries of index.json
+type OCILayoutServer struct {
+	path     string
+	repoName string
+	readOnly bool
+
+	layoutPath layout.Path
+	listener   net.Listener
+	server     *http.Server
+
+	indexLock sync.Mutex
+}
+
+// NewOCILayoutServer constructor for OCILayoutServer. When readOnly is false the folder is created when it does not
+// exist and images can be pushed to it
+func NewOCILayoutServer(path string, readOnly bool) *OCILayoutServer {
+	repoName := strings.Trim(invalidRepositoryChars.ReplaceAllString(strings.ToLower(filepath.Base(path)), "-"), "-._")
+	if repoName == "" {
+		repoName = "oci-layout"
+	}
+	return &OCILayoutServer{path: path, repoName: repoName, readOnly: readOnly}
+}
+
+// Start Opens the OCI Image Layout and starts serving it
+func (s *OCILayoutServer) Start() error {
+	var err error
+	s.layoutPath, err = layout.FromPath(s.path)
+	if err != nil {
+		if s.readOnly {
+			return fmt.Errorf("Opening OCI image layout '%s': %s", s.path, err)
+		}
+		s.layoutPath, err = layout.Write(s.path, empty.Index)
+		if err != nil {
+			return fmt.Errorf("Creating OCI image layout '%s': %s", s.path, err)
+		}
+	}
+
+	s.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("Serving OCI image layout '%s': %s", s.path, err)
+	}
+	s.server = &http.Server{Handler: s} //nolint:gosec
+	go s.server.Serve(s.listener)       //nolint:errcheck
+	return nil
+}
+
+// Repository Location of the repository that serves the OCI Image Layout
+func (s *OCILayoutServer) Repository() string {
+	return s.listener.Addr().String() + "/" + s.repoName
+}
+
+// Close Stops serving the OCI Image Layout and removes the uploads that did not complete
+func (s *OCILayoutServer) Close() error {
+	if s.server == nil {
+		return nil
+	}
+	err := s.server.Close()
+	os.RemoveAll(filepath.Join(s.path, ociLayoutUploadsDir))
+	return err
+}
+
+// ServeHTTP Implements the subset of the OCI Distribution API used to pull and push images
+func (s *OCILayoutServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/v2/" || r.URL.Path == "/v2" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	prefix := "/v2/" + s.repoName + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		writeRegistryError(w, http.StatusNotFound, "NAME_UNKNOWN", "Repository not found")
+		return
+	}
+	route := strings.TrimPrefix(r.URL.Path, prefix)
+
+	isWrite := r.Method != http.MethodGet && r.Method != http.MethodHead
+	if isWrite && s.readOnly {
+		writeRegistryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "OCI image layout is read only")
+		return
+	}
+
+	switch {
+	case route == "tags/list" && r.Method == http.MethodGet:
+		s.listTags(w)
+	case strings.HasPrefix(route, "manifests/") && !isWrite:
+		s.getManifest(w, r, strings.TrimPrefix(route, "manifests/"))
+	case strings.HasPrefix(route, "manifests/") && r.Method == http.MethodPut:
+		s.putManifest(w, r, strings.TrimPrefix(route, "manifests/"))
+	case (route == "blobs/uploads/" || route == "blobs/uploads") && r.Method == http.MethodPost:
+		s.startUpload(w, r)
+	case strings.HasPrefix(route, "blobs/uploads/") && (r.Method == http.MethodPatch || r.Method == http.MethodPut):
+		s.continueUpload(w, r, strings.TrimPrefix(route, "blobs/uploads/"))
+	case strings.HasPrefix(route, "blobs/") && !isWrite:
+		s.getBlob(w, r, strings.TrimPrefix(route, "blobs/"))
+	default:
+		writeRegistryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "Operation not supported")
+	}
+}
+
+func (s *OCILayoutServer) listTags(w http.ResponseWriter) {
+	index, err := s.indexManifest()
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+
+	tags := []string{}
+	for _, desc := range index.Manifests {
+		if tag, found := desc.Annotations[ociRefNameAnnotation]; found {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	json.NewEncoder(w).Encode(map[string]interface{}{"name": s.repoName, "tags": tags}) //nolint:errcheck
+}
+
+func (s *OCILayoutServer) getManifest(w http.ResponseWriter, r *http.Request, reference string) {
+	digest, mediaType, err := s.resolveManifest(reference)
+	if err != nil {
+		writeRegistryError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", err.Error())
+		return
+	}
+
+	manifest, err := os.ReadFile(s.blobPath(digest))
+	if err != nil {
+		writeRegistryError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", err.Error())
+		return
+	}
+	if mediaType == "" {
+		mediaType = manifestMediaType(manifest)
+	}
+
+	w.Header().Set("Content-Type", string(mediaType))
+	w.Header().Set("Docker-Content-Digest", digest.String())
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(manifest)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		w.Write(manifest) //nolint:errcheck
+	}
+}
+
+// resolveManifest returns the digest of the manifest referenced by tag or digest, and its media type when known
+func (s *OCILayoutServer) resolveManifest(reference string) (regv1.Hash, types.MediaType, error) {
+	if digest, err := regv1.NewHash(reference); err == nil {
+		if _, err := os.Stat(s.blobPath(digest)); err != nil {
+			return regv1.Hash{}, "", fmt.Errorf("Manifest '%s' not found", reference)
+		}
+		return digest, "", nil
+	}
+
+	index, err := s.indexManifest()
+	if err != nil {
+		return regv1.Hash{}, "", err
+	}
+	for _, desc := range index.Manifests {
+		if desc.Annotations[ociRefNameAnnotation] == reference {
+			return desc.Digest, desc.MediaType, nil
+		}
+	}
+	return regv1.Hash{}, "", fmt.Errorf("Tag '%s' not found", reference)
+}
+
+func (s *OCILayoutServer) putManifest(w http.ResponseWriter, r *http.Request, reference string) {
+	manifest, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeRegistryError(w, http.StatusBadRequest, "MANIFEST_INVALID", err.Error())
+		return
+	}
+	digest, size, err := regv1.SHA256(bytes.NewReader(manifest))
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+	if refDigest, err := regv1.NewHash(reference); err == nil && refDigest != digest {
+		writeRegistryError(w, http.StatusBadRequest, "DIGEST_INVALID", "Manifest does not match the digest")
+		return
+	}
+
+	err = s.layoutPath.WriteBlob(digest, io.NopCloser(bytes.NewReader(manifest)))
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+
+	// Manifests pushed by digest are stored as blobs, only tags are listed in index.json
+	if _, err := regv1.NewHash(reference); err != nil {
+		mediaType := types.MediaType(r.Header.Get("Content-Type"))
+		if mediaType == "" {
+			mediaType = manifestMediaType(manifest)
+		}
+		desc := regv1.Descriptor{
+			MediaType:   mediaType,
+			Digest:      digest,
+			Size:        size,
+			Annotations: map[string]string{ociRefNameAnnotation: reference},
+		}
+
+		s.indexLock.Lock()
+		err = s.layoutPath.RemoveDescriptors(func(existing regv1.Descriptor) bool {
+			return existing.Annotations[ociRefNameAnnotation] == reference
+		})
+		if err == nil {
+			err = s.layoutPath.AppendDescriptor(desc)
+		}
+		s.indexLock.Unlock()
+		if err != nil {
+			writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+			return
+		}
+	}
+
+	w.Header().Set("Docker-Content-Digest", digest.String())
+	w.Header().Set("Location", fmt.Sprintf("/v2/%s/manifests/%s", s.repoName, digest))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *OCILayoutServer) startUpload(w http.ResponseWriter, r *http.Request) {
+	uploadsDir := filepath.Join(s.path, ociLayoutUploadsDir)
+	err := os.MkdirAll(uploadsDir, 0700)
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+
+	idBytes := make([]byte, 16)
+	_, err = rand.Read(idBytes)
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+	id := hex.EncodeToString(idBytes)
+
+	err = os.WriteFile(filepath.Join(uploadsDir, id), nil, 0600)
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+
+	// Monolithic uploads send the blob with the digest in the first request
+	if r.URL.Query().Get("digest") != "" {
+		s.continueUpload(w, r, id)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%s", s.repoName, id))
+	w.Header().Set("Docker-Upload-UUID", id)
+	w.Header().Set("Range", "0-0")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *OCILayoutServer) continueUpload(w http.ResponseWriter, r *http.Request, id string) {
+	if strings.ContainsAny(id, `/\.`) {
+		writeRegistryError(w, http.StatusNotFound, "BLOB_UPLOAD_UNKNOWN", "Upload not found")
+		return
+	}
+	uploadPath := filepath.Join(s.path, ociLayoutUploadsDir, id)
+
+	file, err := os.OpenFile(uploadPath, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		writeRegistryError(w, http.StatusNotFound, "BLOB_UPLOAD_UNKNOWN", "Upload not found")
+		return
+	}
+	_, err = io.Copy(file, r.Body)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+
+	expectedDigest := r.URL.Query().Get("digest")
+	if expectedDigest == "" {
+		info, err := os.Stat(uploadPath)
+		if err != nil {
+			writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%s", s.repoName, id))
+		w.Header().Set("Docker-Upload-UUID", id)
+		w.Header().Set("Range", fmt.Sprintf("0-%d", info.Size()-1))
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	digest, err := s.completeUpload(uploadPath, expectedDigest)
+	if err != nil {
+		writeRegistryError(w, http.StatusBadRequest, "DIGEST_INVALID", err.Error())
+		return
+	}
+	w.Header().Set("Docker-Content-Digest", digest.String())
+	w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/%s", s.repoName, digest))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// completeUpload checks the digest of the uploaded blob and moves it to the blobs of the layout
+func (s *OCILayoutServer) completeUpload(uploadPath, expectedDigest string) (regv1.Hash, error) {
+	defer os.Remove(uploadPath)
+
+	digest, err := regv1.NewHash(expectedDigest)
+	if err != nil {
+		return regv1.Hash{}, err
+	}
+
+	file, err := os.Open(uploadPath)
+	if err != nil {
+		return regv1.Hash{}, err
+	}
+	actualDigest, _, err := regv1.SHA256(file)
+	file.Close()
+	if err != nil {
+		return regv1.Hash{}, err
+	}
+	if actualDigest != digest {
+		return regv1.Hash{}, fmt.Errorf("Expected blob to have digest '%s', but it has digest '%s'", digest, actualDigest)
+	}
+
+	blobPath := s.blobPath(digest)
+	err = os.MkdirAll(filepath.Dir(blobPath), 0700)
+	if err != nil {
+		return regv1.Hash{}, err
+	}
+	return digest, os.Rename(uploadPath, blobPath)
+}
+
+func (s *OCILayoutServer) getBlob(w http.ResponseWriter, r *http.Request, reference string) {
+	digest, err := regv1.NewHash(reference)
+	if err != nil {
+		writeRegistryError(w, http.StatusBadRequest, "DIGEST_INVALID", err.Error())
+		return
+	}
+
+	file, err := os.Open(s.blobPath(digest))
+	if err != nil {
+		writeRegistryError(w, http.StatusNotFound, "BLOB_UNKNOWN", "Blob not found")
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Docker-Content-Digest", digest.String())
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
+func (s *OCILayoutServer) indexManifest() (*regv1.IndexManifest, error) {
+	s.indexLock.Lock()
+	defer s.indexLock.Unlock()
+
+	index, err := s.layoutPath.ImageIndex()
+	if err != nil {
+		return nil, err
+	}
+	return index.IndexManifest()
+}
+
+func (s *OCILayoutServer) blobPath(digest regv1.Hash) string {
+	return filepath.Join(s.path, "blobs", digest.Algorithm, digest.Hex)
+}
+
+// manifestMediaType returns the media type recorded in the manifest, or guesses it when it is not present
+func manifestMediaType(manifest []byte) types.MediaType {
+	var fields struct {
+		MediaType types.MediaType   `json:"mediaType"`
+		Manifests []json.RawMessage `json:"manifests"`
+	}
+	err := json.Unmarshal(manifest, &fields)
+	switch {
+	case err == nil && fields.MediaType != "":
+		return fields.MediaType
+	case err == nil && fields.Manifests != nil:
+		return types.OCIImageIndex
+	default:
+		return types.OCIManifestSchema1
+	}
+}
+
+func writeRegistryError(w http.ResponseWriter, statusCode int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{ //nolint:errcheck
+		"errors": []map[string]string{{"code": code, "message": message}},
+	})
+}
+
+// ParseFileURL Splits a location with the file:// prefix in the path of the OCI Image Layout and the tag or digest
+// that follows it, e.g. file:///mnt/usb/app:1.0 or file:///mnt/usb/app@sha256:... Returns false when the location
+// does not have the file:// prefix
+func ParseFileURL(location string) (string, string, bool, error) {
+	if !strings.HasPrefix(location, FileURLPrefix) {
+		return "", "", false, nil
+	}
+
+	path := strings.TrimPrefix(location, FileURLPrefix)
+	identifier := ""
+	if at := strings.LastIndex(path, "@"); at != -1 {
+		path, identifier = path[:at], path[at:]
+	} else if colon := strings.LastIndex(path, ":"); colon > strings.LastIndex(path, "/") {
+		path, identifier = path[:colon], path[colon:]
+	}
+	if !filepath.IsAbs(path) {
+		return "", "", false, errors.New("Expected an absolute path after file://, e.g. file:///mnt/usb/app")
+	}
+	return filepath.Clean(path), identifier, true, nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+func TestOCILayoutServer(t *testing.T) {
+	layoutPath := filepath.Join(t.TempDir(), "App Layout")
+
+	t.Run("writes the pushed images to the OCI image layout, tracking their tags in index.json", func(t *testing.T) {
+		subject := registry.NewOCILayoutServer(layoutPath, false)
+		require.NoError(t, subject.Start())
+		defer subject.Close()
+		assert.Contains(t, subject.Repository(), "/app-layout")
+
+		img, err := random.Image(1024, 2)
+		require.NoError(t, err)
+		tag, err := name.NewTag(subject.Repository() + ":1.0")
+		require.NoError(t, err)
+		require.NoError(t, remote.Write(tag, img))
+
+		untagged, err := random.Image(1024, 1)
+		require.NoError(t, err)
+		untaggedDigest, err := untagged.Digest()
+		require.NoError(t, err)
+		digestRef, err := name.NewDigest(subject.Repository() + "@" + untaggedDigest.String())
+		require.NoError(t, err)
+		require.NoError(t, remote.Write(digestRef, untagged))
+
+		tags, err := remote.List(tag.Repository)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1.0"}, tags)
+
+		path, err := layout.FromPath(layoutPath)
+		require.NoError(t, err)
+		index, err := path.ImageIndex()
+		require.NoError(t, err)
+		indexManifest, err := index.IndexManifest()
+		require.NoError(t, err)
+		require.Len(t, indexManifest.Manifests, 1)
+		assert.Equal(t, "1.0", indexManifest.Manifests[0].Annotations["org.opencontainers.image.ref.name"])
+
+		expectedDigest, err := img.Digest()
+		require.NoError(t, err)
+		assert.Equal(t, expectedDigest, indexManifest.Manifests[0].Digest)
+		layoutImg, err := path.Image(expectedDigest)
+		require.NoError(t, err)
+		layers, err := layoutImg.Layers()
+		require.NoError(t, err)
+		assert.Len(t, layers, 2)
+	})
+
+	t.Run("serves the images in the OCI image layout read only", func(t *testing.T) {
+		subject := registry.NewOCILayoutServer(layoutPath, true)
+		require.NoError(t, subject.Start())
+		defer subject.Close()
+
+		tag, err := name.NewTag(subject.Repository() + ":1.0")
+		require.NoError(t, err)
+		img, err := remote.Image(tag)
+		require.NoError(t, err)
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		assert.Len(t, layers, 2)
+		for _, layer := range layers {
+			rc, err := layer.Compressed()
+			require.NoError(t, err)
+			rc.Close()
+		}
+
+		other, err := random.Image(10, 1)
+		require.NoError(t, err)
+		assert.Error(t, remote.Write(tag, other))
+	})
+
+	t.Run("fails to serve a folder that does not exist when read only", func(t *testing.T) {
+		subject := registry.NewOCILayoutServer(filepath.Join(t.TempDir(), "missing"), true)
+		err := subject.Start()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Opening OCI image layout")
+	})
+}
+
+func TestParseFileURL(t *testing.T) {
+	for _, tc := range []struct {
+		location, path, identifier string
+		isFileURL                  bool
+	}{
+		{"registry.io/app:1.0", "", "", false},
+		{"file:///mnt/usb/app", "/mnt/usb/app", "", true},
+		{"file:///mnt/usb/app:1.0", "/mnt/usb/app", ":1.0", true},
+		{"file:///mnt/usb.1:2/app@sha256:abc", "/mnt/usb.1:2/app", "@sha256:abc", true},
+	} {
+		path, identifier, isFileURL, err := registry.ParseFileURL(tc.location)
+		require.NoError(t, err)
+		assert.Equal(t, tc.path, path, tc.location)
+		assert.Equal(t, tc.identifier, identifier, tc.location)
+		assert.Equal(t, tc.isFileURL, isFileURL, tc.location)
+	}
+
+	_, _, _, err := registry.ParseFileURL("file://relative/app")
+	assert.Error(t, err)
+}
@@ -0,0 +1,165 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	regauthn "github.com/google/go-containerregistry/pkg/authn"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// RepositoryPreflight Result of retrieving an image of the repository without credentials
+type RepositoryPreflight struct {
+	Repository string `json:"repository"`
+	Images     int    `json:"images"`
+	// Anonymous is true when the image could be retrieved without credentials
+	Anonymous bool `json:"anonymous"`
+	// RequiresCredentials is true when the registry denied the anonymous access
+	RequiresCredentials bool   `json:"requiresCredentials"`
+	Error               string `json:"error,omitempty"`
+}
+
+// RegistryPreflight Repositories of a registry and whether they can be accessed without credentials
+type RegistryPreflight struct {
+	Registry string `json:"registry"`
+	// Credentials describes the credentials found for the registry, empty when none are configured
+	Credentials  string                `json:"credentials,omitempty"`
+	Repositories []RepositoryPreflight `json:"repositories"`
+}
+
+// RequiresCredentials returns true when any repository of the registry denied the anonymous access
+func (r RegistryPreflight) RequiresCredentials() bool {
+	for _, repo := range r.Repositories {
+		if repo.RequiresCredentials {
+			return true
+		}
+	}
+	return false
+}
+
+// PreflightAuth Groups the images by registry and repository and retrieves the manifest of one image of each
+// repository without credentials, to find out which registries require credentials before they are used. The
+// credentials that would be used for each registry are resolved but not sent
+func PreflightAuth(opts Opts, imageRefs []string) ([]RegistryPreflight, error) {
+	var refOpts []regname.Option
+	if opts.Insecure {
+		refOpts = append(refOpts, regname.Insecure)
+	}
+
+	probes := map[string]regname.Reference{}
+	imagesPerRepo := map[string]int{}
+	var repos []regname.Repository
+	for _, imageRef := range imageRefs {
+		ref, err := regname.ParseReference(imageRef, refOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing image '%s': %s", imageRef, err)
+		}
+		repoName := ref.Context().Name()
+		if _, found := probes[repoName]; !found {
+			probes[repoName] = ref
+			repos = append(repos, ref.Context())
+		}
+		imagesPerRepo[repoName]++
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name() < repos[j].Name() })
+
+	keychain, err := Keychain(opts.keychainOpts(), opts.EnvironFunc)
+	if err != nil {
+		return nil, fmt.Errorf("Creating registry keychain: %s", err)
+	}
+	simpleRegistry, err := NewSimpleRegistry(opts)
+	if err != nil {
+		return nil, err
+	}
+	baseRoundTripper := simpleRegistry.roundTrippers.BaseRoundTripper()
+	if baseRoundTripper == nil {
+		baseRoundTripper = http.DefaultTransport
+	}
+
+	var result []RegistryPreflight
+	for _, repo := range repos {
+		if len(result) == 0 || result[len(result)-1].Registry != repo.RegistryStr() {
+			result = append(result, RegistryPreflight{
+				Registry:    repo.RegistryStr(),
+				Credentials: resolveCredentials(keychain, repo.Registry),
+			})
+		}
+
+		repoResult := probeAnonymousPull(probes[repo.Name()], baseRoundTripper)
+		repoResult.Repository = repo.Name()
+		repoResult.Images = imagesPerRepo[repo.Name()]
+		result[len(result)-1].Repositories = append(result[len(result)-1].Repositories, repoResult)
+	}
+	return result, nil
+}
+
+// resolveCredentials Describes the credentials the keychain provides for the registry, empty when there are none
+func resolveCredentials(keychain regauthn.Keychain, reg regname.Registry) string {
+	auth, err := keychain.Resolve(reg)
+	if err != nil {
+		return fmt.Sprintf("error (%s)", err)
+	}
+	if auth == regauthn.Anonymous {
+		return ""
+	}
+	identity, err := describeAuth(auth)
+	if err != nil {
+		return fmt.Sprintf("error (%s)", err)
+	}
+	return identity
+}
+
+// probeAnonymousPull Requests the manifest of the image without credentials
+func probeAnonymousPull(ref regname.Reference, baseRoundTripper http.RoundTripper) RepositoryPreflight {
+	var result RepositoryPreflight
+	err := headManifest(ref, baseRoundTripper)
+	var transportErr *transport.Error
+	switch {
+	case err == nil:
+		result.Anonymous = true
+	case errors.As(err, &transportErr) && (transportErr.StatusCode == http.StatusUnauthorized || transportErr.StatusCode == http.StatusForbidden):
+		result.RequiresCredentials = true
+	default:
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func headManifest(ref regname.Reference, baseRoundTripper http.RoundTripper) error {
+	rt, err := transport.NewWithContext(context.Background(), ref.Context().Registry, regauthn.Anonymous, baseRoundTripper, []string{ref.Context().Scope(transport.PullScope)})
+	if err != nil {
+		return err
+	}
+
+	manifestURL := url.URL{
+		Scheme: ref.Context().Registry.Scheme(),
+		Host:   ref.Context().RegistryStr(),
+		Path:   fmt.Sprintf("/v2/%s/manifests/%s", ref.Context().RepositoryStr(), ref.Identifier()),
+	}
+	req, err := http.NewRequest(http.MethodHead, manifestURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", strings.Join([]string{
+		string(types.OCIManifestSchema1), string(types.DockerManifestSchema2),
+		string(types.OCIImageIndex), string(types.DockerManifestList),
+	}, ","))
+
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return transport.CheckError(resp, http.StatusOK)
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+func TestPreflightAuth(t *testing.T) {
+	var authorizedRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			authorizedRequests++
+		}
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodHead && strings.HasPrefix(r.URL.Path, "/v2/public/"):
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodHead && strings.HasPrefix(r.URL.Path, "/v2/private/"):
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	digest := "@sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	images := []string{host + "/public/app" + digest, host + "/private/app" + digest, host + "/private/app:v1", host + "/broken/app" + digest}
+
+	t.Run("reports the repositories that require credentials without sending the credentials", func(t *testing.T) {
+		authorizedRequests = 0
+		opts := registry.Opts{
+			Insecure: true,
+			EnvironFunc: func() []string {
+				return []string{"IMGPKG_REGISTRY_HOSTNAME=" + host, "IMGPKG_REGISTRY_USERNAME=reader", "IMGPKG_REGISTRY_PASSWORD=secret"}
+			},
+		}
+
+		result, err := registry.PreflightAuth(opts, images)
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		assert.Equal(t, host, result[0].Registry)
+		assert.Equal(t, "username 'reader'", result[0].Credentials)
+		assert.True(t, result[0].RequiresCredentials())
+		assert.Equal(t, 0, authorizedRequests)
+
+		require.Len(t, result[0].Repositories, 3)
+		assert.Equal(t, host+"/broken/app", result[0].Repositories[0].Repository)
+		assert.NotEmpty(t, result[0].Repositories[0].Error)
+		assert.False(t, result[0].Repositories[0].RequiresCredentials)
+		assert.Equal(t, registry.RepositoryPreflight{Repository: host + "/private/app", Images: 2, RequiresCredentials: true}, result[0].Repositories[1])
+		assert.Equal(t, registry.RepositoryPreflight{Repository: host + "/public/app", Images: 1, Anonymous: true}, result[0].Repositories[2])
+	})
+
+	t.Run("reports no credentials when none are configured", func(t *testing.T) {
+		opts := registry.Opts{Insecure: true, EnvironFunc: func() []string { return nil }}
+		result, err := registry.PreflightAuth(opts, images[:1])
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		assert.Empty(t, result[0].Credentials)
+		assert.False(t, result[0].RequiresCredentials())
+	})
+}
@@ -0,0 +1,46 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+// registryAPIWrite Paths of the registry API that change the content of a repository when called with a method other
+// than GET or HEAD, the first group is the name of the repository. Token endpoints, that can be called with POST, are
+// not part of it
+var registryAPIWrite = regexp.MustCompile(`^/v2/(.+)/(blobs|manifests)/`)
+
+// NewReadOnlyRoundTripper Creates a RoundTripper that refuses every request that changes the content of a repository,
+// except for the writableRepos, in the format registry/repository, e.g. index.docker.io/library/ubuntu
+func NewReadOnlyRoundTripper(inner http.RoundTripper, writableRepos []string) *ReadOnlyRoundTripper {
+	repos := map[string]struct{}{}
+	for _, repo := range writableRepos {
+		repos[repo] = struct{}{}
+	}
+	return &ReadOnlyRoundTripper{inner: inner, writableRepos: repos}
+}
+
+// ReadOnlyRoundTripper Guarantees that no upload, manifest write or delete reaches the repositories that are only
+// read, even when they are in the same registry as a writable repository. It wraps the network transport directly so
+// that no other RoundTripper, including the retries, can bypass it
+type ReadOnlyRoundTripper struct {
+	inner         http.RoundTripper
+	writableRepos map[string]struct{}
+}
+
+// RoundTrip Executes the request when it only reads from the registry or when the repository is writable
+func (r *ReadOnlyRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	match := registryAPIWrite.FindStringSubmatch(req.URL.Path)
+	if req.Method == http.MethodGet || req.Method == http.MethodHead || match == nil {
+		return r.inner.RoundTrip(req)
+	}
+	repo := req.URL.Host + "/" + match[1]
+	if _, found := r.writableRepos[repo]; found {
+		return r.inner.RoundTrip(req)
+	}
+	return nil, fmt.Errorf("Refusing %s request to %s: Repository '%s' is read-only", req.Method, req.URL.Path, repo)
+}
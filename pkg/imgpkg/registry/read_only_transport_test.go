@@ -0,0 +1,76 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+func TestReadOnlyRoundTripper(t *testing.T) {
+	var received []string
+	readOnlyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Method+" "+r.URL.Path)
+	}))
+	defer readOnlyServer.Close()
+	writableServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Method+" "+r.URL.Path)
+	}))
+	defer writableServer.Close()
+
+	writableURL, err := url.Parse(writableServer.URL)
+	require.NoError(t, err)
+	subject := registry.NewReadOnlyRoundTripper(http.DefaultTransport, []string{writableURL.Host + "/dst/repo"})
+
+	send := func(method string, url string) error {
+		req, err := http.NewRequest(method, url, nil)
+		require.NoError(t, err)
+		resp, err := subject.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	t.Run("allows reads and token requests to every registry", func(t *testing.T) {
+		received = nil
+		require.NoError(t, send(http.MethodGet, readOnlyServer.URL+"/v2/some/repo/manifests/latest"))
+		require.NoError(t, send(http.MethodHead, readOnlyServer.URL+"/v2/some/repo/blobs/sha256:abc"))
+		require.NoError(t, send(http.MethodPost, readOnlyServer.URL+"/v2/token"))
+		assert.Len(t, received, 3)
+	})
+
+	t.Run("refuses changes to the read-only registries", func(t *testing.T) {
+		received = nil
+		for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			err := send(method, readOnlyServer.URL+"/v2/some/repo/blobs/uploads/")
+			require.ErrorContains(t, err, "Refusing "+method+" request to /v2/some/repo/blobs/uploads/")
+		}
+		require.ErrorContains(t, send(http.MethodPut, readOnlyServer.URL+"/v2/some/repo/manifests/latest"), "is read-only")
+		assert.Empty(t, received)
+	})
+
+	t.Run("allows changes to the writable repositories", func(t *testing.T) {
+		received = nil
+		require.NoError(t, send(http.MethodPost, writableServer.URL+"/v2/dst/repo/blobs/uploads/?mount=sha256:abc&from=some/repo"))
+		require.NoError(t, send(http.MethodPut, writableServer.URL+"/v2/dst/repo/manifests/latest"))
+		assert.Equal(t, []string{"POST /v2/dst/repo/blobs/uploads/", "PUT /v2/dst/repo/manifests/latest"}, received)
+	})
+
+	t.Run("refuses changes to the other repositories of the registry of the writable repositories", func(t *testing.T) {
+		received = nil
+		for _, path := range []string{"/v2/some/repo/blobs/uploads/", "/v2/dst/blobs/uploads/", "/v2/dst/repo/nested/blobs/uploads/"} {
+			err := send(http.MethodPost, writableServer.URL+path)
+			require.ErrorContains(t, err, "is read-only", path)
+		}
+		require.ErrorContains(t, send(http.MethodDelete, writableServer.URL+"/v2/some/repo/manifests/latest"), "Repository '"+writableURL.Host+"/some/repo' is read-only")
+		assert.Empty(t, received)
+	})
+}
@@ -4,12 +4,15 @@
 package registry
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"regexp"
+	"sort"
 	"sync"
 	"time"
 
@@ -19,6 +22,7 @@ import (
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
 	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/tracing"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry/auth"
 )
@@ -27,6 +31,8 @@ type Opts struct {
 	CACertPaths []string
 	VerifyCerts bool
 	Insecure    bool
+	// CertPoolProvider when provided is called on every new connection to retrieve the CA certificates to trust
+	CertPoolProvider CertPoolProvider
 
 	IncludeNonDistributableLayers bool
 
@@ -39,32 +45,93 @@ type Opts struct {
 
 	ResponseHeaderTimeout time.Duration
 	RetryCount            int
+	// RetryBackoff time waited before retrying a failed request, doubled on each retry. When 0 DefaultRetryBackoff is used
+	RetryBackoff time.Duration
+
+	// EnableHTTP2 allows the negotiation of HTTP/2 with the registry, by default only HTTP/1.1 is used
+	EnableHTTP2 bool
+	// MaxIdleConnsPerHost maximum number of idle connections kept open to each registry, when 0 the Go default is used
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout time an idle connection is kept open before being closed, when 0 the Go default is used
+	IdleConnTimeout time.Duration
+	// KeepAlive interval between TCP keep-alive probes, when 0 the Go default is used and when negative probes are disabled
+	KeepAlive time.Duration
+	// UploadChunkSize size in bytes of the chunks used to upload layers. When 0 the size is adjusted based on the
+	// responses of the registry and when negative layers are always uploaded in a single request
+	UploadChunkSize int64
+	// MaxRequestsPerHost maximum number of requests sent at the same time to each registry, when 0 there is no limit
+	MaxRequestsPerHost int
+	// MaxBandwidth maximum number of bytes per second transferred from and to all the registries, when 0 there is no limit
+	MaxBandwidth int64
+	// DownloadProgress when provided receives the bytes downloaded for each blob
+	DownloadProgress TransferProgressListener
+	// LayerConcurrency number of layers uploaded at the same time, also between layers of the same image.
+	// When 0 the concurrency used to write the images is used
+	LayerConcurrency int
+
+	// UserAgent when provided replaces the default User-Agent sent on every registry request
+	UserAgent string
+	// Headers static HTTP headers added to every registry request
+	Headers map[string]string
+	// CacheDir when provided manifests are cached in this folder
+	CacheDir string
+	// ReadOnly when true requests that change the content of a repository are refused, except for the WritableRepositories
+	ReadOnly bool
+	// WritableRepositories repositories, in the format registry/repository, that can still be changed when ReadOnly is set
+	WritableRepositories []string
 
 	EnvironFunc     func() []string
 	ActiveKeychains []auth.IAASKeychain
+	// DisableDockerCredentialHelpers when true the docker-credential-* helpers configured in the docker config file are
+	// not executed, only the credentials stored in the file are used
+	DisableDockerCredentialHelpers bool
 }
 
 // DeepCopy the options to a new struct
 func (o Opts) DeepCopy() Opts {
 	result := Opts{
-		VerifyCerts:                   o.VerifyCerts,
-		Insecure:                      o.Insecure,
-		IncludeNonDistributableLayers: o.IncludeNonDistributableLayers,
-		Username:                      o.Username,
-		Password:                      o.Password,
-		Token:                         o.Token,
-		Anon:                          o.Anon,
-		EnableIaasAuthProviders:       o.EnableIaasAuthProviders,
-		ResponseHeaderTimeout:         o.ResponseHeaderTimeout,
-		RetryCount:                    o.RetryCount,
-		EnvironFunc:                   o.EnvironFunc,
+		VerifyCerts:                    o.VerifyCerts,
+		Insecure:                       o.Insecure,
+		IncludeNonDistributableLayers:  o.IncludeNonDistributableLayers,
+		Username:                       o.Username,
+		Password:                       o.Password,
+		Token:                          o.Token,
+		Anon:                           o.Anon,
+		EnableIaasAuthProviders:        o.EnableIaasAuthProviders,
+		ResponseHeaderTimeout:          o.ResponseHeaderTimeout,
+		RetryCount:                     o.RetryCount,
+		RetryBackoff:                   o.RetryBackoff,
+		EnableHTTP2:                    o.EnableHTTP2,
+		MaxIdleConnsPerHost:            o.MaxIdleConnsPerHost,
+		IdleConnTimeout:                o.IdleConnTimeout,
+		KeepAlive:                      o.KeepAlive,
+		UploadChunkSize:                o.UploadChunkSize,
+		MaxRequestsPerHost:             o.MaxRequestsPerHost,
+		MaxBandwidth:                   o.MaxBandwidth,
+		DownloadProgress:               o.DownloadProgress,
+		LayerConcurrency:               o.LayerConcurrency,
+		UserAgent:                      o.UserAgent,
+		CacheDir:                       o.CacheDir,
+		ReadOnly:                       o.ReadOnly,
+		CertPoolProvider:               o.CertPoolProvider,
+		EnvironFunc:                    o.EnvironFunc,
+		DisableDockerCredentialHelpers: o.DisableDockerCredentialHelpers,
 	}
 	for _, path := range o.CACertPaths {
 		result.CACertPaths = append(result.CACertPaths, path)
 	}
+	for _, repo := range o.WritableRepositories {
+		result.WritableRepositories = append(result.WritableRepositories, repo)
+	}
 	for _, keychain := range o.ActiveKeychains {
 		result.ActiveKeychains = append(result.ActiveKeychains, keychain)
 	}
+	if o.Headers != nil {
+		result.Headers = map[string]string{}
+		for key, value := range o.Headers {
+			result.Headers[key] = value
+		}
+	}
 	return result
 }
 
@@ -112,6 +179,19 @@ type ImagesReaderWriter interface {
 	CloneWithLogger(logger util.ProgressLogger) Registry
 }
 
+// keychainOpts Options used to build the keychain
+func (o Opts) keychainOpts() auth.KeychainOpts {
+	return auth.KeychainOpts{
+		Username:                       o.Username,
+		Password:                       o.Password,
+		Token:                          o.Token,
+		Anon:                           o.Anon,
+		EnableIaasAuthProviders:        o.EnableIaasAuthProviders,
+		ActiveKeychains:                o.ActiveKeychains,
+		DisableDockerCredentialHelpers: o.DisableDockerCredentialHelpers,
+	}
+}
+
 var _ Registry = &SimpleRegistry{}
 
 // RoundTripperStorage Storage of RoundTripper that will be used to talk to the registry
@@ -129,6 +209,12 @@ type SimpleRegistry struct {
 	authn           map[string]regauthn.Authenticator
 	roundTrippers   RoundTripperStorage
 	transportAccess *sync.Mutex
+
+	includeNonDistributable bool
+	layerConcurrency        int
+	blobUploads             *blobUploads
+	tagListings             *tagListings
+	blobUploadsReport       *BlobUploadsReport
 }
 
 // NewBasicRegistry does not provide any special behavior and all the options as passed as is to the underlying library
@@ -136,37 +222,40 @@ func NewBasicRegistry(regOpts ...regremote.Option) (*SimpleRegistry, error) {
 	return &SimpleRegistry{
 		remoteOpts:      regOpts,
 		roundTrippers:   NewNoopRoundTripperStorage(),
+		authn:           map[string]regauthn.Authenticator{},
 		transportAccess: &sync.Mutex{},
+		blobUploads:     newBlobUploads(),
+		tagListings:     newTagListings(),
 	}, nil
 }
 
 // NewSimpleRegistry Builder for a Simple Registry
 func NewSimpleRegistry(opts Opts) (*SimpleRegistry, error) {
+	return NewSimpleRegistryWithContext(context.Background(), opts)
+}
+
+// NewSimpleRegistryWithContext Builder for a Simple Registry whose requests, including the transfers of layers in
+// progress, are cancelled when ctx is done
+func NewSimpleRegistryWithContext(ctx context.Context, opts Opts) (*SimpleRegistry, error) {
 	httpTran, err := newHTTPTransport(opts)
 	if err != nil {
 		return nil, fmt.Errorf("Creating registry HTTP transport: %s", err)
 	}
-	return NewSimpleRegistryWithTransport(opts, httpTran)
+	return newSimpleRegistry(ctx, opts, httpTran)
 }
 
 // NewSimpleRegistryWithTransport Creates a new Simple Registry using the provided transport
 func NewSimpleRegistryWithTransport(opts Opts, rTripper http.RoundTripper) (*SimpleRegistry, error) {
+	return newSimpleRegistry(context.Background(), opts, rTripper)
+}
+
+func newSimpleRegistry(ctx context.Context, opts Opts, rTripper http.RoundTripper) (*SimpleRegistry, error) {
 	var refOpts []regname.Option
 	if opts.Insecure {
 		refOpts = append(refOpts, regname.Insecure)
 	}
 
-	keychain, err := Keychain(
-		auth.KeychainOpts{
-			Username:                opts.Username,
-			Password:                opts.Password,
-			Token:                   opts.Token,
-			Anon:                    opts.Anon,
-			EnableIaasAuthProviders: opts.EnableIaasAuthProviders,
-			ActiveKeychains:         opts.ActiveKeychains,
-		},
-		opts.EnvironFunc,
-	)
+	keychain, err := Keychain(opts.keychainOpts(), opts.EnvironFunc)
 	if err != nil {
 		return nil, fmt.Errorf("Creating registry keychain: %s", err)
 	}
@@ -179,23 +268,63 @@ func NewSimpleRegistryWithTransport(opts Opts, rTripper http.RoundTripper) (*Sim
 	if tries == 0 {
 		tries = 1
 	}
+	backoff := opts.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
 
 	retryBackoff := regremote.Backoff{
-		Duration: 100 * time.Millisecond,
+		Duration: backoff,
 		Factor:   2,
 		Jitter:   0,
 		Steps:    tries,
-		Cap:      1 * time.Second,
+		Cap:      maxRetryBackoff,
 	}
 	regRemoteOptions = append(regRemoteOptions, regremote.WithRetryBackoff(retryBackoff))
+	regRemoteOptions = append(regRemoteOptions, regremote.WithContext(ctx))
 
-	baseRoundTripper := rTripper
+	baseRoundTripper := tracing.NewRoundTripper(rTripper)
+	if opts.ReadOnly {
+		baseRoundTripper = NewReadOnlyRoundTripper(baseRoundTripper, opts.WritableRepositories)
+	}
+	if opts.MaxBandwidth > 0 {
+		baseRoundTripper = NewBandwidthLimitRoundTripper(baseRoundTripper, opts.MaxBandwidth)
+	}
+	// Wrap before the download progress so that a resumed download is reported as a single one
+	baseRoundTripper = NewBlobRedirectRoundTripper(baseRoundTripper)
+	if opts.DownloadProgress != nil {
+		baseRoundTripper = NewDownloadProgressRoundTripper(baseRoundTripper, opts.DownloadProgress)
+	}
+	if opts.MaxRequestsPerHost > 0 {
+		// Wrap before the retry so that a request waiting to be retried does not hold a slot
+		baseRoundTripper = NewHostLimitRoundTripper(baseRoundTripper, opts.MaxRequestsPerHost)
+	}
+	if opts.CacheDir != "" {
+		baseRoundTripper = NewCacheRoundTripper(baseRoundTripper, opts.CacheDir)
+	}
+	if len(opts.Headers) > 0 {
+		baseRoundTripper = NewHeadersRoundTripper(baseRoundTripper, opts.Headers)
+	}
 	if logs.Enabled(logs.Debug) {
-		baseRoundTripper = transport.NewLogger(rTripper)
+		baseRoundTripper = transport.NewLogger(baseRoundTripper)
 	}
 
-	// Wrap the transport in something that can retry network flakes.
-	baseRoundTripper = transport.NewRetry(baseRoundTripper, transport.WithRetryBackoff(retryBackoff))
+	// Wrap the transport in something that can retry network flakes, rate limiting and transient registry errors
+	baseRoundTripper = NewRetryRoundTripper(baseRoundTripper, tries, backoff)
+
+	// Wrap after the retry so that each chunk can be retried independently
+	baseRoundTripper = NewChunkedUploadRoundTripper(baseRoundTripper, opts.UploadChunkSize)
+
+	// Wrap this around the base transport so that the ping and token requests also carry the User-Agent
+	if opts.UserAgent != "" {
+		baseRoundTripper = transport.NewUserAgent(baseRoundTripper, opts.UserAgent)
+	}
+
+	// Wrap after the retry so that the waits between retries are also cancelled. The requests that are not created
+	// with the context of the registry, e.g. the ones retrieving tokens, are also cancelled
+	if ctx.Done() != nil {
+		baseRoundTripper = NewContextRoundTripper(baseRoundTripper, ctx)
+	}
 
 	return &SimpleRegistry{
 		remoteOpts:      regRemoteOptions,
@@ -204,6 +333,11 @@ func NewSimpleRegistryWithTransport(opts Opts, rTripper http.RoundTripper) (*Sim
 		roundTrippers:   NewMultiRoundTripperStorage(baseRoundTripper),
 		authn:           map[string]regauthn.Authenticator{},
 		transportAccess: &sync.Mutex{},
+
+		includeNonDistributable: opts.IncludeNonDistributableLayers,
+		layerConcurrency:        opts.LayerConcurrency,
+		blobUploads:             newBlobUploads(),
+		tagListings:             newTagListings(),
 	}, nil
 }
 
@@ -238,6 +372,12 @@ func (r SimpleRegistry) CloneWithSingleAuth(imageRef regname.Tag) (Registry, err
 		roundTrippers:   singleRt,
 		authn:           map[string]regauthn.Authenticator{},
 		transportAccess: &sync.Mutex{},
+
+		includeNonDistributable: r.includeNonDistributable,
+		layerConcurrency:        r.layerConcurrency,
+		blobUploads:             r.blobUploads,
+		tagListings:             newTagListings(),
+		blobUploadsReport:       r.blobUploadsReport,
 	}, nil
 }
 
@@ -251,9 +391,21 @@ func (r SimpleRegistry) CloneWithLogger(_ util.ProgressLogger) Registry {
 		roundTrippers:   r.roundTrippers,
 		authn:           map[string]regauthn.Authenticator{},
 		transportAccess: &sync.Mutex{},
+
+		includeNonDistributable: r.includeNonDistributable,
+		layerConcurrency:        r.layerConcurrency,
+		blobUploads:             r.blobUploads,
+		tagListings:             r.tagListings,
+		blobUploadsReport:       r.blobUploadsReport,
 	}
 }
 
+// WithBlobUploadsReport Clones the registry recording in the report the blobs uploaded by MultiWrite
+func (r SimpleRegistry) WithBlobUploadsReport(report *BlobUploadsReport) *SimpleRegistry {
+	r.blobUploadsReport = report
+	return &r
+}
+
 // readOpts Returns the readOpts + the keychain
 func (r *SimpleRegistry) readOpts(ref regname.Reference) ([]regremote.Option, error) {
 	rt, authn, err := r.transport(ref, ref.Scope(transport.PullScope))
@@ -397,11 +549,47 @@ func (r *SimpleRegistry) MultiWrite(imageOrIndexesToUpload map[regname.Reference
 	if err != nil {
 		return err
 	}
-	rOpts := append(append([]regremote.Option{}, opts...), regremote.WithJobs(concurrency))
 	if updatesCh != nil {
-		rOpts = append(rOpts, regremote.WithProgress(updatesCh))
+		defer close(updatesCh)
 	}
-	return regremote.MultiWrite(overriddenImageOrIndexesToUploadRef, rOpts...)
+
+	// Blobs are uploaded before the manifests to control the order in which they are uploaded
+	// and to avoid uploading the same blob more than once
+	var refs []regname.Reference
+	for ref := range overriddenImageOrIndexesToUploadRef {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name() < refs[j].Name() })
+	var taggables []regremote.Taggable
+	for _, ref := range refs {
+		taggables = append(taggables, overriddenImageOrIndexesToUploadRef[ref])
+	}
+	err = r.uploadBlobsLargestFirst(singleRef.Context(), taggables, concurrency, opts, updatesCh)
+	if err != nil {
+		return err
+	}
+
+	// Only the manifests are missing from the images, indexes are left to MultiWrite to upload the
+	// manifests of the images they contain before their own manifest
+	images := map[regname.Reference]regremote.Taggable{}
+	indexes := map[regname.Reference]regremote.Taggable{}
+	for ref, taggable := range overriddenImageOrIndexesToUploadRef {
+		if _, ok := taggable.(regv1.Image); ok {
+			images[ref] = taggable
+		} else {
+			indexes[ref] = taggable
+		}
+	}
+	err = putManifests(images, concurrency, opts)
+	if err != nil {
+		return err
+	}
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	rOpts := append(append([]regremote.Option{}, opts...), regremote.WithJobs(concurrency))
+	return regremote.MultiWrite(indexes, rOpts...)
 }
 
 // WriteImage Upload Image to registry
@@ -421,6 +609,9 @@ func (r *SimpleRegistry) WriteImage(ref regname.Reference, img regv1.Image, upda
 	if updatesCh != nil {
 		opts = append(opts, regremote.WithProgress(updatesCh))
 	}
+	if r.layerConcurrency > 0 {
+		opts = append(opts, regremote.WithJobs(r.layerConcurrency))
+	}
 	err = regremote.Write(overriddenRef, img, opts...)
 	if err != nil {
 		return fmt.Errorf("Writing image: %s", err)
@@ -509,7 +700,9 @@ func (r *SimpleRegistry) ListTags(repo regname.Repository) ([]string, error) {
 	return regremote.List(overriddenRepo, opts...)
 }
 
-// FirstImageExists Returns the first of the provided Image Digests that exists in the Registry
+// FirstImageExists Returns the first of the provided Image Digests that exists in the Registry.
+// Images tagged by imgpkg are found using a single listing of the tags of their repository, the remaining ones
+// are checked one at a time
 func (r *SimpleRegistry) FirstImageExists(digests []string) (string, error) {
 	var err error
 	for _, img := range digests {
@@ -517,6 +710,9 @@ func (r *SimpleRegistry) FirstImageExists(digests []string) (string, error) {
 		if parseErr != nil {
 			return "", parseErr
 		}
+		if r.hasImgpkgTag(ref) {
+			return img, nil
+		}
 		_, err = r.Digest(ref)
 		if err == nil {
 			return img, nil
@@ -525,6 +721,24 @@ func (r *SimpleRegistry) FirstImageExists(digests []string) (string, error) {
 	return "", fmt.Errorf("Checking image existence: %s", err)
 }
 
+// hasImgpkgTag Checks if the repository contains the tag imgpkg creates when it copies the image, when the registry
+// cannot list the tags of the repository returns false
+func (r *SimpleRegistry) hasImgpkgTag(ref regname.Digest) bool {
+	hash, err := regv1.NewHash(ref.DigestStr())
+	if err != nil {
+		return false
+	}
+	tagRef, err := util.BuildDefaultUploadTagRef(util.TagGenDigest{Algorithm: hash.Algorithm, Hex: hash.Hex}, ref.Context())
+	if err != nil {
+		return false
+	}
+
+	found, err := r.tagListings.Contains(ref.Context(), tagRef.TagStr(), func() ([]string, error) {
+		return r.ListTags(ref.Context())
+	})
+	return err == nil && found
+}
+
 func newHTTPTransport(opts Opts) (*http.Transport, error) {
 	var pool *x509.CertPool
 
@@ -545,12 +759,31 @@ func newHTTPTransport(opts Opts) (*http.Transport, error) {
 	}
 
 	clonedDefaultTransport := http.DefaultTransport.(*http.Transport).Clone()
-	clonedDefaultTransport.ForceAttemptHTTP2 = false
+	clonedDefaultTransport.ForceAttemptHTTP2 = opts.EnableHTTP2
+	if !opts.EnableHTTP2 {
+		// A non nil empty map ensures HTTP/2 is never negotiated, even if the default transport was already used
+		clonedDefaultTransport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
 	clonedDefaultTransport.ResponseHeaderTimeout = opts.ResponseHeaderTimeout
+	if opts.MaxIdleConnsPerHost > 0 {
+		clonedDefaultTransport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	if opts.IdleConnTimeout > 0 {
+		clonedDefaultTransport.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	if opts.KeepAlive != 0 {
+		clonedDefaultTransport.DialContext = (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: opts.KeepAlive,
+		}).DialContext
+	}
 	clonedDefaultTransport.TLSClientConfig = &tls.Config{
 		RootCAs:            pool,
 		InsecureSkipVerify: opts.VerifyCerts == false,
 	}
+	if opts.CertPoolProvider != nil && opts.VerifyCerts {
+		clonedDefaultTransport.TLSClientConfig = tlsConfigWithCertPoolProvider(opts.CertPoolProvider, pool)
+	}
 
 	return clonedDefaultTransport, nil
 }
@@ -10,14 +10,21 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
 	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
 )
 
 func TestRegistry_Digest(t *testing.T) {
@@ -103,6 +110,69 @@ func TestRegistry_Get(t *testing.T) {
 
 }
 
+func TestRegistry_RequestTagging(t *testing.T) {
+	t.Run("when headers are provided they are sent on every request", func(t *testing.T) {
+		var receivedHeaders []http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			receivedHeaders = append(receivedHeaders, r.Header.Clone())
+			if r.URL.Path == "/v2/" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", "sha256:477c34d98f9e090a4441cf82d2f1f03e64c8eb730e8c1ef39a8595e685d4df65")
+			w.Header().Set("Content-Type", string(types.DockerManifestSchema2))
+		}))
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{
+			Headers: map[string]string{"X-Trace-Id": "some-trace", "X-Cost-Center": "team-a"},
+		})
+		require.NoError(t, err)
+
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/repo:latest", u.Host))
+		require.NoError(t, err)
+		_, err = subject.Digest(imgRef)
+		require.NoError(t, err)
+
+		require.NotEmpty(t, receivedHeaders)
+		for _, header := range receivedHeaders {
+			assert.Equal(t, "some-trace", header.Get("X-Trace-Id"))
+			assert.Equal(t, "team-a", header.Get("X-Cost-Center"))
+		}
+	})
+
+	t.Run("when user agent is provided it is sent on every request", func(t *testing.T) {
+		var userAgents []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userAgents = append(userAgents, r.Header.Get("User-Agent"))
+			if r.URL.Path == "/v2/" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", "sha256:477c34d98f9e090a4441cf82d2f1f03e64c8eb730e8c1ef39a8595e685d4df65")
+			w.Header().Set("Content-Type", string(types.DockerManifestSchema2))
+		}))
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{UserAgent: "imgpkg/develop pipeline-1"})
+		require.NoError(t, err)
+
+		imgRef, err := name.ParseReference(fmt.Sprintf("%s/repo:latest", u.Host))
+		require.NoError(t, err)
+		_, err = subject.Digest(imgRef)
+		require.NoError(t, err)
+
+		require.NotEmpty(t, userAgents)
+		for _, userAgent := range userAgents {
+			assert.True(t, strings.HasPrefix(userAgent, "imgpkg/develop pipeline-1"), "User-Agent was %s", userAgent)
+		}
+	})
+}
+
 func TestInsecureRegistryFlag(t *testing.T) {
 	tests := []struct {
 		fName string
@@ -304,6 +374,236 @@ func TestBasicRegistry(t *testing.T) {
 	})
 }
 
+func TestRegistry_MultiWrite(t *testing.T) {
+	t.Run("uploads each blob once, starting with the largest ones", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+
+		sharedLayer, err := random.Layer(2000, types.DockerLayer)
+		require.NoError(t, err)
+		blobSizes := map[string]int64{}
+		newImage := func(layerSizes ...int64) regv1.Image {
+			img := empty.Image
+			for _, size := range layerSizes {
+				layer, err := random.Layer(size, types.DockerLayer)
+				require.NoError(t, err)
+				img, err = mutate.AppendLayers(img, layer)
+				require.NoError(t, err)
+			}
+			img, err = mutate.AppendLayers(img, sharedLayer)
+			require.NoError(t, err)
+
+			layers, err := img.Layers()
+			require.NoError(t, err)
+			for _, layer := range layers {
+				digest, err := layer.Digest()
+				require.NoError(t, err)
+				size, err := layer.Size()
+				require.NoError(t, err)
+				blobSizes[digest.String()] = size
+			}
+			configDigest, err := img.ConfigName()
+			require.NoError(t, err)
+			rawConfig, err := img.RawConfigFile()
+			require.NoError(t, err)
+			blobSizes[configDigest.String()] = int64(len(rawConfig))
+			return img
+		}
+
+		rt := &recordingRoundTripper{delegate: http.DefaultTransport}
+		reg, err := registry.NewBasicRegistry(regremote.WithTransport(rt))
+		require.NoError(t, err)
+
+		smallImgRef, err := name.ParseReference(fakeRegistry.ReferenceOnTestServer("repo/app:small"))
+		require.NoError(t, err)
+		bigImgRef, err := name.ParseReference(fakeRegistry.ReferenceOnTestServer("repo/app:big"))
+		require.NoError(t, err)
+
+		err = reg.MultiWrite(map[name.Reference]regremote.Taggable{
+			smallImgRef: newImage(100, 300),
+			bigImgRef:   newImage(50, 8000, 4000),
+		}, 1, nil)
+		require.NoError(t, err)
+
+		var uploadedSizes []int64
+		for _, digest := range rt.uploadedBlobs() {
+			uploadedSizes = append(uploadedSizes, blobSizes[digest])
+		}
+		require.Len(t, uploadedSizes, len(blobSizes))
+		assert.IsNonIncreasing(t, uploadedSizes)
+
+		_, err = reg.Digest(bigImgRef)
+		require.NoError(t, err)
+		_, err = reg.Digest(smallImgRef)
+		require.NoError(t, err)
+	})
+
+	t.Run("when images sharing blobs are written at the same time, checks and uploads each blob once", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+
+		sharedLayer, err := random.Layer(4000, types.DockerLayer)
+		require.NoError(t, err)
+
+		rt := &recordingRoundTripper{delegate: http.DefaultTransport}
+		reg, err := registry.NewBasicRegistry(regremote.WithTransport(rt))
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 4)
+		for i := 0; i < 4; i++ {
+			layer, err := random.Layer(500, types.DockerLayer)
+			require.NoError(t, err)
+			img, err := mutate.AppendLayers(empty.Image, layer, sharedLayer)
+			require.NoError(t, err)
+			ref, err := name.ParseReference(fakeRegistry.ReferenceOnTestServer(fmt.Sprintf("repo/app:tag-%d", i)))
+			require.NoError(t, err)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- reg.MultiWrite(map[name.Reference]regremote.Taggable{ref: img}, 2, nil)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		sharedDigest, err := sharedLayer.Digest()
+		require.NoError(t, err)
+		countOf := func(digests []string, digest string) int {
+			count := 0
+			for _, d := range digests {
+				if d == digest {
+					count++
+				}
+			}
+			return count
+		}
+		assert.Equal(t, 1, countOf(rt.uploadedBlobs(), sharedDigest.String()))
+		assert.Equal(t, 1, countOf(rt.checkedBlobs(), sharedDigest.String()))
+		// 4 images with a config and a layer of their own plus the shared layer
+		assert.Len(t, rt.uploadedBlobs(), 9)
+	})
+
+	t.Run("records in the report the blobs that were uploaded", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+
+		existingLayer, err := random.Layer(1000, types.DockerLayer)
+		require.NoError(t, err)
+		newLayer, err := random.Layer(1000, types.DockerLayer)
+		require.NoError(t, err)
+
+		basicReg, err := registry.NewBasicRegistry(regremote.WithTransport(http.DefaultTransport))
+		require.NoError(t, err)
+		report := registry.NewBlobUploadsReport()
+		reg := basicReg.WithBlobUploadsReport(report)
+
+		existingImg, err := mutate.AppendLayers(empty.Image, existingLayer)
+		require.NoError(t, err)
+		existingRef, err := name.ParseReference(fakeRegistry.ReferenceOnTestServer("repo/app:existing"))
+		require.NoError(t, err)
+		require.NoError(t, basicReg.MultiWrite(map[name.Reference]regremote.Taggable{existingRef: existingImg}, 1, nil))
+
+		img, err := mutate.AppendLayers(existingImg, newLayer)
+		require.NoError(t, err)
+		ref, err := name.ParseReference(fakeRegistry.ReferenceOnTestServer("repo/app:new"))
+		require.NoError(t, err)
+		require.NoError(t, reg.MultiWrite(map[name.Reference]regremote.Taggable{ref: img}, 1, nil))
+
+		existingDigest, err := existingLayer.Digest()
+		require.NoError(t, err)
+		newDigest, err := newLayer.Digest()
+		require.NoError(t, err)
+		assert.False(t, report.Uploaded(ref.Context(), existingDigest))
+		assert.True(t, report.Uploaded(ref.Context(), newDigest))
+	})
+
+	t.Run("when layer concurrency is provided, uploads that many layers of the same image at the same time", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+
+		img := empty.Image
+		for i := 0; i < 6; i++ {
+			layer, err := random.Layer(500, types.DockerLayer)
+			require.NoError(t, err)
+			img, err = mutate.AppendLayers(img, layer)
+			require.NoError(t, err)
+		}
+
+		rt := &recordingRoundTripper{delegate: http.DefaultTransport, checkDelay: 50 * time.Millisecond}
+		reg, err := registry.NewSimpleRegistryWithTransport(registry.Opts{LayerConcurrency: 3}, rt)
+		require.NoError(t, err)
+
+		ref, err := name.ParseReference(fakeRegistry.ReferenceOnTestServer("repo/app:layers"))
+		require.NoError(t, err)
+		require.NoError(t, reg.MultiWrite(map[name.Reference]regremote.Taggable{ref: img}, 1, nil))
+
+		assert.Equal(t, 3, rt.maxConcurrentChecks())
+		_, err = reg.Digest(ref)
+		require.NoError(t, err)
+	})
+}
+
+// recordingRoundTripper records the digest of the blobs that are checked and uploaded
+type recordingRoundTripper struct {
+	delegate http.RoundTripper
+	// checkDelay when provided delays each blob check to detect how many are done at the same time
+	checkDelay time.Duration
+	blobs      []string
+	checks     []string
+	current    int
+	maxChecks  int
+	lock       sync.Mutex
+}
+
+func (r *recordingRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	isCheck := request.Method == http.MethodHead && strings.Contains(request.URL.Path, "/blobs/")
+	r.lock.Lock()
+	switch {
+	case request.Method == http.MethodPut && strings.Contains(request.URL.Path, "/blobs/uploads/"):
+		r.blobs = append(r.blobs, request.URL.Query().Get("digest"))
+	case isCheck:
+		r.checks = append(r.checks, request.URL.Path[strings.LastIndex(request.URL.Path, "/")+1:])
+		r.current++
+		if r.current > r.maxChecks {
+			r.maxChecks = r.current
+		}
+	}
+	r.lock.Unlock()
+
+	if isCheck {
+		time.Sleep(r.checkDelay)
+		defer func() {
+			r.lock.Lock()
+			r.current--
+			r.lock.Unlock()
+		}()
+	}
+	return r.delegate.RoundTrip(request)
+}
+
+func (r *recordingRoundTripper) maxConcurrentChecks() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.maxChecks
+}
+
+func (r *recordingRoundTripper) uploadedBlobs() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string{}, r.blobs...)
+}
+
+func (r *recordingRoundTripper) checkedBlobs() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string{}, r.checks...)
+}
+
 type notFoundRoundTripper struct {
 	RoundTripNumCalls int
 	do                func(request *http.Request) (*http.Response, error)
@@ -313,3 +613,112 @@ func (n *notFoundRoundTripper) RoundTrip(request *http.Request) (*http.Response,
 	n.RoundTripNumCalls++
 	return n.do(request)
 }
+
+func TestRegistry_FirstImageExists(t *testing.T) {
+	taggedDigests := []string{
+		"sha256:1111111111111111111111111111111111111111111111111111111111111111",
+		"sha256:2222222222222222222222222222222222222222222222222222222222222222",
+		"sha256:3333333333333333333333333333333333333333333333333333333333333333",
+	}
+	untaggedDigest := "sha256:4444444444444444444444444444444444444444444444444444444444444444"
+
+	newServer := func(listingSupported bool) (*httptest.Server, *int, *int) {
+		lock := &sync.Mutex{}
+		listRequests := 0
+		manifestRequests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			switch {
+			case r.URL.Path == "/v2/":
+				w.WriteHeader(http.StatusOK)
+			case r.URL.Path == "/v2/repo/tags/list":
+				listRequests++
+				if !listingSupported {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				var tags []string
+				for _, digest := range taggedDigests {
+					tags = append(tags, strings.Replace(digest, ":", "-", 1)+".imgpkg")
+				}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"name":"repo","tags":["%s"]}`, strings.Join(tags, `","`))
+			case strings.HasPrefix(r.URL.Path, "/v2/repo/manifests/"):
+				manifestRequests++
+				digest := strings.TrimPrefix(r.URL.Path, "/v2/repo/manifests/")
+				if digest != untaggedDigest && digest != taggedDigests[0] {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Header().Set("Content-Type", string(types.DockerManifestSchema2))
+				w.Header().Set("Docker-Content-Digest", digest)
+				w.Write([]byte("doesn't matter"))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		return server, &listRequests, &manifestRequests
+	}
+
+	t.Run("lists the tags of the repository once for all the images tagged by imgpkg", func(t *testing.T) {
+		server, listRequests, manifestRequests := newServer(true)
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		for _, digest := range taggedDigests {
+			image := fmt.Sprintf("%s/repo@%s", u.Host, digest)
+			found, err := subject.FirstImageExists([]string{image})
+			require.NoError(t, err)
+			assert.Equal(t, image, found)
+		}
+
+		assert.Equal(t, 1, *listRequests)
+		assert.Equal(t, 0, *manifestRequests)
+	})
+
+	t.Run("checks the manifest of images that are not tagged by imgpkg", func(t *testing.T) {
+		server, listRequests, manifestRequests := newServer(true)
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		image := fmt.Sprintf("%s/repo@%s", u.Host, untaggedDigest)
+		found, err := subject.FirstImageExists([]string{image})
+		require.NoError(t, err)
+		assert.Equal(t, image, found)
+
+		assert.Equal(t, 1, *listRequests)
+		assert.Equal(t, 1, *manifestRequests)
+	})
+
+	t.Run("when the registry cannot list tags, it checks the manifest of each image", func(t *testing.T) {
+		server, listRequests, manifestRequests := newServer(false)
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		subject, err := registry.NewSimpleRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		missingImage := fmt.Sprintf("%s/repo@%s", u.Host, taggedDigests[1])
+		existingImage := fmt.Sprintf("%s/repo@%s", u.Host, taggedDigests[0])
+		found, err := subject.FirstImageExists([]string{missingImage, existingImage})
+		require.NoError(t, err)
+		assert.Equal(t, existingImage, found)
+
+		_, err = subject.FirstImageExists([]string{missingImage})
+		require.Error(t, err)
+
+		assert.Equal(t, 1, *listRequests)
+		assert.GreaterOrEqual(t, *manifestRequests, 3)
+	})
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultRetryBackoff time waited before the first retry when no backoff is provided
+	DefaultRetryBackoff = 100 * time.Millisecond
+	// maxRetryBackoff the wait between retries stops doubling when it reaches this value
+	maxRetryBackoff = time.Minute
+	// maxRetryAfter longest Retry-After requested by a registry that is honored, longer waits are shortened to it
+	maxRetryAfter = 5 * time.Minute
+)
+
+// retryAfterStatusCodes Responses that signal a transient problem of the registry. They are only retried here when
+// the registry says when to retry with Retry-After, otherwise go-containerregistry retries the whole operation
+var retryAfterStatusCodes = map[int]struct{}{
+	http.StatusRequestTimeout:      {},
+	http.StatusInternalServerError: {},
+	http.StatusBadGateway:          {},
+	http.StatusServiceUnavailable:  {},
+	http.StatusGatewayTimeout:      {},
+}
+
+// NewRetryRoundTripper Creates a RoundTripper that sends each request at most tries times, waiting backoff before the
+// first retry and doubling the wait for each following retry
+func NewRetryRoundTripper(inner http.RoundTripper, tries int, backoff time.Duration) *RetryRoundTripper {
+	if tries < 1 {
+		tries = 1
+	}
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	return &RetryRoundTripper{inner: inner, tries: tries, backoff: backoff}
+}
+
+// RetryRoundTripper Retries the requests that failed with a temporary network error, that were rate limited (429),
+// or that failed with a transient problem (408, 500, 502, 503, 504) and a Retry-After header. When the registry
+// provides a Retry-After header it is waited instead of the exponential backoff. Requests with a body are only retried
+// when the body can be read again
+type RetryRoundTripper struct {
+	inner   http.RoundTripper
+	tries   int
+	backoff time.Duration
+}
+
+// RoundTrip Executes the request, retrying it while it fails with a transient error
+func (r *RetryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	wait := r.backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := r.inner.RoundTrip(req)
+		if attempt >= r.tries || !r.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		delay := wait
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				delay = retryAfter
+			}
+			// Read the body so that the connection can be reused
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		if err := sleepWithContext(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		wait *= 2
+		if wait > maxRetryBackoff {
+			wait = maxRetryBackoff
+		}
+
+		if req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+func (r *RetryRoundTripper) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return isTemporaryError(err)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	_, retryable := retryAfterStatusCodes[resp.StatusCode]
+	return retryable && resp.Header.Get("Retry-After") != ""
+}
+
+// isTemporaryError returns true for network errors that are likely to succeed when the request is sent again
+func isTemporaryError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var temporary interface{ Temporary() bool }
+	return errors.As(err, &temporary) && temporary.Temporary()
+}
+
+// parseRetryAfter parses the Retry-After header, either a number of seconds or a date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		delay = date.Sub(now)
+	} else {
+		return 0, false
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	return delay, true
+}
+
+func sleepWithContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+// failingServer Server that responds with the provided responses in order, and with 200 once they are exhausted
+func failingServer(t *testing.T, headers http.Header, statusCodes ...int) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := atomic.AddInt32(&requests, 1) - 1
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		if int(i) < len(statusCodes) {
+			for key, values := range headers {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(statusCodes[i])
+			return
+		}
+		w.Write(body)
+	}))
+	return server, &requests
+}
+
+func TestRetryRoundTripper(t *testing.T) {
+	t.Run("retries 429 responses until the request succeeds", func(t *testing.T) {
+		server, requests := failingServer(t, nil, http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests)
+		defer server.Close()
+
+		subject := registry.NewRetryRoundTripper(http.DefaultTransport, 5, time.Millisecond)
+		req, err := http.NewRequest(http.MethodPut, server.URL, bytes.NewReader([]byte("some-body")))
+		require.NoError(t, err)
+		resp, err := subject.RoundTrip(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(4), atomic.LoadInt32(requests))
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "some-body", string(body), "the body is sent again on each retry")
+	})
+
+	t.Run("returns the last response when all the tries fail", func(t *testing.T) {
+		server, requests := failingServer(t, nil, http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests)
+		defer server.Close()
+
+		subject := registry.NewRetryRoundTripper(http.DefaultTransport, 2, time.Millisecond)
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, err := subject.RoundTrip(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		for _, statusCode := range []int{http.StatusNotFound, http.StatusInternalServerError} {
+			server, requests := failingServer(t, nil, statusCode)
+			defer server.Close()
+
+			subject := registry.NewRetryRoundTripper(http.DefaultTransport, 5, time.Millisecond)
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			require.NoError(t, err)
+			resp, err := subject.RoundTrip(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, statusCode, resp.StatusCode)
+			assert.Equal(t, int32(1), atomic.LoadInt32(requests), "5xx without Retry-After are retried by go-containerregistry")
+		}
+	})
+
+	t.Run("retries 5xx responses with Retry-After", func(t *testing.T) {
+		server, requests := failingServer(t, http.Header{"Retry-After": []string{"0"}}, http.StatusServiceUnavailable, http.StatusBadGateway)
+		defer server.Close()
+
+		subject := registry.NewRetryRoundTripper(http.DefaultTransport, 5, time.Millisecond)
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, err := subject.RoundTrip(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(3), atomic.LoadInt32(requests))
+	})
+
+	t.Run("waits the time requested by the registry with Retry-After", func(t *testing.T) {
+		server, requests := failingServer(t, http.Header{"Retry-After": []string{"1"}}, http.StatusTooManyRequests)
+		defer server.Close()
+
+		subject := registry.NewRetryRoundTripper(http.DefaultTransport, 5, time.Millisecond)
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		start := time.Now()
+		resp, err := subject.RoundTrip(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+		assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	})
+}
@@ -0,0 +1,62 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"sync"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+)
+
+// tagListings Caches the tags of each repository so that checking if many images exist in the same repository
+// only lists its tags once, instead of sending one request per image.
+// When listing fails, e.g. the registry does not support it, the failure is also cached
+type tagListings struct {
+	lock     sync.Mutex
+	listings map[string]*tagListing
+}
+
+type tagListing struct {
+	done chan struct{}
+	tags map[string]struct{}
+	err  error
+}
+
+func newTagListings() *tagListings {
+	return &tagListings{listings: map[string]*tagListing{}}
+}
+
+// Contains Checks if the repository has the tag, list is only called the first time a repository is checked.
+// When multiple callers check the same repository at the same time, only one of them lists the tags
+func (t *tagListings) Contains(repo regname.Repository, tag string, list func() ([]string, error)) (bool, error) {
+	if t == nil {
+		return false, nil
+	}
+
+	key := repo.Name()
+	t.lock.Lock()
+	listing, found := t.listings[key]
+	if !found {
+		listing = &tagListing{done: make(chan struct{})}
+		t.listings[key] = listing
+		t.lock.Unlock()
+
+		tags, err := list()
+		listing.err = err
+		listing.tags = map[string]struct{}{}
+		for _, tag := range tags {
+			listing.tags[tag] = struct{}{}
+		}
+		close(listing.done)
+	} else {
+		t.lock.Unlock()
+		<-listing.done
+	}
+
+	if listing.err != nil {
+		return false, listing.err
+	}
+	_, found = listing.tags[tag]
+	return found, nil
+}
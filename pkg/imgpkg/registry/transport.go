@@ -89,7 +89,7 @@ func (r *MultiRoundTripperStorage) CreateRoundTripper(reg regname.Registry, auth
 	r.readWriteAccess.Lock()
 	defer r.readWriteAccess.Unlock()
 
-	rt, err := transport.NewWithContext(context.Background(), reg, auth, r.baseRoundTripper, []string{scope})
+	rt, err := transport.NewWithContext(context.Background(), reg, auth, NewCacheIdentityRoundTripper(r.baseRoundTripper, reg, auth), []string{scope})
 	if err != nil {
 		return nil, fmt.Errorf("Unable to create round tripper: %s", err)
 	}
@@ -142,7 +142,7 @@ func (r *SingleTripperStorage) CreateRoundTripper(reg regname.Registry, auth aut
 	r.readWriteAccess.Lock()
 	defer r.readWriteAccess.Unlock()
 
-	rt, err := transport.NewWithContext(context.Background(), reg, auth, r.baseRoundTripper, []string{scope})
+	rt, err := transport.NewWithContext(context.Background(), reg, auth, NewCacheIdentityRoundTripper(r.baseRoundTripper, reg, auth), []string{scope})
 	if err != nil {
 		return nil, fmt.Errorf("Unable to create round tripper: %s", err)
 	}
@@ -169,3 +169,24 @@ func (n NoopRoundTripperStorage) CreateRoundTripper(_ regname.Registry, _ authn.
 func (n NoopRoundTripperStorage) BaseRoundTripper() http.RoundTripper {
 	return nil
 }
+
+// NewHeadersRoundTripper Creates a RoundTripper that adds the provided headers to every request
+func NewHeadersRoundTripper(inner http.RoundTripper, headers map[string]string) *HeadersRoundTripper {
+	return &HeadersRoundTripper{inner: inner, headers: headers}
+}
+
+// HeadersRoundTripper Adds a static set of headers to every request before sending it
+type HeadersRoundTripper struct {
+	inner   http.RoundTripper
+	headers map[string]string
+}
+
+// RoundTrip Adds the configured headers and executes the request
+func (h *HeadersRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers should not modify the original request
+	req = req.Clone(req.Context())
+	for key, value := range h.headers {
+		req.Header.Set(key, value)
+	}
+	return h.inner.RoundTrip(req)
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+func TestRegistry_HTTP2(t *testing.T) {
+	var protocols []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protocols = append(protocols, r.Proto)
+		if r.URL.Path == "/v2/" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", string(types.DockerManifestSchema2))
+		w.Header().Set("Docker-Content-Digest", "sha256:477c34d98f9e090a4441cf82d2f1f03e64c8eb730e8c1ef39a8595e685d4df65")
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	imgRef, err := name.ParseReference(fmt.Sprintf("%s/repo:latest", u.Host))
+	require.NoError(t, err)
+
+	t.Run("by default only uses HTTP/1.1", func(t *testing.T) {
+		protocols = nil
+		subject, err := registry.NewSimpleRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		_, err = subject.Digest(imgRef)
+		require.NoError(t, err)
+		require.NotEmpty(t, protocols)
+		for _, proto := range protocols {
+			require.Equal(t, "HTTP/1.1", proto)
+		}
+	})
+
+	t.Run("when HTTP/2 is enabled it is negotiated with the registry", func(t *testing.T) {
+		protocols = nil
+		subject, err := registry.NewSimpleRegistry(registry.Opts{EnableHTTP2: true, MaxIdleConnsPerHost: 10})
+		require.NoError(t, err)
+
+		_, err = subject.Digest(imgRef)
+		require.NoError(t, err)
+		require.NotEmpty(t, protocols)
+		for _, proto := range protocols {
+			require.Equal(t, "HTTP/2.0", proto)
+		}
+	})
+}
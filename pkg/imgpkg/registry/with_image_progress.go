@@ -0,0 +1,184 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"sort"
+	"sync"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+)
+
+var _ Registry = &WithImageProgress{}
+
+// TransferProgressListener Receives the bytes transferred for each image, or blob, identified by its digest.
+// OnTransferProgress can be called concurrently from multiple goroutines
+type TransferProgressListener interface {
+	OnTransferProgress(digest string, update regv1.Update)
+}
+
+// NewRegistryWithImageProgress Creates a Registry that uploads each image on its own, reporting the bytes uploaded
+// for each one of them to the listener
+func NewRegistryWithImageProgress(reg Registry, listener TransferProgressListener) *WithImageProgress {
+	return &WithImageProgress{delegate: reg, listener: listener}
+}
+
+// WithImageProgress Implements Registry interface and reports the upload progress of each image.
+// Images are uploaded with separate writes, the blobs they share are still uploaded once
+type WithImageProgress struct {
+	delegate Registry
+	listener TransferProgressListener
+}
+
+// Get Retrieve Image descriptor for an Image reference
+func (w *WithImageProgress) Get(reference regname.Reference) (*remote.Descriptor, error) {
+	return w.delegate.Get(reference)
+}
+
+// Digest Retrieve the Digest for an Image reference
+func (w *WithImageProgress) Digest(reference regname.Reference) (regv1.Hash, error) {
+	return w.delegate.Digest(reference)
+}
+
+// Index Retrieve regv1.ImageIndex struct for an Index reference
+func (w *WithImageProgress) Index(reference regname.Reference) (regv1.ImageIndex, error) {
+	return w.delegate.Index(reference)
+}
+
+// Image Retrieve the regv1.Image struct for an Image reference
+func (w *WithImageProgress) Image(reference regname.Reference) (regv1.Image, error) {
+	return w.delegate.Image(reference)
+}
+
+// FirstImageExists Returns the first of the provided Image Digests that exists in the Registry
+func (w *WithImageProgress) FirstImageExists(digests []string) (string, error) {
+	return w.delegate.FirstImageExists(digests)
+}
+
+// MultiWrite Uploads at most concurrency Images at a time, each with its own write so that its progress can be reported
+func (w *WithImageProgress) MultiWrite(imageOrIndexesToUpload map[regname.Reference]remote.Taggable, concurrency int, updatesCh chan regv1.Update) error {
+	if updatesCh != nil {
+		defer close(updatesCh)
+	}
+
+	var refs []regname.Reference
+	for ref := range imageOrIndexesToUpload {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name() < refs[j].Name() })
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	throttle := util.NewThrottle(concurrency)
+	errs := make([]error, len(refs))
+	var wg sync.WaitGroup
+	for i, ref := range refs {
+		wg.Add(1)
+		go func(i int, ref regname.Reference) {
+			defer wg.Done()
+			throttle.Take()
+			defer throttle.Done()
+
+			taggable := imageOrIndexesToUpload[ref]
+			errs[i] = w.withProgress(taggable, func(imageUpdatesCh chan regv1.Update) error {
+				return w.delegate.MultiWrite(map[regname.Reference]remote.Taggable{ref: taggable}, concurrency, imageUpdatesCh)
+			})
+		}(i, ref)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteImage Upload Image to registry, reporting its progress
+func (w *WithImageProgress) WriteImage(reference regname.Reference, image regv1.Image, updatesCh chan regv1.Update) error {
+	if updatesCh != nil {
+		defer close(updatesCh)
+	}
+
+	return w.withProgress(image, func(imageUpdatesCh chan regv1.Update) error {
+		return w.delegate.WriteImage(reference, image, imageUpdatesCh)
+	})
+}
+
+// WriteIndex Uploads the Index manifest to the registry
+func (w *WithImageProgress) WriteIndex(reference regname.Reference, index regv1.ImageIndex) error {
+	return w.delegate.WriteIndex(reference, index)
+}
+
+// WriteTag Tag the referenced Image
+func (w *WithImageProgress) WriteTag(tag regname.Tag, taggable remote.Taggable) error {
+	return w.delegate.WriteTag(tag, taggable)
+}
+
+// ListTags Retrieve all tags associated with a Repository
+func (w *WithImageProgress) ListTags(repo regname.Repository) ([]string, error) {
+	return w.delegate.ListTags(repo)
+}
+
+// CloneWithSingleAuth Clones the provided registry replacing the Keychain with a Keychain that can only authenticate
+// the image provided
+func (w WithImageProgress) CloneWithSingleAuth(imageRef regname.Tag) (Registry, error) {
+	delegate, err := w.delegate.CloneWithSingleAuth(imageRef)
+	if err != nil {
+		return nil, err
+	}
+
+	w.delegate = delegate
+	return &w, nil
+}
+
+// CloneWithLogger Clones the provided registry updating the progress
+func (w WithImageProgress) CloneWithLogger(logger util.ProgressLogger) Registry {
+	w.delegate = w.delegate.CloneWithLogger(logger)
+	return &w
+}
+
+// withProgress Calls write with a channel whose updates are forwarded to the listener, identified by the digest of
+// the taggable
+func (w *WithImageProgress) withProgress(taggable remote.Taggable, write func(chan regv1.Update) error) error {
+	digest := ""
+	if withDigest, ok := taggable.(interface{ Digest() (regv1.Hash, error) }); ok {
+		if hash, err := withDigest.Digest(); err == nil {
+			digest = hash.String()
+		}
+	}
+
+	// The delegate closes the channel it receives, unless it fails before starting the upload, so the forwarding
+	// also stops once the write returns. Updates are sent without buffering, none is pending at that point
+	imageUpdatesCh := make(chan regv1.Update)
+	stop := make(chan struct{})
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		for {
+			select {
+			case update, ok := <-imageUpdatesCh:
+				if !ok {
+					return
+				}
+				w.listener.OnTransferProgress(digest, update)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	err := write(imageUpdatesCh)
+	close(stop)
+	<-forwarded
+	if err != nil {
+		w.listener.OnTransferProgress(digest, regv1.Update{Error: err})
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+// recordedProgress Keeps the last update received for each digest
+type recordedProgress struct {
+	lock    sync.Mutex
+	updates map[string]regv1.Update
+}
+
+func (r *recordedProgress) OnTransferProgress(digest string, update regv1.Update) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.updates == nil {
+		r.updates = map[string]regv1.Update{}
+	}
+	r.updates[digest] = update
+}
+
+func TestWithImageProgress_MultiWrite(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	reg := fakeRegistry.Build()
+
+	progress := &recordedProgress{}
+	subject := registry.NewRegistryWithImageProgress(reg, progress)
+
+	images := map[regname.Reference]remote.Taggable{}
+	for i := 0; i < 3; i++ {
+		img, err := random.Image(1000, 2)
+		require.NoError(t, err)
+		ref, err := regname.NewTag(fakeRegistry.ReferenceOnTestServer(fmt.Sprintf("progress/image:%d", i)))
+		require.NoError(t, err)
+		images[ref] = img
+	}
+
+	updatesCh := make(chan regv1.Update)
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for range updatesCh {
+		}
+	}()
+	require.NoError(t, subject.MultiWrite(images, 2, updatesCh))
+	<-drained
+
+	require.Len(t, progress.updates, 3)
+	for ref, img := range images {
+		digest, err := img.(regv1.Image).Digest()
+		require.NoError(t, err)
+		registryDigest, err := reg.Digest(ref)
+		require.NoError(t, err)
+		assert.Equal(t, digest, registryDigest)
+
+		update, found := progress.updates[digest.String()]
+		require.True(t, found, "expected progress of %s", digest)
+		assert.NoError(t, update.Error)
+		assert.NotZero(t, update.Total)
+		assert.Equal(t, update.Total, update.Complete)
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+)
+
+var _ Registry = &WithMirrors{}
+
+// Mirrors Registries that also serve the images of an origin registry, in the order they are tried
+type Mirrors map[string][]string
+
+// NewMirrors Parses mirrors in the format origin=mirror, e.g. index.docker.io=mirror.gcr.io.
+// Mirrors of the same origin are tried in the order they are provided
+func NewMirrors(values []string) (Mirrors, error) {
+	mirrors := Mirrors{}
+	for _, value := range values {
+		originStr, mirrorStr, found := strings.Cut(value, "=")
+		if !found || strings.TrimSpace(originStr) == "" || strings.TrimSpace(mirrorStr) == "" {
+			return nil, fmt.Errorf("Expected registry mirror '%s' to be in the format origin=mirror", value)
+		}
+		origin, err := regname.NewRegistry(strings.TrimSpace(originStr))
+		if err != nil {
+			return nil, fmt.Errorf("Parsing origin registry of mirror '%s': %s", value, err)
+		}
+		mirror, err := regname.NewRegistry(strings.TrimSpace(mirrorStr))
+		if err != nil {
+			return nil, fmt.Errorf("Parsing mirror registry of '%s': %s", value, err)
+		}
+		mirrors[origin.RegistryStr()] = append(mirrors[origin.RegistryStr()], mirror.RegistryStr())
+	}
+	return mirrors, nil
+}
+
+// MirrorsLogger Logger used to report when an image is retrieved from a mirror
+type MirrorsLogger interface {
+	Logf(msg string, args ...interface{})
+	Debugf(msg string, args ...interface{})
+}
+
+// NewRegistryWithMirrors Creates a Registry that, when an image cannot be retrieved from its registry, retrieves it
+// from the mirrors of the registry, one after the other
+func NewRegistryWithMirrors(reg Registry, mirrors Mirrors, logger MirrorsLogger) *WithMirrors {
+	return &WithMirrors{delegate: reg, mirrors: mirrors, logger: logger, servedBy: &servedByMirrors{digests: map[string]string{}}}
+}
+
+// WithMirrors Implements Registry interface and retrieves images from mirrors when their registry fails
+type WithMirrors struct {
+	delegate Registry
+	mirrors  Mirrors
+	// pullThrough mirrors tried before the registry of the image
+	pullThrough Mirrors
+	logger      MirrorsLogger
+	servedBy    *servedByMirrors
+}
+
+// WithPullThroughMirrors retrieves the images from the provided mirrors of their registry, e.g. pull-through caches,
+// before trying the registry itself. The mirrors provided when creating the Registry are tried after the registry
+func (w *WithMirrors) WithPullThroughMirrors(mirrors Mirrors) *WithMirrors {
+	w.pullThrough = mirrors
+	return w
+}
+
+type servedByMirrors struct {
+	lock    sync.Mutex
+	digests map[string]string
+}
+
+// ServedBy Returns the mirror the image, or index, with the provided digest was retrieved from.
+// The second value is false when it was retrieved from its own registry
+func (w *WithMirrors) ServedBy(digest string) (string, bool) {
+	if w == nil {
+		return "", false
+	}
+
+	w.servedBy.lock.Lock()
+	defer w.servedBy.lock.Unlock()
+	mirror, found := w.servedBy.digests[digest]
+	return mirror, found
+}
+
+// Get Retrieve Image descriptor for an Image reference
+func (w *WithMirrors) Get(reference regname.Reference) (*remote.Descriptor, error) {
+	var originErr error
+	for _, source := range w.sources(reference) {
+		desc, err := w.delegate.Get(source)
+		if err == nil {
+			w.recordSource(reference, source, desc.Digest)
+			return desc, nil
+		}
+		originErr = w.sourceFailed(reference, source, originErr, err)
+	}
+	return nil, originErr
+}
+
+// Digest Retrieve the Digest for an Image reference
+func (w *WithMirrors) Digest(reference regname.Reference) (regv1.Hash, error) {
+	var originErr error
+	for _, source := range w.sources(reference) {
+		digest, err := w.delegate.Digest(source)
+		if err == nil {
+			w.recordSource(reference, source, digest)
+			return digest, nil
+		}
+		originErr = w.sourceFailed(reference, source, originErr, err)
+	}
+	return regv1.Hash{}, originErr
+}
+
+// Index Retrieve regv1.ImageIndex struct for an Index reference
+func (w *WithMirrors) Index(reference regname.Reference) (regv1.ImageIndex, error) {
+	var originErr error
+	for _, source := range w.sources(reference) {
+		index, err := w.delegate.Index(source)
+		if err == nil {
+			if digest, err := index.Digest(); err == nil {
+				w.recordSource(reference, source, digest)
+			}
+			return index, nil
+		}
+		originErr = w.sourceFailed(reference, source, originErr, err)
+	}
+	return nil, originErr
+}
+
+// Image Retrieve the regv1.Image struct for an Image reference
+func (w *WithMirrors) Image(reference regname.Reference) (regv1.Image, error) {
+	var originErr error
+	for _, source := range w.sources(reference) {
+		img, err := w.delegate.Image(source)
+		if err == nil {
+			if digest, err := img.Digest(); err == nil {
+				w.recordSource(reference, source, digest)
+			}
+			return img, nil
+		}
+		originErr = w.sourceFailed(reference, source, originErr, err)
+	}
+	return nil, originErr
+}
+
+// FirstImageExists Returns the first of the provided Image Digests that exists in the Registry
+func (w *WithMirrors) FirstImageExists(digests []string) (string, error) {
+	return w.delegate.FirstImageExists(digests)
+}
+
+// MultiWrite Upload multiple Images in Parallel to the Registry
+func (w *WithMirrors) MultiWrite(imageOrIndexesToUpload map[regname.Reference]remote.Taggable, concurrency int, updatesCh chan regv1.Update) error {
+	return w.delegate.MultiWrite(imageOrIndexesToUpload, concurrency, updatesCh)
+}
+
+// WriteImage Upload Image to registry
+func (w *WithMirrors) WriteImage(reference regname.Reference, image regv1.Image, updatesCh chan regv1.Update) error {
+	return w.delegate.WriteImage(reference, image, updatesCh)
+}
+
+// WriteIndex Uploads the Index manifest to the registry
+func (w *WithMirrors) WriteIndex(reference regname.Reference, index regv1.ImageIndex) error {
+	return w.delegate.WriteIndex(reference, index)
+}
+
+// WriteTag Tag the referenced Image
+func (w *WithMirrors) WriteTag(tag regname.Tag, taggable remote.Taggable) error {
+	return w.delegate.WriteTag(tag, taggable)
+}
+
+// ListTags Retrieve all tags associated with a Repository
+func (w *WithMirrors) ListTags(repo regname.Repository) ([]string, error) {
+	return w.delegate.ListTags(repo)
+}
+
+// CloneWithSingleAuth Clones the provided registry replacing the Keychain with a Keychain that can only provide
+// credentials for the provided Image Reference
+func (w *WithMirrors) CloneWithSingleAuth(imageRef regname.Tag) (Registry, error) {
+	delegate, err := w.delegate.CloneWithSingleAuth(imageRef)
+	if err != nil {
+		return nil, err
+	}
+	return &WithMirrors{delegate: delegate, mirrors: w.mirrors, pullThrough: w.pullThrough, logger: w.logger, servedBy: w.servedBy}, nil
+}
+
+// CloneWithLogger Clones the provided registry updating the progress logger
+func (w *WithMirrors) CloneWithLogger(logger util.ProgressLogger) Registry {
+	return &WithMirrors{delegate: w.delegate.CloneWithLogger(logger), mirrors: w.mirrors, pullThrough: w.pullThrough, logger: w.logger, servedBy: w.servedBy}
+}
+
+// sources Returns the same reference in each one of the pull-through mirrors of its registry, followed by the
+// reference and the same reference in each one of the mirrors of its registry
+func (w *WithMirrors) sources(reference regname.Reference) []regname.Reference {
+	var sources []regname.Reference
+	sources = w.appendMirrorSources(sources, reference, w.pullThrough[reference.Context().RegistryStr()])
+	sources = append(sources, reference)
+	return w.appendMirrorSources(sources, reference, w.mirrors[reference.Context().RegistryStr()])
+}
+
+func (w *WithMirrors) appendMirrorSources(sources []regname.Reference, reference regname.Reference, mirrors []string) []regname.Reference {
+	for _, mirror := range mirrors {
+		repo, err := regname.NewRepository(mirror + "/" + reference.Context().RepositoryStr())
+		if err != nil {
+			w.logger.Debugf("skipping mirror '%s' of '%s': %s\n", mirror, reference.Name(), err)
+			continue
+		}
+		if digest, ok := reference.(regname.Digest); ok {
+			sources = append(sources, repo.Digest(digest.DigestStr()))
+		} else {
+			sources = append(sources, repo.Tag(reference.Identifier()))
+		}
+	}
+	return sources
+}
+
+// sourceFailed Logs the failure and returns the error to report when every source fails, the one of the registry
+// of the reference, so that callers can still check, for example, if the image was not found
+func (w *WithMirrors) sourceFailed(reference, source regname.Reference, originErr, err error) error {
+	registry := reference.Context().RegistryStr()
+	if len(w.mirrors[registry]) > 0 || len(w.pullThrough[registry]) > 0 {
+		w.logger.Debugf("retrieving '%s' from '%s' failed: %s\n", reference.Name(), source.Context().RegistryStr(), err)
+	}
+	if originErr == nil || source.Context().RegistryStr() == registry {
+		return err
+	}
+	return originErr
+}
+
+func (w *WithMirrors) recordSource(reference, source regname.Reference, digest regv1.Hash) {
+	if reference.Context().RegistryStr() == source.Context().RegistryStr() {
+		return
+	}
+	if w.isPullThrough(reference, source) {
+		w.logger.Debugf("retrieved '%s' from pull-through mirror '%s'\n", reference.Name(), source.Context().RegistryStr())
+	} else {
+		w.logger.Logf("retrieved '%s' from mirror '%s'\n", reference.Name(), source.Context().RegistryStr())
+	}
+
+	w.servedBy.lock.Lock()
+	defer w.servedBy.lock.Unlock()
+	w.servedBy.digests[digest.String()] = source.Context().RegistryStr()
+}
+
+func (w *WithMirrors) isPullThrough(reference, source regname.Reference) bool {
+	for _, mirror := range w.pullThrough[reference.Context().RegistryStr()] {
+		if mirror == source.Context().RegistryStr() {
+			return true
+		}
+	}
+	return false
+}
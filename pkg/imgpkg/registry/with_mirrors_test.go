@@ -0,0 +1,140 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"bytes"
+	"testing"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestNewMirrors(t *testing.T) {
+	t.Run("keeps the order of the mirrors of each origin", func(t *testing.T) {
+		mirrors, err := registry.NewMirrors([]string{"docker.io=mirror.gcr.io", "index.docker.io=mirror.internal:5000", "quay.io=quay-mirror.internal"})
+		require.NoError(t, err)
+		assert.Equal(t, registry.Mirrors{
+			"index.docker.io": {"mirror.gcr.io", "mirror.internal:5000"},
+			"quay.io":         {"quay-mirror.internal"},
+		}, mirrors)
+	})
+
+	t.Run("invalid mirrors", func(t *testing.T) {
+		for _, mirror := range []string{"docker.io", "=mirror.gcr.io", "docker.io=", "docker.io=mirror/gcr"} {
+			_, err := registry.NewMirrors([]string{mirror})
+			require.Error(t, err, mirror)
+		}
+	})
+}
+
+func TestWithMirrors(t *testing.T) {
+	originRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer originRegistry.CleanUp()
+	originImage := originRegistry.WithRandomImage("library/in-origin")
+
+	firstMirror := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer firstMirror.CleanUp()
+	firstMirror.Build()
+
+	secondMirror := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer secondMirror.CleanUp()
+	mirroredImage := secondMirror.WithRandomImage("library/only-in-mirror")
+	secondMirror.Build()
+
+	reg := originRegistry.Build()
+	mirrors := registry.Mirrors{originRegistry.Host(): {firstMirror.Host(), secondMirror.Host()}}
+
+	t.Run("retrieves the image from its registry when available", func(t *testing.T) {
+		output := bytes.NewBufferString("")
+		subject := registry.NewRegistryWithMirrors(reg, mirrors, util.NewUILevelLogger(util.LogWarn, util.NewBufferLogger(output)))
+
+		ref, err := regname.NewDigest(originImage.RefDigest)
+		require.NoError(t, err)
+		img, err := subject.Image(ref)
+		require.NoError(t, err)
+		digest, err := img.Digest()
+		require.NoError(t, err)
+		assert.Equal(t, originImage.Digest, digest.String())
+
+		_, found := subject.ServedBy(originImage.Digest)
+		assert.False(t, found)
+		assert.Empty(t, output.String())
+	})
+
+	t.Run("retrieves the image from the mirrors, in order, when its registry fails", func(t *testing.T) {
+		output := bytes.NewBufferString("")
+		subject := registry.NewRegistryWithMirrors(reg, mirrors, util.NewUILevelLogger(util.LogWarn, util.NewBufferLogger(output)))
+
+		ref, err := regname.NewDigest(originRegistry.ReferenceOnTestServer("library/only-in-mirror@" + mirroredImage.Digest))
+		require.NoError(t, err)
+		img, err := subject.Image(ref)
+		require.NoError(t, err)
+		digest, err := img.Digest()
+		require.NoError(t, err)
+		assert.Equal(t, mirroredImage.Digest, digest.String())
+
+		desc, err := subject.Get(ref)
+		require.NoError(t, err)
+		assert.Equal(t, mirroredImage.Digest, desc.Digest.String())
+
+		mirror, found := subject.ServedBy(mirroredImage.Digest)
+		require.True(t, found)
+		assert.Equal(t, secondMirror.Host(), mirror)
+		assert.Contains(t, output.String(), "from mirror '"+secondMirror.Host()+"'")
+	})
+
+	t.Run("returns the error of the registry of the image when every mirror fails", func(t *testing.T) {
+		subject := registry.NewRegistryWithMirrors(reg, mirrors, util.NewNoopLevelLogger())
+
+		ref, err := regname.NewTag(originRegistry.ReferenceOnTestServer("library/missing:v1"))
+		require.NoError(t, err)
+		_, err = subject.Digest(ref)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), originRegistry.Host())
+	})
+
+	t.Run("retrieves the image from the pull-through mirrors, in order, before its registry", func(t *testing.T) {
+		pullThroughRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer pullThroughRegistry.CleanUp()
+		cachedImage := pullThroughRegistry.CopyImage(*originImage, "library/in-origin")
+		pullThroughRegistry.Build()
+
+		subject := registry.NewRegistryWithMirrors(reg, nil, util.NewNoopLevelLogger()).
+			WithPullThroughMirrors(registry.Mirrors{originRegistry.Host(): {firstMirror.Host(), pullThroughRegistry.Host()}})
+
+		ref, err := regname.NewDigest(originImage.RefDigest)
+		require.NoError(t, err)
+		desc, err := subject.Get(ref)
+		require.NoError(t, err)
+		assert.Equal(t, cachedImage.Digest, desc.Digest.String())
+
+		mirror, found := subject.ServedBy(originImage.Digest)
+		require.True(t, found)
+		assert.Equal(t, pullThroughRegistry.Host(), mirror)
+	})
+
+	t.Run("falls back to its registry when every pull-through mirror fails, returning the error of the registry", func(t *testing.T) {
+		subject := registry.NewRegistryWithMirrors(reg, nil, util.NewNoopLevelLogger()).
+			WithPullThroughMirrors(registry.Mirrors{originRegistry.Host(): {firstMirror.Host()}})
+
+		ref, err := regname.NewDigest(originImage.RefDigest)
+		require.NoError(t, err)
+		digest, err := subject.Digest(ref)
+		require.NoError(t, err)
+		assert.Equal(t, originImage.Digest, digest.String())
+		_, found := subject.ServedBy(originImage.Digest)
+		assert.False(t, found)
+
+		missingRef, err := regname.NewTag(originRegistry.ReferenceOnTestServer("library/missing:v1"))
+		require.NoError(t, err)
+		_, err = subject.Digest(missingRef)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), originRegistry.Host())
+	})
+}
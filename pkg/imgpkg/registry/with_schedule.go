@@ -0,0 +1,247 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+)
+
+var _ Registry = &WithSchedule{}
+
+// TransferWindow Time of the day, in local time, when images can be uploaded. The window can cross midnight
+type TransferWindow struct {
+	start time.Duration
+	end   time.Duration
+}
+
+// NewTransferWindow Parses a window in the format HH:MM-HH:MM, e.g. 22:00-06:00
+func NewTransferWindow(window string) (TransferWindow, error) {
+	startStr, endStr, found := strings.Cut(window, "-")
+	if !found {
+		return TransferWindow{}, fmt.Errorf("Expected transfer window '%s' to be in the format HH:MM-HH:MM", window)
+	}
+
+	start, err := parseTimeOfDay(startStr)
+	if err != nil {
+		return TransferWindow{}, fmt.Errorf("Parsing start of transfer window '%s': %s", window, err)
+	}
+	end, err := parseTimeOfDay(endStr)
+	if err != nil {
+		return TransferWindow{}, fmt.Errorf("Parsing end of transfer window '%s': %s", window, err)
+	}
+	if start == end {
+		return TransferWindow{}, fmt.Errorf("Expected transfer window '%s' to start and end at different times", window)
+	}
+
+	return TransferWindow{start: start, end: end}, nil
+}
+
+// Contains Checks if t is inside the window
+func (w TransferWindow) Contains(t time.Time) bool {
+	timeOfDay := sinceMidnight(t)
+	if w.start < w.end {
+		return timeOfDay >= w.start && timeOfDay < w.end
+	}
+	return timeOfDay >= w.start || timeOfDay < w.end
+}
+
+// NextStart Returns the next time, after t, when the window opens
+func (w TransferWindow) NextStart(t time.Time) time.Time {
+	midnight := t.Add(-sinceMidnight(t))
+	next := midnight.Add(w.start)
+	if !next.After(t) {
+		next = midnight.AddDate(0, 0, 1).Add(w.start)
+	}
+	return next
+}
+
+// String Returns the window in the format HH:MM-HH:MM
+func (w TransferWindow) String() string {
+	format := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return format(w.start) + "-" + format(w.end)
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	hoursStr, minutesStr, found := strings.Cut(strings.TrimSpace(value), ":")
+	if !found {
+		return 0, fmt.Errorf("Expected '%s' to be in the format HH:MM", value)
+	}
+	hours, err := strconv.Atoi(hoursStr)
+	if err != nil || hours < 0 || hours > 23 {
+		return 0, fmt.Errorf("Expected hours of '%s' to be between 00 and 23", value)
+	}
+	minutes, err := strconv.Atoi(minutesStr)
+	if err != nil || minutes < 0 || minutes > 59 {
+		return 0, fmt.Errorf("Expected minutes of '%s' to be between 00 and 59", value)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+}
+
+// TransferSchedule When images can be uploaded
+type TransferSchedule struct {
+	// Window when provided, uploads only start inside of it
+	Window *TransferWindow
+	// PauseBetweenImages Time waited after uploading an image before uploading the next one
+	PauseBetweenImages time.Duration
+}
+
+// ScheduleLogger Logger used to report when the uploads are waiting
+type ScheduleLogger interface {
+	Logf(msg string, args ...interface{})
+}
+
+// NewRegistryWithSchedule Creates a Registry that uploads images one at a time, following the schedule
+func NewRegistryWithSchedule(reg Registry, schedule TransferSchedule, logger ScheduleLogger) *WithSchedule {
+	return &WithSchedule{delegate: reg, schedule: schedule, logger: logger, now: time.Now, sleep: time.Sleep}
+}
+
+// WithSchedule Implements Registry interface and only uploads images when the schedule allows it
+type WithSchedule struct {
+	delegate Registry
+	schedule TransferSchedule
+	logger   ScheduleLogger
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// Get Retrieve Image descriptor for an Image reference
+func (w *WithSchedule) Get(reference regname.Reference) (*remote.Descriptor, error) {
+	return w.delegate.Get(reference)
+}
+
+// Digest Retrieve the Digest for an Image reference
+func (w *WithSchedule) Digest(reference regname.Reference) (regv1.Hash, error) {
+	return w.delegate.Digest(reference)
+}
+
+// Index Retrieve regv1.ImageIndex struct for an Index reference
+func (w *WithSchedule) Index(reference regname.Reference) (regv1.ImageIndex, error) {
+	return w.delegate.Index(reference)
+}
+
+// Image Retrieve the regv1.Image struct for an Image reference
+func (w *WithSchedule) Image(reference regname.Reference) (regv1.Image, error) {
+	return w.delegate.Image(reference)
+}
+
+// FirstImageExists Returns the first of the provided Image Digests that exists in the Registry
+func (w *WithSchedule) FirstImageExists(digests []string) (string, error) {
+	return w.delegate.FirstImageExists(digests)
+}
+
+// MultiWrite Uploads the Images one at a time, waiting for the transfer window and pausing between them
+func (w *WithSchedule) MultiWrite(imageOrIndexesToUpload map[regname.Reference]remote.Taggable, concurrency int, updatesCh chan regv1.Update) error {
+	if updatesCh != nil {
+		defer close(updatesCh)
+	}
+
+	var refs []regname.Reference
+	for ref := range imageOrIndexesToUpload {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name() < refs[j].Name() })
+
+	for i, ref := range refs {
+		if i > 0 && w.schedule.PauseBetweenImages > 0 {
+			w.logger.Logf("pausing %s before uploading the next image\n", w.schedule.PauseBetweenImages)
+			w.sleep(w.schedule.PauseBetweenImages)
+		}
+		w.waitForWindow()
+
+		// The delegate closes the channel it receives, so each upload gets its own
+		var imageUpdatesCh chan regv1.Update
+		forwarded := make(chan struct{})
+		if updatesCh != nil {
+			imageUpdatesCh = make(chan regv1.Update)
+			go func() {
+				defer close(forwarded)
+				for update := range imageUpdatesCh {
+					updatesCh <- update
+				}
+			}()
+		} else {
+			close(forwarded)
+		}
+
+		err := w.delegate.MultiWrite(map[regname.Reference]remote.Taggable{ref: imageOrIndexesToUpload[ref]}, concurrency, imageUpdatesCh)
+		<-forwarded
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteImage Upload Image to registry, waiting for the transfer window
+func (w *WithSchedule) WriteImage(reference regname.Reference, image regv1.Image, updatesCh chan regv1.Update) error {
+	w.waitForWindow()
+	return w.delegate.WriteImage(reference, image, updatesCh)
+}
+
+// WriteIndex Uploads the Index manifest to the registry
+func (w *WithSchedule) WriteIndex(reference regname.Reference, index regv1.ImageIndex) error {
+	return w.delegate.WriteIndex(reference, index)
+}
+
+// WriteTag Tag the referenced Image
+func (w *WithSchedule) WriteTag(tag regname.Tag, taggable remote.Taggable) error {
+	return w.delegate.WriteTag(tag, taggable)
+}
+
+// ListTags Retrieve all tags associated with a Repository
+func (w *WithSchedule) ListTags(repo regname.Repository) ([]string, error) {
+	return w.delegate.ListTags(repo)
+}
+
+// CloneWithSingleAuth Clones the provided registry replacing the Keychain with a Keychain that can only authenticate
+// the image provided
+func (w WithSchedule) CloneWithSingleAuth(imageRef regname.Tag) (Registry, error) {
+	delegate, err := w.delegate.CloneWithSingleAuth(imageRef)
+	if err != nil {
+		return nil, err
+	}
+
+	w.delegate = delegate
+	return &w, nil
+}
+
+// CloneWithLogger Clones the provided registry updating the progress
+func (w WithSchedule) CloneWithLogger(logger util.ProgressLogger) Registry {
+	w.delegate = w.delegate.CloneWithLogger(logger)
+	return &w
+}
+
+// waitForWindow Sleeps until the transfer window opens, when outside of it
+func (w *WithSchedule) waitForWindow() {
+	if w.schedule.Window == nil {
+		return
+	}
+
+	now := w.now()
+	if w.schedule.Window.Contains(now) {
+		return
+	}
+
+	nextStart := w.schedule.Window.NextStart(now)
+	w.logger.Logf("outside of the transfer window %s, waiting until %s to continue uploading\n",
+		w.schedule.Window, nextStart.Format(time.RFC1123))
+	w.sleep(nextStart.Sub(now))
+}
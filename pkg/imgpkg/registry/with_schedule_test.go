@@ -0,0 +1,95 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestNewTransferWindow(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2023, 5, 10, hour, minute, 0, 0, time.Local)
+	}
+
+	t.Run("window in the same day", func(t *testing.T) {
+		window, err := registry.NewTransferWindow("09:30-17:00")
+		require.NoError(t, err)
+		assert.Equal(t, "09:30-17:00", window.String())
+
+		assert.False(t, window.Contains(at(9, 29)))
+		assert.True(t, window.Contains(at(9, 30)))
+		assert.True(t, window.Contains(at(16, 59)))
+		assert.False(t, window.Contains(at(17, 0)))
+
+		assert.Equal(t, at(9, 30), window.NextStart(at(8, 0)))
+		assert.Equal(t, at(9, 30).AddDate(0, 0, 1), window.NextStart(at(18, 0)))
+	})
+
+	t.Run("window that crosses midnight", func(t *testing.T) {
+		window, err := registry.NewTransferWindow("22:00-06:00")
+		require.NoError(t, err)
+
+		assert.True(t, window.Contains(at(23, 0)))
+		assert.True(t, window.Contains(at(5, 59)))
+		assert.False(t, window.Contains(at(6, 0)))
+		assert.False(t, window.Contains(at(12, 0)))
+
+		assert.Equal(t, at(22, 0), window.NextStart(at(12, 0)))
+	})
+
+	t.Run("invalid windows", func(t *testing.T) {
+		for _, window := range []string{"22:00", "24:00-06:00", "22:60-06:00", "22-06", "06:00-06:00"} {
+			_, err := registry.NewTransferWindow(window)
+			require.Error(t, err, window)
+		}
+	})
+}
+
+func TestWithSchedule_MultiWrite(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	reg := fakeRegistry.Build()
+
+	now := time.Now()
+	window, err := registry.NewTransferWindow(fmt.Sprintf("%02d:00-%02d:00", now.Add(-time.Hour).Hour(), now.Add(2*time.Hour).Hour()))
+	require.NoError(t, err)
+
+	output := bytes.NewBufferString("")
+	subject := registry.NewRegistryWithSchedule(reg, registry.TransferSchedule{Window: &window, PauseBetweenImages: time.Millisecond},
+		util.NewUILevelLogger(util.LogWarn, util.NewBufferLogger(output)))
+
+	images := map[regname.Reference]remote.Taggable{}
+	for i := 0; i < 3; i++ {
+		img, err := random.Image(100, 1)
+		require.NoError(t, err)
+		ref, err := regname.NewTag(fakeRegistry.ReferenceOnTestServer(fmt.Sprintf("scheduled/image:%d", i)))
+		require.NoError(t, err)
+		images[ref] = img
+	}
+
+	require.NoError(t, subject.MultiWrite(images, 2, nil))
+
+	for ref, img := range images {
+		expectedDigest, err := img.(regv1.Image).Digest()
+		require.NoError(t, err)
+		digest, err := reg.Digest(ref)
+		require.NoError(t, err)
+		assert.Equal(t, expectedDigest, digest)
+	}
+	assert.Equal(t, 2, bytes.Count(output.Bytes(), []byte("pausing")))
+	assert.NotContains(t, output.String(), "outside of the transfer window")
+}
@@ -6,6 +6,7 @@ package signature
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	regname "github.com/google/go-containerregistry/pkg/name"
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
@@ -19,14 +20,62 @@ type DigestReader interface {
 	Digest(reference regname.Reference) (regv1.Hash, error)
 }
 
+const (
+	cosignSignatureSuffix   = "sig"
+	cosignAttestationSuffix = "att"
+	cosignSBOMSuffix        = "sbom"
+)
+
+// Types of the artifacts cosign attaches to an image
+const (
+	CosignSignatureType   = "signature"
+	CosignAttestationType = "attestation"
+	CosignSBOMType        = "sbom"
+)
+
+var cosignArtifactTypes = map[string]string{
+	cosignSignatureSuffix:   CosignSignatureType,
+	cosignAttestationSuffix: CosignAttestationType,
+	cosignSBOMSuffix:        CosignSBOMType,
+}
+
+// CosignArtifactFromTag Returns the digest of the image the cosign artifact is attached to, and the type of the artifact,
+// from the tag cosign stores the artifact in (e.g. sha256-<hex>.sig). Returns false when the tag is not a cosign tag
+func CosignArtifactFromTag(tag string) (regv1.Hash, string, bool) {
+	dot := strings.LastIndex(tag, ".")
+	if dot == -1 {
+		return regv1.Hash{}, "", false
+	}
+	artifactType, found := cosignArtifactTypes[tag[dot+1:]]
+	if !found {
+		return regv1.Hash{}, "", false
+	}
+	digest, err := regv1.NewHash(strings.Replace(tag[:dot], "-", ":", 1))
+	if err != nil {
+		return regv1.Hash{}, "", false
+	}
+	return digest, artifactType, true
+}
+
 // Cosign Signature retriever
 type Cosign struct {
 	registry DigestReader
+	suffix   string
 }
 
 // NewCosign constructor for Signature retriever
 func NewCosign(reg DigestReader) *Cosign {
-	return &Cosign{registry: reg}
+	return &Cosign{registry: reg, suffix: cosignSignatureSuffix}
+}
+
+// NewCosignAttestation constructor for the retriever of the attestations cosign attaches to an image, in the .att tag
+func NewCosignAttestation(reg DigestReader) *Cosign {
+	return &Cosign{registry: reg, suffix: cosignAttestationSuffix}
+}
+
+// NewCosignSBOM constructor for the retriever of the SBOMs cosign attaches to an image, in the .sbom tag
+func NewCosignSBOM(reg DigestReader) *Cosign {
+	return &Cosign{registry: reg, suffix: cosignSBOMSuffix}
 }
 
 // Signature retrieves the Image information that contains the signature for the provided Image
@@ -60,5 +109,7 @@ func (c Cosign) signatureTag(reference regname.Digest) (regname.Tag, error) {
 	if err != nil {
 		return regname.Tag{}, fmt.Errorf("Converting to hash: %s", err)
 	}
-	return regname.NewTag(reference.Repository.Name() + ":" + cosign.Munge(regv1.Descriptor{Digest: digest}))
+	// cosign names the tags of all the artifacts of an image like the signature tag, changing only the suffix
+	tag := strings.TrimSuffix(cosign.Munge(regv1.Descriptor{Digest: digest}), "."+cosignSignatureSuffix) + "." + c.suffix
+	return regname.NewTag(reference.Repository.Name() + ":" + tag)
 }
@@ -9,6 +9,8 @@ import (
 	"testing"
 
 	"github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/signature"
@@ -34,6 +36,32 @@ func TestCosign_Signature(t *testing.T) {
 		assert.Equal(t, signatureTag, signature.Tag)
 	})
 
+	t.Run("it returns the attestation and the SBOM when they can be found", func(t *testing.T) {
+		logger := &helpers.Logger{}
+		regBuilder := helpers.NewFakeRegistry(t, logger)
+		img := regBuilder.WithRandomImage("some-image")
+		imgDigestHex := strings.Split(img.Digest, ":")[1]
+		attImg := regBuilder.WithImage(fmt.Sprintf("some-image:sha256-%s.att", imgDigestHex), randomImage(t))
+		sbomImg := regBuilder.WithImage(fmt.Sprintf("some-image:sha256-%s.sbom", imgDigestHex), randomImage(t))
+		reg := regBuilder.Build()
+		defer regBuilder.CleanUp()
+
+		imgDigest, err := name.NewDigest(img.RefDigest)
+		require.NoError(t, err)
+		for _, tc := range []struct {
+			subject  *signature.Cosign
+			artifact *helpers.ImageOrImageIndexWithTarPath
+		}{
+			{signature.NewCosignAttestation(reg), attImg},
+			{signature.NewCosignSBOM(reg), sbomImg},
+		} {
+			artifact, err := tc.subject.Signature(imgDigest)
+			require.NoError(t, err)
+			assert.Equal(t, imgDigest.Context().Digest(tc.artifact.Digest).Name(), artifact.DigestRef)
+			assert.Equal(t, tc.artifact.Tag, artifact.Tag)
+		}
+	})
+
 	t.Run("it returns sign.NotFound when image with the signature tag cannot be found", func(t *testing.T) {
 		logger := &helpers.Logger{}
 		regBuilder := helpers.NewFakeRegistry(t, logger)
@@ -52,3 +80,9 @@ func TestCosign_Signature(t *testing.T) {
 		require.True(t, ok)
 	})
 }
+
+func randomImage(t *testing.T) regv1.Image {
+	img, err := random.Image(500, 1)
+	require.NoError(t, err)
+	return img
+}
@@ -81,18 +81,24 @@ func (f *FetchError) Add(err FetchingError) {
 
 // Signatures Signature fetcher
 type Signatures struct {
-	signatureFinder Finder
-	concurrency     int
+	signatureFinders []Finder
+	concurrency      int
 }
 
 // NewSignatures constructs the Signature Fetcher
 func NewSignatures(finder Finder, concurrency int) *Signatures {
 	return &Signatures{
-		signatureFinder: finder,
-		concurrency:     concurrency,
+		signatureFinders: []Finder{finder},
+		concurrency:      concurrency,
 	}
 }
 
+// WithFinder Adds a Finder used to look for other artifacts attached to each image, e.g. cosign attestations
+func (s *Signatures) WithFinder(finder Finder) *Signatures {
+	s.signatureFinders = append(s.signatureFinders, finder)
+	return s
+}
+
 // Fetch Retrieve the available signatures associated with the images provided
 func (s *Signatures) Fetch(images *imageset.UnprocessedImageRefs) (*imageset.UnprocessedImageRefs, error) {
 	signatures := imageset.NewUnprocessedImageRefs()
@@ -136,26 +142,28 @@ func (s *Signatures) FetchForImageRefs(images []lockconfig.ImageRef) ([]lockconf
 			throttle.Take()
 			defer throttle.Done()
 
-			signature, err := s.signatureFinder.Signature(imgDigest)
-			if err != nil {
-				if _, ok := err.(NotFoundErr); ok {
-					return nil
+			for _, finder := range s.signatureFinders {
+				signature, err := finder.Signature(imgDigest)
+				if err != nil {
+					if _, ok := err.(NotFoundErr); ok {
+						continue
+					}
+					if deniedErr, ok := err.(AccessDeniedErr); ok {
+						lock.Lock()
+						allErrs.Add(deniedErr)
+						lock.Unlock()
+						continue
+					}
+					return fmt.Errorf("Fetching signature for image '%s': %s", imgDigest.Name(), err)
 				}
-				if deniedErr, ok := err.(AccessDeniedErr); ok {
-					lock.Lock()
-					defer lock.Unlock()
-					allErrs.Add(deniedErr)
-					return nil
-				}
-				return fmt.Errorf("Fetching signature for image '%s': %s", imgDigest.Name(), err)
-			}
 
-			lock.Lock()
-			signatures = append(signatures, lockconfig.ImageRef{
-				Image:       signature.DigestRef,
-				Annotations: map[string]string{"tag": signature.Tag},
-			})
-			lock.Unlock()
+				lock.Lock()
+				signatures = append(signatures, lockconfig.ImageRef{
+					Image:       signature.DigestRef,
+					Annotations: map[string]string{"tag": signature.Tag},
+				})
+				lock.Unlock()
+			}
 			return nil
 		})
 	}
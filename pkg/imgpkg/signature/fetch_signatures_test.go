@@ -45,6 +45,27 @@ func TestSignatureRetriever_Signatures(t *testing.T) {
 		assert.Equal(t, imageset.UnprocessedImageRef{DigestRef: "registry.io/img@sha256:cf31af331f38d1d7158470e095b132acd126a7180a54f263d386da88eb681d93", Tag: "some-tag"}, sign2)
 	})
 
+	t.Run("it adds the artifacts found by every finder", func(t *testing.T) {
+		fakeSignatureFinder := &signaturefakes.FakeFinder{}
+		fakeSignatureFinder.SignatureReturns(imageset.UnprocessedImageRef{DigestRef: "registry.io/img@sha256:cf31af331f38d1d7158470e095b132acd126a7180a54f263d386da88eb681d93", Tag: "sha256-4c8b96d4fffdfae29258d94a22ae4ad1fe36139d47288b8960d9958d1e63a9d0.sig"}, nil)
+		fakeAttestationFinder := &signaturefakes.FakeFinder{}
+		fakeAttestationFinder.SignatureReturns(imageset.UnprocessedImageRef{DigestRef: "registry.io/img@sha256:be154cc2b1211a9f98f4d708f4266650c9129784d0485d4507d9b0fa05d928b6", Tag: "sha256-4c8b96d4fffdfae29258d94a22ae4ad1fe36139d47288b8960d9958d1e63a9d0.att"}, nil)
+		fakeSBOMFinder := &signaturefakes.FakeFinder{}
+		fakeSBOMFinder.SignatureReturns(imageset.UnprocessedImageRef{}, signature.NotFoundErr{})
+		subject := signature.NewSignatures(fakeSignatureFinder, 2).WithFinder(fakeAttestationFinder).WithFinder(fakeSBOMFinder)
+
+		args := imageset.NewUnprocessedImageRefs()
+		args.Add(imageset.UnprocessedImageRef{DigestRef: "registry.io/img@sha256:4c8b96d4fffdfae29258d94a22ae4ad1fe36139d47288b8960d9958d1e63a9d0"})
+		signatures, err := subject.Fetch(args)
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []imageset.UnprocessedImageRef{
+			{DigestRef: "registry.io/img@sha256:cf31af331f38d1d7158470e095b132acd126a7180a54f263d386da88eb681d93", Tag: "sha256-4c8b96d4fffdfae29258d94a22ae4ad1fe36139d47288b8960d9958d1e63a9d0.sig"},
+			{DigestRef: "registry.io/img@sha256:be154cc2b1211a9f98f4d708f4266650c9129784d0485d4507d9b0fa05d928b6", Tag: "sha256-4c8b96d4fffdfae29258d94a22ae4ad1fe36139d47288b8960d9958d1e63a9d0.att"},
+		}, signatures.All())
+		assert.Equal(t, 1, fakeSBOMFinder.SignatureCallCount())
+	})
+
 	t.Run("denied errors are provided as part of the error", func(t *testing.T) {
 		fakeSignatureFinder := &signaturefakes.FakeFinder{}
 		subject := signature.NewSignatures(fakeSignatureFinder, 2)
@@ -0,0 +1,229 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/sigstore/sigstore/pkg/signature/kms"
+	"github.com/sigstore/sigstore/pkg/signature/options"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/filelock"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	TrustConfigKind       = "TrustConfig"
+	TrustConfigAPIVersion = "imgpkg.carvel.dev/v1alpha1"
+)
+
+// TrustMode How a failed signature verification is handled
+type TrustMode string
+
+const (
+	// TrustModeEnforce Fail when the image is not signed by one of the keys
+	TrustModeEnforce TrustMode = "enforce"
+	// TrustModeWarn Only warn when the image is not signed by one of the keys
+	TrustModeWarn TrustMode = "warn"
+)
+
+// TrustConfig Public keys that must have signed, using cosign, the images coming from each origin
+type TrustConfig struct {
+	APIVersion string        `json:"apiVersion"` // This generated yaml, but due to lib we need to use `json`
+	Kind       string        `json:"kind"`       // This generated yaml, but due to lib we need to use `json`
+	Origins    []TrustOrigin `json:"origins"`    // This generated yaml, but due to lib we need to use `json`
+}
+
+// TrustOrigin Verification required for the images of a registry or repository
+type TrustOrigin struct {
+	// Repository Registry (e.g. index.docker.io) or repository (e.g. index.docker.io/org) the images come from,
+	// images in nested repositories are also matched
+	Repository string `json:"repository"`
+	// PublicKeys Paths, relative to the configuration file, to PEM encoded public keys
+	PublicKeys []string `json:"publicKeys"`
+	// Mode One of enforce or warn, defaults to enforce
+	Mode TrustMode `json:"mode,omitempty"`
+
+	keys            []crypto.PublicKey
+	allRepositories bool
+}
+
+// NewTrustConfigFromPath Reads the TrustConfig and the public keys it references
+func NewTrustConfigFromPath(path string) (TrustConfig, error) {
+	bs, err := filelock.ReadFile(path)
+	if err != nil {
+		return TrustConfig{}, fmt.Errorf("Reading path %s: %s", path, err)
+	}
+
+	return NewTrustConfigFromBytes(bs, filepath.Dir(path))
+}
+
+// NewTrustConfigFromBytes Parses the TrustConfig, the paths of the public keys are relative to keysDir
+func NewTrustConfigFromBytes(data []byte, keysDir string) (TrustConfig, error) {
+	var config TrustConfig
+
+	err := yaml.UnmarshalStrict(data, &config)
+	if err != nil {
+		return config, fmt.Errorf("Unmarshaling trust config: %s", err)
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return config, fmt.Errorf("Validating trust config: %s", err)
+	}
+
+	for i, origin := range config.Origins {
+		for _, keyPath := range origin.PublicKeys {
+			if !filepath.IsAbs(keyPath) {
+				keyPath = filepath.Join(keysDir, keyPath)
+			}
+			key, err := readPublicKey(keyPath)
+			if err != nil {
+				return config, fmt.Errorf("Reading public key for '%s': %s", origin.Repository, err)
+			}
+			config.Origins[i].keys = append(config.Origins[i].keys, key)
+		}
+	}
+
+	return config, nil
+}
+
+// NewTrustConfigForKeys Builds a TrustConfig that requires the images of every origin to be signed by one of the
+// PEM encoded public keys in keyPaths
+func NewTrustConfigForKeys(keyPaths []string) (TrustConfig, error) {
+	return NewTrustConfigForKeysWithContext(context.Background(), keyPaths)
+}
+
+// NewTrustConfigForKeysWithContext Same as NewTrustConfigForKeys, keyRefs can also reference keys in a KMS (e.g. awskms://, gcpkms://),
+// whose public key is retrieved using ctx. The providers of the KMS need to be registered with kms.AddProvider,
+// usually by importing their package (e.g. github.com/sigstore/sigstore/pkg/signature/kms/aws)
+func NewTrustConfigForKeysWithContext(ctx context.Context, keyRefs []string) (TrustConfig, error) {
+	origin := TrustOrigin{PublicKeys: keyRefs, Mode: TrustModeEnforce, allRepositories: true}
+	for _, keyRef := range keyRefs {
+		var key crypto.PublicKey
+		var err error
+		if isKMSKeyRef(keyRef) {
+			key, err = retrieveKMSPublicKey(ctx, keyRef)
+		} else {
+			key, err = readPublicKey(keyRef)
+		}
+		if err != nil {
+			return TrustConfig{}, fmt.Errorf("Reading public key: %s", err)
+		}
+		origin.keys = append(origin.keys, key)
+	}
+
+	return TrustConfig{APIVersion: TrustConfigAPIVersion, Kind: TrustConfigKind, Origins: []TrustOrigin{origin}}, nil
+}
+
+// Validate Checks the TrustConfig is well formed
+func (c TrustConfig) Validate() error {
+	if c.APIVersion != TrustConfigAPIVersion {
+		return fmt.Errorf("Validating apiVersion: Unknown version (known: %s)", TrustConfigAPIVersion)
+	}
+	if c.Kind != TrustConfigKind {
+		return fmt.Errorf("Validating kind: Unknown kind (known: %s)", TrustConfigKind)
+	}
+	for _, origin := range c.Origins {
+		if origin.Repository == "" {
+			return fmt.Errorf("Expected repository of origin to be provided")
+		}
+		if len(origin.PublicKeys) == 0 {
+			return fmt.Errorf("Expected at least one public key for '%s'", origin.Repository)
+		}
+		switch origin.Mode {
+		case "", TrustModeEnforce, TrustModeWarn:
+		default:
+			return fmt.Errorf("Expected mode of '%s' to be one of %s or %s, got '%s'", origin.Repository, TrustModeEnforce, TrustModeWarn, origin.Mode)
+		}
+	}
+	return nil
+}
+
+// OriginFor Returns the most specific origin that matches the repository of the image
+func (c TrustConfig) OriginFor(ref regname.Reference) (TrustOrigin, bool) {
+	var found TrustOrigin
+	foundLen := -1
+	for _, origin := range c.Origins {
+		originRepo, matches := origin.matches(ref.Context())
+		if matches && len(originRepo) > foundLen {
+			found = origin
+			foundLen = len(originRepo)
+		}
+	}
+	return found, foundLen >= 0
+}
+
+// matches Checks if the repository is the origin or is nested in it, returns the normalized origin
+func (o TrustOrigin) matches(repo regname.Repository) (string, bool) {
+	if o.allRepositories {
+		return "", true
+	}
+
+	// The origin is not parsed as a repository to avoid the library/ prefix added to Docker Hub repositories
+	registryHost, path, _ := strings.Cut(strings.TrimSuffix(o.Repository, "/"), "/")
+	registry, err := regname.NewRegistry(registryHost)
+	if err != nil {
+		return "", false
+	}
+	if path == "" {
+		return registry.RegistryStr(), registry.RegistryStr() == repo.RegistryStr()
+	}
+
+	originRepo := registry.RegistryStr() + "/" + path
+	repoName := repo.RegistryStr() + "/" + repo.RepositoryStr()
+	return originRepo, repoName == originRepo || strings.HasPrefix(repoName, originRepo+"/")
+}
+
+// EffectiveMode Returns the mode of the origin, defaulting to enforce
+func (o TrustOrigin) EffectiveMode() TrustMode {
+	if o.Mode == "" {
+		return TrustModeEnforce
+	}
+	return o.Mode
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(bs)
+	if block == nil {
+		return nil, fmt.Errorf("Expected '%s' to contain a PEM encoded public key", path)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing public key '%s': %s", path, err)
+	}
+	return key, nil
+}
+
+// isKMSKeyRef Checks if the key is a reference to a key in a KMS (e.g. awskms://) instead of a path
+func isKMSKeyRef(keyRef string) bool {
+	return strings.Contains(keyRef, "://")
+}
+
+// retrieveKMSPublicKey Retrieves the public key of the key in the KMS, the hash function is not used to verify signatures
+func retrieveKMSPublicKey(ctx context.Context, keyRef string) (crypto.PublicKey, error) {
+	signerVerifier, err := kms.Get(ctx, keyRef, crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("Loading key '%s' from KMS: %s", keyRef, err)
+	}
+
+	key, err := signerVerifier.PublicKey(options.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("Retrieving public key of '%s' from KMS: %s", keyRef, err)
+	}
+	return key, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/signature"
+)
+
+func TestNewTrustConfigFromPath(t *testing.T) {
+	t.Run("reads the public keys relative to the configuration file", func(t *testing.T) {
+		dir := t.TempDir()
+		writePublicKey(t, generateKey(t), filepath.Join(dir, "cosign.pub"))
+		configPath := filepath.Join(dir, "trust.yml")
+		require.NoError(t, os.WriteFile(configPath, []byte(`
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: TrustConfig
+origins:
+- repository: registry.example.com/org
+  publicKeys: [cosign.pub]
+  mode: warn
+`), 0600))
+
+		config, err := signature.NewTrustConfigFromPath(configPath)
+		require.NoError(t, err)
+		require.Len(t, config.Origins, 1)
+		assert.Equal(t, signature.TrustModeWarn, config.Origins[0].EffectiveMode())
+	})
+
+	t.Run("fails when a public key cannot be read", func(t *testing.T) {
+		dir := t.TempDir()
+		configPath := filepath.Join(dir, "trust.yml")
+		require.NoError(t, os.WriteFile(configPath, []byte(`
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: TrustConfig
+origins:
+- repository: registry.example.com/org
+  publicKeys: [missing.pub]
+`), 0600))
+
+		_, err := signature.NewTrustConfigFromPath(configPath)
+		require.ErrorContains(t, err, "Reading public key for 'registry.example.com/org'")
+	})
+
+	t.Run("fails when the mode is unknown", func(t *testing.T) {
+		_, err := signature.NewTrustConfigFromBytes([]byte(`
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: TrustConfig
+origins:
+- repository: registry.example.com/org
+  publicKeys: [cosign.pub]
+  mode: audit
+`), t.TempDir())
+		require.ErrorContains(t, err, "Expected mode of 'registry.example.com/org' to be one of enforce or warn, got 'audit'")
+	})
+}
+
+func TestTrustConfig_OriginFor(t *testing.T) {
+	config := signature.TrustConfig{
+		Origins: []signature.TrustOrigin{
+			{Repository: "registry.example.com", Mode: signature.TrustModeWarn},
+			{Repository: "registry.example.com/org/team"},
+			{Repository: "docker.io/org"},
+		},
+	}
+
+	for _, test := range []struct {
+		ref        string
+		repository string
+	}{
+		{ref: "registry.example.com/other/app", repository: "registry.example.com"},
+		{ref: "registry.example.com/org/team/app", repository: "registry.example.com/org/team"},
+		{ref: "registry.example.com/org/teams/app", repository: "registry.example.com"},
+		{ref: "org/app", repository: "docker.io/org"},
+	} {
+		ref, err := name.ParseReference(test.ref)
+		require.NoError(t, err)
+
+		origin, found := config.OriginFor(ref)
+		require.True(t, found, test.ref)
+		assert.Equal(t, test.repository, origin.Repository, test.ref)
+	}
+
+	ref, err := name.ParseReference("other.example.com/org/app")
+	require.NoError(t, err)
+	_, found := config.OriginFor(ref)
+	require.False(t, found)
+}
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func writePublicKey(t *testing.T, key *ecdsa.PrivateKey, path string) {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+}
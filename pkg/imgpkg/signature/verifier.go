@@ -0,0 +1,270 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/signature/cosign"
+)
+
+// cosignSignatureAnnotation Annotation of the layers of the signature image that holds the signature of the layer
+const cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+// ImageReader Interface that knows how to read images from a registry
+type ImageReader interface {
+	DigestReader
+	Image(reference regname.Reference) (regv1.Image, error)
+}
+
+// Logger Interface used to report the verifications that failed without failing the operation
+type Logger interface {
+	Warnf(msg string, args ...interface{})
+}
+
+// Verifier Checks that images are signed, using cosign, by the keys the TrustConfig requires for their origin
+type Verifier struct {
+	config   TrustConfig
+	registry ImageReader
+	logger   Logger
+}
+
+// NewVerifier Constructs a Verifier
+func NewVerifier(config TrustConfig, reg ImageReader, logger Logger) Verifier {
+	return Verifier{config: config, registry: reg, logger: logger}
+}
+
+// VerifyImages Verifies all the images, cosign signatures, attestations and SBOMs are skipped
+func (v Verifier) VerifyImages(images *imageset.UnprocessedImageRefs) error {
+	var imageRefs []regname.Digest
+	for _, image := range images.All() {
+		if isCosignArtifact(image.Tag) {
+			continue
+		}
+
+		imageRef, err := regname.NewDigest(image.DigestRef)
+		if err != nil {
+			return fmt.Errorf("Parsing '%s': %s", image.DigestRef, err)
+		}
+		imageRefs = append(imageRefs, imageRef)
+	}
+	return v.VerifyAll(imageRefs)
+}
+
+// VerificationError Images that are not signed by the keys trusted for their origin
+type VerificationError struct {
+	Failures []error
+}
+
+// Error Lists the verification failure of each image
+func (e VerificationError) Error() string {
+	msg := fmt.Sprintf("%d image(s) failed signature verification:", len(e.Failures))
+	for _, failure := range e.Failures {
+		msg += "\n- " + failure.Error()
+	}
+	return msg
+}
+
+// VerifyAll Verifies all the images, returning a VerificationError with every image that failed the verification
+func (v Verifier) VerifyAll(imageRefs []regname.Digest) error {
+	var failures []error
+	for _, imageRef := range imageRefs {
+		if err := v.Verify(imageRef); err != nil {
+			failures = append(failures, err)
+		}
+	}
+	if len(failures) > 0 {
+		return VerificationError{Failures: failures}
+	}
+	return nil
+}
+
+// Verify Checks the image is signed by one of the keys of its origin, images without a matching origin are not verified
+func (v Verifier) Verify(imageRef regname.Digest) error {
+	_, err := v.verifyWithResult(imageRef)
+	return err
+}
+
+// LevelResult Outcome of the verification of the images at one nesting level of a bundle
+type LevelResult struct {
+	// Level Nesting level, 1 holds the images referenced directly by the bundle
+	Level    int
+	Verified int
+	Failed   int
+	// Unverified Images without an origin in the TrustConfig or that failed the verification of an origin in warn mode
+	Unverified int
+}
+
+// VerifyLevels Verifies the images of every nesting level of a bundle, as returned by Bundle.ImageRefsByNestingLevel,
+// returning the outcome of each level and a VerificationError with every image that failed the verification
+func (v Verifier) VerifyLevels(levels [][]regname.Digest) ([]LevelResult, error) {
+	var results []LevelResult
+	var failures []error
+	for i, imageRefs := range levels {
+		result := LevelResult{Level: i + 1}
+		for _, imageRef := range imageRefs {
+			verified, err := v.verifyWithResult(imageRef)
+			switch {
+			case err != nil:
+				result.Failed++
+				failures = append(failures, err)
+			case verified:
+				result.Verified++
+			default:
+				result.Unverified++
+			}
+		}
+		results = append(results, result)
+	}
+	if len(failures) > 0 {
+		return results, VerificationError{Failures: failures}
+	}
+	return results, nil
+}
+
+// verifyWithResult Verifies the image and reports whether its signature was verified, failures of origins in warn
+// mode are logged and reported as not verified
+func (v Verifier) verifyWithResult(imageRef regname.Digest) (bool, error) {
+	origin, found := v.config.OriginFor(imageRef)
+	if !found {
+		return false, nil
+	}
+
+	err := v.verify(imageRef, origin)
+	if err == nil {
+		return true, nil
+	}
+	if origin.EffectiveMode() == TrustModeWarn {
+		v.logger.Warnf("Verifying signature of '%s': %s\n", imageRef.Name(), err)
+		return false, nil
+	}
+	return false, fmt.Errorf("Verifying signature of '%s': %s", imageRef.Name(), err)
+}
+
+func (v Verifier) verify(imageRef regname.Digest, origin TrustOrigin) error {
+	digest, err := regv1.NewHash(imageRef.DigestStr())
+	if err != nil {
+		return fmt.Errorf("Converting to hash: %s", err)
+	}
+	sigTagRef, err := regname.NewTag(imageRef.Context().Name() + ":" + cosign.Munge(regv1.Descriptor{Digest: digest}))
+	if err != nil {
+		return err
+	}
+
+	sigImg, err := v.registry.Image(sigTagRef)
+	if err != nil {
+		if terr, ok := err.(*transport.Error); ok && terr.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("Image is not signed, signature '%s' not found", sigTagRef.Name())
+		}
+		return fmt.Errorf("Fetching signature '%s': %s", sigTagRef.Name(), err)
+	}
+
+	manifest, err := sigImg.Manifest()
+	if err != nil {
+		return err
+	}
+
+	for _, layerDesc := range manifest.Layers {
+		encodedSig, found := layerDesc.Annotations[cosignSignatureAnnotation]
+		if !found {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(encodedSig)
+		if err != nil {
+			continue
+		}
+
+		layer, err := sigImg.LayerByDigest(layerDesc.Digest)
+		if err != nil {
+			return err
+		}
+		payload, err := readPayload(layer)
+		if err != nil {
+			return err
+		}
+
+		if !verifySignature(origin.keys, payload, sig) {
+			continue
+		}
+		if payloadDigest(payload) == imageRef.DigestStr() {
+			return nil
+		}
+	}
+
+	if origin.allRepositories {
+		return fmt.Errorf("No signature in '%s' was created by the provided keys", sigTagRef.Name())
+	}
+	return fmt.Errorf("No signature in '%s' was created by the keys trusted for '%s'", sigTagRef.Name(), origin.Repository)
+}
+
+// isCosignArtifact Checks if the tag is the tag cosign uses for signatures, attestations or SBOMs
+func isCosignArtifact(tag string) bool {
+	for _, suffix := range []string{cosignSignatureSuffix, cosignAttestationSuffix, cosignSBOMSuffix} {
+		if strings.HasSuffix(tag, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func readPayload(layer regv1.Layer) ([]byte, error) {
+	rc, err := layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
+
+// payloadDigest Returns the digest of the image signed in a cosign simple signing payload
+func payloadDigest(payload []byte) string {
+	var simpleSigning struct {
+		Critical struct {
+			Image struct {
+				DockerManifestDigest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+		} `json:"critical"`
+	}
+	if err := json.Unmarshal(payload, &simpleSigning); err != nil {
+		return ""
+	}
+	return simpleSigning.Critical.Image.DockerManifestDigest
+}
+
+func verifySignature(keys []crypto.PublicKey, payload []byte, sig []byte) bool {
+	hash := sha256.Sum256(payload)
+
+	for _, key := range keys {
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, hash[:], sig) {
+				return true
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], sig) == nil {
+				return true
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, payload, sig) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	fakekms "github.com/sigstore/sigstore/pkg/signature/kms/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/signature"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestVerifier_Verify(t *testing.T) {
+	trustedKey := generateKey(t)
+	otherKey := generateKey(t)
+
+	regBuilder := helpers.NewFakeRegistry(t, &helpers.Logger{})
+	defer regBuilder.CleanUp()
+	signedImg := regBuilder.WithRandomImage("trusted/signed")
+	signedByOtherImg := regBuilder.WithRandomImage("trusted/signed-by-other")
+	unsignedImg := regBuilder.WithRandomImage("trusted/unsigned")
+	untrustedImg := regBuilder.WithRandomImage("untrusted/unsigned")
+	regBuilder.WithImage("trusted/signed:"+signatureTag(signedImg.Digest), signatureImage(t, trustedKey, signedImg.Digest))
+	regBuilder.WithImage("trusted/signed-by-other:"+signatureTag(signedByOtherImg.Digest), signatureImage(t, otherKey, signedByOtherImg.Digest))
+	reg := regBuilder.Build()
+
+	trustConfig := func(mode signature.TrustMode) signature.TrustConfig {
+		dir := t.TempDir()
+		writePublicKey(t, trustedKey, filepath.Join(dir, "cosign.pub"))
+		config, err := signature.NewTrustConfigFromBytes([]byte(fmt.Sprintf(`
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: TrustConfig
+origins:
+- repository: %s
+  publicKeys: [cosign.pub]
+  mode: %s
+`, regBuilder.ReferenceOnTestServer("trusted"), mode)), dir)
+		require.NoError(t, err)
+		return config
+	}
+
+	t.Run("succeeds when the image is signed by a trusted key", func(t *testing.T) {
+		subject := signature.NewVerifier(trustConfig(signature.TrustModeEnforce), reg, util.NewNoopLevelLogger())
+		require.NoError(t, subject.Verify(digestRef(t, signedImg.RefDigest)))
+	})
+
+	t.Run("fails when the image is signed by another key", func(t *testing.T) {
+		subject := signature.NewVerifier(trustConfig(signature.TrustModeEnforce), reg, util.NewNoopLevelLogger())
+		err := subject.Verify(digestRef(t, signedByOtherImg.RefDigest))
+		require.ErrorContains(t, err, "No signature in")
+	})
+
+	t.Run("fails when the image is not signed", func(t *testing.T) {
+		subject := signature.NewVerifier(trustConfig(signature.TrustModeEnforce), reg, util.NewNoopLevelLogger())
+		err := subject.Verify(digestRef(t, unsignedImg.RefDigest))
+		require.ErrorContains(t, err, "Verifying signature of")
+	})
+
+	t.Run("only warns when the mode of the origin is warn", func(t *testing.T) {
+		output := bytes.NewBufferString("")
+		logger := util.NewUILevelLogger(util.LogWarn, util.NewBufferLogger(output))
+		subject := signature.NewVerifier(trustConfig(signature.TrustModeWarn), reg, logger)
+		require.NoError(t, subject.Verify(digestRef(t, unsignedImg.RefDigest)))
+		assert.Contains(t, output.String(), "Verifying signature of")
+	})
+
+	t.Run("does not verify images from other origins", func(t *testing.T) {
+		subject := signature.NewVerifier(trustConfig(signature.TrustModeEnforce), reg, util.NewNoopLevelLogger())
+		require.NoError(t, subject.Verify(digestRef(t, untrustedImg.RefDigest)))
+	})
+}
+
+func TestVerifier_VerifyAll(t *testing.T) {
+	trustedKey := generateKey(t)
+	otherKey := generateKey(t)
+
+	regBuilder := helpers.NewFakeRegistry(t, &helpers.Logger{})
+	defer regBuilder.CleanUp()
+	signedImg := regBuilder.WithRandomImage("some/signed")
+	signedByOtherImg := regBuilder.WithRandomImage("other/signed-by-other")
+	unsignedImg := regBuilder.WithRandomImage("some/unsigned")
+	regBuilder.WithImage("some/signed:"+signatureTag(signedImg.Digest), signatureImage(t, trustedKey, signedImg.Digest))
+	regBuilder.WithImage("other/signed-by-other:"+signatureTag(signedByOtherImg.Digest), signatureImage(t, otherKey, signedByOtherImg.Digest))
+	reg := regBuilder.Build()
+
+	keyPath := filepath.Join(t.TempDir(), "cosign.pub")
+	writePublicKey(t, trustedKey, keyPath)
+	config, err := signature.NewTrustConfigForKeys([]string{keyPath})
+	require.NoError(t, err)
+	subject := signature.NewVerifier(config, reg, util.NewNoopLevelLogger())
+
+	t.Run("succeeds when all the images, from any origin, are signed by the keys", func(t *testing.T) {
+		require.NoError(t, subject.VerifyAll([]name.Digest{digestRef(t, signedImg.RefDigest)}))
+	})
+
+	t.Run("reports every image that is unsigned or signed by another key", func(t *testing.T) {
+		err := subject.VerifyAll([]name.Digest{
+			digestRef(t, signedImg.RefDigest),
+			digestRef(t, signedByOtherImg.RefDigest),
+			digestRef(t, unsignedImg.RefDigest),
+		})
+		require.Error(t, err)
+
+		verificationErr, ok := err.(signature.VerificationError)
+		require.True(t, ok, "Unexpected error %+v, while expecting a VerificationError", err)
+		require.Len(t, verificationErr.Failures, 2)
+		assert.Contains(t, verificationErr.Failures[0].Error(), "Verifying signature of '"+signedByOtherImg.RefDigest+"': No signature in")
+		assert.Contains(t, verificationErr.Failures[0].Error(), "was created by the provided keys")
+		assert.Contains(t, verificationErr.Failures[1].Error(), "Verifying signature of '"+unsignedImg.RefDigest+"': Image is not signed")
+		assert.Contains(t, err.Error(), "2 image(s) failed signature verification:")
+	})
+}
+
+func TestVerifier_VerifyAllWithKMSKey(t *testing.T) {
+	trustedKey := generateKey(t)
+
+	regBuilder := helpers.NewFakeRegistry(t, &helpers.Logger{})
+	defer regBuilder.CleanUp()
+	signedImg := regBuilder.WithRandomImage("some/signed")
+	unsignedImg := regBuilder.WithRandomImage("some/unsigned")
+	regBuilder.WithImage("some/signed:"+signatureTag(signedImg.Digest), signatureImage(t, trustedKey, signedImg.Digest))
+	reg := regBuilder.Build()
+
+	ctx := context.WithValue(context.Background(), fakekms.KmsCtxKey{}, trustedKey)
+	config, err := signature.NewTrustConfigForKeysWithContext(ctx, []string{fakekms.ReferenceScheme + "some-key"})
+	require.NoError(t, err)
+	subject := signature.NewVerifier(config, reg, util.NewNoopLevelLogger())
+
+	t.Run("succeeds when the images are signed by the key in the KMS", func(t *testing.T) {
+		require.NoError(t, subject.VerifyAll([]name.Digest{digestRef(t, signedImg.RefDigest)}))
+	})
+
+	t.Run("fails when the images are not signed by the key in the KMS", func(t *testing.T) {
+		err := subject.VerifyAll([]name.Digest{digestRef(t, unsignedImg.RefDigest)})
+		require.ErrorContains(t, err, "Verifying signature of '"+unsignedImg.RefDigest+"': Image is not signed")
+	})
+
+	t.Run("fails when there is no provider for the KMS", func(t *testing.T) {
+		_, err := signature.NewTrustConfigForKeysWithContext(ctx, []string{"unknownkms://some-key"})
+		require.ErrorContains(t, err, "Loading key 'unknownkms://some-key' from KMS: no kms provider found")
+	})
+}
+
+func TestVerifier_VerifyLevels(t *testing.T) {
+	trustedKey := generateKey(t)
+
+	regBuilder := helpers.NewFakeRegistry(t, &helpers.Logger{})
+	defer regBuilder.CleanUp()
+	signedImg := regBuilder.WithRandomImage("trusted/signed")
+	unsignedImg := regBuilder.WithRandomImage("trusted/unsigned")
+	untrustedImg := regBuilder.WithRandomImage("untrusted/unsigned")
+	regBuilder.WithImage("trusted/signed:"+signatureTag(signedImg.Digest), signatureImage(t, trustedKey, signedImg.Digest))
+	reg := regBuilder.Build()
+
+	dir := t.TempDir()
+	writePublicKey(t, trustedKey, filepath.Join(dir, "cosign.pub"))
+	config, err := signature.NewTrustConfigFromBytes([]byte(fmt.Sprintf(`
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: TrustConfig
+origins:
+- repository: %s
+  publicKeys: [cosign.pub]
+`, regBuilder.ReferenceOnTestServer("trusted"))), dir)
+	require.NoError(t, err)
+	subject := signature.NewVerifier(config, reg, util.NewNoopLevelLogger())
+
+	results, err := subject.VerifyLevels([][]name.Digest{
+		{digestRef(t, signedImg.RefDigest), digestRef(t, untrustedImg.RefDigest)},
+		{digestRef(t, unsignedImg.RefDigest)},
+	})
+	require.Error(t, err)
+	verificationErr, ok := err.(signature.VerificationError)
+	require.True(t, ok, "Unexpected error %+v, while expecting a VerificationError", err)
+	require.Len(t, verificationErr.Failures, 1)
+	assert.Contains(t, verificationErr.Failures[0].Error(), "Verifying signature of '"+unsignedImg.RefDigest+"': Image is not signed")
+
+	assert.Equal(t, []signature.LevelResult{
+		{Level: 1, Verified: 1, Unverified: 1},
+		{Level: 2, Failed: 1},
+	}, results)
+}
+
+func digestRef(t *testing.T, ref string) name.Digest {
+	digest, err := name.NewDigest(ref)
+	require.NoError(t, err)
+	return digest
+}
+
+func signatureTag(digest string) string {
+	return strings.ReplaceAll(digest, ":", "-") + ".sig"
+}
+
+// signatureImage Creates an image like the ones created by cosign sign for the image with the provided digest
+func signatureImage(t *testing.T, key *ecdsa.PrivateKey, digest string) regv1.Image {
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":""},"image":{"docker-manifest-digest":"%s"},"type":"cosign container image signature"},"optional":null}`, digest))
+	hash := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+
+	img, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       payloadLayer{payload: payload},
+		Annotations: map[string]string{"dev.cosignproject.cosign/signature": base64.StdEncoding.EncodeToString(sig)},
+	})
+	require.NoError(t, err)
+	return img
+}
+
+// payloadLayer Layer that holds the payload as is, like cosign does
+type payloadLayer struct {
+	payload []byte
+}
+
+func (l payloadLayer) Digest() (regv1.Hash, error) {
+	hash, _, err := regv1.SHA256(bytes.NewReader(l.payload))
+	return hash, err
+}
+func (l payloadLayer) DiffID() (regv1.Hash, error) { return l.Digest() }
+func (l payloadLayer) Compressed() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(l.payload)), nil
+}
+func (l payloadLayer) Uncompressed() (io.ReadCloser, error) { return l.Compressed() }
+func (l payloadLayer) Size() (int64, error)                 { return int64(len(l.payload)), nil }
+func (l payloadLayer) MediaType() (types.MediaType, error) {
+	return "application/vnd.dev.cosign.simplesigning.v1+json", nil
+}
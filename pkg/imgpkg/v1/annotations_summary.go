@@ -0,0 +1,50 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"sort"
+)
+
+// BundleAnnotations Values of selected annotations of a Bundle
+type BundleAnnotations struct {
+	Bundle      string            `json:"bundle"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// SummarizeAnnotations Collects the value of the provided keys for the Bundle and all its Nested Bundles.
+// The value is read from the annotations in the Images Lock and, when not present, from the Bundle metadata.
+// The result is sorted by Bundle location
+func SummarizeAnnotations(description Description, keys []string) []BundleAnnotations {
+	visited := map[string]struct{}{}
+	var result []BundleAnnotations
+
+	var collect func(desc Description)
+	collect = func(desc Description) {
+		if _, ok := visited[desc.Image]; ok {
+			return
+		}
+		visited[desc.Image] = struct{}{}
+
+		bundleAnnotations := BundleAnnotations{Bundle: desc.Image, Annotations: map[string]string{}}
+		for _, key := range keys {
+			if value, found := desc.Annotations[key]; found {
+				bundleAnnotations.Annotations[key] = value
+			} else if value, found := desc.Metadata.Metadata[key]; found {
+				bundleAnnotations.Annotations[key] = value
+			}
+		}
+		result = append(result, bundleAnnotations)
+
+		for _, b := range desc.Content.Bundles {
+			collect(b)
+		}
+	}
+	collect(description)
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Bundle < result[j].Bundle
+	})
+	return result
+}
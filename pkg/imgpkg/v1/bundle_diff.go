@@ -0,0 +1,172 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+)
+
+// AnnotationChange Annotation added, removed or with a different value between two versions of a bundle
+type AnnotationChange struct {
+	Key      string `json:"key"`
+	OldValue string `json:"oldValue,omitempty"`
+	NewValue string `json:"newValue,omitempty"`
+}
+
+// BundleImageDiff Images that come from the same repository in two versions of a bundle
+type BundleImageDiff struct {
+	// Repository Repository the images originally came from, used to match the images of both bundles
+	Repository string           `json:"repository"`
+	ImageType  bundle.ImageType `json:"imageType"`
+	OldImages  []string         `json:"oldImages,omitempty"`
+	NewImages  []string         `json:"newImages,omitempty"`
+	// Annotations Changes to the annotations recorded in the ImagesLock, only compared when the repository has a
+	// single image in each bundle
+	Annotations []AnnotationChange `json:"annotations,omitempty"`
+}
+
+// BundleDiff Difference between the images, and annotations, of two versions of a Bundle, including the images of
+// their nested bundles
+type BundleDiff struct {
+	Added   []BundleImageDiff `json:"added"`
+	Removed []BundleImageDiff `json:"removed"`
+	// Changed Images with a different digest, or with different annotations
+	Changed []BundleImageDiff `json:"changed"`
+	// Annotations Changes to the annotations of the bundle image
+	Annotations []AnnotationChange `json:"annotations"`
+}
+
+// diffImage Image of a bundle, or of one of its nested bundles
+type diffImage struct {
+	image       string
+	imageType   bundle.ImageType
+	annotations map[string]string
+}
+
+// NewBundleDiff Given the Descriptions of two versions of a Bundle, compares the images that come from the same repository
+func NewBundleDiff(oldDescription, newDescription Description) (BundleDiff, error) {
+	oldImages, err := diffImagesByRepository(oldDescription)
+	if err != nil {
+		return BundleDiff{}, err
+	}
+	newImages, err := diffImagesByRepository(newDescription)
+	if err != nil {
+		return BundleDiff{}, err
+	}
+
+	repositories := map[string]struct{}{}
+	for repo := range oldImages {
+		repositories[repo] = struct{}{}
+	}
+	for repo := range newImages {
+		repositories[repo] = struct{}{}
+	}
+
+	diff := BundleDiff{
+		Added:       []BundleImageDiff{},
+		Removed:     []BundleImageDiff{},
+		Changed:     []BundleImageDiff{},
+		Annotations: diffAnnotations(oldDescription.Annotations, newDescription.Annotations),
+	}
+	for repo := range repositories {
+		imageDiff := BundleImageDiff{Repository: repo}
+		for _, img := range oldImages[repo] {
+			imageDiff.OldImages = append(imageDiff.OldImages, img.image)
+			imageDiff.ImageType = img.imageType
+		}
+		for _, img := range newImages[repo] {
+			imageDiff.NewImages = append(imageDiff.NewImages, img.image)
+			imageDiff.ImageType = img.imageType
+		}
+
+		switch {
+		case len(imageDiff.OldImages) == 0:
+			diff.Added = append(diff.Added, imageDiff)
+		case len(imageDiff.NewImages) == 0:
+			diff.Removed = append(diff.Removed, imageDiff)
+		default:
+			if len(oldImages[repo]) == 1 && len(newImages[repo]) == 1 {
+				imageDiff.Annotations = diffAnnotations(oldImages[repo][0].annotations, newImages[repo][0].annotations)
+			}
+			if !sameDigests(imageDiff.OldImages, imageDiff.NewImages) || len(imageDiff.Annotations) > 0 {
+				diff.Changed = append(diff.Changed, imageDiff)
+			}
+		}
+	}
+
+	for _, images := range [][]BundleImageDiff{diff.Added, diff.Removed, diff.Changed} {
+		sort.Slice(images, func(i, j int) bool { return images[i].Repository < images[j].Repository })
+	}
+	return diff, nil
+}
+
+// diffImagesByRepository returns the images and nested bundles, excluding signatures and internal images, of the
+// bundle and all its nested bundles, grouped by the repository they originally came from
+func diffImagesByRepository(description Description) (map[string][]diffImage, error) {
+	visited := map[string]struct{}{}
+	result := map[string][]diffImage{}
+
+	var collect func(desc Description) error
+	collect = func(desc Description) error {
+		for _, img := range desc.Content.Images {
+			if img.ImageType != bundle.ContentImage && img.ImageType != bundle.BundleImage {
+				continue
+			}
+			if _, ok := visited[img.Image]; ok {
+				continue
+			}
+			visited[img.Image] = struct{}{}
+
+			origin := img.Origin
+			if origin == "" {
+				origin = img.Image
+			}
+			originRef, err := name.ParseReference(origin)
+			if err != nil {
+				return fmt.Errorf("Parsing origin of image '%s': %s", img.Image, err)
+			}
+
+			repo := originRef.Context().Name()
+			result[repo] = append(result[repo], diffImage{image: img.Image, imageType: img.ImageType, annotations: img.Annotations})
+		}
+		for _, nested := range desc.Content.Bundles {
+			err := collect(nested)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := collect(description)
+	if err != nil {
+		return nil, err
+	}
+	for _, images := range result {
+		sort.Slice(images, func(i, j int) bool { return images[i].image < images[j].image })
+	}
+	return result, nil
+}
+
+// diffAnnotations returns the annotations added, removed and changed, sorted by key
+func diffAnnotations(oldAnnotations, newAnnotations map[string]string) []AnnotationChange {
+	var changes []AnnotationChange
+	for key, oldValue := range oldAnnotations {
+		newValue, found := newAnnotations[key]
+		if !found || newValue != oldValue {
+			changes = append(changes, AnnotationChange{Key: key, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	for key, newValue := range newAnnotations {
+		if _, found := oldAnnotations[key]; !found {
+			changes = append(changes, AnnotationChange{Key: key, NewValue: newValue})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+)
+
+func TestNewBundleDiff(t *testing.T) {
+	digestA := "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	digestB := "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	digestC := "sha256:cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc"
+	digestD := "sha256:dddddddddddddddddddddddddddddddddddddddddddddddddddddddddddddddd"
+	digestE := "sha256:eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
+
+	oldDescription := v1.Description{
+		Image:       "registry.io/bundle@" + digestA,
+		Annotations: map[string]string{"version": "1.0.0", "removed": "yes"},
+		Content: v1.Content{
+			Images: map[string]v1.ImageInfo{
+				digestB: {Image: "registry.io/bundle@" + digestB, Origin: "origin.io/app@" + digestB, ImageType: bundle.ContentImage, Annotations: map[string]string{"name": "app"}},
+				digestC: {Image: "registry.io/bundle@" + digestC, Origin: "origin.io/db@" + digestC, ImageType: bundle.ContentImage},
+				digestD: {Image: "registry.io/bundle@" + digestD, Origin: "origin.io/cache@" + digestD, ImageType: bundle.ContentImage, Annotations: map[string]string{"tier": "1"}},
+				digestE: {Image: "registry.io/bundle@" + digestE, Origin: "origin.io/app@" + digestE, ImageType: bundle.SignatureImage},
+			},
+		},
+	}
+	newDescription := v1.Description{
+		Image:       "registry.io/bundle@" + digestB,
+		Annotations: map[string]string{"version": "1.1.0", "added": "yes"},
+		Content: v1.Content{
+			Images: map[string]v1.ImageInfo{
+				digestE: {Image: "registry.io/bundle@" + digestE, Origin: "origin.io/app@" + digestE, ImageType: bundle.ContentImage, Annotations: map[string]string{"name": "app"}},
+				digestD: {Image: "registry.io/bundle@" + digestD, Origin: "origin.io/cache@" + digestD, ImageType: bundle.ContentImage, Annotations: map[string]string{"tier": "2"}},
+			},
+			Bundles: map[string]v1.Description{
+				digestA: {
+					Image: "registry.io/bundle@" + digestA,
+					Content: v1.Content{
+						Images: map[string]v1.ImageInfo{
+							digestA: {Image: "registry.io/bundle@" + digestA, Origin: "origin.io/nested@" + digestA, ImageType: bundle.BundleImage},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	diff, err := v1.NewBundleDiff(oldDescription, newDescription)
+	require.NoError(t, err)
+
+	assert.Equal(t, []v1.BundleImageDiff{
+		{Repository: "origin.io/nested", ImageType: bundle.BundleImage, NewImages: []string{"registry.io/bundle@" + digestA}},
+	}, diff.Added)
+	assert.Equal(t, []v1.BundleImageDiff{
+		{Repository: "origin.io/db", ImageType: bundle.ContentImage, OldImages: []string{"registry.io/bundle@" + digestC}},
+	}, diff.Removed)
+	assert.Equal(t, []v1.BundleImageDiff{
+		{Repository: "origin.io/app", ImageType: bundle.ContentImage, OldImages: []string{"registry.io/bundle@" + digestB}, NewImages: []string{"registry.io/bundle@" + digestE}},
+		{
+			Repository: "origin.io/cache", ImageType: bundle.ContentImage,
+			OldImages: []string{"registry.io/bundle@" + digestD}, NewImages: []string{"registry.io/bundle@" + digestD},
+			Annotations: []v1.AnnotationChange{{Key: "tier", OldValue: "1", NewValue: "2"}},
+		},
+	}, diff.Changed)
+	assert.Equal(t, []v1.AnnotationChange{
+		{Key: "added", NewValue: "yes"},
+		{Key: "removed", OldValue: "yes"},
+		{Key: "version", OldValue: "1.0.0", NewValue: "1.1.0"},
+	}, diff.Annotations)
+
+	t.Run("when both bundles have the same images and annotations", func(t *testing.T) {
+		diff, err := v1.NewBundleDiff(oldDescription, oldDescription)
+		require.NoError(t, err)
+		assert.Empty(t, diff.Added)
+		assert.Empty(t, diff.Removed)
+		assert.Empty(t, diff.Changed)
+		assert.Empty(t, diff.Annotations)
+	})
+}
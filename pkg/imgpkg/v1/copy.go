@@ -0,0 +1,152 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/workspace"
+)
+
+// CopyOpts Option that can be provided to the copy request
+type CopyOpts struct {
+	Logger      Logger
+	Concurrency int
+	// UseRepoBasedTags the tags of the copied images are based on the repository they come from, instead of the
+	// default imgpkg-<algorithm>-<digest> tags
+	UseRepoBasedTags bool
+	// EventListener receives an event when the copy of each image starts, completes or fails
+	EventListener imageset.EventListener
+	// Workspace Where the temporary files created during the copy are kept. When not provided a workspace is created
+	// and removed when the copy completes
+	Workspace *workspace.Workspace
+}
+
+// CopyStatus Report from the Copy command
+type CopyStatus struct {
+	// ImageRef reference to the digest of the Image or Bundle in the destination repository
+	ImageRef string `json:"image"`
+	// Images all the images copied, including the Bundle, its nested Bundles and their images
+	Images []CopiedImage `json:"images"`
+}
+
+// CopiedImage Image copied to the destination repository
+type CopiedImage struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+// Copy Copies the image, or the bundle with all its nested bundles and images, to the repository repo
+func Copy(imageRef string, repo string, opts CopyOpts, registryOpts registry.Opts) (CopyStatus, error) {
+	return CopyWithContext(context.Background(), imageRef, repo, opts, registryOpts)
+}
+
+// CopyWithContext Same as Copy, the transfers between the registries are cancelled when ctx is done
+func CopyWithContext(ctx context.Context, imageRef string, repo string, opts CopyOpts, registryOpts registry.Opts) (CopyStatus, error) {
+	reg, err := registry.NewSimpleRegistryWithContext(ctx, registryOpts)
+	if err != nil {
+		return CopyStatus{}, err
+	}
+	return CopyWithRegistry(imageRef, repo, opts, reg)
+}
+
+// CopyWithRegistry Copies the image, or the bundle with all its nested bundles and images, to the repository repo.
+// The tags of the image, or of the bundle, are also copied
+func CopyWithRegistry(imageRef string, repo string, opts CopyOpts, reg registry.Registry) (CopyStatus, error) {
+	logger := opts.Logger
+	if logger == nil {
+		logger = util.NewNoopLevelLogger()
+	}
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	importRepo, err := regname.NewRepository(repo)
+	if err != nil {
+		return CopyStatus{}, fmt.Errorf("Building import repository ref: %s", err)
+	}
+
+	contents, err := archiveImageRefs(imageRef, true, concurrency, logger, reg)
+	if err != nil {
+		return CopyStatus{}, err
+	}
+
+	var tagGen util.TagGenerator = util.DefaultTagGenerator{}
+	if opts.UseRepoBasedTags {
+		tagGen = util.RepoBasedTagGenerator{}
+	}
+	imageSet := imageset.NewImageSet(concurrency, logger, tagGen)
+	if opts.EventListener != nil {
+		imageSet = imageSet.WithEventListener(opts.EventListener)
+	}
+	processedImages, err := imageSet.Relocate(contents.imageRefs, importRepo, reg)
+	if err != nil {
+		return CopyStatus{}, err
+	}
+
+	if len(contents.bundles) > 0 {
+		ws := opts.Workspace
+		if ws == nil {
+			ws, err = workspace.New("")
+			if err != nil {
+				return CopyStatus{}, err
+			}
+			defer ws.Cleanup()
+		}
+		for _, b := range contents.bundles {
+			if err := b.WithWorkspace(ws).NoteCopy(processedImages, reg, logger); err != nil {
+				return CopyStatus{}, fmt.Errorf("Creating copy information for bundle %s: %s", b.DigestRef(), err)
+			}
+		}
+	}
+
+	if err := tagProcessedImages(processedImages, reg); err != nil {
+		return CopyStatus{}, err
+	}
+
+	status := CopyStatus{}
+	for _, item := range processedImages.All() {
+		status.Images = append(status.Images, CopiedImage{Source: item.UnprocessedImageRef.DigestRef, Destination: item.DigestRef})
+		if item.UnprocessedImageRef.DigestRef == contents.rootDigestRef {
+			status.ImageRef = item.DigestRef
+		}
+	}
+	sort.Slice(status.Images, func(i, j int) bool { return status.Images[i].Source < status.Images[j].Source })
+	return status, nil
+}
+
+// tagProcessedImages Copies the tag of the images that had one to the destination
+func tagProcessedImages(processedImages *imageset.ProcessedImages, reg registry.Registry) error {
+	for _, item := range processedImages.All() {
+		if item.Tag == "" {
+			continue
+		}
+
+		digest, err := regname.NewDigest(item.DigestRef)
+		if err != nil {
+			panic(fmt.Sprintf("Internal consistency: %s should be a digest", item.DigestRef))
+		}
+
+		customTagRef := digest.Tag(item.Tag)
+		switch {
+		case item.Image != nil:
+			err = reg.WriteTag(customTagRef, item.Image)
+		case item.ImageIndex != nil:
+			err = reg.WriteTag(customTagRef, item.ImageIndex)
+		default:
+			panic("Unknown item")
+		}
+		if err != nil {
+			return fmt.Errorf("Tagging image %s: %s", digest.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+// recordedEvents EventListener that keeps the type of the events received, the events are received concurrently
+type recordedEvents struct {
+	types []imageset.EventType
+	lock  sync.Mutex
+}
+
+func (r *recordedEvents) OnEvent(event imageset.Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.types = append(r.types, event.Type)
+}
+
+func TestCopy(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegBuilder := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegBuilder.CleanUp()
+
+	img := fakeRegBuilder.WithRandomImage("app/img")
+	bundle := fakeRegBuilder.WithRandomBundleAndImages("app/bundle", []lockconfig.ImageRef{{Image: img.RefDigest}})
+	fakeRegBuilder.Build()
+
+	t.Run("when copying a bundle, it copies the bundle and its images to the repository", func(t *testing.T) {
+		dstRepo := fakeRegBuilder.ReferenceOnTestServer("copied/bundle")
+		events := &recordedEvents{}
+		status, err := v1.Copy(bundle.RefDigest, dstRepo, v1.CopyOpts{Logger: logger, Concurrency: 5, EventListener: events}, registry.Opts{EnvironFunc: os.Environ})
+		require.NoError(t, err)
+
+		assert.Equal(t, dstRepo+"@"+bundle.Digest, status.ImageRef)
+		require.Len(t, status.Images, 2)
+		for _, copied := range status.Images {
+			assert.True(t, strings.HasPrefix(copied.Destination, dstRepo+"@"), copied.Destination)
+		}
+		assert.Contains(t, events.types, imageset.EventImageCompleted)
+
+		isBundle, err := v1.IsBundle(status.ImageRef, registry.Opts{EnvironFunc: os.Environ})
+		require.NoError(t, err)
+		assert.True(t, isBundle)
+	})
+
+	t.Run("when copying an image, it copies only the image", func(t *testing.T) {
+		dstRepo := fakeRegBuilder.ReferenceOnTestServer("copied/img")
+		status, err := v1.Copy(img.RefDigest, dstRepo, v1.CopyOpts{Logger: logger}, registry.Opts{EnvironFunc: os.Environ})
+		require.NoError(t, err)
+
+		assert.Equal(t, dstRepo+"@"+img.Digest, status.ImageRef)
+		require.Len(t, status.Images, 1)
+	})
+
+	t.Run("when the repository is invalid, it fails", func(t *testing.T) {
+		_, err := v1.Copy(img.RefDigest, "Invalid Repo", v1.CopyOpts{Logger: logger}, registry.Opts{EnvironFunc: os.Environ})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Building import repository ref")
+	})
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagetar"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/plainimage"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+// ArchiveWriter Destination of the tar written by CopyToArchive. Allows tools to stream the tar to any sink,
+// e.g. a tape library or an upload to a transfer service, instead of a local file
+type ArchiveWriter interface {
+	// Open returns the writer that receives the tar. It is called once, the tar is written sequentially and
+	// the writer is closed when the tar is complete or the copy fails
+	Open() (io.WriteCloser, error)
+}
+
+// FileArchiveWriter ArchiveWriter that writes the tar to a local file
+type FileArchiveWriter struct {
+	Path string
+}
+
+// Open Creates the file, truncating it when it already exists
+func (f FileArchiveWriter) Open() (io.WriteCloser, error) {
+	file, err := os.Create(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("Creating file '%s': %s", f.Path, err)
+	}
+	return file, nil
+}
+
+// CopyToArchiveOpts Option that can be provided to CopyToArchive
+type CopyToArchiveOpts struct {
+	Logger      Logger
+	Concurrency int
+	// IncludeNonDistributableLayers Include the layers marked as non-distributable in the tar
+	IncludeNonDistributableLayers bool
+}
+
+// CopyToArchive Writes the image, or the bundle with all its nested bundles and images, to the ArchiveWriter as a tar
+// in the same format as imgpkg copy --to-tar. The tar can be copied to a repository with imgpkg copy --tar
+func CopyToArchive(imageRef string, writer ArchiveWriter, opts CopyToArchiveOpts, registryOpts registry.Opts) error {
+	reg, err := registry.NewSimpleRegistry(registryOpts)
+	if err != nil {
+		return err
+	}
+
+	return CopyToArchiveWithRegistry(imageRef, writer, opts, reg)
+}
+
+// CopyToArchiveWithRegistry Writes the image, or the bundle with all its nested bundles and images, to the
+// ArchiveWriter as a tar in the same format as imgpkg copy --to-tar
+func CopyToArchiveWithRegistry(imageRef string, writer ArchiveWriter, opts CopyToArchiveOpts, reg registry.Registry) error {
+	logger := opts.Logger
+	if logger == nil {
+		logger = util.NewNoopLevelLogger()
+	}
+
+	contents, err := archiveImageRefs(imageRef, true, opts.Concurrency, logger, reg)
+	if err != nil {
+		return err
+	}
+
+	// Streams can only be written sequentially, so the writer is never treated as a file that can be written in parallel
+	store := imagetar.NewTarBlobStore(func() (io.WriteCloser, error) {
+		dst, err := writer.Open()
+		if err != nil {
+			return nil, err
+		}
+		return sequentialWriteCloser{dst}, nil
+	}, imagetar.TarWriterOpts{Concurrency: 1}, logger)
+
+	imageSet := imageset.NewImageSet(opts.Concurrency, logger, util.DefaultTagGenerator{})
+	_, err = imageset.NewTarImageSet(imageSet, opts.Concurrency, logger).
+		ExportToBlobStore(contents.imageRefs, store, reg, imagetar.NewImageLayerWriterCheck(opts.IncludeNonDistributableLayers))
+	return err
+}
+
+// archiveContents Images written to an archive
+type archiveContents struct {
+	imageRefs *imageset.UnprocessedImageRefs
+	// rootDigestRef reference to the digest of the image, or bundle, being archived
+	rootDigestRef string
+	isBundle      bool
+	// bundles the bundle and all its nested bundles, only present when the images are included
+	bundles []*bundle.Bundle
+}
+
+// archiveImageRefs returns the image, or the bundle and, when withImages is true, all the images of it and its
+// nested bundles
+func archiveImageRefs(imageRef string, withImages bool, concurrency int, logger Logger, reg registry.Registry) (archiveContents, error) {
+	unprocessedImageRefs := imageset.NewUnprocessedImageRefs()
+
+	lockReader := bundle.NewImagesLockReader()
+	newBundle := bundle.NewBundleFromRef(imageRef, reg, lockReader, bundle.NewRegistryFetcher(reg, lockReader))
+	isBundle, err := newBundle.IsBundle()
+	if err != nil {
+		return archiveContents{}, fmt.Errorf("Unable to check if %s is a bundle: %s", imageRef, err)
+	}
+	if !isBundle {
+		plainImg := plainimage.NewPlainImage(imageRef, reg)
+		_, err = plainImg.Fetch()
+		if err != nil {
+			return archiveContents{}, err
+		}
+		unprocessedImageRefs.Add(imageset.UnprocessedImageRef{DigestRef: plainImg.DigestRef(), Tag: plainImg.Tag()})
+		return archiveContents{imageRefs: unprocessedImageRefs, rootDigestRef: plainImg.DigestRef()}, nil
+	}
+
+	var bundles []*bundle.Bundle
+	if withImages {
+		var imageRefs bundle.ImageRefs
+		bundles, imageRefs, err = newBundle.AllImagesLockRefs(concurrency, logger)
+		if err != nil {
+			return archiveContents{}, fmt.Errorf("Reading Images from Bundle: %s", err)
+		}
+		for _, img := range imageRefs.ImageRefs() {
+			unprocessedImageRefs.Add(imageset.UnprocessedImageRef{DigestRef: img.PrimaryLocation(), OrigRef: img.Image})
+		}
+	}
+	unprocessedImageRefs.Add(imageset.UnprocessedImageRef{
+		DigestRef: newBundle.DigestRef(),
+		Tag:       newBundle.Tag(),
+		Labels:    map[string]string{imageset.RootBundleLabelKey: ""},
+		OrigRef:   newBundle.DigestRef(),
+	})
+	return archiveContents{imageRefs: unprocessedImageRefs, rootDigestRef: newBundle.DigestRef(), isBundle: true, bundles: bundles}, nil
+}
+
+// sequentialWriteCloser hides the type of the destination, so that files are also written sequentially
+type sequentialWriteCloser struct {
+	io.WriteCloser
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+// bufferArchiveWriter Sink that keeps the tar in memory and records how many times it was opened and closed
+type bufferArchiveWriter struct {
+	buf    bytes.Buffer
+	opened int
+	closed int
+}
+
+func (b *bufferArchiveWriter) Open() (io.WriteCloser, error) {
+	b.opened++
+	return b, nil
+}
+
+func (b *bufferArchiveWriter) Write(p []byte) (int, error) { return b.buf.Write(p) }
+
+func (b *bufferArchiveWriter) Close() error {
+	b.closed++
+	return nil
+}
+
+func TestCopyToArchive(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegBuilder := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegBuilder.CleanUp()
+
+	img := fakeRegBuilder.WithRandomImage("app/img")
+	bundle := fakeRegBuilder.WithRandomBundleAndImages("app/bundle", []lockconfig.ImageRef{{Image: img.RefDigest}})
+	fakeRegBuilder.Build()
+
+	tmpDir := t.TempDir()
+
+	t.Run("when copying a bundle, it streams the bundle and its images to the writer", func(t *testing.T) {
+		writer := &bufferArchiveWriter{}
+		err := v1.CopyToArchive(bundle.RefDigest, writer, v1.CopyToArchiveOpts{Logger: logger, Concurrency: 5}, registry.Opts{EnvironFunc: os.Environ})
+		require.NoError(t, err)
+		assert.Equal(t, 1, writer.opened)
+		assert.Equal(t, 1, writer.closed)
+
+		tarPath := filepath.Join(tmpDir, "bundle.tar")
+		require.NoError(t, os.WriteFile(tarPath, writer.buf.Bytes(), 0600))
+		archive, err := v1.OpenTarArchive(tarPath)
+		require.NoError(t, err)
+		defer archive.Close()
+
+		digests := map[string]v1.TarImage{}
+		for _, tarImg := range archive.Images() {
+			digests[tarImg.Digest] = tarImg
+		}
+		require.Len(t, digests, 2)
+		require.Contains(t, digests, img.Digest)
+		require.Contains(t, digests, bundle.Digest)
+		assert.Contains(t, digests[bundle.Digest].Labels, imageset.RootBundleLabelKey)
+		for _, layer := range digests[img.Digest].Layers {
+			assert.True(t, layer.Present)
+		}
+	})
+
+	t.Run("when copying an image to a file, it writes the tar to the file", func(t *testing.T) {
+		tarPath := filepath.Join(tmpDir, "image.tar")
+		err := v1.CopyToArchive(img.RefDigest, v1.FileArchiveWriter{Path: tarPath}, v1.CopyToArchiveOpts{Logger: logger, Concurrency: 5}, registry.Opts{EnvironFunc: os.Environ})
+		require.NoError(t, err)
+
+		archive, err := v1.OpenTarArchive(tarPath)
+		require.NoError(t, err)
+		defer archive.Close()
+		require.Len(t, archive.Images(), 1)
+		assert.Equal(t, img.Digest, archive.Images()[0].Digest)
+	})
+}
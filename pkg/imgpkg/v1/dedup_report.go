@@ -0,0 +1,216 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+// LayerInfo Information about a layer and the images that contain it
+type LayerInfo struct {
+	Digest string   `json:"digest"`
+	Size   int64    `json:"size"`
+	Images []string `json:"images"`
+}
+
+// ImageLayersInfo Size information of the layers of a single image
+type ImageLayersInfo struct {
+	Image string `json:"image"`
+	// Size Sum of the size of all the layers of the image
+	Size int64 `json:"size"`
+	// ExclusiveSize Sum of the size of the layers that are not shared with any other image in the bundle
+	ExclusiveSize int64 `json:"exclusiveSize"`
+}
+
+// DedupReport Layer overlap between all the images present in a Bundle and its Nested Bundles
+type DedupReport struct {
+	// TotalSize Size that all the images would have if no layer was shared
+	TotalSize int64 `json:"totalSize"`
+	// UniqueSize Size of all the unique layers, this is the size stored in a registry
+	UniqueSize int64 `json:"uniqueSize"`
+	// SavedSize Size saved by sharing layers between images
+	SavedSize    int64             `json:"savedSize"`
+	SharedLayers []LayerInfo       `json:"sharedLayers,omitempty"`
+	Images       []ImageLayersInfo `json:"images,omitempty"`
+}
+
+// ImageDescriptorFetcher Interface to retrieve the descriptor of images
+type ImageDescriptorFetcher interface {
+	Get(name.Reference) (*regremote.Descriptor, error)
+}
+
+// NewDedupReport Given the Description of a Bundle calculate the layers shared between all its images
+func NewDedupReport(description Description, registryOpts registry.Opts) (DedupReport, error) {
+	reg, err := registry.NewSimpleRegistry(registryOpts)
+	if err != nil {
+		return DedupReport{}, err
+	}
+
+	return NewDedupReportWithFetcher(description, reg)
+}
+
+// NewDedupReportWithFetcher Given the Description of a Bundle calculate the layers shared between all its images
+func NewDedupReportWithFetcher(description Description, fetcher ImageDescriptorFetcher) (DedupReport, error) {
+	layers := map[string]*LayerInfo{}
+	imagesLayers := map[string][]string{}
+
+	for _, imageRef := range allImagesInDescription(description) {
+		layersDigests, err := imageLayers(imageRef, fetcher, layers)
+		if err != nil {
+			return DedupReport{}, fmt.Errorf("Retrieving layers of image %s: %s", imageRef, err)
+		}
+		imagesLayers[imageRef] = layersDigests
+	}
+
+	report := DedupReport{}
+	for _, layer := range layers {
+		report.UniqueSize += layer.Size
+		report.TotalSize += layer.Size * int64(len(layer.Images))
+		if len(layer.Images) > 1 {
+			sort.Strings(layer.Images)
+			report.SharedLayers = append(report.SharedLayers, *layer)
+		}
+	}
+	report.SavedSize = report.TotalSize - report.UniqueSize
+
+	for imageRef, layersDigests := range imagesLayers {
+		imgInfo := ImageLayersInfo{Image: imageRef}
+		for _, digest := range layersDigests {
+			imgInfo.Size += layers[digest].Size
+			if len(layers[digest].Images) == 1 {
+				imgInfo.ExclusiveSize += layers[digest].Size
+			}
+		}
+		report.Images = append(report.Images, imgInfo)
+	}
+
+	sort.Slice(report.SharedLayers, func(i, j int) bool {
+		if report.SharedLayers[i].Size != report.SharedLayers[j].Size {
+			return report.SharedLayers[i].Size > report.SharedLayers[j].Size
+		}
+		return report.SharedLayers[i].Digest < report.SharedLayers[j].Digest
+	})
+	sort.Slice(report.Images, func(i, j int) bool {
+		if report.Images[i].ExclusiveSize != report.Images[j].ExclusiveSize {
+			return report.Images[i].ExclusiveSize > report.Images[j].ExclusiveSize
+		}
+		return report.Images[i].Image < report.Images[j].Image
+	})
+
+	return report, nil
+}
+
+// imageLayers retrieves the digests of the layers of an image, when the image is an index the layers of
+// all the images in the index are returned. The layers are also registered in allLayers
+func imageLayers(imageRef string, fetcher ImageDescriptorFetcher, allLayers map[string]*LayerInfo) ([]string, error) {
+	ref, err := name.ParseReference(imageRef)
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := fetcher.Get(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var digests []string
+	if desc.MediaType.IsIndex() {
+		idx, err := desc.ImageIndex()
+		if err != nil {
+			return nil, err
+		}
+		manifest, err := idx.IndexManifest()
+		if err != nil {
+			return nil, err
+		}
+		for _, childDesc := range manifest.Manifests {
+			if !childDesc.MediaType.IsImage() {
+				continue
+			}
+			img, err := idx.Image(childDesc.Digest)
+			if err != nil {
+				return nil, err
+			}
+			childDigests, err := registerLayers(imageRef, img, allLayers)
+			if err != nil {
+				return nil, err
+			}
+			digests = append(digests, childDigests...)
+		}
+		return digests, nil
+	}
+
+	img, err := desc.Image()
+	if err != nil {
+		return nil, err
+	}
+	return registerLayers(imageRef, img, allLayers)
+}
+
+// registerLayers adds the layers of img to allLayers and returns their digests
+func registerLayers(imageRef string, img regv1.Image, allLayers map[string]*LayerInfo) ([]string, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	var digests []string
+	for _, layerDesc := range manifest.Layers {
+		digest := layerDesc.Digest.String()
+		layer, found := allLayers[digest]
+		if !found {
+			layer = &LayerInfo{Digest: digest, Size: layerDesc.Size}
+			allLayers[digest] = layer
+		}
+
+		alreadyInImage := false
+		for _, image := range layer.Images {
+			if image == imageRef {
+				alreadyInImage = true
+				break
+			}
+		}
+		if !alreadyInImage {
+			layer.Images = append(layer.Images, imageRef)
+			digests = append(digests, digest)
+		}
+	}
+	return digests, nil
+}
+
+// allImagesInDescription returns the location of all the images, including bundles, present in the description
+func allImagesInDescription(description Description) []string {
+	visited := map[string]struct{}{}
+	var result []string
+
+	var collect func(desc Description)
+	collect = func(desc Description) {
+		if _, ok := visited[desc.Image]; !ok {
+			visited[desc.Image] = struct{}{}
+			result = append(result, desc.Image)
+		}
+		for _, img := range desc.Content.Images {
+			if img.Error != "" {
+				continue
+			}
+			if _, ok := visited[img.Image]; !ok {
+				visited[img.Image] = struct{}{}
+				result = append(result, img.Image)
+			}
+		}
+		for _, b := range desc.Content.Bundles {
+			collect(b)
+		}
+	}
+	collect(description)
+
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestNewDedupReport(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegBuilder := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegBuilder.CleanUp()
+
+	sharedLayer, err := random.Layer(1000, "application/vnd.docker.image.rootfs.diff.tar.gzip")
+	require.NoError(t, err)
+	sharedLayerSize, err := sharedLayer.Size()
+	require.NoError(t, err)
+	sharedLayerDigest, err := sharedLayer.Digest()
+	require.NoError(t, err)
+
+	baseImg1, err := random.Image(500, 1)
+	require.NoError(t, err)
+	img1WithShared, err := mutate.AppendLayers(baseImg1, sharedLayer)
+	require.NoError(t, err)
+	baseImg2, err := random.Image(500, 2)
+	require.NoError(t, err)
+	img2WithShared, err := mutate.AppendLayers(baseImg2, sharedLayer)
+	require.NoError(t, err)
+
+	img1 := fakeRegBuilder.WithImage("app/img1", img1WithShared)
+	img2 := fakeRegBuilder.WithImage("app/img2", img2WithShared)
+	bundle := fakeRegBuilder.WithRandomImageWithLayers("app/bundle", 1)
+	fakeRegBuilder.Build()
+
+	description := v1.Description{
+		Image: bundle.RefDigest,
+		Content: v1.Content{
+			Images: map[string]v1.ImageInfo{
+				img1.Digest: {Image: img1.RefDigest},
+				img2.Digest: {Image: img2.RefDigest},
+				"failed":    {Error: "some error"},
+			},
+		},
+	}
+
+	report, err := v1.NewDedupReport(description, registry.Opts{EnvironFunc: os.Environ})
+	require.NoError(t, err)
+
+	require.Len(t, report.SharedLayers, 1)
+	assert.Equal(t, sharedLayerDigest.String(), report.SharedLayers[0].Digest)
+	assert.Equal(t, sharedLayerSize, report.SharedLayers[0].Size)
+	assert.ElementsMatch(t, []string{img1.RefDigest, img2.RefDigest}, report.SharedLayers[0].Images)
+
+	assert.Equal(t, sharedLayerSize, report.SavedSize)
+	assert.Equal(t, report.TotalSize-sharedLayerSize, report.UniqueSize)
+
+	require.Len(t, report.Images, 3)
+	for _, img := range report.Images {
+		if img.Image == bundle.RefDigest {
+			assert.Equal(t, img.Size, img.ExclusiveSize)
+		} else {
+			assert.Equal(t, img.Size-sharedLayerSize, img.ExclusiveSize)
+		}
+	}
+}
@@ -1,10 +1,19 @@
 // Copyright 2022 VMware, Inc.
 // SPDX-License-Identifier: Apache-2.0
 
-// Package v1 contains the public API version 1 used by other tools to interact with imgpkg
+// Package v1 contains the public API version 1 used by other tools to interact with imgpkg.
+//
+// Push, Pull, Copy and Describe are the entry points of the commands with the same name, each with an options struct
+// and a registry.Opts to configure the access to the registries. The functions ending in WithRegistry receive a
+// registry.Registry instead, to share it between calls. The Logger of the options receives the messages that the
+// commands write, nothing is logged when it is not provided.
+//
+// The operations are cancelled through the context received by the functions ending in WithContext. When it is done the
+// requests in progress, including the transfers of layers, are stopped and the functions return an error
 package v1
 
 import (
+	"context"
 	"fmt"
 	"sort"
 
@@ -40,6 +49,20 @@ type ImageInfo struct {
 	Annotations map[string]string `json:"annotations,omitempty"`
 	ImageType   bundle.ImageType  `json:"imageType"`
 	Error       string            `json:"error,omitempty"`
+	// Size Compressed size of the layers of the image, only present when sizes are included
+	Size int64 `json:"size,omitempty"`
+	// Layers Number of layers of the image, only present when sizes are included
+	Layers int `json:"layers,omitempty"`
+	// CosignArtifacts Signatures, attestations and SBOMs cosign attached to the image, only present when cosign artifacts are included
+	CosignArtifacts []CosignArtifact `json:"cosignArtifacts,omitempty"`
+}
+
+// CosignArtifact Signature, attestation or SBOM that cosign attached to an image
+type CosignArtifact struct {
+	// Type of the artifact, one of signature, attestation or sbom
+	Type   string `json:"type"`
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
 }
 
 // Content Contents present in a Bundle
@@ -54,7 +77,23 @@ type Description struct {
 	Origin      string            `json:"origin"`
 	Annotations map[string]string `json:"annotations,omitempty"`
 	Metadata    Metadata          `json:"metadata,omitempty"`
-	Content     Content           `json:"content"`
+	// Platforms the content of the bundle is intended for, as recorded when the bundle was pushed
+	Platforms []string `json:"platforms,omitempty"`
+	// CopyStats Statistics of the copy that placed the bundle in its location, only present for the described bundle
+	// when it was copied with --stamp-stats
+	CopyStats *bundle.CopyStats `json:"copyStats,omitempty"`
+	// CosignArtifacts Signatures, attestations and SBOMs cosign attached to the bundle, only present when cosign artifacts are included
+	CosignArtifacts []CosignArtifact `json:"cosignArtifacts,omitempty"`
+	// Size Compressed size of the layers of the bundle image, only present when sizes are included
+	Size int64 `json:"size,omitempty"`
+	// Layers Number of layers of the bundle image, only present when sizes are included
+	Layers int `json:"layers,omitempty"`
+	// TransferSize Compressed size of the distinct layers of the bundle, its nested bundles and all their images.
+	// Estimates the data written by copy --to-tar, only present for the described bundle when sizes are included
+	TransferSize int64 `json:"transferSize,omitempty"`
+	// NotExpanded True when the bundle is nested deeper than the requested depth and its content was not retrieved
+	NotExpanded bool    `json:"notExpanded,omitempty"`
+	Content     Content `json:"content"`
 }
 
 // DescribeOpts Options used when calling the Describe function
@@ -62,6 +101,11 @@ type DescribeOpts struct {
 	Logger                 bundle.Logger
 	Concurrency            int
 	IncludeCosignArtifacts bool
+	// Depth Number of levels of bundles, starting with the described bundle, whose content is retrieved.
+	// Bundles nested deeper are marked as not expanded. When zero every level is retrieved
+	Depth int
+	// IncludeSizes Retrieve the compressed size and number of layers of every bundle and image
+	IncludeSizes bool
 }
 
 // SignatureFetcher Interface to retrieve signatures associated with Images
@@ -71,7 +115,12 @@ type SignatureFetcher interface {
 
 // Describe Given a Bundle URL fetch the information about the contents of the Bundle and Nested Bundles
 func Describe(bundleImage string, opts DescribeOpts, registryOpts registry.Opts) (Description, error) {
-	reg, err := registry.NewSimpleRegistry(registryOpts)
+	return DescribeWithContext(context.Background(), bundleImage, opts, registryOpts)
+}
+
+// DescribeWithContext Same as Describe, the requests to the registries are cancelled when ctx is done
+func DescribeWithContext(ctx context.Context, bundleImage string, opts DescribeOpts, registryOpts registry.Opts) (Description, error) {
+	reg, err := registry.NewSimpleRegistryWithContext(ctx, registryOpts)
 	if err != nil {
 		return Description{}, err
 	}
@@ -80,7 +129,9 @@ func Describe(bundleImage string, opts DescribeOpts, registryOpts registry.Opts)
 	if !opts.IncludeCosignArtifacts {
 		signatureRetriever = signature.NewNoop()
 	} else {
-		signatureRetriever = signature.NewSignatures(signature.NewCosign(reg), opts.Concurrency)
+		signatureRetriever = signature.NewSignatures(signature.NewCosign(reg), opts.Concurrency).
+			WithFinder(signature.NewCosignAttestation(reg)).
+			WithFinder(signature.NewCosignSBOM(reg))
 	}
 
 	return DescribeWithRegistryAndSignatureFetcher(bundleImage, opts, reg, signatureRetriever)
@@ -89,7 +140,8 @@ func Describe(bundleImage string, opts DescribeOpts, registryOpts registry.Opts)
 // DescribeWithRegistryAndSignatureFetcher Given a Bundle URL fetch the information about the contents of the Bundle and Nested Bundles
 func DescribeWithRegistryAndSignatureFetcher(bundleImage string, opts DescribeOpts, reg bundle.ImagesMetadata, sigFetcher SignatureFetcher) (Description, error) {
 	lockReader := bundle.NewImagesLockReader()
-	newBundle := bundle.NewBundleFromRef(bundleImage, reg, lockReader, bundle.NewRegistryFetcher(reg, lockReader))
+	newBundle := bundle.NewBundleFromRef(bundleImage, reg, lockReader, bundle.NewRegistryFetcher(reg, lockReader)).
+		WithMaxDepth(opts.Depth)
 	isBundle, err := newBundle.IsBundle()
 	if err != nil {
 		return Description{}, fmt.Errorf("Unable to check if %s is a bundle: %s", bundleImage, err)
@@ -106,7 +158,27 @@ func DescribeWithRegistryAndSignatureFetcher(bundleImage string, opts DescribeOp
 	topBundle := refWithDescription{
 		imgRef: bundle.NewBundleImageRef(lockconfig.ImageRef{Image: newBundle.DigestRef()}),
 	}
-	return topBundle.DescribeBundle(allBundles), nil
+	description, err := topBundle.DescribeBundle(allBundles)
+	if err != nil {
+		return Description{}, err
+	}
+
+	bundleRef, err := name.NewDigest(newBundle.DigestRef())
+	if err != nil {
+		return Description{}, err
+	}
+	description.CopyStats, err = bundle.FetchCopyStats(reg, bundleRef)
+	if err != nil {
+		return Description{}, err
+	}
+
+	if opts.IncludeSizes {
+		description, err = addSizes(description, reg)
+		if err != nil {
+			return Description{}, err
+		}
+	}
+	return description, nil
 }
 
 type refWithDescription struct {
@@ -114,15 +186,15 @@ type refWithDescription struct {
 	bundle Description
 }
 
-func (r *refWithDescription) DescribeBundle(bundles []*bundle.Bundle) Description {
+func (r *refWithDescription) DescribeBundle(bundles []*bundle.Bundle) (Description, error) {
 	var visitedImgs map[string]refWithDescription
 	return r.describeBundleRec(visitedImgs, r.imgRef, bundles)
 }
 
-func (r *refWithDescription) describeBundleRec(visitedImgs map[string]refWithDescription, currentBundle bundle.ImageRef, bundles []*bundle.Bundle) Description {
+func (r *refWithDescription) describeBundleRec(visitedImgs map[string]refWithDescription, currentBundle bundle.ImageRef, bundles []*bundle.Bundle) (Description, error) {
 	desc, wasVisited := visitedImgs[currentBundle.Image]
 	if wasVisited {
-		return desc.bundle
+		return desc.bundle, nil
 	}
 
 	desc = refWithDescription{
@@ -146,25 +218,44 @@ func (r *refWithDescription) describeBundleRec(visitedImgs map[string]refWithDes
 		}
 	}
 	if newBundle == nil {
-		panic(fmt.Sprintf("Internal consistency: bundle with ref '%s' could not be found in list of bundles", currentBundle.PrimaryLocation()))
+		// Bundles nested deeper than the requested depth are not retrieved
+		desc.bundle.NotExpanded = true
+		return desc.bundle, nil
 	}
 
+	platforms, err := newBundle.Platforms()
+	if err != nil {
+		return Description{}, fmt.Errorf("Retrieving platforms of bundle %s: %s", newBundle.DigestRef(), err)
+	}
+	desc.bundle.Platforms = platforms
+
 	imagesRefs := newBundle.ImagesRefsWithErrors()
 	sort.Slice(imagesRefs, func(i, j int) bool {
 		return imagesRefs[i].Image < imagesRefs[j].Image
 	})
 
+	cosignArtifacts := cosignArtifactsBySubject(imagesRefs)
+	if bundleDigest, err := name.NewDigest(currentBundle.PrimaryLocation()); err == nil {
+		desc.bundle.CosignArtifacts = cosignArtifacts[bundleDigest.DigestStr()]
+	}
+
 	for _, ref := range imagesRefs {
 		if ref.IsBundle == nil {
 			panic("Internal consistency: IsBundle after processing must always have a value")
 		}
 
 		if *ref.IsBundle {
-			bundleDesc := r.describeBundleRec(visitedImgs, ref, bundles)
+			bundleDesc, err := r.describeBundleRec(visitedImgs, ref, bundles)
+			if err != nil {
+				return Description{}, err
+			}
 			digest, err := name.NewDigest(bundleDesc.Image)
 			if err != nil {
 				panic(fmt.Sprintf("Internal inconsistency: image %s should be fully resolved", bundleDesc.Image))
 			}
+			if bundleDesc.NotExpanded {
+				bundleDesc.CosignArtifacts = cosignArtifacts[digest.DigestStr()]
+			}
 			desc.bundle.Content.Bundles[digest.DigestStr()] = bundleDesc
 		} else {
 			if ref.Error == "" {
@@ -173,10 +264,11 @@ func (r *refWithDescription) describeBundleRec(visitedImgs map[string]refWithDes
 					panic(fmt.Sprintf("Internal inconsistency: image %s should be fully resolved", ref.Image))
 				}
 				desc.bundle.Content.Images[digest.DigestStr()] = ImageInfo{
-					Image:       ref.PrimaryLocation(),
-					Origin:      ref.Image,
-					Annotations: ref.Annotations,
-					ImageType:   ref.ImageType,
+					Image:           ref.PrimaryLocation(),
+					Origin:          ref.Image,
+					Annotations:     ref.Annotations,
+					ImageType:       ref.ImageType,
+					CosignArtifacts: cosignArtifacts[digest.DigestStr()],
 				}
 			} else {
 				desc.bundle.Content.Images[ref.Image] = ImageInfo{
@@ -187,5 +279,38 @@ func (r *refWithDescription) describeBundleRec(visitedImgs map[string]refWithDes
 		}
 	}
 
-	return desc.bundle
+	return desc.bundle, nil
+}
+
+// cosignArtifactsBySubject Groups the cosign artifacts found for the images of a bundle by the digest of the image they are attached to
+func cosignArtifactsBySubject(imagesRefs []bundle.ImageRef) map[string][]CosignArtifact {
+	artifacts := map[string][]CosignArtifact{}
+	for _, ref := range imagesRefs {
+		if ref.ImageType != bundle.SignatureImage || ref.Error != "" {
+			continue
+		}
+		subject, artifactType, ok := signature.CosignArtifactFromTag(ref.Annotations["tag"])
+		if !ok {
+			continue
+		}
+		digest, err := name.NewDigest(ref.PrimaryLocation())
+		if err != nil {
+			continue
+		}
+		artifacts[subject.String()] = append(artifacts[subject.String()], CosignArtifact{
+			Type:   artifactType,
+			Image:  digest.Name(),
+			Digest: digest.DigestStr(),
+		})
+	}
+
+	for _, subjectArtifacts := range artifacts {
+		sort.Slice(subjectArtifacts, func(i, j int) bool {
+			if subjectArtifacts[i].Type != subjectArtifacts[j].Type {
+				return subjectArtifacts[i].Type < subjectArtifacts[j].Type
+			}
+			return subjectArtifacts[i].Image < subjectArtifacts[j].Image
+		})
+	}
+	return artifacts
 }
@@ -4,6 +4,7 @@
 package v1_test
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/google/go-containerregistry/pkg/name"
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	ctlbundle "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
@@ -367,6 +369,156 @@ func TestDescribeBundle(t *testing.T) {
 		require.Equal(t, ctlbundle.ImageType("Signature"), bundleDescription.Content.Images[keySignToDeny.String()].ImageType)
 		require.Equal(t, "access denied", bundleDescription.Content.Images[keySignToDeny.String()].Error)
 	})
+
+	t.Run("When cosign artifacts are attached to images and bundles, it lists them in the image they are attached to", func(t *testing.T) {
+		fakeRegBuilder := helpers.NewFakeRegistry(t, logger)
+		img1 := fakeRegBuilder.WithRandomImage("other-repo/some-random-img")
+		img1Hash, err := regv1.NewHash(img1.Digest)
+		require.NoError(t, err)
+		b := fakeRegBuilder.
+			WithRandomBundle("repo/bundle-with-cosign-artifacts").
+			WithImageRefs([]lockconfig.ImageRef{{Image: img1.RefDigest}})
+		bundleHash, err := regv1.NewHash(b.Digest)
+		require.NoError(t, err)
+
+		img1SigTag := cosign.Munge(regv1.Descriptor{Digest: img1Hash})
+		img1Sig := fakeRegBuilder.WithRandomTaggedImage(img1.RefDigest, img1SigTag)
+		img1Att := fakeRegBuilder.WithRandomTaggedImage(img1.RefDigest, strings.TrimSuffix(img1SigTag, ".sig")+".att")
+		img1SBOM := fakeRegBuilder.WithRandomTaggedImage(img1.RefDigest, strings.TrimSuffix(img1SigTag, ".sig")+".sbom")
+		bundleSig := fakeRegBuilder.WithRandomTaggedImage(b.RefDigest, cosign.Munge(regv1.Descriptor{Digest: bundleHash}))
+		fakeRegBuilder.Build()
+
+		bundleDescription, err := v1.Describe(b.RefDigest, v1.DescribeOpts{
+			Logger:                 logger,
+			Concurrency:            1,
+			IncludeCosignArtifacts: true,
+		},
+			registry.Opts{
+				EnvironFunc: os.Environ,
+				RetryCount:  3,
+			},
+		)
+		require.NoError(t, err)
+
+		require.Equal(t, []v1.CosignArtifact{
+			{Type: "signature", Image: bundleSig.RefDigest, Digest: bundleSig.Digest},
+		}, bundleDescription.CosignArtifacts)
+		require.Equal(t, []v1.CosignArtifact{
+			{Type: "attestation", Image: img1Att.RefDigest, Digest: img1Att.Digest},
+			{Type: "sbom", Image: img1SBOM.RefDigest, Digest: img1SBOM.Digest},
+			{Type: "signature", Image: img1Sig.RefDigest, Digest: img1Sig.Digest},
+		}, bundleDescription.Content.Images[img1.Digest].CosignArtifacts)
+	})
+
+	t.Run("When a depth is provided, it does not retrieve the content of the bundles nested deeper", func(t *testing.T) {
+		fakeRegBuilder := helpers.NewFakeRegistry(t, logger)
+		img1 := fakeRegBuilder.WithRandomImage("app/img1")
+		img2 := fakeRegBuilder.WithRandomImage("app/img2")
+		innerBundle := fakeRegBuilder.
+			WithRandomBundle("app/inner-bundle").
+			WithImageRefs([]lockconfig.ImageRef{{Image: img1.RefDigest}})
+		middleBundle := fakeRegBuilder.
+			WithRandomBundle("app/middle-bundle").
+			WithImageRefs([]lockconfig.ImageRef{{Image: innerBundle.RefDigest}, {Image: img2.RefDigest}})
+		topBundle := fakeRegBuilder.
+			WithRandomBundle("app/top-bundle").
+			WithImageRefs([]lockconfig.ImageRef{{Image: middleBundle.RefDigest}})
+		fakeRegBuilder.Build()
+
+		bundleDescription, err := v1.Describe(topBundle.RefDigest, v1.DescribeOpts{
+			Logger:      logger,
+			Concurrency: 1,
+			Depth:       2,
+		},
+			registry.Opts{
+				EnvironFunc: os.Environ,
+				RetryCount:  3,
+			},
+		)
+		require.NoError(t, err)
+
+		require.False(t, bundleDescription.NotExpanded)
+		require.Len(t, bundleDescription.Content.Bundles, 1)
+		middleDescription := bundleDescription.Content.Bundles[middleBundle.Digest]
+		require.False(t, middleDescription.NotExpanded)
+		require.Contains(t, middleDescription.Content.Images, img2.Digest)
+		require.Len(t, middleDescription.Content.Bundles, 1)
+
+		innerDescription := middleDescription.Content.Bundles[innerBundle.Digest]
+		require.True(t, innerDescription.NotExpanded)
+		require.Equal(t, innerBundle.RefDigest, innerDescription.Origin)
+		require.Empty(t, innerDescription.Content.Images)
+		require.Empty(t, innerDescription.Content.Bundles)
+	})
+
+	t.Run("When sizes are included, it provides the size and layers of each image and the transfer size of the bundle", func(t *testing.T) {
+		fakeRegBuilder := helpers.NewFakeRegistry(t, logger)
+		randomImg, err := random.Image(500, 3)
+		require.NoError(t, err)
+		img1 := fakeRegBuilder.WithImage("app/img1", randomImg)
+		nestedBundle := fakeRegBuilder.
+			WithRandomBundle("app/nested-bundle").
+			WithImageRefs([]lockconfig.ImageRef{{Image: img1.RefDigest}})
+		topBundle := fakeRegBuilder.
+			WithRandomBundle("app/top-bundle").
+			WithImageRefs([]lockconfig.ImageRef{{Image: nestedBundle.RefDigest}, {Image: img1.RefDigest}})
+		fakeRegBuilder.Build()
+
+		bundleDescription, err := v1.Describe(topBundle.RefDigest, v1.DescribeOpts{
+			Logger:       logger,
+			Concurrency:  1,
+			IncludeSizes: true,
+		},
+			registry.Opts{
+				EnvironFunc: os.Environ,
+				RetryCount:  3,
+			},
+		)
+		require.NoError(t, err)
+
+		img1Size := int64(0)
+		img1Layers, err := randomImg.Layers()
+		require.NoError(t, err)
+		for _, layer := range img1Layers {
+			size, err := layer.Size()
+			require.NoError(t, err)
+			img1Size += size
+		}
+
+		img1Info := bundleDescription.Content.Images[img1.Digest]
+		require.Equal(t, img1Size, img1Info.Size)
+		require.Equal(t, 3, img1Info.Layers)
+
+		nestedDescription := bundleDescription.Content.Bundles[nestedBundle.Digest]
+		require.Equal(t, img1Info, nestedDescription.Content.Images[img1.Digest])
+		require.Greater(t, nestedDescription.Size, int64(0))
+		require.Greater(t, bundleDescription.Size, int64(0))
+
+		// img1 is present in both bundles but its layers are only transferred once
+		require.Equal(t, bundleDescription.Size+nestedDescription.Size+img1Size, bundleDescription.TransferSize)
+		require.Zero(t, nestedDescription.TransferSize)
+	})
+
+	t.Run("When the context is done, it stops retrieving the bundle and returns the error", func(t *testing.T) {
+		fakeRegBuilder := helpers.NewFakeRegistry(t, logger)
+		topBundle := fakeRegBuilder.WithRandomBundle("app/top-bundle")
+		fakeRegBuilder.Build()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := v1.DescribeWithContext(ctx, topBundle.RefDigest, v1.DescribeOpts{
+			Logger:      logger,
+			Concurrency: 1,
+		},
+			registry.Opts{
+				EnvironFunc: os.Environ,
+				RetryCount:  3,
+			},
+		)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), context.Canceled.Error())
+	})
 }
 
 type testImage struct {
@@ -0,0 +1,32 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"fmt"
+
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+// IsBundle Checks if imageRef points to a Bundle, only the manifest and configuration of the image are retrieved
+// Image indexes and plain images are not Bundles
+func IsBundle(imageRef string, registryOpts registry.Opts) (bool, error) {
+	reg, err := registry.NewSimpleRegistry(registryOpts)
+	if err != nil {
+		return false, err
+	}
+
+	return IsBundleWithRegistry(imageRef, reg)
+}
+
+// IsBundleWithRegistry Checks if imageRef points to a Bundle using the provided registry
+func IsBundleWithRegistry(imageRef string, reg bundle.ImagesMetadata) (bool, error) {
+	lockReader := bundle.NewImagesLockReader()
+	isBundle, err := bundle.NewBundleFromRef(imageRef, reg, lockReader, bundle.NewRegistryFetcher(reg, lockReader)).IsBundle()
+	if err != nil {
+		return false, fmt.Errorf("Unable to check if %s is a bundle: %s", imageRef, err)
+	}
+	return isBundle, nil
+}
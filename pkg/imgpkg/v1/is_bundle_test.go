@@ -0,0 +1,45 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestIsBundle(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	bundleInfo := fakeRegistry.WithRandomBundle("some/bundle")
+	img := fakeRegistry.WithRandomImage("some/image")
+	index := fakeRegistry.WithARandomImageIndex("some/index", 2)
+	defer fakeRegistry.CleanUp()
+	fakeRegistry.Build()
+
+	t.Run("when the reference is a bundle, it returns true", func(t *testing.T) {
+		isBundle, err := v1.IsBundle(bundleInfo.RefDigest, registry.Opts{})
+		require.NoError(t, err)
+		require.True(t, isBundle)
+	})
+
+	t.Run("when the reference is a plain image, it returns false", func(t *testing.T) {
+		isBundle, err := v1.IsBundle(img.RefDigest, registry.Opts{})
+		require.NoError(t, err)
+		require.False(t, isBundle)
+	})
+
+	t.Run("when the reference is an image index, it returns false", func(t *testing.T) {
+		isBundle, err := v1.IsBundle(index.RefDigest, registry.Opts{})
+		require.NoError(t, err)
+		require.False(t, isBundle)
+	})
+
+	t.Run("when the reference does not exist, it returns an error", func(t *testing.T) {
+		_, err := v1.IsBundle(fakeRegistry.ReferenceOnTestServer("some/missing:1.0.0"), registry.Opts{})
+		require.ErrorContains(t, err, "Unable to check if")
+	})
+}
@@ -4,13 +4,18 @@
 package v1
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"path/filepath"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/image"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/plainimage"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/signature"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/workspace"
 )
 
 // Logger Interface used for logging
@@ -29,6 +34,31 @@ type PullOpts struct {
 	AsImage bool
 	// IsBundle the image being pulled is a Bundle
 	IsBundle bool
+	// ConfigOnly Only pull the .imgpkg directory and the labels and annotations of the Bundle
+	ConfigOnly bool
+	// CacheDir when provided the extracted contents of bundles are kept in this folder and reused by following pulls
+	CacheDir string
+	// LinkMode How files are placed in the output folder when they are present in CacheDir, defaults to image.LinkModeReflink
+	LinkMode image.LinkMode
+	// WarnOnContentsMismatch Report the files that do not match the contents manifest of the bundle as warnings
+	// instead of failing the pull
+	WarnOnContentsMismatch bool
+	// LocationsRepo Repository where the locations images of the bundle are looked up, defaults to the repository of the bundle
+	LocationsRepo string
+	// TrustConfig When provided the image being pulled must be signed by the keys required for its origin
+	TrustConfig *signature.TrustConfig
+	// Workspace Where the temporary files created during the pull are kept. When not provided a workspace is created
+	// in the default temporary directory and removed when the pull finishes
+	Workspace *workspace.Workspace
+	// NestedImagesLock How the ImagesLock of the nested bundles is written on a recursive pull, defaults to
+	// bundle.NestedImagesLockCollocated
+	NestedImagesLock bundle.NestedImagesLockMode
+	// NestedImagesLockMapping New location of each image, by its original location, written to the ImagesLock of the
+	// nested bundles when NestedImagesLock is bundle.NestedImagesLockMapping
+	NestedImagesLockMapping map[string]string
+	// HTTPClient Client the external artifacts of the bundle are downloaded with. When not provided Pull and
+	// PullRecursive create one with the TLS configuration and context of the registry options
+	HTTPClient *http.Client
 }
 
 // ImagesLockInfo Information about the ImagesLock file
@@ -85,7 +115,17 @@ type PullStatus struct {
 
 // Pull Download the contents of the image referenced by imageRef to the folder outputPath
 func Pull(imageRef string, outputPath string, pullOptions PullOpts, registryOpts registry.Opts) (PullStatus, error) {
-	reg, err := registry.NewSimpleRegistry(registryOpts)
+	return PullWithContext(context.Background(), imageRef, outputPath, pullOptions, registryOpts)
+}
+
+// PullWithContext Same as Pull, the downloads, including the external artifacts of a Bundle, are cancelled when ctx
+// is done
+func PullWithContext(ctx context.Context, imageRef string, outputPath string, pullOptions PullOpts, registryOpts registry.Opts) (PullStatus, error) {
+	reg, err := registry.NewSimpleRegistryWithContext(ctx, registryOpts)
+	if err != nil {
+		return PullStatus{}, err
+	}
+	pullOptions, err = withHTTPClient(ctx, pullOptions, registryOpts)
 	if err != nil {
 		return PullStatus{}, err
 	}
@@ -94,6 +134,11 @@ func Pull(imageRef string, outputPath string, pullOptions PullOpts, registryOpts
 
 // PullWithRegistry Download the contents of the image referenced by imageRef to the folder outputPath
 func PullWithRegistry(imageRef string, outputPath string, pullOptions PullOpts, reg registry.Registry) (PullStatus, error) {
+	err := verifySignature(imageRef, pullOptions, reg)
+	if err != nil {
+		return PullStatus{}, err
+	}
+
 	imagesLockReader := bundle.NewImagesLockReader()
 	bundleToPull := bundle.NewBundleFromRef(imageRef, reg, imagesLockReader, bundle.NewRegistryFetcher(reg, imagesLockReader))
 	isBundle, err := bundleToPull.IsBundle()
@@ -102,6 +147,9 @@ func PullWithRegistry(imageRef string, outputPath string, pullOptions PullOpts,
 	}
 
 	switch {
+	case !isBundle && pullOptions.ConfigOnly: // Trying to pull the configuration of an Image
+		return PullStatus{}, &ErrIsNotBundle{}
+
 	case isBundle && pullOptions.AsImage: // Trying to pull the OCI Image of a Bundle
 		st, err := pullImage(imageRef, outputPath, pullOptions, reg)
 		if err != nil {
@@ -111,7 +159,7 @@ func PullWithRegistry(imageRef string, outputPath string, pullOptions PullOpts,
 		return st, nil
 
 	case isBundle && pullOptions.IsBundle: // Trying to pull a Bundle
-		return pullBundle(imageRef, bundleToPull, outputPath, pullOptions, false)
+		return pullBundle(imageRef, bundleToPull, outputPath, pullOptions, reg, false)
 
 	case !isBundle && pullOptions.IsBundle: // Trying to pull an Image as a Bundle
 		return PullStatus{}, &ErrIsNotBundle{}
@@ -129,7 +177,16 @@ func PullWithRegistry(imageRef string, outputPath string, pullOptions PullOpts,
 // PullRecursive Downloads the contents of the Bundle and Nested Bundles referenced by imageRef to the folder outputPath.
 // This functions should error out when imageRef does not point to a Bundle
 func PullRecursive(imageRef string, outputPath string, pullOptions PullOpts, registryOpts registry.Opts) (PullStatus, error) {
-	reg, err := registry.NewSimpleRegistry(registryOpts)
+	return PullRecursiveWithContext(context.Background(), imageRef, outputPath, pullOptions, registryOpts)
+}
+
+// PullRecursiveWithContext Same as PullRecursive, the downloads are cancelled when ctx is done
+func PullRecursiveWithContext(ctx context.Context, imageRef string, outputPath string, pullOptions PullOpts, registryOpts registry.Opts) (PullStatus, error) {
+	reg, err := registry.NewSimpleRegistryWithContext(ctx, registryOpts)
+	if err != nil {
+		return PullStatus{}, err
+	}
+	pullOptions, err = withHTTPClient(ctx, pullOptions, registryOpts)
 	if err != nil {
 		return PullStatus{}, err
 	}
@@ -140,6 +197,11 @@ func PullRecursive(imageRef string, outputPath string, pullOptions PullOpts, reg
 // PullRecursiveWithRegistry Downloads the contents of the Bundle and Nested Bundles referenced by imageRef to the folder outputPath.
 // This functions should error out when imageRef does not point to a Bundle
 func PullRecursiveWithRegistry(imageRef string, outputPath string, pullOptions PullOpts, reg registry.Registry) (PullStatus, error) {
+	err := verifySignature(imageRef, pullOptions, reg)
+	if err != nil {
+		return PullStatus{}, err
+	}
+
 	imagesLockReader := bundle.NewImagesLockReader()
 	bundleToPull := bundle.NewBundleFromRef(imageRef, reg, imagesLockReader, bundle.NewRegistryFetcher(reg, imagesLockReader))
 	isBundle, err := bundleToPull.IsBundle()
@@ -150,13 +212,61 @@ func PullRecursiveWithRegistry(imageRef string, outputPath string, pullOptions P
 		return PullStatus{}, &ErrIsNotBundle{}
 	}
 
-	return pullBundle(imageRef, bundleToPull, outputPath, pullOptions, true)
+	return pullBundle(imageRef, bundleToPull, outputPath, pullOptions, reg, true)
 }
 
 // pullBundle Downloads the contents of the Bundle Image referenced by imageRef to the folder outputPath.
 // This functions should error out when imageRef does not point to a Bundle
-func pullBundle(imgRef string, bundleToPull *bundle.Bundle, outputPath string, pullOptions PullOpts, pullNestedBundles bool) (PullStatus, error) {
-	isRootBundleRelocated, err := bundleToPull.Pull(outputPath, pullOptions.Logger, pullNestedBundles)
+func pullBundle(imgRef string, bundleToPull *bundle.Bundle, outputPath string, pullOptions PullOpts, reg registry.Registry, pullNestedBundles bool) (PullStatus, error) {
+	if pullOptions.CacheDir != "" {
+		linkMode := pullOptions.LinkMode
+		if linkMode == "" {
+			linkMode = image.LinkModeReflink
+		}
+		bundleToPull.WithLayerCache(image.NewLayerCache(pullOptions.CacheDir, linkMode))
+	}
+
+	if pullOptions.WarnOnContentsMismatch {
+		bundleToPull.WithContentsMismatchWarnings()
+	}
+	if pullOptions.HTTPClient != nil {
+		bundleToPull.WithHTTPClient(pullOptions.HTTPClient)
+	}
+
+	if pullOptions.NestedImagesLock == bundle.NestedImagesLockMapping && len(pullOptions.NestedImagesLockMapping) == 0 {
+		return PullStatus{}, fmt.Errorf("Expected a mapping of the images to write the ImagesLock of the nested bundles")
+	}
+	bundleToPull.WithNestedImagesLock(pullOptions.NestedImagesLock, pullOptions.NestedImagesLockMapping)
+
+	if pullOptions.LocationsRepo != "" {
+		locationsRepo, err := name.NewRepository(pullOptions.LocationsRepo)
+		if err != nil {
+			return PullStatus{}, fmt.Errorf("Parsing locations repository '%s': %s", pullOptions.LocationsRepo, err)
+		}
+		bundleToPull.WithLocationsRepo(locationsRepo)
+	}
+
+	ws := pullOptions.Workspace
+	if ws == nil {
+		var err error
+		ws, err = workspace.New("")
+		if err != nil {
+			return PullStatus{}, err
+		}
+		defer ws.Cleanup()
+	}
+	bundleToPull.WithWorkspace(ws)
+
+	err := verifyBundleImagesSignatures(bundleToPull, pullOptions, reg)
+	if err != nil {
+		return PullStatus{}, err
+	}
+
+	pull := bundleToPull.Pull
+	if pullOptions.ConfigOnly {
+		pull = bundleToPull.PullConfig
+	}
+	isRootBundleRelocated, err := pull(outputPath, pullOptions.Logger, pullNestedBundles)
 	if err != nil {
 		return PullStatus{}, err
 	}
@@ -166,7 +276,14 @@ func pullBundle(imgRef string, bundleToPull *bundle.Bundle, outputPath string, p
 		return PullStatus{}, err
 	}
 
-	bInfo := buildBundleInfoFromBundle(bundleToPull, isRootBundleRelocated)
+	nestedUpdated := isRootBundleRelocated
+	switch pullOptions.NestedImagesLock {
+	case bundle.NestedImagesLockOrigin:
+		nestedUpdated = false
+	case bundle.NestedImagesLockMapping:
+		nestedUpdated = true
+	}
+	bInfo := buildBundleInfoFromBundle(bundleToPull, nestedUpdated)
 	return PullStatus{
 		BundleInfo: BundleInfo{
 			ImageRef: bundleToPull.DigestRef(),
@@ -208,6 +325,19 @@ func pullImage(imageRef string, outputPath string, pullOptions PullOpts, reg reg
 	}, nil
 }
 
+// withHTTPClient Returns the options with a client created from the registry options, when they do not provide one
+func withHTTPClient(ctx context.Context, pullOptions PullOpts, registryOpts registry.Opts) (PullOpts, error) {
+	if pullOptions.HTTPClient != nil {
+		return pullOptions, nil
+	}
+	client, err := registry.NewHTTPClient(ctx, registryOpts)
+	if err != nil {
+		return PullOpts{}, err
+	}
+	pullOptions.HTTPClient = client
+	return pullOptions, nil
+}
+
 func isCacheable(imageRef string, isRootBundleRelocated bool) (bool, error) {
 	_, err := name.NewDigest(imageRef)
 	if err != nil {
@@ -223,3 +353,38 @@ func isCacheable(imageRef string, isRootBundleRelocated bool) (bool, error) {
 
 	return false, nil
 }
+
+// verifySignature Checks the image is signed by the keys the TrustConfig requires for its origin, when provided
+func verifySignature(imageRef string, pullOptions PullOpts, reg registry.Registry) error {
+	if pullOptions.TrustConfig == nil {
+		return nil
+	}
+
+	ref, err := name.ParseReference(imageRef)
+	if err != nil {
+		return fmt.Errorf("Parsing '%s': %s", imageRef, err)
+	}
+	digest, err := reg.Digest(ref)
+	if err != nil {
+		return err
+	}
+
+	return signature.NewVerifier(*pullOptions.TrustConfig, reg, pullOptions.Logger).Verify(ref.Context().Digest(digest.String()))
+}
+
+// verifyBundleImagesSignatures Checks all the images and nested bundles referenced by the bundle are signed by the keys
+// the TrustConfig requires for their origin, when provided, reporting the outcome of each nesting level and all the
+// images that failed the verification
+func verifyBundleImagesSignatures(bundleToPull *bundle.Bundle, pullOptions PullOpts, reg registry.Registry) error {
+	if pullOptions.TrustConfig == nil {
+		return nil
+	}
+
+	bundles, _, err := bundleToPull.AllImagesLockRefs(1, pullOptions.Logger)
+	if err != nil {
+		return fmt.Errorf("Retrieving the images of bundle '%s': %s", bundleToPull.DigestRef(), err)
+	}
+
+	verifier := signature.NewVerifier(*pullOptions.TrustConfig, reg, pullOptions.Logger)
+	return bundleToPull.VerifyNestedSignatures(bundles, verifier, pullOptions.Logger)
+}
@@ -4,6 +4,12 @@
 package v1_test
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,9 +19,11 @@ import (
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/lockconfig"
 	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/signature"
 	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
 	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
 )
@@ -151,6 +159,44 @@ func TestPullBundle(t *testing.T) {
 		assertImagesLock(t, outputFolder, []string{img1.RefDigest, img2.RefDigest})
 	})
 
+	t.Run("when pulling only the configuration, it only extracts the .imgpkg folder and the image metadata", func(t *testing.T) {
+		outputFolder := t.TempDir()
+
+		opts := v1.PullOpts{
+			Logger:     uiLogger,
+			AsImage:    false,
+			IsBundle:   true,
+			ConfigOnly: true,
+		}
+		_, err := v1.Pull(randomBundle, outputFolder, opts, registry.Opts{})
+		require.NoError(t, err)
+
+		entries, err := os.ReadDir(outputFolder)
+		require.NoError(t, err)
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		assert.ElementsMatch(t, []string{".imgpkg", "image-metadata.yml"}, names)
+
+		assertImagesLock(t, outputFolder, []string{img1.RefDigest, img2.RefDigest})
+
+		metadata, err := os.ReadFile(filepath.Join(outputFolder, "image-metadata.yml"))
+		require.NoError(t, err)
+		assert.Contains(t, string(metadata), "dev.carvel.imgpkg.bundle")
+	})
+
+	t.Run("when pulling only the configuration of an image, it fails", func(t *testing.T) {
+		opts := v1.PullOpts{
+			Logger:     uiLogger,
+			AsImage:    false,
+			IsBundle:   false,
+			ConfigOnly: true,
+		}
+		_, err := v1.Pull(img1.RefDigest, t.TempDir(), opts, registry.Opts{})
+		require.ErrorIs(t, err, &v1.ErrIsNotBundle{})
+	})
+
 	t.Run("succeeds when pulling the bundle OCI image and does not update ImagesLock file", func(t *testing.T) {
 		outputFolder := t.TempDir()
 
@@ -397,6 +443,56 @@ func TestPullBundleRecursively(t *testing.T) {
 		assertImagesLock(t, filepath.Join(expectedNestedBundlePath), []string{colImg1.RefDigest, colImg2.RefDigest})
 	})
 
+	t.Run("when the ImagesLock of nested bundles is left at origin, it only updates the ImagesLock file of the root bundle", func(t *testing.T) {
+		outputFolder := t.TempDir()
+
+		opts := v1.PullOpts{
+			Logger:           uiLogger,
+			IsBundle:         true,
+			NestedImagesLock: bundle.NestedImagesLockOrigin,
+		}
+		status, err := v1.PullRecursive(collocatedBundleRef, outputFolder, opts, registry.Opts{})
+		require.NoError(t, err)
+		require.True(t, status.ImagesLock.Updated)
+		require.Len(t, status.NestedBundles, 1)
+		require.False(t, status.NestedBundles[0].ImagesLock.Updated)
+
+		assertImagesLock(t, outputFolder, []string{colImg1.RefDigest, colSimpleBundle.RefDigest})
+		assertImagesLock(t, filepath.Dir(filepath.Dir(status.NestedBundles[0].ImagesLock.Path)), []string{img1.RefDigest, img2.RefDigest})
+	})
+
+	t.Run("when the ImagesLock of nested bundles is written with a mapping, it rewrites the images of nested bundles to their destination", func(t *testing.T) {
+		outputFolder := t.TempDir()
+
+		opts := v1.PullOpts{
+			Logger:           uiLogger,
+			IsBundle:         true,
+			NestedImagesLock: bundle.NestedImagesLockMapping,
+			NestedImagesLockMapping: map[string]string{
+				img1.RefDigest: "mirror.io/images/image-1@" + img1.Digest,
+				img2.RefDigest: "mirror.io/images/image-2@" + img2.Digest,
+			},
+		}
+		status, err := v1.PullRecursive(collocatedBundleRef, outputFolder, opts, registry.Opts{})
+		require.NoError(t, err)
+		require.True(t, status.NestedBundles[0].ImagesLock.Updated)
+
+		assertImagesLock(t, outputFolder, []string{colImg1.RefDigest, colSimpleBundle.RefDigest})
+		assertImagesLock(t, filepath.Dir(filepath.Dir(status.NestedBundles[0].ImagesLock.Path)), []string{"mirror.io/images/image-1@" + img1.Digest, "mirror.io/images/image-2@" + img2.Digest})
+	})
+
+	t.Run("when an image of a nested bundle is not in the mapping, it fails", func(t *testing.T) {
+		opts := v1.PullOpts{
+			Logger:                  uiLogger,
+			IsBundle:                true,
+			NestedImagesLock:        bundle.NestedImagesLockMapping,
+			NestedImagesLockMapping: map[string]string{img1.RefDigest: "mirror.io/images/image-1@" + img1.Digest},
+		}
+		_, err := v1.PullRecursive(collocatedBundleRef, t.TempDir(), opts, registry.Opts{})
+		require.Error(t, err)
+		require.ErrorContains(t, err, "Expected image '"+img2.RefDigest+"' of nested bundle")
+	})
+
 	t.Run("when bundle is fully collocated it is cacheable", func(t *testing.T) {
 		outputFolder := t.TempDir()
 
@@ -448,6 +544,76 @@ func createBundle(fakeRegistry *helpers.FakeTestRegistryBuilder, bundleName stri
 	return randomBundle.WithImageRefs(imgs)
 }
 
+func TestPullBundleVerifiesImagesSignatures(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	img1 := fakeRegistry.WithRandomImage("images/image-1")
+	img2 := fakeRegistry.WithRandomImage("images/image-2")
+	bundleRef := createBundleWithImages(fakeRegistry, "some/bundle", []string{img1.RefDigest, img2.RefDigest})
+	defer fakeRegistry.CleanUp()
+	fakeRegistry.Build()
+
+	// Only the images of the bundle are in the origin, so the bundle itself is not verified
+	trustConfig := imagesTrustConfig(t, fakeRegistry)
+
+	opts := v1.PullOpts{
+		Logger:      util.NewNoopLevelLogger(),
+		IsBundle:    true,
+		TrustConfig: &trustConfig,
+	}
+	_, err := v1.Pull(bundleRef, t.TempDir(), opts, registry.Opts{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 image(s) failed signature verification:")
+	assert.Contains(t, err.Error(), "Verifying signature of '"+img1.RefDigest+"': Image is not signed")
+	assert.Contains(t, err.Error(), "Verifying signature of '"+img2.RefDigest+"': Image is not signed")
+}
+
+func TestPullBundleReportsSignatureVerificationOfEachNestingLevel(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	img1 := fakeRegistry.WithRandomImage("images/image-1")
+	img2 := fakeRegistry.WithRandomImage("images/image-2")
+	nestedBundleRef := createBundleWithImages(fakeRegistry, "some/nested-bundle", []string{img2.RefDigest})
+	bundleRef := createBundleWithImages(fakeRegistry, "some/bundle", []string{img1.RefDigest, nestedBundleRef})
+	defer fakeRegistry.CleanUp()
+	fakeRegistry.Build()
+
+	trustConfig := imagesTrustConfig(t, fakeRegistry)
+
+	output := bytes.NewBufferString("")
+	opts := v1.PullOpts{
+		Logger:      util.NewUILevelLogger(util.LogWarn, util.NewBufferLogger(output)),
+		IsBundle:    true,
+		TrustConfig: &trustConfig,
+	}
+	_, err := v1.Pull(bundleRef, t.TempDir(), opts, registry.Opts{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 image(s) failed signature verification:")
+	assert.Contains(t, output.String(), `Signature verification of the images of bundle '`+bundleRef+`':
+  level 1: 0 verified, 1 failed, 1 not verified
+  level 2: 0 verified, 1 failed, 0 not verified
+`)
+}
+
+// imagesTrustConfig TrustConfig that requires the images in the images repositories of the registry to be signed by a
+// key that did not sign any of them
+func imagesTrustConfig(t *testing.T, fakeRegistry *helpers.FakeTestRegistryBuilder) signature.TrustConfig {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	trustDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(trustDir, "cosign.pub"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: keyDER}), 0600))
+
+	trustConfig, err := signature.NewTrustConfigFromBytes([]byte(`
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: TrustConfig
+origins:
+- repository: `+fakeRegistry.ReferenceOnTestServer("images")+`
+  publicKeys: [cosign.pub]
+`), trustDir)
+	require.NoError(t, err)
+	return trustConfig
+}
+
 func createBundleWithImages(fakeRegistry *helpers.FakeTestRegistryBuilder, bundleName string, refs []string) string {
 	return createBundle(fakeRegistry, bundleName, refs).RefDigest
 }
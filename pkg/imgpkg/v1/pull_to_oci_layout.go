@@ -0,0 +1,91 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagetar"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/signature"
+)
+
+// PullToOCILayoutOpts Option that can be provided to PullToOCILayout
+type PullToOCILayoutOpts struct {
+	Logger      Logger
+	Concurrency int
+	// AsImage Write the image of a Bundle without its images, like any other image
+	AsImage bool
+	// IsBundle the image being pulled is a Bundle
+	IsBundle bool
+	// Recursive Also write the images of the bundle, and of its nested bundles, to the OCI image layout
+	Recursive bool
+	// IncludeNonDistributableLayers Include the layers marked as non-distributable in the OCI image layout
+	IncludeNonDistributableLayers bool
+	// TrustConfig When provided the image being pulled must be signed by the keys required for its origin
+	TrustConfig *signature.TrustConfig
+}
+
+// PullToOCILayout Writes the image, or bundle, referenced by imageRef to the folder outputPath following the OCI Image
+// Layout specification, instead of extracting its files, so that other OCI tools can consume it without a registry
+func PullToOCILayout(imageRef string, outputPath string, opts PullToOCILayoutOpts, registryOpts registry.Opts) (PullStatus, error) {
+	reg, err := registry.NewSimpleRegistry(registryOpts)
+	if err != nil {
+		return PullStatus{}, err
+	}
+	return PullToOCILayoutWithRegistry(imageRef, outputPath, opts, reg)
+}
+
+// PullToOCILayoutWithRegistry Writes the image, or bundle, referenced by imageRef to the folder outputPath following
+// the OCI Image Layout specification
+func PullToOCILayoutWithRegistry(imageRef string, outputPath string, opts PullToOCILayoutOpts, reg registry.Registry) (PullStatus, error) {
+	logger := opts.Logger
+	if logger == nil {
+		logger = util.NewNoopLevelLogger()
+	}
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	pullOpts := PullOpts{Logger: logger, TrustConfig: opts.TrustConfig}
+	err := verifySignature(imageRef, pullOpts, reg)
+	if err != nil {
+		return PullStatus{}, err
+	}
+
+	contents, err := archiveImageRefs(imageRef, opts.Recursive && !opts.AsImage, concurrency, logger, reg)
+	if err != nil {
+		return PullStatus{}, err
+	}
+	switch {
+	case !contents.isBundle && opts.IsBundle:
+		return PullStatus{}, &ErrIsNotBundle{}
+	case contents.isBundle && !opts.IsBundle && !opts.AsImage:
+		return PullStatus{}, &ErrIsBundle{}
+	}
+
+	if contents.isBundle && opts.TrustConfig != nil {
+		lockReader := bundle.NewImagesLockReader()
+		bundleToPull := bundle.NewBundleFromRef(contents.rootDigestRef, reg, lockReader, bundle.NewRegistryFetcher(reg, lockReader))
+		if _, err := bundleToPull.IsBundle(); err != nil {
+			return PullStatus{}, err
+		}
+		err = verifyBundleImagesSignatures(bundleToPull, pullOpts, reg)
+		if err != nil {
+			return PullStatus{}, err
+		}
+	}
+
+	store := imagetar.NewOCILayoutBlobStore(outputPath, concurrency, logger)
+	imageSet := imageset.NewImageSet(concurrency, logger, util.DefaultTagGenerator{})
+	_, err = imageset.NewTarImageSet(imageSet, concurrency, logger).
+		ExportToBlobStore(contents.imageRefs, store, reg, imagetar.NewImageLayerWriterCheck(opts.IncludeNonDistributableLayers))
+	if err != nil {
+		return PullStatus{}, err
+	}
+
+	return PullStatus{BundleInfo: BundleInfo{ImageRef: contents.rootDigestRef}, IsBundle: contents.isBundle}, nil
+}
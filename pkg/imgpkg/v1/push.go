@@ -0,0 +1,144 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/image"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/plainimage"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+)
+
+// PushOpts Option that can be provided to the push request
+type PushOpts struct {
+	Logger Logger
+	// IsBundle the files are pushed as a Bundle, they must contain a .imgpkg directory with an ImagesLock.
+	// When false the files are pushed as an OCI Image and cannot contain a .imgpkg directory
+	IsBundle bool
+	// ExcludedPaths paths of the files, and directories, that are not pushed
+	ExcludedPaths []string
+	// PreservePermissions keep the permissions of the group and others, by default only the owner permissions are kept
+	PreservePermissions bool
+	// Compression algorithm, and level, used to compress the layers, gzip when empty
+	Compression image.LayerCompression
+
+	// The following options can only be used when pushing a Bundle
+
+	// Platforms platforms the Bundle supports, recorded in an annotation of the Bundle (format: os/arch[/variant] or any)
+	Platforms []string
+	// FormatVersion version of the Bundle format recorded in the Bundle, the default format when 0
+	FormatVersion int
+	// ExternalArtifacts artifacts referenced by the Bundle without being copied with it
+	ExternalArtifacts []bundle.ExternalArtifactSource
+	// ContentsManifestAlgorithm when provided, a manifest with the digest of each file calculated with this algorithm
+	// is recorded in the Bundle and checked when it is pulled
+	ContentsManifestAlgorithm string
+	// SBOMFormat when provided, a software bill of materials of the images of the Bundle is pushed next to it in this format
+	SBOMFormat string
+	// AsArtifact push the Bundle with an OCI artifact manifest instead of an image manifest
+	AsArtifact bool
+}
+
+// PushStatus Report from the Push command
+type PushStatus struct {
+	// ImageRef reference to the digest of the pushed Image or Bundle
+	ImageRef string `json:"image"`
+	Digest   string `json:"digest"`
+	Tag      string `json:"tag"`
+}
+
+// Push Uploads the files in paths as an OCI Image, or as a Bundle when opts.IsBundle is true, tagged as imageRef
+func Push(imageRef string, paths []string, opts PushOpts, registryOpts registry.Opts) (PushStatus, error) {
+	return PushWithContext(context.Background(), imageRef, paths, opts, registryOpts)
+}
+
+// PushWithContext Same as Push, the uploads to the registry are cancelled when ctx is done
+func PushWithContext(ctx context.Context, imageRef string, paths []string, opts PushOpts, registryOpts registry.Opts) (PushStatus, error) {
+	reg, err := registry.NewSimpleRegistryWithContext(ctx, registryOpts)
+	if err != nil {
+		return PushStatus{}, err
+	}
+	return PushWithRegistry(imageRef, paths, opts, reg)
+}
+
+// PushWithRegistry Uploads the files in paths as an OCI Image, or as a Bundle when opts.IsBundle is true, tagged as
+// imageRef
+func PushWithRegistry(imageRef string, paths []string, opts PushOpts, reg registry.Registry) (PushStatus, error) {
+	logger := opts.Logger
+	if logger == nil {
+		logger = util.NewNoopLevelLogger()
+	}
+
+	if err := opts.Compression.Validate(); err != nil {
+		return PushStatus{}, err
+	}
+	uploadRef, err := regname.NewTag(imageRef, regname.WeakValidation)
+	if err != nil {
+		return PushStatus{}, fmt.Errorf("Parsing '%s': %s", imageRef, err)
+	}
+
+	var pushedRef string
+	if opts.IsBundle {
+		pushedRef, err = pushBundle(uploadRef, paths, opts, reg, logger)
+	} else {
+		pushedRef, err = pushImage(uploadRef, paths, opts, reg, logger)
+	}
+	if err != nil {
+		return PushStatus{}, err
+	}
+
+	digestRef, err := regname.NewDigest(pushedRef)
+	if err != nil {
+		return PushStatus{}, fmt.Errorf("Parsing '%s': %s", pushedRef, err)
+	}
+	return PushStatus{ImageRef: pushedRef, Digest: digestRef.DigestStr(), Tag: uploadRef.TagStr()}, nil
+}
+
+func pushBundle(uploadRef regname.Tag, paths []string, opts PushOpts, reg registry.Registry, logger Logger) (string, error) {
+	if opts.FormatVersion != 0 {
+		if err := bundle.ValidateFormatVersion(opts.FormatVersion); err != nil {
+			return "", err
+		}
+	}
+
+	contents := bundle.NewContents(paths, opts.ExcludedPaths, opts.PreservePermissions).
+		WithPlatforms(opts.Platforms).
+		WithFormatVersion(opts.FormatVersion).
+		WithExternalArtifacts(opts.ExternalArtifacts).
+		WithCompression(opts.Compression)
+	if opts.ContentsManifestAlgorithm != "" {
+		contents = contents.WithContentsManifest(opts.ContentsManifestAlgorithm)
+	}
+	if opts.SBOMFormat != "" {
+		contents = contents.WithSBOM(opts.SBOMFormat)
+	}
+	if opts.AsArtifact {
+		contents = contents.WithArtifactManifest()
+	}
+	return contents.Push(uploadRef, reg, logger)
+}
+
+func pushImage(uploadRef regname.Tag, paths []string, opts PushOpts, reg registry.Registry, logger Logger) (string, error) {
+	if len(opts.Platforms) > 0 || opts.FormatVersion != 0 || len(opts.ExternalArtifacts) > 0 ||
+		opts.ContentsManifestAlgorithm != "" || opts.SBOMFormat != "" || opts.AsArtifact {
+		return "", fmt.Errorf("Expected platforms, format version, external artifacts, contents manifest, SBOM and artifact manifest to only be used when pushing a bundle")
+	}
+
+	isBundle, err := bundle.NewContents(paths, opts.ExcludedPaths, opts.PreservePermissions).PresentsAsBundle()
+	if err != nil {
+		return "", err
+	}
+	if isBundle {
+		return "", fmt.Errorf("Images cannot be pushed with '.imgpkg' directories, consider pushing a bundle")
+	}
+
+	return plainimage.NewContents(paths, opts.ExcludedPaths, opts.PreservePermissions).
+		WithCompression(opts.Compression).
+		Push(uploadRef, nil, reg, logger)
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestPush(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegBuilder := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegBuilder.CleanUp()
+	fakeRegBuilder.Build()
+
+	bundleDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(bundleDir, ".imgpkg"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(bundleDir, ".imgpkg", "images.yml"), []byte(`---
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images: []
+`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(bundleDir, "config.yml"), []byte("key: value\n"), 0600))
+
+	imageDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(imageDir, "config.yml"), []byte("key: value\n"), 0600))
+
+	t.Run("when pushing a bundle, it returns the digest and the tag of the bundle", func(t *testing.T) {
+		bundleRef := fakeRegBuilder.ReferenceOnTestServer("app/bundle") + ":v1"
+		status, err := v1.Push(bundleRef, []string{bundleDir}, v1.PushOpts{Logger: logger, IsBundle: true, Platforms: []string{"linux/amd64"}}, registry.Opts{EnvironFunc: os.Environ})
+		require.NoError(t, err)
+
+		assert.Equal(t, fakeRegBuilder.ReferenceOnTestServer("app/bundle")+"@"+status.Digest, status.ImageRef)
+		assert.Equal(t, "v1", status.Tag)
+
+		isBundle, err := v1.IsBundle(status.ImageRef, registry.Opts{EnvironFunc: os.Environ})
+		require.NoError(t, err)
+		assert.True(t, isBundle)
+	})
+
+	t.Run("when pushing an image, it is not a bundle", func(t *testing.T) {
+		status, err := v1.Push(fakeRegBuilder.ReferenceOnTestServer("app/image"), []string{imageDir}, v1.PushOpts{Logger: logger}, registry.Opts{EnvironFunc: os.Environ})
+		require.NoError(t, err)
+		assert.Equal(t, "latest", status.Tag)
+
+		isBundle, err := v1.IsBundle(status.ImageRef, registry.Opts{EnvironFunc: os.Environ})
+		require.NoError(t, err)
+		assert.False(t, isBundle)
+	})
+
+	t.Run("when pushing an image with a .imgpkg directory, it fails", func(t *testing.T) {
+		_, err := v1.Push(fakeRegBuilder.ReferenceOnTestServer("app/image"), []string{bundleDir}, v1.PushOpts{Logger: logger}, registry.Opts{EnvironFunc: os.Environ})
+		require.EqualError(t, err, "Images cannot be pushed with '.imgpkg' directories, consider pushing a bundle")
+	})
+
+	t.Run("when pushing an image with options that only apply to bundles, it fails", func(t *testing.T) {
+		_, err := v1.Push(fakeRegBuilder.ReferenceOnTestServer("app/image"), []string{imageDir}, v1.PushOpts{Logger: logger, AsArtifact: true}, registry.Opts{EnvironFunc: os.Environ})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "only be used when pushing a bundle")
+	})
+}
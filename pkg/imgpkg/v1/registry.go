@@ -32,6 +32,11 @@ func OptsFromEnv(base registry.Opts, readEnv func(string) (string, bool)) regist
 		opts.EnableIaasAuthProviders = true
 	}
 
+	useHelpers, found := readEnv("IMGPKG_REGISTRY_USE_DOCKER_CREDENTIAL_HELPERS")
+	if found && strings.ToLower(useHelpers) == "false" {
+		opts.DisableDockerCredentialHelpers = true
+	}
+
 	keychains, found := readEnv("IMGPKG_ACTIVE_KEYCHAINS")
 	if found {
 		if len(keychains) > 0 {
@@ -45,5 +50,27 @@ func OptsFromEnv(base registry.Opts, readEnv func(string) (string, bool)) regist
 		}
 	}
 
+	headers, found := readEnv("IMGPKG_REGISTRY_HEADERS")
+	if found && len(headers) > 0 {
+		for _, header := range strings.Split(headers, ",") {
+			key, value, ok := strings.Cut(header, "=")
+			if !ok {
+				continue
+			}
+			key = strings.TrimSpace(key)
+			if opts.Headers == nil {
+				opts.Headers = map[string]string{}
+			}
+			// Headers provided explicitly take precedence over the environment
+			if _, alreadySet := opts.Headers[key]; !alreadySet {
+				opts.Headers[key] = strings.TrimSpace(value)
+			}
+		}
+	}
+
+	if len(opts.CacheDir) == 0 {
+		opts.CacheDir, _ = readEnv("IMGPKG_REGISTRY_CACHE_DIR")
+	}
+
 	return opts
 }
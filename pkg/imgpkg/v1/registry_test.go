@@ -88,6 +88,20 @@ func TestOptsFromEnv(t *testing.T) {
 		result := v1.OptsFromEnv(opts, env.Value)
 		require.Equal(t, registry.Opts{ActiveKeychains: []auth.IAASKeychain{"acr"}}, result)
 	})
+
+	t.Run("when a list of headers is provided it adds them to the headers", func(t *testing.T) {
+		env := envFake{values: map[string]string{"IMGPKG_REGISTRY_HEADERS": "X-Trace-Id=abc, X-Cost-Center=team-a"}}
+		opts := registry.Opts{}
+		result := v1.OptsFromEnv(opts, env.Value)
+		require.Equal(t, registry.Opts{Headers: map[string]string{"X-Trace-Id": "abc", "X-Cost-Center": "team-a"}}, result)
+	})
+
+	t.Run("when a header is already defined it does not overwrite it", func(t *testing.T) {
+		env := envFake{values: map[string]string{"IMGPKG_REGISTRY_HEADERS": "X-Trace-Id=not-used,X-Cost-Center=team-a"}}
+		opts := registry.Opts{Headers: map[string]string{"X-Trace-Id": "some-trace"}}
+		result := v1.OptsFromEnv(opts, env.Value)
+		require.Equal(t, registry.Opts{Headers: map[string]string{"X-Trace-Id": "some-trace", "X-Cost-Center": "team-a"}}, result)
+	})
 }
 
 type envFake struct {
@@ -0,0 +1,378 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/signature"
+)
+
+// SBOMComponent Package listed in an SBOM
+type SBOMComponent struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// SBOMComponentChange Package listed in both SBOMs with a different version
+type SBOMComponentChange struct {
+	Name       string `json:"name"`
+	OldVersion string `json:"oldVersion"`
+	NewVersion string `json:"newVersion"`
+}
+
+// ImageSBOMDiff Difference between the SBOMs of the images that come from the same repository in two bundles
+type ImageSBOMDiff struct {
+	// Repository Repository the images originally came from, used to match the images of both bundles
+	Repository string   `json:"repository"`
+	OldImages  []string `json:"oldImages,omitempty"`
+	NewImages  []string `json:"newImages,omitempty"`
+	// MissingSBOMs Images without an SBOM attached by cosign, their packages are not compared
+	MissingSBOMs []string              `json:"missingSBOMs,omitempty"`
+	Added        []SBOMComponent       `json:"added,omitempty"`
+	Removed      []SBOMComponent       `json:"removed,omitempty"`
+	Changed      []SBOMComponentChange `json:"changed,omitempty"`
+}
+
+// SBOMDiff Difference between the SBOMs attached to the images of two versions of a Bundle.
+// Only the images whose digest changed are present
+type SBOMDiff struct {
+	Images []ImageSBOMDiff `json:"images,omitempty"`
+}
+
+// ImageFetcher Interface to retrieve images
+type ImageFetcher interface {
+	Image(name.Reference) (regv1.Image, error)
+}
+
+// sbomImage Image of a bundle and the SBOM cosign attached to it
+type sbomImage struct {
+	image string
+	sbom  string
+}
+
+// NewSBOMDiff Given the Descriptions of two versions of a Bundle, retrieved with the cosign artifacts, compare the
+// SBOMs attached to the images that come from the same repository
+func NewSBOMDiff(oldDescription, newDescription Description, registryOpts registry.Opts) (SBOMDiff, error) {
+	reg, err := registry.NewSimpleRegistry(registryOpts)
+	if err != nil {
+		return SBOMDiff{}, err
+	}
+
+	return NewSBOMDiffWithFetcher(oldDescription, newDescription, reg)
+}
+
+// NewSBOMDiffWithFetcher Given the Descriptions of two versions of a Bundle, retrieved with the cosign artifacts,
+// compare the SBOMs attached to the images that come from the same repository
+func NewSBOMDiffWithFetcher(oldDescription, newDescription Description, fetcher ImageFetcher) (SBOMDiff, error) {
+	oldImages, err := sbomImagesByRepository(oldDescription)
+	if err != nil {
+		return SBOMDiff{}, err
+	}
+	newImages, err := sbomImagesByRepository(newDescription)
+	if err != nil {
+		return SBOMDiff{}, err
+	}
+
+	repositories := map[string]struct{}{}
+	for repo := range oldImages {
+		repositories[repo] = struct{}{}
+	}
+	for repo := range newImages {
+		repositories[repo] = struct{}{}
+	}
+
+	diff := SBOMDiff{}
+	for repo := range repositories {
+		imageDiff := ImageSBOMDiff{Repository: repo}
+		for _, img := range oldImages[repo] {
+			imageDiff.OldImages = append(imageDiff.OldImages, img.image)
+		}
+		for _, img := range newImages[repo] {
+			imageDiff.NewImages = append(imageDiff.NewImages, img.image)
+		}
+		if sameDigests(imageDiff.OldImages, imageDiff.NewImages) {
+			continue
+		}
+
+		oldComponents, missing, err := sbomComponents(oldImages[repo], fetcher)
+		if err != nil {
+			return SBOMDiff{}, err
+		}
+		imageDiff.MissingSBOMs = append(imageDiff.MissingSBOMs, missing...)
+		newComponents, missing, err := sbomComponents(newImages[repo], fetcher)
+		if err != nil {
+			return SBOMDiff{}, err
+		}
+		imageDiff.MissingSBOMs = append(imageDiff.MissingSBOMs, missing...)
+
+		imageDiff.Added, imageDiff.Removed, imageDiff.Changed = diffSBOMComponents(oldComponents, newComponents)
+		diff.Images = append(diff.Images, imageDiff)
+	}
+
+	sort.Slice(diff.Images, func(i, j int) bool {
+		return diff.Images[i].Repository < diff.Images[j].Repository
+	})
+	return diff, nil
+}
+
+// sbomImagesByRepository returns the images, excluding bundles and signatures, of the bundle and all its nested bundles,
+// grouped by the repository they originally came from
+func sbomImagesByRepository(description Description) (map[string][]sbomImage, error) {
+	visited := map[string]struct{}{}
+	result := map[string][]sbomImage{}
+
+	var collect func(desc Description) error
+	collect = func(desc Description) error {
+		for _, img := range desc.Content.Images {
+			if img.ImageType != bundle.ContentImage || img.Error != "" {
+				continue
+			}
+			if _, ok := visited[img.Image]; ok {
+				continue
+			}
+			visited[img.Image] = struct{}{}
+
+			origin := img.Origin
+			if origin == "" {
+				origin = img.Image
+			}
+			originRef, err := name.ParseReference(origin)
+			if err != nil {
+				return fmt.Errorf("Parsing origin of image '%s': %s", img.Image, err)
+			}
+
+			entry := sbomImage{image: img.Image}
+			for _, artifact := range img.CosignArtifacts {
+				if artifact.Type == signature.CosignSBOMType {
+					entry.sbom = artifact.Image
+					break
+				}
+			}
+			repo := originRef.Context().Name()
+			result[repo] = append(result[repo], entry)
+		}
+		for _, nested := range desc.Content.Bundles {
+			err := collect(nested)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := collect(description)
+	if err != nil {
+		return nil, err
+	}
+	for _, images := range result {
+		sort.Slice(images, func(i, j int) bool { return images[i].image < images[j].image })
+	}
+	return result, nil
+}
+
+// sameDigests returns true when both lists contain the same images, compared by digest
+func sameDigests(oldImages, newImages []string) bool {
+	digests := func(images []string) []string {
+		var result []string
+		for _, img := range images {
+			result = append(result, img[strings.LastIndex(img, "@")+1:])
+		}
+		sort.Strings(result)
+		return result
+	}
+
+	oldDigests, newDigests := digests(oldImages), digests(newImages)
+	if len(oldDigests) != len(newDigests) {
+		return false
+	}
+	for i := range oldDigests {
+		if oldDigests[i] != newDigests[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sbomComponents retrieves the packages listed in the SBOMs of the images, and the images without an SBOM
+func sbomComponents(images []sbomImage, fetcher ImageFetcher) ([]SBOMComponent, []string, error) {
+	var components []SBOMComponent
+	var missing []string
+	for _, img := range images {
+		if img.sbom == "" {
+			missing = append(missing, img.image)
+			continue
+		}
+		imgComponents, err := fetchSBOMComponents(img.sbom, fetcher)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Reading SBOM of image '%s': %s", img.image, err)
+		}
+		components = append(components, imgComponents...)
+	}
+	return components, missing, nil
+}
+
+// fetchSBOMComponents retrieves the SBOM stored by cosign as the layer of the image sbomRef
+func fetchSBOMComponents(sbomRef string, fetcher ImageFetcher) ([]SBOMComponent, error) {
+	ref, err := name.ParseReference(sbomRef)
+	if err != nil {
+		return nil, err
+	}
+	img, err := fetcher.Image(ref)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+
+	var components []SBOMComponent
+	for _, layer := range layers {
+		rc, err := layer.Uncompressed()
+		if err != nil {
+			return nil, err
+		}
+		doc, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		layerComponents, err := ParseSBOMComponents(doc)
+		if err != nil {
+			return nil, err
+		}
+		components = append(components, layerComponents...)
+	}
+	return components, nil
+}
+
+// sbomDocument Fields of the SPDX and CycloneDX JSON documents that list the packages
+type sbomDocument struct {
+	SPDXVersion string `json:"spdxVersion"`
+	Packages    []struct {
+		Name        string `json:"name"`
+		VersionInfo string `json:"versionInfo"`
+	} `json:"packages"`
+
+	BOMFormat  string                   `json:"bomFormat"`
+	Components []sbomCycloneDXComponent `json:"components"`
+}
+
+type sbomCycloneDXComponent struct {
+	Group      string                   `json:"group"`
+	Name       string                   `json:"name"`
+	Version    string                   `json:"version"`
+	Components []sbomCycloneDXComponent `json:"components"`
+}
+
+// ParseSBOMComponents returns the packages listed in an SBOM in the SPDX or CycloneDX JSON format
+func ParseSBOMComponents(doc []byte) ([]SBOMComponent, error) {
+	var sbom sbomDocument
+	err := json.Unmarshal(doc, &sbom)
+	if err != nil {
+		return nil, fmt.Errorf("Expected SBOM to be in the SPDX or CycloneDX JSON format: %s", err)
+	}
+
+	var components []SBOMComponent
+	switch {
+	case sbom.SPDXVersion != "":
+		for _, pkg := range sbom.Packages {
+			components = append(components, SBOMComponent{Name: pkg.Name, Version: pkg.VersionInfo})
+		}
+	case sbom.BOMFormat == "CycloneDX":
+		var collect func([]sbomCycloneDXComponent)
+		collect = func(cdxComponents []sbomCycloneDXComponent) {
+			for _, component := range cdxComponents {
+				componentName := component.Name
+				if component.Group != "" {
+					componentName = component.Group + "/" + component.Name
+				}
+				components = append(components, SBOMComponent{Name: componentName, Version: component.Version})
+				collect(component.Components)
+			}
+		}
+		collect(sbom.Components)
+	default:
+		return nil, fmt.Errorf("Expected SBOM to be in the SPDX or CycloneDX JSON format")
+	}
+	return components, nil
+}
+
+// diffSBOMComponents compares the versions of each package. When a package has a single version in both lists,
+// a different version is reported as a change, otherwise each version is reported as added or removed
+func diffSBOMComponents(oldComponents, newComponents []SBOMComponent) ([]SBOMComponent, []SBOMComponent, []SBOMComponentChange) {
+	versionsByName := func(components []SBOMComponent) map[string]map[string]struct{} {
+		result := map[string]map[string]struct{}{}
+		for _, component := range components {
+			if result[component.Name] == nil {
+				result[component.Name] = map[string]struct{}{}
+			}
+			result[component.Name][component.Version] = struct{}{}
+		}
+		return result
+	}
+	oldVersions, newVersions := versionsByName(oldComponents), versionsByName(newComponents)
+
+	var added, removed []SBOMComponent
+	var changed []SBOMComponentChange
+	for componentName, versions := range oldVersions {
+		otherVersions := newVersions[componentName]
+		if len(versions) == 1 && len(otherVersions) == 1 {
+			oldVersion, newVersion := singleVersion(versions), singleVersion(otherVersions)
+			if oldVersion != newVersion {
+				changed = append(changed, SBOMComponentChange{Name: componentName, OldVersion: oldVersion, NewVersion: newVersion})
+			}
+			continue
+		}
+		for version := range versions {
+			if _, found := otherVersions[version]; !found {
+				removed = append(removed, SBOMComponent{Name: componentName, Version: version})
+			}
+		}
+		for version := range otherVersions {
+			if _, found := versions[version]; !found {
+				added = append(added, SBOMComponent{Name: componentName, Version: version})
+			}
+		}
+	}
+	for componentName, versions := range newVersions {
+		if _, found := oldVersions[componentName]; found {
+			continue
+		}
+		for version := range versions {
+			added = append(added, SBOMComponent{Name: componentName, Version: version})
+		}
+	}
+
+	sortComponents(added)
+	sortComponents(removed)
+	sort.Slice(changed, func(i, j int) bool { return changed[i].Name < changed[j].Name })
+	return added, removed, changed
+}
+
+func singleVersion(versions map[string]struct{}) string {
+	for version := range versions {
+		return version
+	}
+	return ""
+}
+
+func sortComponents(components []SBOMComponent) {
+	sort.Slice(components, func(i, j int) bool {
+		if components[i].Name != components[j].Name {
+			return components[i].Name < components[j].Name
+		}
+		return components[i].Version < components[j].Version
+	})
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"os"
+	"testing"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/bundle"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/registry"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestNewSBOMDiff(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	fakeRegBuilder := helpers.NewFakeRegistry(t, logger)
+	defer fakeRegBuilder.CleanUp()
+
+	oldApp := fakeRegBuilder.WithRandomImage("relocated/app")
+	newApp := fakeRegBuilder.WithRandomImage("relocated/app-new")
+	sameDB := fakeRegBuilder.WithRandomImage("relocated/db")
+	newCache := fakeRegBuilder.WithRandomImage("relocated/cache")
+	oldSBOM := fakeRegBuilder.WithImage("relocated/app-sbom:old", sbomImage(t, "text/spdx+json", `{
+  "spdxVersion": "SPDX-2.3",
+  "packages": [
+    {"name": "openssl", "versionInfo": "3.0.7"},
+    {"name": "zlib", "versionInfo": "1.2.13"},
+    {"name": "curl", "versionInfo": "7.88.0"}
+  ]
+}`))
+	newSBOM := fakeRegBuilder.WithImage("relocated/app-sbom:new", sbomImage(t, "application/vnd.cyclonedx+json", `{
+  "bomFormat": "CycloneDX",
+  "components": [
+    {"name": "openssl", "version": "3.0.8"},
+    {"name": "zlib", "version": "1.2.13"},
+    {"group": "org.example", "name": "lib", "version": "1.0.0", "components": [{"name": "nested", "version": "2.0.0"}]}
+  ]
+}`))
+	fakeRegBuilder.Build()
+
+	oldDescription := v1.Description{
+		Content: v1.Content{
+			Images: map[string]v1.ImageInfo{
+				oldApp.Digest: {Image: oldApp.RefDigest, Origin: "docker.io/library/app@" + oldApp.Digest, ImageType: bundle.ContentImage,
+					CosignArtifacts: []v1.CosignArtifact{{Type: "sbom", Image: oldSBOM.RefDigest}}},
+				sameDB.Digest: {Image: sameDB.RefDigest, Origin: "docker.io/library/db@" + sameDB.Digest, ImageType: bundle.ContentImage},
+			},
+		},
+	}
+	newDescription := v1.Description{
+		Content: v1.Content{
+			Images: map[string]v1.ImageInfo{
+				newApp.Digest: {Image: newApp.RefDigest, Origin: "docker.io/library/app@" + newApp.Digest, ImageType: bundle.ContentImage,
+					CosignArtifacts: []v1.CosignArtifact{{Type: "sbom", Image: newSBOM.RefDigest}}},
+				sameDB.Digest: {Image: sameDB.RefDigest, Origin: "docker.io/library/db@" + sameDB.Digest, ImageType: bundle.ContentImage},
+			},
+			Bundles: map[string]v1.Description{
+				"nested": {Content: v1.Content{Images: map[string]v1.ImageInfo{
+					newCache.Digest: {Image: newCache.RefDigest, Origin: "docker.io/library/cache@" + newCache.Digest, ImageType: bundle.ContentImage},
+				}}},
+			},
+		},
+	}
+
+	diff, err := v1.NewSBOMDiff(oldDescription, newDescription, registry.Opts{EnvironFunc: os.Environ})
+	require.NoError(t, err)
+
+	require.Len(t, diff.Images, 2, "the db image did not change")
+	assert.Equal(t, v1.ImageSBOMDiff{
+		Repository: "index.docker.io/library/app",
+		OldImages:  []string{oldApp.RefDigest},
+		NewImages:  []string{newApp.RefDigest},
+		Added: []v1.SBOMComponent{
+			{Name: "nested", Version: "2.0.0"},
+			{Name: "org.example/lib", Version: "1.0.0"},
+		},
+		Removed: []v1.SBOMComponent{{Name: "curl", Version: "7.88.0"}},
+		Changed: []v1.SBOMComponentChange{{Name: "openssl", OldVersion: "3.0.7", NewVersion: "3.0.8"}},
+	}, diff.Images[0])
+	assert.Equal(t, v1.ImageSBOMDiff{
+		Repository:   "index.docker.io/library/cache",
+		NewImages:    []string{newCache.RefDigest},
+		MissingSBOMs: []string{newCache.RefDigest},
+	}, diff.Images[1])
+}
+
+func TestParseSBOMComponents(t *testing.T) {
+	t.Run("when the SBOM is not in a JSON format it fails", func(t *testing.T) {
+		_, err := v1.ParseSBOMComponents([]byte("SPDXVersion: SPDX-2.3"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Expected SBOM to be in the SPDX or CycloneDX JSON format")
+	})
+
+	t.Run("when the JSON document is not an SBOM it fails", func(t *testing.T) {
+		_, err := v1.ParseSBOMComponents([]byte(`{"some": "document"}`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Expected SBOM to be in the SPDX or CycloneDX JSON format")
+	})
+
+	t.Run("when a package is present with several versions it returns each version", func(t *testing.T) {
+		components, err := v1.ParseSBOMComponents([]byte(`{"spdxVersion": "SPDX-2.3", "packages": [
+  {"name": "lib", "versionInfo": "1.0.0"}, {"name": "lib", "versionInfo": "2.0.0"}]}`))
+		require.NoError(t, err)
+		assert.Equal(t, []v1.SBOMComponent{{Name: "lib", Version: "1.0.0"}, {Name: "lib", Version: "2.0.0"}}, components)
+	})
+}
+
+// sbomImage Image that contains the SBOM as its only layer, as cosign attaches it
+func sbomImage(t *testing.T, mediaType types.MediaType, doc string) regv1.Image {
+	img, err := mutate.Append(empty.Image, mutate.Addendum{Layer: static.NewLayer([]byte(doc), mediaType)})
+	require.NoError(t, err)
+	return img
+}
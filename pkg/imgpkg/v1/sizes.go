@@ -0,0 +1,79 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"fmt"
+)
+
+// imageSize Compressed size and number of layers of an image
+type imageSize struct {
+	size   int64
+	layers int
+}
+
+// addSizes Sets the compressed size and number of layers of every bundle and image in the description, and the
+// transfer size of the described bundle. Images that are referenced by multiple bundles are only retrieved once
+func addSizes(description Description, fetcher ImageDescriptorFetcher) (Description, error) {
+	allLayers := map[string]*LayerInfo{}
+	sizes := map[string]imageSize{}
+
+	sizeOf := func(imageRef string) (imageSize, error) {
+		if size, found := sizes[imageRef]; found {
+			return size, nil
+		}
+		layersDigests, err := imageLayers(imageRef, fetcher, allLayers)
+		if err != nil {
+			return imageSize{}, fmt.Errorf("Retrieving layers of image %s: %s", imageRef, err)
+		}
+		size := imageSize{layers: len(layersDigests)}
+		for _, digest := range layersDigests {
+			size.size += allLayers[digest].Size
+		}
+		sizes[imageRef] = size
+		return size, nil
+	}
+
+	var addRec func(desc Description) (Description, error)
+	addRec = func(desc Description) (Description, error) {
+		bundleSize, err := sizeOf(desc.Image)
+		if err != nil {
+			return Description{}, err
+		}
+		desc.Size = bundleSize.size
+		desc.Layers = bundleSize.layers
+
+		for digest, img := range desc.Content.Images {
+			if img.Error != "" {
+				continue
+			}
+			size, err := sizeOf(img.Image)
+			if err != nil {
+				return Description{}, err
+			}
+			img.Size = size.size
+			img.Layers = size.layers
+			desc.Content.Images[digest] = img
+		}
+
+		for digest, nestedBundle := range desc.Content.Bundles {
+			nestedBundle, err := addRec(nestedBundle)
+			if err != nil {
+				return Description{}, err
+			}
+			desc.Content.Bundles[digest] = nestedBundle
+		}
+		return desc, nil
+	}
+
+	description, err := addRec(description)
+	if err != nil {
+		return Description{}, err
+	}
+
+	for _, layer := range allLayers {
+		description.TransferSize += layer.Size
+	}
+	return description, nil
+}
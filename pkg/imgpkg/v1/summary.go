@@ -0,0 +1,41 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+// Summary Number of bundles and images in a Bundle, including its Nested Bundles, and their size
+type Summary struct {
+	// Bundles Number of distinct bundles, including the described bundle
+	Bundles int `json:"bundles"`
+	// Images Number of distinct images that are not bundles
+	Images int `json:"images"`
+	// TotalSize Compressed size of all the images, counting shared layers once per image
+	TotalSize int64 `json:"totalSize"`
+	// UniqueSize Compressed size of all the unique layers, this is the size stored in a registry
+	UniqueSize int64 `json:"uniqueSize"`
+}
+
+// NewSummary Given the Description of a Bundle and the layers shared between its images creates a Summary
+func NewSummary(description Description, report DedupReport) Summary {
+	bundles := map[string]struct{}{}
+	images := map[string]struct{}{}
+
+	var collect func(desc Description)
+	collect = func(desc Description) {
+		bundles[desc.Image] = struct{}{}
+		for _, img := range desc.Content.Images {
+			images[img.Image] = struct{}{}
+		}
+		for _, b := range desc.Content.Bundles {
+			collect(b)
+		}
+	}
+	collect(description)
+
+	return Summary{
+		Bundles:    len(bundles),
+		Images:     len(images),
+		TotalSize:  report.TotalSize,
+		UniqueSize: report.UniqueSize,
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+)
+
+func TestNewSummary(t *testing.T) {
+	sharedImage := v1.ImageInfo{Image: "registry.io/app@sha256:1"}
+	nestedBundle := v1.Description{
+		Image: "registry.io/nested@sha256:2",
+		Content: v1.Content{
+			Images: map[string]v1.ImageInfo{
+				"sha256:1": sharedImage,
+				"sha256:3": {Image: "registry.io/other@sha256:3"},
+			},
+		},
+	}
+	description := v1.Description{
+		Image: "registry.io/bundle@sha256:4",
+		Content: v1.Content{
+			Bundles: map[string]v1.Description{
+				"sha256:2": nestedBundle,
+			},
+			Images: map[string]v1.ImageInfo{
+				"sha256:1": sharedImage,
+			},
+		},
+	}
+
+	summary := v1.NewSummary(description, v1.DedupReport{TotalSize: 3000, UniqueSize: 2000, SavedSize: 1000})
+
+	assert.Equal(t, v1.Summary{Bundles: 2, Images: 2, TotalSize: 3000, UniqueSize: 2000}, summary)
+}
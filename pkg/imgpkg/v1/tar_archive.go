@@ -0,0 +1,158 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1
+
+import (
+	"fmt"
+	"io"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagedesc"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagetar"
+)
+
+// TarLayer Layer of an image stored in a tar
+type TarLayer struct {
+	Digest    string
+	MediaType string
+	Size      int64
+	// Present is false when the layer was not included in the tar, e.g. non-distributable layers
+	Present bool
+}
+
+// TarImage Image or index stored in a tar
+type TarImage struct {
+	// Ref Location of the image, in the format registry/repository@digest
+	Ref string
+	// OrigRef Location of the image when it was copied to the tar
+	OrigRef   string
+	Tag       string
+	Digest    string
+	MediaType string
+	Labels    map[string]string
+	// Manifest Raw manifest of the image or index
+	Manifest []byte
+
+	// ConfigDigest and Config are only present for images
+	ConfigDigest string
+	Config       []byte
+	// Layers only present for images
+	Layers []TarLayer
+
+	// Manifests Images and indexes referenced by an index
+	Manifests []TarImage
+}
+
+// IsIndex Checks if the TarImage is an index
+func (t TarImage) IsIndex() bool {
+	return t.ConfigDigest == ""
+}
+
+// TarArchive Reads the images and blobs of a tar created by imgpkg copy --to-tar.
+// Allows tools to import the contents of the tar without having to know the format of the tar
+type TarArchive struct {
+	archive *imagetar.TarArchive
+	images  []TarImage
+}
+
+// OpenTarArchive Opens a tar created by imgpkg, the TarArchive needs to be closed after use
+func OpenTarArchive(path string) (*TarArchive, error) {
+	archive, err := imagetar.OpenTarArchive(path)
+	if err != nil {
+		return nil, err
+	}
+
+	descs, err := archive.Descriptors()
+	if err != nil {
+		archive.Close()
+		return nil, err
+	}
+
+	tarArchive := &TarArchive{archive: archive}
+	for _, desc := range descs {
+		switch {
+		case desc.Image != nil:
+			tarArchive.images = append(tarArchive.images, tarArchive.newTarImage(*desc.Image))
+		case desc.ImageIndex != nil:
+			tarArchive.images = append(tarArchive.images, tarArchive.newTarIndex(*desc.ImageIndex))
+		}
+	}
+
+	return tarArchive, nil
+}
+
+// Images Returns the images and indexes in the tar, images referenced by an index are in TarImage.Manifests
+func (t *TarArchive) Images() []TarImage {
+	return t.images
+}
+
+// OpenBlob Returns the contents of a layer. The result can be read concurrently, using ReadAt,
+// until the TarArchive is closed
+func (t *TarArchive) OpenBlob(digest string) (*io.SectionReader, error) {
+	hash, err := regv1.NewHash(digest)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing digest '%s': %s", digest, err)
+	}
+	return t.archive.Blob(hash)
+}
+
+// Close Closes the tar
+func (t *TarArchive) Close() error {
+	return t.archive.Close()
+}
+
+func (t *TarArchive) newTarImage(desc imagedesc.ImageDescriptor) TarImage {
+	img := TarImage{
+		Ref:          firstRef(desc.Refs),
+		OrigRef:      desc.OrigRef,
+		Tag:          desc.Tag,
+		Digest:       desc.Manifest.Digest,
+		MediaType:    desc.Manifest.MediaType,
+		Labels:       desc.Labels,
+		Manifest:     []byte(desc.Manifest.Raw),
+		ConfigDigest: desc.Config.Digest,
+		Config:       []byte(desc.Config.Raw),
+	}
+
+	for _, layer := range desc.Layers {
+		present := false
+		if hash, err := regv1.NewHash(layer.Digest); err == nil {
+			present = t.archive.HasBlob(hash)
+		}
+		img.Layers = append(img.Layers, TarLayer{
+			Digest:    layer.Digest,
+			MediaType: layer.MediaType,
+			Size:      layer.Size,
+			Present:   present,
+		})
+	}
+	return img
+}
+
+func (t *TarArchive) newTarIndex(desc imagedesc.ImageIndexDescriptor) TarImage {
+	idx := TarImage{
+		Ref:       firstRef(desc.Refs),
+		OrigRef:   desc.OrigRef,
+		Tag:       desc.Tag,
+		Digest:    desc.Digest,
+		MediaType: desc.MediaType,
+		Labels:    desc.Labels,
+		Manifest:  []byte(desc.Raw),
+	}
+
+	for _, img := range desc.Images {
+		idx.Manifests = append(idx.Manifests, t.newTarImage(img))
+	}
+	for _, childIdx := range desc.Indexes {
+		idx.Manifests = append(idx.Manifests, t.newTarIndex(childIdx))
+	}
+	return idx
+}
+
+func firstRef(refs []string) string {
+	if len(refs) == 0 {
+		return ""
+	}
+	return refs[0]
+}
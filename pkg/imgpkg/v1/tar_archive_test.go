@@ -0,0 +1,105 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imageset"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/imagetar"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/internal/util"
+	v1 "github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/v1"
+	"github.com/vmware-tanzu/carvel-imgpkg/test/helpers"
+)
+
+func TestOpenTarArchive(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	img := fakeRegistry.WithRandomImage("some/image")
+	imgWithNonDist, nonDistLayer := fakeRegistry.WithRandomImage("some/image-non-dist").WithNonDistributableLayer()
+	reg := fakeRegistry.Build()
+
+	logger := util.NewNoopLevelLogger()
+	imageSet := imageset.NewImageSet(1, logger, util.DefaultTagGenerator{})
+	tarImageSet := imageset.NewTarImageSet(imageSet, 1, logger)
+
+	refs := imageset.NewUnprocessedImageRefs()
+	refs.Add(imageset.UnprocessedImageRef{DigestRef: img.RefDigest, Tag: "some-tag"})
+	refs.Add(imageset.UnprocessedImageRef{DigestRef: imgWithNonDist.RefDigest})
+
+	tarPath := filepath.Join(t.TempDir(), "images.tar")
+	_, err := tarImageSet.Export(refs, tarPath, reg, imagetar.NewImageLayerWriterCheck(false), false)
+	require.NoError(t, err)
+
+	subject, err := v1.OpenTarArchive(tarPath)
+	require.NoError(t, err)
+	defer subject.Close()
+
+	images := map[string]v1.TarImage{}
+	for _, image := range subject.Images() {
+		images[image.Digest] = image
+	}
+	require.Len(t, images, 2)
+
+	t.Run("lists the images with their manifests and layers", func(t *testing.T) {
+		tarImg := images[img.Digest]
+		assert.False(t, tarImg.IsIndex())
+		assert.Equal(t, img.Digest, tarImg.Digest)
+		assert.Equal(t, "some-tag", tarImg.Tag)
+		assert.NotEmpty(t, tarImg.Manifest)
+		assert.NotEmpty(t, tarImg.Config)
+
+		layers, err := img.Image.Layers()
+		require.NoError(t, err)
+		require.Len(t, tarImg.Layers, len(layers))
+		for _, layer := range tarImg.Layers {
+			assert.True(t, layer.Present)
+		}
+	})
+
+	t.Run("blobs can be read at any offset", func(t *testing.T) {
+		for _, layer := range images[img.Digest].Layers {
+			blob, err := subject.OpenBlob(layer.Digest)
+			require.NoError(t, err)
+			assert.Equal(t, layer.Size, blob.Size())
+
+			contents := make([]byte, blob.Size())
+			_, err = blob.ReadAt(contents, 0)
+			if err != io.EOF {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, layer.Digest, fmt.Sprintf("sha256:%x", sha256.Sum256(contents)))
+
+			tail := make([]byte, 10)
+			_, err = blob.ReadAt(tail, blob.Size()-10)
+			if err != io.EOF {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, contents[len(contents)-10:], tail)
+		}
+	})
+
+	t.Run("non distributable layers are not present", func(t *testing.T) {
+		nonDistDigest, err := nonDistLayer.Digest()
+		require.NoError(t, err)
+
+		found := false
+		for _, layer := range images[imgWithNonDist.Digest].Layers {
+			if layer.Digest == nonDistDigest.String() {
+				found = true
+				assert.False(t, layer.Present)
+			}
+		}
+		assert.True(t, found)
+
+		_, err = subject.OpenBlob(nonDistDigest.String())
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,166 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Workspace Directory that holds the temporary files and folders created during a single operation.
+// Cleanup removes the directory and every artifact tracked outside of it, so that an operation that
+// is cancelled, fails or panics does not leave files behind.
+//
+// A nil *Workspace is valid and creates the temporary files in the default temporary directory of the OS
+type Workspace struct {
+	dir string
+
+	lock    sync.Mutex
+	tracked map[string]struct{}
+	cleaned bool
+}
+
+// New Creates a workspace directory inside parentDir, when parentDir is empty the default temporary
+// directory of the OS is used
+func New(parentDir string) (*Workspace, error) {
+	dir, err := os.MkdirTemp(parentDir, "imgpkg-workspace-")
+	if err != nil {
+		return nil, fmt.Errorf("Creating workspace: %s", err)
+	}
+	return &Workspace{dir: dir, tracked: map[string]struct{}{}}, nil
+}
+
+// Run Creates a workspace, calls fn with it and removes the workspace once fn returns or panics
+func Run(parentDir string, fn func(*Workspace) error) (err error) {
+	ws, err := New(parentDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		cleanupErr := ws.Cleanup()
+		if err == nil {
+			err = cleanupErr
+		}
+	}()
+
+	return fn(ws)
+}
+
+// Dir Path of the workspace directory
+func (w *Workspace) Dir() string {
+	if w == nil {
+		return ""
+	}
+	return w.dir
+}
+
+// MkdirTemp Creates a new temporary directory inside the workspace
+func (w *Workspace) MkdirTemp(pattern string) (string, error) {
+	if w == nil {
+		return os.MkdirTemp("", pattern)
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.cleaned {
+		return "", fmt.Errorf("Creating temporary directory: workspace '%s' was already cleaned up", w.dir)
+	}
+	return os.MkdirTemp(w.dir, pattern)
+}
+
+// CreateTemp Creates a new temporary file inside the workspace
+func (w *Workspace) CreateTemp(pattern string) (*os.File, error) {
+	if w == nil {
+		return os.CreateTemp("", pattern)
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.cleaned {
+		return nil, fmt.Errorf("Creating temporary file: workspace '%s' was already cleaned up", w.dir)
+	}
+	return os.CreateTemp(w.dir, pattern)
+}
+
+// Track Registers an artifact created outside of the workspace directory, to be removed on Cleanup
+func (w *Workspace) Track(path string) {
+	if w == nil {
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.tracked[path] = struct{}{}
+}
+
+// Untrack Stops tracking an artifact, usually because it was moved to its final location or removed
+func (w *Workspace) Untrack(path string) {
+	if w == nil {
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	delete(w.tracked, path)
+}
+
+// Cleanup Removes the workspace directory and all the tracked artifacts.
+// It is safe to call Cleanup more than once and from multiple goroutines
+func (w *Workspace) Cleanup() error {
+	if w == nil {
+		return nil
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.cleaned {
+		return nil
+	}
+	w.cleaned = true
+
+	var paths []string
+	for path := range w.tracked {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	paths = append(paths, w.dir)
+	w.tracked = map[string]struct{}{}
+
+	var errs []string
+	for _, path := range paths {
+		if err := os.RemoveAll(path); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Cleaning up workspace '%s': %s", w.dir, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// CleanupOnDone Removes the workspace when ctx is done.
+// The returned function stops watching ctx and must be called when the operation finishes
+func (w *Workspace) CleanupOnDone(ctx context.Context) func() {
+	stop := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = w.Cleanup()
+		case <-stop:
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(stop) })
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package workspace_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-imgpkg/pkg/imgpkg/workspace"
+)
+
+func TestWorkspace(t *testing.T) {
+	t.Run("creates the temporary files and folders inside the workspace and removes them on cleanup", func(t *testing.T) {
+		ws, err := workspace.New(t.TempDir())
+		require.NoError(t, err)
+
+		tmpDir, err := ws.MkdirTemp("some-dir")
+		require.NoError(t, err)
+		assert.Equal(t, ws.Dir(), filepath.Dir(tmpDir))
+
+		tmpFile, err := ws.CreateTemp("some-file")
+		require.NoError(t, err)
+		require.NoError(t, tmpFile.Close())
+		assert.Equal(t, ws.Dir(), filepath.Dir(tmpFile.Name()))
+
+		require.NoError(t, ws.Cleanup())
+		assert.NoDirExists(t, ws.Dir())
+		require.NoError(t, ws.Cleanup(), "cleanup can be called more than once")
+
+		_, err = ws.CreateTemp("some-file")
+		require.ErrorContains(t, err, "was already cleaned up")
+		_, err = ws.MkdirTemp("some-dir")
+		require.ErrorContains(t, err, "was already cleaned up")
+	})
+
+	t.Run("removes the tracked artifacts that are outside of the workspace", func(t *testing.T) {
+		outsideDir := t.TempDir()
+		tracked := filepath.Join(outsideDir, "tracked")
+		untracked := filepath.Join(outsideDir, "untracked")
+		require.NoError(t, os.WriteFile(tracked, []byte("content"), 0600))
+		require.NoError(t, os.WriteFile(untracked, []byte("content"), 0600))
+
+		ws, err := workspace.New(t.TempDir())
+		require.NoError(t, err)
+		ws.Track(tracked)
+		ws.Track(untracked)
+		ws.Untrack(untracked)
+
+		require.NoError(t, ws.Cleanup())
+		assert.NoFileExists(t, tracked)
+		assert.FileExists(t, untracked)
+	})
+
+	t.Run("removes the workspace when the function panics", func(t *testing.T) {
+		var dir string
+		assert.Panics(t, func() {
+			_ = workspace.Run(t.TempDir(), func(ws *workspace.Workspace) error {
+				dir = ws.Dir()
+				panic("some panic")
+			})
+		})
+		require.NotEmpty(t, dir)
+		assert.NoDirExists(t, dir)
+	})
+
+	t.Run("removes the workspace when the context is cancelled", func(t *testing.T) {
+		ws, err := workspace.New(t.TempDir())
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stop := ws.CleanupOnDone(ctx)
+		defer stop()
+		cancel()
+
+		assert.Eventually(t, func() bool {
+			_, err := os.Stat(ws.Dir())
+			return os.IsNotExist(err)
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("uses the default temporary directory when the workspace is nil", func(t *testing.T) {
+		var ws *workspace.Workspace
+
+		tmpFile, err := ws.CreateTemp("some-file")
+		require.NoError(t, err)
+		defer os.Remove(tmpFile.Name())
+		require.NoError(t, tmpFile.Close())
+		assert.Equal(t, filepath.Clean(os.TempDir()), filepath.Dir(tmpFile.Name()))
+		assert.NoError(t, ws.Cleanup())
+	})
+}